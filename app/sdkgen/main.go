@@ -0,0 +1,660 @@
+// Command sdkgen generates the Go and TypeScript API clients in sdk/ from the
+// routes registered in app/web/main.go and the JSON schemas in
+// app/web/schema.go. It reads the source rather than running the server, so
+// it needs no database:
+//
+//	go run ./app/sdkgen -web app/web -out sdk -routes app/web/routes_gen.go
+//
+// Every route becomes a client method named after its handler. Request bodies
+// validated by a schema get a generated type; responses are returned as raw
+// JSON for the caller to decode. With -routes, the routes are also written
+// back to the web app as a table, which the OpenAPI spec is built from.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Route struct {
+	Method  string
+	Path    string
+	Pattern string
+	Handler string
+	Schema  string
+	Body    bool
+	Auth    string
+}
+
+type Schema struct {
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Enum       []interface{}      `json:"enum"`
+}
+
+func main() {
+	web := flag.String("web", "app/web", "directory of the web app sources")
+	out := flag.String("out", "sdk", "directory to write the clients to")
+	table := flag.String("routes", "", "file to write the route table of the web app to")
+	flag.Parse()
+
+	all, err := parseRoutes(filepath.Join(*web, "main.go"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	schemas, err := parseSchemas(filepath.Join(*web, "schema.go"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *table != "" {
+		src, err := format.Source(generateRoutes(all))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := write(*table, src); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Handlers outside appContext, like the /ws upgrade, don't answer with
+	// JSON and are left out of the clients.
+	routes := []*Route{}
+	for _, r := range all {
+		if r.Handler != "" {
+			routes = append(routes, r)
+		}
+	}
+	nameMethods(routes)
+
+	goSrc, err := format.Source(generateGo(routes, schemas))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := write(filepath.Join(*out, "go", "client.go"), goSrc); err != nil {
+		log.Fatal(err)
+	}
+	if err := write(filepath.Join(*out, "ts", "client.ts"), generateTS(routes, schemas)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func write(path string, src []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, src, 0644)
+}
+
+// parseRoutes collects the router.Get/Post/Put/Patch/Delete calls in routes().
+func parseRoutes(path string) ([]*Route, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []*Route{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !isIdent(sel.X, "router") {
+			return true
+		}
+		method := strings.ToUpper(sel.Sel.Name)
+		switch method {
+		case "GET", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			return true
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return true
+		}
+
+		handler := call.Args[1]
+		if static, ok := handler.(*ast.CallExpr); ok && isIdent(static.Fun, "withStatic") && len(static.Args) == 2 {
+			if statics, ok := static.Args[0].(*ast.CompositeLit); ok {
+				for _, elt := range statics.Elts {
+					kv := elt.(*ast.KeyValueExpr)
+					key, _ := stringLit(kv.Key)
+					static := route(method, strings.Replace(path, ":id", key, 1), kv.Value)
+					static.Pattern = path
+					routes = append(routes, static)
+				}
+			}
+			handler = static.Args[1]
+		}
+		routes = append(routes, route(method, path, handler))
+
+		return false
+	})
+
+	return routes, nil
+}
+
+// route reads the handler name, schema and body type off a middleware chain.
+func route(method string, path string, chain ast.Expr) *Route {
+	r := &Route{Method: method, Path: path, Pattern: path}
+	ast.Inspect(chain, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if (isIdent(n.X, "appC") || isAppContext(n.X)) && strings.HasSuffix(n.Sel.Name, "Handler") {
+				r.Handler = strings.TrimSuffix(n.Sel.Name, "Handler")
+			}
+		case *ast.CallExpr:
+			if isIdent(n.Fun, "schemaHandler") && len(n.Args) == 1 {
+				r.Schema, _ = stringLit(n.Args[0])
+			}
+			if isIdent(n.Fun, "bodyHandler") {
+				r.Body = true
+			}
+			if isIdent(n.Fun, "requireUser") {
+				r.Auth = "user"
+			}
+			if role, ok := n.Fun.(*ast.Ident); ok && role.Name == "requireRole" && len(n.Args) == 2 {
+				if id, ok := n.Args[1].(*ast.Ident); ok {
+					r.Auth = strings.ToLower(strings.TrimPrefix(id.Name, "Role"))
+				}
+			}
+		}
+		return true
+	})
+
+	return r
+}
+
+// parseSchemas decodes the schemaSources map literal.
+func parseSchemas(path string) (map[string]*Schema, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := map[string]*Schema{}
+	var parseErr error
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "schemaSources" {
+			return true
+		}
+		for _, elt := range spec.Values[0].(*ast.CompositeLit).Elts {
+			kv := elt.(*ast.KeyValueExpr)
+			name, _ := stringLit(kv.Key)
+			src, _ := stringLit(kv.Value)
+			s := &Schema{}
+			if err := json.Unmarshal([]byte(src), s); err != nil {
+				parseErr = fmt.Errorf("schema %s: %v", name, err)
+			}
+			schemas[name] = s
+		}
+		return false
+	})
+
+	return schemas, parseErr
+}
+
+// isAppContext matches the (*appContext) of a method expression such as
+// (*appContext).venueHandler.
+func isAppContext(e ast.Expr) bool {
+	paren, ok := e.(*ast.ParenExpr)
+	if !ok {
+		return false
+	}
+	star, ok := paren.X.(*ast.StarExpr)
+	return ok && isIdent(star.X, "appContext")
+}
+
+func isIdent(e ast.Expr, name string) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// nameMethods gives routes sharing a handler distinct names by suffixing the
+// later ones with their path.
+func nameMethods(routes []*Route) {
+	seen := map[string]bool{}
+	for _, r := range routes {
+		name := pascal(r.Handler)
+		if seen[name] {
+			for _, segment := range strings.Split(r.Path, "/") {
+				if segment != "" && !strings.HasPrefix(segment, ":") {
+					name += pascal(segment)
+				}
+			}
+		}
+		seen[name] = true
+		r.Handler = name
+	}
+}
+
+func pascal(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' || r == '/' })
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+func camel(s string) string {
+	s = pascal(s)
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func pathParams(path string) []string {
+	params := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+		}
+	}
+
+	return params
+}
+
+func isRequired(s *Schema, name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedProperties(s *Schema) []string {
+	names := []string{}
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedSchemas(schemas map[string]*Schema) []string {
+	names := []string{}
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Routes
+
+// generateRoutes writes routes as the generatedRoutes table of the web app,
+// see spec.go there.
+func generateRoutes(routes []*Route) []byte {
+	b := &bytes.Buffer{}
+	fmt.Fprint(b, "// Code generated by sdkgen; DO NOT EDIT.\n\npackage main\n\n")
+	fmt.Fprint(b, "var generatedRoutes = []generatedRoute{\n")
+	for _, r := range routes {
+		handler := ""
+		if r.Handler != "" {
+			handler = r.Handler + "Handler"
+		}
+		fmt.Fprintf(b, "\t{%q, %q, %q, %q, %q, %t, %q},\n", r.Method, r.Path, r.Pattern, handler, r.Schema, r.Body, r.Auth)
+	}
+	fmt.Fprint(b, "}\n")
+
+	return b.Bytes()
+}
+
+// Go
+
+func generateGo(routes []*Route, schemas map[string]*Schema) []byte {
+	b := &bytes.Buffer{}
+	types := &bytes.Buffer{}
+	for _, name := range sortedSchemas(schemas) {
+		goStruct(types, pascal(name), schemas[name])
+	}
+
+	header := goHeader
+	if !bytes.Contains(types.Bytes(), []byte("time.Time")) {
+		header = strings.Replace(header, "\t\"time\"\n", "", 1)
+	}
+	fmt.Fprint(b, header)
+	b.Write(types.Bytes())
+
+	for _, r := range routes {
+		args := []string{}
+		for _, p := range pathParams(r.Path) {
+			args = append(args, camel(p)+" string")
+		}
+		body := "nil"
+		switch {
+		case r.Body && r.Schema != "":
+			args = append(args, "body *"+pascal(r.Schema))
+			body = "body"
+		case r.Body || r.Method != "GET" && r.Method != "DELETE":
+			args = append(args, "body interface{}")
+			body = "body"
+		}
+		args = append(args, "query url.Values")
+
+		path := strconv.Quote(r.Path)
+		for _, p := range pathParams(r.Path) {
+			path = strings.Replace(path, ":"+p, `"+url.PathEscape(`+camel(p)+`)+"`, 1)
+		}
+		path = strings.TrimSuffix(path, `+""`)
+
+		fmt.Fprintf(b, "\n// %s calls %s %s.\n", r.Handler, r.Method, r.Path)
+		fmt.Fprintf(b, "func (c *Client) %s(%s) (json.RawMessage, error) {\n", r.Handler, strings.Join(args, ", "))
+		fmt.Fprintf(b, "\treturn c.do(%q, %s, query, %s)\n}\n", r.Method, path, body)
+	}
+
+	return b.Bytes()
+}
+
+func goStruct(b *bytes.Buffer, name string, s *Schema) {
+	nested := &bytes.Buffer{}
+	fmt.Fprintf(b, "\ntype %s struct {\n", name)
+	for _, prop := range sortedProperties(s) {
+		tag := prop
+		if !isRequired(s, prop) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%q`\n", pascal(prop), goType(nested, name+pascal(prop), s.Properties[prop]), tag)
+	}
+	fmt.Fprint(b, "}\n")
+	b.Write(nested.Bytes())
+}
+
+func goType(nested *bytes.Buffer, name string, s *Schema) string {
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "*time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if s.Items == nil {
+			return "[]interface{}"
+		}
+		return "[]" + strings.TrimPrefix(goType(nested, name, s.Items), "*")
+	case "object":
+		if len(s.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+		goStruct(nested, name, s)
+		return "*" + name
+	}
+
+	return "interface{}"
+}
+
+const goHeader = `// Code generated by sdkgen from app/web; DO NOT EDIT.
+
+// Package ivana is a client for the ivana booking API.
+package ivana
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+type Error struct {
+	Id     string ` + "`json:\"id\"`" + `
+	Status int    ` + "`json:\"status\"`" + `
+	Title  string ` + "`json:\"title\"`" + `
+	Detail string ` + "`json:\"detail\"`" + `
+}
+
+// APIError is returned for responses with a 4xx or 5xx status.
+type APIError struct {
+	Status int
+	Errors []Error ` + "`json:\"errors\"`" + `
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("ivana: %d %s: %s", e.Status, e.Errors[0].Id, e.Errors[0].Detail)
+	}
+	return fmt.Sprintf("ivana: %d", e.Status)
+}
+
+func (c *Client) do(method string, path string, query url.Values, body interface{}) (json.RawMessage, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil && res.StatusCode < 400 {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		apiErr := &APIError{Status: res.StatusCode}
+		json.Unmarshal(raw, apiErr)
+		return nil, apiErr
+	}
+
+	return raw, nil
+}
+`
+
+// TypeScript
+
+func generateTS(routes []*Route, schemas map[string]*Schema) []byte {
+	b := &bytes.Buffer{}
+	fmt.Fprint(b, tsHeader)
+
+	for _, name := range sortedSchemas(schemas) {
+		tsInterface(b, pascal(name), schemas[name])
+	}
+
+	fmt.Fprint(b, "\nexport class IvanaClient extends BaseClient {\n")
+	for _, r := range routes {
+		args := []string{}
+		for _, p := range pathParams(r.Path) {
+			args = append(args, camel(p)+": string")
+		}
+		body := "undefined"
+		switch {
+		case r.Body && r.Schema != "":
+			args = append(args, "body: "+pascal(r.Schema))
+			body = "body"
+		case r.Body || r.Method != "GET" && r.Method != "DELETE":
+			args = append(args, "body?: unknown")
+			body = "body"
+		}
+		args = append(args, "query?: Query")
+
+		path := "`" + r.Path + "`"
+		for _, p := range pathParams(r.Path) {
+			path = strings.Replace(path, ":"+p, "${encodeURIComponent("+camel(p)+")}", 1)
+		}
+
+		fmt.Fprintf(b, "  /** %s %s */\n", r.Method, r.Path)
+		fmt.Fprintf(b, "  %s(%s): Promise<unknown> {\n", camel(r.Handler), strings.Join(args, ", "))
+		fmt.Fprintf(b, "    return this.request(%q, %s, query, %s);\n  }\n\n", r.Method, path, body)
+	}
+	b.Truncate(b.Len() - 1)
+	fmt.Fprint(b, "}\n")
+
+	return b.Bytes()
+}
+
+func tsInterface(b *bytes.Buffer, name string, s *Schema) {
+	nested := &bytes.Buffer{}
+	fmt.Fprintf(b, "\nexport interface %s {\n", name)
+	for _, prop := range sortedProperties(s) {
+		optional := "?"
+		if isRequired(s, prop) {
+			optional = ""
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", prop, optional, tsType(nested, name+pascal(prop), s.Properties[prop]))
+	}
+	fmt.Fprint(b, "}\n")
+	b.Write(nested.Bytes())
+}
+
+func tsType(nested *bytes.Buffer, name string, s *Schema) string {
+	if len(s.Enum) > 0 {
+		values := []string{}
+		for _, e := range s.Enum {
+			v, _ := json.Marshal(e)
+			values = append(values, string(v))
+		}
+		return strings.Join(values, " | ")
+	}
+
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		if s.Items == nil {
+			return "unknown[]"
+		}
+		item := tsType(nested, name, s.Items)
+		if strings.Contains(item, "|") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if len(s.Properties) == 0 {
+			return "Record<string, unknown>"
+		}
+		tsInterface(nested, name, s)
+		return name
+	}
+
+	return "unknown"
+}
+
+const tsHeader = `// Code generated by sdkgen from app/web; DO NOT EDIT.
+
+export type Query = Record<string, string | string[]>;
+
+export interface ApiErrorDetail {
+  id: string;
+  status: number;
+  title: string;
+  detail: string;
+}
+
+export class ApiError extends Error {
+  constructor(public status: number, public errors: ApiErrorDetail[]) {
+    super(errors.length > 0 ? ` + "`${status} ${errors[0].id}: ${errors[0].detail}`" + ` : String(status));
+  }
+}
+
+export class BaseClient {
+  constructor(public baseURL: string, public headers: Record<string, string> = {}) {
+    this.baseURL = baseURL.replace(/\/$/, "");
+  }
+
+  protected async request(method: string, path: string, query?: Query, body?: unknown): Promise<unknown> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query ?? {})) {
+      for (const v of Array.isArray(value) ? value : [value]) {
+        params.append(key, v);
+      }
+    }
+    const qs = params.toString();
+
+    const headers: Record<string, string> = { ...this.headers };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+
+    const res = await fetch(this.baseURL + path + (qs ? "?" + qs : ""), {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const data = await res.json().catch(() => undefined);
+    if (!res.ok) {
+      throw new ApiError(res.status, (data && data.errors) || []);
+    }
+
+    return data;
+  }
+}
+`
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Utilization analytics
+//
+// GET /analytics/rooms/:id/utilization and GET
+// /analytics/venues/:id/utilization report on the bookings of a room, or of
+// every room of a venue, starting within ?start_time= and ?end_time= (this
+// week by default): the hours booked against the hours the venue is open,
+// the weekdays and hours bookings start at the most, in the venue's time
+// zone, the share of no-shows and who books the most. A venue's report also
+// breaks the hours down per room.
+//
+// The bookings are summed up by an aggregation pipeline over the events,
+// with the occurrences of recurring events added on top. A no-show is a
+// booking released because nobody checked in, see release.go, or one with a
+// check-in code that ended without a check-in; released bookings count no
+// booked hours. Occurrences aren't checked into, so they're never no-shows.
+// Venues without opening hours are open around the clock.
+//
+// With ?format=csv or xlsx, a report comes as a table of its rooms and their
+// total instead, see export.go.
+const analyticsTop = 5
+
+type Utilization struct {
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        time.Time         `json:"end_time"`
+	TimeZone       string            `json:"time_zone"`
+	BookedHours    float64           `json:"booked_hours"`
+	AvailableHours float64           `json:"available_hours"`
+	Utilization    float64           `json:"utilization"`
+	Bookings       int               `json:"bookings"`
+	NoShows        int               `json:"no_shows"`
+	NoShowRate     float64           `json:"no_show_rate"`
+	PeakTimes      []PeakTime        `json:"peak_times"`
+	TopBookers     []TopBooker       `json:"top_bookers"`
+	Rooms          []RoomUtilization `json:"rooms,omitempty"`
+}
+
+// PeakTime is an hour of a weekday bookings start at.
+type PeakTime struct {
+	Weekday  string  `json:"weekday"`
+	Hour     int     `json:"hour"`
+	Bookings int     `json:"bookings"`
+	Hours    float64 `json:"hours"`
+}
+
+type TopBooker struct {
+	Owner    string  `json:"owner"`
+	Bookings int     `json:"bookings"`
+	Hours    float64 `json:"hours"`
+	NoShows  int     `json:"no_shows"`
+}
+
+type RoomUtilization struct {
+	RoomId         string  `json:"room_id"`
+	Name           string  `json:"name"`
+	BookedHours    float64 `json:"booked_hours"`
+	AvailableHours float64 `json:"available_hours"`
+	Utilization    float64 `json:"utilization"`
+}
+
+// usageRow sums up the bookings of an owner in a room starting at an hour of
+// a weekday, 1 for Sunday.
+type usageRow struct {
+	Id struct {
+		Room    string
+		Owner   string
+		Weekday int
+		Hour    int
+	}
+	Bookings int
+	Hours    float64
+	NoShows  int
+}
+
+// bookingUsage returns the row of a booking starting in loc. Released
+// bookings count as no-shows without hours, as do the ones nobody checked
+// into before they ended by now.
+func bookingUsage(event Event, loc *time.Location, now time.Time) usageRow {
+	start := event.StartTime.In(loc)
+	row := usageRow{Bookings: 1, Hours: event.EndTime.Sub(event.StartTime).Hours()}
+	row.Id.Room, row.Id.Owner = event.LocationID, event.Owner
+	row.Id.Weekday, row.Id.Hour = int(start.Weekday())+1, start.Hour()
+	if event.ReleasedAt != nil {
+		row.Hours, row.NoShows = 0, 1
+	}
+	if event.CheckInCode != "" && event.CheckedInAt.IsZero() && !event.EndTime.After(now) {
+		row.NoShows = 1
+	}
+
+	return row
+}
+
+// openHours returns how many hours of [start_time, end_time) hours leaves
+// the venue open in loc, all of them without opening hours.
+func openHours(hours *OpeningHours, start_time time.Time, end_time time.Time, loc *time.Location) float64 {
+	if hours == nil {
+		return end_time.Sub(start_time).Hours()
+	}
+
+	total := time.Duration(0)
+	start_time, end_time = start_time.In(loc), end_time.In(loc)
+	y, m, d := start_time.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, loc); day.Before(end_time); day = day.AddDate(0, 0, 1) {
+		holiday := false
+		for _, date := range hours.Holidays {
+			holiday = holiday || date == day.Format("2006-01-02")
+		}
+		if holiday {
+			continue
+		}
+
+		intervals := [][2]time.Time{{day, day.AddDate(0, 0, 1)}}
+		if len(hours.Days) > 0 {
+			intervals = nil
+			for _, openingDay := range hours.Days {
+				open, err1 := time.ParseInLocation("15:04", openingDay.Open, loc)
+				close, err2 := time.ParseInLocation("15:04", openingDay.Close, loc)
+				if openingDay.Weekday != day.Weekday() || err1 != nil || err2 != nil {
+					continue
+				}
+				intervals = append(intervals, [2]time.Time{
+					time.Date(day.Year(), day.Month(), day.Day(), open.Hour(), open.Minute(), 0, 0, loc),
+					time.Date(day.Year(), day.Month(), day.Day(), close.Hour(), close.Minute(), 0, 0, loc),
+				})
+			}
+		}
+		for _, interval := range intervals {
+			from, to := interval[0], interval[1]
+			if from.Before(start_time) {
+				from = start_time
+			}
+			if to.After(end_time) {
+				to = end_time
+			}
+			if from.Before(to) {
+				total += to.Sub(from)
+			}
+		}
+	}
+
+	return total.Hours()
+}
+
+// utilization reports on the bookings of rooms, all of venue, starting
+// within [start_time, end_time).
+func (c *appContext) utilization(r *http.Request, venue Venue, rooms []Room, start_time time.Time, end_time time.Time) (Utilization, error) {
+	loc, ok := loadZone(venue.TimeZone)
+	if !ok {
+		loc = defaultLocation
+	}
+	result := Utilization{
+		StartTime:  start_time.In(loc),
+		EndTime:    end_time.In(loc),
+		TimeZone:   loc.String(),
+		PeakTimes:  []PeakTime{},
+		TopBookers: []TopBooker{},
+	}
+
+	roomIds := []string{}
+	for _, room := range rooms {
+		roomIds = append(roomIds, room.Id.Hex())
+	}
+	repo := c.events()
+	bookings, err := repo.AllUsage(r.Context(), roomIds, start_time, end_time)
+	if err != nil {
+		return result, err
+	}
+	rows := []usageRow{}
+	now := clockNow()
+	for _, booking := range bookings {
+		rows = append(rows, bookingUsage(booking, loc, now))
+	}
+	for _, id := range roomIds {
+		occurrences, err := repo.AllOccurrences(r.Context(), id, start_time, end_time, "")
+		if err != nil {
+			return result, err
+		}
+		for _, occurrence := range occurrences {
+			if occurrence.StartTime.Before(start_time) {
+				continue
+			}
+			start := occurrence.StartTime.In(loc)
+			row := usageRow{Bookings: 1, Hours: occurrence.EndTime.Sub(occurrence.StartTime).Hours()}
+			row.Id.Room, row.Id.Owner = id, occurrence.Owner
+			row.Id.Weekday, row.Id.Hour = int(start.Weekday())+1, start.Hour()
+			rows = append(rows, row)
+		}
+	}
+
+	booked := map[string]float64{}
+	peaks := map[[2]int]*PeakTime{}
+	bookers := map[string]*TopBooker{}
+	for _, row := range rows {
+		result.Bookings += row.Bookings
+		result.BookedHours += row.Hours
+		result.NoShows += row.NoShows
+		booked[row.Id.Room] += row.Hours
+
+		slot := [2]int{row.Id.Weekday, row.Id.Hour}
+		if peaks[slot] == nil {
+			peaks[slot] = &PeakTime{Weekday: weekdayName(time.Weekday(row.Id.Weekday - 1)), Hour: row.Id.Hour}
+		}
+		peaks[slot].Bookings += row.Bookings
+		peaks[slot].Hours += row.Hours
+
+		if row.Id.Owner == "" {
+			continue
+		}
+		if bookers[row.Id.Owner] == nil {
+			bookers[row.Id.Owner] = &TopBooker{Owner: row.Id.Owner}
+		}
+		bookers[row.Id.Owner].Bookings += row.Bookings
+		bookers[row.Id.Owner].Hours += row.Hours
+		bookers[row.Id.Owner].NoShows += row.NoShows
+	}
+	if result.Bookings > 0 {
+		result.NoShowRate = float64(result.NoShows) / float64(result.Bookings)
+	}
+
+	for weekday := 1; weekday <= 7; weekday++ {
+		for hour := 0; hour < 24; hour++ {
+			if peak, ok := peaks[[2]int{weekday, hour}]; ok {
+				result.PeakTimes = append(result.PeakTimes, *peak)
+			}
+		}
+	}
+	sort.SliceStable(result.PeakTimes, func(i, j int) bool {
+		a, b := result.PeakTimes[i], result.PeakTimes[j]
+		return a.Bookings > b.Bookings || a.Bookings == b.Bookings && a.Hours > b.Hours
+	})
+	if len(result.PeakTimes) > analyticsTop {
+		result.PeakTimes = result.PeakTimes[:analyticsTop]
+	}
+
+	for _, booker := range bookers {
+		result.TopBookers = append(result.TopBookers, *booker)
+	}
+	sort.Slice(result.TopBookers, func(i, j int) bool {
+		a, b := result.TopBookers[i], result.TopBookers[j]
+		if a.Hours != b.Hours {
+			return a.Hours > b.Hours
+		}
+		return a.Bookings > b.Bookings || a.Bookings == b.Bookings && a.Owner < b.Owner
+	})
+	if len(result.TopBookers) > analyticsTop {
+		result.TopBookers = result.TopBookers[:analyticsTop]
+	}
+
+	available := openHours(venue.OpeningHours, start_time, end_time, loc)
+	for _, room := range rooms {
+		id := room.Id.Hex()
+		roomResult := RoomUtilization{RoomId: id, Name: room.Name, BookedHours: booked[id], AvailableHours: available}
+		if available > 0 {
+			roomResult.Utilization = booked[id] / available
+		}
+		result.Rooms = append(result.Rooms, roomResult)
+		result.AvailableHours += available
+	}
+	if result.AvailableHours > 0 {
+		result.Utilization = result.BookedHours / result.AvailableHours
+	}
+
+	return result, nil
+}
+
+// Analytics Handlers
+func (c *appContext) roomUtilizationHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	format, ok := exportFormat(r)
+	if !ok {
+		WriteError(w, ErrInvalidFormat)
+		return
+	}
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	room, err := c.rooms().Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	venue, err := c.venues().Find(r.Context(), room.VenueId)
+	if err != nil && err != storage.ErrNotFound && err != storage.ErrInvalidId {
+		WriteRepoError(w, err)
+		return
+	}
+
+	result, err := c.utilization(r, venue, []Room{room}, start_time, end_time)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if r.URL.Query().Get("format") != "" {
+		exportUtilization(w, format, result)
+		return
+	}
+	result.Rooms = nil
+
+	WriteSuccess(w, http.StatusOK, result)
+}
+
+func (c *appContext) venueUtilizationHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	format, ok := exportFormat(r)
+	if !ok {
+		WriteError(w, ErrInvalidFormat)
+		return
+	}
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	venue, _, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	rooms, err := c.rooms().AllByVenueId(r.Context(), venue.Id.Hex())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	result, err := c.utilization(r, venue, rooms, start_time, end_time)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if r.URL.Query().Get("format") != "" {
+		exportUtilization(w, format, result)
+		return
+	}
+	WriteSuccess(w, http.StatusOK, result)
+}
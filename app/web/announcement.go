@@ -0,0 +1,258 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Announcements
+//
+// Admins post venue-wide notices such as office closures and fire drill
+// schedules. A notice is displayed between DisplayFrom and DisplayUntil
+// (open-ended when zero); StartTime and EndTime optionally say when the
+// closure or drill itself happens. GET /venues/:id/announcements returns the
+// notices displayed now, or all of them with ?all=true, and room panels show
+// the displayed ones ahead of their regular content.
+const (
+	AnnouncementClosure   = "closure"
+	AnnouncementFireDrill = "fire_drill"
+	AnnouncementNotice    = "notice"
+
+	// announcementPriority puts announcements before any panel content.
+	announcementPriority = 1 << 20
+)
+
+type Announcement struct {
+	Id           storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	VenueId      string           `json:"venue_id"`
+	Kind         string           `json:"kind"`
+	Title        string           `json:"title"`
+	Body         string           `json:"body"`
+	BodyHTML     string           `json:"body_html"`
+	StartTime    time.Time        `json:"start_time"`
+	EndTime      time.Time        `json:"end_time"`
+	DisplayFrom  time.Time        `json:"display_from"`
+	DisplayUntil time.Time        `json:"display_until"`
+	CreatedBy    string           `json:"created_by"`
+}
+
+// prepare normalizes an announcement before it's stored.
+func (a *Announcement) prepare() {
+	a.Body = sanitizeDescription(a.Body)
+	a.BodyHTML = renderDescription(a.Body)
+	if a.Kind == "" {
+		a.Kind = AnnouncementNotice
+	}
+}
+
+func (a Announcement) validate() *Error {
+	if !a.DisplayUntil.IsZero() && !a.DisplayFrom.Before(a.DisplayUntil) {
+		return ErrInvalidTimeRange
+	}
+	if !a.EndTime.IsZero() && !a.StartTime.Before(a.EndTime) {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+// PanelContent returns the announcement as a text item for room panels.
+func (a Announcement) PanelContent() PanelContent {
+	return PanelContent{
+		Id:        a.Id,
+		VenueId:   a.VenueId,
+		RoomIds:   []string{},
+		Kind:      PanelText,
+		Title:     a.Title,
+		Body:      a.Body,
+		BodyHTML:  a.BodyHTML,
+		Duration:  defaultPanelDuration,
+		Priority:  announcementPriority,
+		StartsAt:  a.DisplayFrom,
+		EndsAt:    a.DisplayUntil,
+		CreatedBy: a.CreatedBy,
+	}
+}
+
+// Repo Announcement
+type AnnouncementRepo struct {
+	coll *storage.Collection
+}
+
+func (r *AnnouncementRepo) AllByVenueId(venueId string) ([]Announcement, error) {
+	result := []Announcement{}
+	err := r.coll.Find(bson.M{"venueid": venueId}).Sort("displayfrom").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Displayed returns the announcements of venueId displayed at t.
+func (r *AnnouncementRepo) Displayed(venueId string, t time.Time) ([]Announcement, error) {
+	result := []Announcement{}
+	query := bson.M{
+		"venueid":     venueId,
+		"displayfrom": bson.M{"$lte": t},
+		"$or":         []bson.M{{"displayuntil": time.Time{}}, {"displayuntil": bson.M{"$gt": t}}},
+	}
+
+	err := r.coll.Find(query).Sort("displayfrom").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *AnnouncementRepo) Find(id string) (Announcement, error) {
+	result := Announcement{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *AnnouncementRepo) Create(announcement *Announcement) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, announcement)
+	if err != nil {
+		return err
+	}
+
+	announcement.Id = id
+
+	return nil
+}
+
+func (r *AnnouncementRepo) Update(announcement *Announcement) error {
+	err := r.coll.UpdateId(announcement.Id, announcement)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AnnouncementRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Announcement Handlers
+func (c *appContext) venueAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/announcements")
+		return
+	}
+
+	repo := AnnouncementRepo{c.db.C("announcements")}
+	var announcements []Announcement
+	if r.URL.Query().Get("all") == "true" {
+		announcements, err = repo.AllByVenueId(venue.Id.Hex())
+	} else {
+		announcements, err = repo.Displayed(venue.Id.Hex(), clockNow())
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, announcements)
+}
+
+func (c *appContext) createAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Announcement)
+	venueRepo := c.venues()
+	venue, err := venueRepo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	body.VenueId = venue.Id.Hex()
+	if e := body.validate(); e != nil {
+		WriteError(w, e)
+		return
+	}
+	if body.CreatedBy == "" {
+		body.CreatedBy = r.Context().Value(userKey).(User).Email
+	}
+	body.prepare()
+
+	repo := AnnouncementRepo{c.db.C("announcements")}
+	err = repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("announcement", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Announcement)
+	repo := AnnouncementRepo{c.db.C("announcements")}
+	announcement, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	body.Id = announcement.Id
+	body.VenueId = announcement.VenueId
+	if e := body.validate(); e != nil {
+		WriteError(w, e)
+		return
+	}
+	if body.CreatedBy == "" {
+		body.CreatedBy = announcement.CreatedBy
+	}
+	body.prepare()
+
+	err = repo.Update(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("announcement", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := AnnouncementRepo{c.db.C("announcements")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("announcement", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Announcement has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// API keys
+//
+// Machine clients, like room display panels and calendar sync jobs, send an
+// X-API-Key header instead of acting as a person. Admins issue keys with
+// POST /admin/api-keys, list them with GET /admin/api-keys and revoke them
+// with DELETE /admin/api-keys/:id. A key is only returned once, and only its
+// hash is stored. It acts as the user it was issued for, the admin issuing
+// it by default, within its scope: read keys can only make GET requests,
+// read_write keys can do whatever that user can, and panel keys, issued for
+// the room_id of a room display, read and take the actions of that room's
+// panel, see roompanel.go. Keys stop working when their user leaves the
+// organization they were issued in.
+const (
+	APIKeyRead      = "read"
+	APIKeyReadWrite = "read_write"
+	APIKeyPanel     = "panel"
+
+	apiKeyBytes = 32
+)
+
+type APIKey struct {
+	Id        storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string           `json:"name"`
+	Key       string           `json:"key,omitempty" bson:"-"`
+	Hash      string           `json:"-"`
+	Scope     string           `json:"scope"`
+	RoomId    string           `json:"room_id,omitempty" bson:",omitempty"`
+	User      string           `json:"user"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+	RevokedAt time.Time        `json:"revoked_at,omitempty"`
+
+	// OrgId is the organization the key belongs to, see organization.go.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newAPIKey() string {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Allows reports whether k may make r.
+func (k APIKey) Allows(r *http.Request) bool {
+	if k.Scope == APIKeyReadWrite {
+		return true
+	}
+	if k.Scope == APIKeyPanel && strings.HasPrefix(r.URL.Path, "/rooms/"+k.RoomId+"/panel/") {
+		return true
+	}
+
+	return r.Method == "GET" || r.Method == "HEAD"
+}
+
+// Repo APIKey
+type APIKeyRepo struct {
+	coll *storage.Collection
+}
+
+func (r *APIKeyRepo) All() ([]APIKey, error) {
+	result := []APIKey{}
+	err := r.coll.Find(nil).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *APIKeyRepo) FindByKey(key string) (APIKey, error) {
+	result := APIKey{}
+	err := r.coll.Find(bson.M{"hash": hashAPIKey(key)}).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *APIKeyRepo) Create(key *APIKey) error {
+	id := storage.NewObjectId()
+	key.Hash = hashAPIKey(key.Key)
+	_, err := r.coll.UpsertId(id, key)
+	if err != nil {
+		return err
+	}
+
+	key.Id = id
+
+	return nil
+}
+
+func (r *APIKeyRepo) Revoke(id string, t time.Time) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.Update(bson.M{"_id": oid, "revokedat": time.Time{}}, bson.M{"$set": bson.M{"revokedat": t}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Middleware
+// apiKeyHandler admits requests carrying an X-API-Key as the user of the key,
+// when its scope allows the request. Requests without a key are passed on
+// unchanged.
+func apiKeyHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get("X-API-Key")
+			if value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rc := c.forRequest(r)
+			repo := APIKeyRepo{rc.db.C("api_keys")}
+			key, err := repo.FindByKey(value)
+			if err == storage.ErrNotFound {
+				WriteError(w, ErrInvalidAPIKey)
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+			if !key.RevokedAt.IsZero() {
+				WriteError(w, ErrInvalidAPIKey)
+				return
+			}
+			if !key.Allows(r) {
+				WriteError(w, ErrAPIKeyReadOnly)
+				return
+			}
+
+			user := rc.directoryUser(key.User)
+			if user.OrgId != key.OrgId {
+				WriteError(w, ErrInvalidAPIKey)
+				return
+			}
+
+			r = withValue(r, apiKeyKey, key)
+			r = withValue(r, userKey, user)
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// API Key Handlers
+func (c *appContext) apiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	repo := APIKeyRepo{c.db.C("api_keys")}
+	keys, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, keys)
+}
+
+func (c *appContext) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*APIKey)
+	user := r.Context().Value(userKey).(User)
+
+	key := APIKey{
+		Name:      body.Name,
+		Key:       newAPIKey(),
+		Scope:     body.Scope,
+		User:      body.User,
+		CreatedBy: user.Email,
+		CreatedAt: time.Now(),
+	}
+	if key.Scope == "" {
+		key.Scope = APIKeyRead
+	}
+	if key.User == "" {
+		key.User = user.Email
+	}
+	if key.Scope == APIKeyPanel {
+		room, err := c.rooms().Find(r.Context(), body.RoomId)
+		if err == ErrDocumentNotFound || err == ErrInvalidId {
+			WriteError(w, ErrPanelKeyRoom)
+			return
+		}
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		key.RoomId = room.Id.Hex()
+	}
+
+	repo := APIKeyRepo{c.db.C("api_keys")}
+	err := repo.Create(&key)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, key)
+}
+
+func (c *appContext) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := APIKeyRepo{c.db.C("api_keys")}
+	err := repo.Revoke(params.ByName("id"), time.Now())
+	if err == storage.ErrNotFound || err == storage.ErrInvalidId {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "API key has been revoked successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Attachments
+//
+// Event attachments are stored in GridFS. A fresh upload is
+// "pending" until the configured Scanner has looked at it; only "clean" files
+// can be downloaded. Infected files are kept "quarantined" for review and
+// the uploader is notified.
+const (
+	AttachmentPending     = "pending"
+	AttachmentClean       = "clean"
+	AttachmentQuarantined = "quarantined"
+	AttachmentScanFailed  = "scan_failed"
+
+	maxAttachmentSize = 20 << 20
+)
+
+type Attachment struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerType   string           `json:"owner_type"`
+	OwnerId     string           `json:"owner_id"`
+	FileName    string           `json:"file_name"`
+	ContentType string           `json:"content_type"`
+	Size        int64            `json:"size"`
+	FileId      storage.ObjectId `json:"-"`
+	Uploader    string           `json:"uploader"`
+	Status      string           `json:"status"`
+	Signature   string           `json:"signature,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ScannedAt   time.Time        `json:"scanned_at,omitempty"`
+}
+
+// Repo Attachment
+type AttachmentRepo struct {
+	coll *storage.Collection
+}
+
+func (r *AttachmentRepo) AllByOwner(ownerType string, ownerId string) ([]Attachment, error) {
+	result := []Attachment{}
+	err := r.coll.Find(bson.M{"ownertype": ownerType, "ownerid": ownerId}).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *AttachmentRepo) Find(id string) (Attachment, error) {
+	result := Attachment{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *AttachmentRepo) Create(attachment *Attachment) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, attachment)
+	if err != nil {
+		return err
+	}
+
+	attachment.Id = id
+
+	return nil
+}
+
+func (r *AttachmentRepo) SetStatus(id storage.ObjectId, status string, signature string) error {
+	err := r.coll.UpdateId(id, bson.M{"$set": bson.M{
+		"status":    status,
+		"signature": signature,
+		"scannedat": time.Now(),
+	}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// scanAttachment runs the stored file through scanner and records the verdict.
+func (c *appContext) scanAttachment(scanner Scanner, attachment Attachment) {
+	repo := AttachmentRepo{c.db.C("attachments")}
+
+	file, err := c.db.GridFS("attachments").OpenId(attachment.FileId)
+	if err != nil {
+		log.Printf("attachments: unable to open %s for scanning: %v", attachment.Id.Hex(), err)
+		attachment.Status = AttachmentScanFailed
+	} else {
+		result, err := scanner.Scan(attachment.FileName, file)
+		file.Close()
+
+		switch {
+		case err != nil:
+			log.Printf("attachments: scanning %s failed: %v", attachment.Id.Hex(), err)
+			attachment.Status = AttachmentScanFailed
+		case result.Clean:
+			attachment.Status = AttachmentClean
+		default:
+			attachment.Status = AttachmentQuarantined
+			attachment.Signature = result.Signature
+		}
+	}
+
+	if err := repo.SetStatus(attachment.Id, attachment.Status, attachment.Signature); err != nil {
+		log.Printf("attachments: unable to update %s: %v", attachment.Id.Hex(), err)
+		return
+	}
+
+	if attachment.Status != AttachmentClean {
+		notifyUploader(attachment)
+	}
+}
+
+func notifyUploader(attachment Attachment) {
+	log.Printf("attachments: notifying %s that %q (%s) was %s %s",
+		attachment.Uploader, attachment.FileName, attachment.Id.Hex(), attachment.Status, attachment.Signature)
+}
+
+// uploadAttachment stores the multipart "file" field for the given owner and
+// queues it for scanning.
+func (c *appContext) uploadAttachment(w http.ResponseWriter, r *http.Request, ownerType string, ownerId string) {
+	scanner, err := newScanner()
+	if err != nil {
+		log.Printf("attachments: %v", err)
+		WriteError(w, ErrUploadsDisabled)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		WriteError(w, ErrInvalidUpload)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		WriteError(w, ErrInvalidUpload)
+		return
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = "application/octet-stream"
+	}
+
+	gf, err := c.db.GridFS("attachments").Create(header.Filename)
+	if err != nil {
+		panic(err)
+	}
+	gf.SetContentType(contentType)
+	size, err := io.Copy(gf, io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		gf.Abort()
+		gf.Close()
+		panic(err)
+	}
+	if size > maxAttachmentSize {
+		gf.Abort()
+		gf.Close()
+		WriteError(w, ErrUploadTooLarge)
+		return
+	}
+	if err := gf.Close(); err != nil {
+		panic(err)
+	}
+
+	attachment := Attachment{
+		OwnerType:   ownerType,
+		OwnerId:     ownerId,
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Size:        size,
+		FileId:      gf.Id(),
+		Uploader:    r.FormValue("uploader"),
+		Status:      AttachmentPending,
+		CreatedAt:   time.Now(),
+	}
+
+	repo := AttachmentRepo{c.db.C("attachments")}
+	if err := repo.Create(&attachment); err != nil {
+		panic(err)
+	}
+
+	bg := c.detach()
+	go func() {
+		defer bg.close()
+		bg.scanAttachment(scanner, attachment)
+	}()
+
+	WriteSuccess(w, http.StatusAccepted, attachment)
+}
+
+// Attachment Handlers
+func (c *appContext) uploadEventAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	c.uploadAttachment(w, r, "event", event.Id.Hex())
+}
+
+func (c *appContext) eventAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := AttachmentRepo{c.db.C("attachments")}
+	attachments, err := repo.AllByOwner("event", params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, attachments)
+}
+
+func (c *appContext) attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := AttachmentRepo{c.db.C("attachments")}
+	attachment, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, attachment)
+}
+
+func (c *appContext) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := AttachmentRepo{c.db.C("attachments")}
+	attachment, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	switch attachment.Status {
+	case AttachmentClean:
+	case AttachmentPending:
+		WriteError(w, ErrAttachmentPending)
+		return
+	default:
+		WriteError(w, ErrFileQuarantined)
+		return
+	}
+
+	file, err := c.db.GridFS("attachments").OpenId(attachment.FileId)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", file.UploadDate(), file)
+}
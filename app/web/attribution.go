@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Booking sources
+//
+// Every event records where it was booked in BookedVia: the channel (web,
+// mobile, slack, outlook, api, inbound, import or panel) and, for
+// integrations, which one. Clients name their channel in the X-Booking-Source
+// header; requests without it are from the web app, or from the api channel
+// when they carry an X-Client-Key, which then names the integration. Inbound
+// bookings are attributed to the inbound source that pushed them, imported
+// ones to the file format they came in and quick bookings to the room panel
+// they were made on. GET /events filters by ?source=channel or
+// ?source=channel:integration, and GET /reports/adoption breaks the bookings
+// of a window down by source.
+const (
+	ChannelWeb     = "web"
+	ChannelMobile  = "mobile"
+	ChannelSlack   = "slack"
+	ChannelOutlook = "outlook"
+	ChannelAPI     = "api"
+	ChannelInbound = "inbound"
+	ChannelImport  = "import"
+	ChannelPanel   = "panel"
+
+	// ChannelUnknown is reported for events booked before sources were
+	// recorded.
+	ChannelUnknown = "unknown"
+)
+
+var bookingChannels = map[string]bool{
+	ChannelWeb:     true,
+	ChannelMobile:  true,
+	ChannelSlack:   true,
+	ChannelOutlook: true,
+	ChannelAPI:     true,
+}
+
+// sourceMatches reports whether s is named by filter, a channel optionally
+// followed by ":" and an integration.
+func sourceMatches(s BookingSource, filter string) bool {
+	channel, integration := filter, ""
+	if i := strings.Index(filter, ":"); i >= 0 {
+		channel, integration = filter[:i], filter[i+1:]
+	}
+
+	return s.Channel == channel && (integration == "" || s.Integration == integration)
+}
+
+// bookingSource returns the source of a booking made through r.
+func bookingSource(r *http.Request) (BookingSource, *Error) {
+	channel := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Booking-Source")))
+	key := r.Header.Get("X-Client-Key")
+	if channel == "" {
+		channel = ChannelWeb
+		if key != "" {
+			channel = ChannelAPI
+		}
+	}
+	if !bookingChannels[channel] {
+		return BookingSource{}, ErrUnknownSource
+	}
+
+	return BookingSource{Channel: channel, Integration: key}, nil
+}
+
+// sourceOf returns the recorded source of e, making one up for events booked
+// before sources were recorded.
+func sourceOf(e Event) BookingSource {
+	if e.BookedVia.Channel != "" {
+		return e.BookedVia
+	}
+	if strings.HasPrefix(e.Source, "inbound:") {
+		return BookingSource{Channel: ChannelInbound, Integration: strings.TrimPrefix(e.Source, "inbound:")}
+	}
+
+	return BookingSource{Channel: ChannelUnknown}
+}
+
+func filterBySource(events []Event, filter string) []Event {
+	if filter == "" {
+		return events
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if sourceMatches(sourceOf(event), filter) {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+type AdoptionRow struct {
+	Channel     string  `json:"channel"`
+	Integration string  `json:"integration,omitempty"`
+	Bookings    int     `json:"bookings"`
+	Hours       float64 `json:"hours"`
+	Owners      int     `json:"owners"`
+	Share       float64 `json:"share"`
+}
+
+type AdoptionReport struct {
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Total     int           `json:"total"`
+	Sources   []AdoptionRow `json:"sources"`
+}
+
+// adoptionReportHandler counts the bookings starting within ?start_time= and
+// ?end_time= (this week by default) per source. A recurring event counts as
+// one booking.
+func (c *appContext) adoptionReportHandler(w http.ResponseWriter, r *http.Request) {
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	repo := c.events()
+	events, err := repo.All(r.Context(), start_time, end_time, false)
+	if err != nil {
+		panic(err)
+	}
+
+	rows := map[BookingSource]*AdoptionRow{}
+	owners := map[BookingSource]map[string]bool{}
+	for _, event := range events {
+		source := sourceOf(event)
+		if rows[source] == nil {
+			rows[source] = &AdoptionRow{Channel: source.Channel, Integration: source.Integration}
+			owners[source] = map[string]bool{}
+		}
+		rows[source].Bookings++
+		rows[source].Hours += event.EndTime.Sub(event.StartTime).Hours()
+		owners[source][event.Owner] = true
+	}
+
+	report := AdoptionReport{start_time, end_time, len(events), []AdoptionRow{}}
+	for source, row := range rows {
+		row.Owners = len(owners[source])
+		row.Share = float64(row.Bookings) / float64(len(events))
+		report.Sources = append(report.Sources, *row)
+	}
+	sort.Slice(report.Sources, func(i, j int) bool {
+		a, b := report.Sources[i], report.Sources[j]
+		if a.Bookings != b.Bookings {
+			return a.Bookings > b.Bookings
+		}
+		return a.Channel+":"+a.Integration < b.Channel+":"+b.Integration
+	})
+
+	WriteSuccess(w, http.StatusOK, report)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Audit export
+//
+// Each change log entry keeps a snapshot of the document as it was written,
+// so a day of mutations can be replayed elsewhere. GET /admin/audit/export
+// ?date=YYYY-MM-DD streams that day's entries in sequence order as NDJSON,
+// one AuditEntry per line: created and updated entries carry the document
+// to upsert into collection, deleted entries only its id. Since the snapshots
+// hold every field of every document, only admins may export.
+var entityCollections = map[string]string{
+	"venue":               "venues",
+	"room":                "rooms",
+	"event":               "events",
+	"event_series":        "event_series",
+	"equipment":           "equipment",
+	"floor":               "floors",
+	"desk":                "desks",
+	"desk_booking":        "desk_bookings",
+	"parking_spot":        "parking_spots",
+	"parking_reservation": "parking_reservations",
+	"panel_content":       "panel_content",
+	"announcement":        "announcements",
+	"legal_hold":          "legal_holds",
+	"event_group":         "event_groups",
+	"booking_link":        "booking_links",
+}
+
+type AuditEntry struct {
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Entity     string    `json:"entity"`
+	EntityId   string    `json:"entity_id"`
+	Action     string    `json:"action"`
+	Collection string    `json:"collection"`
+	Document   bson.M    `json:"document,omitempty"`
+}
+
+// snapshot returns the stored document of a created or updated entity.
+func (c *appContext) snapshot(entity string, id storage.ObjectId, action string) bson.M {
+	if _, ok := entityCollections[entity]; !ok || action == ChangeDeleted {
+		return nil
+	}
+
+	doc, err := c.stored(entity, id)
+	if err != nil {
+		log.Printf("changes: unable to snapshot %s %s: %v", entity, id.Hex(), err)
+		return nil
+	}
+
+	return doc
+}
+
+// stored returns the document of entity with id, deleted or not. Venues,
+// rooms and events are read from their repos, which may not be MongoDB's.
+func (c *appContext) stored(entity string, id storage.ObjectId) (bson.M, error) {
+	ctx := context.Background()
+	var found interface{}
+	var err error
+	switch entity {
+	case "venue":
+		found, err = c.venues().Find(ctx, id.Hex())
+	case "room":
+		found, err = c.rooms().Find(ctx, id.Hex())
+	case "event":
+		found, err = c.events().Find(ctx, id.Hex())
+		if err == ErrDocumentNotFound {
+			found, err = c.events().FindDeleted(ctx, id.Hex())
+		}
+	default:
+		doc := bson.M{}
+		err = c.db.C(entityCollections[entity]).FindId(id).One(&doc)
+		return doc, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := bson.Marshal(found)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	err = bson.Unmarshal(raw, &doc)
+
+	return doc, err
+}
+
+// orgOf returns the organization of the document of entity with id.
+func (c *appContext) orgOf(entity string, id storage.ObjectId) (string, error) {
+	if _, ok := entityCollections[entity]; !ok {
+		return "", nil
+	}
+	doc, err := c.stored(entity, id)
+	if err != nil {
+		return "", err
+	}
+	org, _ := doc["orgid"].(string)
+
+	return org, nil
+}
+
+// Repo Change day
+func (r *ChangeRepo) Between(start_time time.Time, end_time time.Time) *storage.Iter {
+	query := bson.M{"time": bson.M{"$gte": start_time, "$lt": end_time}}
+	return r.coll.Find(query).Sort("seq").Iter()
+}
+
+// Audit Handlers
+func (c *appContext) auditExportHandler(w http.ResponseWriter, r *http.Request) {
+	date, ok := dayParam(r)
+	if !ok {
+		WriteError(w, ErrInvalidDate)
+		return
+	}
+
+	loc := defaultLocation
+	start_time, _ := time.ParseInLocation("2006-01-02", date, loc)
+
+	repo := ChangeRepo{c.db.C("changes"), c.db.C("counters")}
+	iter := repo.Between(start_time, start_time.AddDate(0, 0, 1))
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"audit-"+date+".ndjson\"")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	change := Change{}
+	for iter.Next(&change) {
+		err := enc.Encode(AuditEntry{
+			Seq:        change.Seq,
+			Time:       change.Time,
+			Entity:     change.Entity,
+			EntityId:   change.EntityId,
+			Action:     change.Action,
+			Collection: entityCollections[change.Entity],
+			Document:   change.Document,
+		})
+		if err != nil {
+			break
+		}
+		change = Change{}
+	}
+	if err := iter.Close(); err != nil {
+		// Headers are gone already; the truncated stream is all we can do.
+		log.Printf("audit: export of %s failed: %v", date, err)
+	}
+}
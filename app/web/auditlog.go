@@ -0,0 +1,228 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Audit log
+//
+// Every change recorded in the change log is also written to audit_logs with
+// who made it: the caller's email, the method, path and X-Request-ID of the
+// request, or "system" for background work, and the fields the change made
+// differ from the entity's previous snapshot. GET /audit-logs lists them,
+// newest first, for admins, filtered by any of actor, entity, entity_id,
+// action, request_id, and start_time and end_time (RFC 3339).
+const auditSystemActor = "system"
+
+// auditOrigin is the request a change was made by.
+type auditOrigin struct {
+	Actor     string
+	Method    string
+	Path      string
+	RequestId string
+}
+
+type AuditFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type AuditLog struct {
+	Id        storage.ObjectId   `json:"id" bson:"_id,omitempty"`
+	Time      time.Time          `json:"time"`
+	Actor     string             `json:"actor"`
+	Method    string             `json:"method,omitempty"`
+	Path      string             `json:"path,omitempty"`
+	RequestId string             `json:"request_id,omitempty"`
+	Entity    string             `json:"entity"`
+	EntityId  string             `json:"entity_id"`
+	Action    string             `json:"action"`
+	Seq       int64              `json:"seq"`
+	Diff      []AuditFieldChange `json:"diff"`
+}
+
+var auditLogSortFields = map[string]string{"time": "time"}
+
+func newAuditOrigin(w http.ResponseWriter, r *http.Request) *auditOrigin {
+	origin := &auditOrigin{
+		Actor:     r.Header.Get("X-User-Email"),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		RequestId: r.Header.Get("X-Request-ID"),
+	}
+	if rec, ok := w.(*routeRecorder); ok {
+		origin.RequestId = rec.requestId
+	}
+
+	return origin
+}
+
+// auditDiff returns the top level fields that differ between before and
+// after, by name.
+func auditDiff(before bson.M, after bson.M) []AuditFieldChange {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	delete(fields, "_id")
+
+	names := []string{}
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	result := []AuditFieldChange{}
+	for _, field := range names {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			result = append(result, AuditFieldChange{field, before[field], after[field]})
+		}
+	}
+
+	return result
+}
+
+// Repo Change previous
+func (r *ChangeRepo) Previous(entity string, entityId string, seq int64) (Change, error) {
+	result := Change{}
+	query := bson.M{"entity": entity, "entityid": entityId, "seq": bson.M{"$lt": seq}}
+	err := r.coll.Find(query).Sort("-seq").One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Repo AuditLog
+type AuditLogRepo struct {
+	coll *storage.Collection
+}
+
+func (r *AuditLogRepo) All(query bson.M, opts ListOptions) ([]AuditLog, int, error) {
+	result := []AuditLog{}
+	total, err := r.coll.Find(query).Count()
+	if err != nil {
+		return result, 0, err
+	}
+	err = opts.Apply(r.coll.Find(query)).All(&result)
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *AuditLogRepo) Create(entry *AuditLog) error {
+	entry.Id = storage.NewObjectId()
+	err := r.coll.Insert(entry)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// recordAudit writes the audit log entry of change, just recorded.
+func (c *appContext) recordAudit(change Change) {
+	entry := AuditLog{
+		Time:     change.Time,
+		Actor:    auditSystemActor,
+		Entity:   change.Entity,
+		EntityId: change.EntityId,
+		Action:   change.Action,
+		Seq:      change.Seq,
+	}
+	if c.origin != nil {
+		entry.Actor = c.origin.Actor
+		entry.Method = c.origin.Method
+		entry.Path = c.origin.Path
+		entry.RequestId = c.origin.RequestId
+	}
+
+	changes := ChangeRepo{c.db.C("changes"), c.db.C("counters")}
+	previous, err := changes.Previous(change.Entity, change.EntityId, change.Seq)
+	if err != nil && err != storage.ErrNotFound {
+		log.Printf("audit: unable to find the previous %s %s: %v", change.Entity, change.EntityId, err)
+	}
+	entry.Diff = auditDiff(previous.Document, change.Document)
+
+	repo := AuditLogRepo{c.db.C("audit_logs")}
+	if err := repo.Create(&entry); err != nil {
+		log.Printf("audit: unable to log %s %s %s: %v", change.Entity, change.Action, change.EntityId, err)
+	}
+}
+
+// auditLogQuery reads the filters of r.
+func auditLogQuery(r *http.Request) (bson.M, *Error) {
+	params := r.URL.Query()
+	query := bson.M{}
+	for param, field := range map[string]string{
+		"actor":      "actor",
+		"entity":     "entity",
+		"entity_id":  "entityid",
+		"action":     "action",
+		"request_id": "requestid",
+	} {
+		if s := strings.TrimSpace(params.Get(param)); s != "" {
+			query[field] = s
+		}
+	}
+
+	between := bson.M{}
+	for param, op := range map[string]string{"start_time": "$gte", "end_time": "$lt"} {
+		if s := params.Get(param); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, ErrInvalidTime
+			}
+			between[op] = t
+		}
+	}
+	if len(between) > 0 {
+		query["time"] = between
+	}
+
+	return query, nil
+}
+
+// Audit Log Handlers
+func (c *appContext) auditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	opts, errRes := listOptions(r, "audit_logs", auditLogSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	// The log only grows, so it's always paged.
+	opts.Paged = true
+	if len(opts.Sort) == 0 {
+		opts.Sort = []string{"-time"}
+	}
+
+	query, errRes := auditLogQuery(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	repo := AuditLogRepo{c.db.C("audit_logs")}
+	entries, total, err := repo.All(query, opts)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	writeList(w, r, opts, total, entries)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Roles
+//
+// The caller is identified by the X-User-Email header, which the
+// authenticating proxy in front of the API sets. Users have a role in the
+// user directory: admins manage venues and rooms and any event, other users
+// only the events they own. Admins can be bootstrapped from the environment:
+//
+//	ADMIN_EMAILS  comma-separated emails that are always admins
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+func bootstrapAdmin(email string) bool {
+	for _, admin := range strings.Split(setting("ADMIN_EMAILS"), ",") {
+		if strings.TrimSpace(admin) == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// caller returns the user making the request, if the request names one.
+func (c *appContext) caller(r *http.Request) (User, bool) {
+	email := r.Header.Get("X-User-Email")
+	if email == "" {
+		return User{}, false
+	}
+
+	return c.directoryUser(email), true
+}
+
+// directoryUser returns the user with email from the user directory, with the
+// role it has there.
+func (c *appContext) directoryUser(email string) User {
+	// Contexts without a database keep theirs in their repos.
+	user := User{}
+	if c.db == nil {
+		user = c.repos.Users[email]
+	} else {
+		var err error
+		repo := UserRepo{c.db.C("users")}
+		user, err = repo.FindByEmail(email)
+		if err != nil && err != storage.ErrNotFound {
+			panic(err)
+		}
+	}
+	user.Email = email
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	if bootstrapAdmin(email) {
+		user.Role = RoleAdmin
+	}
+
+	return user
+}
+
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// CanManage reports whether u may change or delete event.
+func (u User) CanManage(event Event) bool {
+	return u.IsAdmin() || event.Owner == u.Email
+}
+
+// Middleware
+func requireUser(c *appContext) func(http.Handler) http.Handler {
+	return requireRole(c, "")
+}
+
+// requireRole lets the request through when the caller has role, or any role
+// when role is empty, and stores the caller in the context as "user". Guests
+// admitted by guestHandler count as having the guest role, and machine
+// clients admitted by apiKeyHandler as the user of their key.
+func requireRole(c *appContext, role string) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(guestTokenKey).(GuestToken); ok {
+				if role != "" && role != RoleGuest {
+					WriteError(w, ErrForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, ok := r.Context().Value(userKey).(User)
+			if _, viaKey := r.Context().Value(apiKeyKey).(APIKey); !viaKey {
+				user, ok = c.forRequest(r).caller(r)
+			}
+			if !ok {
+				WriteError(w, ErrUnauthenticated)
+				return
+			}
+			if role != "" && user.Role != role && !user.IsAdmin() {
+				WriteError(w, ErrForbidden)
+				return
+			}
+
+			r = withValue(r, userKey, user)
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
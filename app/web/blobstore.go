@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Blob stores
+//
+// Uploaded images, floor plans and photos, are kept in a BlobStore by key, with
+// what they are recorded in MongoDB. BLOB_STORE picks where:
+//
+//	disk  the default, files under BLOB_DIR, default "blobs"
+//	s3    objects in S3_BUCKET, signed with AWS_ACCESS_KEY_ID,
+//	      AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN if any, in S3_REGION,
+//	      default us-east-1, at S3_ENDPOINT for S3 compatible stores such as
+//	      MinIO, default AWS
+type BlobStore interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+func newBlobStore() (BlobStore, error) {
+	switch setting("BLOB_STORE") {
+	case "", "disk":
+		dir := setting("BLOB_DIR")
+		if dir == "" {
+			dir = "blobs"
+		}
+		return &DiskBlobStore{Dir: dir}, nil
+	case "s3":
+		store := &S3BlobStore{
+			Bucket:       setting("S3_BUCKET"),
+			Region:       setting("S3_REGION"),
+			Endpoint:     setting("S3_ENDPOINT"),
+			AccessKey:    setting("AWS_ACCESS_KEY_ID"),
+			SecretKey:    setting("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: setting("AWS_SESSION_TOKEN"),
+		}
+		if store.Region == "" {
+			store.Region = "us-east-1"
+		}
+		if store.Endpoint == "" {
+			store.Endpoint = "https://s3." + store.Region + ".amazonaws.com"
+		}
+		if store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+			return nil, fmt.Errorf("BLOB_STORE=s3 needs S3_BUCKET, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		return store, nil
+	}
+
+	return nil, fmt.Errorf("unknown BLOB_STORE %q", setting("BLOB_STORE"))
+}
+
+// DiskBlobStore keeps blobs as files under Dir, by key.
+type DiskBlobStore struct {
+	Dir string
+}
+
+func (s *DiskBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.Dir, filepath.FromSlash(clean)), nil
+}
+
+func (s *DiskBlobStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Written aside first, so a blob is never read half written.
+	f, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (s *DiskBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+
+	return f, err
+}
+
+func (s *DiskBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// S3BlobStore keeps blobs as objects of Bucket, addressed by path, signing
+// its requests with AWS Signature Version 4.
+type S3BlobStore struct {
+	Bucket       string
+	Region       string
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	res, err := s.do(ctx, "PUT", key, contentType, data)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+func (s *S3BlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, "GET", key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, "DELETE", key, "", nil)
+	if err == ErrBlobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+// do sends a signed request for the object key, failing unless it answers
+// 2xx.
+func (s *S3BlobStore) do(ctx context.Context, method string, key string, contentType string, data []byte) (*http.Response, error) {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.Endpoint, "/")+"/"+url.PathEscape(s.Bucket)+"/"+strings.Join(segments, "/"), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, data, time.Now())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if res.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3: %s %s: %s %s", method, key, res.Status, b)
+	}
+
+	return res, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sign adds the Authorization header of req, with payload, at t.
+func (s *S3BlobStore) sign(req *http.Request, payload []byte, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{"host": req.URL.Host, "x-amz-content-sha256": payloadHash, "x-amz-date": amzDate}
+	if s.SessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		values["x-amz-security-token"] = s.SessionToken
+	}
+	canonicalHeaders := ""
+	for _, name := range headers {
+		canonicalHeaders += name + ":" + values[name] + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
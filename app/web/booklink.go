@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Booking links
+//
+// An admin can open a room to people outside the user directory, like a
+// demo room booked by prospects, with PUT /rooms/:id/booking-link. The room
+// then has a link token: GET /book/:token shows the room and when it's busy
+// over the next DaysAhead days, without what it's booked for, and
+// POST /book/:token books it for the requester named in the body. Such
+// bookings wait for an admin to approve them unless the link is set to
+// auto_confirm. DELETE /rooms/:id/booking-link turns the link off; turning it
+// on again issues a new token. Requesters aren't signed in, so the link
+// scopes the request to the organization of its room.
+const (
+	defaultBookingDaysAhead = 30
+	bookingLinkTokenBytes   = 16
+	bookingLinkIntegration  = "booking_link"
+)
+
+type BookingLink struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	OrgId       string           `json:"-" bson:"orgid,omitempty"`
+	RoomId      string           `json:"room_id"`
+	Token       string           `json:"token"`
+	Enabled     bool             `json:"enabled"`
+	AutoConfirm bool             `json:"auto_confirm"`
+	DaysAhead   int              `json:"days_ahead"`
+	CreatedBy   string           `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type BusySlot struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type BookingConfirmation struct {
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	PendingApproval bool      `json:"pending_approval"`
+}
+
+type BookingPage struct {
+	RoomName  string     `json:"room_name"`
+	Capacity  int        `json:"capacity"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Busy      []BusySlot `json:"busy"`
+}
+
+// BookingRequest is what a requester sends to book through a link.
+type BookingRequest struct {
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Company     string    `json:"company,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+}
+
+func newBookingLinkToken() string {
+	b := make([]byte, bookingLinkTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// window returns the times a requester can book through link, from now.
+func (link BookingLink) window(t time.Time) (time.Time, time.Time) {
+	days := link.DaysAhead
+	if days <= 0 {
+		days = defaultBookingDaysAhead
+	}
+
+	return t, t.AddDate(0, 0, days)
+}
+
+// Repo BookingLink
+type BookingLinkRepo struct {
+	coll *storage.Collection
+}
+
+func (r *BookingLinkRepo) FindByRoomId(roomId string) (BookingLink, error) {
+	result := BookingLink{}
+	err := r.coll.Find(bson.M{"roomid": roomId}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *BookingLinkRepo) FindByToken(token string) (BookingLink, error) {
+	result := BookingLink{}
+	err := r.coll.Find(bson.M{"token": token, "enabled": true}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Save creates or replaces the link of link.RoomId.
+func (r *BookingLinkRepo) Save(link *BookingLink) error {
+	if link.Id == "" {
+		link.Id = storage.NewObjectId()
+	}
+	_, err := r.coll.UpsertId(link.Id, link)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Middleware
+// bookingLinkHandler resolves the :token of the request to its enabled link,
+// stores it in the context as "booking_link" and scopes the request to the
+// organization of the link.
+func bookingLinkHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			params := routeParams(r)
+			repo := BookingLinkRepo{c.forRequest(r).allOrgs(r).C("booking_links")}
+			link, err := repo.FindByToken(params.ByName("token"))
+			if err == storage.ErrNotFound {
+				WriteError(w, ErrNotFound)
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+
+			r = c.inOrg(r, link.OrgId)
+			r = withValue(r, bookingLinkKey, link)
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// Booking Link Handlers
+func (c *appContext) roomBookingLinkHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := BookingLinkRepo{c.db.C("booking_links")}
+	link, err := repo.FindByRoomId(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, link)
+}
+
+func (c *appContext) updateRoomBookingLinkHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*BookingLink)
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	repo := BookingLinkRepo{c.db.C("booking_links")}
+	link, err := repo.FindByRoomId(room.Id.Hex())
+	if err != nil && err != storage.ErrNotFound {
+		panic(err)
+	}
+	created := err == storage.ErrNotFound
+	if created || !link.Enabled {
+		link.Token = newBookingLinkToken()
+		link.CreatedBy = r.Context().Value(userKey).(User).Email
+		link.CreatedAt = time.Now()
+	}
+	link.RoomId = room.Id.Hex()
+	link.Enabled = true
+	link.AutoConfirm = body.AutoConfirm
+	link.DaysAhead = body.DaysAhead
+	if link.DaysAhead <= 0 {
+		link.DaysAhead = defaultBookingDaysAhead
+	}
+
+	err = repo.Save(&link)
+	if err != nil {
+		panic(err)
+	}
+	if created {
+		c.recordChange("booking_link", link.Id, ChangeCreated)
+	} else {
+		c.recordChange("booking_link", link.Id, ChangeUpdated)
+	}
+
+	WriteSuccess(w, http.StatusOK, link)
+}
+
+func (c *appContext) deleteRoomBookingLinkHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := BookingLinkRepo{c.db.C("booking_links")}
+	link, err := repo.FindByRoomId(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	link.Enabled = false
+	err = repo.Save(&link)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("booking_link", link.Id, ChangeUpdated)
+
+	data := MessageSuccess{MessageInfo{Message: "Booking link has been turned off successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// bookingLinkRoom returns the link bookingLinkHandler found for r and its
+// room.
+func (c *appContext) bookingLinkRoom(r *http.Request) (BookingLink, Room, *Error) {
+	link := r.Context().Value(bookingLinkKey).(BookingLink)
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), link.RoomId)
+	if err == ErrDocumentNotFound {
+		return link, room, ErrNotFound
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return link, room, nil
+}
+
+func (c *appContext) bookingPageHandler(w http.ResponseWriter, r *http.Request) {
+	link, room, errRes := c.bookingLinkRoom(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	start_time, end_time := link.window(clockNow())
+	repo := c.events()
+	events, err := repo.Overlapping(r.Context(), room.Id.Hex(), start_time, end_time, "")
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	page := BookingPage{room.Name, room.Capacity, start_time, end_time, []BusySlot{}}
+	for _, event := range events {
+		page.Busy = append(page.Busy, BusySlot{event.StartTime, event.EndTime})
+	}
+	sort.Slice(page.Busy, func(i, j int) bool { return page.Busy[i].StartTime.Before(page.Busy[j].StartTime) })
+
+	WriteSuccess(w, http.StatusOK, page)
+}
+
+func (c *appContext) bookThroughLinkHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*BookingRequest)
+	link, room, errRes := c.bookingLinkRoom(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+	start_time, end_time := link.window(clockNow())
+	if body.StartTime.Before(start_time) || body.EndTime.After(end_time) {
+		WriteError(w, ErrOutsideBookingWindow)
+		return
+	}
+
+	name := strings.TrimSpace(body.Title)
+	if name == "" {
+		name = "Booking by " + body.Name
+	}
+	event := Event{
+		Name:            name,
+		LocationID:      room.Id.Hex(),
+		Location:        room.Name,
+		Description:     body.Description,
+		Guests:          []string{body.Email},
+		StartTime:       body.StartTime,
+		EndTime:         body.EndTime,
+		PendingApproval: !link.AutoConfirm,
+		BookedVia:       BookingSource{Channel: ChannelWeb, Integration: bookingLinkIntegration},
+		Requester:       &BookingRequester{Name: body.Name, Email: body.Email, Company: body.Company},
+	}
+
+	repo := c.events()
+	err := repo.Create(r.Context(), &event)
+	if _, ok := err.(*EventConflict); ok {
+		WriteError(w, ErrSlotTaken)
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeCreated)
+	notifyEvent(NotifyCreated, event)
+	c.announceBooking(r.Context(), event)
+
+	WriteSuccess(w, http.StatusCreated, BookingConfirmation{event.StartTime, event.EndTime, event.PendingApproval})
+}
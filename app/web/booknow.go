@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Book now
+//
+// POST /rooms/:id/book-now books a room for someone walking up to it: from
+// now for duration minutes, 30 by default, cut short so it ends when the
+// room's next booking starts. The booking is checked in, since whoever made
+// it is at the room. When the room is in use it answers 409 with when the
+// room frees up, after any meetings following back to back, in the detail
+// and as Retry-After. Room panels book the same way, see roompanel.go.
+type BookNowRequest struct {
+	Duration int    `json:"duration"`
+	Name     string `json:"name"`
+}
+
+func roomOccupiedError(freeAt time.Time) *Error {
+	return &Error{"room_occupied", http.StatusConflict, "Conflict", "The room is in use until " + freeAt.Format(time.RFC3339) + "."}
+}
+
+// writeRoomOccupied answers that the room of panel, occupied at now, can't
+// be booked before it frees up.
+func writeRoomOccupied(w http.ResponseWriter, panel RoomPanel, now time.Time) {
+	seconds := int(panel.FreeAt.Sub(now).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	WriteError(w, roomOccupiedError(*panel.FreeAt))
+}
+
+// bookFromNow books event, naming its owner, source and end, in the room of
+// panel, free at now, from now until its end or the room's next booking.
+func (c *appContext) bookFromNow(ctx context.Context, panel RoomPanel, now time.Time, event *Event) *Error {
+	event.LocationID = panel.Room.Id.Hex()
+	event.Location = panel.Room.Name
+	event.Guests = []string{}
+	event.StartTime = now
+	event.CheckedInAt = now
+	if panel.FreeUntil != nil && panel.FreeUntil.Before(event.EndTime) {
+		event.EndTime = *panel.FreeUntil
+	}
+	if errRes := c.checkVenueRules(ctx, *event); errRes != nil {
+		return errRes
+	}
+
+	err := c.events().Create(ctx, event)
+	if conflict, ok := err.(*EventConflict); ok {
+		return conflictError(conflict.Event)
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event", event.Id, ChangeCreated)
+
+	return nil
+}
+
+// Book Now Handlers
+func (c *appContext) bookNowHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*BookNowRequest)
+	user := r.Context().Value(userKey).(User)
+	source, errRes := bookingSource(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	now := clockNow().Truncate(time.Minute)
+	panel, err := c.panelOf(r, now)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if panel.Current != nil {
+		writeRoomOccupied(w, panel, now)
+		return
+	}
+	if body.Duration == 0 {
+		body.Duration = defaultQuickBook
+	}
+	if body.Name == "" {
+		body.Name = quickBookName
+	}
+
+	event := Event{
+		Name:      body.Name,
+		Owner:     user.Email,
+		EndTime:   now.Add(time.Duration(body.Duration) * time.Minute),
+		BookedVia: source,
+	}
+	if errRes := c.bookFromNow(r.Context(), panel, now, &event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	WriteSuccess(w, http.StatusCreated, event)
+}
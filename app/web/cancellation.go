@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Cancellations
+//
+// POST /events/:id/cancel cancels an event with the reason given, keeping
+// the event: who cancelled it, when and why are recorded on it before it's
+// deleted, see softdelete.go, so its room is free again and it's left out of
+// lookups and conflict checks like a deleted event. Its owner and guests are
+// told why.
+//
+// GET /events filters by ?status=, a comma separated list of the statuses of
+// myevents.go or cancelled; the cancelled events are only listed when asked
+// for, for audit. A single occurrence of a recurring event is still
+// cancelled with DELETE /events/:id?occurrence=.
+const EventCancelled = "cancelled"
+
+var (
+	ErrInvalidStatus = &Error{"invalid_status", 400, "Bad request", "status must be a comma separated list of pending_approval, tentative, checked_in, confirmed or cancelled."}
+)
+
+var listedEventStatuses = map[string]bool{EventPendingApproval: true, EventTentative: true, EventCheckedIn: true, EventConfirmed: true, EventCancelled: true}
+
+type EventCancellation struct {
+	Reason string `json:"reason"`
+}
+
+// filterByStatus keeps the events with one of statuses, all of them when
+// there are none.
+func filterByStatus(events []Event, statuses map[string]bool) []Event {
+	if len(statuses) == 0 {
+		return events
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if statuses[eventStatus(event)] {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// withoutDeleted leaves out the events that were deleted without being
+// cancelled, read along with the cancelled ones.
+func withoutDeleted(events []Event) []Event {
+	result := []Event{}
+	for _, event := range events {
+		if event.DeletedAt == nil || event.CancelledAt != nil {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// cancelEvent cancels existing on behalf of by, telling its owner and guests
+// why, and returns it as cancelled.
+func (c *appContext) cancelEvent(ctx context.Context, existing Event, by string, reason string) (Event, error) {
+	repo := c.events()
+	event := existing
+	now := time.Now()
+	event.CancelledAt = &now
+	event.CancelledBy = by
+	event.CancellationReason = reason
+	if err := repo.Cancel(ctx, &event); err != nil {
+		return event, err
+	}
+	c.recordChange("event", event.Id, ChangeDeleted)
+	notifyEvent(NotifyCancelled, event)
+	if event.Recurrence != nil {
+		c.deleteDetached(ctx, event)
+	}
+	c.recordLateCancel(event)
+	if event.Recurrence == nil {
+		c.recordFreedSlot(event.LocationID, event.StartTime, event.EndTime)
+	}
+
+	return event, nil
+}
+
+// Cancellation Handlers
+func (c *appContext) cancelEventHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*EventCancellation)
+	repo := c.events()
+	existing, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManage(existing) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	event, err := c.cancelEvent(r.Context(), existing, user.Email, body.Reason)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	zones.event(&event)
+
+	WriteSuccess(w, http.StatusAccepted, event)
+}
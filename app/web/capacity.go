@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Room capacity
+//
+// Capacity is the number of people a room seats. GET /rooms?min_capacity=N
+// lists the rooms seating at least N, and booking a room for more people
+// than it seats, counting the owner and the guests, is refused, as is
+// booking a location_id that isn't a room.
+//
+// Capacity used to be stored as a string, see migration 0001 in
+// internal/migrations. Rooms whose capacity it couldn't read seat no one
+// until an admin sets it.
+var (
+	ErrInvalidMinCapacity = &Error{"invalid_min_capacity", 400, "Bad request", "min_capacity must be a positive integer."}
+	ErrUnknownLocation    = &Error{"unknown_location", 400, "Bad request", "location_id does not name a room."}
+)
+
+func overCapacityError(room Room, seats int) *Error {
+	return &Error{
+		"over_capacity",
+		http.StatusUnprocessableEntity,
+		"Unprocessable Entity",
+		"The room \"" + room.Name + "\" seats " + strconv.Itoa(room.Capacity) + " people, the event has " + strconv.Itoa(seats) + ".",
+	}
+}
+
+// seats is the number of people e needs room for.
+func seats(e Event) int {
+	return len(e.Guests) + 1
+}
+
+// minCapacity reads ?min_capacity=, 0 when absent.
+func minCapacity(r *http.Request) (int, *Error) {
+	s := r.URL.Query().Get("min_capacity")
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, ErrInvalidMinCapacity
+	}
+
+	return n, nil
+}
+
+// checkCapacity verifies the room of event seats everyone invited. It returns
+// the error to send to the client, or nil.
+func (c *appContext) checkCapacity(ctx context.Context, event Event) *Error {
+	if event.LocationID == "" {
+		return nil
+	}
+
+	room, err := c.rooms().Find(ctx, event.LocationID)
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return ErrUnknownLocation
+	}
+	if err != nil {
+		panic(err)
+	}
+	if seats(event) > room.Capacity {
+		return overCapacityError(room, seats(event))
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Change log
+//
+// Every mutation of a venue, room or event appends an entry to the changes
+// collection with a monotonically increasing sequence number, counted per
+// organization, see organization.go. Signed-in consumers that
+// can't hold a WebSocket open (kiosk hardware, integrations) read the log with
+// GET /changes?since=<seq>, optionally long-polling with &wait=30s. &limit=
+// caps the entries returned, up to the changes page size (see pagination.go).
+// Long polls outlast the server's WRITE_TIMEOUT, see server.go, so their
+// write deadline is pushed back to the wait plus changesWriteHeadroom.
+const (
+	ChangeCreated  = "created"
+	ChangeUpdated  = "updated"
+	ChangeDeleted  = "deleted"
+	ChangeRestored = "restored"
+
+	maxChangesWait       = 60 * time.Second
+	changesWriteHeadroom = 10 * time.Second
+	changesPageSize      = 500
+)
+
+type Change struct {
+	OrgId    string    `json:"-" bson:"orgid,omitempty"`
+	Seq      int64     `json:"seq"`
+	Entity   string    `json:"entity"`
+	EntityId string    `json:"entity_id"`
+	Action   string    `json:"action"`
+	Time     time.Time `json:"time"`
+	Document bson.M    `json:"-" bson:",omitempty"`
+}
+
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	Next    int64    `json:"next"`
+}
+
+// Repo Change
+type ChangeRepo struct {
+	coll     *storage.Collection
+	counters *storage.Collection
+}
+
+func (r *ChangeRepo) Since(seq int64, limit int) ([]Change, error) {
+	result := []Change{}
+	err := r.coll.Find(bson.M{"seq": bson.M{"$gt": seq}}).Sort("seq").Limit(limit).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// counterId returns the id of the sequence counter of the organization r is
+// scoped to, "changes" for the default one as before organizations.
+func (r *ChangeRepo) counterId() string {
+	if org, _ := r.counters.Database.Org(); org != "" {
+		return "changes:" + org
+	}
+
+	return "changes"
+}
+
+func (r *ChangeRepo) Create(change *Change) error {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+	_, err := r.counters.FindId(r.counterId()).Apply(storage.Change{
+		Update:    bson.M{"$inc": bson.M{"seq": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	if err != nil {
+		return err
+	}
+
+	change.Seq = counter.Seq
+	return r.coll.Insert(change)
+}
+
+// changeNotifier wakes up long-polling requests served by this process as
+// soon as a change is recorded. Changes written by other instances are picked
+// up by the polling interval in changesHandler.
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (n *changeNotifier) Wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+
+	return n.ch
+}
+
+func (n *changeNotifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+var changeFeed = &changeNotifier{}
+
+func (c *appContext) recordChange(entity string, id storage.ObjectId, action string) {
+	// Contexts without a database, running on in-memory repos, keep no log.
+	if c.db == nil {
+		return
+	}
+	if _, scoped := c.db.Org(); !scoped {
+		org, err := c.orgOf(entity, id)
+		if err != nil {
+			log.Printf("changes: unable to tell the organization of %s %s: %v", entity, id.Hex(), err)
+			return
+		}
+		c = c.inOrgOf(org)
+	}
+
+	repo := ChangeRepo{c.db.C("changes"), c.db.C("counters")}
+	org, _ := c.db.Org()
+	change := Change{
+		OrgId:    org,
+		Entity:   entity,
+		EntityId: id.Hex(),
+		Action:   action,
+		Time:     time.Now(),
+		Document: c.snapshot(entity, id, action),
+	}
+	err := repo.Create(&change)
+	if err != nil {
+		log.Printf("changes: unable to record %s %s %s: %v", entity, action, id.Hex(), err)
+		return
+	}
+
+	changeFeed.Notify()
+	live.Broadcast(change)
+	c.recordAudit(change)
+	c.queueWebhooks(change)
+}
+
+// Change Handlers
+func (c *appContext) changesHandler(w http.ResponseWriter, r *http.Request) {
+	since := int64(0)
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		since, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || since < 0 {
+			WriteError(w, ErrInvalidSince)
+			return
+		}
+	}
+
+	wait := time.Duration(0)
+	if s := r.URL.Query().Get("wait"); s != "" {
+		var err error
+		wait, err = time.ParseDuration(s)
+		if err != nil || wait < 0 {
+			WriteError(w, ErrInvalidWait)
+			return
+		}
+		if wait > maxChangesWait {
+			wait = maxChangesWait
+		}
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + changesWriteHeadroom)); err != nil {
+			log.Printf("changes: extending the write deadline: %v", err)
+		}
+	}
+
+	size := pageSize("changes")
+	limit := size.Default
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			WriteError(w, ErrInvalidLimit)
+			return
+		}
+		if n > size.Max {
+			WriteError(w, perPageError("limit", size.Max))
+			return
+		}
+		limit = n
+	}
+
+	repo := ChangeRepo{c.db.C("changes"), c.db.C("counters")}
+	deadline := time.After(wait)
+	for {
+		notified := changeFeed.Wait()
+		changes, err := repo.Since(since, limit)
+		if err != nil {
+			panic(err)
+		}
+
+		if len(changes) > 0 || wait == 0 {
+			next := since
+			if len(changes) > 0 {
+				next = changes[len(changes)-1].Seq
+			}
+
+			WriteSuccess(w, http.StatusOK, ChangesResponse{changes, next})
+			return
+		}
+
+		select {
+		case <-notified:
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			WriteSuccess(w, http.StatusOK, ChangesResponse{changes, since})
+			return
+		}
+	}
+}
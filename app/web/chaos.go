@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chaos mode
+//
+// With CHAOS_MODE=true, which is refused when ENV=production, QA can make a
+// running instance misbehave on purpose:
+//
+//	GET    /admin/chaos            the faults in effect
+//	PUT    /admin/chaos            replace them
+//	DELETE /admin/chaos            clear them
+//	POST   /admin/chaos/jobs/:job  run noshows, standby or reminders now
+//
+// latency_ms delays every request, or those under latency_path, before it
+// reaches the database. fail_reads makes the next reads of the venue,
+// room and event repos fail with a connection error, one per attempt, so
+// up to failoverRetries are recovered by retryRead and more end in a 503.
+// fail_writes answers the next mutating requests as an interrupted write.
+// frozen_at stops the clock bookings are judged against, the one of
+// check-ins, no-shows, standby and reminders, at that time. Requests to
+// /admin/chaos are never delayed or failed, so faults can always be cleared.
+const chaosPath = "/admin/chaos"
+
+var (
+	ErrChaosUnknownJob = &Error{"unknown_job", 404, "Not Found", "The job must be one of noshows, standby or reminders."}
+
+	// errChaosRead is the error of a failed read, one isTransient knows.
+	errChaosRead = io.ErrUnexpectedEOF
+)
+
+type ChaosSettings struct {
+	LatencyMs   int        `json:"latency_ms"`
+	LatencyPath string     `json:"latency_path,omitempty"`
+	FailReads   int        `json:"fail_reads"`
+	FailWrites  int        `json:"fail_writes"`
+	FrozenAt    *time.Time `json:"frozen_at,omitempty"`
+}
+
+type ChaosJobRun struct {
+	Job   string    `json:"job"`
+	RanAt time.Time `json:"ran_at"`
+}
+
+type chaosFaults struct {
+	sync.Mutex
+	enabled  bool
+	settings ChaosSettings
+}
+
+// chaos is on with CHAOS_MODE, see config.go.
+var chaos = &chaosFaults{}
+
+func (f *chaosFaults) Settings() ChaosSettings {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.settings
+}
+
+func (f *chaosFaults) Set(settings ChaosSettings) {
+	f.Lock()
+	defer f.Unlock()
+	f.settings = settings
+}
+
+// take uses up one of *n, reporting whether there was one left.
+func (f *chaosFaults) take(n *int) bool {
+	f.Lock()
+	defer f.Unlock()
+	if !f.enabled || *n <= 0 {
+		return false
+	}
+	*n--
+
+	return true
+}
+
+// read runs read unless it's to fail.
+func (f *chaosFaults) read(read func() error) error {
+	if f.take(&f.settings.FailReads) {
+		return errChaosRead
+	}
+
+	return read()
+}
+
+// clockNow is the time bookings are judged against, frozen in chaos mode.
+func clockNow() time.Time {
+	chaos.Lock()
+	defer chaos.Unlock()
+	if chaos.enabled && chaos.settings.FrozenAt != nil {
+		return *chaos.settings.FrozenAt
+	}
+
+	return time.Now()
+}
+
+// chaosHandler applies the latency and write faults to a request.
+func chaosHandler(next http.Handler) http.Handler {
+	if !chaos.enabled {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, chaosPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		settings := chaos.Settings()
+		if settings.LatencyMs > 0 && strings.HasPrefix(r.URL.Path, settings.LatencyPath) {
+			time.Sleep(time.Duration(settings.LatencyMs) * time.Millisecond)
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && chaos.take(&chaos.settings.FailWrites) {
+			writeUnavailable(w, ErrRetryableWrite)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Chaos Handlers
+func (c *appContext) chaosSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	WriteSuccess(w, http.StatusOK, chaos.Settings())
+}
+
+func (c *appContext) updateChaosHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*ChaosSettings)
+	chaos.Set(*body)
+	log.Printf("chaos: %s set latency_ms=%d fail_reads=%d fail_writes=%d frozen=%t",
+		r.Header.Get("X-User-Email"), body.LatencyMs, body.FailReads, body.FailWrites, body.FrozenAt != nil)
+
+	WriteSuccess(w, http.StatusOK, chaos.Settings())
+}
+
+func (c *appContext) resetChaosHandler(w http.ResponseWriter, r *http.Request) {
+	chaos.Set(ChaosSettings{})
+
+	WriteSuccess(w, http.StatusOK, chaos.Settings())
+}
+
+func (c *appContext) runChaosJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobs := map[string]func() error{
+		"noshows":   func() error { return c.recordNoShows(r.Context()) },
+		"standby":   func() error { return c.matchStandby(r.Context()) },
+		"reminders": func() error { return c.sendReminders(r.Context()) },
+	}
+	name := routeParams(r).ByName("job")
+	job, ok := jobs[name]
+	if !ok {
+		WriteError(w, ErrChaosUnknownJob)
+		return
+	}
+	if err := job(); err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, ChaosJobRun{name, clockNow()})
+}
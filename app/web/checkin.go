@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check-in codes
+//
+// Every event gets a short numeric code, returned when it's booked, that can
+// be typed on keypad-only room panels to check in. Codes are unique among the
+// room's events of the surrounding day and are accepted from
+// checkInEarly before the start until the end of the event. A room is locked
+// for checkInLockout after checkInMaxFailures wrong codes so codes can't be
+// guessed.
+const (
+	checkInCodeDigits  = 6
+	checkInEarly       = 15 * time.Minute
+	checkInMaxFailures = 5
+	checkInLockout     = 15 * time.Minute
+)
+
+type CheckInRequest struct {
+	RoomId string `json:"room_id"`
+	Code   string `json:"code"`
+}
+
+type checkInLimiter struct {
+	sync.Mutex
+	failures map[string][]time.Time
+}
+
+var checkInFailures = &checkInLimiter{failures: map[string][]time.Time{}}
+
+// Locked reports whether roomId has too many recent failures.
+func (l *checkInLimiter) Locked(roomId string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	recent := []time.Time{}
+	for _, t := range l.failures[roomId] {
+		if time.Since(t) < checkInLockout {
+			recent = append(recent, t)
+		}
+	}
+	l.failures[roomId] = recent
+
+	return len(recent) >= checkInMaxFailures
+}
+
+func (l *checkInLimiter) Fail(roomId string) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.failures[roomId] = append(l.failures[roomId], time.Now())
+}
+
+func (l *checkInLimiter) Reset(roomId string) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.failures, roomId)
+}
+
+// newCheckInCode returns a random code no other event in the same room uses
+// around the time of event.
+func (c *appContext) newCheckInCode(ctx context.Context, event Event) (string, error) {
+	repo := c.events()
+	max := big.NewInt(1)
+	for i := 0; i < checkInCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	for {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code := fmt.Sprintf("%0*d", checkInCodeDigits, n)
+
+		taken, err := repo.CheckInCodeTaken(ctx, event.LocationID, code, event.StartTime.Add(-24*time.Hour), event.EndTime.Add(24*time.Hour))
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return code, nil
+		}
+	}
+}
+
+// Check-in Handlers
+func (c *appContext) checkInCodeHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*CheckInRequest)
+	if checkInFailures.Locked(body.RoomId) {
+		WriteError(w, ErrCheckInLocked)
+		return
+	}
+
+	repo := c.events()
+	now := clockNow()
+	event, err := repo.FindByCheckInCode(r.Context(), body.RoomId, body.Code, now, checkInEarly)
+	if err == ErrDocumentNotFound {
+		checkInFailures.Fail(body.RoomId)
+		WriteError(w, ErrInvalidCheckInCode)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	checkInFailures.Reset(body.RoomId)
+
+	if event.CheckedInAt.IsZero() {
+		err = repo.CheckIn(r.Context(), event.Id, now)
+		if err != nil {
+			panic(err)
+		}
+		event.CheckedInAt = now
+		c.recordChange("event", event.Id, ChangeUpdated)
+	}
+
+	WriteSuccess(w, http.StatusOK, event)
+}
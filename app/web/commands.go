@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/ivansaputr4/ivana/internal/migrations"
+)
+
+// Commands
+//
+// Run with arguments, the server runs a command against the database and
+// exits instead of serving:
+//
+//	seed                  create the sample data, see seed.go
+//	migrate up            apply the pending migrations
+//	migrate down [steps]  revert the latest migrations (default 1)
+//	migrate status        list the migrations and when they were applied
+//
+// Serving applies the pending migrations first, like migrate up.
+const commandsUsage = "usage: web [seed | migrate up | migrate down [steps] | migrate status]"
+
+func runCommand(c *appContext, args []string) {
+	switch args[0] {
+	case "seed":
+		if _, err := migrations.Up(c.db); err != nil {
+			log.Fatalf("seed: %v", err)
+		}
+		if err := c.seed(context.Background()); err != nil {
+			log.Fatalf("seed: %v", err)
+		}
+	case "migrate":
+		if err := migrateCommand(c, args[1:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, commandsUsage)
+		os.Exit(2)
+	}
+}
+
+func migrateCommand(c *appContext, args []string) error {
+	if len(args) == 0 {
+		args = []string{"up"}
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(c.db)
+		log.Printf("migrate: applied %d", n)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("steps must be a positive integer")
+			}
+		}
+		n, err := migrations.Down(c.db, steps)
+		log.Printf("migrate: reverted %d", n)
+		return err
+	case "status":
+		list, err := migrations.List(c.db)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", m.Version, m.Name, applied)
+		}
+		return nil
+	default:
+		return errors.New(commandsUsage)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Room comparison
+//
+// GET /rooms/compare?ids=a,b,c&date= returns the schedules of up to
+// maxCompareRooms rooms for one day, each with its events and the free gaps
+// between them over the same window, so they can be laid out side by side.
+const maxCompareRooms = 10
+
+type Interval struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type RoomSchedule struct {
+	Room   Room       `json:"room"`
+	Events []Event    `json:"events"`
+	Free   []Interval `json:"free"`
+}
+
+type RoomComparison struct {
+	Date      string         `json:"date"`
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Rooms     []RoomSchedule `json:"rooms"`
+}
+
+// freeIntervals returns the gaps in [start_time, end_time) not covered by
+// events, which must be sorted by start.
+func freeIntervals(events []Event, start_time time.Time, end_time time.Time) []Interval {
+	free := []Interval{}
+	cursor := start_time
+	for _, event := range events {
+		if event.StartTime.After(cursor) {
+			free = append(free, Interval{cursor, event.StartTime})
+		}
+		if event.EndTime.After(cursor) {
+			cursor = event.EndTime
+		}
+	}
+	if cursor.Before(end_time) {
+		free = append(free, Interval{cursor, end_time})
+	}
+
+	return free
+}
+
+// Room Compare Handlers
+func (c *appContext) compareRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	date, ok := dayParam(r)
+	if !ok {
+		WriteError(w, ErrInvalidDate)
+		return
+	}
+
+	ids := []string{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			if !storage.IsObjectIdHex(id) {
+				WriteError(w, ErrUnknownRoom)
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxCompareRooms {
+		WriteError(w, ErrCompareRooms)
+		return
+	}
+
+	loc := defaultLocation
+	start_time, _ := time.ParseInLocation("2006-01-02", date, loc)
+	end_time := start_time.AddDate(0, 0, 1)
+
+	roomRepo := c.rooms()
+	rooms := []Room{}
+	for _, id := range ids {
+		room, err := roomRepo.Find(r.Context(), id)
+		if err == ErrDocumentNotFound {
+			WriteError(w, ErrUnknownRoom)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		rooms = append(rooms, room)
+	}
+
+	repo := c.events()
+	events, err := repo.AllByLocationIds(r.Context(), ids, start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+
+	byRoom := map[string][]Event{}
+	for _, event := range events {
+		byRoom[event.LocationID] = append(byRoom[event.LocationID], event)
+	}
+
+	comparison := RoomComparison{date, start_time, end_time, []RoomSchedule{}}
+	for _, room := range rooms {
+		roomEvents := byRoom[room.Id.Hex()]
+		if roomEvents == nil {
+			roomEvents = []Event{}
+		}
+		comparison.Rooms = append(comparison.Rooms, RoomSchedule{room, roomEvents, freeIntervals(roomEvents, start_time, end_time)})
+	}
+
+	WriteSuccess(w, http.StatusOK, comparison)
+}
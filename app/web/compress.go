@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compression and ETags
+//
+// compressHandler holds the response of a request until it knows how to
+// send it. A successful GET or HEAD whose body fits in etagMaxSize gets a
+// weak ETag, a hash of its body, and is answered 304 Not Modified without a
+// body when the If-None-Match of the request names it. Bodies of at least
+// gzipMinSize bytes are gzipped for clients accepting it, unless their type
+// is compressed already, such as images, zip archives and XLSX workbooks.
+// Larger bodies, and those of handlers that flush, are streamed once what's
+// held runs out: through a gzip.Writer, without an ETag. Streams,
+// text/event-stream and NDJSON, are sent as they're written. Request bodies
+// sent with Content-Encoding: gzip are read uncompressed.
+const (
+	gzipMinSize = 1024
+	etagMaxSize = 64 << 10
+)
+
+// compressedTypes are the media types not gzipped, and streamTypes those
+// sent as they're written. Those ending in / or . are prefixes.
+var (
+	compressedTypes = []string{"image/", "video/", "audio/", "font/", "application/zip", "application/gzip", "application/pdf", "application/vnd.openxmlformats-officedocument."}
+	streamTypes     = []string{"text/event-stream", "application/x-ndjson", "application/ndjson"}
+)
+
+// hasMediaType reports whether the media type of contentType is one of
+// types.
+func hasMediaType(contentType string, types []string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, t := range types {
+		if mediaType == t || (strings.HasSuffix(t, "/") || strings.HasSuffix(t, ".")) && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressWriter holds the status and up to limit bytes of the body written
+// to it, then streams the rest, see Compression and ETags.
+type compressWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	limit     int
+	status    int
+	body      bytes.Buffer
+	streaming bool
+	gz        *gzip.Writer
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.streaming {
+		if cw.body.Len()+len(p) <= cw.limit && !hasMediaType(cw.Header().Get("Content-Type"), streamTypes) {
+			return cw.body.Write(p)
+		}
+		cw.stream()
+	}
+	if cw.gz != nil {
+		return cw.gz.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what's held and streams the rest.
+func (cw *compressWriter) Flush() {
+	if !cw.streaming {
+		cw.stream()
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// RecordError passes the id of the error answered with on, see
+// httpapi.ErrorRecorder.
+func (cw *compressWriter) RecordError(id string) {
+	if rec, ok := cw.ResponseWriter.(interface{ RecordError(string) }); ok {
+		rec.RecordError(id)
+	}
+}
+
+// compressible reports whether the body may be gzipped, whatever its size.
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	return header.Get("Content-Encoding") == "" && !hasMediaType(header.Get("Content-Type"), compressedTypes) && !hasMediaType(header.Get("Content-Type"), streamTypes)
+}
+
+// stream sends the status and what's held, and has Write send the rest of
+// the body as it comes.
+func (cw *compressWriter) stream() {
+	cw.streaming = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	header := cw.Header()
+	if cw.compressible() {
+		header.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(cw.r) {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			cw.gz = gzip.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.gz != nil {
+		cw.gz.Write(cw.body.Bytes())
+	} else {
+		cw.ResponseWriter.Write(cw.body.Bytes())
+	}
+	cw.body.Reset()
+}
+
+// finish sends a response the handler is done with.
+func (cw *compressWriter) finish() {
+	if cw.streaming {
+		if cw.gz != nil {
+			cw.gz.Close()
+		}
+		return
+	}
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	w, r := cw.ResponseWriter, cw.r
+	body := cw.body.Bytes()
+	compress := len(body) >= gzipMinSize && cw.compressible()
+
+	if compress {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if (r.Method == "GET" || r.Method == "HEAD") && cw.status == http.StatusOK && w.Header().Get("ETag") == "" {
+		etag := bodyETag(body)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if !compress || !acceptsGzip(r) {
+		w.WriteHeader(cw.status)
+		w.Write(body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(cw.status)
+	gz := gzip.NewWriter(w)
+	gz.Write(body)
+	gz.Close()
+}
+
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value names etag,
+// comparing weakly.
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsGzip reports whether the Accept-Encoding of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(coding, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[len("q="):], 64); err == nil && q == 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// gzipBody reads the body of a request sent with Content-Encoding: gzip.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// Middleware
+func compressHandler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+			r.Body = gzipBody{reader, r.Body}
+			r.Header.Del("Content-Encoding")
+			r.ContentLength = -1
+		}
+
+		cw := &compressWriter{ResponseWriter: w, r: r, limit: gzipMinSize}
+		if r.Method == "GET" || r.Method == "HEAD" {
+			cw.limit = etagMaxSize
+		}
+		next.ServeHTTP(cw, r)
+		cw.finish()
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/postgres"
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Configuration
+//
+// The app reads its settings from the environment and, when CONFIG_FILE
+// names one, from a JSON file of the same settings by name, which the
+// environment overrides:
+//
+//	{"PORT": "8080", "MONGODB_URI": "mongodb://db:27017", "MAILER": "smtp"}
+//
+// What the app needs to start is loaded into a Config by loadConfig, which
+// fails at startup with every missing or invalid setting at once:
+//
+//	ENV                  development, staging, production or test
+//	PORT                 required
+//	MONGODB_URI          and the MONGODB_* pool settings, see storage.Config
+//	MONGODB_DATABASE     default ivana
+//	DATABASE_URL         PostgreSQL for venues, rooms and events, see repository.go
+//	TIME_ZONE            the org's, for venues without one, default Asia/Bangkok
+//	MAILER               smtp, sendgrid or log, with MAIL_FROM, SMTP_ADDR,
+//	                     SMTP_USERNAME, SMTP_PASSWORD and SENDGRID_API_KEY
+//	CHAOS_MODE           true to turn chaos mode on, see chaos.go
+//	PRESENCE_VISIBILITY  on to list who is in the office, see presence.go
+//	SLACK_*              where bookings are posted, see slack.go
+//	MSGRAPH_*            the app bookings are pushed to Outlook as, see msgraph.go
+//	CORS_*               the policy of other origins, see cors.go
+//	RATE_LIMIT           requests per minute per caller, see limits.go
+//
+// The settings tuning a running app, like OVERBOOK_NOTICE, are read where
+// they are used with setting, which sees the file too.
+type Config struct {
+	Env         string
+	Port        string
+	Mongo       storage.Config
+	Database    string
+	DatabaseURL string
+	TimeZone    string
+	Mail        MailConfig
+	Features    Features
+	Slack       SlackConfig
+	MSGraph     MSGraphConfig
+	CORS        CORSPolicy
+	RateLimit   int
+}
+
+// MailConfig is how notifications are mailed, see notifier.go.
+type MailConfig struct {
+	Mailer         string
+	From           string
+	SMTPAddr       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SendGridAPIKey string
+}
+
+// SlackConfig is where bookings are posted, see slack.go.
+type SlackConfig struct {
+	WebhookURL    string
+	VenueWebhooks map[string]string
+}
+
+// MSGraphConfig is the Azure AD app bookings are pushed to Outlook as, see
+// msgraph.go.
+type MSGraphConfig struct {
+	TenantID        string
+	ClientID        string
+	ClientSecret    string
+	Mailbox         string
+	Teams           bool
+	NotificationURL string
+	ClientState     string
+}
+
+// Features are the optional features turned on.
+type Features struct {
+	Chaos    bool
+	Presence bool
+}
+
+var configEnvs = []string{"", "development", "staging", "production", "test"}
+
+// configFile are the settings of CONFIG_FILE, by name.
+var configFile = map[string]string{}
+
+// setting returns the setting name, from the environment or else the
+// config file.
+func setting(name string) string {
+	if s := os.Getenv(name); s != "" {
+		return s
+	}
+
+	return configFile[name]
+}
+
+// readConfigFile reads the JSON object of settings at path.
+func readConfigFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	result := map[string]string{}
+	for name, value := range values {
+		if s, ok := value.(string); ok {
+			result[name] = s
+			continue
+		}
+		result[name] = fmt.Sprint(value)
+	}
+
+	return result, nil
+}
+
+// loadConfig reads the config file, if any, and the settings the app starts
+// with.
+func loadConfig() (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %v", err)
+		}
+		configFile = values
+	}
+
+	cfg := &Config{
+		Env:         setting("ENV"),
+		Port:        setting("PORT"),
+		Mongo:       storage.ConfigFrom(setting),
+		Database:    setting("MONGODB_DATABASE"),
+		DatabaseURL: setting("DATABASE_URL"),
+		TimeZone:    setting("TIME_ZONE"),
+		Mail: MailConfig{
+			Mailer:         setting("MAILER"),
+			From:           setting("MAIL_FROM"),
+			SMTPAddr:       setting("SMTP_ADDR"),
+			SMTPUsername:   setting("SMTP_USERNAME"),
+			SMTPPassword:   setting("SMTP_PASSWORD"),
+			SendGridAPIKey: setting("SENDGRID_API_KEY"),
+		},
+		Features: Features{
+			Chaos:    setting("CHAOS_MODE") == "true",
+			Presence: setting("PRESENCE_VISIBILITY") == "on",
+		},
+		Slack: SlackConfig{
+			WebhookURL:    strings.TrimSpace(setting("SLACK_WEBHOOK_URL")),
+			VenueWebhooks: slackVenueWebhooks(setting("SLACK_VENUE_WEBHOOKS")),
+		},
+		MSGraph: MSGraphConfig{
+			TenantID:        strings.TrimSpace(setting("MSGRAPH_TENANT_ID")),
+			ClientID:        strings.TrimSpace(setting("MSGRAPH_CLIENT_ID")),
+			ClientSecret:    setting("MSGRAPH_CLIENT_SECRET"),
+			Mailbox:         strings.TrimSpace(setting("MSGRAPH_MAILBOX")),
+			Teams:           setting("MSGRAPH_TEAMS") == "true",
+			NotificationURL: strings.TrimSpace(setting("MSGRAPH_NOTIFICATION_URL")),
+			ClientState:     setting("MSGRAPH_CLIENT_STATE"),
+		},
+		CORS: CORSPolicy{
+			Origins: envList("CORS_ORIGINS", []string{"*"}),
+			Methods: envList("CORS_METHODS", corsMethods),
+			Headers: envList("CORS_HEADERS", corsHeaders),
+			MaxAge:  defaultCORSMaxAge,
+		},
+		RateLimit: defaultRateLimit,
+	}
+	if s := setting("CORS_MAX_AGE"); s != "" {
+		cfg.CORS.MaxAge, _ = time.ParseDuration(s)
+	}
+	if s := setting("RATE_LIMIT"); s != "" {
+		cfg.RateLimit, _ = strconv.Atoi(s)
+	}
+	if cfg.Database == "" {
+		cfg.Database = storage.DatabaseName
+	}
+	if cfg.TimeZone == "" {
+		cfg.TimeZone = defaultTimeZone
+	}
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = defaultMailFrom
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validate fails with every problem of c.
+func (c *Config) validate() error {
+	problems := []string{}
+	if !contains(configEnvs, c.Env) {
+		problems = append(problems, "ENV must be one of development, staging, production or test")
+	}
+	if c.Port == "" {
+		problems = append(problems, "PORT is required")
+	}
+	if !strings.HasPrefix(c.Mongo.URI, "mongodb://") && !strings.HasPrefix(c.Mongo.URI, "mongodb+srv://") {
+		problems = append(problems, "MONGODB_URI must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if c.DatabaseURL != "" && !postgres.IsURL(c.DatabaseURL) {
+		problems = append(problems, "DATABASE_URL must be a postgres:// or postgresql:// URL")
+	}
+	if _, ok := loadZone(c.TimeZone); !ok {
+		problems = append(problems, "TIME_ZONE must be an IANA time zone such as Asia/Jakarta")
+	}
+	switch c.Mail.Mailer {
+	case "", "log":
+	case "smtp":
+		if _, _, err := net.SplitHostPort(c.Mail.SMTPAddr); err != nil {
+			problems = append(problems, "SMTP_ADDR must look like host:port with MAILER=smtp")
+		}
+	case "sendgrid":
+		if c.Mail.SendGridAPIKey == "" {
+			problems = append(problems, "SENDGRID_API_KEY is required with MAILER=sendgrid")
+		}
+	default:
+		problems = append(problems, "MAILER must be smtp, sendgrid or log")
+	}
+	graph := c.MSGraph
+	if (graph.TenantID != "" || graph.ClientID != "" || graph.ClientSecret != "") && (graph.TenantID == "" || graph.ClientID == "" || graph.ClientSecret == "") {
+		problems = append(problems, "MSGRAPH_TENANT_ID, MSGRAPH_CLIENT_ID and MSGRAPH_CLIENT_SECRET are required together")
+	}
+	if c.CORS.MaxAge <= 0 {
+		problems = append(problems, "CORS_MAX_AGE must be a positive duration such as 10m")
+	}
+	if c.RateLimit <= 0 {
+		problems = append(problems, "RATE_LIMIT must be a positive number of requests per minute")
+	}
+	if c.Features.Chaos && c.Env == "production" {
+		problems = append(problems, "CHAOS_MODE can't be enabled in production")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("config: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CORS
+//
+// Browsers calling the API from another origin are answered with the CORS
+// headers by corsHandler, which wraps every route, see handle. Their
+// preflights, OPTIONS requests sent before a PATCH, a DELETE or a request
+// with headers of its own, are answered on every path with the methods
+// registered for it. The policy is the one of the config, see config.go:
+//
+//	CORS_ORIGINS  comma-separated origins, e.g. "https://app.example.com",
+//	              default "*" for any
+//	CORS_METHODS  comma-separated methods, default GET,POST,PUT,PATCH,DELETE
+//	CORS_HEADERS  comma-separated request headers, default the ones the API
+//	              reads
+//	CORS_MAX_AGE  e.g. "1h", how long browsers cache a preflight, default 10m
+type CORSPolicy struct {
+	Origins []string
+	Methods []string
+	Headers []string
+	MaxAge  time.Duration
+}
+
+var (
+	corsMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	corsHeaders = []string{"Accept", "Accept-Language", "Authorization", "Content-Encoding", "Content-Type", "Idempotency-Key",
+		"If-None-Match", "Prefer", "Time-Zone", "X-API-Key", "X-Booking-Source", "X-Client-Key", "X-Request-ID", "X-User-Email"}
+
+	// corsExposedHeaders are the response headers scripts can read.
+	corsExposedHeaders = []string{"API-Version", "Content-Disposition", "Deprecation", "ETag", "Idempotent-Replayed", "Link", "Preference-Applied",
+		"Retry-After", "Sunset", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-ID", "X-Total-Count"}
+)
+
+const defaultCORSMaxAge = 10 * time.Minute
+
+// envList reads a comma-separated list from the environment.
+func envList(name string, fallback []string) []string {
+	result := []string{}
+	for _, value := range strings.Split(setting(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	if len(result) == 0 {
+		return fallback
+	}
+
+	return result
+}
+
+// AllowOrigin returns the Access-Control-Allow-Origin of a request from
+// origin, or false when the policy doesn't allow it.
+func (p CORSPolicy) AllowOrigin(origin string) (string, bool) {
+	for _, allowed := range p.Origins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if origin != "" && strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+
+	return "", false
+}
+
+func (p CORSPolicy) allowsMethod(method string) bool {
+	for _, allowed := range p.Methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeOrigin sets the headers every answer to a request of another origin
+// has, and reports whether the origin is allowed.
+func (p CORSPolicy) writeOrigin(w http.ResponseWriter, r *http.Request) bool {
+	allowed, ok := p.AllowOrigin(r.Header.Get("Origin"))
+	if allowed != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+	if !ok {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	return true
+}
+
+// Middleware
+func corsHandler(policy CORSPolicy) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if policy.writeOrigin(w, r) {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// allowPreflight answers OPTIONS on path, once per path.
+func (r *router) allowPreflight(path string) {
+	if r.preflights == nil {
+		r.preflights = map[string]bool{}
+	}
+	if r.preflights[path] {
+		return
+	}
+	r.preflights[path] = true
+
+	r.OPTIONS(path, func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		r.preflightHandler(path, w, req)
+	})
+}
+
+// preflightHandler answers the preflight of a request to path with the
+// methods registered for it that the policy allows.
+func (r *router) preflightHandler(path string, w http.ResponseWriter, req *http.Request) {
+	policy := r.cors
+	methods := []string{}
+	for _, route := range r.routes {
+		method := strings.SplitN(route, " ", 2)[0]
+		if route == method+" "+path && policy.allowsMethod(method) {
+			methods = append(methods, method)
+		}
+	}
+	methods = append(methods, "OPTIONS")
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+
+	if policy.writeOrigin(w, req) {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.Headers, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Deprecations
+//
+// Routes or payload formats scheduled for removal are registered here and
+// wrapped with deprecationHandler, which advertises them through the
+// Deprecation/Sunset headers and counts usage per client so we know who still
+// has to migrate before the removal ships.
+type Deprecation struct {
+	Feature string
+	Since   time.Time
+	Sunset  time.Time
+	Link    string
+}
+
+var deprecations = map[string]Deprecation{
+	// EventResponse splits the start and end of an event into date, month,
+	// year, start_hour, start_minute, end_hour and end_minute. Requests can
+	// send start_time and end_time instead, and /v2 replaces them with plain
+	// timestamps.
+	"event_date_fields": {
+		Feature: "event_date_fields",
+		Link:    "/schemas/event",
+	},
+}
+
+// Repo Deprecation usage
+type DeprecationUsage struct {
+	Feature   string    `json:"feature"`
+	ClientKey string    `json:"client_key"`
+	Day       string    `json:"day"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type DeprecationUsageRepo struct {
+	coll *storage.Collection
+}
+
+func (r *DeprecationUsageRepo) All() ([]DeprecationUsage, error) {
+	result := []DeprecationUsage{}
+	err := r.coll.Find(nil).Sort("-day", "feature", "clientkey").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeprecationUsageRepo) Record(feature string, clientKey string, t time.Time) error {
+	_, err := r.coll.Upsert(
+		bson.M{"feature": feature, "clientkey": clientKey, "day": t.Format("2006-01-02")},
+		bson.M{"$inc": bson.M{"count": 1}, "$set": bson.M{"lastseen": t}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func clientKey(r *http.Request) string {
+	if key := r.Header.Get("X-Client-Key"); key != "" {
+		return key
+	}
+	if ua := r.Header.Get("User-Agent"); ua != "" {
+		return ua
+	}
+
+	return "anonymous"
+}
+
+// Middleware
+func deprecationHandler(c *appContext, feature string) func(http.Handler) http.Handler {
+	d, ok := deprecations[feature]
+	if !ok {
+		panic("unknown deprecation " + feature)
+	}
+
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if d.Since.IsZero() {
+				w.Header().Set("Deprecation", "true")
+			} else {
+				w.Header().Set("Deprecation", d.Since.UTC().Format(http.TimeFormat))
+			}
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Link != "" {
+				w.Header().Add("Link", "<"+d.Link+`>; rel="deprecation"`)
+			}
+
+			// Contexts without a database, running on in-memory repos,
+			// record no usage.
+			if rc := c.forRequest(r); rc.db != nil {
+				repo := DeprecationUsageRepo{rc.db.C("deprecation_usage")}
+				if err := repo.Record(d.Feature, clientKey(r), time.Now()); err != nil {
+					log.Printf("deprecation: unable to record usage of %s: %v", d.Feature, err)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// Deprecation Handlers
+func (c *appContext) deprecationUsageHandler(w http.ResponseWriter, r *http.Request) {
+	repo := DeprecationUsageRepo{c.db.C("deprecation_usage")}
+	usage, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, usage)
+}
@@ -0,0 +1,774 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Desk hoteling
+//
+// Floors belong to a venue and hold desks placed on the floor map with x/y
+// coordinates. Desks are grouped into neighborhoods, which can be assigned to
+// a team. Desks are booked per day in half-day ("am", "pm") or full-day
+// slots. Admins lay out the floors; users book desks for themselves.
+const (
+	SlotMorning   = "am"
+	SlotAfternoon = "pm"
+	SlotFullDay   = "full"
+)
+
+type Floor struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	VenueId     string           `json:"venue_id"`
+	Name        string           `json:"name"`
+	Level       int              `json:"level"`
+	MapImageURL string           `json:"map_image_url"`
+	Width       float64          `json:"width"`
+	Height      float64          `json:"height"`
+}
+
+type Neighborhood struct {
+	Id      storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	FloorId string           `json:"floor_id"`
+	Name    string           `json:"name"`
+	Team    string           `json:"team"`
+}
+
+type Desk struct {
+	Id             storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	FloorId        string           `json:"floor_id"`
+	NeighborhoodId string           `json:"neighborhood_id"`
+	Label          string           `json:"label"`
+	X              float64          `json:"x"`
+	Y              float64          `json:"y"`
+	Amenities      []string         `json:"amenities"`
+}
+
+type DeskBooking struct {
+	Id     storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	DeskId string           `json:"desk_id"`
+	User   string           `json:"user"`
+	Team   string           `json:"team"`
+	Date   string           `json:"date"`
+	Slot   string           `json:"slot"`
+}
+
+type DeskStatus struct {
+	Desk
+	Bookings []DeskBooking `json:"bookings"`
+}
+
+type FloorMap struct {
+	Floor         Floor          `json:"floor"`
+	Date          string         `json:"date"`
+	Neighborhoods []Neighborhood `json:"neighborhoods"`
+	Desks         []DeskStatus   `json:"desks"`
+}
+
+// slotsOverlap reports whether two slots of the same day collide.
+func slotsOverlap(a string, b string) bool {
+	return a == b || a == SlotFullDay || b == SlotFullDay
+}
+
+// dayParam returns the ?date= query parameter, defaulting to today.
+func dayParam(r *http.Request) (string, bool) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		return clockNow().In(defaultLocation).Format("2006-01-02"), true
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return date, false
+	}
+
+	return date, true
+}
+
+// Repo Floor
+type FloorRepo struct {
+	coll *storage.Collection
+}
+
+func (r *FloorRepo) All() ([]Floor, error) {
+	result := []Floor{}
+	err := r.coll.Find(nil).Sort("venueid", "level").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *FloorRepo) Find(id string) (Floor, error) {
+	result := Floor{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *FloorRepo) Create(floor *Floor) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, floor)
+	if err != nil {
+		return err
+	}
+
+	floor.Id = id
+
+	return nil
+}
+
+func (r *FloorRepo) Update(floor *Floor) error {
+	err := r.coll.UpdateId(floor.Id, floor)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *FloorRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo Neighborhood
+type NeighborhoodRepo struct {
+	coll *storage.Collection
+}
+
+func (r *NeighborhoodRepo) AllByFloorId(floorId string) ([]Neighborhood, error) {
+	result := []Neighborhood{}
+	err := r.coll.Find(bson.M{"floorid": floorId}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *NeighborhoodRepo) Find(id string) (Neighborhood, error) {
+	result := Neighborhood{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *NeighborhoodRepo) Create(neighborhood *Neighborhood) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, neighborhood)
+	if err != nil {
+		return err
+	}
+
+	neighborhood.Id = id
+
+	return nil
+}
+
+func (r *NeighborhoodRepo) Update(neighborhood *Neighborhood) error {
+	err := r.coll.UpdateId(neighborhood.Id, neighborhood)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *NeighborhoodRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo Desk
+type DeskRepo struct {
+	coll *storage.Collection
+}
+
+func (r *DeskRepo) AllByFloorId(floorId string) ([]Desk, error) {
+	result := []Desk{}
+	err := r.coll.Find(bson.M{"floorid": floorId}).Sort("label").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeskRepo) Find(id string) (Desk, error) {
+	result := Desk{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeskRepo) Create(desk *Desk) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, desk)
+	if err != nil {
+		return err
+	}
+
+	desk.Id = id
+
+	return nil
+}
+
+func (r *DeskRepo) Update(desk *Desk) error {
+	err := r.coll.UpdateId(desk.Id, desk)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *DeskRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo DeskBooking
+type DeskBookingRepo struct {
+	coll *storage.Collection
+}
+
+func (r *DeskBookingRepo) AllByDate(deskIds []string, date string) ([]DeskBooking, error) {
+	result := []DeskBooking{}
+	err := r.coll.Find(bson.M{"deskid": bson.M{"$in": deskIds}, "date": date}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeskBookingRepo) AllByUser(user string, date string) ([]DeskBooking, error) {
+	result := []DeskBooking{}
+	query := bson.M{"user": user}
+	if date != "" {
+		query["date"] = date
+	}
+
+	err := r.coll.Find(query).Sort("date").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeskBookingRepo) Find(id string) (DeskBooking, error) {
+	result := DeskBooking{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *DeskBookingRepo) Create(booking *DeskBooking) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, booking)
+	if err != nil {
+		return err
+	}
+
+	booking.Id = id
+
+	return nil
+}
+
+func (r *DeskBookingRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// floorStatus loads the desks of a floor together with their bookings on
+// date.
+func (c *appContext) floorStatus(floorId string, date string) ([]DeskStatus, error) {
+	deskRepo := DeskRepo{c.db.C("desks")}
+	desks, err := deskRepo.AllByFloorId(floorId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, desk := range desks {
+		ids = append(ids, desk.Id.Hex())
+	}
+
+	bookingRepo := DeskBookingRepo{c.db.C("desk_bookings")}
+	bookings, err := bookingRepo.AllByDate(ids, date)
+	if err != nil {
+		return nil, err
+	}
+
+	byDesk := map[string][]DeskBooking{}
+	for _, booking := range bookings {
+		byDesk[booking.DeskId] = append(byDesk[booking.DeskId], booking)
+	}
+
+	result := []DeskStatus{}
+	for _, desk := range desks {
+		deskBookings := byDesk[desk.Id.Hex()]
+		if deskBookings == nil {
+			deskBookings = []DeskBooking{}
+		}
+		result = append(result, DeskStatus{desk, deskBookings})
+	}
+
+	return result, nil
+}
+
+// Floor Handlers
+func (c *appContext) floorsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := FloorRepo{c.db.C("floors")}
+	floors, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, floors)
+}
+
+func (c *appContext) floorHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := FloorRepo{c.db.C("floors")}
+	floor, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, floor)
+}
+
+func (c *appContext) createFloorHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*Floor)
+	repo := FloorRepo{c.db.C("floors")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("floor", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateFloorHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Floor)
+	body.Id = storage.ObjectIdHex(params.ByName("id"))
+	repo := FloorRepo{c.db.C("floors")}
+	err := repo.Update(body)
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("floor", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteFloorHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := FloorRepo{c.db.C("floors")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("floor", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Floor has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+func (c *appContext) floorMapHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	date, ok := dayParam(r)
+	if !ok {
+		WriteError(w, ErrInvalidDate)
+		return
+	}
+
+	repo := FloorRepo{c.db.C("floors")}
+	floor, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	neighborhoodRepo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	neighborhoods, err := neighborhoodRepo.AllByFloorId(floor.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+
+	desks, err := c.floorStatus(floor.Id.Hex(), date)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, FloorMap{floor, date, neighborhoods, desks})
+}
+
+// availableDesksHandler lists the desks of a floor that are free for
+// ?date= and ?slot=. With ?team= desks in the team's neighborhoods come
+// first, then desks closest to where teammates sit that day.
+func (c *appContext) availableDesksHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	date, ok := dayParam(r)
+	if !ok {
+		WriteError(w, ErrInvalidDate)
+		return
+	}
+	slot := r.URL.Query().Get("slot")
+	if slot == "" {
+		slot = SlotFullDay
+	}
+	if slot != SlotMorning && slot != SlotAfternoon && slot != SlotFullDay {
+		WriteError(w, ErrInvalidSlot)
+		return
+	}
+	team := r.URL.Query().Get("team")
+
+	desks, err := c.floorStatus(params.ByName("id"), date)
+	if err != nil {
+		panic(err)
+	}
+
+	neighborhoodRepo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	neighborhoods, err := neighborhoodRepo.AllByFloorId(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+	teamNeighborhoods := map[string]bool{}
+	for _, n := range neighborhoods {
+		if team != "" && n.Team == team {
+			teamNeighborhoods[n.Id.Hex()] = true
+		}
+	}
+
+	teammates := []Desk{}
+	free := []Desk{}
+	for _, desk := range desks {
+		available := true
+		for _, booking := range desk.Bookings {
+			if team != "" && booking.Team == team {
+				teammates = append(teammates, desk.Desk)
+			}
+			if slotsOverlap(slot, booking.Slot) {
+				available = false
+			}
+		}
+		if available {
+			free = append(free, desk.Desk)
+		}
+	}
+
+	distance := func(desk Desk) float64 {
+		best := math.Inf(1)
+		for _, mate := range teammates {
+			if d := math.Hypot(desk.X-mate.X, desk.Y-mate.Y); d < best {
+				best = d
+			}
+		}
+		return best
+	}
+
+	sort.SliceStable(free, func(i, j int) bool {
+		ti, tj := teamNeighborhoods[free[i].NeighborhoodId], teamNeighborhoods[free[j].NeighborhoodId]
+		if ti != tj {
+			return ti
+		}
+		return distance(free[i]) < distance(free[j])
+	})
+
+	WriteSuccess(w, http.StatusOK, free)
+}
+
+// Neighborhood Handlers
+func (c *appContext) neighborhoodsHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	neighborhoods, err := repo.AllByFloorId(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, neighborhoods)
+}
+
+func (c *appContext) createNeighborhoodHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Neighborhood)
+	body.FloorId = params.ByName("id")
+	repo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateNeighborhoodHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Neighborhood)
+	repo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	neighborhood, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	body.Id = neighborhood.Id
+	body.FloorId = neighborhood.FloorId
+	err = repo.Update(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteNeighborhoodHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := NeighborhoodRepo{c.db.C("neighborhoods")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "Neighborhood has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// Desk Handlers
+func (c *appContext) desksHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := DeskRepo{c.db.C("desks")}
+	desks, err := repo.AllByFloorId(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, desks)
+}
+
+func (c *appContext) deskHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := DeskRepo{c.db.C("desks")}
+	desk, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, desk)
+}
+
+func (c *appContext) createDeskHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Desk)
+	body.FloorId = params.ByName("id")
+	repo := DeskRepo{c.db.C("desks")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("desk", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateDeskHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Desk)
+	repo := DeskRepo{c.db.C("desks")}
+	desk, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	body.Id = desk.Id
+	body.FloorId = desk.FloorId
+	err = repo.Update(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("desk", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteDeskHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := DeskRepo{c.db.C("desks")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("desk", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Desk has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// Desk Booking Handlers
+func (c *appContext) deskBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.URL.Query().Get("user")
+	if user == "" {
+		WriteError(w, ErrMissingUser)
+		return
+	}
+
+	repo := DeskBookingRepo{c.db.C("desk_bookings")}
+	bookings, err := repo.AllByUser(user, r.URL.Query().Get("date"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, bookings)
+}
+
+func (c *appContext) createDeskBookingHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*DeskBooking)
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && body.User != user.Email {
+		WriteError(w, ErrNotBookingOwner)
+		return
+	}
+
+	deskRepo := DeskRepo{c.db.C("desks")}
+	desk, err := deskRepo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	body.DeskId = desk.Id.Hex()
+
+	repo := DeskBookingRepo{c.db.C("desk_bookings")}
+	existing, err := repo.AllByDate([]string{body.DeskId}, body.Date)
+	if err != nil {
+		panic(err)
+	}
+	for _, booking := range existing {
+		if slotsOverlap(body.Slot, booking.Slot) {
+			WriteError(w, ErrDeskTaken)
+			return
+		}
+	}
+
+	mine, err := repo.AllByUser(body.User, body.Date)
+	if err != nil {
+		panic(err)
+	}
+	for _, booking := range mine {
+		if slotsOverlap(body.Slot, booking.Slot) {
+			WriteError(w, ErrDoubleDeskBooking)
+			return
+		}
+	}
+
+	err = repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("desk_booking", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) deleteDeskBookingHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	if !storage.IsObjectIdHex(params.ByName("id")) {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	repo := DeskBookingRepo{c.db.C("desk_bookings")}
+	booking, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && booking.User != user.Email {
+		WriteError(w, ErrNotBookingOwner)
+		return
+	}
+
+	err = repo.Delete(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("desk_booking", booking.Id, ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Desk booking has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Dry runs
+//
+// Event create and update accept ?dry_run=true, or a "Prefer: dry-run"
+// header, to run every check they would run and answer with the event as it
+// would be stored, without storing it or bumping anything. A dry run answers
+// 200 with "Preference-Applied: dry-run", or the error the real request would
+// get.
+func dryRun(r *http.Request) bool {
+	if r.URL.Query().Get("dry_run") == "true" {
+		return true
+	}
+	for _, pref := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.TrimSpace(pref) == "dry-run" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dryRunConflict returns the booking that would make storing event fail,
+// leaving out the tentative ones it would bump.
+func (c *appContext) dryRunConflict(ctx context.Context, event Event) *Event {
+	_, bumped := c.bumpable(ctx, event)
+	if len(bumped) > 0 {
+		return nil
+	}
+
+	repo := c.events()
+	err := repo.CheckConflict(ctx, &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		return &conflict.Event
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return nil
+}
+
+func writeDryRun(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Preference-Applied", "dry-run")
+	WriteSuccess(w, http.StatusOK, data)
+}
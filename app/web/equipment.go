@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Repo Equipment
+//
+// Equipment is pooled per venue (projectors, VC kits, portable chairs) and
+// booked with a quantity on events. An item is available for a window when
+// the peak number of units booked by overlapping events plus the requested
+// quantity doesn't exceed the pool.
+type Equipment struct {
+	Id       storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name     string           `json:"name"`
+	VenueId  string           `json:"venue_id"`
+	Quantity int              `json:"quantity"`
+}
+
+type EquipmentAvailability struct {
+	Equipment Equipment `json:"equipment"`
+	Booked    int       `json:"booked"`
+	Available int       `json:"available"`
+}
+
+type EquipmentRepo struct {
+	coll *storage.Collection
+}
+
+func (r *EquipmentRepo) All() ([]Equipment, error) {
+	result := []Equipment{}
+	err := r.coll.Find(nil).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *EquipmentRepo) AllByVenueId(venueId string) ([]Equipment, error) {
+	result := []Equipment{}
+	err := r.coll.Find(bson.M{"venueid": venueId}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *EquipmentRepo) Find(id string) (Equipment, error) {
+	result := Equipment{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *EquipmentRepo) Create(equipment *Equipment) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, equipment)
+	if err != nil {
+		return err
+	}
+
+	equipment.Id = id
+
+	return nil
+}
+
+func (r *EquipmentRepo) Update(equipment *Equipment) error {
+	err := r.coll.UpdateId(equipment.Id, equipment)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *EquipmentRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// peakEquipmentUsage returns the highest number of units of equipmentId
+// booked at the same time by events within [start_time, end_time).
+func peakEquipmentUsage(events []Event, equipmentId string, start_time time.Time, end_time time.Time) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+
+	edges := []edge{}
+	for _, event := range events {
+		quantity := 0
+		for _, booking := range event.Equipment {
+			if booking.EquipmentId == equipmentId {
+				quantity += booking.Quantity
+			}
+		}
+		if quantity == 0 {
+			continue
+		}
+
+		from, to := event.StartTime, event.EndTime
+		if from.Before(start_time) {
+			from = start_time
+		}
+		if to.After(end_time) {
+			to = end_time
+		}
+		edges = append(edges, edge{from, quantity}, edge{to, -quantity})
+	}
+
+	// Ends sort before starts at the same instant so back-to-back bookings
+	// don't count as overlapping.
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+
+	peak, current := 0, 0
+	for _, e := range edges {
+		current += e.delta
+		if current > peak {
+			peak = current
+		}
+	}
+
+	return peak
+}
+
+// checkEquipment verifies every item booked by event is available for its
+// time window. It returns the error to send to the client, or nil.
+func (c *appContext) checkEquipment(ctx context.Context, event *Event) *Error {
+	if len(event.Equipment) == 0 {
+		return nil
+	}
+
+	equipmentRepo := EquipmentRepo{c.db.C("equipment")}
+	repo := c.events()
+
+	requested := map[string]int{}
+	ids := []string{}
+	for _, booking := range event.Equipment {
+		if _, ok := requested[booking.EquipmentId]; !ok {
+			ids = append(ids, booking.EquipmentId)
+		}
+		requested[booking.EquipmentId] += booking.Quantity
+	}
+
+	for _, id := range ids {
+		if !storage.IsObjectIdHex(id) {
+			return ErrUnknownEquipment
+		}
+		equipment, err := equipmentRepo.Find(id)
+		if err == storage.ErrNotFound {
+			return ErrUnknownEquipment
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		events, err := repo.AllByEquipment(ctx, id, event.StartTime, event.EndTime, event.Id)
+		if err != nil {
+			panic(err)
+		}
+
+		booked := peakEquipmentUsage(events, id, event.StartTime, event.EndTime)
+		if booked+requested[id] > equipment.Quantity {
+			available := equipment.Quantity - booked
+			if available < 0 {
+				available = 0
+			}
+			return &Error{
+				"equipment_exhausted",
+				http.StatusConflict,
+				"Conflict",
+				fmt.Sprintf("%s is exhausted: %d requested, %d of %d available.", equipment.Name, requested[id], available, equipment.Quantity),
+			}
+		}
+	}
+
+	return nil
+}
+
+// Equipment Handlers
+func (c *appContext) equipmentListHandler(w http.ResponseWriter, r *http.Request) {
+	repo := EquipmentRepo{c.db.C("equipment")}
+	var equipment []Equipment
+	var err error
+	if venueId := r.URL.Query().Get("venue_id"); venueId != "" {
+		equipment, err = repo.AllByVenueId(venueId)
+	} else {
+		equipment, err = repo.All()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, equipment)
+}
+
+func (c *appContext) equipmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := EquipmentRepo{c.db.C("equipment")}
+	equipment, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, equipment)
+}
+
+func (c *appContext) createEquipmentHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*Equipment)
+	repo := EquipmentRepo{c.db.C("equipment")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("equipment", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateEquipmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Equipment)
+	body.Id = storage.ObjectIdHex(params.ByName("id"))
+	repo := EquipmentRepo{c.db.C("equipment")}
+	err := repo.Update(body)
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("equipment", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteEquipmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := EquipmentRepo{c.db.C("equipment")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("equipment", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Equipment has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+func (c *appContext) equipmentAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	equipmentRepo := EquipmentRepo{c.db.C("equipment")}
+	equipment, err := equipmentRepo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	repo := c.events()
+	events, err := repo.AllByEquipment(r.Context(), equipment.Id.Hex(), start_time, end_time, "")
+	if err != nil {
+		panic(err)
+	}
+
+	booked := peakEquipmentUsage(events, equipment.Id.Hex(), start_time, end_time)
+	available := equipment.Quantity - booked
+	if available < 0 {
+		available = 0
+	}
+
+	WriteSuccess(w, http.StatusOK, EquipmentAvailability{equipment, booked, available})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo errors
+//
+// The venue, room and event repos return these instead of driver errors or
+// panics on malformed ids. Handlers answer with the matching Error through
+// WriteRepoError; any other error is unexpected and answered with a 500,
+// unless it's a transient one from a failover (see failover.go). They're
+// the ones of internal/storage.
+var (
+	ErrDocumentNotFound    = storage.ErrNotFound
+	ErrInvalidId           = storage.ErrInvalidId
+	ErrConflict            = storage.ErrConflict
+	ErrRetryableWrite      = storage.ErrRetryableWrite
+	ErrDatabaseUnavailable = storage.ErrDatabaseUnavailable
+)
+
+// repoError translates a driver error into a repo error.
+func repoError(err error) error {
+	return storage.Error(err)
+}
+
+// objectId parses id, which comes from a request and may be malformed.
+func objectId(id string) (storage.ObjectId, error) {
+	return storage.ParseObjectId(id)
+}
+
+func WriteRepoError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrDocumentNotFound:
+		WriteError(w, ErrNotFound)
+	case ErrInvalidId:
+		WriteError(w, ErrMalformedId)
+	case ErrConflict:
+		WriteError(w, ErrDuplicate)
+	default:
+		if isTransient(err) {
+			writeUnavailable(w, err)
+			return
+		}
+		if rec, ok := w.(*routeRecorder); ok {
+			log.Printf("repo error: request_id=%s %v", rec.requestId, err)
+		} else {
+			log.Printf("repo error: %v", err)
+		}
+		WriteError(w, ErrInternalServer)
+	}
+}
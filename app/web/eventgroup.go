@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Event groups
+//
+// A conference or training day spanning several rooms and sessions is an
+// EventGroup. Each session stays an ordinary event, booked and checked for
+// conflicts like any other, with GroupId pointing at its group. The group
+// lists its sessions with attendee counts, and its owner (or an admin) can
+// move every session by the same offset with POST /event-groups/:id/reschedule
+// or cancel them all with POST /event-groups/:id/cancel. Rescheduling is all
+// or nothing: if any session would conflict with an event outside the group,
+// none is moved.
+type EventGroup struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Owner       string           `json:"owner"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type EventGroupStats struct {
+	Sessions          int       `json:"sessions"`
+	Rooms             int       `json:"rooms"`
+	Attendees         int       `json:"attendees"`
+	UniqueAttendees   int       `json:"unique_attendees"`
+	CheckedInSessions int       `json:"checked_in_sessions"`
+	StartTime         time.Time `json:"start_time"`
+	EndTime           time.Time `json:"end_time"`
+}
+
+type EventGroupResponse struct {
+	EventGroup
+	Stats    EventGroupStats `json:"stats"`
+	Sessions []Event         `json:"sessions"`
+}
+
+type EventGroupReschedule struct {
+	// OffsetMinutes moves every session, later when positive.
+	OffsetMinutes int `json:"offset_minutes"`
+}
+
+// CanManageGroup reports whether u may change or cancel the sessions of
+// group.
+func (u User) CanManageGroup(group EventGroup) bool {
+	return u.IsAdmin() || group.Owner == u.Email
+}
+
+func groupStats(sessions []Event) EventGroupStats {
+	stats := EventGroupStats{Sessions: len(sessions)}
+	rooms := map[string]bool{}
+	people := map[string]bool{}
+	for _, session := range sessions {
+		rooms[session.LocationID] = true
+		stats.Attendees += len(session.Guests) + 1
+		people[session.Owner] = true
+		for _, guest := range session.Guests {
+			people[guest] = true
+		}
+		if !session.CheckedInAt.IsZero() {
+			stats.CheckedInSessions++
+		}
+		if stats.StartTime.IsZero() || session.StartTime.Before(stats.StartTime) {
+			stats.StartTime = session.StartTime
+		}
+		if session.EndTime.After(stats.EndTime) {
+			stats.EndTime = session.EndTime
+		}
+	}
+	stats.Rooms = len(rooms)
+	stats.UniqueAttendees = len(people)
+
+	return stats
+}
+
+// Repo EventGroup
+type EventGroupRepo struct {
+	coll *storage.Collection
+}
+
+func (r *EventGroupRepo) All() ([]EventGroup, error) {
+	result := []EventGroup{}
+	err := r.coll.Find(nil).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *EventGroupRepo) Find(id string) (EventGroup, error) {
+	result := EventGroup{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *EventGroupRepo) Create(group *EventGroup) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, group)
+	if err != nil {
+		return err
+	}
+
+	group.Id = id
+
+	return nil
+}
+
+func (r *EventGroupRepo) Update(group *EventGroup) error {
+	err := r.coll.UpdateId(group.Id, group)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *EventGroupRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkGroup makes sure the event group with groupId exists and user may add
+// sessions to it.
+func (c *appContext) checkGroup(user User, groupId string) *Error {
+	if groupId == "" {
+		return nil
+	}
+	if !storage.IsObjectIdHex(groupId) {
+		return ErrUnknownGroup
+	}
+
+	repo := EventGroupRepo{c.db.C("event_groups")}
+	group, err := repo.Find(groupId)
+	if err == storage.ErrNotFound {
+		return ErrUnknownGroup
+	}
+	if err != nil {
+		panic(err)
+	}
+	if !user.CanManageGroup(group) {
+		return ErrNotGroupOwner
+	}
+
+	return nil
+}
+
+// findGroup answers with an error and returns false when the group at :id
+// can't be found.
+func (c *appContext) findGroup(w http.ResponseWriter, r *http.Request) (EventGroup, bool) {
+	params := routeParams(r)
+	if !storage.IsObjectIdHex(params.ByName("id")) {
+		WriteError(w, ErrNotFound)
+		return EventGroup{}, false
+	}
+
+	repo := EventGroupRepo{c.db.C("event_groups")}
+	group, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return group, false
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return group, true
+}
+
+// rescheduleConflict returns the first event outside of sessions that one of
+// them would conflict with once moved.
+func (c *appContext) rescheduleConflict(ctx context.Context, sessions []Event) *Event {
+	moving := map[storage.ObjectId]bool{}
+	for _, session := range sessions {
+		moving[session.Id] = true
+	}
+
+	repo := c.events()
+	for _, session := range sessions {
+		if session.LocationID == "" {
+			continue
+		}
+		end_time := session.EndTime
+		if session.Recurrence != nil {
+			end_time = session.SeriesEndTime
+		}
+		others, err := repo.Overlapping(ctx, session.LocationID, session.StartTime, end_time, session.Id)
+		if err != nil {
+			panic(err)
+		}
+		for _, occurrence := range session.Occurrences(session.StartTime, end_time) {
+			for _, other := range others {
+				if moving[other.Id] {
+					continue
+				}
+				if other.StartTime.Before(occurrence.EndTime) && other.EndTime.After(occurrence.StartTime) && !session.Replaces(other) {
+					return &other
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Event Group Handlers
+func (c *appContext) eventGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := EventGroupRepo{c.db.C("event_groups")}
+	groups, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, groups)
+}
+
+func (c *appContext) eventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	group, ok := c.findGroup(w, r)
+	if !ok {
+		return
+	}
+
+	repo := c.events()
+	sessions, err := repo.AllByGroupId(r.Context(), group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, EventGroupResponse{group, groupStats(sessions), sessions})
+}
+
+func (c *appContext) createEventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*EventGroup)
+	user := r.Context().Value(userKey).(User)
+	if body.Owner == "" || !user.IsAdmin() {
+		body.Owner = user.Email
+	}
+	body.Description = sanitizeDescription(body.Description)
+	body.CreatedAt = time.Now()
+
+	repo := EventGroupRepo{c.db.C("event_groups")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event_group", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateEventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*EventGroup)
+	group, ok := c.findGroup(w, r)
+	if !ok {
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManageGroup(group) {
+		WriteError(w, ErrNotGroupOwner)
+		return
+	}
+
+	body.Id = group.Id
+	body.CreatedAt = group.CreatedAt
+	if body.Owner == "" || !user.IsAdmin() {
+		body.Owner = group.Owner
+	}
+	body.Description = sanitizeDescription(body.Description)
+
+	repo := EventGroupRepo{c.db.C("event_groups")}
+	err := repo.Update(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event_group", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+// deleteEventGroupHandler deletes the group but keeps its sessions as
+// standalone events.
+func (c *appContext) deleteEventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	group, ok := c.findGroup(w, r)
+	if !ok {
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManageGroup(group) {
+		WriteError(w, ErrNotGroupOwner)
+		return
+	}
+
+	repo := c.events()
+	sessions, err := repo.AllByGroupId(r.Context(), group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	err = repo.Ungroup(r.Context(), group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	for _, session := range sessions {
+		c.recordChange("event", session.Id, ChangeUpdated)
+	}
+
+	groupRepo := EventGroupRepo{c.db.C("event_groups")}
+	err = groupRepo.Delete(group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event_group", group.Id, ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Event group has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+func (c *appContext) rescheduleEventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*EventGroupReschedule)
+	group, ok := c.findGroup(w, r)
+	if !ok {
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManageGroup(group) {
+		WriteError(w, ErrNotGroupOwner)
+		return
+	}
+
+	repo := c.events()
+	sessions, err := repo.AllByGroupId(r.Context(), group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+
+	offset := time.Duration(body.OffsetMinutes) * time.Minute
+	grouped := map[storage.ObjectId]bool{}
+	for _, session := range sessions {
+		grouped[session.Id] = true
+	}
+	moved := []Event{}
+	for _, session := range sessions {
+		session.StartTime = session.StartTime.Add(offset)
+		session.EndTime = session.EndTime.Add(offset)
+		if session.Recurrence != nil {
+			recurrence := *session.Recurrence
+			if !recurrence.Until.IsZero() {
+				recurrence.Until = recurrence.Until.Add(offset)
+			}
+			recurrence.Exceptions = []time.Time{}
+			for _, t := range session.Recurrence.Exceptions {
+				recurrence.Exceptions = append(recurrence.Exceptions, t.Add(offset))
+			}
+			session.SetRecurrence(&recurrence)
+		}
+		if grouped[session.RecurringEventId] {
+			// Changed occurrences keep replacing their occurrence, which
+			// moves with the recurring event.
+			session.OriginalStartTime = session.OriginalStartTime.Add(offset)
+		}
+		moved = append(moved, session)
+	}
+
+	if conflict := c.rescheduleConflict(r.Context(), moved); conflict != nil {
+		WriteError(w, conflictError(*conflict))
+		return
+	}
+	if dryRun(r) {
+		writeDryRun(w, EventGroupResponse{group, groupStats(moved), moved})
+		return
+	}
+
+	for idx := range moved {
+		err = repo.Replace(r.Context(), &moved[idx])
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		c.recordChange("event", moved[idx].Id, ChangeUpdated)
+		notifyEvent(NotifyUpdated, moved[idx])
+	}
+	for _, session := range sessions {
+		if session.Recurrence == nil {
+			c.recordFreedSlot(session.LocationID, session.StartTime, session.EndTime)
+		}
+	}
+
+	WriteSuccess(w, http.StatusAccepted, EventGroupResponse{group, groupStats(moved), moved})
+}
+
+func (c *appContext) cancelEventGroupHandler(w http.ResponseWriter, r *http.Request) {
+	group, ok := c.findGroup(w, r)
+	if !ok {
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManageGroup(group) {
+		WriteError(w, ErrNotGroupOwner)
+		return
+	}
+
+	repo := c.events()
+	sessions, err := repo.AllByGroupId(r.Context(), group.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Recurrence != nil && sessions[j].Recurrence == nil })
+
+	for _, session := range sessions {
+		err = repo.Delete(r.Context(), session.Id.Hex())
+		if err == ErrDocumentNotFound {
+			// Deleted with the recurring event it was changed from.
+			continue
+		}
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		c.recordChange("event", session.Id, ChangeDeleted)
+		notifyEvent(NotifyCancelled, session)
+		c.deleteDetached(r.Context(), session)
+		c.recordLateCancel(session)
+		if session.Recurrence == nil {
+			c.recordFreedSlot(session.LocationID, session.StartTime, session.EndTime)
+		}
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "Event group sessions have been cancelled successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
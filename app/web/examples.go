@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Examples
+//
+// GET /examples/:resource serves a request body the API accepts for the
+// schema named resource, and for venues, rooms and events the response
+// creating it answers with. Request bodies are generated from the schemas in
+// schema.go, the ones requests are validated against: every required field,
+// plus the optional ones with a value in exampleHints. Each example is checked
+// against its schema when the server starts, so a schema change that breaks
+// one fails loudly instead of leaving integrators with a stale sample.
+// GET /examples lists the resources.
+const (
+	exampleObjectId = "5f1d7a3c9e2b4a0012345678"
+	exampleOwner    = "user@example.com"
+	objectIdPattern = "^[0-9a-fA-F]{24}$"
+)
+
+type Example struct {
+	Resource string      `json:"resource"`
+	Request  interface{} `json:"request"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+// exampleStart is when example events start: 10:00 UTC+7 on the next
+// weekday after t.
+func exampleStart(t time.Time) time.Time {
+	loc := defaultLocation
+	day := t.In(loc).AddDate(0, 0, 1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, loc)
+}
+
+// exampleHints are the values of fields, by resource and path, that can't be
+// told from their schema or read better than a generated one.
+func exampleHints(t time.Time) map[string]interface{} {
+	start := exampleStart(t)
+	end := start.Add(time.Hour)
+
+	return map[string]interface{}{
+		"venue.name":        "Head Office",
+		"room.name":         "Board Room",
+		"room.names":        map[string]interface{}{"ja": "会議室", "id": "Ruang Rapat"},
+		"room.building":     "Tower A",
+		"room.floor":        "12",
+		"room.amenities":    []interface{}{"projector", "whiteboard"},
+		"event.name":        "Weekly sync",
+		"event.location":    "Board Room",
+		"event.description": "Agenda in the team doc.",
+		"event.guests":      []interface{}{"guest@example.com"},
+		"event.start_time":  start.Format(time.RFC3339),
+		"event.end_time":    end.Format(time.RFC3339),
+
+		"check_in.code":                     "123456",
+		"room_suggestion.start_time":        start.Format(time.RFC3339),
+		"room_suggestion.end_time":          end.Format(time.RFC3339),
+		"room_suggestion.amenities":         []interface{}{"projector"},
+		"free_busy.start_time":              start.Format(time.RFC3339),
+		"free_busy.end_time":                start.Add(8 * time.Hour).Format(time.RFC3339),
+		"free_busy.users":                   []interface{}{exampleOwner},
+		"desk_booking.date":                 start.Format("2006-01-02"),
+		"parking_reservation.license_plate": "B 1234 XYZ",
+		"working_hours.days.start":          "09:00",
+		"working_hours.days.end":            "17:00",
+		"webhook.url":                       "https://hooks.example.com/ivana",
+		"webhook.events":                    []interface{}{"event.created", "event.cancelled"},
+	}
+}
+
+// exampleValue generates a value of s for the field at path of resource.
+func exampleValue(s *Schema, resource string, path string, hints map[string]interface{}, t time.Time) interface{} {
+	if v, ok := hints[resource+"."+path]; ok {
+		return v
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+
+	switch s.Type {
+	case "string":
+		switch {
+		case s.Format == "email":
+			return exampleOwner
+		case s.Format == "date-time":
+			return exampleStart(t).Format(time.RFC3339)
+		case s.Pattern == objectIdPattern:
+			return exampleObjectId
+		case s.Pattern != "":
+			return "example"
+		}
+		name := path[strings.LastIndex(path, ".")+1:]
+		return "Example " + strings.Replace(name, "_", " ", -1)
+	case "integer", "number":
+		n := 1.0
+		if s.Minimum != nil && *s.Minimum > n {
+			n = *s.Minimum
+		}
+		if s.Maximum != nil && *s.Maximum < n {
+			n = *s.Maximum
+		}
+		return n
+	case "boolean":
+		return false
+	case "array":
+		if s.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{exampleValue(s.Items, resource, path, hints, t)}
+	case "object":
+		result := map[string]interface{}{}
+		for name, p := range s.Properties {
+			field := joinPath(path, name)
+			_, hinted := hints[resource+"."+field]
+			if hinted || contains(s.Required, name) {
+				result[name] = exampleValue(p, resource, field, hints, t)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
+
+// exampleResponses turn a request example into what creating it answers
+// with, the way the create handlers do.
+var exampleResponses = map[string]func(b []byte) (interface{}, error){
+	"venue": func(b []byte) (interface{}, error) {
+		venue := Venue{}
+		err := json.Unmarshal(b, &venue)
+		venue.Id = storage.ObjectIdHex(exampleObjectId)
+		venue.Slug = slugify(venue.Name)
+		return venue, err
+	},
+	"room": func(b []byte) (interface{}, error) {
+		room := Room{}
+		err := json.Unmarshal(b, &room)
+		room.Id = storage.ObjectIdHex(exampleObjectId)
+		room.Slug = slugify(room.Name)
+		return room, err
+	},
+	"event": func(b []byte) (interface{}, error) {
+		body := EventResponse{}
+		err := json.Unmarshal(b, &body)
+		if err != nil {
+			return nil, err
+		}
+		start_time, end_time, err := body.times(defaultLocation)
+		event := Event{
+			Id:          storage.ObjectIdHex(exampleObjectId),
+			Name:        body.Name,
+			LocationID:  body.LocationID,
+			Location:    body.Location,
+			Description: body.Description,
+			Guests:      body.Guests,
+			Owner:       exampleOwner,
+			StartTime:   start_time,
+			EndTime:     end_time,
+			BookedVia:   BookingSource{Channel: ChannelWeb},
+		}
+		return event, err
+	},
+}
+
+// example builds the example of resource as of t.
+func example(resource string, t time.Time) (Example, error) {
+	s, ok := schemas[resource]
+	if !ok {
+		return Example{}, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	request := exampleValue(s, resource, "", exampleHints(t), t)
+
+	// Round trip through JSON so the example is checked the way a request
+	// body is.
+	b, err := json.Marshal(request)
+	if err != nil {
+		return Example{}, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return Example{}, err
+	}
+	if msgs := s.Validate(v); len(msgs) > 0 {
+		return Example{}, fmt.Errorf("example %s: %s", resource, strings.Join(msgs, "; "))
+	}
+
+	ex := Example{Resource: resource, Request: v}
+	if respond, ok := exampleResponses[resource]; ok {
+		ex.Response, err = respond(b)
+		if err != nil {
+			return Example{}, fmt.Errorf("example %s: %v", resource, err)
+		}
+	}
+
+	return ex, nil
+}
+
+// checkExamples builds every example, failing on the first that doesn't
+// validate.
+func checkExamples() error {
+	for name := range schemaSources {
+		if _, err := example(name, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Example Handlers
+func (c *appContext) examplesHandler(w http.ResponseWriter, r *http.Request) {
+	result := []string{}
+	for name := range schemaSources {
+		result = append(result, "/examples/"+name)
+	}
+	sort.Strings(result)
+
+	WriteSuccess(w, http.StatusOK, result)
+}
+
+func (c *appContext) exampleHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	if _, ok := schemas[params.ByName("resource")]; !ok {
+		WriteError(w, ErrNotFound)
+		return
+	}
+
+	ex, err := example(params.ByName("resource"), time.Now())
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, ex)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exports
+//
+// GET /events/export streams the events starting within ?start_time= and
+// ?end_time= (this week by default), with the occurrences of recurring
+// events, one per row in the time zone of their venue. The utilization
+// reports of analytics.go export the same way with ?format=. format is csv,
+// the default, or xlsx, a single sheet workbook; either comes as an
+// attachment named after the report and its window. Text cells of a CSV
+// starting like a formula are prefixed with ' so spreadsheets don't run
+// them.
+var ErrInvalidFormat = &Error{"invalid_format", 400, "Bad request", "format must be csv or xlsx."}
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// table writes the rows of an export, cells being strings, numbers, bools
+// or times.
+type table interface {
+	Write(row ...interface{}) error
+	Close() error
+}
+
+// exportFormat returns the ?format= of r.
+func exportFormat(r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		return "csv", true
+	case "csv", "xlsx":
+		return format, true
+	}
+
+	return "", false
+}
+
+// newTable starts the response to r as an attachment named name in format,
+// with the header row columns.
+func newTable(w http.ResponseWriter, format string, name string, columns ...interface{}) (table, error) {
+	var t table
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", xlsxContentType)
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+".xlsx\"")
+		w.WriteHeader(http.StatusOK)
+		xt, err := newXLSXTable(w, name)
+		if err != nil {
+			return nil, err
+		}
+		t = xt
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+".csv\"")
+		w.WriteHeader(http.StatusOK)
+		t = &csvTable{csv.NewWriter(w)}
+	}
+
+	return t, t.Write(columns...)
+}
+
+// exportName names the export of report over [start_time, end_time).
+func exportName(report string, start_time time.Time, end_time time.Time) string {
+	return report + "-" + start_time.Format("2006-01-02") + "-" + end_time.Format("2006-01-02")
+}
+
+// CSV
+type csvTable struct {
+	w *csv.Writer
+}
+
+func (t *csvTable) Write(row ...interface{}) error {
+	record := []string{}
+	for _, cell := range row {
+		s := cellText(cell)
+		if _, ok := cell.(string); ok && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+			s = "'" + s
+		}
+		record = append(record, s)
+	}
+
+	return t.w.Write(record)
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func cellText(cell interface{}) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(cell)
+}
+
+// XLSX
+//
+// The workbook is written as it goes: the package parts first, then the
+// rows of its sheet as inline strings and numbers, so nothing is buffered
+// past the etagMaxSize compressHandler holds, see compress.go. Being a zip
+// archive, it isn't gzipped again.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+type xlsxTable struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// newXLSXTable starts a workbook with a sheet named name on w.
+func newXLSXTable(w io.Writer, name string) (*xlsxTable, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sheet names are at most 31 characters.
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	f, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	io.WriteString(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	xml.EscapeText(f, []byte(name))
+	if _, err := io.WriteString(f, `" sheetId="1" r:id="rId1"/></sheets></workbook>`); err != nil {
+		return nil, err
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxTable{zw: zw, sheet: sheet}, nil
+}
+
+func (t *xlsxTable) Write(row ...interface{}) error {
+	t.rows++
+	fmt.Fprintf(t.sheet, `<row r="%d">`, t.rows)
+	for _, cell := range row {
+		switch v := cell.(type) {
+		case int, float64:
+			fmt.Fprintf(t.sheet, `<c><v>%s</v></c>`, cellText(v))
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(t.sheet, `<c t="b"><v>%d</v></c>`, b)
+		default:
+			io.WriteString(t.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(t.sheet, []byte(cellText(cell)))
+			io.WriteString(t.sheet, `</t></is></c>`)
+		}
+	}
+	_, err := io.WriteString(t.sheet, `</row>`)
+
+	return err
+}
+
+func (t *xlsxTable) Close() error {
+	if _, err := io.WriteString(t.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return t.zw.Close()
+}
+
+// closeTable ends the export t of name, logging what went wrong once the
+// headers are gone.
+func closeTable(t table, name string, err error) {
+	if err == nil {
+		err = t.Close()
+	}
+	if err != nil {
+		log.Printf("export: %s failed: %v", name, err)
+	}
+}
+
+// Export Handlers
+func (c *appContext) exportEventsHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		WriteError(w, ErrInvalidFormat)
+		return
+	}
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	events, err := c.events().All(r.Context(), start_time, end_time, false)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	events = c.withOccurrences(r.Context(), events, start_time, end_time)
+	zones.events(events)
+
+	name := exportName("events", start_time, end_time)
+	t, err := newTable(w, format, name, "id", "name", "room", "location_id", "owner", "guests",
+		"start_time", "end_time", "time_zone", "hours", "category", "tentative", "checked_in_at")
+	if err != nil {
+		closeTable(t, name, err)
+		return
+	}
+	rooms := map[string]string{}
+	for _, event := range events {
+		room, ok := rooms[event.LocationID]
+		if !ok {
+			room = event.Location
+			if found, err := c.rooms().Find(r.Context(), event.LocationID); err == nil {
+				room = found.Name
+			}
+			rooms[event.LocationID] = room
+		}
+		id := event.Id.Hex()
+		if event.RecurringEventId != "" {
+			id = event.RecurringEventId.Hex()
+		}
+
+		err = t.Write(id, event.Name, room, event.LocationID, event.Owner, strings.Join(event.Guests, "; "),
+			event.StartTime, event.EndTime, event.TimeZone, event.EndTime.Sub(event.StartTime).Hours(),
+			event.Category, event.Tentative, event.CheckedInAt)
+		if err != nil {
+			break
+		}
+	}
+	closeTable(t, name, err)
+}
+
+// exportUtilization writes report, of the rooms of a venue, in format.
+func exportUtilization(w http.ResponseWriter, format string, report Utilization) {
+	name := exportName("utilization", report.StartTime, report.EndTime)
+	t, err := newTable(w, format, name, "room_id", "room", "booked_hours", "available_hours", "utilization")
+	if err != nil {
+		closeTable(t, name, err)
+		return
+	}
+	for _, room := range report.Rooms {
+		err = t.Write(room.RoomId, room.Name, room.BookedHours, room.AvailableHours, room.Utilization)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = t.Write("", "total", report.BookedHours, report.AvailableHours, report.Utilization)
+	}
+	closeTable(t, name, err)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Extending and ending events
+//
+// POST /events/:id/extend moves the end of an event minutes later, as long
+// as its room is free until then and its venue's rules allow the longer
+// booking. POST /events/:id/end ends an event taking place now, freeing its
+// room for the rest of the slot, see standby.go; the venue's shortest
+// booking doesn't apply to ending early. Owners and guests are told of
+// either change. Recurring events are changed through their occurrences.
+var (
+	ErrEventNotInProgress = &Error{"event_not_in_progress", 409, "Conflict", "Only an event taking place now can be ended."}
+	ErrRecurringChange    = &Error{"recurring_change", 409, "Conflict", "Recurring events can't be extended or ended, only their occurrences."}
+)
+
+type EventExtension struct {
+	Minutes int `json:"minutes"`
+}
+
+// changeEventTimes writes updated, existing with other times, and tells its
+// owner and guests.
+func (c *appContext) changeEventTimes(ctx context.Context, updated *Event, existing Event) *Error {
+	err := c.events().Patch(ctx, updated, existing)
+	if conflict, ok := err.(*EventConflict); ok {
+		return conflictError(conflict.Event)
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event", updated.Id, ChangeUpdated)
+	notifyEvent(NotifyUpdated, *updated)
+
+	return nil
+}
+
+// extendEvent extends existing by minutes, refusing to run into the next
+// booking of its room.
+func (c *appContext) extendEvent(ctx context.Context, existing Event, minutes int) (Event, *Error) {
+	event := existing
+	event.EndTime = existing.EndTime.Add(time.Duration(minutes) * time.Minute)
+	if existing.LocationID != "" {
+		next, err := c.events().Overlapping(ctx, existing.LocationID, existing.EndTime, event.EndTime, existing.Id)
+		if err != nil {
+			panic(err)
+		}
+		if len(next) > 0 {
+			sort.Slice(next, func(i, j int) bool { return next[i].StartTime.Before(next[j].StartTime) })
+			return existing, conflictError(next[0])
+		}
+	}
+	if errRes := c.checkVenueRules(ctx, event); errRes != nil {
+		return existing, errRes
+	}
+	if errRes := c.changeEventTimes(ctx, &event, existing); errRes != nil {
+		return existing, errRes
+	}
+
+	return event, nil
+}
+
+// endEvent ends existing, taking place at now, then.
+func (c *appContext) endEvent(ctx context.Context, existing Event, now time.Time) (Event, *Error) {
+	if existing.StartTime.After(now) || !existing.EndTime.After(now) {
+		return existing, ErrEventNotInProgress
+	}
+
+	event := existing
+	event.EndTime = now
+	if errRes := c.changeEventTimes(ctx, &event, existing); errRes != nil {
+		return existing, errRes
+	}
+	c.recordFreedSlot(event.LocationID, now, existing.EndTime)
+
+	return event, nil
+}
+
+// managedEvent reads the event of the request, to be extended or ended by
+// its caller.
+func (c *appContext) managedEvent(r *http.Request) (Event, *Error) {
+	params := routeParams(r)
+	event, err := c.events().Find(r.Context(), params.ByName("id"))
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return event, ErrNotFound
+	}
+	if err != nil {
+		panic(err)
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManage(event) {
+		return event, ErrNotEventOwner
+	}
+	if event.Recurrence != nil {
+		return event, ErrRecurringChange
+	}
+
+	return event, nil
+}
+
+// Extend Handlers
+func (c *appContext) extendEventHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*EventExtension)
+	existing, errRes := c.managedEvent(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	event, errRes := c.extendEvent(r.Context(), existing, body.Minutes)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones.event(&event)
+
+	WriteSuccess(w, http.StatusAccepted, event)
+}
+
+func (c *appContext) endEventHandler(w http.ResponseWriter, r *http.Request) {
+	existing, errRes := c.managedEvent(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	event, errRes := c.endEvent(r.Context(), existing, clockNow())
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones.event(&event)
+
+	WriteSuccess(w, http.StatusAccepted, event)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Database failover
+//
+// When the primary steps down or the connection to it drops, the driver
+// answers the queries in flight with errors that go away once a new primary
+// is elected; it finds the new primary by itself. The main reads of
+// the venue, room and event repos are retried through retryRead, backing
+// off between attempts, up to failoverRetries times. Writes aren't retried since they may have been applied: repoWriteError
+// turns such a failure into ErrRetryableWrite, answered with a 503 and a
+// Retry-After so the client can decide. Transient errors that still reach
+// WriteRepoError or recoverHandler are answered with a 503 instead of a 500.
+// GET /admin/database/failovers counts what was seen since the process
+// started.
+const (
+	failoverRetries    = 3
+	failoverBackoff    = 250 * time.Millisecond
+	failoverRetryAfter = "5"
+)
+
+// Error codes and messages of a server that isn't, or is no longer, the
+// primary, or is shutting down.
+var (
+	notPrimaryCodes    = []int{10058, 10107, 13435, 13436, 189, 11600, 11602, 91}
+	notPrimaryMessages = []string{"not master", "not primary", "node is recovering"}
+)
+
+type FailoverStats struct {
+	Since            time.Time `json:"since"`
+	Stepdowns        int       `json:"stepdowns"`
+	NetworkErrors    int       `json:"network_errors"`
+	ReadRetries      int       `json:"read_retries"`
+	ReadsRecovered   int       `json:"reads_recovered"`
+	ReadsFailed      int       `json:"reads_failed"`
+	RetryableWrites  int       `json:"retryable_writes"`
+	LastFailoverTime time.Time `json:"last_failover_time,omitempty"`
+	LastError        string    `json:"last_error,omitempty"`
+}
+
+type failoverMetrics struct {
+	sync.Mutex
+	stats FailoverStats
+}
+
+var failovers = &failoverMetrics{stats: FailoverStats{Since: time.Now()}}
+
+func (m *failoverMetrics) record(fn func(*FailoverStats)) {
+	m.Lock()
+	defer m.Unlock()
+
+	fn(&m.stats)
+}
+
+// seen counts err, a transient error, as a stepdown or a network error.
+func (m *failoverMetrics) seen(err error) {
+	m.record(func(s *FailoverStats) {
+		if isStepdown(err) {
+			s.Stepdowns++
+		} else {
+			s.NetworkErrors++
+		}
+		s.LastFailoverTime = time.Now()
+		s.LastError = err.Error()
+	})
+}
+
+func (m *failoverMetrics) Stats() FailoverStats {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.stats
+}
+
+// isStepdown reports whether err says the server isn't, or is no longer, the
+// primary.
+func isStepdown(err error) bool {
+	if storage.HasErrorCode(err, notPrimaryCodes...) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, m := range notPrimaryMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTransient reports whether err is expected to go away once the replica
+// set has a reachable primary again.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == ErrRetryableWrite || err == ErrDatabaseUnavailable {
+		return true
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	if storage.IsNetworkError(err) {
+		return true
+	}
+
+	return isStepdown(err)
+}
+
+// retryRead runs read, retrying it while it fails with a transient error.
+func retryRead(read func() error) error {
+	err := chaos.read(read)
+	for attempt := 1; attempt <= failoverRetries && isTransient(err); attempt++ {
+		failovers.seen(err)
+		failovers.record(func(s *FailoverStats) { s.ReadRetries++ })
+		time.Sleep(time.Duration(attempt) * failoverBackoff)
+
+		err = chaos.read(read)
+		if err == nil {
+			failovers.record(func(s *FailoverStats) { s.ReadsRecovered++ })
+		}
+	}
+	if isTransient(err) {
+		failovers.record(func(s *FailoverStats) { s.ReadsFailed++ })
+		return ErrDatabaseUnavailable
+	}
+
+	return err
+}
+
+// repoWriteError translates a driver error of a write into a repo error.
+func repoWriteError(err error) error {
+	if isTransient(err) {
+		failovers.seen(err)
+		failovers.record(func(s *FailoverStats) { s.RetryableWrites++ })
+		return ErrRetryableWrite
+	}
+
+	return repoError(err)
+}
+
+// writeUnavailable answers a request that failed on a transient error.
+func writeUnavailable(w http.ResponseWriter, err error) {
+	if rec, ok := w.(*routeRecorder); ok {
+		log.Printf("database unavailable: request_id=%s %v", rec.requestId, err)
+	} else {
+		log.Printf("database unavailable: %v", err)
+	}
+	w.Header().Set("Retry-After", failoverRetryAfter)
+	if err == ErrRetryableWrite {
+		WriteError(w, ErrWriteInterrupted)
+		return
+	}
+
+	WriteError(w, ErrServiceUnavailable)
+}
+
+// Failover Handlers
+func (c *appContext) failoverStatsHandler(w http.ResponseWriter, r *http.Request) {
+	WriteSuccess(w, http.StatusOK, failovers.Stats())
+}
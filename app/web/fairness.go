@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Fairness
+//
+// Users belong to a team in the user directory. Teams can be given a weekly
+// cap on booked hours, counted over the events their members own. A "soft"
+// cap only warns when a booking takes the team over it, a "hard" cap rejects
+// the booking. GET /reports/fairness shows each team's hours against its cap.
+const (
+	CapSoft = "soft"
+	CapHard = "hard"
+)
+
+type User struct {
+	Id           storage.ObjectId `json:"-" bson:"_id,omitempty"`
+	Email        string           `json:"email"`
+	Name         string           `json:"name"`
+	Team         string           `json:"team"`
+	Role         string           `json:"role,omitempty"`
+	HidePresence bool             `json:"hide_presence"`
+
+	// OrgId is the organization the user is a member of, see
+	// organization.go.
+	OrgId string `json:"org_id,omitempty" bson:"orgid,omitempty"`
+}
+
+type TeamCap struct {
+	Id          storage.ObjectId `json:"-" bson:"_id,omitempty"`
+	Team        string           `json:"team"`
+	WeeklyHours float64          `json:"weekly_hours"`
+	Mode        string           `json:"mode"`
+}
+
+type FairnessRow struct {
+	Team        string  `json:"team"`
+	BookedHours float64 `json:"booked_hours"`
+	CapHours    float64 `json:"cap_hours"`
+	Mode        string  `json:"mode"`
+	Share       float64 `json:"share"`
+	OverCap     bool    `json:"over_cap"`
+}
+
+type FairnessReport struct {
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Teams     []FairnessRow `json:"teams"`
+}
+
+// Repo User
+type UserRepo struct {
+	coll *storage.Collection
+}
+
+func (r *UserRepo) All() ([]User, error) {
+	result := []User{}
+	err := r.coll.Find(nil).Sort("email").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *UserRepo) FindByEmail(email string) (User, error) {
+	result := User{}
+	err := r.coll.Find(bson.M{"email": email}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// AllByEmails returns the directory entries of emails, keyed by email.
+func (r *UserRepo) AllByEmails(emails []string) (map[string]User, error) {
+	users := []User{}
+	err := r.coll.Find(bson.M{"email": bson.M{"$in": emails}}).All(&users)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]User{}
+	for _, user := range users {
+		result[user.Email] = user
+	}
+
+	return result, nil
+}
+
+func (r *UserRepo) AllByTeam(team string) ([]User, error) {
+	result := []User{}
+	err := r.coll.Find(bson.M{"team": team}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *UserRepo) Upsert(user *User) error {
+	_, err := r.coll.Upsert(bson.M{"email": user.Email}, bson.M{"$set": bson.M{
+		"email":        user.Email,
+		"name":         user.Name,
+		"team":         user.Team,
+		"role":         user.Role,
+		"hidepresence": user.HidePresence,
+	}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo TeamCap
+type TeamCapRepo struct {
+	coll *storage.Collection
+}
+
+func (r *TeamCapRepo) All() ([]TeamCap, error) {
+	result := []TeamCap{}
+	err := r.coll.Find(nil).Sort("team").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *TeamCapRepo) Find(team string) (TeamCap, error) {
+	result := TeamCap{}
+	err := r.coll.Find(bson.M{"team": team}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *TeamCapRepo) Upsert(teamCap *TeamCap) error {
+	_, err := r.coll.Upsert(bson.M{"team": teamCap.Team}, bson.M{"$set": bson.M{
+		"team":        teamCap.Team,
+		"weeklyhours": teamCap.WeeklyHours,
+		"mode":        teamCap.Mode,
+	}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// bookedHours sums the durations of events, ignoring exceptId.
+func bookedHours(events []Event, exceptId storage.ObjectId) float64 {
+	total := time.Duration(0)
+	for _, event := range events {
+		if exceptId != "" && event.Id == exceptId {
+			continue
+		}
+		total += event.EndTime.Sub(event.StartTime)
+	}
+
+	return total.Hours()
+}
+
+// checkTeamCap checks whether event takes its owner's team over the weekly
+// cap. It returns the error to send for hard caps, or a warning for soft
+// caps.
+func (c *appContext) checkTeamCap(ctx context.Context, event Event) (*Error, string) {
+	// Contexts without a database, running on in-memory repos, have no teams.
+	if c.db == nil {
+		return nil, ""
+	}
+
+	userRepo := UserRepo{c.db.C("users")}
+	user, err := userRepo.FindByEmail(event.Owner)
+	if err == storage.ErrNotFound || user.Team == "" {
+		return nil, ""
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	capRepo := TeamCapRepo{c.db.C("team_caps")}
+	teamCap, err := capRepo.Find(user.Team)
+	if err == storage.ErrNotFound {
+		return nil, ""
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	members, err := userRepo.AllByTeam(user.Team)
+	if err != nil {
+		panic(err)
+	}
+	owners := []string{}
+	for _, member := range members {
+		owners = append(owners, member.Email)
+	}
+
+	locale := orgLocale()
+	repo := c.events()
+	events, err := repo.AllByOwners(ctx, owners, locale.BeginningOfWeek(event.StartTime), locale.EndOfWeek(event.StartTime))
+	if err != nil {
+		panic(err)
+	}
+
+	booked := bookedHours(events, event.Id) + event.EndTime.Sub(event.StartTime).Hours()
+	if booked <= teamCap.WeeklyHours {
+		return nil, ""
+	}
+
+	detail := fmt.Sprintf("Team %s would book %.1f of its %.1f weekly hours.", teamCap.Team, booked, teamCap.WeeklyHours)
+	if teamCap.Mode == CapHard {
+		return &Error{"team_cap_exceeded", http.StatusConflict, "Conflict", detail}, ""
+	}
+
+	return nil, detail
+}
+
+// User Handlers
+func (c *appContext) usersHandler(w http.ResponseWriter, r *http.Request) {
+	repo := UserRepo{c.db.C("users")}
+	users, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, users)
+}
+
+func (c *appContext) userHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := UserRepo{c.db.C("users")}
+	user, err := repo.FindByEmail(params.ByName("user"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, user)
+}
+
+func (c *appContext) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*User)
+	body.Email = params.ByName("user")
+	caller := r.Context().Value(userKey).(User)
+	if !caller.IsAdmin() && caller.Email != body.Email {
+		WriteError(w, ErrForbidden)
+		return
+	}
+
+	repo := UserRepo{c.db.C("users")}
+	existing, err := repo.FindByEmail(body.Email)
+	if err != nil && err != storage.ErrNotFound {
+		panic(err)
+	}
+	if !caller.IsAdmin() {
+		body.Role = existing.Role
+	}
+
+	err = repo.Upsert(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+// Team Cap Handlers
+func (c *appContext) teamCapsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := TeamCapRepo{c.db.C("team_caps")}
+	caps, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, caps)
+}
+
+func (c *appContext) updateTeamCapHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*TeamCap)
+	body.Team = params.ByName("team")
+	if body.Mode == "" {
+		body.Mode = CapSoft
+	}
+	repo := TeamCapRepo{c.db.C("team_caps")}
+	err := repo.Upsert(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+// fairnessReportHandler aggregates booked hours per team over ?start_time=
+// and ?end_time= (this week by default). Caps are weekly and are scaled to
+// the length of the window.
+func (c *appContext) fairnessReportHandler(w http.ResponseWriter, r *http.Request) {
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	weeks := end_time.Sub(start_time).Hours() / (7 * 24)
+
+	userRepo := UserRepo{c.db.C("users")}
+	users, err := userRepo.All()
+	if err != nil {
+		panic(err)
+	}
+	teamOf := map[string]string{}
+	owners := []string{}
+	for _, user := range users {
+		if user.Team != "" {
+			teamOf[user.Email] = user.Team
+			owners = append(owners, user.Email)
+		}
+	}
+
+	repo := c.events()
+	events, err := repo.AllByOwners(r.Context(), owners, start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+
+	rows := map[string]*FairnessRow{}
+	row := func(team string) *FairnessRow {
+		if rows[team] == nil {
+			rows[team] = &FairnessRow{Team: team}
+		}
+		return rows[team]
+	}
+
+	total := 0.0
+	for _, event := range events {
+		hours := event.EndTime.Sub(event.StartTime).Hours()
+		row(teamOf[event.Owner]).BookedHours += hours
+		total += hours
+	}
+
+	capRepo := TeamCapRepo{c.db.C("team_caps")}
+	caps, err := capRepo.All()
+	if err != nil {
+		panic(err)
+	}
+	for _, teamCap := range caps {
+		teamRow := row(teamCap.Team)
+		teamRow.CapHours = teamCap.WeeklyHours * weeks
+		teamRow.Mode = teamCap.Mode
+	}
+
+	report := FairnessReport{start_time, end_time, []FairnessRow{}}
+	for _, teamRow := range rows {
+		if total > 0 {
+			teamRow.Share = teamRow.BookedHours / total
+		}
+		teamRow.OverCap = teamRow.Mode != "" && teamRow.BookedHours > teamRow.CapHours
+		report.Teams = append(report.Teams, *teamRow)
+	}
+	sort.Slice(report.Teams, func(i, j int) bool {
+		return report.Teams[i].BookedHours > report.Teams[j].BookedHours
+	})
+
+	WriteSuccess(w, http.StatusOK, report)
+}
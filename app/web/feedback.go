@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Room feedback
+//
+// Attendees rate the room of an event on comfort, AV quality and cleanliness
+// from 1 to 5 once the event has started, for up to feedbackWindow after it
+// ended. Ratings are averaged per room; a dimension averaging below
+// attentionThreshold is flagged for facilities.
+const (
+	feedbackWindow     = 7 * 24 * time.Hour
+	attentionThreshold = 3.0
+)
+
+type Feedback struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	EventId     string           `json:"event_id"`
+	RoomId      string           `json:"room_id"`
+	Attendee    string           `json:"attendee"`
+	Comfort     int              `json:"comfort"`
+	AV          int              `json:"av"`
+	Cleanliness int              `json:"cleanliness"`
+	Comment     string           `json:"comment"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type FeedbackSummary struct {
+	RoomId         string    `json:"room_id"`
+	Room           string    `json:"room,omitempty"`
+	Count          int       `json:"count"`
+	Comfort        float64   `json:"comfort"`
+	AV             float64   `json:"av"`
+	Cleanliness    float64   `json:"cleanliness"`
+	Overall        float64   `json:"overall"`
+	NeedsAttention []string  `json:"needs_attention"`
+	Comments       []string  `json:"comments"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+}
+
+// Repo Feedback
+type FeedbackRepo struct {
+	coll *storage.Collection
+}
+
+func (r *FeedbackRepo) AllByRoomId(roomId string, start_time time.Time, end_time time.Time) ([]Feedback, error) {
+	result := []Feedback{}
+	query := bson.M{"roomid": roomId, "createdat": bson.M{"$gte": start_time, "$lte": end_time}}
+	err := r.coll.Find(query).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *FeedbackRepo) All(start_time time.Time, end_time time.Time) ([]Feedback, error) {
+	result := []Feedback{}
+	query := bson.M{"createdat": bson.M{"$gte": start_time, "$lte": end_time}}
+	err := r.coll.Find(query).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Upsert stores feedback, replacing an earlier rating by the same attendee
+// for the same event.
+func (r *FeedbackRepo) Upsert(feedback *Feedback) error {
+	info, err := r.coll.Upsert(bson.M{"eventid": feedback.EventId, "attendee": feedback.Attendee}, feedback)
+	if err != nil {
+		return err
+	}
+
+	if id, ok := info.UpsertedId.(storage.ObjectId); ok {
+		feedback.Id = id
+	}
+
+	return nil
+}
+
+// summarizeFeedback averages ratings of a single room.
+func summarizeFeedback(roomId string, feedback []Feedback) FeedbackSummary {
+	summary := FeedbackSummary{RoomId: roomId, NeedsAttention: []string{}, Comments: []string{}}
+	if len(feedback) == 0 {
+		return summary
+	}
+
+	for _, f := range feedback {
+		summary.Comfort += float64(f.Comfort)
+		summary.AV += float64(f.AV)
+		summary.Cleanliness += float64(f.Cleanliness)
+		if f.Comment != "" && len(summary.Comments) < 20 {
+			summary.Comments = append(summary.Comments, f.Comment)
+		}
+	}
+
+	n := float64(len(feedback))
+	summary.Count = len(feedback)
+	summary.Comfort /= n
+	summary.AV /= n
+	summary.Cleanliness /= n
+	summary.Overall = (summary.Comfort + summary.AV + summary.Cleanliness) / 3
+
+	if summary.Comfort < attentionThreshold {
+		summary.NeedsAttention = append(summary.NeedsAttention, "comfort")
+	}
+	if summary.AV < attentionThreshold {
+		summary.NeedsAttention = append(summary.NeedsAttention, "av")
+	}
+	if summary.Cleanliness < attentionThreshold {
+		summary.NeedsAttention = append(summary.NeedsAttention, "cleanliness")
+	}
+
+	return summary
+}
+
+// feedbackWindowOf returns the ?start_time= / ?end_time= window, defaulting
+// to the last 90 days.
+func feedbackWindowOf(r *http.Request) (time.Time, time.Time) {
+	end_time := clockNow()
+	start_time := end_time.AddDate(0, 0, -90)
+	if v, err := time.Parse(time.RFC3339, r.URL.Query().Get("start_time")); err == nil {
+		start_time = v
+	}
+	if v, err := time.Parse(time.RFC3339, r.URL.Query().Get("end_time")); err == nil {
+		end_time = v
+	}
+
+	return start_time, end_time
+}
+
+// Feedback Handlers
+func (c *appContext) createFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Feedback)
+
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	attended := false
+	for _, p := range participantsOf(event) {
+		if p == body.Attendee {
+			attended = true
+		}
+	}
+	if !attended {
+		WriteError(w, ErrNotAttendee)
+		return
+	}
+
+	now := clockNow()
+	if now.Before(event.StartTime) || now.After(event.EndTime.Add(feedbackWindow)) {
+		WriteError(w, ErrFeedbackClosed)
+		return
+	}
+
+	body.EventId = event.Id.Hex()
+	body.RoomId = event.LocationID
+	body.Comment = sanitizeDescription(body.Comment)
+	body.CreatedAt = now
+
+	feedbackRepo := FeedbackRepo{c.db.C("feedback")}
+	err = feedbackRepo.Upsert(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) roomFeedbackSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	start_time, end_time := feedbackWindowOf(r)
+
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	repo := FeedbackRepo{c.db.C("feedback")}
+	feedback, err := repo.AllByRoomId(room.Id.Hex(), start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+
+	summary := summarizeFeedback(room.Id.Hex(), feedback)
+	summary.Room = room.Name
+	summary.StartTime, summary.EndTime = start_time, end_time
+
+	WriteSuccess(w, http.StatusOK, summary)
+}
+
+// maintenanceReportHandler ranks rooms by their overall rating, worst first,
+// so facilities know where to start.
+func (c *appContext) maintenanceReportHandler(w http.ResponseWriter, r *http.Request) {
+	start_time, end_time := feedbackWindowOf(r)
+
+	repo := FeedbackRepo{c.db.C("feedback")}
+	feedback, err := repo.All(start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+
+	byRoom := map[string][]Feedback{}
+	for _, f := range feedback {
+		byRoom[f.RoomId] = append(byRoom[f.RoomId], f)
+	}
+
+	roomRepo := c.rooms()
+	rooms, _, err := roomRepo.All(r.Context(), ListOptions{})
+	if err != nil {
+		panic(err)
+	}
+	names := map[string]string{}
+	for _, room := range rooms {
+		names[room.Id.Hex()] = room.Name
+	}
+
+	result := []FeedbackSummary{}
+	for roomId, roomFeedback := range byRoom {
+		summary := summarizeFeedback(roomId, roomFeedback)
+		summary.Room = names[roomId]
+		summary.StartTime, summary.EndTime = start_time, end_time
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].NeedsAttention) != len(result[j].NeedsAttention) {
+			return len(result[i].NeedsAttention) > len(result[j].NeedsAttention)
+		}
+		return result[i].Overall < result[j].Overall
+	})
+
+	WriteSuccess(w, http.StatusOK, result)
+}
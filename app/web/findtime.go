@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Find a time
+//
+// GET /find-a-time suggests slots where every participant is free. Slots are
+// tried every ?step= (default 15m) between start_time and end_time. Slots
+// outside any participant's working hours are skipped unless
+// ?allow_outside_hours=true, in which case they're returned after the others
+// with the participants they inconvenience. With ?venue_id= the travel time
+// from and to each participant's other events is kept free as well.
+const maxSuggestions = 50
+
+type FindTimeRequest struct {
+	Participants      []string
+	Duration          time.Duration
+	Step              time.Duration
+	StartTime         time.Time
+	EndTime           time.Time
+	AllowOutsideHours bool
+	Limit             int
+}
+
+type TimeSuggestion struct {
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	OutsideHours []string  `json:"outside_hours"`
+}
+
+func parseFindTimeRequest(r *http.Request) (FindTimeRequest, *Error) {
+	q := r.URL.Query()
+	req := FindTimeRequest{Duration: 30 * time.Minute, Step: 15 * time.Minute, Limit: 10}
+
+	for _, p := range strings.Split(q.Get("participants"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			req.Participants = append(req.Participants, p)
+		}
+	}
+	if len(req.Participants) == 0 {
+		return req, ErrMissingParticipants
+	}
+
+	var err error
+	if v := q.Get("duration"); v != "" {
+		if req.Duration, err = time.ParseDuration(v); err != nil || req.Duration <= 0 {
+			return req, ErrInvalidDuration
+		}
+	}
+	if v := q.Get("step"); v != "" {
+		if req.Step, err = time.ParseDuration(v); err != nil || req.Step < 5*time.Minute {
+			return req, ErrInvalidDuration
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil || req.Limit < 1 || req.Limit > maxSuggestions {
+			return req, ErrBadRequest
+		}
+	}
+	req.AllowOutsideHours = q.Get("allow_outside_hours") == "true"
+
+	req.StartTime = clockNow().Truncate(req.Step).Add(req.Step)
+	if v := q.Get("start_time"); v != "" {
+		if req.StartTime, err = time.Parse(time.RFC3339, v); err != nil {
+			return req, ErrInvalidTimeRange
+		}
+	}
+	req.EndTime = req.StartTime.Add(7 * 24 * time.Hour)
+	if v := q.Get("end_time"); v != "" {
+		if req.EndTime, err = time.Parse(time.RFC3339, v); err != nil {
+			return req, ErrInvalidTimeRange
+		}
+	}
+	if !req.StartTime.Before(req.EndTime) {
+		return req, ErrInvalidTimeRange
+	}
+
+	return req, nil
+}
+
+// findTimes returns up to req.Limit slots where none of busy overlaps, those
+// inside everyone's working hours first.
+func findTimes(req FindTimeRequest, busy []Event, hours map[string]WorkingHours) []TimeSuggestion {
+	inside := []TimeSuggestion{}
+	outside := []TimeSuggestion{}
+
+	for start := req.StartTime; !start.Add(req.Duration).After(req.EndTime); start = start.Add(req.Step) {
+		end := start.Add(req.Duration)
+
+		free := true
+		for _, event := range busy {
+			if event.StartTime.Before(end) && event.EndTime.After(start) {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+
+		suggestion := TimeSuggestion{start, end, []string{}}
+		for _, p := range req.Participants {
+			if !hours[p].Covers(start, end) {
+				suggestion.OutsideHours = append(suggestion.OutsideHours, p)
+			}
+		}
+
+		if len(suggestion.OutsideHours) == 0 {
+			inside = append(inside, suggestion)
+			if len(inside) == req.Limit {
+				break
+			}
+		} else if req.AllowOutsideHours {
+			outside = append(outside, suggestion)
+		}
+	}
+
+	sort.SliceStable(outside, func(i, j int) bool {
+		return len(outside[i].OutsideHours) < len(outside[j].OutsideHours)
+	})
+	result := append(inside, outside...)
+	if len(result) > req.Limit {
+		result = result[:req.Limit]
+	}
+
+	return result
+}
+
+// Find A Time Handlers
+func (c *appContext) findTimeHandler(w http.ResponseWriter, r *http.Request) {
+	req, e := parseFindTimeRequest(r)
+	if e != nil {
+		WriteError(w, e)
+		return
+	}
+
+	travelRepo := TravelTimeRepo{c.db.C("travel_times")}
+	matrix, err := travelRepo.Matrix()
+	if err != nil {
+		panic(err)
+	}
+	venueId := r.URL.Query().Get("venue_id")
+
+	repo := c.events()
+	busy, err := repo.AllByParticipants(r.Context(), req.Participants, req.StartTime.Add(-matrix.Max()), req.EndTime.Add(matrix.Max()))
+	if err != nil {
+		panic(err)
+	}
+	if venueId != "" {
+		venues, err := c.roomVenues(r.Context(), busy)
+		if err != nil {
+			panic(err)
+		}
+		busy = padForTravel(busy, venues, matrix, venueId)
+	}
+
+	hoursRepo := WorkingHoursRepo{c.db.C("working_hours")}
+	hours, err := hoursRepo.AllByUsers(req.Participants)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, findTimes(req, busy, hours))
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Floor plans
+//
+// Rooms say where they are: the building and floor signposted, map
+// coordinates on the plan of their floor, see Floor, and a photo. Admins
+// upload the plan of a floor with PUT /floors/:id/plan, as the multipart
+// "file" field, a PNG, JPEG, GIF or WebP image of at most maxImageSize; it's
+// kept in the BlobStore, served at GET /floors/:id/plan and becomes the
+// floor's map_image_url. DELETE /floors/:id/plan removes it.
+//
+// GET /venues/:id/floor-plans lists the floors of a venue, lowest first, with
+// their plan and the rooms placed on each, so a booking page can show where
+// a room is.
+const maxImageSize = 10 << 20
+
+var (
+	ErrImagesDisabled = &Error{"images_disabled", 503, "Service Unavailable", "Image uploads are disabled until a blob store is configured."}
+	ErrInvalidImage   = &Error{"invalid_image", 415, "Unsupported Media Type", "Images must be PNG, JPEG, GIF or WebP."}
+	ErrImageTooLarge  = &Error{"image_too_large", 413, "Payload Too Large", "Images must be at most 10 MB."}
+)
+
+// imageTypes are the image types accepted, by content type, with the
+// extension they're stored with.
+var imageTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// FloorPlanImage is the plan of a floor, by the floor's id, in the blob
+// store.
+type FloorPlanImage struct {
+	Id          storage.ObjectId `bson:"_id"`
+	Key         string
+	ContentType string
+	Size        int
+	UploadedBy  string
+	UploadedAt  time.Time
+}
+
+type FloorPlan struct {
+	Floor Floor  `json:"floor"`
+	Rooms []Room `json:"rooms"`
+}
+
+// readImage reads the image of the multipart "file" field of r, sniffing
+// its content type.
+func readImage(w http.ResponseWriter, r *http.Request) ([]byte, string, *Error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return nil, "", ErrInvalidUpload
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", ErrInvalidUpload
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		return nil, "", ErrInvalidUpload
+	}
+	if len(data) > maxImageSize {
+		return nil, "", ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := imageTypes[contentType]; !ok {
+		return nil, "", ErrInvalidImage
+	}
+
+	return data, contentType, nil
+}
+
+// serveBlob answers r with the blob key of store.
+func serveBlob(w http.ResponseWriter, r *http.Request, store BlobStore, key string, contentType string, modified time.Time) {
+	blob, err := store.Open(r.Context(), key)
+	if err == ErrBlobNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("blobs: serving %s: %v", key, err)
+	}
+}
+
+// Repo FloorPlanImage
+type FloorPlanImageRepo struct {
+	coll *storage.Collection
+}
+
+func (r *FloorPlanImageRepo) Find(floorId storage.ObjectId) (FloorPlanImage, error) {
+	result := FloorPlanImage{}
+	err := r.coll.FindId(floorId).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *FloorPlanImageRepo) Save(image FloorPlanImage) error {
+	_, err := r.coll.UpsertId(image.Id, image)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *FloorPlanImageRepo) Delete(floorId storage.ObjectId) error {
+	err := r.coll.RemoveId(floorId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetMapImageURL points the map of the floor with id at url.
+func (r *FloorRepo) SetMapImageURL(id storage.ObjectId, url string) error {
+	err := r.coll.UpdateId(id, bson.M{"$set": bson.M{"mapimageurl": url}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Floor Plan Handlers
+func (c *appContext) uploadFloorPlanHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	floors := FloorRepo{c.db.C("floors")}
+	floor, err := floors.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+	data, contentType, errRes := readImage(w, r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	repo := FloorPlanImageRepo{c.db.C("floor_plans")}
+	previous, err := repo.Find(floor.Id)
+	if err != nil && err != storage.ErrNotFound {
+		panic(err)
+	}
+	image := FloorPlanImage{
+		Id:          floor.Id,
+		Key:         "floor-plans/" + floor.Id.Hex() + "-" + storage.NewObjectId().Hex() + imageTypes[contentType],
+		ContentType: contentType,
+		Size:        len(data),
+		UploadedBy:  r.Context().Value(userKey).(User).Email,
+		UploadedAt:  time.Now(),
+	}
+	if err := store.Put(r.Context(), image.Key, contentType, data); err != nil {
+		panic(err)
+	}
+	if err := repo.Save(image); err != nil {
+		panic(err)
+	}
+	if previous.Key != "" {
+		if err := store.Delete(r.Context(), previous.Key); err != nil {
+			log.Printf("blobs: removing %s: %v", previous.Key, err)
+		}
+	}
+
+	floor.MapImageURL = "/floors/" + floor.Id.Hex() + "/plan"
+	if err := floors.SetMapImageURL(floor.Id, floor.MapImageURL); err != nil {
+		panic(err)
+	}
+	c.recordChange("floor", floor.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusOK, floor)
+}
+
+func (c *appContext) floorPlanHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := FloorPlanImageRepo{c.db.C("floor_plans")}
+	image, err := repo.Find(storage.ObjectIdHex(params.ByName("id")))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+
+	serveBlob(w, r, store, image.Key, image.ContentType, image.UploadedAt)
+}
+
+func (c *appContext) deleteFloorPlanHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := FloorPlanImageRepo{c.db.C("floor_plans")}
+	image, err := repo.Find(storage.ObjectIdHex(params.ByName("id")))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+
+	if err := repo.Delete(image.Id); err != nil {
+		panic(err)
+	}
+	if err := store.Delete(r.Context(), image.Key); err != nil {
+		log.Printf("blobs: removing %s: %v", image.Key, err)
+	}
+	floors := FloorRepo{c.db.C("floors")}
+	if err := floors.SetMapImageURL(image.Id, ""); err != nil && err != storage.ErrNotFound {
+		panic(err)
+	}
+	c.recordChange("floor", image.Id, ChangeUpdated)
+
+	data := MessageSuccess{MessageInfo{Message: "Floor plan has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+func (c *appContext) venueFloorPlansHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/floor-plans")
+		return
+	}
+
+	floors, err := (&FloorRepo{c.db.C("floors")}).AllByVenueId(venue.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	rooms, err := c.rooms().AllByVenueId(r.Context(), venue.Id.Hex())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	result := []FloorPlan{}
+	for _, floor := range floors {
+		plan := FloorPlan{Floor: floor, Rooms: []Room{}}
+		for _, room := range rooms {
+			if room.FloorId == floor.Id.Hex() {
+				plan.Rooms = append(plan.Rooms, room)
+			}
+		}
+		result = append(result, plan)
+	}
+
+	WriteSuccess(w, http.StatusOK, result)
+}
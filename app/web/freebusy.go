@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Free/busy
+//
+// POST /freebusy answers when each of users and room_ids, up to 50 of each,
+// is busy between start_time and end_time, at most maxEventsWindow apart,
+// for scheduling assistants: the events and occurrences they own, are a
+// guest of, unless they declined, or take place in them, merged into
+// intervals clipped to the window. The events are read in one query,
+// answered by the indexes on owner, guests and locationid with starttime,
+// see ensureIndexes.
+var (
+	ErrEmptyFreeBusy = &Error{"empty_free_busy", 422, "Unprocessable Entity", "users or room_ids must list at least one user or room."}
+)
+
+type FreeBusyRequest struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Users     []string  `json:"users"`
+	RoomIds   []string  `json:"room_ids"`
+}
+
+type UserFreeBusy struct {
+	Email string     `json:"email"`
+	Busy  []Interval `json:"busy"`
+}
+
+type RoomFreeBusy struct {
+	RoomId string     `json:"room_id"`
+	Busy   []Interval `json:"busy"`
+}
+
+type FreeBusy struct {
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Users     []UserFreeBusy `json:"users"`
+	Rooms     []RoomFreeBusy `json:"rooms"`
+}
+
+// busyFor reports whether e keeps user busy: they own it or are a guest who
+// didn't decline.
+func busyFor(e Event, user string) bool {
+	if strings.EqualFold(e.Owner, user) {
+		return true
+	}
+	if !isGuest(e, user) {
+		return false
+	}
+	for _, participant := range e.Participants {
+		if strings.EqualFold(participant.Email, user) {
+			return participant.Status != RSVPDeclined
+		}
+	}
+
+	return true
+}
+
+// busyIntervals merges the times of events into the intervals of
+// [start_time, end_time) they cover.
+func busyIntervals(events []Event, start_time time.Time, end_time time.Time) []Interval {
+	intervals := []Interval{}
+	for _, event := range events {
+		interval := Interval{event.StartTime, event.EndTime}
+		if interval.StartTime.Before(start_time) {
+			interval.StartTime = start_time
+		}
+		if interval.EndTime.After(end_time) {
+			interval.EndTime = end_time
+		}
+		if interval.StartTime.Before(interval.EndTime) {
+			intervals = append(intervals, interval)
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].StartTime.Before(intervals[j].StartTime) })
+
+	result := []Interval{}
+	for _, interval := range intervals {
+		if n := len(result); n > 0 && !interval.StartTime.After(result[n-1].EndTime) {
+			if interval.EndTime.After(result[n-1].EndTime) {
+				result[n-1].EndTime = interval.EndTime
+			}
+			continue
+		}
+		result = append(result, interval)
+	}
+
+	return result
+}
+
+// freeBusy sorts events out by user and room of req.
+func freeBusy(req FreeBusyRequest, events []Event) FreeBusy {
+	result := FreeBusy{StartTime: req.StartTime, EndTime: req.EndTime, Users: []UserFreeBusy{}, Rooms: []RoomFreeBusy{}}
+	for _, user := range req.Users {
+		busy := []Event{}
+		for _, event := range events {
+			if busyFor(event, user) {
+				busy = append(busy, event)
+			}
+		}
+		result.Users = append(result.Users, UserFreeBusy{user, busyIntervals(busy, req.StartTime, req.EndTime)})
+	}
+	for _, roomId := range req.RoomIds {
+		busy := []Event{}
+		for _, event := range events {
+			if event.LocationID == roomId {
+				busy = append(busy, event)
+			}
+		}
+		result.Rooms = append(result.Rooms, RoomFreeBusy{roomId, busyIntervals(busy, req.StartTime, req.EndTime)})
+	}
+
+	return result
+}
+
+// Free/Busy Handlers
+func (c *appContext) freeBusyHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*FreeBusyRequest)
+	if errRes := checkWindow(body.StartTime, body.EndTime); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if len(body.Users)+len(body.RoomIds) == 0 {
+		WriteError(w, ErrEmptyFreeBusy)
+		return
+	}
+
+	repo := c.events()
+	events, err := repo.AllBusy(r.Context(), body.Users, body.RoomIds, body.StartTime, body.EndTime)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, freeBusy(*body, events))
+}
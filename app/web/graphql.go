@@ -0,0 +1,906 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GraphQL
+//
+// /graphql answers GraphQL queries over the venues, rooms and events, so a
+// dashboard can fetch them nested in one request rather than a REST call per
+// venue and room; see graphqlschema.go for the types and what each field
+// resolves to. Queries are sent with POST as {"query", "variables",
+// "operationName"}, or with GET as the same query parameters; mutations only
+// with POST. Answers are {"data", "errors"}, with the id and status of the
+// REST error behind an error in its extensions.
+//
+// The executor supports what dashboards send: operations with variables,
+// aliases, named and inline fragments, and @skip and @include. Fields are
+// named like in the REST API. There's no introspection; the schema is the
+// one documented in graphqlschema.go.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   interface{}     `json:"data,omitempty"`
+	Errors []*GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func gqlErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+// gqlError is a field failing with the error the REST API answers with.
+type gqlError struct {
+	err *Error
+}
+
+func (e gqlError) Error() string {
+	return e.err.Detail
+}
+
+// newGraphQLError describes err, met at path.
+func newGraphQLError(err error, path []interface{}) *GraphQLError {
+	gqlErr := &GraphQLError{Message: err.Error(), Path: path}
+	if errRes, ok := err.(gqlError); ok {
+		gqlErr.Extensions = map[string]interface{}{"id": errRes.err.Id, "status": errRes.err.Status}
+	}
+
+	return gqlErr
+}
+
+// Lexer
+type gqlToken struct {
+	kind  byte // 'n'ame, 'i'nt, 'f'loat, 's'tring, 'p'unctuator, 0 at the end
+	value string
+	pos   int
+}
+
+func isGqlNameStart(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
+
+func isGqlDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func gqlLex(src string) ([]gqlToken, error) {
+	tokens := []gqlToken{}
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, gqlToken{'p', "...", i})
+			i += 3
+		case strings.IndexByte("{}()[]:=!$@|&", ch) >= 0:
+			tokens = append(tokens, gqlToken{'p', string(ch), i})
+			i++
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, gqlErrorf("Unterminated string at %d.", i)
+			}
+			tokens = append(tokens, gqlToken{'s', src[i+3 : i+3+end], i})
+			i += end + 6
+		case ch == '"':
+			j := i + 1
+			for ; j < len(src) && src[j] != '"' && src[j] != '\n'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(src) || src[j] != '"' {
+				return nil, gqlErrorf("Unterminated string at %d.", i)
+			}
+			value := ""
+			if err := json.Unmarshal([]byte(src[i:j+1]), &value); err != nil {
+				return nil, gqlErrorf("Invalid string at %d.", i)
+			}
+			tokens = append(tokens, gqlToken{'s', value, i})
+			i = j + 1
+		case isGqlNameStart(ch):
+			j := i + 1
+			for j < len(src) && (isGqlNameStart(src[j]) || isGqlDigit(src[j])) {
+				j++
+			}
+			tokens = append(tokens, gqlToken{'n', src[i:j], i})
+			i = j
+		case ch == '-' || isGqlDigit(ch):
+			j := i + 1
+			for j < len(src) && (isGqlDigit(src[j]) || strings.IndexByte(".eE+-", src[j]) >= 0) {
+				j++
+			}
+			kind := byte('i')
+			if strings.ContainsAny(src[i:j], ".eE") {
+				kind = 'f'
+			}
+			tokens = append(tokens, gqlToken{kind, src[i:j], i})
+			i = j
+		default:
+			return nil, gqlErrorf("Unexpected character %q at %d.", ch, i)
+		}
+	}
+
+	return append(tokens, gqlToken{pos: len(src)}), nil
+}
+
+// Parser
+type gqlDocument struct {
+	Operations []*gqlOperation
+	Fragments  map[string]*gqlFragment
+}
+
+type gqlOperation struct {
+	Type       string
+	Name       string
+	Variables  []gqlVariableDef
+	Selections []gqlSelection
+}
+
+type gqlVariableDef struct {
+	Name       string
+	NonNull    bool
+	Default    interface{}
+	HasDefault bool
+}
+
+type gqlFragment struct {
+	TypeCondition string
+	Selections    []gqlSelection
+}
+
+// gqlSelection is a field, a fragment spread when Spread is set, or an
+// inline fragment when Inline is.
+type gqlSelection struct {
+	Alias      string
+	Name       string
+	Args       map[string]interface{}
+	Directives map[string]map[string]interface{}
+	Selections []gqlSelection
+
+	Spread        string
+	Inline        bool
+	TypeCondition string
+}
+
+// key is the name of the field in the answer.
+func (s gqlSelection) key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+
+	return s.Name
+}
+
+// gqlVariable is a $variable in a value, replaced when the operation runs.
+type gqlVariable string
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *gqlParser) next() gqlToken {
+	token := p.tokens[p.pos]
+	if token.kind != 0 {
+		p.pos++
+	}
+
+	return token
+}
+
+// is reports whether the next token is the punctuator or name value.
+func (p *gqlParser) is(value string) bool {
+	token := p.peek()
+	return (token.kind == 'p' || token.kind == 'n') && token.value == value
+}
+
+func (p *gqlParser) skip(value string) bool {
+	if p.is(value) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *gqlParser) unexpected() error {
+	token := p.peek()
+	if token.kind == 0 {
+		return gqlErrorf("Unexpected end of the document.")
+	}
+
+	return gqlErrorf("Unexpected %q at %d.", token.value, token.pos)
+}
+
+func (p *gqlParser) expect(value string) error {
+	if !p.skip(value) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.peek().kind != 'n' {
+		return "", p.unexpected()
+	}
+
+	return p.next().value, nil
+}
+
+func parseGraphQL(src string) (*gqlDocument, error) {
+	tokens, err := gqlLex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{Fragments: map[string]*gqlFragment{}}
+	for p.peek().kind != 0 {
+		switch {
+		case p.is("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &gqlOperation{Type: "query", Selections: selections})
+		case p.is("query") || p.is("mutation") || p.is("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.skip("fragment"):
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			fragment := &gqlFragment{}
+			if err := p.expect("on"); err != nil {
+				return nil, err
+			}
+			if fragment.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+			if fragment.Selections, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+			doc.Fragments[name] = fragment
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, gqlErrorf("The document has no operation.")
+	}
+
+	return doc, nil
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	op := &gqlOperation{Type: p.next().value}
+	if p.peek().kind == 'n' {
+		op.Name = p.next().value
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			def := gqlVariableDef{}
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			def.Name = name
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if def.NonNull, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.skip("=") {
+				if def.Default, err = p.value(); err != nil {
+					return nil, err
+				}
+				def.HasDefault = true
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, def)
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+
+	return op, nil
+}
+
+// typeRef skips a type, reporting whether it's non-null.
+func (p *gqlParser) typeRef() (bool, error) {
+	if p.skip("[") {
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+
+	return p.skip("!"), nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	selections := []gqlSelection{}
+	for !p.skip("}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, gqlErrorf("Empty selection set at %d.", p.peek().pos)
+	}
+
+	return selections, nil
+}
+
+func (p *gqlParser) selection() (gqlSelection, error) {
+	s := gqlSelection{}
+	var err error
+	if p.skip("...") {
+		if p.peek().kind == 'n' && !p.is("on") {
+			s.Spread = p.next().value
+			s.Directives, err = p.directives()
+			return s, err
+		}
+		s.Inline = true
+		if p.skip("on") {
+			if s.TypeCondition, err = p.name(); err != nil {
+				return s, err
+			}
+		}
+		if s.Directives, err = p.directives(); err != nil {
+			return s, err
+		}
+		s.Selections, err = p.selectionSet()
+		return s, err
+	}
+
+	if s.Name, err = p.name(); err != nil {
+		return s, err
+	}
+	if p.skip(":") {
+		s.Alias = s.Name
+		if s.Name, err = p.name(); err != nil {
+			return s, err
+		}
+	}
+	if s.Args, err = p.arguments(); err != nil {
+		return s, err
+	}
+	if s.Directives, err = p.directives(); err != nil {
+		return s, err
+	}
+	if p.is("{") {
+		s.Selections, err = p.selectionSet()
+	}
+
+	return s, err
+}
+
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if !p.skip("(") {
+		return args, nil
+	}
+	for !p.skip(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
+}
+
+func (p *gqlParser) directives() (map[string]map[string]interface{}, error) {
+	directives := map[string]map[string]interface{}{}
+	for p.skip("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if directives[name], err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+
+	return directives, nil
+}
+
+func (p *gqlParser) value() (interface{}, error) {
+	token := p.next()
+	switch token.kind {
+	case 'i':
+		n, err := strconv.Atoi(token.value)
+		if err != nil {
+			return nil, gqlErrorf("Invalid number %q at %d.", token.value, token.pos)
+		}
+		return n, nil
+	case 'f':
+		f, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, gqlErrorf("Invalid number %q at %d.", token.value, token.pos)
+		}
+		return f, nil
+	case 's':
+		return token.value, nil
+	case 'n':
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// Enum values are taken as their names.
+		return token.value, nil
+	}
+
+	switch token.value {
+	case "$":
+		name, err := p.name()
+		return gqlVariable(name), err
+	case "[":
+		list := []interface{}{}
+		for !p.skip("]") {
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case "{":
+		object := map[string]interface{}{}
+		for !p.skip("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	}
+	if token.kind != 0 {
+		p.pos--
+	}
+
+	return nil, p.unexpected()
+}
+
+// Executor
+//
+// gqlType is an object type. Fields without Resolve are the field of the
+// same name in the JSON of the value, like the REST API answers with; fields
+// without Type are scalars.
+type gqlType struct {
+	Name   string
+	Fields map[string]*gqlField
+}
+
+type gqlField struct {
+	Type    *gqlType
+	Resolve func(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+// gqlRequest is the request an operation runs for.
+type gqlRequest struct {
+	c      *appContext
+	r      *http.Request
+	router http.Handler
+}
+
+// gqlObject is an object of the answer, keeping its fields in the order they
+// were asked for.
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+type gqlExecutor struct {
+	q         *gqlRequest
+	fragments map[string]*gqlFragment
+	variables map[string]interface{}
+	errors    []*GraphQLError
+}
+
+// execute runs the operation named name of doc, the only one when name is
+// empty.
+func (q *gqlRequest) execute(doc *gqlDocument, name string, variables map[string]interface{}) GraphQLResponse {
+	var op *gqlOperation
+	for _, candidate := range doc.Operations {
+		if candidate.Name == name || name == "" && len(doc.Operations) == 1 {
+			op = candidate
+		}
+	}
+	if op == nil {
+		return GraphQLResponse{Errors: []*GraphQLError{{Message: "operationName must name one of the operations of the document."}}}
+	}
+
+	root := gqlQueryType
+	switch {
+	case op.Type == "mutation" && q.r.Method != "POST":
+		return GraphQLResponse{Errors: []*GraphQLError{{Message: "Mutations must be sent with POST."}}}
+	case op.Type == "mutation":
+		root = gqlMutationType
+	case op.Type == "subscription":
+		return GraphQLResponse{Errors: []*GraphQLError{{Message: "Subscriptions aren't supported, see /ws."}}}
+	}
+
+	values := map[string]interface{}{}
+	for _, def := range op.Variables {
+		value, ok := variables[def.Name]
+		if !ok && def.HasDefault {
+			value, ok = def.Default, true
+		}
+		if (!ok || value == nil) && def.NonNull {
+			return GraphQLResponse{Errors: []*GraphQLError{{Message: fmt.Sprintf("Variable $%s is required.", def.Name)}}}
+		}
+		values[def.Name] = value
+	}
+
+	e := &gqlExecutor{q: q, fragments: doc.Fragments, variables: values}
+	data := e.object(root, nil, op.Selections, nil)
+
+	return GraphQLResponse{Data: data, Errors: e.errors}
+}
+
+func (e *gqlExecutor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, newGraphQLError(err, path))
+}
+
+// resolve replaces the variables in value.
+func (e *gqlExecutor) resolve(value interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariable:
+		return e.variables[string(v)]
+	case []interface{}:
+		list := []interface{}{}
+		for _, item := range v {
+			list = append(list, e.resolve(item))
+		}
+		return list
+	case map[string]interface{}:
+		object := map[string]interface{}{}
+		for key, item := range v {
+			object[key] = e.resolve(item)
+		}
+		return object
+	}
+
+	return value
+}
+
+// included reports whether the @skip and @include of directives keep a
+// selection.
+func (e *gqlExecutor) included(directives map[string]map[string]interface{}) bool {
+	if args, ok := directives["skip"]; ok && e.resolve(args["if"]) == true {
+		return false
+	}
+	if args, ok := directives["include"]; ok && e.resolve(args["if"]) != true {
+		return false
+	}
+
+	return true
+}
+
+// collect returns the fields of selections asked of t, in order, merging the
+// ones answered under the same key.
+func (e *gqlExecutor) collect(t *gqlType, selections []gqlSelection, fields []gqlSelection, seen map[string]bool) []gqlSelection {
+	for _, s := range selections {
+		if !e.included(s.Directives) {
+			continue
+		}
+		switch {
+		case s.Spread != "":
+			fragment, ok := e.fragments[s.Spread]
+			if ok && !seen[s.Spread] && fragment.TypeCondition == t.Name {
+				seen[s.Spread] = true
+				fields = e.collect(t, fragment.Selections, fields, seen)
+			}
+		case s.Inline:
+			if s.TypeCondition == "" || s.TypeCondition == t.Name {
+				fields = e.collect(t, s.Selections, fields, seen)
+			}
+		default:
+			merged := false
+			for i := range fields {
+				if fields[i].key() == s.key() {
+					fields[i].Selections = append(append([]gqlSelection{}, fields[i].Selections...), s.Selections...)
+					merged = true
+				}
+			}
+			if !merged {
+				fields = append(fields, s)
+			}
+		}
+	}
+
+	return fields
+}
+
+func (e *gqlExecutor) object(t *gqlType, source interface{}, selections []gqlSelection, path []interface{}) *gqlObject {
+	result := &gqlObject{values: map[string]interface{}{}}
+	var document map[string]interface{}
+	for _, s := range e.collect(t, selections, nil, map[string]bool{}) {
+		key := s.key()
+		fieldPath := append(append([]interface{}{}, path...), key)
+		result.keys = append(result.keys, key)
+		if s.Name == "__typename" {
+			result.values[key] = t.Name
+			continue
+		}
+
+		field, ok := t.Fields[s.Name]
+		if !ok {
+			e.fail(fieldPath, gqlErrorf("Cannot query field %q on type %q.", s.Name, t.Name))
+			result.values[key] = nil
+			continue
+		}
+
+		var value interface{}
+		var err error
+		if field.Resolve != nil {
+			args := e.resolve(s.Args).(map[string]interface{})
+			value, err = field.Resolve(e.q, source, args)
+		} else {
+			if document == nil {
+				document, err = jsonDocument(source)
+			}
+			value = document[s.Name]
+		}
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.values[key] = nil
+			continue
+		}
+		result.values[key] = e.complete(field.Type, value, s, fieldPath)
+	}
+
+	return result
+}
+
+// complete answers value, of type t, for the field s.
+func (e *gqlExecutor) complete(t *gqlType, value interface{}, s gqlSelection, path []interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	if value == nil || (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return nil
+	}
+	if t == nil {
+		if len(s.Selections) > 0 {
+			e.fail(path, gqlErrorf("Field %q is a scalar and has no fields.", s.Name))
+			return nil
+		}
+		return value
+	}
+	if rv.Kind() == reflect.Slice {
+		list := []interface{}{}
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, e.complete(t, rv.Index(i).Interface(), s, append(append([]interface{}{}, path...), i)))
+		}
+		return list
+	}
+	if len(s.Selections) == 0 {
+		e.fail(path, gqlErrorf("Field %q of type %q must select fields.", s.Name, t.Name))
+		return nil
+	}
+
+	return e.object(t, value, s.Selections, path)
+}
+
+// jsonDocument returns the fields of the JSON of value.
+func jsonDocument(value interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]interface{}{}
+	err = json.Unmarshal(b, &document)
+
+	return document, err
+}
+
+// Subrequests
+//
+// Mutations are made as the REST request they stand for, with the headers of
+// the GraphQL request, so they're checked and recorded exactly like it.
+
+// requestScope is the context of a request without its values, for requests
+// made on its behalf.
+type requestScope struct {
+	context.Context
+}
+
+func (requestScope) Value(key interface{}) interface{} {
+	return nil
+}
+
+// gqlRecorder keeps the answer to a subrequest.
+type gqlRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *gqlRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *gqlRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *gqlRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	return rec.body.Write(p)
+}
+
+// call makes the REST request method path with body, decoding the answer
+// into result unless it's nil. Errors are returned as the gqlError answered.
+func (q *gqlRequest) call(method string, path string, body interface{}, result interface{}) error {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return err
+		}
+	}
+	sub, err := http.NewRequestWithContext(requestScope{q.r.Context()}, method, "/"+requestVersion(q.r)+path, &b)
+	if err != nil {
+		return err
+	}
+	sub.Header = q.r.Header.Clone()
+	for _, name := range []string{"Accept-Encoding", "Content-Encoding", "Content-Length", "If-None-Match"} {
+		sub.Header.Del(name)
+	}
+	sub.Header.Set("Content-Type", "application/json")
+	sub.RemoteAddr = q.r.RemoteAddr
+
+	rec := &gqlRecorder{header: http.Header{}}
+	q.router.ServeHTTP(rec, sub)
+	if rec.status >= 400 {
+		errs := struct {
+			Errors []*Error `json:"errors"`
+		}{}
+		if err := json.Unmarshal(rec.body.Bytes(), &errs); err != nil || len(errs.Errors) == 0 {
+			return gqlError{&Error{"internal_server_error", rec.status, http.StatusText(rec.status), rec.body.String()}}
+		}
+		return gqlError{errs.Errors[0]}
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(rec.body.Bytes(), result)
+}
+
+// GraphQL Handlers
+func graphqlHandler(router http.Handler) func(*appContext, http.ResponseWriter, *http.Request) {
+	return func(c *appContext, w http.ResponseWriter, r *http.Request) {
+		req := GraphQLRequest{}
+		if r.Method == "POST" {
+			req = *r.Context().Value(bodyKey).(*GraphQLRequest)
+		} else {
+			query := r.URL.Query()
+			req.Query = query.Get("query")
+			req.OperationName = query.Get("operationName")
+			if v := query.Get("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{Errors: []*GraphQLError{{Message: "variables must be a JSON object."}}})
+					return
+				}
+			}
+		}
+
+		doc, err := parseGraphQL(req.Query)
+		if err != nil {
+			writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{Errors: []*GraphQLError{newGraphQLError(err, nil)}})
+			return
+		}
+
+		q := &gqlRequest{c: c, r: r, router: router}
+		res := q.execute(doc, req.OperationName, req.Variables)
+		status := http.StatusOK
+		if res.Data == nil {
+			status = http.StatusBadRequest
+		}
+		writeGraphQL(w, status, res)
+	}
+}
+
+func writeGraphQL(w http.ResponseWriter, status int, res GraphQLResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// GraphQL schema
+//
+//	type Query {
+//	  venues: [Venue]
+//	  venue(id: ID!): Venue                 # id or slug
+//	  rooms(venue_id: ID, min_capacity: Int): [Room]
+//	  room(id: ID!): Room
+//	  events(start_time: String, end_time: String, location_id: ID): [Event]
+//	  event(id: ID!): Event
+//	  availability(start_time: String!, end_time: String!, min_capacity: Int, venue_id: ID): [Room]
+//	  search(q: String!, start_time: String, end_time: String): [Event]
+//	}
+//
+//	type Mutation {
+//	  book(input: EventInput!): Event       # POST /events
+//	  cancel(id: ID!): Boolean              # DELETE /events/:id
+//	}
+//
+//	type Venue { id name slug time_zone description rooms: [Room] }
+//	type Room {
+//	  id name slug venue_id floor_id capacity description high_demand
+//	  venue: Venue
+//	  events(start_time: String, end_time: String): [Event]
+//	}
+//	type Event {
+//	  id name location_id location description guests owner start_time
+//	  end_time time_zone category tentative pending_approval
+//	  recurring_event_id room: Room participants: [Participant] rsvp: RSVP
+//	}
+//	type Participant { email status optional responded_at }
+//	type RSVP { accepted declined tentative needs_action required_pending }
+//
+// Times are RFC 3339, and windows default to this week like GET /events.
+// Recurring events are expanded into their occurrences within the window.
+// availability lists the rooms with no booking overlapping the window.
+// EventInput is the body of POST /events, which book is made as, so it's
+// checked, and answers errors, like that request; cancel likewise.
+var (
+	gqlQueryType       = &gqlType{Name: "Query"}
+	gqlMutationType    = &gqlType{Name: "Mutation"}
+	gqlVenueType       = &gqlType{Name: "Venue"}
+	gqlRoomType        = &gqlType{Name: "Room"}
+	gqlEventType       = &gqlType{Name: "Event"}
+	gqlParticipantType = &gqlType{Name: "Participant"}
+	gqlRSVPType        = &gqlType{Name: "RSVP"}
+)
+
+// gqlScalars returns the fields named names, answered from the JSON of the
+// value.
+func gqlScalars(names ...string) map[string]*gqlField {
+	fields := map[string]*gqlField{}
+	for _, name := range names {
+		fields[name] = &gqlField{}
+	}
+
+	return fields
+}
+
+func init() {
+	gqlQueryType.Fields = map[string]*gqlField{
+		"venues":       {Type: gqlVenueType, Resolve: gqlVenues},
+		"venue":        {Type: gqlVenueType, Resolve: gqlVenue},
+		"rooms":        {Type: gqlRoomType, Resolve: gqlRooms},
+		"room":         {Type: gqlRoomType, Resolve: gqlRoom},
+		"events":       {Type: gqlEventType, Resolve: gqlEvents},
+		"event":        {Type: gqlEventType, Resolve: gqlEvent},
+		"availability": {Type: gqlRoomType, Resolve: gqlAvailability},
+		"search":       {Type: gqlEventType, Resolve: gqlSearch},
+	}
+	gqlMutationType.Fields = map[string]*gqlField{
+		"book":   {Type: gqlEventType, Resolve: gqlBook},
+		"cancel": {Resolve: gqlCancel},
+	}
+
+	gqlVenueType.Fields = gqlScalars("id", "name", "slug", "time_zone", "description")
+	gqlVenueType.Fields["rooms"] = &gqlField{Type: gqlRoomType, Resolve: gqlVenueRooms}
+
+	gqlRoomType.Fields = gqlScalars("id", "name", "slug", "venue_id", "floor_id", "capacity", "description", "high_demand")
+	gqlRoomType.Fields["venue"] = &gqlField{Type: gqlVenueType, Resolve: gqlRoomVenue}
+	gqlRoomType.Fields["events"] = &gqlField{Type: gqlEventType, Resolve: gqlRoomEvents}
+
+	gqlEventType.Fields = gqlScalars("id", "name", "location_id", "location", "description", "guests", "owner",
+		"start_time", "end_time", "time_zone", "category", "tentative", "pending_approval", "recurring_event_id")
+	gqlEventType.Fields["room"] = &gqlField{Type: gqlRoomType, Resolve: gqlEventRoom}
+	gqlEventType.Fields["participants"] = &gqlField{Type: gqlParticipantType, Resolve: gqlEventParticipants}
+	gqlEventType.Fields["rsvp"] = &gqlField{Type: gqlRSVPType, Resolve: gqlEventRSVP}
+
+	gqlParticipantType.Fields = gqlScalars("email", "status", "optional", "responded_at")
+	gqlRSVPType.Fields = gqlScalars("accepted", "declined", "tentative", "needs_action", "required_pending")
+}
+
+// Arguments
+func gqlString(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func gqlInt(args map[string]interface{}, name string) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	}
+
+	return 0, gqlErrorf("%s must be an Int.", name)
+}
+
+// window returns the start_time and end_time of args, this week when they're
+// not required and left out.
+func (q *gqlRequest) window(args map[string]interface{}, required bool) (time.Time, time.Time, error) {
+	locale := requestLocale(q.r)
+	times := []time.Time{locale.BeginningOfWeek(clockNow()), locale.EndOfWeek(clockNow())}
+	for i, name := range []string{"start_time", "end_time"} {
+		s := gqlString(args, name)
+		if s == "" && required {
+			return time.Time{}, time.Time{}, gqlErrorf("%s is required.", name)
+		}
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, gqlError{ErrInvalidTime}
+		}
+		times[i] = t
+	}
+	if errRes := checkWindow(times[0], times[1]); errRes != nil {
+		return time.Time{}, time.Time{}, gqlError{errRes}
+	}
+
+	return times[0], times[1], nil
+}
+
+func (q *gqlRequest) ctx() context.Context {
+	return q.r.Context()
+}
+
+// gqlRepoError is the error a field answers with when a repo fails with err.
+func gqlRepoError(err error) error {
+	switch err {
+	case ErrDocumentNotFound:
+		return gqlError{ErrNotFound}
+	case ErrInvalidId:
+		return gqlError{ErrMalformedId}
+	}
+	log.Printf("graphql: repo error: %v", err)
+
+	return gqlError{ErrInternalServer}
+}
+
+// gqlFound answers value, or null when there's none.
+func gqlFound(value interface{}, err error) (interface{}, error) {
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	return value, nil
+}
+
+// localize sets the times of events in their zone, see timezone.go.
+func (q *gqlRequest) localize(events []Event) ([]Event, error) {
+	zones, errRes := q.c.zones(q.r)
+	if errRes != nil {
+		return nil, gqlError{errRes}
+	}
+	zones.events(events)
+	withRSVP(events)
+
+	return events, nil
+}
+
+// roomEvents returns the events of the room with id within
+// [start_time, end_time), with the occurrences of its recurring ones.
+func (q *gqlRequest) roomEvents(id string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	repo := q.c.events()
+	events, err := repo.AllByLocationIds(q.ctx(), []string{id}, start_time, end_time)
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+	occurrences, err := repo.AllOccurrences(q.ctx(), id, start_time, end_time, "")
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if event.Recurrence == nil {
+			result = append(result, event)
+		}
+	}
+	for _, occurrence := range occurrences {
+		if !occurrence.StartTime.Before(start_time) {
+			result = append(result, occurrence)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].StartTime.Before(result[j].StartTime) })
+
+	return q.localize(result)
+}
+
+// Query
+func gqlVenues(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	venues, _, err := q.c.venues().All(q.ctx(), storage.ListOptions{Sort: []string{"name"}})
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	return venues, nil
+}
+
+func gqlVenue(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	venue, _, err := resolveVenue(q.ctx(), q.c.venues(), gqlString(args, "id"))
+	return gqlFound(venue, err)
+}
+
+func gqlRooms(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	minCapacity, err := gqlInt(args, "min_capacity")
+	if err != nil {
+		return nil, err
+	}
+	rooms, _, err := q.c.rooms().AllMatching(q.ctx(), RoomFilter{MinCapacity: minCapacity}, storage.ListOptions{Sort: []string{"name"}})
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	venueId := gqlString(args, "venue_id")
+	result := []Room{}
+	for _, room := range rooms {
+		if venueId == "" || room.VenueId == venueId {
+			result = append(result, room)
+		}
+	}
+
+	return result, nil
+}
+
+func gqlRoom(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return gqlFound(q.c.rooms().Find(q.ctx(), gqlString(args, "id")))
+}
+
+func gqlEvents(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	start_time, end_time, err := q.window(args, false)
+	if err != nil {
+		return nil, err
+	}
+	if id := gqlString(args, "location_id"); id != "" {
+		return q.roomEvents(id, start_time, end_time)
+	}
+
+	events, err := q.c.events().All(q.ctx(), start_time, end_time, false)
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	return q.localize(q.c.withOccurrences(q.ctx(), events, start_time, end_time))
+}
+
+func gqlEvent(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	event, err := q.c.events().Find(q.ctx(), gqlString(args, "id"))
+	if err != nil {
+		return gqlFound(nil, err)
+	}
+	events, err := q.localize([]Event{event})
+	if err != nil {
+		return nil, err
+	}
+
+	return events[0], nil
+}
+
+func gqlAvailability(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	start_time, end_time, err := q.window(args, true)
+	if err != nil {
+		return nil, err
+	}
+	value, err := gqlRooms(q, source, args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Room{}
+	for _, room := range value.([]Room) {
+		events, err := q.c.events().Overlapping(q.ctx(), room.Id.Hex(), start_time, end_time, "")
+		if err != nil {
+			return nil, gqlRepoError(err)
+		}
+		if len(events) == 0 {
+			result = append(result, room)
+		}
+	}
+
+	return result, nil
+}
+
+func gqlSearch(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	start_time, end_time, err := q.window(args, false)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := q.c.events()
+	events, err := repo.Search(q.r.Context(), EventSearch{Text: strings.TrimSpace(gqlString(args, "q")), StartTime: start_time, EndTime: end_time})
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	return q.localize(events)
+}
+
+// Mutation
+func gqlBook(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	event := Event{}
+	err := q.call("POST", "/events", args["input"], &event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func gqlCancel(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	err := q.call("DELETE", "/events/"+url.PathEscape(gqlString(args, "id")), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+// Venue, Room and Event
+func gqlVenueRooms(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	rooms, err := q.c.rooms().AllByVenueId(q.ctx(), source.(Venue).Id.Hex())
+	if err != nil {
+		return nil, gqlRepoError(err)
+	}
+
+	return rooms, nil
+}
+
+func gqlRoomVenue(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return gqlFound(q.c.venues().Find(q.ctx(), source.(Room).VenueId))
+}
+
+func gqlRoomEvents(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	start_time, end_time, err := q.window(args, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.roomEvents(source.(Room).Id.Hex(), start_time, end_time)
+}
+
+func gqlEventRoom(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	id := source.(Event).LocationID
+	if id == "" {
+		return nil, nil
+	}
+
+	return gqlFound(q.c.rooms().Find(q.ctx(), id))
+}
+
+func gqlEventParticipants(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return participants(source.(Event)), nil
+}
+
+func gqlEventRSVP(q *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+	return rsvpSummary(source.(Event)), nil
+}
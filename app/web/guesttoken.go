@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Guest tokens
+//
+// The owner of an event can give people outside the user directory a token
+// scoped to that event with POST /events/:id/guest-tokens. The token is only
+// returned once, and only its hash is stored. It's sent as
+// "Authorization: Guest <token>" (or ?guest_token=) and lets the guest do what
+// its scopes allow on routes wrapped with guestHandler, from guestTokenLead
+// before the event starts until it ends. Tokens can be revoked with
+// DELETE /guest-tokens/:id.
+const (
+	GuestView     = "view"
+	GuestRSVP     = "rsvp"
+	GuestWaitlist = "waitlist"
+	GuestCheckIn  = "check_in"
+
+	RoleGuest = "guest"
+
+	guestTokenLead  = 24 * time.Hour
+	guestTokenBytes = 24
+)
+
+type GuestToken struct {
+	Id        storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Token     string           `json:"token,omitempty" bson:"-"`
+	Hash      string           `json:"-"`
+	EventId   storage.ObjectId `json:"event_id"`
+	Guest     string           `json:"guest"`
+	Scopes    []string         `json:"scopes"`
+	NotBefore time.Time        `json:"not_before"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+	RevokedAt time.Time        `json:"revoked_at,omitempty"`
+
+	// OrgId is the organization of the event, see organization.go.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+}
+
+func hashGuestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newGuestToken() string {
+	b := make([]byte, guestTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Allows reports whether t lets its guest act with scope on eventId at now.
+func (t GuestToken) Allows(eventId string, scope string, now time.Time) bool {
+	if t.EventId.Hex() != eventId || !t.RevokedAt.IsZero() {
+		return false
+	}
+	if now.Before(t.NotBefore) || !now.Before(t.ExpiresAt) {
+		return false
+	}
+
+	return contains(t.Scopes, scope)
+}
+
+// guestTokenParam returns the guest token the request carries, if any.
+func guestTokenParam(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Guest ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Guest "))
+	}
+
+	return r.URL.Query().Get("guest_token")
+}
+
+// Repo GuestToken
+type GuestTokenRepo struct {
+	coll *storage.Collection
+}
+
+func (r *GuestTokenRepo) FindByToken(token string) (GuestToken, error) {
+	result := GuestToken{}
+	err := r.coll.Find(bson.M{"hash": hashGuestToken(token)}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *GuestTokenRepo) Find(id string) (GuestToken, error) {
+	result := GuestToken{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *GuestTokenRepo) Create(token *GuestToken) error {
+	id := storage.NewObjectId()
+	token.Hash = hashGuestToken(token.Token)
+	_, err := r.coll.UpsertId(id, token)
+	if err != nil {
+		return err
+	}
+
+	token.Id = id
+
+	return nil
+}
+
+func (r *GuestTokenRepo) Revoke(id storage.ObjectId, t time.Time) error {
+	err := r.coll.UpdateId(id, bson.M{"$set": bson.M{"revokedat": t}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Middleware
+// guestHandler admits requests carrying a guest token that allows scope on
+// the event at :id, as a user with the guest role. Requests without a token
+// are passed on unchanged.
+func guestHandler(c *appContext, scope string) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			value := guestTokenParam(r)
+			if value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			repo := GuestTokenRepo{c.forRequest(r).allOrgs(r).C("guest_tokens")}
+			token, err := repo.FindByToken(value)
+			if err == storage.ErrNotFound {
+				WriteError(w, ErrInvalidGuestToken)
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+
+			params := routeParams(r)
+			if !token.Allows(params.ByName("id"), scope, clockNow()) {
+				WriteError(w, ErrInvalidGuestToken)
+				return
+			}
+
+			r = c.inOrg(r, token.OrgId)
+			r = withValue(r, userKey, User{Email: token.Guest, Role: RoleGuest})
+			r = withValue(r, guestTokenKey, token)
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// Guest Token Handlers
+func (c *appContext) createGuestTokenHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*GuestToken)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+
+	end_time := event.EndTime
+	if event.Recurrence != nil {
+		end_time = event.SeriesEndTime
+	}
+	if !end_time.After(clockNow()) {
+		WriteError(w, ErrEventEnded)
+		return
+	}
+
+	token := GuestToken{
+		Token:     newGuestToken(),
+		EventId:   event.Id,
+		Guest:     body.Guest,
+		Scopes:    body.Scopes,
+		NotBefore: event.StartTime.Add(-guestTokenLead),
+		ExpiresAt: end_time,
+		CreatedBy: user.Email,
+		CreatedAt: time.Now(),
+	}
+	if len(token.Scopes) == 0 {
+		token.Scopes = []string{GuestView, GuestRSVP, GuestWaitlist, GuestCheckIn}
+	}
+
+	tokenRepo := GuestTokenRepo{c.db.C("guest_tokens")}
+	err = tokenRepo.Create(&token)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, token)
+}
+
+func (c *appContext) revokeGuestTokenHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	if !storage.IsObjectIdHex(params.ByName("id")) {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	tokenRepo := GuestTokenRepo{c.db.C("guest_tokens")}
+	token, err := tokenRepo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	repo := c.events()
+	event, err := repo.Find(r.Context(), token.EventId.Hex())
+	if err != nil && err != ErrDocumentNotFound {
+		WriteRepoError(w, err)
+		return
+	}
+	user := r.Context().Value(userKey).(User)
+	if err == nil && !user.CanManage(event) || err != nil && !user.IsAdmin() {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+
+	err = tokenRepo.Revoke(token.Id, time.Now())
+	if err != nil {
+		panic(err)
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "Guest token has been revoked successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// checkInEventHandler checks the caller in to the event at :id, from
+// checkInEarly before it starts until it ends. Guests need a token with the
+// check_in scope, users must own or be invited to the event.
+func (c *appContext) checkInEventHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	user := r.Context().Value(userKey).(User)
+	if user.Role != RoleGuest && !user.CanManage(event) && !contains(event.Guests, user.Email) {
+		WriteError(w, ErrForbidden)
+		return
+	}
+
+	now := clockNow()
+	if now.Before(event.StartTime.Add(-checkInEarly)) || !now.Before(event.EndTime) {
+		WriteError(w, ErrCheckInClosed)
+		return
+	}
+
+	if event.CheckedInAt.IsZero() {
+		err = repo.CheckIn(r.Context(), event.Id, now)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		event.CheckedInAt = now
+		c.recordChange("event", event.Id, ChangeUpdated)
+	}
+
+	WriteSuccess(w, http.StatusOK, event)
+}
@@ -0,0 +1,1890 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"github.com/justinas/alice"
+)
+
+// testApp serves the routes of main, see routes, on in-memory repos with no
+// database behind the appContext, as user, within its organization.
+type testApp struct {
+	c      *appContext
+	router *router
+	user   User
+}
+
+func newTestApp(t *testing.T, user User) *testApp {
+	t.Helper()
+
+	repos := NewMemRepositories()
+	repos.Users = map[string]User{user.Email: user}
+	c := newAppContext(nil, repos)
+
+	return &testApp{c, routes(c), user}
+}
+
+// ServeHTTP serves r as the user of the app.
+func (a *testApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Header.Set("X-User-Email", a.user.Email)
+	a.router.ServeHTTP(w, r)
+}
+
+// do serves a request with body encoded as JSON, decoding the response into
+// result unless it's nil.
+func (a *testApp) do(t *testing.T, method string, target string, body interface{}, result interface{}) int {
+	t.Helper()
+
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(method, target, &b))
+
+	if result != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s: %v in %s", method, target, err, w.Body.String())
+		}
+	}
+
+	return w.Code
+}
+
+func (a *testApp) venue(t *testing.T, name string) Venue {
+	t.Helper()
+
+	venue := Venue{Name: name}
+	if err := assignVenueSlug(context.Background(), a.c.venues(), &venue, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.c.venues().Create(context.Background(), &venue); err != nil {
+		t.Fatal(err)
+	}
+
+	return venue
+}
+
+func (a *testApp) room(t *testing.T, venue Venue, name string, capacity int) Room {
+	t.Helper()
+
+	room := Room{Name: name, VenueId: venue.Id.Hex(), Capacity: capacity}
+	if err := assignRoomSlug(context.Background(), a.c.rooms(), &room, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.c.rooms().Create(context.Background(), &room); err != nil {
+		t.Fatal(err)
+	}
+
+	return room
+}
+
+func (a *testApp) event(t *testing.T, event Event) Event {
+	t.Helper()
+
+	if err := a.c.events().Create(context.Background(), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+var testAdmin = User{Email: "admin@example.com", Role: RoleAdmin}
+
+func TestVenueHandlers(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+
+	created := Venue{}
+	if code := app.do(t, "POST", "/venues", map[string]string{"name": "Main Office"}, &created); code != http.StatusCreated {
+		t.Fatalf("POST /venues: got %d, want 201", code)
+	}
+	if created.Slug != "main-office" || !created.Id.Valid() {
+		t.Fatalf("POST /venues: got slug %q and id %q", created.Slug, created.Id.Hex())
+	}
+	if code := app.do(t, "POST", "/venues", map[string]string{"name": " "}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /venues without a name: got %d, want 422", code)
+	}
+
+	app.room(t, created, "Board Room", 12)
+
+	venues := []Venue{}
+	if code := app.do(t, "GET", "/venues", nil, &venues); code != http.StatusOK {
+		t.Fatalf("GET /venues: got %d, want 200", code)
+	}
+	if len(venues) != 1 || venues[0].RoomsCount == nil || *venues[0].RoomsCount != 1 {
+		t.Fatalf("GET /venues: got %+v, want the venue with one room", venues)
+	}
+	if hint := venues[0].NextAvailableRoom; hint == nil || hint.Name != "Board Room" {
+		t.Errorf("GET /venues: got next available room %+v, want Board Room", hint)
+	}
+
+	found := Venue{}
+	if code := app.do(t, "GET", "/venues/main-office", nil, &found); code != http.StatusOK || found.Id != created.Id {
+		t.Errorf("GET /venues/main-office: got %d and %+v", code, found)
+	}
+
+	updated := Venue{}
+	if code := app.do(t, "PATCH", "/venues/"+created.Id.Hex(), map[string]string{"name": "Main Office", "time_zone": "Asia/Jakarta"}, &updated); code != http.StatusAccepted {
+		t.Fatalf("PATCH /venues/:id: got %d, want 202", code)
+	}
+	if updated.TimeZone != "Asia/Jakarta" || updated.Slug != "main-office" {
+		t.Errorf("PATCH /venues/:id: got %+v", updated)
+	}
+
+	updated = Venue{}
+	patch := map[string]interface{}{"description": "Head office", "booking_rules": map[string]int{"max_duration": 120}}
+	if code := app.do(t, "PATCH", "/venues/"+created.Id.Hex(), patch, &updated); code != http.StatusAccepted {
+		t.Fatalf("PATCH /venues/:id with some fields: got %d, want 202", code)
+	}
+	if updated.Name != "Main Office" || updated.TimeZone != "Asia/Jakarta" || updated.Description != "Head office" {
+		t.Errorf("PATCH /venues/:id with some fields: got %+v, want the others kept", updated)
+	}
+	updated = Venue{}
+	if code := app.do(t, "PATCH", "/venues/"+created.Id.Hex(), map[string]interface{}{"time_zone": nil}, &updated); code != http.StatusAccepted {
+		t.Fatalf("PATCH /venues/:id removing a field: got %d, want 202", code)
+	}
+	if updated.TimeZone != "" || updated.BookingRules == nil || updated.BookingRules.MaxDuration != 120 {
+		t.Errorf("PATCH /venues/:id removing a field: got %+v", updated)
+	}
+	if code := app.do(t, "PATCH", "/venues/"+created.Id.Hex(), map[string]interface{}{"name": " "}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH /venues/:id with a blank name: got %d, want 422", code)
+	}
+
+	if code := app.do(t, "DELETE", "/venues/"+created.Id.Hex(), nil, nil); code != http.StatusAccepted {
+		t.Fatalf("DELETE /venues/:id: got %d, want 202", code)
+	}
+	if code := app.do(t, "GET", "/venues/"+created.Id.Hex(), nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET of a deleted venue: got %d, want 404", code)
+	}
+	if code := app.do(t, "GET", "/venues/"+created.Id.Hex()[:10], nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET /venues/:id of an unknown slug: got %d, want 404", code)
+	}
+}
+
+func TestRoomHandlers(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+
+	created := Room{}
+	body := map[string]interface{}{"name": "Board Room", "venue_id": venue.Id.Hex(), "capacity": 12}
+	if code := app.do(t, "POST", "/rooms", body, &created); code != http.StatusCreated {
+		t.Fatalf("POST /rooms: got %d, want 201", code)
+	}
+	if code := app.do(t, "POST", "/rooms", map[string]string{"name": "Closet", "venue_id": venue.Id.Hex()}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /rooms without a capacity: got %d, want 422", code)
+	}
+	app.room(t, venue, "Huddle", 4)
+
+	for target, want := range map[string]int{"/rooms": 2, "/rooms?min_capacity=10": 1, "/rooms?min_capacity=20": 0} {
+		rooms := []Room{}
+		if code := app.do(t, "GET", target, nil, &rooms); code != http.StatusOK || len(rooms) != want {
+			t.Errorf("GET %s: got %d with %d rooms, want %d rooms", target, code, len(rooms), want)
+		}
+	}
+
+	rooms := []Room{}
+	if code := app.do(t, "GET", "/venues/"+venue.Slug+"/rooms", nil, &rooms); code != http.StatusOK || len(rooms) != 2 {
+		t.Errorf("GET /venues/:id/rooms: got %d with %d rooms, want 2", code, len(rooms))
+	}
+
+	found := Room{}
+	if code := app.do(t, "GET", "/rooms/"+created.Id.Hex(), nil, &found); code != http.StatusOK || found.Name != "Board Room" {
+		t.Errorf("GET /rooms/:id: got %d and %+v", code, found)
+	}
+	if code := app.do(t, "GET", "/rooms/nope", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("GET /rooms/:id with a malformed id: got %d, want 400", code)
+	}
+
+	updated := Room{}
+	if code := app.do(t, "PATCH", "/rooms/"+created.Id.Hex(), map[string]interface{}{"capacity": 20}, &updated); code != http.StatusAccepted {
+		t.Fatalf("PATCH /rooms/:id: got %d, want 202", code)
+	}
+	if updated.Capacity != 20 || updated.Name != "Board Room" || updated.VenueId != venue.Id.Hex() {
+		t.Errorf("PATCH /rooms/:id: got %+v, want the capacity changed and the rest kept", updated)
+	}
+	if code := app.do(t, "PATCH", "/rooms/"+created.Id.Hex(), map[string]interface{}{"capacity": 0}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH /rooms/:id to no capacity: got %d, want 422", code)
+	}
+
+	placed := map[string]interface{}{"building": "Tower A", "floor": "12", "map_coordinates": map[string]float64{"x": 4, "y": 7.5}}
+	if code := app.do(t, "PATCH", "/rooms/"+created.Id.Hex(), placed, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH /rooms/:id with map coordinates and no floor: got %d, want 422", code)
+	}
+	placed["floor_id"] = exampleObjectId
+	placed["photo_url"] = "https://cdn.example.com/board-room.jpg"
+	updated = Room{}
+	if code := app.do(t, "PATCH", "/rooms/"+created.Id.Hex(), placed, &updated); code != http.StatusAccepted {
+		t.Fatalf("PATCH /rooms/:id with its location: got %d, want 202", code)
+	}
+	if updated.Building != "Tower A" || updated.MapCoordinates == nil || updated.MapCoordinates.Y != 7.5 {
+		t.Errorf("PATCH /rooms/:id with its location: got %+v", updated)
+	}
+	if code := app.do(t, "PATCH", "/rooms/"+created.Id.Hex(), map[string]interface{}{"photo_url": "javascript:alert(1)"}, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH /rooms/:id with a script photo_url: got %d, want 422", code)
+	}
+}
+
+func TestEventHandlers(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	room := app.room(t, venue, "Board Room", 12)
+	other := app.room(t, app.venue(t, "Warehouse"), "Loading Bay", 6)
+
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	single := app.event(t, Event{Name: "Review", LocationID: room.Id.Hex(), StartTime: monday, EndTime: monday.Add(time.Hour)})
+	standup := Event{Name: "Standup", LocationID: room.Id.Hex(), StartTime: monday.Add(2 * time.Hour), EndTime: monday.Add(2*time.Hour + 15*time.Minute)}
+	standup.SetRecurrence(&Recurrence{Frequency: FrequencyDaily, Count: 5})
+	app.event(t, standup)
+	app.event(t, Event{Name: "Delivery", LocationID: other.Id.Hex(), StartTime: monday, EndTime: monday.Add(time.Hour)})
+
+	window := "start_time=2030-06-03T00:00:00Z&end_time=2030-06-05T00:00:00Z"
+	events := []Event{}
+	if code := app.do(t, "GET", "/events?"+window, nil, &events); code != http.StatusOK {
+		t.Fatalf("GET /events: got %d, want 200", code)
+	}
+	names := map[string]int{}
+	for _, event := range events {
+		names[event.Name]++
+	}
+	if len(events) != 4 || names["Standup"] != 2 || names["Review"] != 1 || names["Delivery"] != 1 {
+		t.Errorf("GET /events: got %v, want Review, Delivery and two Standups", names)
+	}
+
+	events = []Event{}
+	if code := app.do(t, "GET", "/venues/"+venue.Slug+"/events?"+window, nil, &events); code != http.StatusOK {
+		t.Fatalf("GET /venues/:id/events: got %d, want 200", code)
+	}
+	for _, event := range events {
+		if event.LocationID != room.Id.Hex() {
+			t.Errorf("GET /venues/:id/events: got %q of another venue", event.Name)
+		}
+	}
+
+	found := EventResponse{}
+	if code := app.do(t, "GET", "/events/"+single.Id.Hex(), nil, &found); code != http.StatusOK || found.Name != "Review" {
+		t.Errorf("GET /events/:id: got %d and %+v", code, found)
+	}
+	if code := app.do(t, "GET", "/events/"+room.Id.Hex(), nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET /events/:id of an unknown event: got %d, want 404", code)
+	}
+}
+
+func TestRoomLocationHandler(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	room := app.room(t, app.venue(t, "Main Office"), "Board Room", 12)
+
+	// POST /rooms/:id/events goes on to book the event in Google Calendar,
+	// so the body it's handed over is echoed instead.
+	app.router.Post("/rooms/:id/location", alice.New(roomLocationHandler(app.c), bodyHandler(EventResponse{})).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteSuccess(w, http.StatusOK, r.Context().Value(bodyKey))
+	}))
+	body := EventResponse{}
+	if code := app.do(t, "POST", "/rooms/"+room.Id.Hex()+"/location", map[string]string{"name": "Review"}, &body); code != http.StatusOK {
+		t.Fatalf("POST /rooms/:id/events: got %d, want 200", code)
+	}
+	if body.LocationID != room.Id.Hex() || body.Location != "Board Room" {
+		t.Errorf("POST /rooms/:id/events: got location %q %q, want the room's", body.LocationID, body.Location)
+	}
+
+	mismatch := map[string]string{"name": "Review", "location_id": app.venue(t, "Warehouse").Id.Hex()}
+	if code := app.do(t, "POST", "/rooms/"+room.Id.Hex()+"/events", mismatch, nil); code != ErrLocationMismatch.Status {
+		t.Errorf("POST /rooms/:id/events for another location: got %d, want %d", code, ErrLocationMismatch.Status)
+	}
+	if code := app.do(t, "POST", "/rooms/nope/events", map[string]string{"name": "Review"}, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /rooms/:id/events with a malformed id: got %d, want 400", code)
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	room := app.room(t, app.venue(t, "Main Office"), "Huddle", 2)
+
+	tests := []struct {
+		name   string
+		event  Event
+		wantId string
+	}{
+		{"fits", Event{LocationID: room.Id.Hex(), Guests: []string{"a@example.com"}}, ""},
+		{"over capacity", Event{LocationID: room.Id.Hex(), Guests: []string{"a@example.com", "b@example.com"}}, "over_capacity"},
+		{"unknown room", Event{LocationID: app.venue(t, "Warehouse").Id.Hex()}, ErrUnknownLocation.Id},
+		{"no room", Event{}, ""},
+	}
+	for _, test := range tests {
+		errRes := app.c.checkCapacity(context.Background(), test.event)
+		if got := ""; errRes != nil {
+			got = errRes.Id
+			if got != test.wantId {
+				t.Errorf("%s: got %q, want %q", test.name, got, test.wantId)
+			}
+		} else if test.wantId != "" {
+			t.Errorf("%s: got no error, want %q", test.name, test.wantId)
+		}
+	}
+}
+
+func TestMemEventRepoConflict(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	room := app.room(t, app.venue(t, "Main Office"), "Board Room", 12)
+
+	start := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	booked := app.event(t, Event{Name: "Review", LocationID: room.Id.Hex(), StartTime: start, EndTime: start.Add(time.Hour)})
+
+	overlapping := Event{Name: "Planning", LocationID: room.Id.Hex(), StartTime: start.Add(30 * time.Minute), EndTime: start.Add(90 * time.Minute)}
+	err := app.c.events().Create(context.Background(), &overlapping)
+	if conflict, ok := err.(*EventConflict); !ok || conflict.Event.Id != booked.Id {
+		t.Fatalf("Create of an overlapping event: got %v, want a conflict with %s", err, booked.Id.Hex())
+	}
+
+	after := Event{Name: "Planning", LocationID: room.Id.Hex(), StartTime: start.Add(time.Hour), EndTime: start.Add(2 * time.Hour)}
+	if err := app.c.events().Create(context.Background(), &after); err != nil {
+		t.Errorf("Create of an adjacent event: got %v, want no error", err)
+	}
+}
+
+func TestBumpable(t *testing.T) {
+	t.Setenv("OVERBOOK_PRIORITIES", "board=100")
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	room := app.room(t, venue, "Auditorium", 50)
+	small := app.room(t, venue, "Huddle", 4)
+
+	start := clockNow().Add(72 * time.Hour).Truncate(time.Hour)
+	tentative := app.event(t, Event{Name: "Offsite", LocationID: room.Id.Hex(), StartTime: start, EndTime: start.Add(time.Hour), Tentative: true})
+
+	board := Event{Name: "Board", Category: "board", LocationID: room.Id.Hex(), StartTime: start, EndTime: start.Add(time.Hour)}
+	bumpedRoom, bumped := app.c.bumpable(context.Background(), board)
+	if bumpedRoom.Id != room.Id || len(bumped) != 1 || bumped[0].Id != tentative.Id {
+		t.Errorf("bumpable: got %v in %q, want the tentative booking", bumped, bumpedRoom.Name)
+	}
+
+	board.LocationID = small.Id.Hex()
+	if _, bumped := app.c.bumpable(context.Background(), board); len(bumped) != 0 {
+		t.Errorf("bumpable below OVERBOOK_MIN_CAPACITY: got %v, want none", bumped)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	app := newTestApp(t, User{Email: "admin@example.com", Role: RoleAdmin})
+	app.c.config.CORS = CORSPolicy{Origins: []string{"https://app.example.com"}, Methods: []string{"GET", "PATCH"}, Headers: corsHeaders, MaxAge: defaultCORSMaxAge}
+	app.router = routes(app.c)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", "/venues/0123456789abcdef01234567", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", "PATCH")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	w := preflight("https://app.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight: got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("preflight: allowed origin %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, PATCH, OPTIONS" {
+		t.Errorf("preflight: allowed methods %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("preflight: max age %q", got)
+	}
+
+	w = preflight("https://evil.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("preflight of another origin: allowed origin %q", got)
+	}
+
+	r := httptest.NewRequest("GET", "/venues", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("GET /venues: allowed origin %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("GET /venues: vary %q", got)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	for i := 0; i < 20; i++ {
+		app.room(t, venue, "Huddle", 4)
+	}
+
+	get := func(header string, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/rooms", nil)
+		r.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("Accept-Encoding", "gzip")
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("GET /rooms accepting gzip: content encoding %q", got)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rooms := []Room{}
+	if err := json.NewDecoder(gz).Decode(&rooms); err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 20 {
+		t.Errorf("GET /rooms accepting gzip: got %d rooms, want 20", len(rooms))
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET /rooms: no ETag")
+	}
+	if w := get("If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("GET /rooms with a matching ETag: got %d and %d bytes, want 304 and none", w.Code, w.Body.Len())
+	}
+
+	app.room(t, venue, "Board Room", 12)
+	if w := get("If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("GET /rooms after a change: got %d, want 200", w.Code)
+	}
+}
+
+func TestCompressionStreams(t *testing.T) {
+	serve := func(contentType string, size int, flush bool) *httptest.ResponseRecorder {
+		h := compressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.Write(bytes.Repeat([]byte("a"), size))
+			if flush {
+				w.(http.Flusher).Flush()
+			}
+		}))
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("text/csv", etagMaxSize+1, false)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") != "" {
+		t.Errorf("large body: got encoding %q and ETag %q, want gzip and none", w.Header().Get("Content-Encoding"), w.Header().Get("ETag"))
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := ioutil.ReadAll(gz); err != nil || len(body) != etagMaxSize+1 {
+		t.Errorf("large body: got %d bytes, %v", len(body), err)
+	}
+
+	for _, contentType := range []string{"image/png", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/zip"} {
+		if w := serve(contentType, etagMaxSize+1, false); w.Header().Get("Content-Encoding") != "" || w.Body.Len() != etagMaxSize+1 {
+			t.Errorf("%s: got encoding %q and %d bytes, want it sent as is", contentType, w.Header().Get("Content-Encoding"), w.Body.Len())
+		}
+	}
+
+	for _, contentType := range []string{"text/event-stream", "application/x-ndjson"} {
+		if w := serve(contentType, gzipMinSize, true); !w.Flushed || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: got flushed %v and encoding %q, want it flushed as is", contentType, w.Flushed, w.Header().Get("Content-Encoding"))
+		}
+	}
+	if w := serve("application/json", 10, true); !w.Flushed {
+		t.Error("flushed JSON: not flushed")
+	}
+}
+
+func TestRSVPHandler(t *testing.T) {
+	guest := User{Email: "guest@example.com", Role: RoleUser}
+	app := newTestApp(t, guest)
+	room := app.room(t, app.venue(t, "Main Office"), "Board Room", 12)
+
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	event := Event{Name: "Review", LocationID: room.Id.Hex(), StartTime: monday, EndTime: monday.Add(time.Hour),
+		Guests:       []string{guest.Email, "optional@example.com", "other@example.com"},
+		Participants: []Participant{{Email: "optional@example.com", Optional: true}}}
+	syncParticipants(&event, nil)
+	event = app.event(t, event)
+
+	answered := Event{}
+	if code := app.do(t, "POST", "/events/"+event.Id.Hex()+"/rsvp", RSVPRequest{Status: RSVPAccepted}, &answered); code != http.StatusOK {
+		t.Fatalf("POST /events/:id/rsvp: got %d, want 200", code)
+	}
+	want := RSVPSummary{Accepted: 1, NeedsAction: 2, RequiredPending: 1}
+	if answered.RSVP == nil || *answered.RSVP != want {
+		t.Errorf("POST /events/:id/rsvp: got summary %+v, want %+v", answered.RSVP, want)
+	}
+
+	found := EventResponse{}
+	app.do(t, "GET", "/events/"+event.Id.Hex(), nil, &found)
+	if len(found.Participants) != 3 || found.Participants[0].Status != RSVPAccepted || !found.Participants[1].Optional {
+		t.Errorf("GET /events/:id: got participants %+v", found.Participants)
+	}
+
+	other := Event{Name: "Sync", LocationID: room.Id.Hex(), StartTime: monday.Add(2 * time.Hour), EndTime: monday.Add(3 * time.Hour)}
+	other = app.event(t, other)
+	if code := app.do(t, "POST", "/events/"+other.Id.Hex()+"/rsvp", RSVPRequest{Status: RSVPDeclined}, nil); code != http.StatusForbidden {
+		t.Errorf("POST /events/:id/rsvp of another event: got %d, want 403", code)
+	}
+}
+
+func TestCheckVenueRules(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	weekdays := []OpeningDay{}
+	for d := time.Monday; d <= time.Friday; d++ {
+		weekdays = append(weekdays, OpeningDay{Weekday: d, Open: "08:00", Close: "18:00"})
+	}
+	monday := clockNow().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	venue := Venue{Name: "Main Office", Slug: "main-office", TimeZone: "UTC",
+		OpeningHours: &OpeningHours{Days: weekdays, Holidays: []string{monday.AddDate(0, 0, 1).Format("2006-01-02")}},
+		BookingRules: &BookingRules{MinDuration: 15, MaxDuration: 240, MaxAdvanceDays: 30}}
+	if err := app.c.venues().Create(context.Background(), &venue); err != nil {
+		t.Fatal(err)
+	}
+	room := app.room(t, venue, "Board Room", 12)
+
+	at := func(day int, hour int, minutes int) Event {
+		start := monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+		return Event{LocationID: room.Id.Hex(), StartTime: start, EndTime: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	daily := at(0, 9, 60)
+	daily.SetRecurrence(&Recurrence{Frequency: FrequencyDaily, Count: 3})
+
+	tests := []struct {
+		name   string
+		event  Event
+		wantId string
+	}{
+		{"open", at(0, 9, 60), ""},
+		{"before opening", at(0, 7, 60), "venue_closed"},
+		{"past closing", at(0, 17, 120), "venue_closed"},
+		{"weekend", at(-1, 9, 60), "venue_closed"},
+		{"holiday", at(1, 9, 60), "venue_closed"},
+		{"occurrence on a holiday", daily, "venue_closed"},
+		{"too short", at(0, 9, 10), "invalid_duration"},
+		{"too long", at(0, 9, 300), "invalid_duration"},
+		{"too far ahead", at(35, 9, 60), "booking_too_far_ahead"},
+	}
+	for _, tt := range tests {
+		errRes := app.c.checkVenueRules(context.Background(), tt.event)
+		if tt.wantId == "" && errRes != nil || tt.wantId != "" && (errRes == nil || errRes.Id != tt.wantId) {
+			t.Errorf("%s: got %v, want %q", tt.name, errRes, tt.wantId)
+		}
+	}
+}
+
+func TestOrgScoping(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	acme := newTestApp(t, User{Email: "admin@acme.example.com", Role: RoleAdmin, OrgId: "acme"})
+	acme.c.repos.Venues = app.c.repos.Venues
+
+	venue := Venue{Name: "Acme HQ", Slug: "acme-hq"}
+	if err := app.c.venues().Create(storage.WithOrg(context.Background(), "acme"), &venue); err != nil {
+		t.Fatal(err)
+	}
+	own := app.venue(t, "Main Office")
+
+	tests := []struct {
+		name   string
+		app    *testApp
+		target string
+		want   int
+	}{
+		{"own venue", acme, "/venues/" + venue.Id.Hex(), http.StatusOK},
+		{"other org's venue", acme, "/venues/" + own.Id.Hex(), http.StatusNotFound},
+		{"venue of an org", app, "/venues/" + venue.Id.Hex(), http.StatusNotFound},
+		{"slug of an org", app, "/venues/acme-hq", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := tt.app.do(t, "GET", tt.target, nil, nil); code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	for _, a := range []*testApp{app, acme} {
+		venues := []Venue{}
+		a.do(t, "GET", "/venues", nil, &venues)
+		if len(venues) != 1 {
+			t.Errorf("got %d venues, want only the organization's", len(venues))
+		}
+	}
+}
+
+func TestOrgIsolation(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	acme := newTestApp(t, User{Email: "admin@acme.example.com", Role: RoleAdmin, OrgId: "acme"})
+	acme.c.repos = app.c.repos
+	acme.c.repos.Users[acme.user.Email] = acme.user
+
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	rooms := map[*testApp]Room{}
+	for org, a := range map[string]*testApp{"": app, "acme": acme} {
+		venue := Venue{Name: "Office", OrgId: org}
+		if err := a.c.venues().Create(context.Background(), &venue); err != nil {
+			t.Fatal(err)
+		}
+		room := Room{Name: "Board Room", VenueId: venue.Id.Hex(), Capacity: 8, OrgId: org}
+		if err := a.c.rooms().Create(context.Background(), &room); err != nil {
+			t.Fatal(err)
+		}
+		a.event(t, Event{Name: "Review", LocationID: room.Id.Hex(), StartTime: monday, EndTime: monday.Add(time.Hour), OrgId: org})
+		rooms[a] = room
+	}
+
+	for a, other := range map[*testApp]*testApp{app: acme, acme: app} {
+		theirs := "/rooms/" + rooms[other].Id.Hex()
+		tests := []struct {
+			method string
+			target string
+			body   interface{}
+		}{
+			{"GET", theirs, nil},
+			{"PATCH", theirs, map[string]interface{}{"capacity": 20}},
+			{"DELETE", theirs, nil},
+		}
+		for _, tt := range tests {
+			if code := a.do(t, tt.method, tt.target, tt.body, nil); code != http.StatusNotFound {
+				t.Errorf("%s %s %s: got %d, want 404", a.user.Email, tt.method, tt.target, code)
+			}
+		}
+
+		listed := []Room{}
+		a.do(t, "GET", "/rooms", nil, &listed)
+		if len(listed) != 1 || listed[0].Id != rooms[a].Id {
+			t.Errorf("%s GET /rooms: got %+v, want only the organization's room", a.user.Email, listed)
+		}
+		events := []EventResponse{}
+		a.do(t, "GET", "/events?start_time=2030-06-03T00:00:00Z&end_time=2030-06-04T00:00:00Z", nil, &events)
+		if len(events) != 1 || events[0].LocationID != rooms[a].Id.Hex() {
+			t.Errorf("%s GET /events: got %+v, want only the organization's event", a.user.Email, events)
+		}
+	}
+
+	for _, target := range []string{"/changes", "/ws"} {
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a user: got %d, want 401", target, w.Code)
+		}
+	}
+
+	scoped := map[string]bool{}
+	for _, name := range orgCollections {
+		scoped[name] = true
+	}
+	for entity, collection := range entityCollections {
+		if !scoped[collection] {
+			t.Errorf("the %s collection of %s changes isn't scoped by organization", collection, entity)
+		}
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	board := app.room(t, venue, "Board Room", 12)
+	app.room(t, venue, "Phone Booth", 1)
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	app.event(t, Event{Name: "Review", LocationID: board.Id.Hex(), StartTime: monday, EndTime: monday.Add(time.Hour)})
+
+	query := `query Rooms($start: String!, $end: String!) {
+  venues { name rooms { name ...booked } }
+  free: availability(start_time: $start, end_time: $end, min_capacity: 2) { name }
+}
+fragment booked on Room { events(start_time: "2030-06-03T00:00:00Z", end_time: "2030-06-04T00:00:00Z") { name } }`
+	variables := map[string]interface{}{"start": "2030-06-03T09:30:00Z", "end": "2030-06-03T10:30:00Z"}
+	res := struct {
+		Data struct {
+			Venues []struct {
+				Name  string
+				Rooms []struct {
+					Name   string
+					Events []struct{ Name string }
+				}
+			}
+			Free []struct{ Name string }
+		}
+		Errors []GraphQLError
+	}{}
+	code := app.do(t, "POST", "/graphql", GraphQLRequest{Query: query, Variables: variables}, &res)
+	if code != http.StatusOK || len(res.Errors) > 0 {
+		t.Fatalf("POST /graphql: got %d and %+v", code, res.Errors)
+	}
+	if len(res.Data.Venues) != 1 || len(res.Data.Venues[0].Rooms) != 2 {
+		t.Fatalf("POST /graphql: got venues %+v", res.Data.Venues)
+	}
+	for _, room := range res.Data.Venues[0].Rooms {
+		if booked := len(room.Events) == 1; booked != (room.Name == "Board Room") {
+			t.Errorf("POST /graphql: got events %+v in %s", room.Events, room.Name)
+		}
+	}
+	if len(res.Data.Free) != 0 {
+		t.Errorf("POST /graphql: got available rooms %+v, want none", res.Data.Free)
+	}
+
+	if code := app.do(t, "GET", "/graphql?query=%7Bvenues%7B", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("GET /graphql with a syntax error: got %d, want 400", code)
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	app.venue(t, "Main Office")
+	app.router.Version("v2").Get("/venues", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteSuccess(w, http.StatusOK, map[string]string{"version": "v2"})
+	}))
+
+	tests := []struct {
+		target  string
+		accept  string
+		want    int
+		version string
+	}{
+		{"/venues", "", http.StatusOK, "v1"},
+		{"/v1/venues", "", http.StatusOK, "v1"},
+		{"/v2/venues", "", http.StatusOK, "v2"},
+		{"/venues", "application/vnd.ivana.v2+json", http.StatusOK, "v2"},
+		{"/v1/venues", "application/vnd.ivana.v2+json", http.StatusOK, "v1"},
+		{"/venues", "application/vnd.ivana.v3+json", http.StatusNotAcceptable, ""},
+		{"/v3/venues", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("GET %s accepting %q: got %d, want %d", tt.target, tt.accept, w.Code, tt.want)
+			continue
+		}
+		if tt.version == "" {
+			continue
+		}
+		if got := w.Header().Get("API-Version"); got != tt.version {
+			t.Errorf("GET %s accepting %q: served %s, want %s", tt.target, tt.accept, got, tt.version)
+		}
+		v2 := bytes.Contains(w.Body.Bytes(), []byte(`"version":"v2"`))
+		if v2 != (tt.version == "v2") {
+			t.Errorf("GET %s accepting %q: got %s", tt.target, tt.accept, w.Body.String())
+		}
+	}
+
+	if code := app.do(t, "GET", "/v2/rooms", nil, nil); code != http.StatusOK {
+		t.Errorf("GET /v2/rooms: got %d, want the v1 route", code)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Cleanup(func() { configFile = map[string]string{} })
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"PORT": 8080, "MAILER": "log", "TIME_ZONE": "Asia/Jakarta", "RATE_LIMIT": 120, "CORS_ORIGINS": "https://app.example.com"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("MAILER", "sendgrid")
+	t.Setenv("SENDGRID_API_KEY", "key")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "8080" || cfg.TimeZone != "Asia/Jakarta" || cfg.Database != "ivana" {
+		t.Errorf("got %+v, want the settings of the file", cfg)
+	}
+	if cfg.Mail.Mailer != "sendgrid" {
+		t.Errorf("got MAILER %q, want the environment's", cfg.Mail.Mailer)
+	}
+	if cfg.RateLimit != 120 || !reflect.DeepEqual(cfg.CORS.Origins, []string{"https://app.example.com"}) || cfg.CORS.MaxAge != defaultCORSMaxAge {
+		t.Errorf("got rate limit %d and CORS %+v, want the file's with the default max age", cfg.RateLimit, cfg.CORS)
+	}
+
+	t.Setenv("SENDGRID_API_KEY", "")
+	t.Setenv("TIME_ZONE", "Mars/Olympus")
+	t.Setenv("RATE_LIMIT", "lots")
+	t.Setenv("MSGRAPH_TENANT_ID", "contoso")
+	_, err = loadConfig()
+	for _, name := range []string{"SENDGRID_API_KEY", "TIME_ZONE", "RATE_LIMIT", "MSGRAPH_CLIENT_ID"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("got %v, want a problem with %s", err, name)
+		}
+	}
+}
+
+func TestEventsWindow(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"start_time=2030-06-03T00:00:00Z&end_time=2030-06-10T00:00:00Z", http.StatusOK},
+		{"start_time=2030-06-03", http.StatusBadRequest},
+		{"end_time=tomorrow", http.StatusBadRequest},
+		{"start_time=2030-06-10T00:00:00Z&end_time=2030-06-03T00:00:00Z", http.StatusBadRequest},
+		{"start_time=2030-01-01T00:00:00Z&end_time=2032-01-01T00:00:00Z", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := app.do(t, "GET", "/events?"+tt.query, nil, nil); code != tt.want {
+			t.Errorf("GET /events?%s: got %d, want %d", tt.query, code, tt.want)
+		}
+	}
+}
+
+func TestOpenHours(t *testing.T) {
+	monday := time.Date(2030, time.June, 3, 0, 0, 0, 0, time.UTC)
+	hours := &OpeningHours{
+		Days: []OpeningDay{
+			{Weekday: time.Monday, Open: "09:00", Close: "17:00"},
+			{Weekday: time.Tuesday, Open: "09:00", Close: "17:00"},
+		},
+		Holidays: []string{"2030-06-04"},
+	}
+
+	tests := []struct {
+		name  string
+		hours *OpeningHours
+		start time.Time
+		end   time.Time
+		want  float64
+	}{
+		{"around the clock", nil, monday, monday.AddDate(0, 0, 7), 168},
+		{"week with a holiday", hours, monday, monday.AddDate(0, 0, 7), 8},
+		{"part of a day", hours, monday.Add(12 * time.Hour), monday.AddDate(0, 0, 7), 5},
+	}
+	for _, tt := range tests {
+		if got := openHours(tt.hours, tt.start, tt.end, time.UTC); got != tt.want {
+			t.Errorf("%s: got %v hours, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportEvents(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	room := app.room(t, app.venue(t, "Main Office"), "Board Room", 12)
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	app.event(t, Event{Name: "=HYPERLINK(\"x\")", LocationID: room.Id.Hex(), StartTime: monday, EndTime: monday.Add(90 * time.Minute), Guests: []string{"a@example.com", "b@example.com"}})
+
+	window := "start_time=2030-06-03T00:00:00Z&end_time=2030-06-05T00:00:00Z"
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/events/export?"+window, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /events/export: got %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="events-2030-06-03-2030-06-05.csv"` {
+		t.Errorf("GET /events/export: got Content-Disposition %q", got)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "id,name,room,") {
+		t.Fatalf("GET /events/export: got %q, want a header and a row", w.Body.String())
+	}
+	for _, want := range []string{`"'=HYPERLINK(""x"")"`, "Board Room", "a@example.com; b@example.com", ",1.5,"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("GET /events/export: got row %q, want %s in it", lines[1], want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/events/export?format=xlsx&"+window, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != xlsxContentType {
+		t.Fatalf("GET /events/export?format=xlsx: got %d and %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("GET /events/export?format=xlsx: %v", err)
+	}
+	parts := map[string]bool{}
+	for _, f := range zr.File {
+		parts[f.Name] = true
+	}
+	if !parts["[Content_Types].xml"] || !parts["xl/workbook.xml"] || !parts["xl/worksheets/sheet1.xml"] {
+		t.Errorf("GET /events/export?format=xlsx: got parts %v", parts)
+	}
+
+	if code := app.do(t, "GET", "/events/export?format=pdf", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("GET /events/export?format=pdf: got %d, want 400", code)
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	body := []byte(`{"name":"Review"}`)
+	r := httptest.NewRequest("POST", "/events", nil)
+	same := idempotencyRequest(httptest.NewRequest("POST", "/events", nil), body)
+	if idempotencyRequest(r, body) != same {
+		t.Error("idempotencyRequest: got different hashes for the same request")
+	}
+	if idempotencyRequest(r, []byte(`{"name":"Standup"}`)) == same {
+		t.Error("idempotencyRequest: got the same hash for another body")
+	}
+	if idempotencyRequest(httptest.NewRequest("POST", "/events?dry_run=true", nil), body) == same {
+		t.Error("idempotencyRequest: got the same hash for another query")
+	}
+
+	w := httptest.NewRecorder()
+	rec := &idempotencyRecorder{ResponseWriter: w}
+	rec.Header().Set("Location", "/events/1")
+	WriteSuccess(rec, http.StatusCreated, map[string]string{"id": "1"})
+	key := IdempotencyKey{Status: rec.status, Body: rec.body.Bytes(), Header: map[string]string{"Location": w.Header().Get("Location")}}
+
+	replayed := httptest.NewRecorder()
+	key.replay(replayed)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != w.Body.String() {
+		t.Errorf("replay: got %d %q, want %d %q", replayed.Code, replayed.Body.String(), w.Code, w.Body.String())
+	}
+	if replayed.Header().Get("Location") != "/events/1" || replayed.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: got headers %v", replayed.Header())
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := map[string]interface{}{
+		"name":       "Standup",
+		"category":   "team",
+		"recurrence": map[string]interface{}{"frequency": "daily", "count": 5.0},
+	}
+	mergePatch(doc, map[string]interface{}{
+		"category":   nil,
+		"recurrence": map[string]interface{}{"count": 3.0},
+		"tentative":  true,
+	})
+
+	want := map[string]interface{}{
+		"name":       "Standup",
+		"recurrence": map[string]interface{}{"frequency": "daily", "count": 3.0},
+		"tentative":  true,
+	}
+	got, _ := json.Marshal(doc)
+	wanted, _ := json.Marshal(want)
+	if string(got) != string(wanted) {
+		t.Errorf("mergePatch: got %s, want %s", got, wanted)
+	}
+}
+
+func TestDiskBlobStore(t *testing.T) {
+	store := &DiskBlobStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "floor-plans/1.png", "image/png", []byte("plan")); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := store.Open(ctx, "floor-plans/1.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	b.ReadFrom(blob)
+	blob.Close()
+	if b.String() != "plan" {
+		t.Errorf("Open: got %q, want plan", b.String())
+	}
+
+	if err := store.Delete(ctx, "floor-plans/1.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, "floor-plans/1.png"); err != ErrBlobNotFound {
+		t.Errorf("Open of a deleted blob: got %v, want ErrBlobNotFound", err)
+	}
+	for _, key := range []string{"../escape.png", "a/../../b", ""} {
+		if err := store.Put(ctx, key, "image/png", []byte("x")); err == nil {
+			t.Errorf("Put %q: got no error", key)
+		}
+	}
+}
+
+func TestProcessPhoto(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4000, 1000))
+	for x := 0; x < 4000; x++ {
+		for y := 0; y < 1000; y++ {
+			src.Set(x, y, color.RGBA{200, 100, 50, 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	photo, errRes := processPhoto(b.Bytes())
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if photo.ContentType != "image/jpeg" || photo.Width != photoMaxSize || photo.Height != photoMaxSize/4 {
+		t.Errorf("processPhoto: got %s %dx%d, want image/jpeg %dx%d", photo.ContentType, photo.Width, photo.Height, photoMaxSize, photoMaxSize/4)
+	}
+	thumbnail, err := jpeg.Decode(bytes.NewReader(photo.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := thumbnail.Bounds().Size(); size.X != photoThumbnailSize || size.Y != photoThumbnailSize/4 {
+		t.Errorf("thumbnail: got %v, want %dx%d", size, photoThumbnailSize, photoThumbnailSize/4)
+	}
+	if r, g, _, _ := thumbnail.At(10, 10).RGBA(); r>>8 < 190 || g>>8 > 110 {
+		t.Errorf("thumbnail: got color %v, want about 200,100,50", thumbnail.At(10, 10))
+	}
+
+	b.Reset()
+	if err := png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, 10, 20))); err != nil {
+		t.Fatal(err)
+	}
+	small, errRes := processPhoto(b.Bytes())
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if small.ContentType != "image/png" || small.Width != 10 || small.Height != 20 {
+		t.Errorf("processPhoto of a small PNG: got %s %dx%d, want image/png 10x20", small.ContentType, small.Width, small.Height)
+	}
+
+	if _, errRes := processPhoto([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")); errRes != ErrInvalidPhoto {
+		t.Errorf("processPhoto of a WebP: got %v, want ErrInvalidPhoto", errRes)
+	}
+}
+
+func TestCancelledEventStatus(t *testing.T) {
+	cancelledAt := time.Now()
+	events := []Event{
+		{Name: "Standup"},
+		{Name: "Review", Tentative: true},
+		{Name: "Offsite", CancelledAt: &cancelledAt, CancelledBy: exampleOwner, CancellationReason: "Venue closed"},
+	}
+
+	statuses, ok := parseEventStatuses("cancelled", listedEventStatuses)
+	if !ok {
+		t.Fatal("parseEventStatuses: cancelled refused")
+	}
+	if got := filterByStatus(events, statuses); len(got) != 1 || got[0].Name != "Offsite" {
+		t.Errorf("filterByStatus cancelled: got %v, want Offsite", got)
+	}
+	statuses, _ = parseEventStatuses("confirmed, tentative", listedEventStatuses)
+	if got := filterByStatus(events, statuses); len(got) != 2 {
+		t.Errorf("filterByStatus confirmed, tentative: got %d events, want 2", len(got))
+	}
+	if _, ok := parseEventStatuses("cancelled", eventStatuses); ok {
+		t.Error("parseEventStatuses: cancelled accepted for user schedules")
+	}
+
+	_, body := eventMessage(NotifyCancelled, events[2])
+	if !strings.Contains(body, "Reason: Venue closed") {
+		t.Errorf("eventMessage: got %q, want the reason", body)
+	}
+}
+
+func TestSuggestRooms(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Head Office")
+	withAmenities := func(room Room, amenities ...string) Room {
+		room.Amenities = amenities
+		if err := app.c.rooms().Update(context.Background(), &room); err != nil {
+			t.Fatal(err)
+		}
+		return room
+	}
+	snug := withAmenities(app.room(t, venue, "Snug", 4), "Projector")
+	busy := withAmenities(app.room(t, venue, "Busy", 4), "projector")
+	used := withAmenities(app.room(t, venue, "Used", 4), "projector", "whiteboard")
+	hall := withAmenities(app.room(t, venue, "Hall", 20), "projector")
+	withAmenities(app.room(t, venue, "Bare", 4))
+	app.room(t, venue, "Tiny", 2)
+
+	now := clockNow()
+	start := now.Add(24 * time.Hour).Truncate(time.Hour)
+	app.event(t, Event{Name: "Taken", LocationID: busy.Id.Hex(), StartTime: start, EndTime: start.Add(time.Hour)})
+	app.event(t, Event{Name: "Earlier", LocationID: used.Id.Hex(), StartTime: now.Add(-48 * time.Hour), EndTime: now.Add(-46 * time.Hour)})
+
+	request := map[string]interface{}{
+		"start_time": start.Add(30 * time.Minute).Format(time.RFC3339),
+		"end_time":   start.Add(90 * time.Minute).Format(time.RFC3339),
+		"attendees":  3,
+		"amenities":  []string{"projector"},
+	}
+	suggestions := []RoomSuggestion{}
+	if code := app.do(t, "POST", "/rooms/suggest", request, &suggestions); code != http.StatusOK {
+		t.Fatalf("POST /rooms/suggest: got %d, want 200", code)
+	}
+	names := []string{}
+	for _, suggestion := range suggestions {
+		names = append(names, suggestion.Room.Name)
+	}
+	if strings.Join(names, ",") != "Snug,Used,Hall" {
+		t.Errorf("POST /rooms/suggest: got %v, want Snug, Used, Hall", names)
+	}
+	if len(suggestions) == 3 && (suggestions[1].BookedHours != 2 || suggestions[2].SpareSeats != 17) {
+		t.Errorf("POST /rooms/suggest: got %+v", suggestions)
+	}
+
+	ranked := rankRooms([]Room{hall, snug, used}, 3, map[string]bool{"floor-12": true}, nil)
+	if ranked[0].Room.Name != "Snug" {
+		t.Errorf("rankRooms: got %s first, want Snug", ranked[0].Room.Name)
+	}
+	used.FloorId = "floor-12"
+	ranked = rankRooms([]Room{hall, snug, used}, 3, map[string]bool{"floor-12": true}, nil)
+	if ranked[0].Room.Name != "Used" || !ranked[0].TeamFloor {
+		t.Errorf("rankRooms: got %s first, want Used on the team's floor", ranked[0].Room.Name)
+	}
+
+	request["end_time"] = request["start_time"]
+	if code := app.do(t, "POST", "/rooms/suggest", request, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /rooms/suggest with an empty window: got %d, want 422", code)
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, defaultLocation)
+	at := func(hour int, minute int) time.Time {
+		return start.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	events := []Event{
+		{Name: "Standup", Owner: "ana@example.com", LocationID: "room-a", StartTime: at(0, 0), EndTime: at(0, 30)},
+		{Name: "Review", Owner: "bo@example.com", Guests: []string{"ana@example.com"}, LocationID: "room-a", StartTime: at(0, 15), EndTime: at(1, 0)},
+		{Name: "Lunch", Owner: "bo@example.com", Guests: []string{"Ana@example.com"}, StartTime: at(3, 0), EndTime: at(4, 0),
+			Participants: []Participant{{Email: "ana@example.com", Status: RSVPDeclined}}},
+		{Name: "Late", Owner: "bo@example.com", LocationID: "room-b", StartTime: at(7, 30), EndTime: at(10, 0)},
+	}
+	req := FreeBusyRequest{StartTime: start, EndTime: at(8, 0), Users: []string{"ana@example.com", "bo@example.com"}, RoomIds: []string{"room-a", "room-b", "room-c"}}
+
+	result := freeBusy(req, events)
+	want := map[string][]Interval{
+		"ana@example.com": {{at(0, 0), at(1, 0)}},
+		"bo@example.com":  {{at(0, 15), at(1, 0)}, {at(3, 0), at(4, 0)}, {at(7, 30), at(8, 0)}},
+		"room-a":          {{at(0, 0), at(1, 0)}},
+		"room-b":          {{at(7, 30), at(8, 0)}},
+		"room-c":          {},
+	}
+	got := map[string][]Interval{}
+	for _, user := range result.Users {
+		got[user.Email] = user.Busy
+	}
+	for _, room := range result.Rooms {
+		got[room.RoomId] = room.Busy
+	}
+	for key, intervals := range want {
+		if len(got[key]) != len(intervals) {
+			t.Errorf("freeBusy %s: got %v, want %v", key, got[key], intervals)
+			continue
+		}
+		for i := range intervals {
+			if !got[key][i].StartTime.Equal(intervals[i].StartTime) || !got[key][i].EndTime.Equal(intervals[i].EndTime) {
+				t.Errorf("freeBusy %s: got %v, want %v", key, got[key], intervals)
+				break
+			}
+		}
+	}
+}
+
+func TestGraphEvent(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, defaultLocation)
+	event := Event{Id: storage.NewObjectId(), Name: "Planning", Description: "Q2", Location: "Orchid", Guests: []string{"bo@example.com"},
+		CheckInCode: "1234", StartTime: start, EndTime: start.Add(time.Hour)}
+
+	var got graphEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/users/ana@example.com/events" {
+			t.Errorf("createEvent: got %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id":"AAMk","onlineMeeting":{"joinUrl":"https://teams.microsoft.com/l/1"}}`))
+	}))
+	defer server.Close()
+	g := &MSGraph{client: server.Client(), base: server.URL, teams: true}
+
+	created, err := g.createEvent(context.Background(), g.mailboxOf(Event{Owner: "ana@example.com"}), graphEventOf(event, g.teams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Id != "AAMk" || created.OnlineMeeting == nil || created.OnlineMeeting.JoinURL != "https://teams.microsoft.com/l/1" {
+		t.Errorf("createEvent: got %+v", created)
+	}
+	if got.Subject != "Planning" || got.Start.DateTime != "2026-03-02T02:00:00" || got.Start.TimeZone != "UTC" || got.End.DateTime != "2026-03-02T03:00:00" {
+		t.Errorf("graphEventOf: got %+v %+v %+v", got, got.Start, got.End)
+	}
+	if got.Body.Content != "Q2\n\nCheck-in code: 1234" || got.TransactionId != event.Id.Hex() {
+		t.Errorf("graphEventOf: got body %q, transaction %q", got.Body.Content, got.TransactionId)
+	}
+	if len(got.Attendees) != 1 || got.Attendees[0].EmailAddress.Address != "bo@example.com" {
+		t.Errorf("graphEventOf: got attendees %+v", got.Attendees)
+	}
+	if !got.IsOnlineMeeting || got.OnlineMeetingProvider != "teamsForBusiness" {
+		t.Errorf("graphEventOf: got no Teams meeting")
+	}
+
+	if g.acceptsNotification(graphNotification{ClientState: ""}) {
+		t.Errorf("acceptsNotification: accepted a notification with no client state configured")
+	}
+	g.clientState = "s3cret"
+	if !g.acceptsNotification(graphNotification{ClientState: "s3cret"}) || g.acceptsNotification(graphNotification{ClientState: "guess"}) {
+		t.Errorf("acceptsNotification: client state not checked")
+	}
+
+	w := httptest.NewRecorder()
+	(&appContext{}).graphNotificationsHandler(w, httptest.NewRequest("POST", "/integrations/msgraph/notifications?validationToken=abc+123", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "abc 123" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("validation: got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	if kind := webhookType(Change{Entity: "event", Action: ChangeDeleted}); kind != "event.cancelled" {
+		t.Errorf("webhookType: got %s", kind)
+	}
+	if kind := webhookType(Change{Entity: "room", Action: ChangeUpdated}); kind != "room.updated" {
+		t.Errorf("webhookType: got %s", kind)
+	}
+	webhook := Webhook{Events: []string{"event.created", "event.cancelled"}, Secret: "0123456789abcdef"}
+	if !webhook.Wants("event.cancelled") || webhook.Wants("room.updated") || !(Webhook{Events: []string{"*"}}).Wants("room.updated") {
+		t.Errorf("Wants: got the wrong filter")
+	}
+	if msgs := schemas["webhook"].Validate(map[string]interface{}{"url": "https://hooks.example.com", "events": []interface{}{}}); len(msgs) != 1 {
+		t.Errorf("schema: got %v, want events too short", msgs)
+	}
+
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Signature") != signWebhook(webhook.Secret, b) || r.Header.Get("X-Webhook-Event") != "event.created" {
+			t.Errorf("deliver: got headers %v", r.Header)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	webhook.URL = server.URL
+
+	delivery := WebhookDelivery{Id: storage.NewObjectId(), Type: "event.created", Payload: `{"type":"event.created"}`, Status: DeliveryPending}
+	before := time.Now()
+	deliver(server.Client(), webhook, &delivery)
+	if delivery.Status != DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != 500 || delivery.NextAttemptAt.Before(before.Add(webhookBackoff)) {
+		t.Errorf("deliver: got %+v after a 500", delivery)
+	}
+	if retry := webhookRetryAt(before, 3); !retry.Equal(before.Add(4 * webhookBackoff)) {
+		t.Errorf("webhookRetryAt: got %v", retry.Sub(before))
+	}
+
+	delivery.Attempts = webhookAttempts - 1
+	deliver(server.Client(), webhook, &delivery)
+	if delivery.Status != DeliveryFailed {
+		t.Errorf("deliver: got %s after the last attempt", delivery.Status)
+	}
+
+	status = http.StatusNoContent
+	delivery = WebhookDelivery{Id: storage.NewObjectId(), Type: "event.created", Payload: `{}`, Status: DeliveryPending}
+	deliver(server.Client(), webhook, &delivery)
+	if delivery.Status != DeliveryDelivered || delivery.DeliveredAt == nil {
+		t.Errorf("deliver: got %+v after a 204", delivery)
+	}
+}
+
+func TestRoomPanel(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	busy := app.room(t, venue, "Board Room", 12)
+	free := app.room(t, venue, "Huddle", 4)
+	now := clockNow().Truncate(time.Minute)
+	meeting := app.event(t, Event{Name: "Standup", Owner: "ana@example.com", LocationID: busy.Id.Hex(), StartTime: now.Add(-10 * time.Minute), EndTime: now.Add(20 * time.Minute)})
+	next := app.event(t, Event{Name: "Review", Owner: "ana@example.com", LocationID: free.Id.Hex(), StartTime: now.Add(40 * time.Minute), EndTime: now.Add(time.Hour)})
+
+	panel := RoomPanel{}
+	if code := app.do(t, "GET", "/rooms/"+busy.Id.Hex()+"/panel", nil, &panel); code != http.StatusOK {
+		t.Fatalf("GET /rooms/:id/panel: got %d", code)
+	}
+	if panel.Status != PanelOccupied || panel.Current == nil || panel.Current.Id != meeting.Id || len(panel.Actions) != 2 {
+		t.Errorf("GET /rooms/:id/panel: got %+v", panel)
+	}
+	if code := app.do(t, "POST", "/rooms/"+busy.Id.Hex()+"/panel/book", map[string]int{"minutes": 30}, nil); code != http.StatusConflict {
+		t.Errorf("POST /rooms/:id/panel/book on a busy room: got %d, want 409", code)
+	}
+	extended := Event{}
+	if code := app.do(t, "POST", "/rooms/"+busy.Id.Hex()+"/panel/extend", map[string]int{"minutes": 15}, &extended); code != http.StatusAccepted {
+		t.Fatalf("POST /rooms/:id/panel/extend: got %d", code)
+	}
+	if !extended.EndTime.Equal(meeting.EndTime.Add(15 * time.Minute)) {
+		t.Errorf("POST /rooms/:id/panel/extend: got end %v", extended.EndTime)
+	}
+
+	if code := app.do(t, "POST", "/rooms/"+free.Id.Hex()+"/panel/extend", map[string]int{"minutes": 15}, nil); code != http.StatusConflict {
+		t.Errorf("POST /rooms/:id/panel/extend on a free room: got %d, want 409", code)
+	}
+	booked := Event{}
+	if code := app.do(t, "POST", "/rooms/"+free.Id.Hex()+"/panel/book", map[string]int{"minutes": 60}, &booked); code != http.StatusCreated {
+		t.Fatalf("POST /rooms/:id/panel/book: got %d", code)
+	}
+	if !booked.EndTime.Equal(next.StartTime) || booked.BookedVia.Channel != ChannelPanel || booked.CheckedInAt.IsZero() {
+		t.Errorf("POST /rooms/:id/panel/book: got %+v, want it to end when the next meeting starts", booked)
+	}
+
+	if viewer := roomPanel(free, []Event{}, now, false); viewer.Status != PanelFree || len(viewer.Actions) != 0 {
+		t.Errorf("roomPanel for a viewer: got %+v", viewer)
+	}
+	key := APIKey{Scope: APIKeyPanel, RoomId: busy.Id.Hex()}
+	if !key.Allows(httptest.NewRequest("POST", "/rooms/"+busy.Id.Hex()+"/panel/end", nil)) || key.Allows(httptest.NewRequest("POST", "/rooms/"+free.Id.Hex()+"/panel/end", nil)) {
+		t.Errorf("Allows: a panel key must only act on its room")
+	}
+}
+
+func TestBookNow(t *testing.T) {
+	app := newTestApp(t, User{Email: "ana@example.com", Role: RoleUser})
+	venue := app.venue(t, "Main Office")
+	room := app.room(t, venue, "Huddle", 4)
+	now := clockNow().Truncate(time.Minute)
+	next := app.event(t, Event{Name: "Review", Owner: "bo@example.com", LocationID: room.Id.Hex(), StartTime: now.Add(20 * time.Minute), EndTime: now.Add(time.Hour)})
+	app.event(t, Event{Name: "Retro", Owner: "bo@example.com", LocationID: room.Id.Hex(), StartTime: now.Add(time.Hour), EndTime: now.Add(90 * time.Minute)})
+
+	booked := Event{}
+	if code := app.do(t, "POST", "/rooms/"+room.Id.Hex()+"/book-now", map[string]int{"duration": 45}, &booked); code != http.StatusCreated {
+		t.Fatalf("POST /rooms/:id/book-now: got %d", code)
+	}
+	if booked.Owner != "ana@example.com" || !booked.EndTime.Equal(next.StartTime) || booked.Name != quickBookName || booked.BookedVia.Channel != ChannelWeb {
+		t.Errorf("POST /rooms/:id/book-now: got %+v, want it cut short before the next booking", booked)
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("POST", "/rooms/"+room.Id.Hex()+"/book-now", strings.NewReader(`{}`)))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), now.Add(90*time.Minute).Format(time.RFC3339)) || w.Header().Get("Retry-After") == "" {
+		t.Errorf("POST /rooms/:id/book-now on a busy room: got %d %s, want 409 free at the end of the retro", w.Code, w.Body.String())
+	}
+}
+
+func TestExtendEvent(t *testing.T) {
+	app := newTestApp(t, User{Email: "ana@example.com", Role: RoleUser})
+	venue := app.venue(t, "Main Office")
+	room := app.room(t, venue, "Huddle", 4)
+	start := clockNow().Truncate(time.Minute).Add(time.Hour)
+	event := app.event(t, Event{Name: "Planning", Owner: "ana@example.com", LocationID: room.Id.Hex(), StartTime: start, EndTime: start.Add(30 * time.Minute)})
+	app.event(t, Event{Name: "Review", Owner: "bo@example.com", LocationID: room.Id.Hex(), StartTime: start.Add(45 * time.Minute), EndTime: start.Add(time.Hour)})
+	other := app.event(t, Event{Name: "Retro", Owner: "bo@example.com", StartTime: start, EndTime: start.Add(time.Hour)})
+
+	extended := Event{}
+	if code := app.do(t, "POST", "/events/"+event.Id.Hex()+"/extend", EventExtension{Minutes: 15}, &extended); code != http.StatusAccepted {
+		t.Fatalf("POST /events/:id/extend: got %d", code)
+	}
+	if !extended.EndTime.Equal(start.Add(45 * time.Minute)) {
+		t.Errorf("POST /events/:id/extend: got end %v", extended.EndTime)
+	}
+	if code := app.do(t, "POST", "/events/"+event.Id.Hex()+"/extend", EventExtension{Minutes: 5}, nil); code != http.StatusConflict {
+		t.Errorf("POST /events/:id/extend into the next booking: got %d, want 409", code)
+	}
+	if code := app.do(t, "POST", "/events/"+other.Id.Hex()+"/extend", EventExtension{Minutes: 5}, nil); code != http.StatusForbidden {
+		t.Errorf("POST /events/:id/extend of another's event: got %d, want 403", code)
+	}
+	if code := app.do(t, "POST", "/events/"+event.Id.Hex()+"/end", nil, nil); code != http.StatusConflict {
+		t.Errorf("POST /events/:id/end before it starts: got %d, want 409", code)
+	}
+}
+
+func TestIdParams(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+
+	for _, tc := range []struct {
+		target string
+		want   int
+	}{
+		{"/events/nope", http.StatusBadRequest},
+		{"/events/5b0e8f3a9d1e4c2b7a6f0e13", http.StatusNotFound},
+		{"/rooms/5b0e8f3a9d1e4c2b%7A", http.StatusBadRequest},
+		{"/venues/" + venue.Slug, http.StatusOK},
+		{"/venues/no-such-office", http.StatusNotFound},
+		{"/venues/Main%20Office", http.StatusBadRequest},
+	} {
+		if code := app.do(t, "GET", tc.target, nil, nil); code != tc.want {
+			t.Errorf("GET %s: got %d, want %d", tc.target, code, tc.want)
+		}
+	}
+	if code := app.do(t, "GET", "/events/export", nil, nil); code == http.StatusBadRequest {
+		t.Errorf("GET /events/export: got %d", code)
+	}
+}
+
+func TestSanitizeInput(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+
+	room := Room{}
+	body := map[string]interface{}{"name": "  Huddle \n", "venue_id": venue.Id.Hex(), "capacity": 4, "names": map[string]string{"ja": " ハドル "}}
+	if code := app.do(t, "POST", "/rooms", body, &room); code != http.StatusCreated {
+		t.Fatalf("POST /rooms: got %d", code)
+	}
+	if room.Name != "Huddle" || room.Names["ja"] != "ハドル" {
+		t.Errorf("POST /rooms: got name %q and names %v, want them trimmed", room.Name, room.Names)
+	}
+
+	body["name"] = strings.Repeat("a", maxInputLength+1)
+	if code := app.do(t, "POST", "/rooms", body, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /rooms with a name too long: got %d, want 422", code)
+	}
+}
+
+func TestEventsStatusCancelled(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	start := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	live := app.event(t, Event{Name: "Planning", StartTime: start, EndTime: start.Add(time.Hour)})
+	deleted := app.event(t, Event{Name: "Review", StartTime: start, EndTime: start.Add(time.Hour)})
+	if err := app.c.events().Delete(context.Background(), deleted.Id.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	cancelled := app.event(t, Event{Name: "Retro", StartTime: start, EndTime: start.Add(time.Hour)})
+	now := time.Now()
+	cancelled.CancelledAt = &now
+	if err := app.c.events().Cancel(context.Background(), &cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []Event{}
+	target := "/events?status=cancelled,confirmed&start_time=2030-06-03T00:00:00Z&end_time=2030-06-04T00:00:00Z"
+	if code := app.do(t, "GET", target, nil, &events); code != http.StatusOK {
+		t.Fatalf("GET %s: got %d", target, code)
+	}
+	got := map[string]string{}
+	for _, event := range events {
+		got[event.Id.Hex()] = eventStatus(event)
+	}
+	want := map[string]string{live.Id.Hex(): EventConfirmed, cancelled.Id.Hex(): EventCancelled}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GET %s: got %v, want %v", target, got, want)
+	}
+}
+
+func TestEventSearchAndRestore(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	start := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	planning := app.event(t, Event{Name: "Quarterly Planning", StartTime: start, EndTime: start.Add(time.Hour)})
+	app.event(t, Event{Name: "Retro", StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)})
+
+	events := []Event{}
+	target := "/events/search?q=planning&start_time=2030-06-03T00:00:00Z&end_time=2030-06-04T00:00:00Z"
+	if code := app.do(t, "GET", target, nil, &events); code != http.StatusOK {
+		t.Fatalf("GET %s: got %d", target, code)
+	}
+	if len(events) != 1 || events[0].Id != planning.Id {
+		t.Errorf("GET %s: got %d events, want %s", target, len(events), planning.Name)
+	}
+
+	now := time.Now()
+	planning.CancelledAt = &now
+	if err := app.c.events().Cancel(context.Background(), &planning); err != nil {
+		t.Fatal(err)
+	}
+	restored := Event{}
+	target = "/events/" + planning.Id.Hex() + "/restore"
+	if code := app.do(t, "POST", target, nil, &restored); code != http.StatusOK {
+		t.Fatalf("POST %s: got %d", target, code)
+	}
+	if status := eventStatus(restored); status != EventConfirmed {
+		t.Errorf("POST %s: got status %s, want %s", target, status, EventConfirmed)
+	}
+}
+
+func TestRoomSlugUnique(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	room := app.room(t, venue, "Huddle", 4)
+
+	taken := Room{Name: "Huddle", Slug: room.Slug, VenueId: room.VenueId, Capacity: 4}
+	if err := app.c.rooms().Create(context.Background(), &taken); err != ErrConflict {
+		t.Errorf("Create with a slug taken in the venue: got %v, want ErrConflict", err)
+	}
+	other := Room{Name: "Huddle", Slug: room.Slug, VenueId: app.venue(t, "Warehouse").Id.Hex(), Capacity: 4}
+	if err := app.c.rooms().Create(context.Background(), &other); err != nil {
+		t.Errorf("Create with the slug of a room of another venue: %v", err)
+	}
+	other.VenueId = room.VenueId
+	if err := app.c.rooms().Update(context.Background(), &other); err != ErrConflict {
+		t.Errorf("Update to a slug taken in the venue: got %v, want ErrConflict", err)
+	}
+}
+
+func TestRecurrenceStarts(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(year int, month time.Month, day int, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		first      time.Time
+		want       []time.Time
+	}{
+		{
+			"daily across the spring DST change",
+			Recurrence{Frequency: FrequencyDaily, Count: 3},
+			at(2030, time.March, 9, 9),
+			[]time.Time{at(2030, time.March, 9, 9), at(2030, time.March, 10, 9), at(2030, time.March, 11, 9)},
+		},
+		{
+			"weekly across the autumn DST change until an occurrence",
+			Recurrence{Frequency: FrequencyWeekly, Weekdays: []time.Weekday{time.Monday, time.Friday}, Until: at(2030, time.November, 4, 9)},
+			at(2030, time.October, 28, 9),
+			[]time.Time{at(2030, time.October, 28, 9), at(2030, time.November, 1, 9), at(2030, time.November, 4, 9)},
+		},
+		{
+			"every other day counted",
+			Recurrence{Frequency: FrequencyDaily, Interval: 2, Count: 3},
+			at(2030, time.June, 3, 9),
+			[]time.Time{at(2030, time.June, 3, 9), at(2030, time.June, 5, 9), at(2030, time.June, 7, 9)},
+		},
+		{
+			"until before the second occurrence",
+			Recurrence{Frequency: FrequencyDaily, Until: at(2030, time.June, 4, 8)},
+			at(2030, time.June, 3, 9),
+			[]time.Time{at(2030, time.June, 3, 9)},
+		},
+		{
+			"count reached before until",
+			Recurrence{Frequency: FrequencyWeekly, Count: 2, Until: at(2030, time.December, 31, 9)},
+			at(2030, time.June, 3, 9),
+			[]time.Time{at(2030, time.June, 3, 9), at(2030, time.June, 10, 9)},
+		},
+		{
+			"monthly on the 31st skips shorter months",
+			Recurrence{Frequency: FrequencyMonthly, Count: 3},
+			at(2030, time.January, 31, 9),
+			[]time.Time{at(2030, time.January, 31, 9), at(2030, time.March, 31, 9), at(2030, time.May, 31, 9)},
+		},
+		{
+			"weeks from monday",
+			Recurrence{Frequency: FrequencyWeekly, Interval: 2, Count: 4, Weekdays: []time.Weekday{time.Tuesday, time.Sunday}},
+			at(1997, time.August, 5, 9),
+			[]time.Time{at(1997, time.August, 5, 9), at(1997, time.August, 10, 9), at(1997, time.August, 19, 9), at(1997, time.August, 24, 9)},
+		},
+		{
+			"weeks from sunday",
+			Recurrence{Frequency: FrequencyWeekly, Interval: 2, Count: 4, Weekdays: []time.Weekday{time.Tuesday, time.Sunday}, WeekStart: "sunday"},
+			at(1997, time.August, 5, 9),
+			[]time.Time{at(1997, time.August, 5, 9), at(1997, time.August, 17, 9), at(1997, time.August, 19, 9), at(1997, time.August, 31, 9)},
+		},
+		{
+			"unknown frequency",
+			Recurrence{Frequency: "hourly", Count: 3},
+			at(2030, time.June, 3, 9),
+			[]time.Time{at(2030, time.June, 3, 9)},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.recurrence.Starts(tt.first)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	endless := Recurrence{Frequency: FrequencyDaily}
+	if got := len(endless.Starts(at(2030, time.June, 3, 9))); got != 1000 {
+		t.Errorf("series without an end: got %d occurrences, want 1000", got)
+	}
+}
+
+func TestEventOccurrences(t *testing.T) {
+	monday := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.UTC)
+	event := Event{Id: storage.NewObjectId(), Name: "Standup", StartTime: monday, EndTime: monday.Add(30 * time.Minute)}
+	event.SetRecurrence(&Recurrence{
+		Frequency:  FrequencyDaily,
+		Count:      5,
+		Exceptions: []time.Time{monday.AddDate(0, 0, 2).In(time.FixedZone("WIB", 7*60*60))},
+	})
+	if want := monday.AddDate(0, 0, 4).Add(30 * time.Minute); !event.SeriesEndTime.Equal(want) {
+		t.Errorf("SeriesEndTime: got %v, want %v", event.SeriesEndTime, want)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       []int
+	}{
+		{"the whole series", monday, monday.AddDate(0, 0, 7), []int{0, 1, 3, 4}},
+		{"the exception alone", monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3), []int{}},
+		{"overlapping an occurrence's end", monday.Add(15 * time.Minute), monday.Add(time.Hour), []int{0}},
+		{"starting as an occurrence ends", monday.Add(30 * time.Minute), monday.Add(time.Hour), []int{}},
+		{"after the series", monday.AddDate(0, 0, 5), monday.AddDate(0, 0, 7), []int{}},
+	}
+	for _, tt := range tests {
+		got := event.Occurrences(tt.start, tt.end)
+		days := []int{}
+		for _, occurrence := range got {
+			days = append(days, int(occurrence.StartTime.Sub(monday).Hours()/24))
+			if occurrence.RecurringEventId != event.Id || occurrence.Recurrence != nil || occurrence.EndTime.Sub(occurrence.StartTime) != 30*time.Minute {
+				t.Errorf("%s: got occurrence %+v", tt.name, occurrence)
+			}
+		}
+		if !reflect.DeepEqual(days, tt.want) {
+			t.Errorf("%s: got the occurrences of days %v, want %v", tt.name, days, tt.want)
+		}
+	}
+}
+
+func TestRRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		recurrence *Recurrence
+		want       []string
+	}{
+		{"none", nil, []string{}},
+		{"daily counted", &Recurrence{Frequency: FrequencyDaily, Interval: 2, Count: 10}, []string{"RRULE:FREQ=DAILY;INTERVAL=2;COUNT=10"}},
+		{
+			"weekly until with exceptions",
+			&Recurrence{
+				Frequency:  FrequencyWeekly,
+				Weekdays:   []time.Weekday{time.Monday, time.Wednesday},
+				Until:      time.Date(2030, time.June, 30, 16, 0, 0, 0, time.FixedZone("WIB", 7*60*60)),
+				Exceptions: []time.Time{time.Date(2030, time.June, 5, 9, 0, 0, 0, time.UTC)},
+				WeekStart:  "Sunday",
+			},
+			[]string{"RRULE:FREQ=WEEKLY;WKST=SU;BYDAY=MO,WE;UNTIL=20300630T090000Z", "EXDATE:20300605T090000Z"},
+		},
+	}
+	for _, tt := range tests {
+		if got := rrule(tt.recurrence); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImportEvent(t *testing.T) {
+	board := Room{Id: storage.NewObjectId(), Name: "Board Room", Slug: "board-room"}
+	rooms := newImportRooms([]Room{board})
+	eastern := "BEGIN:VTIMEZONE\r\nTZID:Eastern Standard Time\r\n" +
+		"BEGIN:DAYLIGHT\r\nTZOFFSETTO:-0400\r\nEND:DAYLIGHT\r\n" +
+		"BEGIN:STANDARD\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+	june3 := func(hour int, min int) time.Time {
+		return time.Date(2030, time.June, 3, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		user    User
+		vevent  string
+		status  string
+		message string
+		check   func(Event) bool
+	}{
+		{
+			"folded lines", testAdmin,
+			"UID:fold-1\r\nSUMMARY:Quarterly\r\n  planning\r\n\t review\r\nDESCRIPTION:Agenda\\, notes\\nand\r\n  actions\r\n" +
+				"DTSTART:20300603T090000Z\r\nDTEND:20300603T100000Z\r\nLOCATION:Board\r\n  Room\r\n",
+			ImportReady, "",
+			func(e Event) bool {
+				return e.Name == "Quarterly planning review" && e.Description == "Agenda, notes\nand actions" && e.LocationID == board.Id.Hex()
+			},
+		},
+		{
+			"TZID of the time zone database", testAdmin,
+			"UID:tz-1\r\nDTSTART;TZID=America/New_York:20300603T090000\r\nDTEND;TZID=America/New_York:20300603T093000\r\nLOCATION:Board Room\r\n",
+			ImportReady, "",
+			func(e Event) bool { return e.StartTime.Equal(june3(13, 0)) && e.EndTime.Equal(june3(13, 30)) },
+		},
+		{
+			"TZID of a VTIMEZONE", testAdmin,
+			"UID:tz-2\r\nDTSTART;TZID=\"Eastern Standard Time\":20300603T090000\r\nDURATION:PT1H30M\r\nLOCATION:Board Room\r\n",
+			ImportReady, "",
+			func(e Event) bool { return e.StartTime.Equal(june3(14, 0)) && e.EndTime.Equal(june3(15, 30)) },
+		},
+		{
+			"floating time", testAdmin,
+			"UID:tz-3\r\nDTSTART:20300603T090000\r\nLOCATION:Board Room\r\n",
+			ImportReady, "",
+			func(e Event) bool {
+				return e.StartTime.Equal(time.Date(2030, time.June, 3, 9, 0, 0, 0, icalLocation)) && e.EndTime.Sub(e.StartTime) == time.Hour
+			},
+		},
+		{
+			"all day", testAdmin,
+			"UID:day-1\r\nDTSTART;VALUE=DATE:20300603\r\nLOCATION:Board Room\r\n",
+			ImportReady, "",
+			func(e Event) bool { return e.EndTime.Sub(e.StartTime) == 24*time.Hour },
+		},
+		{
+			"room resource with a quoted name", testAdmin,
+			"UID:res-1\r\nDTSTART:20300603T090000Z\r\nATTENDEE;CUTYPE=RESOURCE;CN=\"Board: 12 seats\":mailto:board-room@rooms.example.com\r\n" +
+				"ATTENDEE;CN=Ana:mailto:ana@example.com\r\n",
+			ImportReady, "",
+			func(e Event) bool {
+				return e.LocationID == board.Id.Hex() && reflect.DeepEqual(e.Guests, []string{"ana@example.com"})
+			},
+		},
+		{
+			"recurring with exceptions", testAdmin,
+			"UID:rec-1\r\nDTSTART:20300603T090000Z\r\nDTEND:20300603T093000Z\r\nRRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=6\r\n" +
+				"EXDATE:20300605T090000Z,20300610T090000Z\r\nLOCATION:Board Room\r\n",
+			ImportReady, "",
+			func(e Event) bool {
+				return e.Recurrence != nil && e.Recurrence.Count == 6 && len(e.Recurrence.Exceptions) == 2 &&
+					len(e.Occurrences(june3(0, 0), june3(0, 0).AddDate(0, 1, 0))) == 4
+			},
+		},
+		{"lines without a colon", testAdmin, "UID:junk-1\r\nthis is not a property\r\nDTSTART:20300603T090000Z\r\nLOCATION:Board Room\r\n", ImportReady, "", nil},
+		{"no room", testAdmin, "UID:room-1\r\nDTSTART:20300603T090000Z\r\nLOCATION:Cafeteria\r\n", ImportUnmatchedRoom, "No room", nil},
+		{"cancelled", testAdmin, "UID:cxl-1\r\nSTATUS:CANCELLED\r\nDTSTART:20300603T090000Z\r\n", ImportSkipped, "cancelled", nil},
+		{"no UID", testAdmin, "DTSTART:20300603T090000Z\r\nLOCATION:Board Room\r\n", ImportInvalid, "no UID", nil},
+		{"no DTSTART", testAdmin, "UID:bad-1\r\nLOCATION:Board Room\r\n", ImportInvalid, "no DTSTART", nil},
+		{"malformed DTSTART", testAdmin, "UID:bad-2\r\nDTSTART:2030-06-03T09:00:00Z\r\n", ImportInvalid, "DTSTART", nil},
+		{"malformed DTEND", testAdmin, "UID:bad-3\r\nDTSTART:20300603T090000Z\r\nDTEND:tomorrow\r\n", ImportInvalid, "DTEND", nil},
+		{"malformed DURATION", testAdmin, "UID:bad-4\r\nDTSTART:20300603T090000Z\r\nDURATION:1 hour\r\n", ImportInvalid, "DURATION", nil},
+		{"ending before it starts", testAdmin, "UID:bad-5\r\nDTSTART:20300603T090000Z\r\nDTEND:20300603T080000Z\r\n", ImportInvalid, "end after", nil},
+		{"malformed RECURRENCE-ID", testAdmin, "UID:bad-6\r\nRECURRENCE-ID:soon\r\nDTSTART:20300603T090000Z\r\n", ImportInvalid, "RECURRENCE-ID", nil},
+		{"unsupported frequency", testAdmin, "UID:bad-7\r\nDTSTART:20300603T090000Z\r\nRRULE:FREQ=YEARLY\r\n", ImportInvalid, "YEARLY", nil},
+		{"BYDAY on a daily rule", testAdmin, "UID:bad-8\r\nDTSTART:20300603T090000Z\r\nRRULE:FREQ=DAILY;BYDAY=MO\r\n", ImportInvalid, "BYDAY", nil},
+		{"malformed EXDATE", testAdmin, "UID:bad-9\r\nDTSTART:20300603T090000Z\r\nRRULE:FREQ=DAILY\r\nEXDATE:someday\r\n", ImportInvalid, "EXDATE", nil},
+		{
+			"organized by someone else", User{Email: "ana@example.com", Role: RoleUser},
+			"UID:org-1\r\nDTSTART:20300603T090000Z\r\nORGANIZER:mailto:bo@example.com\r\nLOCATION:Board Room\r\n",
+			ImportForbidden, "admins", nil,
+		},
+	}
+	for _, tt := range tests {
+		cal, err := parseICal(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + eastern + "BEGIN:VEVENT\r\n" + tt.vevent + "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var vevent *icalComponent
+		for _, c := range cal.Components {
+			if c.Name == "VEVENT" {
+				vevent = c
+			}
+		}
+
+		item := importEvent(vevent, icalZones(cal), rooms, tt.user)
+		if item.Status != tt.status || !strings.Contains(item.Message, tt.message) {
+			t.Errorf("%s: got %s %q, want %s with %q", tt.name, item.Status, item.Message, tt.status, tt.message)
+			continue
+		}
+		if tt.check != nil && (item.Event == nil || !tt.check(*item.Event)) {
+			t.Errorf("%s: got event %+v", tt.name, item.Event)
+		}
+	}
+
+	if _, err := parseICal(strings.NewReader("BEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\n")); err != errNoCalendar {
+		t.Errorf("parseICal without a VCALENDAR: got %v, want errNoCalendar", err)
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	app := newTestApp(t, testAdmin)
+	venue := app.venue(t, "Main Office")
+	board := app.room(t, venue, "Board Room", 12)
+	app.event(t, Event{Name: "Imported", LocationID: board.Id.Hex(), StartTime: time.Date(2030, time.July, 1, 9, 0, 0, 0, time.UTC),
+		EndTime: time.Date(2030, time.July, 1, 10, 0, 0, 0, time.UTC), Source: importSource, ExternalId: "dup-1"})
+
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:series-1\r\nSUMMARY:Standup\r\nDTSTART:20300603T090000Z\r\nDTEND:20300603T093000Z\r\nRRULE:FREQ=DAILY;COUNT=3\r\nLOCATION:Board Room\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:series-1\r\nSUMMARY:Standup, moved\r\nRECURRENCE-ID:20300604T090000Z\r\nDTSTART:20300604T100000Z\r\nDTEND:20300604T103000Z\r\nLOCATION:Board Room\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:clash-1\r\nSUMMARY:Clash\r\nDTSTART:20300605T091500Z\r\nDTEND:20300605T094500Z\r\nLOCATION:Board Room\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:free-1\r\nSUMMARY:Free\r\nDTSTART:20300604T091500Z\r\nDTEND:20300604T094500Z\r\nLOCATION:Board Room\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:dup-1\r\nSUMMARY:Again\r\nDTSTART:20300701T090000Z\r\nDTEND:20300701T100000Z\r\nLOCATION:Board Room\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	cal, err := parseICal(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := app.c.planImport(context.Background(), cal, testAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{ImportReady, ImportReady, ImportConflict, ImportReady, ImportDuplicate}
+	got := []string{}
+	for _, item := range items {
+		got = append(got, item.Status)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	series, moved := items[0].Event, items[1].Event
+	if len(series.Recurrence.Exceptions) != 1 || !series.Recurrence.Exceptions[0].Equal(items[1].RecurrenceId) {
+		t.Errorf("got exceptions %v, want the moved occurrence's", series.Recurrence.Exceptions)
+	}
+	if moved.RecurringEventId != series.Id || !moved.OriginalStartTime.Equal(items[1].RecurrenceId) {
+		t.Errorf("got the moved occurrence %+v, want it of the series", moved)
+	}
+}
+
+func TestSanitizeDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"script", "Agenda<script>alert(document.cookie)</script> here", "Agenda here"},
+		{"script in upper case with attributes", "<SCRIPT type=\"text/javascript\">\nsteal()\n</SCRIPT >Agenda", "Agenda"},
+		{"style", "<style>body { display: none }</style>Agenda", "Agenda"},
+		{"event handler", "<img src=x onerror=alert(1)>Agenda", "Agenda"},
+		{"tags split across lines", "<a\nhref=\"javascript:alert(1)\">Agenda</a>", "Agenda"},
+		{"nested tags", "<scr<script>ipt>alert(1)</script>", "<scr"},
+		{"control characters", "Agenda\x00\x1b[31m\r\nnext", "Agenda[31m\nnext"},
+		{"markdown", "**Agenda** and [notes](https://example.com)", "**Agenda** and [notes](https://example.com)"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeDescription(tt.raw); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := len([]rune(sanitizeDescription(strings.Repeat("é", maxDescriptionLength+1)))); got != maxDescriptionLength {
+		t.Errorf("a description too long: got %d runes, want %d", got, maxDescriptionLength)
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"paragraphs and breaks", "One\ntwo\n\nThree", "<p>One<br>two</p>\n<p>Three</p>"},
+		{"list", "- **bold**\n* *italic*", "<ul><li><strong>bold</strong></li><li><em>italic</em></li></ul>"},
+		{"code", "Run `a < b`", "<p>Run <code>a &lt; b</code></p>"},
+		{"https link", "[notes](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">notes</a></p>`},
+		{"mailto link", "[mail](mailto:ana@example.com)", `<p><a href="mailto:ana@example.com" rel="nofollow noopener">mail</a></p>`},
+		{"javascript link", "[click](javascript:alert(1))", "<p>click)</p>"},
+		{"javascript link in mixed case", "[click](JaVaScRiPt:alert`1`)", "<p>click</p>"},
+		{"javascript link as entities", "[click](&#106;avascript:alert)", "<p>click</p>"},
+		{"data link", "[click](data:text/html;base64,PHNjcmlwdD4=)", "<p>click</p>"},
+		{"quote breaking out of href", `[x](https://a.example.com"onmouseover="alert)`, `<p><a href="https://a.example.com&#34;onmouseover=&#34;alert" rel="nofollow noopener">x</a></p>`},
+		{"markup left after sanitizing", "<scr<script>ipt>alert(1)</script>", "<p>&lt;scr</p>"},
+		{"html in the rendered text", "<b>bold</b> & <i>", "<p>bold &amp;</p>"},
+	}
+	for _, tt := range tests {
+		event := Event{}
+		setDescription(&event, tt.raw)
+		if event.DescriptionHTML != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, event.DescriptionHTML, tt.want)
+		}
+	}
+
+	stored := Event{Description: "<script>alert(1)</script>[x](javascript:alert(1))"}
+	if got := renderedDescription(stored); strings.Contains(got, "script") || strings.Contains(got, "href") {
+		t.Errorf("rendering an event stored unsanitized: got %q", got)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Health
+//
+// GET /healthz answers 200 for as long as the process can serve requests,
+// without touching any dependency, so a stuck database never gets the
+// process restarted; it reports when the process started. GET /readyz
+// probes MongoDB and the virus scanner, when one is configured, and answers
+// 503 if any of them is down, so traffic is routed away until it's back.
+// Probe results are also taken in the background and kept in memory, so they
+// survive the database being away:
+//
+//	HEALTH_INTERVAL  time between background probes (default 30s)
+//
+// GET /admin/health/history?since= returns the samples since a time (the last
+// 24 hours by default) with the uptime of each dependency and the periods in
+// which it was down.
+const (
+	healthHistorySize = 2880
+	healthTimeout     = 5 * time.Second
+)
+
+// processStartedAt is when the process started.
+var processStartedAt = time.Now()
+
+type Liveness struct {
+	OK            bool      `json:"ok"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+}
+
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	OK        bool    `json:"ok"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthSample struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	OK        bool          `json:"ok"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+type HealthIncident struct {
+	Check     string    `json:"check"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+	Error     string    `json:"error"`
+}
+
+type HealthHistory struct {
+	Since     time.Time          `json:"since"`
+	Uptime    map[string]float64 `json:"uptime"`
+	Incidents []HealthIncident   `json:"incidents"`
+	Samples   []HealthSample     `json:"samples"`
+}
+
+// healthLog is a ring of the most recent samples, oldest first.
+type healthLog struct {
+	sync.Mutex
+	samples []HealthSample
+}
+
+var healthSamples = &healthLog{}
+
+func (l *healthLog) Add(sample HealthSample) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.samples = append(l.samples, sample)
+	if len(l.samples) > healthHistorySize {
+		l.samples = l.samples[len(l.samples)-healthHistorySize:]
+	}
+}
+
+func (l *healthLog) Since(since time.Time) []HealthSample {
+	l.Lock()
+	defer l.Unlock()
+
+	result := []HealthSample{}
+	for _, sample := range l.samples {
+		if !sample.CheckedAt.Before(since) {
+			result = append(result, sample)
+		}
+	}
+
+	return result
+}
+
+func timedCheck(name string, probe func() error) HealthCheck {
+	start := time.Now()
+	err := probe()
+	check := HealthCheck{Name: name, OK: err == nil, LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+// scannerAddr returns the address of the configured network scanner, if any.
+func scannerAddr() string {
+	switch setting("SCANNER") {
+	case "clamav":
+		if addr := setting("CLAMAV_ADDR"); addr != "" {
+			return addr
+		}
+		return "localhost:3310"
+	case "icap":
+		if u, err := url.Parse(setting("ICAP_URL")); err == nil {
+			return u.Host
+		}
+	}
+
+	return ""
+}
+
+// probeHealth checks every dependency once and records the result.
+func (c *appContext) probeHealth() HealthSample {
+	sample := HealthSample{CheckedAt: time.Now(), OK: true}
+
+	session := c.db.Session.Copy()
+	session.SetTimeout(healthTimeout)
+	sample.Checks = append(sample.Checks, timedCheck("mongodb", session.Ping))
+	session.Close()
+
+	if addr := scannerAddr(); addr != "" {
+		sample.Checks = append(sample.Checks, timedCheck("scanner", func() error {
+			conn, err := net.DialTimeout("tcp", addr, healthTimeout)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}))
+	}
+
+	for _, check := range sample.Checks {
+		sample.OK = sample.OK && check.OK
+	}
+	healthSamples.Add(sample)
+
+	return sample
+}
+
+// watchHealth probes in the background for as long as the process runs.
+func (c *appContext) watchHealth() {
+	interval := 30 * time.Second
+	if d, err := time.ParseDuration(setting("HEALTH_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	for range time.Tick(interval) {
+		c.probeHealth()
+	}
+}
+
+// summarizeHealth computes per-check uptime and the periods each check was
+// failing over samples, which must be in order.
+func summarizeHealth(samples []HealthSample) (map[string]float64, []HealthIncident) {
+	ok := map[string]int{}
+	total := map[string]int{}
+	open := map[string]*HealthIncident{}
+	incidents := []HealthIncident{}
+
+	for _, sample := range samples {
+		for _, check := range sample.Checks {
+			total[check.Name]++
+			if check.OK {
+				ok[check.Name]++
+				if incident := open[check.Name]; incident != nil {
+					incident.EndTime = sample.CheckedAt
+					incidents = append(incidents, *incident)
+					delete(open, check.Name)
+				}
+				continue
+			}
+			if open[check.Name] == nil {
+				open[check.Name] = &HealthIncident{Check: check.Name, StartTime: sample.CheckedAt, Error: check.Error}
+			}
+		}
+	}
+	for _, incident := range open {
+		incidents = append(incidents, *incident)
+	}
+
+	uptime := map[string]float64{}
+	for name, n := range total {
+		uptime[name] = float64(ok[name]) / float64(n)
+	}
+
+	return uptime, incidents
+}
+
+// Health Handlers
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	WriteSuccess(w, http.StatusOK, Liveness{true, processStartedAt, time.Since(processStartedAt).Seconds()})
+}
+
+func (c *appContext) readyHandler(w http.ResponseWriter, r *http.Request) {
+	sample := c.probeHealth()
+	if !sample.OK {
+		WriteSuccess(w, http.StatusServiceUnavailable, sample)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, sample)
+}
+
+func (c *appContext) healthHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-24 * time.Hour)
+	if v, err := time.Parse(time.RFC3339, r.URL.Query().Get("since")); err == nil {
+		since = v
+	}
+
+	samples := healthSamples.Since(since)
+	uptime, incidents := summarizeHealth(samples)
+
+	WriteSuccess(w, http.StatusOK, HealthHistory{since, uptime, incidents, samples})
+}
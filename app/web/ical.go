@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// iCalendar
+//
+// GET /events/:id/ical renders an event and GET /rooms/:id/calendar.ics the
+// schedule of a room as RFC 5545 calendars, so they can be imported or
+// subscribed to from calendar apps. Times are written in the venues' local
+// zone, described by a VTIMEZONE, so recurring events keep their wall-clock
+// time. Recurring events carry their RRULE and EXDATEs, and changed
+// occurrences a RECURRENCE-ID. A room feed covers the events from
+// icalPastWindow ago to icalFutureWindow ahead.
+const (
+	icalTimezone     = defaultTimeZone
+	icalPastWindow   = 30 * 24 * time.Hour
+	icalFutureWindow = 365 * 24 * time.Hour
+
+	icalLocalFormat = "20060102T150405"
+	icalUTCFormat   = "20060102T150405Z"
+)
+
+// icalVTimezone describes icalTimezone, which has been UTC+7 without daylight
+// saving time since 1920.
+var icalVTimezone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:" + icalTimezone,
+	"BEGIN:STANDARD",
+	"DTSTART:19200401T000000",
+	"TZOFFSETFROM:+0700",
+	"TZOFFSETTO:+0700",
+	"TZNAME:ICT",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+var icalLocation = ictLocation
+
+type icalWriter struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folded at 75 octets without splitting UTF-8
+// sequences.
+func (w *icalWriter) line(s string) {
+	for len(s) > 75 {
+		cut := 75
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.buf.WriteString(s[:cut] + "\r\n")
+		s = " " + s[cut:]
+	}
+	w.buf.WriteString(s + "\r\n")
+}
+
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func icalLocal(t time.Time) string {
+	return ";TZID=" + icalTimezone + ":" + t.In(icalLocation).Format(icalLocalFormat)
+}
+
+func (w *icalWriter) begin(name string) {
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//Ivana//Room Booking//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if name != "" {
+		w.line("X-WR-CALNAME:" + icalText(name))
+		w.line("X-WR-TIMEZONE:" + icalTimezone)
+	}
+	for _, l := range icalVTimezone {
+		w.line(l)
+	}
+}
+
+func (w *icalWriter) end() {
+	w.line("END:VCALENDAR")
+}
+
+func (w *icalWriter) event(event Event, stamp time.Time) {
+	w.line("BEGIN:VEVENT")
+	uid := event.Id.Hex()
+	if event.RecurringEventId != "" {
+		uid = event.RecurringEventId.Hex()
+	}
+	w.line("UID:" + uid + "@ivana")
+	w.line("DTSTAMP:" + stamp.UTC().Format(icalUTCFormat))
+	w.line("DTSTART" + icalLocal(event.StartTime))
+	w.line("DTEND" + icalLocal(event.EndTime))
+	if event.RecurringEventId != "" {
+		w.line("RECURRENCE-ID" + icalLocal(event.OriginalStartTime))
+	}
+	if event.Recurrence != nil {
+		w.line(rrule(event.Recurrence)[0])
+		for _, t := range event.Recurrence.Exceptions {
+			w.line("EXDATE" + icalLocal(t))
+		}
+	}
+	w.line("SUMMARY:" + icalText(event.Name))
+	if event.Location != "" {
+		w.line("LOCATION:" + icalText(event.Location))
+	}
+	if event.Description != "" {
+		w.line("DESCRIPTION:" + icalText(event.Description))
+	}
+	if event.Owner != "" {
+		w.line("ORGANIZER:mailto:" + event.Owner)
+	}
+	for _, guest := range event.Guests {
+		w.line("ATTENDEE;ROLE=REQ-PARTICIPANT:mailto:" + guest)
+	}
+	if event.Tentative || event.PendingApproval {
+		w.line("STATUS:TENTATIVE")
+	} else {
+		w.line("STATUS:CONFIRMED")
+	}
+	w.line("END:VEVENT")
+}
+
+func writeCalendar(w http.ResponseWriter, filename string, cal *icalWriter) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(cal.buf.Bytes())
+}
+
+// iCalendar Handlers
+func (c *appContext) eventICalHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	cal := &icalWriter{}
+	cal.begin("")
+	cal.event(event, clockNow())
+	cal.end()
+
+	writeCalendar(w, event.Id.Hex()+".ics", cal)
+}
+
+func (c *appContext) roomCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	start_time := clockNow().Add(-icalPastWindow)
+	end_time := clockNow().Add(icalFutureWindow)
+	repo := c.events()
+	events, err := repo.AllByLocationIds(r.Context(), []string{room.Id.Hex()}, start_time, end_time)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	recurring, err := repo.AllRecurring(r.Context(), room.Id.Hex(), start_time, end_time, "")
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	stamp := time.Now()
+	cal := &icalWriter{}
+	cal.begin(room.Name)
+	seen := map[string]bool{}
+	for _, event := range append(events, recurring...) {
+		if seen[event.Id.Hex()] {
+			continue
+		}
+		seen[event.Id.Hex()] = true
+		cal.event(event, stamp)
+	}
+	cal.end()
+
+	writeCalendar(w, room.Slug+".ics", cal)
+}
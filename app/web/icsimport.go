@@ -0,0 +1,795 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Calendar import
+//
+// POST /events/import takes an iCalendar export, as multipart/form-data with
+// a "file" field, and answers with a report of what importing it would do,
+// one item per VEVENT, without booking anything. Each event is mapped to a
+// room by its resource attendees, whose email's local part is matched
+// against the room slugs and whose name against the room names, then by its
+// LOCATION; it's checked for conflicts with the schedule and with the events
+// before it in the file. POST /event-imports/:id/confirm books the items
+// that were ready, checking for conflicts again since the schedule may have
+// changed, and answers with the report updated. Reports are kept in
+// event_imports; GET /event-imports/:id reads one.
+//
+// Recurring events keep their RRULE (daily, weekly or monthly) and EXDATEs,
+// and changed occurrences, a RECURRENCE-ID with the UID of their series,
+// become changed occurrences of it. The organizer owns an event when the
+// caller may book for them, the caller otherwise. Events are marked with
+// their UID, so importing a file twice reports the second copies as
+// duplicates. Imported events aren't announced.
+const (
+	ImportPending   = "pending"
+	ImportConfirmed = "confirmed"
+
+	ImportReady         = "ready"
+	ImportConflict      = "conflict"
+	ImportUnmatchedRoom = "unmatched_room"
+	ImportDuplicate     = "duplicate"
+	ImportInvalid       = "invalid"
+	ImportSkipped       = "skipped"
+	ImportForbidden     = "forbidden"
+	ImportImported      = "imported"
+	ImportFailed        = "failed"
+
+	importSource    = "ics"
+	maxImportSize   = 10 << 20
+	maxImportEvents = 2000
+)
+
+var (
+	ErrInvalidCalendar   = &Error{"invalid_calendar", 400, "Bad request", "The file must be an iCalendar file with at least one VEVENT."}
+	ErrImportTooLarge    = &Error{"import_too_large", 413, "Payload Too Large", "Calendars can have at most 2000 events and 10 MB."}
+	ErrImportConfirmed   = &Error{"import_confirmed", 409, "Conflict", "The import has already been confirmed."}
+	ErrNotImportOwner    = &Error{"not_import_owner", 403, "Forbidden", "Only who uploaded the calendar or an admin can confirm its import."}
+	icalDurationPattern  = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	icalFrequencies      = map[string]string{"DAILY": FrequencyDaily, "WEEKLY": FrequencyWeekly, "MONTHLY": FrequencyMonthly}
+	icalImportDateFormat = "20060102"
+
+	errNoCalendar = errors.New("ical: no VCALENDAR")
+)
+
+type ImportItem struct {
+	Uid          string    `json:"uid"`
+	RecurrenceId time.Time `json:"recurrence_id,omitempty"`
+	Summary      string    `json:"summary"`
+	Status       string    `json:"status"`
+	Message      string    `json:"message,omitempty"`
+	ConflictWith string    `json:"conflict_with,omitempty"`
+	Event        *Event    `json:"event,omitempty"`
+}
+
+type EventImport struct {
+	Id          storage.ObjectId `json:"id" bson:"_id,omitempty"`
+	Filename    string           `json:"filename"`
+	Status      string           `json:"status"`
+	CreatedBy   string           `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ConfirmedAt time.Time        `json:"confirmed_at,omitempty"`
+	Counts      map[string]int   `json:"counts"`
+	Items       []ImportItem     `json:"items"`
+}
+
+func (imp *EventImport) count() {
+	imp.Counts = map[string]int{}
+	for _, item := range imp.Items {
+		imp.Counts[item.Status]++
+	}
+}
+
+// icalProperty is a content line: NAME;PARAM=VALUE:VALUE.
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icalComponent is a component with its properties and subcomponents.
+type icalComponent struct {
+	Name       string
+	Properties []icalProperty
+	Components []*icalComponent
+}
+
+func (c *icalComponent) get(name string) (icalProperty, bool) {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return icalProperty{}, false
+}
+
+func (c *icalComponent) all(name string) []icalProperty {
+	result := []icalProperty{}
+	for _, p := range c.Properties {
+		if p.Name == name {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// icalLines reads the unfolded content lines of r.
+func icalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportSize)
+	lines := []string{}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// icalParseLine splits a content line, honouring quoted parameter values.
+func icalParseLine(line string) (icalProperty, bool) {
+	p := icalProperty{Params: map[string]string{}}
+	quoted := false
+	colon := -1
+	for i, ch := range line {
+		if ch == '"' {
+			quoted = !quoted
+		}
+		if ch == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return p, false
+	}
+
+	p.Value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	p.Name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if i := strings.Index(param, "="); i > 0 {
+			p.Params[strings.ToUpper(param[:i])] = strings.Trim(param[i+1:], `"`)
+		}
+	}
+
+	return p, true
+}
+
+// parseICal reads the VCALENDAR in r.
+func parseICal(r io.Reader) (*icalComponent, error) {
+	lines, err := icalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &icalComponent{}
+	stack := []*icalComponent{root}
+	for _, line := range lines {
+		p, ok := icalParseLine(line)
+		if !ok {
+			continue
+		}
+		top := stack[len(stack)-1]
+		switch p.Name {
+		case "BEGIN":
+			c := &icalComponent{Name: strings.ToUpper(p.Value)}
+			top.Components = append(top.Components, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			top.Properties = append(top.Properties, p)
+		}
+	}
+	for _, c := range root.Components {
+		if c.Name == "VCALENDAR" {
+			return c, nil
+		}
+	}
+
+	return nil, errNoCalendar
+}
+
+func icalUnescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// icalZones reads the offsets of the VTIMEZONEs of cal, for the TZIDs the
+// time zone database doesn't know, such as Windows zone names.
+func icalZones(cal *icalComponent) map[string]*time.Location {
+	zones := map[string]*time.Location{}
+	for _, c := range cal.Components {
+		tzid, ok := c.get("TZID")
+		if c.Name != "VTIMEZONE" || !ok {
+			continue
+		}
+		for _, sub := range c.Components {
+			offset, ok := sub.get("TZOFFSETTO")
+			if sub.Name != "STANDARD" || !ok {
+				continue
+			}
+			if t, err := time.Parse("-0700", offset.Value); err == nil {
+				_, seconds := t.Zone()
+				zones[tzid.Value] = time.FixedZone(tzid.Value, seconds)
+			}
+		}
+	}
+
+	return zones
+}
+
+// icalTime reads a DATE or DATE-TIME value. Floating times and dates are in
+// the venues' zone.
+func icalTime(p icalProperty, zones map[string]*time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.Value)
+	if p.Params["VALUE"] == "DATE" || len(value) == len(icalImportDateFormat) {
+		t, err := time.ParseInLocation(icalImportDateFormat, value, icalLocation)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalUTCFormat, value)
+		return t, false, err
+	}
+
+	loc := icalLocation
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if zone, ok := zones[tzid]; ok {
+			loc = zone
+		}
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation(icalLocalFormat, value, loc)
+
+	return t, false, err
+}
+
+// icalDuration reads a DURATION value such as PT1H30M.
+func icalDuration(s string) (time.Duration, bool) {
+	m := icalDurationPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	d := time.Duration(0)
+	for i, unit := range units {
+		if n, err := strconv.Atoi(m[i+2]); err == nil {
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, true
+}
+
+// icalRecurrence reads an RRULE, reporting why when it can't be kept.
+func icalRecurrence(rule string, zones map[string]*time.Location) (*Recurrence, string) {
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.ToUpper(strings.TrimSpace(kv[1]))
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			freq, ok := icalFrequencies[value]
+			if !ok {
+				return nil, "RRULE frequency " + value + " isn't supported"
+			}
+			r.Frequency = freq
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, "RRULE interval " + value + " isn't valid"
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, "RRULE count " + value + " isn't valid"
+			}
+			r.Count = n
+		case "UNTIL":
+			t, _, err := icalTime(icalProperty{Value: value}, zones)
+			if err != nil {
+				return nil, "RRULE until " + value + " isn't valid"
+			}
+			r.Until = t
+		case "WKST":
+			i := indexOf(rruleWeekdays, value)
+			if i < 0 {
+				return nil, "RRULE week start " + value + " isn't valid"
+			}
+			r.WeekStart = weekdayName(time.Weekday(i))
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				i := indexOf(rruleWeekdays, day)
+				if i < 0 {
+					return nil, "RRULE BYDAY " + day + " isn't supported"
+				}
+				r.Weekdays = append(r.Weekdays, time.Weekday(i))
+			}
+		default:
+			return nil, "RRULE part " + strings.ToUpper(kv[0]) + " isn't supported"
+		}
+	}
+	if r.Frequency == "" {
+		return nil, "RRULE has no frequency"
+	}
+	if len(r.Weekdays) > 0 && r.Frequency != FrequencyWeekly {
+		return nil, "RRULE BYDAY is only supported on weekly rules"
+	}
+
+	return r, ""
+}
+
+func indexOf(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// importRooms matches calendar resources and locations to rooms.
+type importRooms struct {
+	bySlug map[string]Room
+	byName map[string]Room
+}
+
+func newImportRooms(rooms []Room) importRooms {
+	m := importRooms{map[string]Room{}, map[string]Room{}}
+	for _, room := range rooms {
+		m.bySlug[room.Slug] = room
+		for _, slug := range room.OldSlugs {
+			m.bySlug[slug] = room
+		}
+		m.byName[strings.ToLower(strings.TrimSpace(room.Name))] = room
+		for _, name := range room.Names {
+			m.byName[strings.ToLower(strings.TrimSpace(name))] = room
+		}
+	}
+
+	return m
+}
+
+func (m importRooms) match(vevent *icalComponent) (Room, bool) {
+	for _, attendee := range vevent.all("ATTENDEE") {
+		if strings.ToUpper(attendee.Params["CUTYPE"]) != "RESOURCE" && strings.ToUpper(attendee.Params["CUTYPE"]) != "ROOM" {
+			continue
+		}
+		email := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(attendee.Value, "mailto:"), "MAILTO:"))
+		if i := strings.Index(email, "@"); i > 0 {
+			if room, ok := m.bySlug[email[:i]]; ok {
+				return room, true
+			}
+		}
+		if room, ok := m.byName[strings.ToLower(strings.TrimSpace(attendee.Params["CN"]))]; ok {
+			return room, true
+		}
+	}
+	if location, ok := vevent.get("LOCATION"); ok {
+		name := strings.ToLower(strings.TrimSpace(icalUnescape(location.Value)))
+		if room, ok := m.byName[name]; ok {
+			return room, true
+		}
+		if room, ok := m.bySlug[slugify(name)]; ok {
+			return room, true
+		}
+	}
+
+	return Room{}, false
+}
+
+// importEvent reads vevent into an item for user.
+func importEvent(vevent *icalComponent, zones map[string]*time.Location, rooms importRooms, user User) ImportItem {
+	item := ImportItem{Status: ImportReady}
+	if uid, ok := vevent.get("UID"); ok {
+		item.Uid = strings.TrimSpace(uid.Value)
+	}
+	if summary, ok := vevent.get("SUMMARY"); ok {
+		item.Summary = icalUnescape(summary.Value)
+	}
+	invalid := func(msg string) ImportItem {
+		item.Status, item.Message = ImportInvalid, msg
+		return item
+	}
+
+	if status, ok := vevent.get("STATUS"); ok && strings.ToUpper(status.Value) == "CANCELLED" {
+		item.Status, item.Message = ImportSkipped, "The event is cancelled."
+		return item
+	}
+	if item.Uid == "" {
+		return invalid("The event has no UID.")
+	}
+	if recurrenceId, ok := vevent.get("RECURRENCE-ID"); ok {
+		t, _, err := icalTime(recurrenceId, zones)
+		if err != nil {
+			return invalid("RECURRENCE-ID isn't a valid time.")
+		}
+		item.RecurrenceId = t
+	}
+
+	dtstart, ok := vevent.get("DTSTART")
+	if !ok {
+		return invalid("The event has no DTSTART.")
+	}
+	start, allDay, err := icalTime(dtstart, zones)
+	if err != nil {
+		return invalid("DTSTART isn't a valid time.")
+	}
+	end := start.Add(time.Hour)
+	if allDay {
+		end = start.AddDate(0, 0, 1)
+	}
+	if dtend, ok := vevent.get("DTEND"); ok {
+		end, _, err = icalTime(dtend, zones)
+		if err != nil {
+			return invalid("DTEND isn't a valid time.")
+		}
+	} else if duration, ok := vevent.get("DURATION"); ok {
+		d, ok := icalDuration(duration.Value)
+		if !ok {
+			return invalid("DURATION isn't a valid duration.")
+		}
+		end = start.Add(d)
+	}
+	if !start.Before(end) {
+		return invalid("The event must end after it starts.")
+	}
+
+	event := Event{
+		Id:         storage.NewObjectId(),
+		Name:       item.Summary,
+		StartTime:  start,
+		EndTime:    end,
+		Source:     importSource,
+		ExternalId: item.Uid,
+		Guests:     []string{},
+		BookedVia:  BookingSource{Channel: ChannelImport, Integration: importSource},
+	}
+	if !item.RecurrenceId.IsZero() {
+		event.ExternalId += "/" + item.RecurrenceId.UTC().Format(icalUTCFormat)
+	}
+	if description, ok := vevent.get("DESCRIPTION"); ok {
+		event.Description = icalUnescape(description.Value)
+	}
+	setDescription(&event, event.Description)
+
+	if organizer, ok := vevent.get("ORGANIZER"); ok {
+		event.Owner = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(organizer.Value, "mailto:"), "MAILTO:"))
+	}
+	if event.Owner == "" {
+		event.Owner = user.Email
+	}
+	if !user.CanManage(event) {
+		item.Status, item.Message = ImportForbidden, "Only admins can import events organized by someone else."
+		return item
+	}
+	for _, attendee := range vevent.all("ATTENDEE") {
+		cutype := strings.ToUpper(attendee.Params["CUTYPE"])
+		email := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(attendee.Value, "mailto:"), "MAILTO:"))
+		if cutype != "RESOURCE" && cutype != "ROOM" && email != event.Owner && emailFormat.MatchString(email) {
+			event.Guests = append(event.Guests, email)
+		}
+	}
+
+	if rrule, ok := vevent.get("RRULE"); ok && item.RecurrenceId.IsZero() {
+		recurrence, msg := icalRecurrence(rrule.Value, zones)
+		if recurrence == nil {
+			return invalid(msg)
+		}
+		for _, exdate := range vevent.all("EXDATE") {
+			for _, value := range strings.Split(exdate.Value, ",") {
+				t, _, err := icalTime(icalProperty{exdate.Name, exdate.Params, value}, zones)
+				if err != nil {
+					return invalid("EXDATE isn't a valid time.")
+				}
+				recurrence.Exceptions = append(recurrence.Exceptions, t)
+			}
+		}
+		event.SetRecurrence(recurrence)
+	}
+
+	room, ok := rooms.match(vevent)
+	if !ok {
+		item.Status, item.Message = ImportUnmatchedRoom, "No room matches the event's resources or location."
+	}
+	event.LocationID = room.Id.Hex()
+	event.Location = room.Name
+	item.Event = &event
+
+	return item
+}
+
+// importOverlaps reports whether a and b are booked at the same time.
+func importOverlaps(a Event, b Event) bool {
+	end := func(e Event) time.Time {
+		if e.Recurrence != nil {
+			return e.SeriesEndTime
+		}
+		return e.EndTime
+	}
+	for _, occurrence := range a.Occurrences(b.StartTime, end(b)) {
+		for _, other := range b.Occurrences(occurrence.StartTime, occurrence.EndTime) {
+			if !occurrence.Replaces(other) && !other.Replaces(occurrence) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// planImport reads cal into the items of an import by user.
+func (c *appContext) planImport(ctx context.Context, cal *icalComponent, user User) ([]ImportItem, error) {
+	roomRepo := c.rooms()
+	all, _, err := roomRepo.All(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rooms := newImportRooms(all)
+	zones := icalZones(cal)
+
+	items := []ImportItem{}
+	for _, vevent := range cal.Components {
+		if vevent.Name == "VEVENT" {
+			items = append(items, importEvent(vevent, zones, rooms, user))
+		}
+	}
+
+	// Changed occurrences take the place of one of their series'.
+	series := map[string]*ImportItem{}
+	for idx := range items {
+		item := &items[idx]
+		if item.Event != nil && item.RecurrenceId.IsZero() && item.Event.Recurrence != nil {
+			series[item.Uid] = item
+		}
+	}
+	for idx := range items {
+		item := &items[idx]
+		master, ok := series[item.Uid]
+		if item.Event == nil || item.RecurrenceId.IsZero() || !ok {
+			continue
+		}
+		master.Event.Recurrence.Exceptions = append(master.Event.Recurrence.Exceptions, item.RecurrenceId)
+		master.Event.SetRecurrence(master.Event.Recurrence)
+		item.Event.RecurringEventId = master.Event.Id
+		item.Event.OriginalStartTime = item.RecurrenceId
+	}
+
+	repo := c.events()
+	for idx := range items {
+		item := &items[idx]
+		if item.Status != ImportReady {
+			continue
+		}
+		_, err := repo.FindByExternalId(ctx, importSource, item.Event.ExternalId)
+		if err == nil {
+			item.Status, item.Message = ImportDuplicate, "The event was imported before."
+			continue
+		}
+		if err != ErrDocumentNotFound {
+			return nil, err
+		}
+
+		err = repo.CheckConflict(ctx, item.Event)
+		if conflict, ok := err.(*EventConflict); ok {
+			item.Status, item.ConflictWith = ImportConflict, conflict.Event.Id.Hex()
+			item.Message = conflictError(conflict.Event).Detail
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range items[:idx] {
+			if other.Status == ImportReady && other.Event.LocationID == item.Event.LocationID && importOverlaps(*item.Event, *other.Event) {
+				item.Status = ImportConflict
+				item.Message = "The room is already booked by \"" + other.Summary + "\" (" + other.Uid + ") earlier in the file."
+				break
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// Repo EventImport
+type EventImportRepo struct {
+	coll *storage.Collection
+}
+
+func (r *EventImportRepo) Find(id string) (EventImport, error) {
+	result := EventImport{}
+	oid, err := objectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.coll.FindId(oid).One(&result)
+	if err != nil {
+		return result, repoError(err)
+	}
+
+	return result, nil
+}
+
+func (r *EventImportRepo) Create(imp *EventImport) error {
+	imp.Id = storage.NewObjectId()
+	err := r.coll.Insert(imp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Confirm saves imp, confirmed, unless it was confirmed meanwhile.
+func (r *EventImportRepo) Confirm(imp *EventImport) error {
+	err := r.coll.Update(bson.M{"_id": imp.Id, "status": ImportPending}, imp)
+	if err != nil {
+		return repoError(err)
+	}
+
+	return nil
+}
+
+// Import Handlers
+func (c *appContext) importEventsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		WriteError(w, ErrInvalidUpload)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		WriteError(w, ErrInvalidUpload)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxImportSize {
+		WriteError(w, ErrImportTooLarge)
+		return
+	}
+
+	cal, err := parseICal(file)
+	if err != nil {
+		WriteError(w, ErrInvalidCalendar)
+		return
+	}
+
+	user := r.Context().Value(userKey).(User)
+	items, err := c.planImport(r.Context(), cal, user)
+	if err != nil {
+		panic(err)
+	}
+	if len(items) == 0 {
+		WriteError(w, ErrInvalidCalendar)
+		return
+	}
+	if len(items) > maxImportEvents {
+		WriteError(w, ErrImportTooLarge)
+		return
+	}
+
+	imp := EventImport{
+		Filename:  header.Filename,
+		Status:    ImportPending,
+		CreatedBy: user.Email,
+		CreatedAt: time.Now(),
+		Items:     items,
+	}
+	imp.count()
+	repo := EventImportRepo{c.db.C("event_imports")}
+	if err := repo.Create(&imp); err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, imp)
+}
+
+func (c *appContext) eventImportHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := EventImportRepo{c.db.C("event_imports")}
+	imp, err := repo.Find(params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && imp.CreatedBy != user.Email {
+		WriteError(w, ErrNotImportOwner)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, imp)
+}
+
+func (c *appContext) confirmEventImportHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := EventImportRepo{c.db.C("event_imports")}
+	imp, err := repo.Find(params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && imp.CreatedBy != user.Email {
+		WriteError(w, ErrNotImportOwner)
+		return
+	}
+	if imp.Status != ImportPending {
+		WriteError(w, ErrImportConfirmed)
+		return
+	}
+
+	// Series are booked before their changed occurrences, which are
+	// checked against them.
+	eventRepo := c.events()
+	for pass := 0; pass < 2; pass++ {
+		for idx := range imp.Items {
+			item := &imp.Items[idx]
+			if item.Status != ImportReady || (pass == 0) != item.RecurrenceId.IsZero() {
+				continue
+			}
+			if item.Event.RecurringEventId != "" {
+				if _, err := eventRepo.Find(r.Context(), item.Event.RecurringEventId.Hex()); err == ErrDocumentNotFound {
+					item.Event.RecurringEventId = ""
+				}
+			}
+
+			err := eventRepo.Create(r.Context(), item.Event)
+			if conflict, ok := err.(*EventConflict); ok {
+				item.Status, item.ConflictWith = ImportFailed, conflict.Event.Id.Hex()
+				item.Message = conflictError(conflict.Event).Detail
+				continue
+			}
+			if err != nil {
+				panic(err)
+			}
+			item.Status = ImportImported
+			c.recordChange("event", item.Event.Id, ChangeCreated)
+		}
+	}
+
+	imp.Status = ImportConfirmed
+	imp.ConfirmedAt = time.Now()
+	imp.count()
+	err = repo.Confirm(&imp)
+	if err == ErrDocumentNotFound {
+		WriteError(w, ErrImportConfirmed)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, imp)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Idempotency keys
+//
+// The create endpoints, such as POST /events, accept an Idempotency-Key
+// header so a client can retry a request that timed out without booking
+// twice. The first request with a key runs as usual and its response is
+// kept with the key for idempotencyKeyTTL; retries with the same key get
+// that response again, with "Idempotent-Replayed: true", instead of creating
+// a second document. Keys belong to the caller, within their organization.
+//
+// A retry arriving while the first request still runs answers 409, and a
+// key reused for another request, a different method, path, query or body,
+// answers 422. Responses of 500 and above aren't kept, so the request can
+// be retried with the same key. Dry runs don't take a key.
+const (
+	idempotencyKeyTTL    = 24 * time.Hour
+	idempotencyKeyMaxLen = 255
+)
+
+var (
+	ErrInvalidIdempotencyKey    = &Error{"invalid_idempotency_key", 400, "Bad request", "Idempotency-Key must be at most 255 characters."}
+	ErrIdempotencyKeyInProgress = &Error{"idempotency_key_in_progress", 409, "Conflict", "A request with this Idempotency-Key is still being processed, retry later."}
+	ErrIdempotencyKeyReused     = &Error{"idempotency_key_reused", 422, "Unprocessable Entity", "Idempotency-Key was already used for another request."}
+)
+
+// idempotentHeaders are the response headers kept with a key.
+var idempotentHeaders = []string{"Content-Type", "Location"}
+
+type IdempotencyKey struct {
+	Id        storage.ObjectId  `bson:"_id,omitempty"`
+	Key       string            `bson:"key"`
+	User      string            `bson:"user"`
+	Request   string            `bson:"request"`
+	Status    int               `bson:"status"`
+	Header    map[string]string `bson:"header,omitempty"`
+	Body      []byte            `bson:"body,omitempty"`
+	CreatedAt time.Time         `bson:"createdat"`
+}
+
+// Done reports whether the response of the request of k is kept.
+func (k IdempotencyKey) Done() bool {
+	return k.Status != 0
+}
+
+// replay writes the response kept with k.
+func (k IdempotencyKey) replay(w http.ResponseWriter) {
+	for name, value := range k.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(k.Status)
+	w.Write(k.Body)
+}
+
+// idempotencyRequest hashes what tells the request r with body apart from
+// another one with the same key.
+func idempotencyRequest(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	sum.Write(body)
+
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// idempotencyRecorder keeps a copy of the response it writes.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *idempotencyRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(p)
+
+	return rec.ResponseWriter.Write(p)
+}
+
+// RecordError passes the id of the error answered with on, see
+// httpapi.ErrorRecorder.
+func (rec *idempotencyRecorder) RecordError(id string) {
+	if r, ok := rec.ResponseWriter.(interface{ RecordError(string) }); ok {
+		r.RecordError(id)
+	}
+}
+
+// Repo IdempotencyKey
+type IdempotencyKeyRepo struct {
+	coll *storage.Collection
+}
+
+// Find returns the key of user, failing with storage.ErrNotFound when it's
+// expired, even if it's still to be removed.
+func (r *IdempotencyKeyRepo) Find(user string, key string) (IdempotencyKey, error) {
+	result := IdempotencyKey{}
+	err := r.coll.Find(bson.M{"user": user, "key": key, "createdat": bson.M{"$gt": time.Now().Add(-idempotencyKeyTTL)}}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Start records that the request of k runs, failing with
+// storage.ErrConflict when k is taken.
+func (r *IdempotencyKeyRepo) Start(k *IdempotencyKey) error {
+	// A key expired but not removed yet is free again.
+	_, err := r.coll.RemoveAll(bson.M{"user": k.User, "key": k.Key, "createdat": bson.M{"$lte": time.Now().Add(-idempotencyKeyTTL)}})
+	if err != nil {
+		return err
+	}
+
+	k.Id = storage.NewObjectId()
+	k.CreatedAt = time.Now()
+	if err := r.coll.Insert(k); err != nil {
+		return storage.Error(err)
+	}
+
+	return nil
+}
+
+// Finish keeps the response of the request of k.
+func (r *IdempotencyKeyRepo) Finish(k IdempotencyKey) error {
+	return r.coll.UpdateId(k.Id, bson.M{"$set": bson.M{"status": k.Status, "header": k.Header, "body": k.Body}})
+}
+
+// Abandon frees the key of a request whose response isn't kept.
+func (r *IdempotencyKeyRepo) Abandon(k IdempotencyKey) error {
+	return r.coll.RemoveId(k.Id)
+}
+
+// Middleware
+func idempotencyHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || dryRun(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotencyKeyMaxLen {
+				WriteError(w, ErrInvalidIdempotencyKey)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			user, _ := r.Context().Value(userKey).(User)
+			repo := IdempotencyKeyRepo{c.forRequest(r).db.C("idempotency_keys")}
+			record := IdempotencyKey{Key: key, User: user.Email, Request: idempotencyRequest(r, body)}
+			err = repo.Start(&record)
+			if err == storage.ErrConflict {
+				found, err := repo.Find(record.User, key)
+				if err == storage.ErrNotFound {
+					// Abandoned since, the client can retry.
+					WriteError(w, ErrIdempotencyKeyInProgress)
+					return
+				}
+				if err != nil {
+					panic(err)
+				}
+				switch {
+				case found.Request != record.Request:
+					WriteError(w, ErrIdempotencyKeyReused)
+				case !found.Done():
+					WriteError(w, ErrIdempotencyKeyInProgress)
+				default:
+					found.replay(w)
+				}
+				return
+			}
+			if err != nil {
+				panic(err)
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w}
+			defer func() {
+				if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+					if err := repo.Abandon(record); err != nil {
+						log.Printf("idempotency: freeing key %q: %v", record.Key, err)
+					}
+					return
+				}
+				record.Status = rec.status
+				record.Body = rec.body.Bytes()
+				record.Header = map[string]string{}
+				for _, name := range idempotentHeaders {
+					if value := w.Header().Get(name); value != "" {
+						record.Header[name] = value
+					}
+				}
+				if err := repo.Finish(record); err != nil {
+					panic(err)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
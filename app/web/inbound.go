@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Inbound integrations
+//
+// External booking systems (building management, the legacy booking tool)
+// push their bookings to POST /integrations/inbound/:source. Each source is
+// configured through the environment:
+//
+//	INBOUND_<SOURCE>_SECRET    shared HMAC-SHA256 key, required
+//	INBOUND_<SOURCE>_CONFLICT  "reject" (default) or "accept"
+//
+// Requests are signed with an X-Signature header of the form
+// "sha256=<hex digest of the body>".
+const (
+	ConflictReject = "reject"
+	ConflictAccept = "accept"
+)
+
+type InboundSource struct {
+	Name           string
+	Secret         string
+	ConflictPolicy string
+}
+
+func inboundSource(name string) (InboundSource, bool) {
+	prefix := "INBOUND_" + strings.ToUpper(name) + "_"
+	source := InboundSource{
+		Name:           name,
+		Secret:         setting(prefix + "SECRET"),
+		ConflictPolicy: setting(prefix + "CONFLICT"),
+	}
+	if source.Secret == "" {
+		return source, false
+	}
+	if source.ConflictPolicy != ConflictAccept {
+		source.ConflictPolicy = ConflictReject
+	}
+
+	return source, true
+}
+
+type InboundBooking struct {
+	ExternalId  string    `json:"external_id"`
+	RoomId      string    `json:"room_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	Guests      []string  `json:"guests"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Cancelled   bool      `json:"cancelled"`
+}
+
+// Middleware
+func signatureHandler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		params := routeParams(r)
+		source, ok := inboundSource(params.ByName("source"))
+		if !ok {
+			WriteError(w, ErrNotFound)
+			return
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			WriteError(w, ErrBadRequest)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(source.Secret))
+		mac.Write(b)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Signature"))) {
+			WriteError(w, ErrInvalidSignature)
+			return
+		}
+
+		r = withValue(r, sourceKey, source)
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Inbound Handlers
+func (c *appContext) inboundBookingHandler(w http.ResponseWriter, r *http.Request) {
+	source := r.Context().Value(sourceKey).(InboundSource)
+	body := r.Context().Value(bodyKey).(*InboundBooking)
+	tag := "inbound:" + source.Name
+
+	repo := c.events()
+	existing, err := repo.FindByExternalId(r.Context(), tag, body.ExternalId)
+	if err != nil && err != ErrDocumentNotFound {
+		panic(err)
+	}
+	found := err == nil
+
+	if body.Cancelled {
+		if found {
+			if err := repo.Delete(r.Context(), existing.Id.Hex()); err != nil {
+				panic(err)
+			}
+			c.recordChange("event", existing.Id, ChangeDeleted)
+		}
+
+		data := MessageSuccess{MessageInfo{Message: "Event has been deleted successfully"}}
+		WriteSuccess(w, http.StatusAccepted, data)
+		return
+	}
+
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+
+	if !storage.IsObjectIdHex(body.RoomId) {
+		WriteError(w, ErrUnknownRoom)
+		return
+	}
+
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), body.RoomId)
+	if err == ErrDocumentNotFound {
+		WriteError(w, ErrUnknownRoom)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	event := Event{
+		Id:          existing.Id,
+		Name:        body.Name,
+		LocationID:  room.Id.Hex(),
+		Location:    room.Name,
+		Description: body.Description,
+		Guests:      body.Guests,
+		Owner:       body.Owner,
+		StartTime:   body.StartTime,
+		EndTime:     body.EndTime,
+		Source:      tag,
+		ExternalId:  body.ExternalId,
+		BookedVia:   BookingSource{Channel: ChannelInbound, Integration: source.Name},
+	}
+	setDescription(&event, event.Description)
+
+	// Sources that accept conflicts write past the repo's conflict check.
+	save, action := repo.Create, ChangeCreated
+	if found {
+		save, action = repo.Update, ChangeUpdated
+	}
+	if source.ConflictPolicy == ConflictAccept {
+		save = repo.Insert
+		if found {
+			save = repo.Replace
+		}
+	}
+
+	err = save(r.Context(), &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event", event.Id, action)
+
+	if found {
+		WriteSuccess(w, http.StatusAccepted, event)
+		return
+	}
+
+	WriteSuccess(w, http.StatusCreated, event)
+}
+
+func conflictError(event Event) *Error {
+	return &Error{
+		"booking_conflict",
+		http.StatusConflict,
+		"Conflict",
+		"The room is already booked by \"" + event.Name + "\" (" + event.Id.Hex() + ") from " +
+			event.StartTime.Format(time.RFC3339) + " to " + event.EndTime.Format(time.RFC3339) + ".",
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Legal holds
+//
+// Admins put a user, a date range or both on legal hold. Until the hold is
+// lifted, the events the user owns or is a guest of, the change log entries
+// and the attachments falling within the range must be kept: anything that
+// deletes data for retention or erasure asks heldEvent, heldChange or
+// heldAttachment first. Lifting a hold keeps it with LiftedAt set, and
+// placing and lifting holds are both recorded in the change log.
+type LegalHold struct {
+	Id        storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	User      string           `json:"user,omitempty"`
+	StartTime time.Time        `json:"start_time,omitempty"`
+	EndTime   time.Time        `json:"end_time,omitempty"`
+	Reason    string           `json:"reason"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+	LiftedBy  string           `json:"lifted_by,omitempty"`
+	LiftedAt  time.Time        `json:"lifted_at,omitempty"`
+}
+
+func (h LegalHold) validate() *Error {
+	if h.User == "" && h.StartTime.IsZero() && h.EndTime.IsZero() {
+		return ErrEmptyHold
+	}
+	if !h.StartTime.IsZero() && !h.EndTime.IsZero() && !h.StartTime.Before(h.EndTime) {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+// within reports whether [start_time, end_time) overlaps the range of h.
+// Holds without a range cover all time.
+func (h LegalHold) within(start_time time.Time, end_time time.Time) bool {
+	if !h.StartTime.IsZero() && !end_time.After(h.StartTime) {
+		return false
+	}
+	if !h.EndTime.IsZero() && !start_time.Before(h.EndTime) {
+		return false
+	}
+
+	return true
+}
+
+func (h LegalHold) coversEvent(event Event) bool {
+	if h.User != "" && event.Owner != h.User && !contains(event.Guests, h.User) {
+		return false
+	}
+
+	end_time := event.EndTime
+	if event.Recurrence != nil {
+		end_time = event.SeriesEndTime
+	}
+
+	return h.within(event.StartTime, end_time)
+}
+
+func (h LegalHold) coversTime(t time.Time) bool {
+	return h.within(t, t.Add(time.Nanosecond))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Repo LegalHold
+type LegalHoldRepo struct {
+	coll *storage.Collection
+}
+
+func (r *LegalHoldRepo) All() ([]LegalHold, error) {
+	result := []LegalHold{}
+	err := r.coll.Find(nil).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *LegalHoldRepo) Active() ([]LegalHold, error) {
+	result := []LegalHold{}
+	err := r.coll.Find(bson.M{"liftedat": time.Time{}}).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *LegalHoldRepo) Find(id string) (LegalHold, error) {
+	result := LegalHold{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *LegalHoldRepo) Create(hold *LegalHold) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, hold)
+	if err != nil {
+		return err
+	}
+
+	hold.Id = id
+
+	return nil
+}
+
+func (r *LegalHoldRepo) Lift(id storage.ObjectId, by string, t time.Time) error {
+	err := r.coll.UpdateId(id, bson.M{"$set": bson.M{"liftedby": by, "liftedat": t}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *appContext) activeHolds() []LegalHold {
+	repo := LegalHoldRepo{c.db.C("legal_holds")}
+	holds, err := repo.Active()
+	if err != nil {
+		panic(err)
+	}
+
+	return holds
+}
+
+// heldEvent reports whether event is under a legal hold.
+func (c *appContext) heldEvent(event Event) bool {
+	for _, hold := range c.activeHolds() {
+		if hold.coversEvent(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// heldChange reports whether a change log entry is under a legal hold. Holds
+// on a user cover the entries of their events.
+func (c *appContext) heldChange(ctx context.Context, change Change) bool {
+	for _, hold := range c.activeHolds() {
+		if !hold.coversTime(change.Time) {
+			continue
+		}
+		if hold.User == "" {
+			return true
+		}
+		if change.Entity == "event" && storage.IsObjectIdHex(change.EntityId) {
+			repo := c.events()
+			event, err := repo.Find(ctx, change.EntityId)
+			if err == nil && hold.coversEvent(event) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// heldAttachment reports whether an attachment is under a legal hold, either
+// by itself or through the event it's attached to.
+func (c *appContext) heldAttachment(ctx context.Context, attachment Attachment) bool {
+	for _, hold := range c.activeHolds() {
+		if hold.User == "" && hold.coversTime(attachment.CreatedAt) {
+			return true
+		}
+		if hold.User != "" && attachment.Uploader == hold.User && hold.coversTime(attachment.CreatedAt) {
+			return true
+		}
+	}
+	if attachment.OwnerType != "event" {
+		return false
+	}
+
+	repo := c.events()
+	event, err := repo.Find(ctx, attachment.OwnerId)
+	if err != nil {
+		return false
+	}
+
+	return c.heldEvent(event)
+}
+
+// Legal Hold Handlers
+func (c *appContext) legalHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := LegalHoldRepo{c.db.C("legal_holds")}
+	var holds []LegalHold
+	var err error
+	if r.URL.Query().Get("all") == "true" {
+		holds, err = repo.All()
+	} else {
+		holds, err = repo.Active()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, holds)
+}
+
+func (c *appContext) createLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*LegalHold)
+	if e := body.validate(); e != nil {
+		WriteError(w, e)
+		return
+	}
+	body.CreatedBy = r.Context().Value(userKey).(User).Email
+	body.CreatedAt = time.Now()
+	body.LiftedBy = ""
+	body.LiftedAt = time.Time{}
+
+	repo := LegalHoldRepo{c.db.C("legal_holds")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("legal_hold", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) liftLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := LegalHoldRepo{c.db.C("legal_holds")}
+	hold, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	if !hold.LiftedAt.IsZero() {
+		WriteError(w, ErrHoldLifted)
+		return
+	}
+
+	err = repo.Lift(hold.Id, r.Context().Value(userKey).(User).Email, time.Now())
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("legal_hold", hold.Id, ChangeUpdated)
+
+	data := MessageSuccess{MessageInfo{Message: "Legal hold has been lifted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Limits
+//
+// Each caller, identified by X-User-Email or else by clientKey, gets a
+// budget of requests per minute. The limit is soft: requests over it are
+// still served, but every response carries the state of the caller's window
+// so well-behaved clients can slow down before a hard limit is introduced:
+//
+//	RATE_LIMIT  requests per minute per caller (default 600)
+//
+// GET /me/limits returns the same window together with the caller's booking
+// quota, the weekly cap of their team.
+const (
+	rateWindow       = time.Minute
+	defaultRateLimit = 600
+)
+
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+type BookingQuota struct {
+	Team            string  `json:"team,omitempty"`
+	BookedHours     float64 `json:"booked_hours"`
+	TeamBookedHours float64 `json:"team_booked_hours"`
+	CapHours        float64 `json:"cap_hours,omitempty"`
+	Mode            string  `json:"mode,omitempty"`
+}
+
+type Limits struct {
+	User      string       `json:"user"`
+	RateLimit RateLimit    `json:"rate_limit"`
+	Bookings  BookingQuota `json:"bookings"`
+}
+
+type rateWindowCount struct {
+	start time.Time
+	count int
+}
+
+type rateLimiter struct {
+	sync.Mutex
+	limit   int
+	windows map[string]*rateWindowCount
+}
+
+var rateLimits = &rateLimiter{limit: defaultRateLimit, windows: map[string]*rateWindowCount{}}
+
+func rateKey(r *http.Request) string {
+	if email := r.Header.Get("X-User-Email"); email != "" {
+		return email
+	}
+
+	return clientKey(r)
+}
+
+// Hit counts a request for key, or only reports the window when count is
+// false.
+func (l *rateLimiter) Hit(key string, count bool) RateLimit {
+	l.Lock()
+	defer l.Unlock()
+
+	t := clockNow()
+	window := l.windows[key]
+	if window == nil || t.Sub(window.start) >= rateWindow {
+		window = &rateWindowCount{start: t.Truncate(rateWindow)}
+		l.windows[key] = window
+
+		// Forget callers whose window has passed, so the map stays small.
+		for k, w := range l.windows {
+			if t.Sub(w.start) >= rateWindow {
+				delete(l.windows, k)
+			}
+		}
+	}
+	if count {
+		window.count++
+	}
+
+	limit := l.limit
+	remaining := limit - window.count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return RateLimit{limit, remaining, window.start.Add(rateWindow)}
+}
+
+func writeRateLimit(w http.ResponseWriter, limit RateLimit) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(limit.Reset.Unix(), 10))
+}
+
+// Limits Handlers
+func (c *appContext) myLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userKey).(User)
+	limits := Limits{User: user.Email, RateLimit: rateLimits.Hit(rateKey(r), false)}
+
+	locale, t := orgLocale(), clockNow()
+	repo := c.events()
+	events, err := repo.AllByOwners(r.Context(), []string{user.Email}, locale.BeginningOfWeek(t), locale.EndOfWeek(t))
+	if err != nil {
+		panic(err)
+	}
+	limits.Bookings.BookedHours = bookedHours(events, "")
+
+	if user.Team != "" {
+		limits.Bookings.Team = user.Team
+
+		userRepo := UserRepo{c.db.C("users")}
+		members, err := userRepo.AllByTeam(user.Team)
+		if err != nil {
+			panic(err)
+		}
+		owners := []string{}
+		for _, member := range members {
+			owners = append(owners, member.Email)
+		}
+		teamEvents, err := repo.AllByOwners(r.Context(), owners, locale.BeginningOfWeek(t), locale.EndOfWeek(t))
+		if err != nil {
+			panic(err)
+		}
+		limits.Bookings.TeamBookedHours = bookedHours(teamEvents, "")
+
+		capRepo := TeamCapRepo{c.db.C("team_caps")}
+		teamCap, err := capRepo.Find(user.Team)
+		if err != nil && err != storage.ErrNotFound {
+			panic(err)
+		}
+		limits.Bookings.CapHours = teamCap.WeeklyHours
+		limits.Bookings.Mode = teamCap.Mode
+	}
+
+	WriteSuccess(w, http.StatusOK, limits)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Live updates
+//
+// Dashboards can follow the schedule over a WebSocket instead of polling
+// /events. GET /ws, for signed-in callers, upgrades the connection and pushes
+// a message for each venue, room and event change of the caller's
+// organization recorded by this process; changes made through
+// other instances reach their own clients only, so dashboards behind a load
+// balancer should fall back to GET /changes. ?rooms=<id>,<id> subscribes to
+// the events in those rooms and the rooms themselves, and the client can
+// replace its subscription at any time by sending {"rooms": [...]}, where []
+// means everything. Venue changes, and deleted events whose room isn't known
+// any more, go to every client. A "ping" message is sent every
+// liveHeartbeat so idle connections aren't dropped by proxies; clients that
+// can't keep up are disconnected.
+const (
+	LiveChange = "change"
+	LivePing   = "ping"
+
+	liveBufferSize   = 64
+	liveHeartbeat    = 30 * time.Second
+	liveWriteTimeout = 10 * time.Second
+)
+
+type LiveUpdate struct {
+	Type     string    `json:"type"`
+	Seq      int64     `json:"seq,omitempty"`
+	Entity   string    `json:"entity,omitempty"`
+	EntityId string    `json:"entity_id,omitempty"`
+	Action   string    `json:"action,omitempty"`
+	RoomId   string    `json:"room_id,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+type LiveSubscription struct {
+	Rooms []string `json:"rooms"`
+}
+
+type liveClient struct {
+	send chan LiveUpdate
+	org  string
+
+	mu    sync.Mutex
+	rooms map[string]bool
+}
+
+func (c *liveClient) subscribe(rooms []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rooms = map[string]bool{}
+	for _, id := range rooms {
+		if id = strings.TrimSpace(id); id != "" {
+			c.rooms[id] = true
+		}
+	}
+}
+
+func (c *liveClient) wants(roomId string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.rooms) == 0 || roomId == "" || c.rooms[roomId]
+}
+
+// liveHub fans changes out to the WebSocket clients of this process.
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[*liveClient]bool
+}
+
+var live = &liveHub{clients: map[*liveClient]bool{}}
+
+func (h *liveHub) add(c *liveClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = true
+}
+
+// remove drops c, closing its channel, unless it's gone already.
+func (h *liveHub) remove(c *liveClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// Broadcast pushes change to the clients subscribed to it.
+func (h *liveHub) Broadcast(change Change) {
+	update := LiveUpdate{
+		Type:     LiveChange,
+		Seq:      change.Seq,
+		Entity:   change.Entity,
+		EntityId: change.EntityId,
+		Action:   change.Action,
+		Time:     change.Time,
+	}
+	switch change.Entity {
+	case "event":
+		update.RoomId, _ = change.Document["locationid"].(string)
+	case "room":
+		update.RoomId = change.EntityId
+	case "venue":
+	default:
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c.org != change.OrgId || !c.wants(update.RoomId) {
+			continue
+		}
+		select {
+		case c.send <- update:
+		default:
+			delete(h.clients, c)
+			close(c.send)
+		}
+	}
+}
+
+// serveLive writes the updates of c to ws until either side goes away,
+// reading subscription changes meanwhile.
+func serveLive(ws *websocket.Conn, c *liveClient) {
+	// Clear the deadlines the server set for the upgrade request.
+	ws.SetDeadline(time.Time{})
+
+	live.add(c)
+	defer live.remove(c)
+
+	go func() {
+		defer ws.Close()
+		heartbeat := time.NewTicker(liveHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			update, ok := LiveUpdate{}, true
+			select {
+			case update, ok = <-c.send:
+				if !ok {
+					return
+				}
+			case t := <-heartbeat.C:
+				update = LiveUpdate{Type: LivePing, Time: t}
+			}
+			ws.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := websocket.JSON.Send(ws, update); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		sub := LiveSubscription{}
+		if err := websocket.JSON.Receive(ws, &sub); err != nil {
+			return
+		}
+		c.subscribe(sub.Rooms)
+	}
+}
+
+// Live Handlers
+//
+// liveHandler holds no database session, so it's served outside
+// commonHandlers, which would keep one for as long as the socket is open.
+func liveHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userKey).(User)
+	c := &liveClient{send: make(chan LiveUpdate, liveBufferSize), org: user.OrgId}
+	if rooms := r.URL.Query().Get("rooms"); rooms != "" {
+		c.subscribe(strings.Split(rooms, ","))
+	}
+
+	server := websocket.Server{Handler: func(ws *websocket.Conn) { serveLive(ws, c) }}
+	server.ServeHTTP(w, r)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale
+//
+// Weeks start on the org's week start day, and the first week of a year is
+// the first one with at least FirstWeekMinDays of its days in that year:
+//
+//	WEEK_START           e.g. "monday", default sunday
+//	FIRST_WEEK_MIN_DAYS  1 to 7, default 1; "monday" with 4 gives ISO 8601
+//	                     weeks
+//
+// The defaults match the week the API used before. Default
+// windows ("this week"), weekly team caps and new weekly recurrences follow
+// the locale, and a request can override it with ?week_start= and
+// ?first_week_min_days=. Recurrences keep the week start they were created
+// with, so changing it doesn't move existing occurrences. GET /locale shows
+// the locale in effect and the current week.
+type Locale struct {
+	WeekStart        time.Weekday
+	FirstWeekMinDays int
+}
+
+type LocaleResponse struct {
+	WeekStart        string    `json:"week_start"`
+	FirstWeekMinDays int       `json:"first_week_min_days"`
+	Year             int       `json:"year"`
+	Week             int       `json:"week"`
+	StartTime        time.Time `json:"start_time"`
+	EndTime          time.Time `json:"end_time"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(s))]
+	return day, ok
+}
+
+func weekdayName(day time.Weekday) string {
+	return strings.ToLower(day.String())
+}
+
+// orgLocale returns the locale configured for the org.
+func orgLocale() Locale {
+	locale := Locale{time.Sunday, 1}
+	if day, ok := parseWeekday(setting("WEEK_START")); ok {
+		locale.WeekStart = day
+	}
+	if n, err := strconv.Atoi(setting("FIRST_WEEK_MIN_DAYS")); err == nil && n >= 1 && n <= 7 {
+		locale.FirstWeekMinDays = n
+	}
+
+	return locale
+}
+
+// requestLocale returns the org locale with the overrides of r applied.
+// Invalid overrides are ignored, like invalid window bounds are.
+func requestLocale(r *http.Request) Locale {
+	locale := orgLocale()
+	query := r.URL.Query()
+	if day, ok := parseWeekday(query.Get("week_start")); ok {
+		locale.WeekStart = day
+	}
+	if n, err := strconv.Atoi(query.Get("first_week_min_days")); err == nil && n >= 1 && n <= 7 {
+		locale.FirstWeekMinDays = n
+	}
+
+	return locale
+}
+
+// offset returns how many days into the week day is.
+func (l Locale) offset(day time.Weekday) int {
+	return (int(day) - int(l.WeekStart) + 7) % 7
+}
+
+// BeginningOfWeek returns the start of the week t is in, in t's location.
+func (l Locale) BeginningOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -l.offset(day.Weekday()))
+}
+
+// EndOfWeek returns the last instant of the week t is in.
+func (l Locale) EndOfWeek(t time.Time) time.Time {
+	return l.BeginningOfWeek(t).AddDate(0, 0, 7).Add(-time.Nanosecond)
+}
+
+// Week returns the year the week of t belongs to and its number in it.
+func (l Locale) Week(t time.Time) (int, int) {
+	start := l.BeginningOfWeek(t)
+	year := start.AddDate(0, 0, 7-l.FirstWeekMinDays).Year()
+
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	first := l.BeginningOfWeek(jan1)
+	if 7-l.offset(jan1.Weekday()) < l.FirstWeekMinDays {
+		first = first.AddDate(0, 0, 7)
+	}
+
+	// Count days on calendar dates so DST changes don't shift the count.
+	days := int(civilDate(start).Sub(civilDate(first)).Hours() / 24)
+
+	return year, days/7 + 1
+}
+
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Locale Handlers
+func (c *appContext) localeHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	t := clockNow()
+	year, week := locale.Week(t)
+
+	WriteSuccess(w, http.StatusOK, LocaleResponse{
+		WeekStart:        weekdayName(locale.WeekStart),
+		FirstWeekMinDays: locale.FirstWeekMinDays,
+		Year:             year,
+		Week:             week,
+		StartTime:        locale.BeginningOfWeek(t),
+		EndTime:          locale.EndOfWeek(t),
+	})
+}
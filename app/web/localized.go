@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Localized names
+//
+// Venues and rooms can carry their name and description in other languages,
+// keyed by language tag, next to the default ones:
+//
+//	{"name": "Board Room", "names": {"ja": "会議室", "id": "Ruang Rapat"}}
+//
+// Responses answer with the name and description in the language the client
+// prefers most, from ?lang= or else Accept-Language, and keep the maps so
+// editors see every translation. A tag matches a key of the same tag, then
+// the key of its language alone (ja-JP reads ja), then any key of the same
+// language (ja reads ja-JP); when none does, the default name is kept.
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+type localizedLanguage struct {
+	tag string
+	q   float64
+}
+
+// requestLanguages returns the languages r prefers, most preferred first.
+func requestLanguages(r *http.Request) []string {
+	if lang := strings.TrimSpace(r.URL.Query().Get("lang")); lang != "" {
+		return []string{strings.ToLower(lang)}
+	}
+
+	langs := []localizedLanguage{}
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, localizedLanguage{tag, q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	result := []string{}
+	for _, lang := range langs {
+		result = append(result, lang.tag)
+	}
+
+	return result
+}
+
+// languageBase is the language of tag, without its region or script.
+func languageBase(tag string) string {
+	if i := strings.Index(tag, "-"); i >= 0 {
+		return tag[:i]
+	}
+
+	return tag
+}
+
+// localized picks the translation in values of the first of langs it has
+// one for, falling back to fallback.
+func localized(values map[string]string, langs []string, fallback string) string {
+	if len(values) == 0 {
+		return fallback
+	}
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, lang := range langs {
+		for _, key := range keys {
+			if strings.EqualFold(key, lang) {
+				return values[key]
+			}
+		}
+		for _, key := range keys {
+			if strings.EqualFold(key, languageBase(lang)) {
+				return values[key]
+			}
+		}
+		for _, key := range keys {
+			if strings.EqualFold(languageBase(key), languageBase(lang)) {
+				return values[key]
+			}
+		}
+	}
+
+	return fallback
+}
+
+// localizing returns the languages to answer r in, noting on w that the
+// response depends on them.
+func localizing(w http.ResponseWriter, r *http.Request) []string {
+	w.Header().Add("Vary", "Accept-Language")
+
+	return requestLanguages(r)
+}
+
+func localizeVenue(v *Venue, langs []string) {
+	v.Name = localized(v.Names, langs, v.Name)
+	v.Description = localized(v.Descriptions, langs, v.Description)
+	for idx := range v.Rooms {
+		localizeRoom(&v.Rooms[idx], langs)
+	}
+	if v.NextAvailableRoom != nil {
+		v.NextAvailableRoom.Name = localized(v.NextAvailableRoom.Names, langs, v.NextAvailableRoom.Name)
+	}
+}
+
+func localizeRoom(r *Room, langs []string) {
+	r.Name = localized(r.Names, langs, r.Name)
+	r.Description = localized(r.Descriptions, langs, r.Description)
+}
+
+func localizeVenues(venues []Venue, langs []string) {
+	for idx := range venues {
+		localizeVenue(&venues[idx], langs)
+	}
+}
+
+func localizeRooms(rooms []Room, langs []string) {
+	for idx := range rooms {
+		localizeRoom(&rooms[idx], langs)
+	}
+}
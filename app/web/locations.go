@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Location names
+//
+// Events keep the name of their room in Location, as it was when they were
+// booked. Renaming a room starts a backfill job bringing the Location of the
+// room's future events, and of recurring events still running, up to date;
+// events that have started keep the name they were booked under. Renaming a
+// venue runs one over its rooms. Jobs are kept in location_backfills:
+//
+//	GET  /admin/locations/backfills      the latest jobs
+//	POST /admin/locations/backfills      run a job over every room
+//	GET  /admin/locations/backfills/:id  a job, to follow it
+//	GET  /admin/locations/report         events whose Location isn't their
+//	                                     room's name, stale (future) or
+//	                                     historical (frozen)
+const (
+	BackfillRunning = "running"
+	BackfillDone    = "done"
+	BackfillFailed  = "failed"
+
+	BackfillRoomRenamed  = "room_renamed"
+	BackfillVenueRenamed = "venue_renamed"
+	BackfillManual       = "manual"
+
+	locationBackfillsPageSize = 50
+	locationReportLimit       = 500
+)
+
+type LocationBackfill struct {
+	Id         storage.ObjectId `json:"id" bson:"_id,omitempty"`
+	Reason     string           `json:"reason"`
+	RoomIds    []string         `json:"room_ids,omitempty"`
+	Status     string           `json:"status"`
+	Updated    int              `json:"updated"`
+	Error      string           `json:"error,omitempty"`
+	StartedBy  string           `json:"started_by,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at,omitempty"`
+}
+
+type LocationMismatch struct {
+	EventId    storage.ObjectId `json:"event_id"`
+	RoomId     string           `json:"room_id"`
+	Location   string           `json:"location"`
+	RoomName   string           `json:"room_name"`
+	StartTime  time.Time        `json:"start_time"`
+	Historical bool             `json:"historical"`
+}
+
+type LocationReport struct {
+	Stale      int                `json:"stale"`
+	Historical int                `json:"historical"`
+	Mismatches []LocationMismatch `json:"mismatches"`
+}
+
+// Repo LocationBackfill
+type LocationBackfillRepo struct {
+	coll *storage.Collection
+}
+
+func (r *LocationBackfillRepo) All() ([]LocationBackfill, error) {
+	result := []LocationBackfill{}
+	err := r.coll.Find(nil).Sort("-startedat").Limit(locationBackfillsPageSize).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *LocationBackfillRepo) Find(id string) (LocationBackfill, error) {
+	result := LocationBackfill{}
+	oid, err := objectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.coll.FindId(oid).One(&result)
+	if err != nil {
+		return result, repoError(err)
+	}
+
+	return result, nil
+}
+
+func (r *LocationBackfillRepo) Create(job *LocationBackfill) error {
+	job.Id = storage.NewObjectId()
+	err := r.coll.Insert(job)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *LocationBackfillRepo) Update(job *LocationBackfill) error {
+	err := r.coll.UpdateId(job.Id, job)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// startLocationBackfill records a job over roomIds, every room when empty,
+// and runs it on a session of its own.
+func (c *appContext) startLocationBackfill(reason string, startedBy string, roomIds []string) (LocationBackfill, error) {
+	job := LocationBackfill{
+		Reason:    reason,
+		RoomIds:   roomIds,
+		Status:    BackfillRunning,
+		StartedBy: startedBy,
+		StartedAt: time.Now(),
+	}
+	repo := LocationBackfillRepo{c.db.C("location_backfills")}
+	err := repo.Create(&job)
+	if err != nil {
+		return job, err
+	}
+
+	bg := c.detach()
+	go func() {
+		defer bg.close()
+		bg.runLocationBackfill(context.Background(), job)
+	}()
+
+	return job, nil
+}
+
+func (c *appContext) runLocationBackfill(ctx context.Context, job LocationBackfill) {
+	err := c.backfillLocations(ctx, &job)
+	job.Status = BackfillDone
+	if err != nil {
+		job.Status = BackfillFailed
+		job.Error = err.Error()
+	}
+	job.FinishedAt = time.Now()
+
+	repo := LocationBackfillRepo{c.db.C("location_backfills")}
+	if err := repo.Update(&job); err != nil {
+		log.Println("locations: saving backfill failed:", err)
+	}
+}
+
+func (c *appContext) backfillLocations(ctx context.Context, job *LocationBackfill) error {
+	roomRepo := c.rooms()
+	rooms := []Room{}
+	if len(job.RoomIds) == 0 {
+		all, _, err := roomRepo.All(ctx, ListOptions{})
+		if err != nil {
+			return err
+		}
+		rooms = all
+	}
+	for _, id := range job.RoomIds {
+		room, err := roomRepo.Find(ctx, id)
+		if err == ErrDocumentNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rooms = append(rooms, room)
+	}
+
+	repo := c.events()
+	for _, room := range rooms {
+		ids, err := repo.StaleLocations(ctx, room.Id.Hex(), room.Name, job.StartedAt)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		err = repo.SetLocation(ctx, ids, room.Name)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			c.recordChange("event", id, ChangeUpdated)
+		}
+		job.Updated += len(ids)
+	}
+
+	return nil
+}
+
+// Location Handlers
+func (c *appContext) locationBackfillsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := LocationBackfillRepo{c.db.C("location_backfills")}
+	jobs, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, jobs)
+}
+
+func (c *appContext) locationBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := LocationBackfillRepo{c.db.C("location_backfills")}
+	job, err := repo.Find(params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, job)
+}
+
+func (c *appContext) createLocationBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userKey).(User)
+	job, err := c.startLocationBackfill(BackfillManual, user.Email, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, job)
+}
+
+func (c *appContext) locationReportHandler(w http.ResponseWriter, r *http.Request) {
+	roomRepo := c.rooms()
+	rooms, _, err := roomRepo.All(r.Context(), ListOptions{})
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	t := clockNow()
+	repo := c.events()
+	report := LocationReport{Mismatches: []LocationMismatch{}}
+	for _, room := range rooms {
+		events, err := repo.LocationMismatches(r.Context(), room.Id.Hex(), room.Name)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		for _, event := range events {
+			historical := !event.Upcoming(t)
+			if historical {
+				report.Historical++
+			} else {
+				report.Stale++
+			}
+			if len(report.Mismatches) < locationReportLimit {
+				report.Mismatches = append(report.Mismatches, LocationMismatch{
+					event.Id, room.Id.Hex(), event.Location, room.Name, event.StartTime, historical,
+				})
+			}
+		}
+	}
+
+	WriteSuccess(w, http.StatusOK, report)
+}
@@ -1,6 +1,9 @@
 package main
 
+//go:generate go run ../sdkgen -web . -out ../../sdk -routes routes_gen.go
+
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,60 +13,119 @@ import (
 	"reflect"
 	"time"
 
-	"github.com/gorilla/context"
-	"github.com/jinzhu/now"
+	"github.com/ivansaputr4/ivana/internal/httpapi"
+	"github.com/ivansaputr4/ivana/internal/migrations"
+	"github.com/ivansaputr4/ivana/internal/storage"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 	"github.com/subosito/gotenv"
-	mgo "gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
-
+	"go.mongodb.org/mongo-driver/bson"
 	ncontext "golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
 )
 
-// Errors
-type Errors struct {
-	Errors []*Error `json:"errors"`
-}
+// Errors, see internal/httpapi. Error is a type of its own so that the
+// errors below can be declared by position.
+type (
+	Errors = httpapi.Errors
+	Error  httpapi.Error
+)
 
-type Error struct {
-	Id     string `json:"id"`
-	Status int    `json:"status"`
-	Title  string `json:"title"`
-	Detail string `json:"detail"`
+func WriteError(w http.ResponseWriter, err *Error) {
+	httpapi.WriteError(w, (*httpapi.Error)(err))
 }
 
-func WriteError(w http.ResponseWriter, err *Error) {
-	w.Header().Set("Content-Type", "application/vnd.api+json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(err.Status)
-	json.NewEncoder(w).Encode(Errors{[]*Error{err}})
+func WriteErrors(w http.ResponseWriter, httpStatus int, errs []*Error) {
+	apiErrs := []*httpapi.Error{}
+	for _, err := range errs {
+		apiErrs = append(apiErrs, (*httpapi.Error)(err))
+	}
+	httpapi.WriteErrors(w, httpStatus, apiErrs)
 }
 
 var (
 	ErrBadRequest           = &Error{"bad_request", 400, "Bad request", "Request body is not well-formed. It must be JSON."}
+	ErrNotFound             = &Error{"not_found", 404, "Not Found", "The requested resource could not be found."}
+	ErrSlugTaken            = &Error{"slug_taken", 409, "Conflict", "The slug is already in use."}
 	ErrNotAcceptable        = &Error{"not_acceptable", 406, "Not Acceptable", "Accept header must be set to 'application/vnd.api+json'."}
 	ErrUnsupportedMediaType = &Error{"unsupported_media_type", 415, "Unsupported Media Type", "Content-Type header must be set to: 'application/vnd.api+json'."}
+	ErrLocationMismatch     = &Error{"location_mismatch", 422, "Unprocessable Entity", "location_id in the body must match the room in the path."}
+	ErrInvalidSignature     = &Error{"invalid_signature", 401, "Unauthorized", "X-Signature header does not match the request body."}
+	ErrInvalidTime          = &Error{"invalid_time", 400, "Bad request", "start_time and end_time must be RFC 3339 times such as 2018-06-01T09:00:00+07:00."}
+	ErrInvalidTimeRange     = &Error{"invalid_time_range", 422, "Unprocessable Entity", "start_time must be before end_time."}
+	ErrInvalidWindow        = &Error{"invalid_time_range", 400, "Bad request", "start_time must be before end_time."}
+	ErrWindowTooLong        = &Error{"window_too_long", 400, "Bad request", "start_time and end_time must be at most 366 days apart."}
+	ErrUnknownRoom          = &Error{"unknown_room", 422, "Unprocessable Entity", "room_id does not refer to an existing room."}
+	ErrInvalidSince         = &Error{"invalid_since", 400, "Bad request", "since must be a non-negative sequence number."}
+	ErrInvalidWait          = &Error{"invalid_wait", 400, "Bad request", "wait must be a duration such as 30s."}
+	ErrInvalidLimit         = &Error{"invalid_limit", 400, "Bad request", "limit must be a positive integer."}
+	ErrInvalidUpload        = &Error{"invalid_upload", 400, "Bad request", "Request must be multipart/form-data with a \"file\" field."}
+	ErrUploadTooLarge       = &Error{"upload_too_large", 413, "Payload Too Large", "Uploaded files must be at most 20 MB."}
+	ErrAttachmentPending    = &Error{"attachment_pending", 409, "Conflict", "The file is still being scanned, try again shortly."}
+	ErrFileQuarantined      = &Error{"attachment_quarantined", 403, "Forbidden", "The file failed the virus scan and has been quarantined."}
+	ErrUploadsDisabled      = &Error{"uploads_disabled", 503, "Service Unavailable", "File uploads are disabled until a virus scanner is configured."}
+	ErrUnknownEquipment     = &Error{"unknown_equipment", 422, "Unprocessable Entity", "equipment_id does not refer to existing equipment."}
+	ErrInvalidDate          = &Error{"invalid_date", 400, "Bad request", "date must be formatted as YYYY-MM-DD."}
+	ErrInvalidSlot          = &Error{"invalid_slot", 400, "Bad request", "slot must be one of am, pm or full."}
+	ErrMissingUser          = &Error{"missing_user", 400, "Bad request", "user query parameter is required."}
+	ErrDeskTaken            = &Error{"desk_taken", 409, "Conflict", "The desk is already booked for that slot."}
+	ErrDoubleDeskBooking    = &Error{"double_desk_booking", 409, "Conflict", "The user already has a desk booked for that slot."}
+	ErrInvalidPlate         = &Error{"invalid_license_plate", 422, "Unprocessable Entity", "license_plate must contain only letters, digits, spaces and dashes."}
+	ErrMissingVenue         = &Error{"missing_venue", 400, "Bad request", "venue_id is required."}
+	ErrVisitorHasSpot       = &Error{"visitor_has_spot", 409, "Conflict", "The visitor already has a parking spot for that time."}
+	ErrSpotTaken            = &Error{"spot_taken", 409, "Conflict", "The parking spot is already reserved for that time."}
+	ErrParkingFull          = &Error{"parking_full", 409, "Conflict", "No parking spot of that kind is free for that time."}
+	ErrMissingImage         = &Error{"missing_image", 422, "Unprocessable Entity", "Image content needs an image_url or the id of an uploaded attachment."}
+	ErrInvalidTimeZone      = &Error{"invalid_time_zone", 422, "Unprocessable Entity", "time_zone must be an IANA time zone such as Asia/Jakarta."}
+	ErrMissingParticipants  = &Error{"missing_participants", 400, "Bad request", "participants must list at least one user."}
+	ErrInvalidDuration      = &Error{"invalid_duration", 400, "Bad request", "duration and step must be durations such as 30m."}
+	ErrInvalidCheckInCode   = &Error{"invalid_check_in_code", 422, "Unprocessable Entity", "No event in this room is open for check-in with that code."}
+	ErrCheckInLocked        = &Error{"check_in_locked", 429, "Too Many Requests", "Too many wrong codes, try again in 15 minutes."}
+	ErrNotAttendee          = &Error{"not_attendee", 403, "Forbidden", "Only the owner and guests of the event can leave feedback."}
+	ErrFeedbackClosed       = &Error{"feedback_closed", 409, "Conflict", "Feedback opens when the event starts and closes a week after it ends."}
+	ErrCompareRooms         = &Error{"invalid_ids", 400, "Bad request", "ids must list between 1 and 10 room ids."}
+	ErrPresenceDisabled     = &Error{"presence_disabled", 403, "Forbidden", "Presence listings are turned off for this organisation."}
+	ErrUnauthenticated      = &Error{"unauthenticated", 401, "Unauthorized", "X-User-Email header is required."}
+	ErrForbidden            = &Error{"forbidden", 403, "Forbidden", "Your role does not allow this."}
+	ErrNotEventOwner        = &Error{"not_event_owner", 403, "Forbidden", "Only the owner of the event or an admin can change it."}
+	ErrNotParticipant       = &Error{"not_participant", 403, "Forbidden", "Only the guests of the event can answer it."}
+	ErrInvalidOccurrence    = &Error{"invalid_occurrence", 422, "Unprocessable Entity", "occurrence must be the RFC 3339 start time of an occurrence of the event."}
+	ErrNotPending           = &Error{"not_pending_approval", 409, "Conflict", "The event is not waiting for approval."}
+	ErrInvalidPage          = &Error{"invalid_page", 400, "Bad request", "page and per_page must be positive integers."}
+	ErrInvalidSort          = &Error{"invalid_sort", 400, "Bad request", "sort must list fields the list can be sorted by, optionally prefixed with -."}
+	ErrUnknownSource        = &Error{"unknown_source", 400, "Bad request", "X-Booking-Source must be web, mobile, slack, outlook or api."}
+	ErrEmptyHold            = &Error{"empty_legal_hold", 422, "Unprocessable Entity", "A legal hold needs a user, a start_time or an end_time."}
+	ErrHoldLifted           = &Error{"legal_hold_lifted", 409, "Conflict", "The legal hold has already been lifted."}
+	ErrInvalidGuestToken    = &Error{"invalid_guest_token", 403, "Forbidden", "The guest token is unknown, revoked, expired or not valid for this."}
+	ErrInvalidAPIKey        = &Error{"invalid_api_key", 401, "Unauthorized", "The API key is unknown or has been revoked."}
+	ErrAPIKeyReadOnly       = &Error{"api_key_read_only", 403, "Forbidden", "The API key can only read."}
+	ErrEventEnded           = &Error{"event_ended", 422, "Unprocessable Entity", "The event has already ended."}
+	ErrCheckInClosed        = &Error{"check_in_closed", 422, "Unprocessable Entity", "Check-in is only open shortly before and during the event."}
+	ErrUnknownGroup         = &Error{"unknown_event_group", 422, "Unprocessable Entity", "group_id must be the id of an existing event group."}
+	ErrNotGroupOwner        = &Error{"not_event_group_owner", 403, "Forbidden", "Only the owner of the event group or an admin can change it."}
+	ErrServiceUnavailable   = &Error{"database_unavailable", 503, "Service Unavailable", "The database is failing over, try again shortly."}
+	ErrWriteInterrupted     = &Error{"retryable_write", 503, "Service Unavailable", "The change may not have been saved because the database failed over, retry it."}
+	ErrOutsideBookingWindow = &Error{"outside_booking_window", 422, "Unprocessable Entity", "The room can only be booked through this link from now until the end of its booking window."}
+	ErrSlotTaken            = &Error{"slot_taken", 409, "Conflict", "The room is already booked for part of that time."}
+	ErrMissingDeviceToken   = &Error{"missing_device_token", 400, "Bad request", "device_token is required."}
+	ErrInvalidSyncToken     = &Error{"invalid_sync_token", 400, "Bad request", "since must be a sync_token returned by GET /sync."}
+	ErrNotBookingOwner      = &Error{"not_booking_owner", 403, "Forbidden", "Only whoever made the booking or an admin can change it."}
+	ErrNotOwnWorkingHours   = &Error{"not_own_working_hours", 403, "Forbidden", "Only the user or an admin can change their working hours."}
+	ErrMalformedId          = &Error{"invalid_id", 400, "Bad request", "The id in the path is not a valid id."}
+	ErrDuplicate            = &Error{"conflict", 409, "Conflict", "The resource conflicts with an existing one."}
 	ErrInternalServer       = &Error{"internal_server_error", 500, "Internal Server Error", "Something went wrong."}
 )
 
 // Response Success
-type MessageSuccess struct {
-	Data MessageInfo `json:"data"`
-}
-
-type MessageInfo struct {
-	Message string `json:"message`
-}
+type (
+	MessageSuccess httpapi.MessageSuccess
+	MessageInfo    = httpapi.MessageInfo
+)
 
 func WriteSuccess(w http.ResponseWriter, httpStatus int, data interface{}) {
-	w.Header().Set("Content-Type", "application/vnd.api+json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(httpStatus)
-	json.NewEncoder(w).Encode(data)
+	httpapi.WriteSuccess(w, httpStatus, data)
 }
 
 // Middlewares
@@ -71,7 +133,17 @@ func recoverHandler(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("panic: %+v", err)
+				if rec, ok := w.(*routeRecorder); ok {
+					rec.panic = fmt.Sprint(err)
+					log.Printf("panic: request_id=%s %+v", rec.requestId, err)
+				} else {
+					log.Printf("panic: %+v", err)
+				}
+				if err, ok := err.(error); ok && isTransient(err) {
+					w.Header().Set("Retry-After", failoverRetryAfter)
+					WriteError(w, ErrServiceUnavailable)
+					return
+				}
 				WriteError(w, ErrInternalServer)
 			}
 		}()
@@ -131,9 +203,13 @@ func bodyHandler(v interface{}) func(http.Handler) http.Handler {
 				WriteError(w, ErrBadRequest)
 				return
 			}
+			if errs := sanitizeInput(val); len(errs) > 0 {
+				WriteErrors(w, http.StatusUnprocessableEntity, errs)
+				return
+			}
 
 			if next != nil {
-				context.Set(r, "body", val)
+				r = withValue(r, bodyKey, val)
 				next.ServeHTTP(w, r)
 			}
 		}
@@ -147,435 +223,545 @@ func bodyHandler(v interface{}) func(http.Handler) http.Handler {
 // Router
 type router struct {
 	*httprouter.Router
+
+	// routes are the registered routes, see spec.go.
+	routes []string
+
+	// cors is the policy of other origins and preflights the paths
+	// answering OPTIONS, see cors.go.
+	cors       CORSPolicy
+	preflights map[string]bool
+
+	// versions are the API versions newer than baseVersion, oldest first,
+	// and overrides the handlers of the routes they change, by route and
+	// version, see version.go.
+	versions  []string
+	overrides map[string]map[string]http.Handler
 }
 
 func (r *router) Get(path string, handler http.Handler) {
-	r.GET(path, wrapHandler(handler))
+	r.handle("GET", path, handler)
 }
 
 func (r *router) Post(path string, handler http.Handler) {
-	r.POST(path, wrapHandler(handler))
+	r.handle("POST", path, handler)
 }
 
-func (r *router) Patch(path string, handler http.Handler) {
-	r.PATCH(path, wrapHandler(handler))
-}
-
-func (r *router) Delete(path string, handler http.Handler) {
-	r.DELETE(path, wrapHandler(handler))
+func (r *router) Put(path string, handler http.Handler) {
+	r.handle("PUT", path, handler)
 }
 
-func NewRouter() *router {
-	return &router{httprouter.New()}
+func (r *router) Patch(path string, handler http.Handler) {
+	r.handle("PATCH", path, handler)
 }
 
-func wrapHandler(h http.Handler) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		context.Set(r, "params", ps)
-		h.ServeHTTP(w, r)
-	}
+func (r *router) Delete(path string, handler http.Handler) {
+	r.handle("DELETE", path, handler)
 }
 
-// Main handlers
-type appContext struct {
-	db *mgo.Database
+func (r *router) handle(method string, path string, handler http.Handler) {
+	route := method + " " + path
+	r.register(method, path)
+	r.allowPreflight(path)
+	r.Handle(method, path, wrapHandler(route, corsHandler(r.cors)(r.versioned(route, idParamsHandler(path, handler)))))
 }
 
-// Repo Venue
-type Venue struct {
-	Id    bson.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
-	Name  string        `json:"name"`
-	Rooms []Room        `json:"rooms,omitempty"`
+// withStatic serves the handler registered for the value of the :id segment,
+// or next when there is none. httprouter doesn't allow a static segment such
+// as /rooms/compare next to /rooms/:id, so those routes are dispatched here.
+func withStatic(statics map[string]http.Handler, next http.Handler) http.Handler {
+	return staticHandler{statics, next}
 }
 
-type VenueRepo struct {
-	coll *mgo.Collection
+type staticHandler struct {
+	statics map[string]http.Handler
+	next    http.Handler
 }
 
-func (r *VenueRepo) All() ([]Venue, error) {
-	result := []Venue{}
-	err := r.coll.Find(nil).All(&result)
-	if err != nil {
-		return result, err
+func (h staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	if static, ok := h.statics[params.ByName("id")]; ok {
+		static.ServeHTTP(w, r)
+		return
 	}
 
-	return result, nil
+	h.next.ServeHTTP(w, r)
 }
 
-func (r *VenueRepo) Find(id string) (Venue, error) {
-	result := Venue{}
-	err := r.coll.FindId(bson.ObjectIdHex(id)).One(&result)
-	if err != nil {
-		return result, err
-	}
-
-	return result, nil
+func NewRouter(cors CORSPolicy) *router {
+	return &router{Router: httprouter.New(), cors: cors}
 }
 
-func (r *VenueRepo) Create(venue *Venue) error {
-	id := bson.NewObjectId()
-	_, err := r.coll.UpsertId(id, venue)
-	if err != nil {
-		return err
-	}
-
-	venue.Id = id
+func wrapHandler(route string, h http.Handler) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		rec := &routeRecorder{ResponseWriter: w, requestId: r.Header.Get("X-Request-ID"), status: http.StatusOK}
+		if rec.requestId == "" {
+			rec.requestId = newRequestId()
+		}
+		w.Header().Set("X-Request-ID", rec.requestId)
+		writeRateLimit(w, rateLimits.Hit(rateKey(r), true))
 
-	return nil
+		r = withValue(r, paramsKey, ps)
+		h.ServeHTTP(rec, r)
+		metrics.Record(route, rec)
+	}
 }
 
-func (r *VenueRepo) Update(venue *Venue) error {
-	err := r.coll.UpdateId(venue.Id, venue)
-	if err != nil {
-		return err
-	}
+// Main handlers
+type appContext struct {
+	db *storage.Database
 
-	return nil
-}
+	// repos replaces the MongoDB repos of db, see repository.go.
+	repos *Repositories
 
-func (r *VenueRepo) Delete(id string) error {
-	err := r.coll.RemoveId(bson.ObjectIdHex(id))
-	if err != nil {
-		return err
-	}
+	// origin is the request the context serves, see auditlog.go.
+	origin *auditOrigin
 
-	return nil
+	// config is what the app was started with, see config.go.
+	config *Config
 }
 
 // Venue Handlers
 func (c *appContext) venuesHandler(w http.ResponseWriter, r *http.Request) {
-	repo := VenueRepo{c.db.C("venues")}
-	roomRepo := RoomRepo{c.db.C("rooms")}
-	venues, err := repo.All()
+	repo := c.venues()
+	roomRepo := c.rooms()
+	opts, errRes := listOptions(r, "venues", venueSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	opts.IncludeDeleted, errRes = c.includeDeleted(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	venues, total, err := repo.All(r.Context(), opts)
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
 
-	for idx, venue := range venues {
-		rooms, err := roomRepo.AllByVenueId(venue.Id.Hex())
-		if err != nil {
-			panic(err)
+	err = c.summarizeVenues(r.Context(), venues)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	if included(r, "rooms") {
+		for idx, venue := range venues {
+			rooms, err := roomRepo.AllByVenueId(r.Context(), venue.Id.Hex())
+			if err != nil {
+				WriteRepoError(w, err)
+				return
+			}
+			venues[idx].Rooms = rooms
 		}
-		venues[idx].Rooms = rooms
 	}
+	localizeVenues(venues, localizing(w, r))
 
-	WriteSuccess(w, http.StatusOK, venues)
+	writeList(w, r, opts, total, venues)
 }
 
 func (c *appContext) venueHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := VenueRepo{c.db.C("venues")}
-	venue, err := repo.Find(params.ByName("id"))
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
+	}
+
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug)
+		return
 	}
+	localizeVenue(&venue, localizing(w, r))
 
 	WriteSuccess(w, http.StatusOK, venue)
 }
 
 func (c *appContext) createVenueHandler(w http.ResponseWriter, r *http.Request) {
-	body := context.Get(r, "body").(*Venue)
-	repo := VenueRepo{c.db.C("venues")}
-	err := repo.Create(body)
-	if err != nil {
-		panic(err)
+	body := r.Context().Value(bodyKey).(*Venue)
+	repo := c.venues()
+	err := assignVenueSlug(r.Context(), repo, body, nil)
+	if err == errSlugTaken {
+		WriteError(w, ErrSlugTaken)
+		return
 	}
-
-	WriteSuccess(w, http.StatusCreated, body)
-}
-
-func (c *appContext) updateVenueHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	body := context.Get(r, "body").(*Venue)
-	body.Id = bson.ObjectIdHex(params.ByName("id"))
-	repo := VenueRepo{c.db.C("venues")}
-	err := repo.Update(body)
 	if err != nil {
 		panic(err)
 	}
 
-	WriteSuccess(w, http.StatusAccepted, body)
-}
-
-func (c *appContext) deleteVenueHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := VenueRepo{c.db.C("venues")}
-	err := repo.Delete(params.ByName("id"))
+	err = repo.Create(r.Context(), body)
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
+	c.recordChange("venue", body.Id, ChangeCreated)
 
-	data := MessageSuccess{MessageInfo{Message: "Venue has been deleted successfully"}}
-	WriteSuccess(w, http.StatusAccepted, data)
-}
-
-// Repo Room
-type Room struct {
-	Id       bson.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
-	Name     string        `json:"name"`
-	VenueId  string        `json:"venue_id"`
-	Capacity string        `json:"capacity"`
-}
-
-type RoomRepo struct {
-	coll *mgo.Collection
+	WriteSuccess(w, http.StatusCreated, body)
 }
 
-func (r *RoomRepo) All() ([]Room, error) {
-	result := []Room{}
-	err := r.coll.Find(nil).All(&result)
+func (c *appContext) updateVenueHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Venue)
+	repo := c.venues()
+	existing, err := repo.Find(r.Context(), params.ByName("id"))
 	if err != nil {
-		return result, err
+		WriteRepoError(w, err)
+		return
 	}
+	body.Id = existing.Id
 
-	return result, nil
-}
-
-func (r *RoomRepo) Find(id string) (Room, error) {
-	result := Room{}
-	err := r.coll.FindId(bson.ObjectIdHex(id)).One(&result)
-	if err != nil {
-		return result, err
+	err = assignVenueSlug(r.Context(), repo, body, &existing)
+	if err == errSlugTaken {
+		WriteError(w, ErrSlugTaken)
+		return
 	}
-
-	return result, nil
-}
-
-func (r *RoomRepo) Create(room *Room) error {
-	id := bson.NewObjectId()
-	_, err := r.coll.UpsertId(id, room)
 	if err != nil {
-		return err
+		panic(err)
 	}
 
-	room.Id = id
-
-	return nil
-}
-
-func (r *RoomRepo) Update(room *Room) error {
-	err := r.coll.UpdateId(room.Id, room)
+	err = repo.Patch(r.Context(), body, existing)
 	if err != nil {
-		return err
+		WriteRepoError(w, err)
+		return
 	}
-
-	return nil
-}
-
-func (r *RoomRepo) Delete(id string) error {
-	err := r.coll.RemoveId(bson.ObjectIdHex(id))
-	if err != nil {
-		return err
+	c.recordChange("venue", body.Id, ChangeUpdated)
+	if body.Name != existing.Name {
+		roomRepo := c.rooms()
+		rooms, err := roomRepo.AllByVenueId(r.Context(), body.Id.Hex())
+		if err != nil {
+			panic(err)
+		}
+		roomIds := []string{}
+		for _, room := range rooms {
+			roomIds = append(roomIds, room.Id.Hex())
+		}
+		if len(roomIds) > 0 {
+			_, err = c.startLocationBackfill(BackfillVenueRenamed, r.Context().Value(userKey).(User).Email, roomIds)
+			if err != nil {
+				panic(err)
+			}
+		}
 	}
 
-	return nil
+	WriteSuccess(w, http.StatusAccepted, body)
 }
 
-func (r *RoomRepo) AllByVenueId(venueId string) ([]Room, error) {
-	result := []Room{}
-	err := r.coll.Find(bson.M{"venueid": venueId}).All(&result)
+func (c *appContext) deleteVenueHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.venues()
+	err := repo.Delete(r.Context(), params.ByName("id"))
 	if err != nil {
-		return result, err
+		WriteRepoError(w, err)
+		return
 	}
-	return result, nil
+	c.recordChange("venue", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Venue has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
 }
 
 // Room Handlers
 func (c *appContext) roomsHandler(w http.ResponseWriter, r *http.Request) {
-	repo := RoomRepo{c.db.C("rooms")}
-	rooms, err := repo.All()
+	repo := c.rooms()
+	opts, errRes := listOptions(r, "rooms", roomSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	opts.IncludeDeleted, errRes = c.includeDeleted(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	seats, errRes := minCapacity(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	rooms, total, err := repo.AllMatching(r.Context(), RoomFilter{MinCapacity: seats}, opts)
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
+	localizeRooms(rooms, localizing(w, r))
 
-	WriteSuccess(w, http.StatusOK, rooms)
+	writeList(w, r, opts, total, rooms)
 }
 
 func (c *appContext) roomHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := RoomRepo{c.db.C("rooms")}
-	room, err := repo.Find(params.ByName("id"))
+	params := routeParams(r)
+	repo := c.rooms()
+	room, err := repo.Find(r.Context(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
+	localizeRoom(&room, localizing(w, r))
 
 	WriteSuccess(w, http.StatusOK, room)
 }
 
 func (c *appContext) createRoomHandler(w http.ResponseWriter, r *http.Request) {
-	body := context.Get(r, "body").(*Room)
-	repo := RoomRepo{c.db.C("rooms")}
-	err := repo.Create(body)
+	body := r.Context().Value(bodyKey).(*Room)
+	repo := c.rooms()
+	err := assignRoomSlug(r.Context(), repo, body, nil)
+	if err == errSlugTaken {
+		WriteError(w, ErrSlugTaken)
+		return
+	}
 	if err != nil {
 		panic(err)
 	}
 
+	err = repo.Create(r.Context(), body)
+	if err == ErrConflict {
+		WriteError(w, ErrSlugTaken)
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("room", body.Id, ChangeCreated)
+
 	WriteSuccess(w, http.StatusCreated, body)
 }
 
 func (c *appContext) updateRoomHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println(context.Get(r, "body"))
-	params := context.Get(r, "params").(httprouter.Params)
-	body := context.Get(r, "body").(*Room)
-	body.Id = bson.ObjectIdHex(params.ByName("id"))
-	repo := RoomRepo{c.db.C("rooms")}
-	err := repo.Update(body)
+	fmt.Println(r.Context().Value(bodyKey))
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*Room)
+	repo := c.rooms()
+	existing, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	body.Id = existing.Id
+
+	err = assignRoomSlug(r.Context(), repo, body, &existing)
+	if err == errSlugTaken {
+		WriteError(w, ErrSlugTaken)
+		return
+	}
 	if err != nil {
 		panic(err)
 	}
 
+	err = repo.Patch(r.Context(), body, existing)
+	if err == ErrConflict {
+		WriteError(w, ErrSlugTaken)
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("room", body.Id, ChangeUpdated)
+	if body.Name != existing.Name {
+		_, err = c.startLocationBackfill(BackfillRoomRenamed, r.Context().Value(userKey).(User).Email, []string{body.Id.Hex()})
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	WriteSuccess(w, http.StatusAccepted, body)
 }
 
 func (c *appContext) deleteRoomHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := RoomRepo{c.db.C("rooms")}
-	err := repo.Delete(params.ByName("id"))
+	params := routeParams(r)
+	repo := c.rooms()
+	err := repo.Delete(r.Context(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
+	c.recordChange("room", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
 
 	data := MessageSuccess{MessageInfo{Message: "Room has been deleted successfully"}}
 	WriteSuccess(w, http.StatusAccepted, data)
 }
 
 func (c *appContext) roomsVenueHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := RoomRepo{c.db.C("rooms")}
-	rooms, err := repo.AllByVenueId(params.ByName("id"))
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
 
-	WriteSuccess(w, http.StatusOK, rooms)
-}
-
-// Repo Event
-type Event struct {
-	Id          bson.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
-	Name        string        `json:"name"`
-	LocationID  string        `json:"location_id"`
-	Location    string        `json:"location"`
-	Description string        `json:"description"`
-	Guests      []string      `json:"guests"`
-	Owner       string        `json:"owner"`
-	StartTime   time.Time     `json:"start_time"`
-	EndTime     time.Time     `json:"end_time"`
-}
-
-type EventResponse struct {
-	Id          bson.ObjectId `json:"id,omitempty"`
-	Name        string        `json:"name"`
-	LocationID  string        `json:"location_id"`
-	Location    string        `json:"location"`
-	Description string        `json:"description"`
-	Guests      []string      `json:"guests"`
-	Owner       string        `json:"owner"`
-	Date        int           `json:"date"`
-	Month       int           `json:"month"`
-	Year        int           `json:"year"`
-	StartHour   int           `json:"start_hour"`
-	StartMinute int           `json:"start_minute"`
-	EndHour     int           `json:"end_hour"`
-	EndMinute   int           `json:"end_minute"`
-}
-
-type EventRepo struct {
-	coll *mgo.Collection
-}
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/rooms")
+		return
+	}
 
-func (r *EventRepo) All(start_time time.Time, end_time time.Time) ([]Event, error) {
-	result := []Event{}
-	err := r.coll.Find(bson.M{"starttime": bson.M{"$gte": start_time, "$lte": end_time}}).All(&result)
+	repo := c.rooms()
+	rooms, err := repo.AllByVenueId(r.Context(), venue.Id.Hex())
 	if err != nil {
-		return result, err
+		WriteRepoError(w, err)
+		return
 	}
+	localizeRooms(rooms, localizing(w, r))
 
-	return result, nil
+	WriteSuccess(w, http.StatusOK, rooms)
 }
 
-func (r *EventRepo) Find(id string) (Event, error) {
-	result := Event{}
-	err := r.coll.FindId(bson.ObjectIdHex(id)).One(&result)
-	if err != nil {
-		return result, err
-	}
-
-	return result, nil
+type EventResponse struct {
+	Id          storage.ObjectId `json:"id,omitempty"`
+	Name        string           `json:"name"`
+	LocationID  string           `json:"location_id"`
+	Location    string           `json:"location"`
+	Description string           `json:"description"`
+	Guests      []string         `json:"guests"`
+	Owner       string           `json:"owner"`
+	Date        int              `json:"date"`
+	Month       int              `json:"month"`
+	Year        int              `json:"year"`
+	StartHour   int              `json:"start_hour"`
+	StartMinute int              `json:"start_minute"`
+	EndHour     int              `json:"end_hour"`
+	EndMinute   int              `json:"end_minute"`
+	TimeZone    string           `json:"time_zone,omitempty"`
+
+	// StartTime and EndTime are RFC 3339 times, taking the place of the date
+	// and hour fields above when given.
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+
+	Equipment       []EquipmentBooking `json:"equipment,omitempty"`
+	TravelWarnings  []TravelWarning    `json:"travel_warnings,omitempty"`
+	Category        string             `json:"category,omitempty"`
+	Tentative       bool               `json:"tentative"`
+	CheckInCode     string             `json:"check_in_code,omitempty"`
+	CapWarning      string             `json:"cap_warning,omitempty"`
+	PendingApproval bool               `json:"pending_approval"`
+
+	Participants []Participant `json:"participants,omitempty"`
+	RSVP         *RSVPSummary  `json:"rsvp,omitempty"`
+
+	Recurrence       *Recurrence        `json:"recurrence,omitempty"`
+	RecurringEventId storage.ObjectId   `json:"recurring_event_id,omitempty"`
+	Upgrade          *UpgradePreference `json:"upgrade,omitempty"`
+	BookedVia        *BookingSource     `json:"booked_via,omitempty"`
+	GroupId          string             `json:"group_id,omitempty"`
 }
 
-func (r *EventRepo) Create(event *Event) error {
-	id := bson.NewObjectId()
-	_, err := r.coll.UpsertId(id, event)
-	if err != nil {
-		return err
+// times returns when the event starts and ends, from start_time and
+// end_time, or else from the date and hour fields read in loc. It fails
+// when start_time or end_time isn't an RFC 3339 time.
+func (e *EventResponse) times(loc *time.Location) (time.Time, time.Time, error) {
+	if e.StartTime != "" || e.EndTime != "" {
+		start_time, err := time.Parse(time.RFC3339, e.StartTime)
+		if err != nil {
+			return start_time, start_time, err
+		}
+		end_time, err := time.Parse(time.RFC3339, e.EndTime)
+		return start_time, end_time, err
 	}
 
-	event.Id = id
-
-	return nil
+	return time.Date(e.Year, time.Month(e.Month), e.Date, e.StartHour, e.StartMinute, 0, 0, loc),
+		time.Date(e.Year, time.Month(e.Month), e.Date, e.EndHour, e.EndMinute, 0, 0, loc), nil
 }
 
-func (r *EventRepo) Update(event *Event) error {
-	err := r.coll.UpdateId(event.Id, event)
-	if err != nil {
-		return err
+// Event Handlers
+// maxEventsWindow is the longest window events can be listed over.
+const maxEventsWindow = 366 * 24 * time.Hour
+
+// eventsWindow reads the ?start_time= and ?end_time= of r, this week of its
+// locale by default, failing unless they're RFC 3339 times at most
+// maxEventsWindow apart, start_time first.
+func eventsWindow(r *http.Request) (time.Time, time.Time, *Error) {
+	locale := requestLocale(r)
+	start_time, errRes := queryTime(r, "start_time", locale.BeginningOfWeek(clockNow()))
+	if errRes != nil {
+		return time.Time{}, time.Time{}, errRes
+	}
+	end_time, errRes := queryTime(r, "end_time", locale.EndOfWeek(clockNow()))
+	if errRes != nil {
+		return time.Time{}, time.Time{}, errRes
 	}
 
-	return nil
+	return start_time, end_time, checkWindow(start_time, end_time)
 }
 
-func (r *EventRepo) Delete(id string) error {
-	err := r.coll.RemoveId(bson.ObjectIdHex(id))
+// queryTime reads the RFC 3339 time of the query parameter name of r, or
+// fallback when it's left out.
+func queryTime(r *http.Request, name string, fallback time.Time) (time.Time, *Error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return err
+		return time.Time{}, ErrInvalidTime
 	}
 
-	return nil
+	return t.In(defaultLocation), nil
 }
 
-func (r *EventRepo) Search(roomIds []string, owner string, guests string) ([]Event, error) {
-	result := []Event{}
-	beginningOfWeek := now.BeginningOfWeek()
-	endOfWeek := now.EndOfWeek()
-
-	err := r.coll.Find(bson.M{
-		"room_id": bson.M{
-			"$in": roomIds,
-		}, "$or": []bson.M{
-			{"owner": owner},
-			{"guests": guests},
-		}, "start_time": bson.M{
-			"$gte": beginningOfWeek,
-			"$lte": endOfWeek,
-		},
-	}).All(&result)
-	if err != nil {
-		return result, err
+// checkWindow fails unless start_time is before end_time, at most
+// maxEventsWindow earlier.
+func checkWindow(start_time time.Time, end_time time.Time) *Error {
+	if !start_time.Before(end_time) {
+		return ErrInvalidWindow
+	}
+	if end_time.Sub(start_time) > maxEventsWindow {
+		return ErrWindowTooLong
 	}
 
-	return result, nil
+	return nil
 }
 
-// Event Handlers
 func (c *appContext) eventsHandler(w http.ResponseWriter, r *http.Request) {
-	repo := EventRepo{c.db.C("events")}
-	loc := time.FixedZone("UTC+7", 7*60*60)
-	start_time := now.BeginningOfWeek()
-	if r.URL.Query().Get("start_time") != "" {
-		start_time, _ = time.Parse(time.RFC3339, r.URL.Query().Get("start_time"))
-		start_time = start_time.In(loc)
+	repo := c.events()
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	opts, errRes := listOptions(r, "events", eventSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
 	}
-	end_time := now.EndOfWeek()
-	if r.URL.Query().Get("end_time") != "" {
-		end_time, _ = time.Parse(time.RFC3339, r.URL.Query().Get("end_time"))
-		end_time = end_time.In(loc)
+	opts.IncludeDeleted, errRes = c.includeDeleted(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	statuses, ok := parseEventStatuses(r.URL.Query().Get("status"), listedEventStatuses)
+	if !ok {
+		WriteError(w, ErrInvalidStatus)
+		return
 	}
 
-	events, err := repo.All(start_time, end_time)
+	events, err := repo.All(r.Context(), start_time, end_time, opts.IncludeDeleted || statuses[EventCancelled])
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
 	}
+	if !opts.IncludeDeleted {
+		events = withoutDeleted(events)
+	}
+	events = c.withOccurrences(r.Context(), events, start_time, end_time)
+	events = filterByStatus(events, statuses)
+	events = filterBySource(events, r.URL.Query().Get("source"))
+	withRSVP(events)
+	sortEvents(events, opts.Sort)
+	total := len(events)
+	lo, hi := opts.Bounds(total)
+	events = events[lo:hi]
+	if renderHTML(r) {
+		events = withRenderedDescriptions(events)
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones.events(events)
 
 	// results := []EventResponse{}
 	// for _, event := range events {
@@ -598,16 +784,23 @@ func (c *appContext) eventsHandler(w http.ResponseWriter, r *http.Request) {
 	// }
 	// WriteSuccess(w, http.StatusOK, results)
 
-	WriteSuccess(w, http.StatusOK, events)
+	writeList(w, r, opts, total, events)
 }
 
 func (c *appContext) eventHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := EventRepo{c.db.C("events")}
-	event, err := repo.Find(params.ByName("id"))
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
 	}
+	zones.event(&event)
 
 	eventRes := EventResponse{
 		Id:          event.Id,
@@ -624,31 +817,158 @@ func (c *appContext) eventHandler(w http.ResponseWriter, r *http.Request) {
 		StartMinute: event.StartTime.Minute(),
 		EndHour:     event.EndTime.Hour(),
 		EndMinute:   event.EndTime.Minute(),
+		TimeZone:    event.TimeZone,
+		StartTime:   event.StartTime.Format(time.RFC3339),
+		EndTime:     event.EndTime.Format(time.RFC3339),
+		Equipment:   event.Equipment,
+		Category:    event.Category,
+		Tentative:   event.Tentative,
+		CheckInCode: event.CheckInCode,
+
+		PendingApproval: event.PendingApproval,
+
+		Participants: participants(event),
+		RSVP:         rsvpSummary(event),
+
+		Recurrence:       event.Recurrence,
+		RecurringEventId: event.RecurringEventId,
+		Upgrade:          event.Upgrade,
+		GroupId:          event.GroupId,
+	}
+	source := sourceOf(event)
+	eventRes.BookedVia = &source
+	if renderHTML(r) {
+		eventRes.Description = renderedDescription(event)
 	}
 
 	WriteSuccess(w, http.StatusOK, eventRes)
 }
 
 func (c *appContext) createEventHandler(w http.ResponseWriter, r *http.Request) {
-	loc := time.FixedZone("UTC+7", 7*60*60)
-	body := context.Get(r, "body").(*EventResponse)
+	body := r.Context().Value(bodyKey).(*EventResponse)
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	loc, errRes := zones.bodyZone(body)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	start_time, end_time, err := body.times(loc)
+	if err != nil {
+		WriteError(w, ErrInvalidTime)
+		return
+	}
 	event := Event{
 		Name:        body.Name,
 		LocationID:  body.LocationID,
 		Location:    body.Location,
 		Description: body.Description,
-		Guests:      body.Guests,
+		Guests:      body.guestList(),
 		Owner:       body.Owner,
-		StartTime:   time.Date(body.Year, time.Month(body.Month), body.Date, body.StartHour, body.StartMinute, 0, 0, loc),
-		EndTime:     time.Date(body.Year, time.Month(body.Month), body.Date, body.EndHour, body.EndMinute, 0, 0, loc),
+		StartTime:   start_time,
+		EndTime:     end_time,
+		Equipment:   body.Equipment,
+		Category:    body.Category,
+		Tentative:   body.Tentative,
+		Upgrade:     body.Upgrade,
+		GroupId:     body.GroupId,
+
+		Participants: body.Participants,
+	}
+	setDescription(&event, event.Description)
+	syncParticipants(&event, nil)
+	if body.Recurrence != nil && body.Recurrence.WeekStart == "" {
+		body.Recurrence.WeekStart = weekdayName(requestLocale(r).WeekStart)
+	}
+	event.SetRecurrence(body.Recurrence)
+
+	source, errRes := bookingSource(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	event.BookedVia = source
+
+	user := r.Context().Value(userKey).(User)
+	if event.Owner == "" {
+		event.Owner = user.Email
+	}
+	if !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+	if errRes := c.checkGroup(user, event.GroupId); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	if errRes := c.checkEquipment(r.Context(), &event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if errRes := c.checkCapacity(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if errRes := c.checkVenueRules(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	errRes, capWarning := c.checkTeamCap(r.Context(), event)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
 	}
+	event.CapWarning = capWarning
+	event.PendingApproval = c.needsApproval(r.Context(), event)
 
-	repo := EventRepo{c.db.C("events")}
-	err := repo.Create(&event)
+	if dryRun(r) {
+		if conflict := c.dryRunConflict(r.Context(), event); conflict != nil {
+			WriteError(w, conflictError(*conflict))
+			return
+		}
+		event.TravelWarnings = c.travelWarnings(r.Context(), event)
+		zones.event(&event)
+		writeDryRun(w, event)
+		return
+	}
+
+	code, err := c.newCheckInCode(r.Context(), event)
 	if err != nil {
 		panic(err)
 	}
+	event.CheckInCode = code
+
+	// Tentative bookings this one may bump make way before the conflict
+	// check, so the new event needs its id up front. They're put back when
+	// the event isn't created after all.
+	event.Id = storage.NewObjectId()
+	bumps := c.bumpForEvent(r.Context(), event)
+
+	repo := c.events()
+	err = repo.Create(r.Context(), &event)
+	if err != nil {
+		c.undoBumps(r.Context(), bumps)
+	}
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeCreated)
+	notifyBumps(bumps, event)
+	notifyEvent(NotifyCreated, event)
+	c.announceBooking(r.Context(), event)
+	c.pushToGraph(event)
 	body.Id = event.Id
+	event.TravelWarnings = c.travelWarnings(r.Context(), event)
 
 	// create event in gcal
 	client := getClient()
@@ -669,7 +989,7 @@ func (c *appContext) createEventHandler(w http.ResponseWriter, r *http.Request)
 	ev := &calendar.Event{
 		Summary:     event.Name,
 		Location:    event.Location,
-		Description: event.Description,
+		Description: event.Description + "\n\nCheck-in code: " + event.CheckInCode,
 		Start: &calendar.EventDateTime{
 			DateTime: event.StartTime.Format("2006-01-02T15:04:05-07:00"),
 			TimeZone: "Asia/Bangkok",
@@ -678,7 +998,7 @@ func (c *appContext) createEventHandler(w http.ResponseWriter, r *http.Request)
 			DateTime: event.EndTime.Format("2006-01-02T15:04:05-07:00"),
 			TimeZone: "Asia/Bangkok",
 		},
-		Recurrence: []string{},
+		Recurrence: rrule(event.Recurrence),
 		Attendees:  attendees,
 	}
 
@@ -688,84 +1008,357 @@ func (c *appContext) createEventHandler(w http.ResponseWriter, r *http.Request)
 		log.Fatalf("Unable to create event. %v\n", err)
 		panic(err)
 	}
-	fmt.Println("Event created: %s\n", ev.HtmlLink)
+	fmt.Printf("Event created: %s\n", ev.HtmlLink)
+	zones.event(&event)
 
 	WriteSuccess(w, http.StatusCreated, event)
 }
 
 func (c *appContext) updateEventHandler(w http.ResponseWriter, r *http.Request) {
-	loc := time.FixedZone("UTC+7", 7*60*60)
-	params := context.Get(r, "params").(httprouter.Params)
-	body := context.Get(r, "body").(*EventResponse)
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*EventResponse)
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	loc, errRes := zones.bodyZone(body)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	body.TimeZone = loc.String()
+	start_time, end_time, err := body.times(loc)
+	if err != nil {
+		WriteError(w, ErrInvalidTime)
+		return
+	}
+	id, err := objectId(params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
 	event := Event{
-		Id:          bson.ObjectIdHex(params.ByName("id")),
+		Id:          id,
 		Name:        body.Name,
 		LocationID:  body.LocationID,
 		Location:    body.Location,
 		Description: body.Description,
-		Guests:      body.Guests,
+		Guests:      body.guestList(),
 		Owner:       body.Owner,
-		StartTime:   time.Date(body.Year, time.Month(body.Month), body.Date, body.StartHour, body.StartMinute, 0, 0, loc),
-		EndTime:     time.Date(body.Year, time.Month(body.Month), body.Date, body.EndHour, body.EndMinute, 0, 0, loc),
+		StartTime:   start_time,
+		EndTime:     end_time,
+		Equipment:   body.Equipment,
+		Category:    body.Category,
+		Tentative:   body.Tentative,
+		Upgrade:     body.Upgrade,
+		GroupId:     body.GroupId,
+
+		Participants: body.Participants,
+	}
+	setDescription(&event, event.Description)
+
+	if errRes := c.checkEquipment(r.Context(), &event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if errRes := c.checkCapacity(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
 	}
 
-	repo := EventRepo{c.db.C("events")}
-	err := repo.Update(&event)
+	errRes, capWarning := c.checkTeamCap(r.Context(), event)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	event.CapWarning = capWarning
+
+	repo := c.events()
+	existing, err := repo.Find(r.Context(), event.Id.Hex())
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
+	}
+	if event.Owner == "" {
+		event.Owner = existing.Owner
+	}
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManage(existing) || !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+	if event.GroupId != existing.GroupId {
+		if errRes := c.checkGroup(user, event.GroupId); errRes != nil {
+			WriteError(w, errRes)
+			return
+		}
+	}
+	event.CheckInCode = existing.CheckInCode
+	event.CheckedInAt = existing.CheckedInAt
+	event.PendingApproval = existing.PendingApproval
+	event.Source = existing.Source
+	event.ExternalId = existing.ExternalId
+	event.BookedVia = existing.BookedVia
+	event.Requester = existing.Requester
+	syncParticipants(&event, existing.Participants)
+	if event.LocationID != existing.LocationID {
+		event.PendingApproval = c.needsApproval(r.Context(), event)
+	}
+
+	occurrence, errRes := occurrenceParam(r, existing)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if occurrence.IsZero() {
+		recurrence := body.Recurrence
+		if recurrence != nil && recurrence.Exceptions == nil && existing.Recurrence != nil {
+			recurrence.Exceptions = existing.Recurrence.Exceptions
+		}
+		if recurrence != nil && recurrence.WeekStart == "" {
+			if existing.Recurrence != nil {
+				recurrence.WeekStart = existing.Recurrence.WeekStart
+			} else {
+				recurrence.WeekStart = weekdayName(requestLocale(r).WeekStart)
+			}
+		}
+		event.SetRecurrence(recurrence)
+		event.RecurringEventId = existing.RecurringEventId
+		event.OriginalStartTime = existing.OriginalStartTime
+	} else {
+		// The changed occurrence becomes an event of its own.
+		event.Id = storage.NewObjectId()
+		event.RecurringEventId = existing.Id
+		event.OriginalStartTime = occurrence
+		event.CheckInCode = ""
+		event.CheckedInAt = time.Time{}
+		body.Id = event.Id
+		body.RecurringEventId = existing.Id
+	}
+	if errRes := c.checkVenueRules(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if event.CheckInCode == "" || event.LocationID != existing.LocationID {
+		event.CheckInCode, err = c.newCheckInCode(r.Context(), event)
+		if err != nil {
+			panic(err)
+		}
+	}
+	body.CheckInCode = event.CheckInCode
+	body.Guests = event.Guests
+	body.Participants = event.Participants
+	body.RSVP = event.RSVP
+	body.CapWarning = event.CapWarning
+	body.PendingApproval = event.PendingApproval
+	body.StartTime = event.StartTime.In(loc).Format(time.RFC3339)
+	body.EndTime = event.EndTime.In(loc).Format(time.RFC3339)
+
+	if dryRun(r) {
+		if conflict := c.dryRunConflict(r.Context(), event); conflict != nil {
+			WriteError(w, conflictError(*conflict))
+			return
+		}
+		body.Description = event.Description
+		body.TravelWarnings = c.travelWarnings(r.Context(), event)
+		writeDryRun(w, body)
+		return
+	}
+
+	bumps := c.bumpForEvent(r.Context(), event)
+	if occurrence.IsZero() {
+		err = repo.Patch(r.Context(), &event, existing)
+	} else {
+		err = repo.Create(r.Context(), &event)
+	}
+	if err != nil {
+		c.undoBumps(r.Context(), bumps)
+	}
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
 	}
+	if occurrence.IsZero() {
+		c.recordChange("event", event.Id, ChangeUpdated)
+	} else {
+		err = repo.AddException(r.Context(), existing.Id, occurrence)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		c.recordChange("event", existing.Id, ChangeUpdated)
+		c.recordChange("event", event.Id, ChangeCreated)
+	}
+	notifyBumps(bumps, event)
+	notifyEvent(NotifyUpdated, event)
+	if !occurrence.IsZero() {
+		c.recordFreedSlot(existing.LocationID, occurrence, occurrence.Add(existing.EndTime.Sub(existing.StartTime)))
+	} else if existing.Recurrence == nil && (event.LocationID != existing.LocationID || !event.StartTime.Equal(existing.StartTime) || !event.EndTime.Equal(existing.EndTime)) {
+		c.recordFreedSlot(existing.LocationID, existing.StartTime, existing.EndTime)
+	}
+	body.Description = event.Description
+	body.TravelWarnings = c.travelWarnings(r.Context(), event)
 
 	WriteSuccess(w, http.StatusAccepted, body)
 }
 
 func (c *appContext) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
-	params := context.Get(r, "params").(httprouter.Params)
-	repo := EventRepo{c.db.C("events")}
-	err := repo.Delete(params.ByName("id"))
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
 	if err != nil {
-		panic(err)
+		WriteRepoError(w, err)
+		return
+	}
+	if user := r.Context().Value(userKey).(User); !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+
+	occurrence, errRes := occurrenceParam(r, event)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	if !occurrence.IsZero() {
+		err = repo.AddException(r.Context(), event.Id, occurrence)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		c.recordChange("event", event.Id, ChangeUpdated)
+		cancelled := event.Occurrences(occurrence, occurrence.Add(time.Nanosecond))[0]
+		notifyEvent(NotifyCancelled, cancelled)
+		c.recordLateCancel(cancelled)
+		c.recordFreedSlot(cancelled.LocationID, cancelled.StartTime, cancelled.EndTime)
+
+		data := MessageSuccess{MessageInfo{Message: "Occurrence has been deleted successfully"}}
+		WriteSuccess(w, http.StatusAccepted, data)
+		return
+	}
+
+	err = repo.Delete(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+	notifyEvent(NotifyCancelled, event)
+	c.deleteDetached(r.Context(), event)
+	c.recordLateCancel(event)
+	if event.Recurrence == nil {
+		c.recordFreedSlot(event.LocationID, event.StartTime, event.EndTime)
 	}
 
 	data := MessageSuccess{MessageInfo{Message: "Event has been deleted successfully"}}
 	WriteSuccess(w, http.StatusAccepted, data)
 }
 
-// func (c *appContext) searchEventsHandler(w http.ResponseWriter, r *http.Request) {
-//      params := context.Get(r, "params").(httprouter.Params)
-//      roomIds := r.URL.Query()["room_ids[]"]
-//      owner := params.ByName("owner")
-//      guests := params.ByName("guests")
-//      fmt.Println(roomIds)
-//      fmt.Println(owner)
-//      fmt.Println(owner)
-//      repo := EventRepo{c.db.C("events")}
-//      events, err := repo.Search(roomIds, owner, guests)
-//      if err != nil {
-//              panic(err)
-//      }
+// Nested Event Handlers
+func roomLocationHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			params := routeParams(r)
+			id := params.ByName("id")
+			if !storage.IsObjectIdHex(id) {
+				WriteError(w, ErrNotFound)
+				return
+			}
 
-//      result := []Event{[]EventResponse{}}
-//      for idx, event := range events {
-//              result[idx] = EventResponse{
-//                      Id:          event.Id,
-//                      Name:        event.Name,
-//                      LocationID:  event.LocationID,
-//                      Location:    event.Location,
-//                      Description: event.Description,
-//                      Guests:      event.Guests,
-//                      Owner:       event.Owner,
-//                      Date:        event.StartTime.Day(),
-//                      Month:       int(event.StartTime.Month()),
-//                      Year:        event.StartTime.Year(),
-//                      StartHour:   event.StartTime.Hour(),
-//                      StartMinute: event.StartTime.Minute(),
-//                      EndHour:     event.EndTime.Hour(),
-//                      EndMinute:   event.EndTime.Minute(),
-//              }
-//      }
+			repo := c.forRequest(r).rooms()
+			room, err := repo.Find(r.Context(), id)
+			if err != nil {
+				WriteRepoError(w, err)
+				return
+			}
 
-//      WriteSuccess(w, http.StatusOK, result)
-// }
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+
+			body := map[string]interface{}{}
+			if err := json.Unmarshal(b, &body); err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+
+			if locationId, ok := body["location_id"]; ok && locationId != room.Id.Hex() {
+				WriteError(w, ErrLocationMismatch)
+				return
+			}
+			body["location_id"] = room.Id.Hex()
+			body["location"] = room.Name
+
+			b, err = json.Marshal(body)
+			if err != nil {
+				panic(err)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+func (c *appContext) venueEventsHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/events")
+		return
+	}
+
+	roomRepo := c.rooms()
+	rooms, err := roomRepo.AllByVenueId(r.Context(), venue.Id.Hex())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	roomIds := []string{}
+	for _, room := range rooms {
+		roomIds = append(roomIds, room.Id.Hex())
+	}
+
+	repo := c.events()
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	events, err := repo.AllByLocationIds(r.Context(), roomIds, start_time, end_time)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if renderHTML(r) {
+		events = withRenderedDescriptions(events)
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones.events(events)
+
+	WriteSuccess(w, http.StatusOK, events)
+}
 
 // Retrieve a token, saves the token, then returns the generated client.
 func getClient() *http.Client {
@@ -833,49 +1426,537 @@ func saveToken(path string, token *oauth2.Token) {
 	json.NewEncoder(f).Encode(token)
 }
 
-func main() {
-	gotenv.Load()
+// Indexes
+func ensureIndexes(db *storage.Database) {
+	err := db.C("venues").EnsureIndex(storage.Index{Key: []string{"orgid", "slug"}, Unique: true, Sparse: true})
+	if err != nil {
+		panic(err)
+	}
 
-	session, err := mgo.Dial("localhost")
+	err = db.C("venues").EnsureIndexKey("oldslugs")
 	if err != nil {
 		panic(err)
 	}
-	defer session.Close()
-	session.SetMode(mgo.Monotonic, true)
 
-	// Index
-	appC := appContext{session.DB("ivana")}
-	commonHandlers := alice.New(context.ClearHandler, loggingHandler, recoverHandler)
-	router := NewRouter()
+	err = db.C("rooms").EnsureIndex(storage.Index{Key: []string{"orgid", "venueid", "slug"}, Unique: true, PartialFilter: bson.M{"slug": bson.M{"$gt": ""}}})
+	if err != nil {
+		panic(err)
+	}
 
-	// Routing
+	err = db.C("events").EnsureIndexKey("equipment.equipmentid", "starttime")
+	if err != nil {
+		panic(err)
+	}
 
-	router.Get("/venues/:id", commonHandlers.ThenFunc(appC.venueHandler))
-	router.Patch("/venues/:id", commonHandlers.Append(bodyHandler(Venue{})).ThenFunc(appC.updateVenueHandler))
-	router.Delete("/venues/:id", commonHandlers.ThenFunc(appC.deleteVenueHandler))
-	router.Get("/venues", commonHandlers.ThenFunc(appC.venuesHandler))
-	router.Post("/venues", commonHandlers.Append(bodyHandler(Venue{})).ThenFunc(appC.createVenueHandler))
+	err = db.C("attachments").EnsureIndexKey("ownertype", "ownerid")
+	if err != nil {
+		panic(err)
+	}
 
-	router.Get("/venues/:id/rooms", commonHandlers.ThenFunc(appC.roomsVenueHandler))
+	err = db.C("photos").EnsureIndexKey("ownertype", "ownerid", "-createdat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("msgraph_events").EnsureIndexKey("graphid")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("msgraph_subscriptions").EnsureIndexKey("mailbox")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("msgraph_subscriptions").EnsureIndexKey("expiresat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("webhook_deliveries").EnsureIndexKey("webhookid", "-createdat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("webhook_deliveries").EnsureIndexKey("status", "nextattemptat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("changes").EnsureIndex(storage.Index{Key: []string{"orgid", "seq"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("changes").EnsureIndexKey("time")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("desks").EnsureIndexKey("floorid", "label")
+	if err != nil {
+		panic(err)
+	}
 
-	router.Get("/rooms/:id", commonHandlers.ThenFunc(appC.roomHandler))
-	router.Patch("/rooms/:id", commonHandlers.Append(bodyHandler(Room{})).ThenFunc(appC.updateRoomHandler))
-	router.Delete("/rooms/:id", commonHandlers.ThenFunc(appC.deleteRoomHandler))
-	router.Get("/rooms", commonHandlers.ThenFunc(appC.roomsHandler))
-	router.Post("/rooms", commonHandlers.Append(bodyHandler(Room{})).ThenFunc(appC.createRoomHandler))
+	err = db.C("desk_bookings").EnsureIndexKey("deskid", "date")
+	if err != nil {
+		panic(err)
+	}
 
-	router.Get("/events/:id", commonHandlers.ThenFunc(appC.eventHandler))
-	router.Patch("/events/:id", commonHandlers.Append(bodyHandler(EventResponse{})).ThenFunc(appC.updateEventHandler))
-	router.Delete("/events/:id", commonHandlers.ThenFunc(appC.deleteEventHandler))
-	router.Post("/events", commonHandlers.Append(bodyHandler(EventResponse{})).ThenFunc(appC.createEventHandler))
-	router.Get("/events", commonHandlers.ThenFunc(appC.eventsHandler))
+	err = db.C("desk_bookings").EnsureIndexKey("user", "date")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("parking_spots").EnsureIndexKey("venueid", "label")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("parking_reservations").EnsureIndexKey("venueid", "starttime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("parking_reservations").EnsureIndexKey("eventid")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("panel_content").EnsureIndexKey("venueid", "-priority")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("working_hours").EnsureIndex(storage.Index{Key: []string{"orgid", "user"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("owner", "starttime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("guests", "starttime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("travel_times").EnsureIndex(storage.Index{Key: []string{"fromvenueid", "tovenueid"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("locationid", "checkincode")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("users").EnsureIndex(storage.Index{Key: []string{"email"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("team_caps").EnsureIndex(storage.Index{Key: []string{"orgid", "team"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("feedback").EnsureIndex(storage.Index{Key: []string{"eventid", "attendee"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("feedback").EnsureIndexKey("roomid", "createdat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("locationid", "seriesendtime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("starttime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("locationid", "starttime", "endtime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndexKey("recurringeventid")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("announcements").EnsureIndexKey("venueid", "displayfrom")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("reliability").EnsureIndex(storage.Index{Key: []string{"eventid", "kind", "starttime"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("reliability").EnsureIndexKey("user", "starttime")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("legal_holds").EnsureIndexKey("liftedat", "-createdat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("guest_tokens").EnsureIndex(storage.Index{Key: []string{"hash"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("api_keys").EnsureIndex(storage.Index{Key: []string{"hash"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("events").EnsureIndex(storage.Index{Key: []string{"groupid", "starttime"}, Sparse: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("booking_links").EnsureIndex(storage.Index{Key: []string{"roomid"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("booking_links").EnsureIndex(storage.Index{Key: []string{"token"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("reminders").EnsureIndex(storage.Index{Key: []string{"eventid", "starttime"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("sync_devices").EnsureIndex(storage.Index{Key: []string{"token", "user"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("location_backfills").EnsureIndexKey("-startedat")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("changes").EnsureIndexKey("entity", "entityid", "seq")
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("idempotency_keys").EnsureIndex(storage.Index{Key: []string{"orgid", "user", "key"}, Unique: true})
+	if err != nil {
+		panic(err)
+	}
+
+	err = db.C("idempotency_keys").EnsureIndex(storage.Index{Key: []string{"createdat"}, ExpireAfter: idempotencyKeyTTL})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, key := range [][]string{{"time"}, {"actor", "time"}, {"entity", "entityid", "time"}, {"requestid"}} {
+		err = db.C("audit_logs").EnsureIndexKey(key...)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// routes returns the router of every route of the API, served by appC.
+func routes(appC *appContext) *router {
+	commonHandlers := alice.New(loggingHandler, compressHandler, recoverHandler, chaosHandler, sessionHandler(appC), apiKeyHandler(appC), orgHandler(appC))
+	router := NewRouter(appC.config.CORS)
+
+	router.Get("/venues/:id", commonHandlers.ThenFunc(appC.handle((*appContext).venueHandler)))
+	router.Patch("/venues/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), mergePatchHandler(appC, venuePatchBase), schemaHandler("venue"), bodyHandler(Venue{}), validateHandler).ThenFunc(appC.handle((*appContext).updateVenueHandler)))
+	router.Delete("/venues/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteVenueHandler)))
+	router.Get("/venues", commonHandlers.ThenFunc(appC.handle((*appContext).venuesHandler)))
+	router.Post("/venues", commonHandlers.Append(requireRole(appC, RoleAdmin), idempotencyHandler(appC), schemaHandler("venue"), bodyHandler(Venue{}), validateHandler).ThenFunc(appC.handle((*appContext).createVenueHandler)))
+
+	router.Get("/venues/:id/rooms", commonHandlers.ThenFunc(appC.handle((*appContext).roomsVenueHandler)))
+	router.Get("/venues/:id/floor-plans", commonHandlers.ThenFunc(appC.handle((*appContext).venueFloorPlansHandler)))
+	router.Get("/venues/:id/photos", commonHandlers.ThenFunc(appC.handle((*appContext).venuePhotosHandler)))
+	router.Post("/venues/:id/photos", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).uploadVenuePhotoHandler)))
+	router.Get("/venues/:id/rooms/:room", commonHandlers.ThenFunc(appC.handle((*appContext).venueRoomHandler)))
+
+	router.Get("/rooms/:id", withStatic(map[string]http.Handler{
+		"compare": commonHandlers.ThenFunc(appC.handle((*appContext).compareRoomsHandler)),
+	}, commonHandlers.ThenFunc(appC.handle((*appContext).roomHandler))))
+	router.Patch("/rooms/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), mergePatchHandler(appC, roomPatchBase), schemaHandler("room"), bodyHandler(Room{}), validateHandler).ThenFunc(appC.handle((*appContext).updateRoomHandler)))
+	router.Delete("/rooms/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteRoomHandler)))
+	router.Post("/rooms/:id/book-now", commonHandlers.Append(requireUser(appC), schemaHandler("book_now"), bodyHandler(BookNowRequest{})).ThenFunc(appC.handle((*appContext).bookNowHandler)))
+	router.Get("/rooms/:id/photos", commonHandlers.ThenFunc(appC.handle((*appContext).roomPhotosHandler)))
+	router.Post("/rooms/:id/photos", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).uploadRoomPhotoHandler)))
+	router.Get("/photos/:id/image", commonHandlers.ThenFunc(appC.handle((*appContext).photoImageHandler)))
+	router.Get("/photos/:id/thumbnail", commonHandlers.ThenFunc(appC.handle((*appContext).photoThumbnailHandler)))
+	router.Delete("/photos/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deletePhotoHandler)))
+	router.Get("/rooms", commonHandlers.ThenFunc(appC.handle((*appContext).roomsHandler)))
+	router.Post("/rooms", commonHandlers.Append(requireRole(appC, RoleAdmin), idempotencyHandler(appC), schemaHandler("room"), bodyHandler(Room{}), validateHandler).ThenFunc(appC.handle((*appContext).createRoomHandler)))
+	router.Post("/rooms/:id", withStatic(map[string]http.Handler{
+		"suggest": commonHandlers.Append(requireUser(appC), schemaHandler("room_suggestion"), bodyHandler(RoomSuggestionRequest{})).ThenFunc(appC.handle((*appContext).suggestRoomsHandler)),
+	}, http.NotFoundHandler()))
+
+	router.Get("/events/:id", withStatic(map[string]http.Handler{
+		"search": commonHandlers.ThenFunc(appC.handle((*appContext).searchEventsHandler)),
+		"export": commonHandlers.ThenFunc(appC.handle((*appContext).exportEventsHandler)),
+	}, commonHandlers.Append(guestHandler(appC, GuestView), deprecationHandler(appC, "event_date_fields")).ThenFunc(appC.handle((*appContext).eventHandler))))
+	router.Patch("/events/:id", commonHandlers.Append(requireUser(appC), deprecationHandler(appC, "event_date_fields"), mergePatchHandler(appC, eventPatchBase), schemaHandler("event"), bodyHandler(EventResponse{}), validateHandler).ThenFunc(appC.handle((*appContext).updateEventHandler)))
+	router.Delete("/events/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).deleteEventHandler)))
+	router.Post("/events/:id/cancel", commonHandlers.Append(requireUser(appC), schemaHandler("event_cancellation"), bodyHandler(EventCancellation{})).ThenFunc(appC.handle((*appContext).cancelEventHandler)))
+	router.Post("/events/:id/extend", commonHandlers.Append(requireUser(appC), schemaHandler("event_extension"), bodyHandler(EventExtension{})).ThenFunc(appC.handle((*appContext).extendEventHandler)))
+	router.Post("/events/:id/end", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).endEventHandler)))
+	router.Post("/events", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), deprecationHandler(appC, "event_date_fields"), schemaHandler("event"), bodyHandler(EventResponse{}), validateHandler).ThenFunc(appC.handle((*appContext).createEventHandler)))
+	router.Get("/events", commonHandlers.ThenFunc(appC.handle((*appContext).eventsHandler)))
+	router.Post("/events/:id", withStatic(map[string]http.Handler{
+		"import": commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).importEventsHandler)),
+	}, http.NotFoundHandler()))
+	router.Get("/event-imports/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).eventImportHandler)))
+	router.Post("/event-imports/:id/confirm", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).confirmEventImportHandler)))
+
+	router.Get("/rooms/:id/booking-link", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).roomBookingLinkHandler)))
+	router.Put("/rooms/:id/booking-link", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("booking_link"), bodyHandler(BookingLink{})).ThenFunc(appC.handle((*appContext).updateRoomBookingLinkHandler)))
+	router.Delete("/rooms/:id/booking-link", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteRoomBookingLinkHandler)))
+	router.Get("/book/:token", commonHandlers.Append(bookingLinkHandler(appC)).ThenFunc(appC.handle((*appContext).bookingPageHandler)))
+	router.Post("/book/:token", commonHandlers.Append(bookingLinkHandler(appC), schemaHandler("booking_request"), bodyHandler(BookingRequest{})).ThenFunc(appC.handle((*appContext).bookThroughLinkHandler)))
+
+	router.Post("/rooms/:id/events", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), deprecationHandler(appC, "event_date_fields"), roomLocationHandler(appC), schemaHandler("event"), bodyHandler(EventResponse{}), validateHandler).ThenFunc(appC.handle((*appContext).createEventHandler)))
+	router.Get("/venues/:id/events", commonHandlers.ThenFunc(appC.handle((*appContext).venueEventsHandler)))
+	router.Get("/venues/:id/presence", commonHandlers.ThenFunc(appC.handle((*appContext).venuePresenceHandler)))
+
+	router.Post("/integrations/inbound/:source", commonHandlers.Append(signatureHandler, schemaHandler("inbound_booking"), bodyHandler(InboundBooking{})).ThenFunc(appC.handle((*appContext).inboundBookingHandler)))
+	router.Post("/integrations/msgraph/notifications", commonHandlers.ThenFunc(appC.handle((*appContext).graphNotificationsHandler)))
+
+	router.Get("/equipment/:id/availability", commonHandlers.ThenFunc(appC.handle((*appContext).equipmentAvailabilityHandler)))
+	router.Get("/equipment/:id", commonHandlers.ThenFunc(appC.handle((*appContext).equipmentHandler)))
+	router.Patch("/equipment/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("equipment"), bodyHandler(Equipment{})).ThenFunc(appC.handle((*appContext).updateEquipmentHandler)))
+	router.Delete("/equipment/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteEquipmentHandler)))
+	router.Get("/equipment", commonHandlers.ThenFunc(appC.handle((*appContext).equipmentListHandler)))
+	router.Post("/equipment", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("equipment"), bodyHandler(Equipment{})).ThenFunc(appC.handle((*appContext).createEquipmentHandler)))
+
+	router.Post("/events/:id/attachments", commonHandlers.ThenFunc(appC.handle((*appContext).uploadEventAttachmentHandler)))
+	router.Get("/events/:id/attachments", commonHandlers.ThenFunc(appC.handle((*appContext).eventAttachmentsHandler)))
+	router.Get("/attachments/:id/download", commonHandlers.ThenFunc(appC.handle((*appContext).downloadAttachmentHandler)))
+	router.Get("/attachments/:id", commonHandlers.ThenFunc(appC.handle((*appContext).attachmentHandler)))
+
+	router.Get("/users/:user", commonHandlers.ThenFunc(appC.handle((*appContext).userHandler)))
+	router.Put("/users/:user", commonHandlers.Append(requireUser(appC), schemaHandler("user"), bodyHandler(User{})).ThenFunc(appC.handle((*appContext).updateUserHandler)))
+	router.Get("/users", commonHandlers.ThenFunc(appC.handle((*appContext).usersHandler)))
+	router.Get("/locale", commonHandlers.ThenFunc(appC.handle((*appContext).localeHandler)))
+	router.Get("/sync", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).syncHandler)))
+	router.Get("/me/events", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).myEventsHandler)))
+	router.Get("/users/:user/events", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).userEventsHandler)))
+	router.Get("/me/limits", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).myLimitsHandler)))
+	router.Get("/users/:user/reliability", commonHandlers.ThenFunc(appC.handle((*appContext).userReliabilityHandler)))
+	router.Post("/events/:id/approve", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).approveEventHandler)))
+	router.Get("/admin/approvals", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).pendingApprovalsHandler)))
+	router.Get("/teams", commonHandlers.ThenFunc(appC.handle((*appContext).teamCapsHandler)))
+	router.Put("/teams/:team/cap", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("team_cap"), bodyHandler(TeamCap{})).ThenFunc(appC.handle((*appContext).updateTeamCapHandler)))
+	router.Post("/events/:id/feedback", commonHandlers.Append(schemaHandler("feedback"), bodyHandler(Feedback{})).ThenFunc(appC.handle((*appContext).createFeedbackHandler)))
+	router.Get("/rooms/:id/feedback/summary", commonHandlers.ThenFunc(appC.handle((*appContext).roomFeedbackSummaryHandler)))
+	router.Get("/reports/maintenance", commonHandlers.ThenFunc(appC.handle((*appContext).maintenanceReportHandler)))
+	router.Get("/reports/fairness", commonHandlers.ThenFunc(appC.handle((*appContext).fairnessReportHandler)))
+	router.Get("/reports/adoption", commonHandlers.ThenFunc(appC.handle((*appContext).adoptionReportHandler)))
+	router.Get("/analytics/rooms/:id/utilization", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).roomUtilizationHandler)))
+	router.Get("/analytics/venues/:id/utilization", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).venueUtilizationHandler)))
+	router.Get("/users/:user/working-hours", commonHandlers.ThenFunc(appC.handle((*appContext).workingHoursHandler)))
+	router.Put("/users/:user/working-hours", commonHandlers.Append(requireUser(appC), schemaHandler("working_hours"), bodyHandler(WorkingHours{})).ThenFunc(appC.handle((*appContext).updateWorkingHoursHandler)))
+	router.Get("/find-a-time", commonHandlers.ThenFunc(appC.handle((*appContext).findTimeHandler)))
+	router.Post("/freebusy", commonHandlers.Append(requireUser(appC), schemaHandler("free_busy"), bodyHandler(FreeBusyRequest{})).ThenFunc(appC.handle((*appContext).freeBusyHandler)))
+	router.Get("/users/:user/schedule-check", commonHandlers.ThenFunc(appC.handle((*appContext).scheduleCheckHandler)))
+	router.Post("/checkin/code", commonHandlers.Append(schemaHandler("check_in"), bodyHandler(CheckInRequest{})).ThenFunc(appC.handle((*appContext).checkInCodeHandler)))
+	router.Get("/admin/bumped-events", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).bumpedEventsHandler)))
+	router.Get("/travel-times", commonHandlers.ThenFunc(appC.handle((*appContext).travelTimesHandler)))
+	router.Put("/travel-times", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("travel_time"), bodyHandler(TravelTime{})).ThenFunc(appC.handle((*appContext).updateTravelTimeHandler)))
+
+	router.Post("/admin/venues/onboard", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("venue_onboarding"), bodyHandler(VenueOnboarding{})).ThenFunc(appC.handle((*appContext).onboardVenueHandler)))
+
+	router.Get("/event-series/:id/occurrences", commonHandlers.ThenFunc(appC.handle((*appContext).seriesOccurrencesHandler)))
+	router.Get("/event-series/:id", commonHandlers.ThenFunc(appC.handle((*appContext).eventSeriesHandler)))
+	router.Patch("/event-series/:id", commonHandlers.Append(requireUser(appC), schemaHandler("event_series"), bodyHandler(EventSeries{})).ThenFunc(appC.handle((*appContext).updateEventSeriesHandler)))
+	router.Delete("/event-series/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).deleteEventSeriesHandler)))
+	router.Post("/event-series", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), schemaHandler("event_series"), bodyHandler(EventSeries{})).ThenFunc(appC.handle((*appContext).createEventSeriesHandler)))
+
+	router.Get("/event-groups/:id", commonHandlers.ThenFunc(appC.handle((*appContext).eventGroupHandler)))
+	router.Patch("/event-groups/:id", commonHandlers.Append(requireUser(appC), schemaHandler("event_group"), bodyHandler(EventGroup{})).ThenFunc(appC.handle((*appContext).updateEventGroupHandler)))
+	router.Delete("/event-groups/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).deleteEventGroupHandler)))
+	router.Post("/event-groups/:id/reschedule", commonHandlers.Append(requireUser(appC), schemaHandler("event_group_reschedule"), bodyHandler(EventGroupReschedule{})).ThenFunc(appC.handle((*appContext).rescheduleEventGroupHandler)))
+	router.Post("/event-groups/:id/cancel", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).cancelEventGroupHandler)))
+	router.Get("/event-groups", commonHandlers.ThenFunc(appC.handle((*appContext).eventGroupsHandler)))
+	router.Post("/event-groups", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), schemaHandler("event_group"), bodyHandler(EventGroup{})).ThenFunc(appC.handle((*appContext).createEventGroupHandler)))
+
+	router.Get("/rooms/:id/panel", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).roomPanelHandler)))
+	router.Post("/rooms/:id/panel/book", commonHandlers.Append(requireUser(appC), requirePanel, schemaHandler("panel_action"), bodyHandler(PanelAction{})).ThenFunc(appC.handle((*appContext).panelBookHandler)))
+	router.Post("/rooms/:id/panel/extend", commonHandlers.Append(requireUser(appC), requirePanel, schemaHandler("panel_action"), bodyHandler(PanelAction{})).ThenFunc(appC.handle((*appContext).panelExtendHandler)))
+	router.Post("/rooms/:id/panel/end", commonHandlers.Append(requireUser(appC), requirePanel).ThenFunc(appC.handle((*appContext).panelEndHandler)))
+	router.Get("/rooms/:id/panel/content", commonHandlers.ThenFunc(appC.handle((*appContext).roomPanelContentHandler)))
+	router.Get("/venues/:id/panel/content", commonHandlers.ThenFunc(appC.handle((*appContext).venuePanelContentHandler)))
+	router.Post("/venues/:id/panel/content", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("panel_content"), bodyHandler(PanelContent{})).ThenFunc(appC.handle((*appContext).createPanelContentHandler)))
+	router.Patch("/panel/content/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("panel_content"), bodyHandler(PanelContent{})).ThenFunc(appC.handle((*appContext).updatePanelContentHandler)))
+	router.Delete("/panel/content/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deletePanelContentHandler)))
+	router.Get("/venues/:id/announcements", commonHandlers.ThenFunc(appC.handle((*appContext).venueAnnouncementsHandler)))
+	router.Post("/venues/:id/announcements", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("announcement"), bodyHandler(Announcement{})).ThenFunc(appC.handle((*appContext).createAnnouncementHandler)))
+	router.Patch("/announcements/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("announcement"), bodyHandler(Announcement{})).ThenFunc(appC.handle((*appContext).updateAnnouncementHandler)))
+	router.Delete("/announcements/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteAnnouncementHandler)))
+
+	router.Get("/parking/spots/:id", commonHandlers.ThenFunc(appC.handle((*appContext).parkingSpotHandler)))
+	router.Patch("/parking/spots/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("parking_spot"), bodyHandler(ParkingSpot{})).ThenFunc(appC.handle((*appContext).updateParkingSpotHandler)))
+	router.Delete("/parking/spots/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteParkingSpotHandler)))
+	router.Get("/parking/spots", commonHandlers.ThenFunc(appC.handle((*appContext).parkingSpotsHandler)))
+	router.Post("/parking/spots", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("parking_spot"), bodyHandler(ParkingSpot{})).ThenFunc(appC.handle((*appContext).createParkingSpotHandler)))
+	router.Delete("/parking/reservations/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).deleteParkingReservationHandler)))
+	router.Get("/parking/reservations", commonHandlers.ThenFunc(appC.handle((*appContext).parkingReservationsHandler)))
+	router.Post("/parking/reservations", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), schemaHandler("parking_reservation"), bodyHandler(ParkingReservation{})).ThenFunc(appC.handle((*appContext).createParkingReservationHandler)))
+	router.Get("/events/:id/parking", commonHandlers.ThenFunc(appC.handle((*appContext).eventParkingHandler)))
+	router.Get("/events/:id/ical", commonHandlers.ThenFunc(appC.handle((*appContext).eventICalHandler)))
+	router.Post("/events/:id/guest-tokens", commonHandlers.Append(requireUser(appC), schemaHandler("guest_token"), bodyHandler(GuestToken{})).ThenFunc(appC.handle((*appContext).createGuestTokenHandler)))
+	router.Delete("/guest-tokens/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).revokeGuestTokenHandler)))
+	router.Post("/events/:id/rsvp", commonHandlers.Append(guestHandler(appC, GuestRSVP), requireUser(appC), schemaHandler("rsvp"), bodyHandler(RSVPRequest{})).ThenFunc(appC.handle((*appContext).rsvpEventHandler)))
+	router.Post("/events/:id/check-in", commonHandlers.Append(guestHandler(appC, GuestCheckIn), requireUser(appC)).ThenFunc(appC.handle((*appContext).checkInEventHandler)))
+	router.Get("/rooms/:id/calendar.ics", commonHandlers.ThenFunc(appC.handle((*appContext).roomCalendarHandler)))
+
+	router.Get("/floors/:id/map", commonHandlers.ThenFunc(appC.handle((*appContext).floorMapHandler)))
+	router.Put("/floors/:id/plan", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).uploadFloorPlanHandler)))
+	router.Get("/floors/:id/plan", commonHandlers.ThenFunc(appC.handle((*appContext).floorPlanHandler)))
+	router.Delete("/floors/:id/plan", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteFloorPlanHandler)))
+	router.Get("/floors/:id/available", commonHandlers.ThenFunc(appC.handle((*appContext).availableDesksHandler)))
+	router.Get("/floors/:id/neighborhoods", commonHandlers.ThenFunc(appC.handle((*appContext).neighborhoodsHandler)))
+	router.Post("/floors/:id/neighborhoods", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("neighborhood"), bodyHandler(Neighborhood{})).ThenFunc(appC.handle((*appContext).createNeighborhoodHandler)))
+	router.Get("/floors/:id/desks", commonHandlers.ThenFunc(appC.handle((*appContext).desksHandler)))
+	router.Post("/floors/:id/desks", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("desk"), bodyHandler(Desk{})).ThenFunc(appC.handle((*appContext).createDeskHandler)))
+	router.Get("/floors/:id", commonHandlers.ThenFunc(appC.handle((*appContext).floorHandler)))
+	router.Patch("/floors/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("floor"), bodyHandler(Floor{})).ThenFunc(appC.handle((*appContext).updateFloorHandler)))
+	router.Delete("/floors/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteFloorHandler)))
+	router.Get("/floors", commonHandlers.ThenFunc(appC.handle((*appContext).floorsHandler)))
+	router.Post("/floors", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("floor"), bodyHandler(Floor{})).ThenFunc(appC.handle((*appContext).createFloorHandler)))
+	router.Patch("/neighborhoods/:id", commonHandlers.Append(schemaHandler("neighborhood"), bodyHandler(Neighborhood{})).ThenFunc(appC.handle((*appContext).updateNeighborhoodHandler)))
+	router.Delete("/neighborhoods/:id", commonHandlers.ThenFunc(appC.handle((*appContext).deleteNeighborhoodHandler)))
+	router.Get("/desks/:id", commonHandlers.ThenFunc(appC.handle((*appContext).deskHandler)))
+	router.Patch("/desks/:id", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("desk"), bodyHandler(Desk{})).ThenFunc(appC.handle((*appContext).updateDeskHandler)))
+	router.Delete("/desks/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteDeskHandler)))
+	router.Post("/desks/:id/bookings", commonHandlers.Append(requireUser(appC), idempotencyHandler(appC), schemaHandler("desk_booking"), bodyHandler(DeskBooking{})).ThenFunc(appC.handle((*appContext).createDeskBookingHandler)))
+	router.Get("/desk-bookings", commonHandlers.ThenFunc(appC.handle((*appContext).deskBookingsHandler)))
+	router.Delete("/desk-bookings/:id", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).deleteDeskBookingHandler)))
+
+	router.Get("/examples", commonHandlers.ThenFunc(appC.handle((*appContext).examplesHandler)))
+	router.Get("/examples/:resource", commonHandlers.ThenFunc(appC.handle((*appContext).exampleHandler)))
+	router.Post("/venues/:id/restore", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).restoreVenueHandler)))
+	router.Post("/rooms/:id/restore", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).restoreRoomHandler)))
+	router.Post("/events/:id/restore", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).restoreEventHandler)))
+	router.Get("/audit-logs", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).auditLogsHandler)))
+	router.Get("/changes", commonHandlers.Append(requireUser(appC)).ThenFunc(appC.handle((*appContext).changesHandler)))
+	router.Get("/graphql", commonHandlers.ThenFunc(appC.handle(graphqlHandler(router))))
+	router.Post("/graphql", commonHandlers.Append(schemaHandler("graphql"), bodyHandler(GraphQLRequest{})).ThenFunc(appC.handle(graphqlHandler(router))))
+	router.Get("/ws", alice.New(loggingHandler, recoverHandler, apiKeyHandler(appC), requireUser(appC)).ThenFunc(liveHandler))
+
+	router.Get("/admin/api-keys", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).apiKeysHandler)))
+	router.Post("/admin/api-keys", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("api_key"), bodyHandler(APIKey{})).ThenFunc(appC.handle((*appContext).createAPIKeyHandler)))
+	router.Delete("/admin/api-keys/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).revokeAPIKeyHandler)))
+	router.Get("/admin/webhooks", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).webhooksHandler)))
+	router.Post("/admin/webhooks", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("webhook"), bodyHandler(Webhook{})).ThenFunc(appC.handle((*appContext).createWebhookHandler)))
+	router.Delete("/admin/webhooks/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deleteWebhookHandler)))
+	router.Get("/admin/webhooks/:id/deliveries", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).webhookDeliveriesHandler)))
+	router.Get("/admin/organizations", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).organizationsHandler)))
+	router.Post("/admin/organizations", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("organization"), bodyHandler(Organization{})).ThenFunc(appC.handle((*appContext).createOrganizationHandler)))
+	router.Get("/admin/organizations/:id/members", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).organizationMembersHandler)))
+	router.Put("/admin/organizations/:id/members/:user", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).addOrganizationMemberHandler)))
+	router.Delete("/admin/organizations/:id/members/:user", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).removeOrganizationMemberHandler)))
+	router.Get("/admin/deprecations", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).deprecationUsageHandler)))
+	router.Get("/admin/audit/export", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).auditExportHandler)))
+	router.Get("/admin/errors/summary", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).errorSummaryHandler)))
+	router.Get("/admin/health/history", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).healthHistoryHandler)))
+	router.Get("/admin/locations/backfills", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).locationBackfillsHandler)))
+	router.Post("/admin/locations/backfills", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).createLocationBackfillHandler)))
+	router.Get("/admin/locations/backfills/:id", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).locationBackfillHandler)))
+	router.Get("/admin/locations/report", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).locationReportHandler)))
+	if chaos.enabled {
+		router.Get("/admin/chaos", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).chaosSettingsHandler)))
+		router.Put("/admin/chaos", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("chaos"), bodyHandler(ChaosSettings{})).ThenFunc(appC.handle((*appContext).updateChaosHandler)))
+		router.Delete("/admin/chaos", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).resetChaosHandler)))
+		router.Post("/admin/chaos/jobs/:job", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).runChaosJobHandler)))
+	}
+	router.Get("/admin/database/failovers", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).failoverStatsHandler)))
+	router.Get("/admin/legal-holds", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).legalHoldsHandler)))
+	router.Post("/admin/legal-holds", commonHandlers.Append(requireRole(appC, RoleAdmin), schemaHandler("legal_hold"), bodyHandler(LegalHold{})).ThenFunc(appC.handle((*appContext).createLegalHoldHandler)))
+	router.Post("/admin/legal-holds/:id/lift", commonHandlers.Append(requireRole(appC, RoleAdmin)).ThenFunc(appC.handle((*appContext).liftLegalHoldHandler)))
+	router.Get("/healthz", alice.New(loggingHandler, recoverHandler).ThenFunc(healthzHandler))
+	router.Get("/readyz", commonHandlers.ThenFunc(appC.handle((*appContext).readyHandler)))
+
+	router.Get("/schemas/:name", commonHandlers.ThenFunc(appC.handle((*appContext).schemaDocHandler)))
+	router.Get("/schemas", commonHandlers.ThenFunc(appC.handle((*appContext).schemaDocsHandler)))
+	router.Get("/openapi.json", alice.New(loggingHandler, recoverHandler).ThenFunc(openAPIHandler(router)))
+	router.Get("/docs", alice.New(loggingHandler, recoverHandler).ThenFunc(docsHandler))
+
+	return router
+}
+
+func main() {
+	gotenv.Load()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defaultLocation, _ = loadZone(cfg.TimeZone)
+	chaos.enabled = cfg.Features.Chaos
+	rateLimits.limit = cfg.RateLimit
+
+	session, err := storage.Dial(cfg.Mongo)
+	if err != nil {
+		panic(err)
+	}
+	storage.ScopeByOrg(orgCollections...)
+	ensureIndexes(session.DB(cfg.Database))
+	if err := checkExamples(); err != nil {
+		panic(err)
+	}
+
+	// Index
+	repos, err := openRepositories(cfg.DatabaseURL)
+	if err != nil {
+		panic(err)
+	}
+	appC := newAppContext(session.DB(cfg.Database), repos)
+	appC.config = cfg
+	if len(os.Args) > 1 {
+		runCommand(appC, os.Args[1:])
+		return
+	}
+	if _, err := migrations.Up(appC.db); err != nil {
+		panic(err)
+	}
+	go appC.watchHealth()
+	go appC.watchNoShows()
+	go appC.watchReleases()
+	go appC.watchStandby()
+	notifier = startNotifier(cfg.Mail)
+	slack = startSlack(cfg.Slack)
+	msgraph = startMSGraph(cfg.MSGraph)
+	go appC.watchGraphSubscriptions()
+	go appC.watchWebhooks()
+	go appC.watchReminders()
+	router := routes(appC)
+	if err := checkSpec(router); err != nil {
+		panic(err)
+	}
 
-	port := os.Getenv("PORT")
-	msg := fmt.Sprintf("Listening at port %s", port)
-	msgport := fmt.Sprintf(":%s", port)
+	msg := fmt.Sprintf("Listening at port %s", cfg.Port)
+	msgport := fmt.Sprintf(":%s", cfg.Port)
 
-	if os.Getenv("ENV") == "development" || os.Getenv("ENV") == "staging" {
+	if cfg.Env == "development" || cfg.Env == "staging" {
 		log.Println(msg)
 	}
-	log.Fatal(http.ListenAndServe(msgport, router))
+	serve(newServer(msgport, router), session)
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Route metrics
+//
+// Every routed request is counted per route (method and path pattern) and
+// carries an X-Request-ID, taken from the request or generated. Responses
+// with a 5xx status count against the route's error budget:
+//
+//	ERROR_BUDGET  allowed share of failing requests per route (default 0.01)
+//
+// The last errorSampleSize 4xx/5xx responses are kept with their request ID,
+// error code and, for panics, the recovered value. GET /admin/errors/summary
+// lists routes by how much of their budget they burnt since the process
+// started, with the recent samples.
+const errorSampleSize = 200
+
+type RouteStats struct {
+	Route        string  `json:"route"`
+	Requests     int     `json:"requests"`
+	ClientErrors int     `json:"client_errors"`
+	ServerErrors int     `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	BudgetUsed   float64 `json:"budget_used"`
+}
+
+type ErrorSample struct {
+	RequestId string    `json:"request_id"`
+	Route     string    `json:"route"`
+	Status    int       `json:"status"`
+	Code      string    `json:"code"`
+	Panic     string    `json:"panic,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+type ErrorSummary struct {
+	Since   time.Time     `json:"since"`
+	Budget  float64       `json:"budget"`
+	Routes  []RouteStats  `json:"routes"`
+	Samples []ErrorSample `json:"samples"`
+}
+
+type routeMetrics struct {
+	sync.Mutex
+	since   time.Time
+	routes  map[string]*RouteStats
+	samples []ErrorSample
+}
+
+var metrics = &routeMetrics{since: time.Now(), routes: map[string]*RouteStats{}}
+
+func (m *routeMetrics) Record(route string, rec *routeRecorder) {
+	m.Lock()
+	defer m.Unlock()
+
+	stats := m.routes[route]
+	if stats == nil {
+		stats = &RouteStats{Route: route}
+		m.routes[route] = stats
+	}
+	stats.Requests++
+
+	if rec.status < 400 {
+		return
+	}
+	if rec.status < 500 {
+		stats.ClientErrors++
+	} else {
+		stats.ServerErrors++
+	}
+
+	m.samples = append(m.samples, ErrorSample{rec.requestId, route, rec.status, rec.code, rec.panic, time.Now()})
+	if len(m.samples) > errorSampleSize {
+		m.samples = m.samples[len(m.samples)-errorSampleSize:]
+	}
+}
+
+func (m *routeMetrics) Summary(budget float64) ErrorSummary {
+	m.Lock()
+	defer m.Unlock()
+
+	summary := ErrorSummary{Since: m.since, Budget: budget, Routes: []RouteStats{}, Samples: []ErrorSample{}}
+	for _, stats := range m.routes {
+		s := *stats
+		s.ErrorRate = float64(s.ServerErrors) / float64(s.Requests)
+		s.BudgetUsed = s.ErrorRate / budget
+		summary.Routes = append(summary.Routes, s)
+	}
+	sort.Slice(summary.Routes, func(i, j int) bool {
+		if summary.Routes[i].BudgetUsed != summary.Routes[j].BudgetUsed {
+			return summary.Routes[i].BudgetUsed > summary.Routes[j].BudgetUsed
+		}
+		return summary.Routes[i].Route < summary.Routes[j].Route
+	})
+
+	// Newest first.
+	for i := len(m.samples) - 1; i >= 0; i-- {
+		summary.Samples = append(summary.Samples, m.samples[i])
+	}
+
+	return summary
+}
+
+func errorBudget() float64 {
+	if v, err := strconv.ParseFloat(setting("ERROR_BUDGET"), 64); err == nil && v > 0 {
+		return v
+	}
+
+	return 0.01
+}
+
+// routeRecorder captures what a handler answered. WriteError and
+// recoverHandler fill in code and panic when they see one.
+type routeRecorder struct {
+	http.ResponseWriter
+	requestId string
+	status    int
+	code      string
+	panic     string
+}
+
+func (rec *routeRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (rec *routeRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// RecordError keeps the id of the error answered with, see
+// httpapi.ErrorRecorder.
+func (rec *routeRecorder) RecordError(id string) {
+	rec.code = id
+}
+
+func newRequestId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Metrics Handlers
+func (c *appContext) errorSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	WriteSuccess(w, http.StatusOK, metrics.Summary(errorBudget()))
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/oauth2/microsoft"
+)
+
+// Microsoft Graph
+//
+// Bookings are pushed to Outlook calendars through Microsoft Graph, like they
+// are to Google Calendar, once an Azure AD app with the Calendars.ReadWrite
+// application permission is configured:
+//
+//	MSGRAPH_TENANT_ID         the directory of the app
+//	MSGRAPH_CLIENT_ID         its application id
+//	MSGRAPH_CLIENT_SECRET     one of its client secrets
+//	MSGRAPH_MAILBOX           the mailbox bookings go to, their owner's when unset
+//	MSGRAPH_TEAMS             "true" to attach a Teams meeting to each
+//	MSGRAPH_NOTIFICATION_URL  the public URL of
+//	                          POST /integrations/msgraph/notifications
+//	MSGRAPH_CLIENT_STATE      the secret Graph sends back with notifications
+//
+// Each booking created, recurring ones aside, becomes an Outlook event in the
+// background and the two are kept together in msgraph_events. With a
+// notification URL, the calendars written to are subscribed to, the
+// subscriptions renewed before they lapse, and a booking whose Outlook event
+// is cancelled or deleted is cancelled too, see cancellation.go.
+const (
+	msgraphBaseURL         = "https://graph.microsoft.com/v1.0"
+	msgraphTimeout         = 30 * time.Second
+	msgraphSubscriptionTTL = 70 * time.Hour
+	msgraphRenewInterval   = time.Hour
+	msgraphTimeLayout      = "2006-01-02T15:04:05"
+	msgraphCancelledBy     = "outlook"
+	msgraphCancelReason    = "Cancelled in Outlook."
+)
+
+var errGraphNotFound = errors.New("msgraph: not found")
+
+// MSGraph calls Microsoft Graph as the configured app. A nil MSGraph pushes
+// nothing.
+type MSGraph struct {
+	client          *http.Client
+	base            string
+	mailbox         string
+	teams           bool
+	notificationURL string
+	clientState     string
+}
+
+var msgraph *MSGraph
+
+// GraphEvent is the Outlook event of a booking, by the booking's id.
+type GraphEvent struct {
+	Id        storage.ObjectId `bson:"_id"`
+	GraphId   string
+	Mailbox   string
+	JoinURL   string
+	CreatedAt time.Time
+}
+
+// GraphSubscription is a subscription to the events of a mailbox, by its
+// Graph id.
+type GraphSubscription struct {
+	Id        string `bson:"_id"`
+	Mailbox   string
+	ExpiresAt time.Time
+}
+
+type graphBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type graphTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphLocation struct {
+	DisplayName string `json:"displayName"`
+}
+
+type graphEmail struct {
+	Address string `json:"address"`
+}
+
+type graphAttendee struct {
+	EmailAddress graphEmail `json:"emailAddress"`
+	Type         string     `json:"type"`
+}
+
+type graphOnlineMeeting struct {
+	JoinURL string `json:"joinUrl"`
+}
+
+type graphEvent struct {
+	Id                    string              `json:"id,omitempty"`
+	Subject               string              `json:"subject,omitempty"`
+	Body                  *graphBody          `json:"body,omitempty"`
+	Start                 *graphTime          `json:"start,omitempty"`
+	End                   *graphTime          `json:"end,omitempty"`
+	Location              *graphLocation      `json:"location,omitempty"`
+	Attendees             []graphAttendee     `json:"attendees,omitempty"`
+	IsOnlineMeeting       bool                `json:"isOnlineMeeting,omitempty"`
+	OnlineMeetingProvider string              `json:"onlineMeetingProvider,omitempty"`
+	OnlineMeeting         *graphOnlineMeeting `json:"onlineMeeting,omitempty"`
+	TransactionId         string              `json:"transactionId,omitempty"`
+	IsCancelled           bool                `json:"isCancelled,omitempty"`
+}
+
+type graphSubscription struct {
+	Id                 string    `json:"id,omitempty"`
+	ChangeType         string    `json:"changeType,omitempty"`
+	NotificationURL    string    `json:"notificationUrl,omitempty"`
+	Resource           string    `json:"resource,omitempty"`
+	ExpirationDateTime time.Time `json:"expirationDateTime"`
+	ClientState        string    `json:"clientState,omitempty"`
+}
+
+type graphNotification struct {
+	SubscriptionId string `json:"subscriptionId"`
+	ClientState    string `json:"clientState"`
+	ChangeType     string `json:"changeType"`
+	ResourceData   struct {
+		Id string `json:"id"`
+	} `json:"resourceData"`
+}
+
+// startMSGraph returns a client of the app of cfg, or nil when there's none.
+func startMSGraph(cfg MSGraphConfig) *MSGraph {
+	if cfg.TenantID == "" || cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil
+	}
+	config := clientcredentials.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		TokenURL:     microsoft.AzureADEndpoint(cfg.TenantID).TokenURL,
+		Scopes:       []string{"https://graph.microsoft.com/.default"},
+	}
+
+	client := config.Client(context.Background())
+	client.Timeout = msgraphTimeout
+	return &MSGraph{
+		client:          client,
+		base:            msgraphBaseURL,
+		mailbox:         cfg.Mailbox,
+		teams:           cfg.Teams,
+		notificationURL: cfg.NotificationURL,
+		clientState:     cfg.ClientState,
+	}
+}
+
+// do sends in as JSON to path and decodes the answer into out, either being
+// nil for none.
+func (g *MSGraph) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, g.base+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return errGraphNotFound
+	}
+	if res.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("msgraph: %s %s: %s: %s", method, path, res.Status, b)
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// mailboxOf returns the mailbox event goes to, none when it has no owner.
+func (g *MSGraph) mailboxOf(event Event) string {
+	if g.mailbox != "" {
+		return g.mailbox
+	}
+
+	return event.Owner
+}
+
+// graphEventOf returns event as an Outlook event, with a Teams meeting when
+// teams is set.
+func graphEventOf(event Event, teams bool) graphEvent {
+	content := event.Description
+	if event.CheckInCode != "" {
+		content = strings.TrimSpace(content + "\n\nCheck-in code: " + event.CheckInCode)
+	}
+	result := graphEvent{
+		Subject:       event.Name,
+		Body:          &graphBody{ContentType: "text", Content: content},
+		Start:         &graphTime{event.StartTime.UTC().Format(msgraphTimeLayout), "UTC"},
+		End:           &graphTime{event.EndTime.UTC().Format(msgraphTimeLayout), "UTC"},
+		Location:      &graphLocation{event.Location},
+		Attendees:     []graphAttendee{},
+		TransactionId: event.Id.Hex(),
+	}
+	for _, guest := range event.Guests {
+		result.Attendees = append(result.Attendees, graphAttendee{graphEmail{guest}, "required"})
+	}
+	if teams {
+		result.IsOnlineMeeting = true
+		result.OnlineMeetingProvider = "teamsForBusiness"
+	}
+
+	return result
+}
+
+// createEvent adds event to the calendar of mailbox and returns it as
+// created.
+func (g *MSGraph) createEvent(ctx context.Context, mailbox string, event graphEvent) (graphEvent, error) {
+	result := graphEvent{}
+	err := g.do(ctx, http.MethodPost, "/users/"+url.PathEscape(mailbox)+"/events", event, &result)
+
+	return result, err
+}
+
+// eventCancelled reports whether the event id of mailbox was cancelled or
+// deleted.
+func (g *MSGraph) eventCancelled(ctx context.Context, mailbox string, id string) (bool, error) {
+	result := graphEvent{}
+	err := g.do(ctx, http.MethodGet, "/users/"+url.PathEscape(mailbox)+"/events/"+url.PathEscape(id)+"?$select=isCancelled", nil, &result)
+	if err == errGraphNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return result.IsCancelled, nil
+}
+
+// acceptsNotification reports whether n carries the configured client state.
+func (g *MSGraph) acceptsNotification(n graphNotification) bool {
+	return g.clientState != "" && hmac.Equal([]byte(n.ClientState), []byte(g.clientState))
+}
+
+// Repo GraphEvent
+type GraphEventRepo struct {
+	coll *storage.Collection
+}
+
+func (r *GraphEventRepo) FindByGraphId(graphId string) (GraphEvent, error) {
+	result := GraphEvent{}
+	err := r.coll.Find(bson.M{"graphid": graphId}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *GraphEventRepo) Save(event GraphEvent) error {
+	_, err := r.coll.UpsertId(event.Id, event)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo GraphSubscription
+type GraphSubscriptionRepo struct {
+	coll *storage.Collection
+}
+
+func (r *GraphSubscriptionRepo) FindByMailbox(mailbox string) (GraphSubscription, error) {
+	result := GraphSubscription{}
+	err := r.coll.Find(bson.M{"mailbox": mailbox}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *GraphSubscriptionRepo) AllExpiringBefore(t time.Time) ([]GraphSubscription, error) {
+	result := []GraphSubscription{}
+	err := r.coll.Find(bson.M{"expiresat": bson.M{"$lt": t}}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *GraphSubscriptionRepo) Save(subscription GraphSubscription) error {
+	_, err := r.coll.UpsertId(subscription.Id, subscription)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GraphSubscriptionRepo) Delete(id string) error {
+	err := r.coll.RemoveId(id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// pushToGraph pushes event, just booked, to Outlook in the background.
+func (c *appContext) pushToGraph(event Event) {
+	if msgraph == nil || event.Recurrence != nil || msgraph.mailboxOf(event) == "" {
+		return
+	}
+
+	bg := c.detach()
+	go func() {
+		defer bg.close()
+		if err := bg.pushGraphEvent(context.Background(), msgraph, event); err != nil {
+			log.Printf("msgraph: pushing %s: %v", event.Id.Hex(), err)
+		}
+	}()
+}
+
+func (c *appContext) pushGraphEvent(ctx context.Context, g *MSGraph, event Event) error {
+	mailbox := g.mailboxOf(event)
+	created, err := g.createEvent(ctx, mailbox, graphEventOf(event, g.teams))
+	if err != nil {
+		return err
+	}
+
+	mapping := GraphEvent{Id: event.Id, GraphId: created.Id, Mailbox: mailbox, CreatedAt: time.Now()}
+	if created.OnlineMeeting != nil {
+		mapping.JoinURL = created.OnlineMeeting.JoinURL
+	}
+	repo := GraphEventRepo{c.db.C("msgraph_events")}
+	if err := repo.Save(mapping); err != nil {
+		return err
+	}
+
+	return c.subscribeGraph(ctx, g, mailbox)
+}
+
+// subscribeGraph subscribes to the changes of the events of mailbox, unless
+// it already is or there's no notification URL.
+func (c *appContext) subscribeGraph(ctx context.Context, g *MSGraph, mailbox string) error {
+	if g.notificationURL == "" {
+		return nil
+	}
+	repo := GraphSubscriptionRepo{c.db.C("msgraph_subscriptions")}
+	_, err := repo.FindByMailbox(mailbox)
+	if err == nil {
+		return nil
+	}
+	if err != storage.ErrNotFound {
+		return err
+	}
+
+	subscription := graphSubscription{
+		ChangeType:         "updated,deleted",
+		NotificationURL:    g.notificationURL,
+		Resource:           "users/" + mailbox + "/events",
+		ExpirationDateTime: time.Now().Add(msgraphSubscriptionTTL).UTC(),
+		ClientState:        g.clientState,
+	}
+	created := graphSubscription{}
+	if err := g.do(ctx, http.MethodPost, "/subscriptions", subscription, &created); err != nil {
+		return err
+	}
+
+	return repo.Save(GraphSubscription{Id: created.Id, Mailbox: mailbox, ExpiresAt: created.ExpirationDateTime})
+}
+
+// renewGraphSubscriptions extends the subscriptions lapsing before the next
+// renewal, subscribing again to the mailboxes of those Graph dropped.
+func (c *appContext) renewGraphSubscriptions(ctx context.Context, g *MSGraph) error {
+	repo := GraphSubscriptionRepo{c.db.C("msgraph_subscriptions")}
+	subscriptions, err := repo.AllExpiringBefore(time.Now().Add(2 * msgraphRenewInterval))
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		renewed := graphSubscription{}
+		patch := graphSubscription{ExpirationDateTime: time.Now().Add(msgraphSubscriptionTTL).UTC()}
+		err := g.do(ctx, http.MethodPatch, "/subscriptions/"+url.PathEscape(subscription.Id), patch, &renewed)
+		if err == errGraphNotFound {
+			if err := repo.Delete(subscription.Id); err != nil {
+				return err
+			}
+			err = c.subscribeGraph(ctx, g, subscription.Mailbox)
+		} else if err == nil {
+			subscription.ExpiresAt = renewed.ExpirationDateTime
+			err = repo.Save(subscription)
+		}
+		if err != nil {
+			log.Printf("msgraph: renewing the subscription of %s: %v", subscription.Mailbox, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *appContext) watchGraphSubscriptions() {
+	if msgraph == nil || msgraph.notificationURL == "" {
+		return
+	}
+
+	for range time.Tick(msgraphRenewInterval) {
+		if err := c.renewGraphSubscriptions(context.Background(), msgraph); err != nil {
+			log.Println("Renewing Graph subscriptions failed:", err)
+		}
+	}
+}
+
+// applyGraphChange cancels the booking of the Outlook event n is about when
+// that event was cancelled or deleted.
+func (c *appContext) applyGraphChange(ctx context.Context, g *MSGraph, n graphNotification) error {
+	repo := GraphEventRepo{c.db.C("msgraph_events")}
+	mapping, err := repo.FindByGraphId(n.ResourceData.Id)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cancelled := n.ChangeType == "deleted"
+	if !cancelled {
+		cancelled, err = g.eventCancelled(ctx, mapping.Mailbox, mapping.GraphId)
+		if err != nil {
+			return err
+		}
+	}
+	if !cancelled {
+		return nil
+	}
+
+	event, err := c.events().Find(ctx, mapping.Id.Hex())
+	if err == ErrDocumentNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.cancelEvent(ctx, event, msgraphCancelledBy, msgraphCancelReason)
+
+	return err
+}
+
+// Microsoft Graph Handlers
+func (c *appContext) graphNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	// Graph checks the notification URL before subscribing by having it
+	// echo a token.
+	if token := r.URL.Query().Get("validationToken"); token != "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(token))
+		return
+	}
+	if msgraph == nil {
+		WriteError(w, ErrNotFound)
+		return
+	}
+
+	var body struct {
+		Value []graphNotification `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteError(w, ErrBadRequest)
+		return
+	}
+	changes := []graphNotification{}
+	for _, n := range body.Value {
+		if !msgraph.acceptsNotification(n) {
+			log.Printf("msgraph: dropping a notification of subscription %s with the wrong client state", n.SubscriptionId)
+			continue
+		}
+		changes = append(changes, n)
+	}
+
+	// Graph wants an answer within seconds, so changes are applied in the
+	// background, across organizations like the other jobs.
+	if len(changes) > 0 {
+		bg := (&appContext{db: c.allOrgs(r), repos: c.repos, config: c.config}).detach()
+		go func() {
+			defer bg.close()
+			for _, n := range changes {
+				if err := bg.applyGraphChange(context.Background(), msgraph, n); err != nil {
+					log.Printf("msgraph: applying a change to %s: %v", n.ResourceData.Id, err)
+				}
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// User schedules
+//
+// GET /me/events lists the events the caller owns or is a guest of, and
+// GET /users/:user/events those of another user, for the user or an admin.
+// Both cover start_time to end_time (this week by default), with recurring
+// events expanded to their occurrences, and filter by ?role=owner or guest
+// and by ?status=, a comma separated list of:
+//
+//	pending_approval  waiting for an admin, see reliability.go
+//	tentative         may be bumped, see overbook.go
+//	checked_in        someone checked in
+//	confirmed         any other
+const (
+	EventPendingApproval = "pending_approval"
+	EventTentative       = "tentative"
+	EventCheckedIn       = "checked_in"
+	EventConfirmed       = "confirmed"
+
+	ParticipantOwner = "owner"
+	ParticipantGuest = "guest"
+)
+
+var (
+	ErrInvalidEventStatus = &Error{"invalid_status", 400, "Bad request", "status must be a comma separated list of pending_approval, tentative, checked_in or confirmed."}
+	ErrInvalidEventRole   = &Error{"invalid_role", 400, "Bad request", "role must be owner or guest."}
+)
+
+var eventStatuses = map[string]bool{EventPendingApproval: true, EventTentative: true, EventCheckedIn: true, EventConfirmed: true}
+
+// eventStatus is where the booking of e stands.
+func eventStatus(e Event) string {
+	switch {
+	case e.CancelledAt != nil:
+		return EventCancelled
+	case e.PendingApproval:
+		return EventPendingApproval
+	case !e.CheckedInAt.IsZero():
+		return EventCheckedIn
+	case e.Tentative:
+		return EventTentative
+	default:
+		return EventConfirmed
+	}
+}
+
+// isGuest reports whether user is invited to e.
+func isGuest(e Event, user string) bool {
+	for _, guest := range e.Guests {
+		if strings.EqualFold(guest, user) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UserEventsFilter selects the events of a user's schedule.
+type UserEventsFilter struct {
+	User     string
+	Role     string
+	Statuses map[string]bool
+}
+
+func parseUserEventsFilter(r *http.Request, user string) (UserEventsFilter, *Error) {
+	q := r.URL.Query()
+	filter := UserEventsFilter{User: user, Role: q.Get("role"), Statuses: map[string]bool{}}
+	if filter.Role != "" && filter.Role != ParticipantOwner && filter.Role != ParticipantGuest {
+		return filter, ErrInvalidEventRole
+	}
+	statuses, ok := parseEventStatuses(q.Get("status"), eventStatuses)
+	if !ok {
+		return filter, ErrInvalidEventStatus
+	}
+	filter.Statuses = statuses
+
+	return filter, nil
+}
+
+// parseEventStatuses parses the comma separated list of statuses value,
+// reporting false when one of them isn't allowed.
+func parseEventStatuses(value string, allowed map[string]bool) (map[string]bool, bool) {
+	statuses := map[string]bool{}
+	for _, status := range strings.Split(value, ",") {
+		if status = strings.TrimSpace(status); status == "" {
+			continue
+		}
+		if !allowed[status] {
+			return statuses, false
+		}
+		statuses[status] = true
+	}
+
+	return statuses, true
+}
+
+func (f UserEventsFilter) matches(event Event) bool {
+	owner := strings.EqualFold(event.Owner, f.User)
+	guest := isGuest(event, f.User)
+	switch f.Role {
+	case ParticipantOwner:
+		if !owner {
+			return false
+		}
+	case ParticipantGuest:
+		if !guest {
+			return false
+		}
+	default:
+		if !owner && !guest {
+			return false
+		}
+	}
+
+	return len(f.Statuses) == 0 || f.Statuses[eventStatus(event)]
+}
+
+// userEvents returns the events and occurrences filter selects within
+// [start_time, end_time).
+func (c *appContext) userEvents(ctx context.Context, filter UserEventsFilter, start_time time.Time, end_time time.Time) ([]Event, error) {
+	repo := c.events()
+	events, err := repo.AllByParticipants(ctx, []string{filter.User}, start_time, end_time)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := repo.AllOccurrences(ctx, "", start_time, end_time, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if event.Recurrence == nil && filter.matches(event) {
+			result = append(result, event)
+		}
+	}
+	for _, occurrence := range occurrences {
+		if !occurrence.StartTime.Before(start_time) && filter.matches(occurrence) {
+			result = append(result, occurrence)
+		}
+	}
+
+	return result, nil
+}
+
+// User Event Handlers
+func (c *appContext) myEventsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userKey).(User)
+	c.writeUserEvents(w, r, user.Email)
+}
+
+func (c *appContext) userEventsHandler(w http.ResponseWriter, r *http.Request) {
+	email := routeParams(r).ByName("user")
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && !strings.EqualFold(user.Email, email) {
+		WriteError(w, ErrForbidden)
+		return
+	}
+
+	c.writeUserEvents(w, r, email)
+}
+
+func (c *appContext) writeUserEvents(w http.ResponseWriter, r *http.Request, email string) {
+	opts, errRes := listOptions(r, "events", eventSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	filter, errRes := parseUserEventsFilter(r, email)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	events, err := c.userEvents(r.Context(), filter, start_time, end_time)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = []string{"starttime"}
+	}
+	sortEvents(events, opts.Sort)
+	total := len(events)
+	lo, hi := opts.Bounds(total)
+	events = events[lo:hi]
+	if renderHTML(r) {
+		events = withRenderedDescriptions(events)
+	}
+	zones.events(events)
+
+	writeList(w, r, opts, total, events)
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Email notifications
+//
+// The owner and guests of an event are emailed when it's created, updated or
+// cancelled, and REMINDER_LEAD before it starts. Handlers only queue the
+// messages; a pool of NOTIFY_WORKERS goroutines sends them through the
+// Mailer picked with MAILER:
+//
+//	smtp      SMTP_ADDR (host:port), with SMTP_USERNAME and SMTP_PASSWORD
+//	          when the server wants them
+//	sendgrid  the SendGrid v3 API with SENDGRID_API_KEY
+//	log       log the messages instead, for local development
+//
+// Messages are sent from MAIL_FROM. When MAILER is unset nothing is sent.
+// REMINDER_LEAD is a duration such as "30m", default 15m; "0" turns reminders
+// off. Each occurrence is reminded once, tracked in the reminders collection.
+const (
+	NotifyCreated   = "created"
+	NotifyUpdated   = "updated"
+	NotifyCancelled = "cancelled"
+	NotifyReminder  = "reminder"
+	NotifyReleased  = "released"
+
+	notifyQueueSize     = 1000
+	notifyAttempts      = 3
+	defaultNotifyPool   = 4
+	reminderInterval    = time.Minute
+	sendGridURL         = "https://api.sendgrid.com/v3/mail/send"
+	defaultMailFrom     = "ivana@localhost"
+	defaultReminderLead = 15 * time.Minute
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var ErrMailerNotConfigured = errors.New("no mailer configured")
+
+func newMailer(cfg MailConfig) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			return nil, fmt.Errorf("SMTP_ADDR must look like host:port")
+		}
+		var auth smtp.Auth
+		if cfg.SMTPUsername != "" {
+			auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+		}
+		return &SMTPMailer{Addr: cfg.SMTPAddr, Auth: auth, From: cfg.From}, nil
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("SENDGRID_API_KEY is required with MAILER=sendgrid")
+		}
+		return &SendGridMailer{APIKey: cfg.SendGridAPIKey, From: cfg.From}, nil
+	case "log":
+		return logMailer{}, nil
+	case "":
+		return nil, ErrMailerNotConfigured
+	}
+
+	return nil, fmt.Errorf("unknown MAILER %q", cfg.Mailer)
+}
+
+type logMailer struct{}
+
+func (logMailer) Send(msg Message) error {
+	log.Printf("mail: to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPMailer sends plain text messages through an SMTP server.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, b.Bytes())
+}
+
+// SendGridMailer sends messages through the SendGrid v3 mail send API.
+type SendGridMailer struct {
+	APIKey string
+	From   string
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+func (m *SendGridMailer) Send(msg Message) error {
+	mail := sendGridMail{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{msg.To}}}},
+		From:             sendGridAddress{m.From},
+		Subject:          msg.Subject,
+		Content:          []sendGridContent{{"text/plain", msg.Body}},
+	}
+
+	b, err := json.Marshal(mail)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", sendGridURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid: %s", res.Status)
+	}
+
+	return nil
+}
+
+// Notifier queues messages for a pool of workers sending them through its
+// mailer. A nil Notifier drops everything.
+type Notifier struct {
+	mailer Mailer
+	jobs   chan Message
+}
+
+var notifier *Notifier
+
+// startNotifier starts the workers of the mailer configured with MAILER, or
+// returns nil when there's none.
+func startNotifier(cfg MailConfig) *Notifier {
+	mailer, err := newMailer(cfg)
+	if err == ErrMailerNotConfigured {
+		return nil
+	}
+	if err != nil {
+		log.Printf("notifications: disabled: %v", err)
+		return nil
+	}
+
+	workers := defaultNotifyPool
+	if n, err := strconv.Atoi(setting("NOTIFY_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+
+	n := &Notifier{mailer: mailer, jobs: make(chan Message, notifyQueueSize)}
+	for i := 0; i < workers; i++ {
+		go n.work()
+	}
+
+	return n
+}
+
+func (n *Notifier) work() {
+	for msg := range n.jobs {
+		var err error
+		for attempt := 1; attempt <= notifyAttempts; attempt++ {
+			if err = n.mailer.Send(msg); err == nil {
+				break
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if err != nil {
+			log.Printf("notifications: sending %q to %s failed: %v", msg.Subject, msg.To, err)
+		}
+	}
+}
+
+// Enqueue queues msg without blocking, dropping it when the queue is full.
+func (n *Notifier) Enqueue(msg Message) {
+	if n == nil {
+		return
+	}
+
+	select {
+	case n.jobs <- msg:
+	default:
+		log.Printf("notifications: queue full, dropping %q to %s", msg.Subject, msg.To)
+	}
+}
+
+// recipients returns the owner and guests of event, each once.
+func recipients(event Event) []string {
+	result := []string{}
+	for _, email := range append([]string{event.Owner}, event.Guests...) {
+		email = strings.TrimSpace(email)
+		if email != "" && !contains(result, email) {
+			result = append(result, email)
+		}
+	}
+
+	return result
+}
+
+func eventMessage(kind string, event Event) (string, string) {
+	loc := defaultLocation
+	subjects := map[string]string{
+		NotifyCreated:   "Invitation: ",
+		NotifyUpdated:   "Updated: ",
+		NotifyCancelled: "Cancelled: ",
+		NotifyReminder:  "Reminder: ",
+		NotifyReleased:  "Released: ",
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n\n", event.Name)
+	fmt.Fprintf(&b, "When:  %s - %s (UTC+7)\n",
+		event.StartTime.In(loc).Format("Mon 2 Jan 2006 15:04"), event.EndTime.In(loc).Format("15:04"))
+	if event.Location != "" {
+		fmt.Fprintf(&b, "Where: %s\n", event.Location)
+	}
+	if event.Owner != "" {
+		fmt.Fprintf(&b, "Owner: %s\n", event.Owner)
+	}
+	if kind == NotifyCancelled {
+		b.WriteString("\nThis event has been cancelled.\n")
+		if event.CancellationReason != "" {
+			fmt.Fprintf(&b, "Reason: %s\n", event.CancellationReason)
+		}
+	} else if kind == NotifyReleased {
+		b.WriteString("\nNobody checked in, so the booking has been cancelled and the room released.\n")
+	} else if event.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", event.Description)
+	}
+
+	return subjects[kind] + event.Name, b.String()
+}
+
+// notifyEvent queues a message of kind about event for its owner and guests.
+func notifyEvent(kind string, event Event) {
+	if notifier == nil {
+		return
+	}
+
+	subject, body := eventMessage(kind, event)
+	for _, to := range recipients(event) {
+		notifier.Enqueue(Message{To: to, Subject: subject, Body: body})
+	}
+}
+
+func reminderLead() time.Duration {
+	if d, err := time.ParseDuration(setting("REMINDER_LEAD")); err == nil && d >= 0 {
+		return d
+	}
+
+	return defaultReminderLead
+}
+
+// Repo Reminder
+type ReminderRepo struct {
+	coll *storage.Collection
+}
+
+// Claim records that the occurrence of eventId at startTime is being
+// reminded of, and reports whether it hadn't been already.
+func (r *ReminderRepo) Claim(eventId storage.ObjectId, startTime time.Time) (bool, error) {
+	selector := bson.M{"eventid": eventId, "starttime": startTime}
+	info, err := r.coll.Upsert(selector, bson.M{"$setOnInsert": bson.M{"remindedat": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+
+	return info.UpsertedId != nil, nil
+}
+
+// sendReminders reminds of the events starting within the reminder lead.
+func (c *appContext) sendReminders(ctx context.Context) error {
+	lead := reminderLead()
+	if lead == 0 || notifier == nil {
+		return nil
+	}
+
+	t := clockNow()
+	repo := c.events()
+	events, err := repo.All(ctx, t, t.Add(lead), false)
+	if err != nil {
+		return err
+	}
+	events = c.withOccurrences(ctx, events, t, t.Add(lead))
+
+	reminders := ReminderRepo{c.db.C("reminders")}
+	for _, event := range events {
+		if !event.StartTime.After(t) || event.PendingApproval {
+			continue
+		}
+		claimed, err := reminders.Claim(event.Id, event.StartTime)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			notifyEvent(NotifyReminder, event)
+		}
+	}
+
+	return nil
+}
+
+func (c *appContext) watchReminders() {
+	for range time.Tick(reminderInterval) {
+		if err := c.sendReminders(context.Background()); err != nil {
+			log.Println("notifications: reminders failed:", err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Venue onboarding
+//
+// POST /admin/venues/onboard creates a whole building from one document: the
+// venue, its floors with their rooms, neighborhoods and desks, the venue's
+// equipment and its parking spots. The document is checked as a whole first
+// and every problem is reported at once. MongoDB gives us no transactions, so
+// everything created is tracked and removed again if a later insert fails.
+type OnboardDesk struct {
+	Desk
+	Neighborhood string `json:"neighborhood,omitempty"`
+}
+
+type OnboardFloor struct {
+	Floor
+	Rooms         []Room         `json:"rooms"`
+	Neighborhoods []Neighborhood `json:"neighborhoods"`
+	Desks         []OnboardDesk  `json:"desks"`
+}
+
+type VenueOnboarding struct {
+	Venue
+	Floors       []OnboardFloor `json:"floors"`
+	Equipment    []Equipment    `json:"equipment"`
+	ParkingSpots []ParkingSpot  `json:"parking_spots"`
+}
+
+// onboarding remembers what has been inserted so it can be undone.
+type onboarding struct {
+	c       *appContext
+	created []onboarded
+}
+
+// onboarded is a created document; entity is its name in the change feed,
+// empty for documents the feed does not cover.
+type onboarded struct {
+	collection string
+	entity     string
+	id         storage.ObjectId
+}
+
+func (o *onboarding) track(collection string, entity string, id storage.ObjectId) {
+	o.created = append(o.created, onboarded{collection, entity, id})
+}
+
+// rollback removes everything tracked, newest first, the venue and rooms
+// from their repos.
+func (o *onboarding) rollback(ctx context.Context) {
+	for i := len(o.created) - 1; i >= 0; i-- {
+		doc := o.created[i]
+		var err error
+		switch doc.collection {
+		case "venues":
+			err = o.c.venues().Remove(ctx, doc.id)
+		case "rooms":
+			err = o.c.rooms().Remove(ctx, doc.id)
+		default:
+			err = o.c.db.C(doc.collection).RemoveId(doc.id)
+		}
+		if err != nil && err != storage.ErrNotFound {
+			panic(err)
+		}
+	}
+}
+
+func onboardingError(field string, msg string) *Error {
+	return &Error{"validation_failed", http.StatusUnprocessableEntity, "Unprocessable Entity", fmt.Sprintf("%s: %s", field, msg)}
+}
+
+// validateOnboarding checks what the schema cannot: uniqueness within the
+// document and against existing venues, and references between its parts.
+func validateOnboarding(ctx context.Context, repo VenueRepository, doc *VenueOnboarding) []*Error {
+	errs := []*Error{}
+
+	if doc.Slug != "" {
+		taken, err := repo.SlugTaken(ctx, doc.Slug, "")
+		if err != nil {
+			panic(err)
+		}
+		if taken {
+			errs = append(errs, onboardingError("slug", "is already in use"))
+		}
+	}
+
+	levels := map[int]bool{}
+	roomSlugs := map[string]bool{}
+	for i, floor := range doc.Floors {
+		path := fmt.Sprintf("floors[%d]", i)
+		if levels[floor.Level] {
+			errs = append(errs, onboardingError(path+".level", "is used by another floor"))
+		}
+		levels[floor.Level] = true
+
+		for j, room := range floor.Rooms {
+			if room.Slug == "" {
+				continue
+			}
+			if roomSlugs[room.Slug] {
+				errs = append(errs, onboardingError(fmt.Sprintf("%s.rooms[%d].slug", path, j), "is used by another room"))
+			}
+			roomSlugs[room.Slug] = true
+		}
+
+		neighborhoods := map[string]bool{}
+		for j, neighborhood := range floor.Neighborhoods {
+			if neighborhoods[neighborhood.Name] {
+				errs = append(errs, onboardingError(fmt.Sprintf("%s.neighborhoods[%d].name", path, j), "is used by another neighborhood"))
+			}
+			neighborhoods[neighborhood.Name] = true
+		}
+
+		labels := map[string]bool{}
+		for j, desk := range floor.Desks {
+			deskPath := fmt.Sprintf("%s.desks[%d]", path, j)
+			if labels[desk.Label] {
+				errs = append(errs, onboardingError(deskPath+".label", "is used by another desk"))
+			}
+			labels[desk.Label] = true
+
+			if desk.Neighborhood != "" && !neighborhoods[desk.Neighborhood] {
+				errs = append(errs, onboardingError(deskPath+".neighborhood", "does not name a neighborhood of the floor"))
+			}
+			if (floor.Width > 0 && desk.X > floor.Width) || (floor.Height > 0 && desk.Y > floor.Height) {
+				errs = append(errs, onboardingError(deskPath, "lies outside the floor"))
+			}
+		}
+	}
+
+	names := map[string]bool{}
+	for i, equipment := range doc.Equipment {
+		if names[equipment.Name] {
+			errs = append(errs, onboardingError(fmt.Sprintf("equipment[%d].name", i), "is used by other equipment"))
+		}
+		names[equipment.Name] = true
+	}
+
+	spots := map[string]bool{}
+	for i, spot := range doc.ParkingSpots {
+		if spots[spot.Label] {
+			errs = append(errs, onboardingError(fmt.Sprintf("parking_spots[%d].label", i), "is used by another spot"))
+		}
+		spots[spot.Label] = true
+	}
+
+	return errs
+}
+
+// create inserts the document, filling in ids and references as it goes.
+func (o *onboarding) create(ctx context.Context, doc *VenueOnboarding) error {
+	// Rooms belong to floors here, never to the venue document itself.
+	doc.Rooms = nil
+
+	venueRepo := o.c.venues()
+	err := assignVenueSlug(ctx, venueRepo, &doc.Venue, nil)
+	if err != nil {
+		return err
+	}
+	err = venueRepo.Create(ctx, &doc.Venue)
+	if err != nil {
+		return err
+	}
+	o.track("venues", "venue", doc.Id)
+	venueId := doc.Id.Hex()
+
+	floorRepo := FloorRepo{o.c.db.C("floors")}
+	roomRepo := o.c.rooms()
+	neighborhoodRepo := NeighborhoodRepo{o.c.db.C("neighborhoods")}
+	deskRepo := DeskRepo{o.c.db.C("desks")}
+	for i := range doc.Floors {
+		floor := &doc.Floors[i]
+		floor.VenueId = venueId
+		err = floorRepo.Create(&floor.Floor)
+		if err != nil {
+			return err
+		}
+		o.track("floors", "floor", floor.Id)
+		floorId := floor.Id.Hex()
+
+		for j := range floor.Rooms {
+			room := &floor.Rooms[j]
+			room.VenueId, room.FloorId = venueId, floorId
+			err = assignRoomSlug(ctx, roomRepo, room, nil)
+			if err != nil {
+				return err
+			}
+			err = roomRepo.Create(ctx, room)
+			if err != nil {
+				return err
+			}
+			o.track("rooms", "room", room.Id)
+		}
+
+		neighborhoodIds := map[string]string{}
+		for j := range floor.Neighborhoods {
+			neighborhood := &floor.Neighborhoods[j]
+			neighborhood.FloorId = floorId
+			err = neighborhoodRepo.Create(neighborhood)
+			if err != nil {
+				return err
+			}
+			o.track("neighborhoods", "", neighborhood.Id)
+			neighborhoodIds[neighborhood.Name] = neighborhood.Id.Hex()
+		}
+
+		for j := range floor.Desks {
+			desk := &floor.Desks[j]
+			desk.FloorId = floorId
+			desk.NeighborhoodId = neighborhoodIds[desk.Neighborhood]
+			err = deskRepo.Create(&desk.Desk)
+			if err != nil {
+				return err
+			}
+			o.track("desks", "desk", desk.Id)
+		}
+	}
+
+	equipmentRepo := EquipmentRepo{o.c.db.C("equipment")}
+	for i := range doc.Equipment {
+		equipment := &doc.Equipment[i]
+		equipment.VenueId = venueId
+		err = equipmentRepo.Create(equipment)
+		if err != nil {
+			return err
+		}
+		o.track("equipment", "equipment", equipment.Id)
+	}
+
+	spotRepo := ParkingSpotRepo{o.c.db.C("parking_spots")}
+	for i := range doc.ParkingSpots {
+		spot := &doc.ParkingSpots[i]
+		spot.VenueId = venueId
+		if spot.Kind == "" {
+			spot.Kind = SpotStandard
+		}
+		err = spotRepo.Create(spot)
+		if err != nil {
+			return err
+		}
+		o.track("parking_spots", "parking_spot", spot.Id)
+	}
+
+	return nil
+}
+
+// Onboarding Handlers
+func (c *appContext) onboardVenueHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*VenueOnboarding)
+	repo := c.venues()
+	if errs := validateOnboarding(r.Context(), repo, body); len(errs) > 0 {
+		WriteErrors(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+
+	o := &onboarding{c: c}
+	err := o.create(r.Context(), body)
+	if err == errSlugTaken {
+		o.rollback(r.Context())
+		WriteError(w, ErrSlugTaken)
+		return
+	}
+	if err != nil {
+		o.rollback(r.Context())
+		panic(err)
+	}
+
+	for _, doc := range o.created {
+		if doc.entity != "" {
+			c.recordChange(doc.entity, doc.id, ChangeCreated)
+		}
+	}
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
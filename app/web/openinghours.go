@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Opening hours
+//
+// A venue can have opening_hours, the times it's open each weekday in its
+// time zone and the holidays it's closed, and booking_rules, how many days
+// ahead and for how many minutes at least and at most its rooms can be
+// booked. A booking of one of its rooms must fit in a single opening day, as
+// must each occurrence of a recurring booking over its first
+// openingHoursHorizon; the advance limit applies to the first occurrence.
+// Venues without them take bookings of any length at any time.
+const openingHoursHorizon = 366 * 24 * time.Hour
+
+var ErrVenueClosed = &Error{"venue_closed", 422, "Unprocessable Entity", "The venue is closed for part of the event."}
+
+func tooFarAheadError(rules BookingRules) *Error {
+	return &Error{
+		"booking_too_far_ahead",
+		http.StatusUnprocessableEntity,
+		"Unprocessable Entity",
+		"Rooms of this venue can be booked at most " + strconv.Itoa(rules.MaxAdvanceDays) + " days ahead.",
+	}
+}
+
+func durationError(rules BookingRules) *Error {
+	detail := "Bookings at this venue last"
+	if rules.MinDuration > 0 {
+		detail += " at least " + strconv.Itoa(rules.MinDuration) + " minutes"
+	}
+	if rules.MinDuration > 0 && rules.MaxDuration > 0 {
+		detail += " and"
+	}
+	if rules.MaxDuration > 0 {
+		detail += " at most " + strconv.Itoa(rules.MaxDuration) + " minutes"
+	}
+
+	return &Error{"invalid_duration", http.StatusUnprocessableEntity, "Unprocessable Entity", detail + "."}
+}
+
+// checkVenueRules verifies event keeps to the opening hours and booking rules
+// of the venue of its room. It returns the error to send to the client, or
+// nil.
+func (c *appContext) checkVenueRules(ctx context.Context, event Event) *Error {
+	if event.LocationID == "" {
+		return nil
+	}
+
+	// checkCapacity tells about locations that aren't rooms.
+	room, err := c.rooms().Find(ctx, event.LocationID)
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return nil
+	}
+	if err != nil {
+		panic(err)
+	}
+	venue, err := c.venues().Find(ctx, room.VenueId)
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return nil
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if rules := venue.BookingRules; rules != nil {
+		duration := event.EndTime.Sub(event.StartTime)
+		if rules.MinDuration > 0 && duration < time.Duration(rules.MinDuration)*time.Minute ||
+			rules.MaxDuration > 0 && duration > time.Duration(rules.MaxDuration)*time.Minute {
+			return durationError(*rules)
+		}
+		if rules.MaxAdvanceDays > 0 && event.StartTime.After(clockNow().AddDate(0, 0, rules.MaxAdvanceDays)) {
+			return tooFarAheadError(*rules)
+		}
+	}
+
+	if hours := venue.OpeningHours; hours != nil {
+		loc, ok := loadZone(venue.TimeZone)
+		if !ok {
+			loc = defaultLocation
+		}
+		for _, occurrence := range event.Occurrences(event.StartTime, event.StartTime.Add(openingHoursHorizon)) {
+			if !hours.Covers(occurrence.StartTime, occurrence.EndTime, loc) {
+				return ErrVenueClosed
+			}
+		}
+	}
+
+	return nil
+}
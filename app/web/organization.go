@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Organizations
+//
+// Venues, rooms, events, the user directory, API keys, guest tokens and
+// everything else the API stores for them, from equipment, floors, desks and
+// parking to the change and audit logs, belong to an organization, see
+// storage.ScopeByOrg. Each request only sees those of its caller's
+// organization: the one of the caller's entry in the user directory, of the
+// API key it sent, of the guest token it holds or of the booking link it
+// follows. Anonymous callers, and users without an organization, are in the
+// default one, where everything stored before organizations is. Documents
+// of another organization answer 404, as if they didn't exist, whatever
+// their id. Background jobs see every organization, and record what they do
+// in the one of the document they do it to, see inOrgOf. Organizations,
+// deprecation usage and the bookkeeping of reminders, standby and Microsoft
+// Graph, which no caller lists, aren't scoped.
+//
+// Admins of the default organization create organizations with POST
+// /admin/organizations; admins of an organization manage its members with
+// PUT and DELETE /admin/organizations/:id/members/:user. A user is a member
+// of one organization at a time; users removed from one are back in the
+// default organization with the user role.
+var orgCollections = []string{
+	"venues", "rooms", "events", "event_series", "users", "api_keys", "guest_tokens", "idempotency_keys", "webhooks",
+	"changes", "counters", "audit_logs", "webhook_deliveries", "location_backfills",
+	"equipment", "floors", "floor_plans", "neighborhoods", "desks", "desk_bookings", "parking_spots", "parking_reservations",
+	"announcements", "panel_content", "attachments", "photos", "booking_links", "event_groups", "event_imports", "bumped_events",
+	"feedback", "reliability", "legal_holds", "team_caps", "travel_times", "working_hours", "sync_devices",
+}
+
+var (
+	ErrOtherOrgMember = &Error{"other_org_member", 409, "Conflict", "The user is a member of another organization."}
+)
+
+type Organization struct {
+	Id        storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string           `json:"name"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Repo Organization
+type OrganizationRepo struct {
+	coll *storage.Collection
+}
+
+func (r *OrganizationRepo) All() ([]Organization, error) {
+	result := []Organization{}
+	err := r.coll.Find(nil).Sort("name").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *OrganizationRepo) Find(id string) (Organization, error) {
+	result := Organization{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.coll.FindId(oid).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *OrganizationRepo) Create(org *Organization) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, org)
+	if err != nil {
+		return err
+	}
+
+	org.Id = id
+
+	return nil
+}
+
+// AllByOrg returns the members of the organization org.
+func (r *UserRepo) AllByOrg(org string) ([]User, error) {
+	result := []User{}
+	err := r.coll.Find(bson.M{"orgid": org}).Sort("email").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// SetOrg makes the user with email a member of the organization org, adding
+// it to the directory if it isn't there. Members of another organization are
+// a storage.ErrConflict.
+func (r *UserRepo) SetOrg(email string, org string) error {
+	_, err := r.coll.Upsert(
+		bson.M{"email": email, "orgid": bson.M{"$in": []interface{}{nil, org}}},
+		bson.M{"$set": bson.M{"email": email, "orgid": org}},
+	)
+	if err != nil {
+		return storage.Error(err)
+	}
+
+	return nil
+}
+
+// RemoveFromOrg moves the user with email from the organization org back to
+// the default one, as a user.
+func (r *UserRepo) RemoveFromOrg(email string, org string) error {
+	err := r.coll.Update(bson.M{"email": email, "orgid": org}, bson.M{
+		"$unset": bson.M{"orgid": ""},
+		"$set":   bson.M{"role": RoleUser},
+	})
+	if err != nil {
+		return storage.Error(err)
+	}
+
+	return nil
+}
+
+// inOrg returns r scoped to the organization org, with the appContext of the
+// request on a session scoped to it.
+func (c *appContext) inOrg(r *http.Request, org string) *http.Request {
+	ctx := storage.WithOrg(r.Context(), org)
+	rc := c.forRequest(r)
+	scoped := &appContext{origin: rc.origin, repos: rc.repos, config: rc.config}
+	if rc.db != nil {
+		scoped.db = rc.db.With(rc.db.Session.WithContext(ctx))
+	}
+
+	return withValue(r.WithContext(ctx), appKey, scoped)
+}
+
+// inOrgOf returns c scoped to the organization org unless it's scoped
+// already, for background jobs recording what they did to a document of org.
+func (c *appContext) inOrgOf(org string) *appContext {
+	if _, ok := c.db.Org(); ok {
+		return c
+	}
+	ctx := storage.WithOrg(context.Background(), org)
+
+	return &appContext{db: c.db.With(c.db.Session.WithContext(ctx)), origin: c.origin, repos: c.repos, config: c.config}
+}
+
+// allOrgs returns the database as seen from every organization, for managing
+// them.
+func (c *appContext) allOrgs(r *http.Request) *storage.Database {
+	return c.db.With(c.db.Session.WithContext(storage.WithoutOrg(r.Context())))
+}
+
+// managesOrg reports whether user may manage the organization with id.
+func (u User) managesOrg(id string) bool {
+	return u.IsAdmin() && (u.OrgId == "" || u.OrgId == id)
+}
+
+// Middleware
+// orgHandler scopes the request to the organization of its caller.
+func orgHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rc := c.forRequest(r)
+			org := ""
+			if key, ok := r.Context().Value(apiKeyKey).(APIKey); ok {
+				org = key.OrgId
+			} else if user, ok := rc.caller(r); ok {
+				org = user.OrgId
+			}
+
+			next.ServeHTTP(w, rc.inOrg(r, org))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// Organization Handlers
+func (c *appContext) organizationsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := OrganizationRepo{c.db.C("organizations")}
+	orgs, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	user := r.Context().Value(userKey).(User)
+	result := []Organization{}
+	for _, org := range orgs {
+		if user.managesOrg(org.Id.Hex()) {
+			result = append(result, org)
+		}
+	}
+
+	WriteSuccess(w, http.StatusOK, result)
+}
+
+func (c *appContext) createOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*Organization)
+	user := r.Context().Value(userKey).(User)
+	if user.OrgId != "" {
+		WriteError(w, ErrForbidden)
+		return
+	}
+
+	org := Organization{
+		Name:      body.Name,
+		CreatedBy: user.Email,
+		CreatedAt: time.Now(),
+	}
+	repo := OrganizationRepo{c.db.C("organizations")}
+	err := repo.Create(&org)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, org)
+}
+
+// managedOrg returns the organization of the request the caller manages,
+// writing the error when there's none.
+func (c *appContext) managedOrg(w http.ResponseWriter, r *http.Request) (Organization, bool) {
+	params := routeParams(r)
+	user := r.Context().Value(userKey).(User)
+	if !user.managesOrg(params.ByName("id")) {
+		WriteError(w, ErrNotFound)
+		return Organization{}, false
+	}
+
+	repo := OrganizationRepo{c.db.C("organizations")}
+	org, err := repo.Find(params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return Organization{}, false
+	}
+
+	return org, true
+}
+
+func (c *appContext) organizationMembersHandler(w http.ResponseWriter, r *http.Request) {
+	org, ok := c.managedOrg(w, r)
+	if !ok {
+		return
+	}
+
+	repo := UserRepo{c.allOrgs(r).C("users")}
+	users, err := repo.AllByOrg(org.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, users)
+}
+
+func (c *appContext) addOrganizationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	org, ok := c.managedOrg(w, r)
+	if !ok {
+		return
+	}
+
+	params := routeParams(r)
+	repo := UserRepo{c.allOrgs(r).C("users")}
+	err := repo.SetOrg(params.ByName("user"), org.Id.Hex())
+	if err == storage.ErrConflict {
+		WriteError(w, ErrOtherOrgMember)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	user, err := repo.FindByEmail(params.ByName("user"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, user)
+}
+
+func (c *appContext) removeOrganizationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	org, ok := c.managedOrg(w, r)
+	if !ok {
+		return
+	}
+
+	params := routeParams(r)
+	repo := UserRepo{c.allOrgs(r).C("users")}
+	err := repo.RemoveFromOrg(params.ByName("user"), org.Id.Hex())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "User has been removed from the organization successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Overbooking
+//
+// In rooms of at least OVERBOOK_MIN_CAPACITY seats a booking may bump the
+// tentative bookings it overlaps when its category has a higher priority and
+// the bumped bookings start at least OVERBOOK_NOTICE from now. Priorities are
+// configured per event category:
+//
+//	OVERBOOK_PRIORITIES    e.g. "board=100,customer=50"; unlisted categories are 0
+//	OVERBOOK_NOTICE        e.g. "48h", default 24h
+//	OVERBOOK_MIN_CAPACITY  default 20
+//
+// Bumped bookings are moved to another free room of the same venue with
+// enough seats when possible. Otherwise they're taken off the calendar and
+// kept in the bumped_events collection. Either way the owner is notified.
+type OverbookPolicy struct {
+	Priorities  map[string]int
+	Notice      time.Duration
+	MinCapacity int
+}
+
+type BumpedEvent struct {
+	Event    Event     `json:"event"`
+	BumpedBy string    `json:"bumped_by"`
+	BumpedAt time.Time `json:"bumped_at"`
+}
+
+// Repo BumpedEvent
+type BumpedEventRepo struct {
+	coll *storage.Collection
+}
+
+func (r *BumpedEventRepo) All(owner string) ([]BumpedEvent, error) {
+	result := []BumpedEvent{}
+	query := bson.M{}
+	if owner != "" {
+		query["event.owner"] = owner
+	}
+
+	err := r.coll.Find(query).Sort("-bumpedat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *BumpedEventRepo) Create(bumped *BumpedEvent) error {
+	_, err := r.coll.UpsertId(bumped.Event.Id, bumped)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete drops the record of a bumped booking, if there's one.
+func (r *BumpedEventRepo) Delete(id storage.ObjectId) error {
+	err := r.coll.RemoveId(id)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+func overbookPolicy() OverbookPolicy {
+	policy := OverbookPolicy{Priorities: map[string]int{}, Notice: 24 * time.Hour, MinCapacity: 20}
+
+	for _, pair := range strings.Split(setting("OVERBOOK_PRIORITIES"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if priority, err := strconv.Atoi(kv[1]); err == nil {
+			policy.Priorities[kv[0]] = priority
+		}
+	}
+	if d, err := time.ParseDuration(setting("OVERBOOK_NOTICE")); err == nil {
+		policy.Notice = d
+	}
+	if n, err := strconv.Atoi(setting("OVERBOOK_MIN_CAPACITY")); err == nil {
+		policy.MinCapacity = n
+	}
+
+	return policy
+}
+
+func (p OverbookPolicy) Priority(event Event) int {
+	return p.Priorities[event.Category]
+}
+
+// CanBump reports whether event may take the slot of other.
+func (p OverbookPolicy) CanBump(event Event, other Event) bool {
+	return other.Tentative &&
+		!other.IsOccurrence() &&
+		p.Priority(event) > p.Priority(other) &&
+		other.StartTime.Sub(clockNow()) >= p.Notice
+}
+
+// bumpForEvent makes room for event by bumping the tentative bookings it
+// overlaps, if the policy allows bumping all of them. Nothing is touched
+// otherwise. The returned bumps are undone with undoBumps when event can't be
+// written after all, or announced with notifyBumps once it is.
+func (c *appContext) bumpForEvent(ctx context.Context, event Event) []Bump {
+	room, overlapping := c.bumpable(ctx, event)
+	bumps := []Bump{}
+	for _, other := range overlapping {
+		bumps = append(bumps, c.bump(ctx, other, event, room))
+	}
+
+	return bumps
+}
+
+// Bump is a booking bumped by bumpForEvent, as it was before.
+type Bump struct {
+	Event   Event
+	Outcome string
+}
+
+// undoBumps puts the bumped bookings back as they were.
+func (c *appContext) undoBumps(ctx context.Context, bumps []Bump) {
+	repo := c.events()
+	bumpedRepo := BumpedEventRepo{c.db.C("bumped_events")}
+	for _, bump := range bumps {
+		event := bump.Event
+		if err := repo.Update(ctx, &event); err != nil {
+			panic(err)
+		}
+		if err := bumpedRepo.Delete(event.Id); err != nil {
+			panic(err)
+		}
+		c.recordChange("event", event.Id, ChangeUpdated)
+	}
+}
+
+func notifyBumps(bumps []Bump, by Event) {
+	for _, bump := range bumps {
+		notifyBumped(bump.Event, by, bump.Outcome)
+	}
+}
+
+// bumpable returns the room of event and the bookings it would bump, or no
+// bookings when it can't bump all those it overlaps.
+func (c *appContext) bumpable(ctx context.Context, event Event) (Room, []Event) {
+	if !storage.IsObjectIdHex(event.LocationID) {
+		return Room{}, nil
+	}
+
+	policy := overbookPolicy()
+	room, err := c.rooms().Find(ctx, event.LocationID)
+	if err == ErrDocumentNotFound {
+		return room, nil
+	}
+	if err != nil {
+		panic(err)
+	}
+	if room.Capacity < policy.MinCapacity {
+		return room, nil
+	}
+
+	overlapping, err := c.events().Overlapping(ctx, event.LocationID, event.StartTime, event.EndTime, event.Id)
+	if err != nil {
+		panic(err)
+	}
+	bumped := []Event{}
+	for _, other := range overlapping {
+		if event.Replaces(other) {
+			continue
+		}
+		if !policy.CanBump(event, other) {
+			return room, nil
+		}
+		bumped = append(bumped, other)
+	}
+
+	return room, bumped
+}
+
+// bump moves other out of room, relocating it within the venue when a free
+// room is big enough.
+func (c *appContext) bump(ctx context.Context, other Event, by Event, room Room) Bump {
+	repo := c.events()
+	rooms, err := c.rooms().AllByVenueId(ctx, room.VenueId)
+	if err != nil {
+		panic(err)
+	}
+
+	needed := seats(other)
+	for _, candidate := range rooms {
+		if candidate.Id == room.Id {
+			continue
+		}
+		if candidate.Capacity < needed {
+			continue
+		}
+		busy, err := repo.Overlapping(ctx, candidate.Id.Hex(), other.StartTime, other.EndTime, other.Id)
+		if err != nil {
+			panic(err)
+		}
+		if len(busy) > 0 {
+			continue
+		}
+
+		moved := other
+		moved.LocationID = candidate.Id.Hex()
+		moved.Location = candidate.Name
+		if err := repo.Update(ctx, &moved); err != nil {
+			panic(err)
+		}
+		c.recordChange("event", other.Id, ChangeUpdated)
+		return Bump{other, "moved to " + candidate.Name}
+	}
+
+	bumpedRepo := BumpedEventRepo{c.db.C("bumped_events")}
+	err = bumpedRepo.Create(&BumpedEvent{other, by.Id.Hex(), time.Now()})
+	if err != nil {
+		panic(err)
+	}
+	if err := repo.Delete(ctx, other.Id.Hex()); err != nil {
+		panic(err)
+	}
+	c.recordChange("event", other.Id, ChangeDeleted)
+	return Bump{other, "cancelled, no other room was free"}
+}
+
+func notifyBumped(event Event, by Event, outcome string) {
+	log.Printf("overbooking: notifying %s that %q (%s) was bumped by %q (%s) and %s",
+		event.Owner, event.Name, event.Id.Hex(), by.Name, by.Id.Hex(), outcome)
+}
+
+// Overbooking Handlers
+func (c *appContext) bumpedEventsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := BumpedEventRepo{c.db.C("bumped_events")}
+	bumped, err := repo.All(r.URL.Query().Get("owner"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, bumped)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Pagination
+//
+// GET /venues, /rooms and /events take ?page (from 1), ?per_page and ?sort,
+// a comma-separated list of fields each optionally prefixed with "-" for
+// descending order. Without page or per_page the whole list is returned as a
+// plain array, as before. With either, it's wrapped in a Page carrying the
+// total count and links to the neighbouring pages. Events are paged after
+// recurring events are expanded, so they're sorted and sliced in memory
+// within the requested window.
+//
+// The default and largest per_page of each collection, and the limit of
+// GET /changes, can be set with
+//
+//	PAGE_SIZES  e.g. "events=100:1000,changes=200:2000", default:max per
+//	            collection; collections left out keep the defaults below
+//
+// A per_page, or limit, above the maximum is answered with a 400.
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+type PageSize struct {
+	Default int
+	Max     int
+}
+
+var defaultPageSizes = map[string]PageSize{
+	"venues":     {defaultPerPage, maxPerPage},
+	"rooms":      {defaultPerPage, maxPerPage},
+	"events":     {defaultPerPage, maxPerPage},
+	"changes":    {changesPageSize, changesPageSize},
+	"audit_logs": {defaultPerPage, maxPerPage},
+
+	"webhook_deliveries": {defaultPerPage, maxPerPage},
+}
+
+// pageSize returns the page sizes of collection, from PAGE_SIZES when it has
+// a valid entry for it.
+func pageSize(collection string) PageSize {
+	size := defaultPageSizes[collection]
+	for _, entry := range strings.Split(setting("PAGE_SIZES"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] != collection {
+			continue
+		}
+		bounds := strings.SplitN(parts[1], ":", 2)
+		def, err := strconv.Atoi(bounds[0])
+		if err != nil || def < 1 {
+			continue
+		}
+		max := def
+		if len(bounds) == 2 {
+			max, err = strconv.Atoi(bounds[1])
+			if err != nil || max < def {
+				continue
+			}
+		}
+		size = PageSize{def, max}
+	}
+
+	return size
+}
+
+// perPageError explains that a page can hold at most max items.
+func perPageError(param string, max int) *Error {
+	return &Error{"per_page_too_large", 400, "Bad request", param + " must be at most " + strconv.Itoa(max) + "."}
+}
+
+// Sortable fields of each list, by query name, with their stored names.
+var (
+	venueSortFields = map[string]string{"name": "name", "slug": "slug"}
+	roomSortFields  = map[string]string{"name": "name", "slug": "slug", "venue_id": "venueid", "capacity": "capacity"}
+	eventSortFields = map[string]string{"start_time": "starttime", "end_time": "endtime", "name": "name", "location_id": "locationid", "owner": "owner"}
+)
+
+// ListOptions is read from the request by listOptions and applied by the
+// repos, see internal/storage.
+type ListOptions = storage.ListOptions
+
+type PageMeta struct {
+	Total   int `json:"total"`
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+}
+
+type PageLinks struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+type Page struct {
+	Data  interface{} `json:"data"`
+	Meta  PageMeta    `json:"meta"`
+	Links PageLinks   `json:"links"`
+}
+
+// listOptions reads the paging and sort parameters of r for a list of
+// collection, allowing sorting by fields.
+func listOptions(r *http.Request, collection string, fields map[string]string) (ListOptions, *Error) {
+	query := r.URL.Query()
+	size := pageSize(collection)
+	opts := ListOptions{Page: 1, PerPage: size.Default}
+
+	if s := query.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return opts, ErrInvalidPage
+		}
+		opts.Page = n
+		opts.Paged = true
+	}
+	if s := query.Get("per_page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return opts, ErrInvalidPage
+		}
+		if n > size.Max {
+			return opts, perPageError("per_page", size.Max)
+		}
+		opts.PerPage = n
+		opts.Paged = true
+	}
+
+	if s := query.Get("sort"); s != "" {
+		for _, key := range strings.Split(s, ",") {
+			key = strings.TrimSpace(key)
+			prefix := ""
+			if strings.HasPrefix(key, "-") {
+				prefix = "-"
+				key = key[1:]
+			}
+			field, ok := fields[key]
+			if !ok {
+				return opts, ErrInvalidSort
+			}
+			opts.Sort = append(opts.Sort, prefix+field)
+		}
+	}
+
+	return opts, nil
+}
+
+// sortEvents sorts events in memory like Apply would sort them in the
+// database.
+func sortEvents(events []Event, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	compare := func(a Event, b Event, field string) int {
+		switch field {
+		case "starttime":
+			return compareTimes(a.StartTime.Unix(), b.StartTime.Unix())
+		case "endtime":
+			return compareTimes(a.EndTime.Unix(), b.EndTime.Unix())
+		case "name":
+			return strings.Compare(a.Name, b.Name)
+		case "locationid":
+			return strings.Compare(a.LocationID, b.LocationID)
+		case "owner":
+			return strings.Compare(a.Owner, b.Owner)
+		}
+		return 0
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		for _, key := range keys {
+			field, desc := key, false
+			if strings.HasPrefix(key, "-") {
+				field, desc = key[1:], true
+			}
+			if n := compare(events[i], events[j], field); n != 0 {
+				return (n < 0) != desc
+			}
+		}
+		return false
+	})
+}
+
+func compareTimes(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// writeList answers with data, which holds the items on the page, wrapped
+// in a Page when the client asked for one.
+func writeList(w http.ResponseWriter, r *http.Request, opts ListOptions, total int, data interface{}) {
+	if !opts.Paged {
+		WriteSuccess(w, http.StatusOK, data)
+		return
+	}
+
+	link := func(page int) string {
+		u := *r.URL
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+
+	last := (total + opts.PerPage - 1) / opts.PerPage
+	if last < 1 {
+		last = 1
+	}
+	links := PageLinks{Self: link(opts.Page), First: link(1), Last: link(last)}
+	if opts.Page > 1 {
+		links.Prev = link(opts.Page - 1)
+	}
+	if opts.Page < last {
+		links.Next = link(opts.Page + 1)
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	WriteSuccess(w, http.StatusOK, Page{data, PageMeta{total, opts.Page, opts.PerPage}, links})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Panel content
+//
+// Venue admins schedule announcements that room panels cycle through while
+// the room is idle. An item targets the whole venue or a subset of its rooms
+// and is shown between StartsAt and EndsAt (open-ended when zero). Images
+// point at a clean attachment or an external URL; text uses the same limited
+// Markdown as event descriptions.
+const (
+	PanelText  = "text"
+	PanelImage = "image"
+
+	defaultPanelDuration = 15
+)
+
+type PanelContent struct {
+	Id           storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	VenueId      string           `json:"venue_id"`
+	RoomIds      []string         `json:"room_ids"`
+	Kind         string           `json:"kind"`
+	Title        string           `json:"title"`
+	Body         string           `json:"body"`
+	BodyHTML     string           `json:"body_html"`
+	ImageURL     string           `json:"image_url"`
+	AttachmentId string           `json:"attachment_id"`
+	Duration     int              `json:"duration"`
+	Priority     int              `json:"priority"`
+	StartsAt     time.Time        `json:"starts_at"`
+	EndsAt       time.Time        `json:"ends_at"`
+	CreatedBy    string           `json:"created_by"`
+}
+
+// prepare normalizes an item before it's stored.
+func (p *PanelContent) prepare() {
+	p.Body = sanitizeDescription(p.Body)
+	p.BodyHTML = renderDescription(p.Body)
+	if p.Duration <= 0 {
+		p.Duration = defaultPanelDuration
+	}
+	if p.AttachmentId != "" {
+		p.ImageURL = "/attachments/" + p.AttachmentId + "/download"
+	}
+}
+
+// Repo PanelContent
+type PanelContentRepo struct {
+	coll *storage.Collection
+}
+
+func (r *PanelContentRepo) AllByVenueId(venueId string) ([]PanelContent, error) {
+	result := []PanelContent{}
+	err := r.coll.Find(bson.M{"venueid": venueId}).Sort("-priority", "startsat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Active returns the items of venueId shown on roomId at t.
+func (r *PanelContentRepo) Active(venueId string, roomId string, t time.Time) ([]PanelContent, error) {
+	result := []PanelContent{}
+	query := bson.M{
+		"venueid": venueId,
+		"$and": []bson.M{
+			{"$or": []bson.M{{"roomids": bson.M{"$size": 0}}, {"roomids": nil}, {"roomids": roomId}}},
+			{"$or": []bson.M{{"startsat": time.Time{}}, {"startsat": bson.M{"$lte": t}}}},
+			{"$or": []bson.M{{"endsat": time.Time{}}, {"endsat": bson.M{"$gt": t}}}},
+		},
+	}
+
+	err := r.coll.Find(query).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *PanelContentRepo) Find(id string) (PanelContent, error) {
+	result := PanelContent{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *PanelContentRepo) Create(content *PanelContent) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, content)
+	if err != nil {
+		return err
+	}
+
+	content.Id = id
+
+	return nil
+}
+
+func (r *PanelContentRepo) Update(content *PanelContent) error {
+	err := r.coll.UpdateId(content.Id, content)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *PanelContentRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkPanelContent validates the image source and schedule of content. It
+// returns the error to send to the client, or nil.
+func (c *appContext) checkPanelContent(content *PanelContent) *Error {
+	if !content.EndsAt.IsZero() && !content.StartsAt.Before(content.EndsAt) {
+		return ErrInvalidTimeRange
+	}
+	if content.Kind != PanelImage {
+		return nil
+	}
+	if content.AttachmentId == "" {
+		if content.ImageURL == "" {
+			return ErrMissingImage
+		}
+		return nil
+	}
+
+	repo := AttachmentRepo{c.db.C("attachments")}
+	attachment, err := repo.Find(content.AttachmentId)
+	if err == storage.ErrNotFound {
+		return ErrMissingImage
+	}
+	if err != nil {
+		panic(err)
+	}
+	switch attachment.Status {
+	case AttachmentClean:
+		return nil
+	case AttachmentPending:
+		return ErrAttachmentPending
+	}
+
+	return ErrFileQuarantined
+}
+
+// Panel Content Handlers
+func (c *appContext) venuePanelContentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := PanelContentRepo{c.db.C("panel_content")}
+	content, err := repo.AllByVenueId(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, content)
+}
+
+func (c *appContext) createPanelContentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*PanelContent)
+	body.VenueId = params.ByName("id")
+	if e := c.checkPanelContent(body); e != nil {
+		WriteError(w, e)
+		return
+	}
+	body.prepare()
+
+	repo := PanelContentRepo{c.db.C("panel_content")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("panel_content", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updatePanelContentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*PanelContent)
+	repo := PanelContentRepo{c.db.C("panel_content")}
+	content, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	body.Id = content.Id
+	body.VenueId = content.VenueId
+	if e := c.checkPanelContent(body); e != nil {
+		WriteError(w, e)
+		return
+	}
+	body.prepare()
+
+	err = repo.Update(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("panel_content", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deletePanelContentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := PanelContentRepo{c.db.C("panel_content")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("panel_content", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Panel content has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// roomPanelContentHandler serves the slideshow for a room panel: the items
+// and venue announcements active right now, highest priority first.
+func (c *appContext) roomPanelContentHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	repo := PanelContentRepo{c.db.C("panel_content")}
+	content, err := repo.Active(room.VenueId, room.Id.Hex(), clockNow())
+	if err != nil {
+		panic(err)
+	}
+
+	announcementRepo := AnnouncementRepo{c.db.C("announcements")}
+	announcements, err := announcementRepo.Displayed(room.VenueId, clockNow())
+	if err != nil {
+		panic(err)
+	}
+	for _, announcement := range announcements {
+		content = append(content, announcement.PanelContent())
+	}
+
+	sort.SliceStable(content, func(i, j int) bool {
+		if content[i].Priority != content[j].Priority {
+			return content[i].Priority > content[j].Priority
+		}
+		return content[i].StartsAt.Before(content[j].StartsAt)
+	})
+
+	WriteSuccess(w, http.StatusOK, content)
+}
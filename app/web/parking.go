@@ -0,0 +1,544 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Parking
+//
+// Venues have parking spots that visitors reserve for a time window, either
+// standalone or linked to an event, in which case the window defaults to the
+// event's. A visitor holds at most one spot at a time. When no spot is given
+// the first free spot of the requested kind at the venue is allocated. Admins
+// manage the spots; a reservation can be cancelled by whoever made it.
+//
+// Reservations are pushed to the parking gate so it can open for the plate:
+//
+//	PARKING_GATE_URL     webhook receiving grant/revoke notifications
+//	PARKING_GATE_SECRET  HMAC-SHA256 key used to sign them in X-Signature
+const (
+	SpotStandard   = "standard"
+	SpotEV         = "ev"
+	SpotAccessible = "accessible"
+)
+
+var licensePlate = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,14}$`)
+
+type ParkingSpot struct {
+	Id      storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	VenueId string           `json:"venue_id"`
+	Label   string           `json:"label"`
+	Kind    string           `json:"kind"`
+}
+
+type ParkingReservation struct {
+	Id           storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	SpotId       string           `json:"spot_id"`
+	VenueId      string           `json:"venue_id"`
+	EventId      string           `json:"event_id,omitempty"`
+	Visitor      string           `json:"visitor"`
+	LicensePlate string           `json:"license_plate"`
+	Kind         string           `json:"kind,omitempty"`
+	StartTime    time.Time        `json:"start_time"`
+	EndTime      time.Time        `json:"end_time"`
+	CreatedBy    string           `json:"created_by,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+type GateNotification struct {
+	Action       string    `json:"action"`
+	Reservation  string    `json:"reservation_id"`
+	Spot         string    `json:"spot"`
+	LicensePlate string    `json:"license_plate"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+}
+
+// normalizePlate upper-cases a license plate and collapses its whitespace.
+func normalizePlate(plate string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(plate)), " ")
+}
+
+// Repo ParkingSpot
+type ParkingSpotRepo struct {
+	coll *storage.Collection
+}
+
+func (r *ParkingSpotRepo) All() ([]ParkingSpot, error) {
+	result := []ParkingSpot{}
+	err := r.coll.Find(nil).Sort("venueid", "label").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingSpotRepo) AllByVenueId(venueId string) ([]ParkingSpot, error) {
+	result := []ParkingSpot{}
+	err := r.coll.Find(bson.M{"venueid": venueId}).Sort("label").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingSpotRepo) Find(id string) (ParkingSpot, error) {
+	result := ParkingSpot{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingSpotRepo) Create(spot *ParkingSpot) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, spot)
+	if err != nil {
+		return err
+	}
+
+	spot.Id = id
+
+	return nil
+}
+
+func (r *ParkingSpotRepo) Update(spot *ParkingSpot) error {
+	err := r.coll.UpdateId(spot.Id, spot)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ParkingSpotRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo ParkingReservation
+type ParkingReservationRepo struct {
+	coll *storage.Collection
+}
+
+func (r *ParkingReservationRepo) Find(id string) (ParkingReservation, error) {
+	result := ParkingReservation{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingReservationRepo) AllByEventId(eventId string) ([]ParkingReservation, error) {
+	result := []ParkingReservation{}
+	err := r.coll.Find(bson.M{"eventid": eventId}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingReservationRepo) AllByVenueId(venueId string, start_time time.Time, end_time time.Time) ([]ParkingReservation, error) {
+	result := []ParkingReservation{}
+	query := bson.M{
+		"venueid":   venueId,
+		"starttime": bson.M{"$lt": end_time},
+		"endtime":   bson.M{"$gt": start_time},
+	}
+
+	err := r.coll.Find(query).Sort("starttime").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Overlapping returns the reservations intersecting [start_time, end_time)
+// that are on spotId or held by the same visitor or plate.
+func (r *ParkingReservationRepo) Overlapping(reservation *ParkingReservation) ([]ParkingReservation, error) {
+	result := []ParkingReservation{}
+	holders := []bson.M{{"licenseplate": reservation.LicensePlate}}
+	if reservation.Visitor != "" {
+		holders = append(holders, bson.M{"visitor": reservation.Visitor})
+	}
+	if reservation.SpotId != "" {
+		holders = append(holders, bson.M{"spotid": reservation.SpotId})
+	}
+	query := bson.M{
+		"$or":       holders,
+		"starttime": bson.M{"$lt": reservation.EndTime},
+		"endtime":   bson.M{"$gt": reservation.StartTime},
+	}
+
+	err := r.coll.Find(query).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *ParkingReservationRepo) Create(reservation *ParkingReservation) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, reservation)
+	if err != nil {
+		return err
+	}
+
+	reservation.Id = id
+
+	return nil
+}
+
+func (r *ParkingReservationRepo) Delete(id string) error {
+	err := r.coll.RemoveId(storage.ObjectIdHex(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// notifyGate sends a signed grant/revoke notification to PARKING_GATE_URL.
+// Failures are logged; the gate can always be opened by the front desk.
+func notifyGate(action string, reservation ParkingReservation, spot ParkingSpot) {
+	url := setting("PARKING_GATE_URL")
+	if url == "" {
+		return
+	}
+
+	b, err := json.Marshal(GateNotification{
+		Action:       action,
+		Reservation:  reservation.Id.Hex(),
+		Spot:         spot.Label,
+		LicensePlate: reservation.LicensePlate,
+		StartTime:    reservation.StartTime,
+		EndTime:      reservation.EndTime,
+	})
+	if err != nil {
+		log.Printf("parking: unable to encode gate notification: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		log.Printf("parking: invalid PARKING_GATE_URL: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := setting("PARKING_GATE_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(b)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		log.Printf("parking: gate notification for %s failed: %v", reservation.Id.Hex(), err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Printf("parking: gate rejected %s notification for %s: %s", action, reservation.Id.Hex(), res.Status)
+	}
+}
+
+// Parking Spot Handlers
+func (c *appContext) parkingSpotsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := ParkingSpotRepo{c.db.C("parking_spots")}
+	var spots []ParkingSpot
+	var err error
+	if venueId := r.URL.Query().Get("venue_id"); venueId != "" {
+		spots, err = repo.AllByVenueId(venueId)
+	} else {
+		spots, err = repo.All()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, spots)
+}
+
+func (c *appContext) parkingSpotHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := ParkingSpotRepo{c.db.C("parking_spots")}
+	spot, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, spot)
+}
+
+func (c *appContext) createParkingSpotHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*ParkingSpot)
+	if body.Kind == "" {
+		body.Kind = SpotStandard
+	}
+	repo := ParkingSpotRepo{c.db.C("parking_spots")}
+	err := repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("parking_spot", body.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) updateParkingSpotHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*ParkingSpot)
+	body.Id = storage.ObjectIdHex(params.ByName("id"))
+	if body.Kind == "" {
+		body.Kind = SpotStandard
+	}
+	repo := ParkingSpotRepo{c.db.C("parking_spots")}
+	err := repo.Update(body)
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("parking_spot", body.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+func (c *appContext) deleteParkingSpotHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := ParkingSpotRepo{c.db.C("parking_spots")}
+	err := repo.Delete(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("parking_spot", storage.ObjectIdHex(params.ByName("id")), ChangeDeleted)
+
+	data := MessageSuccess{MessageInfo{Message: "Parking spot has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+// Parking Reservation Handlers
+func (c *appContext) parkingReservationsHandler(w http.ResponseWriter, r *http.Request) {
+	venueId := r.URL.Query().Get("venue_id")
+	if venueId == "" {
+		WriteError(w, ErrMissingVenue)
+		return
+	}
+
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	repo := ParkingReservationRepo{c.db.C("parking_reservations")}
+	reservations, err := repo.AllByVenueId(venueId, start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, reservations)
+}
+
+func (c *appContext) eventParkingHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := ParkingReservationRepo{c.db.C("parking_reservations")}
+	reservations, err := repo.AllByEventId(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, reservations)
+}
+
+func (c *appContext) createParkingReservationHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*ParkingReservation)
+	body.LicensePlate = normalizePlate(body.LicensePlate)
+	if !licensePlate.MatchString(body.LicensePlate) {
+		WriteError(w, ErrInvalidPlate)
+		return
+	}
+
+	user := r.Context().Value(userKey).(User)
+	if body.EventId != "" {
+		eventRepo := c.events()
+		event, err := eventRepo.Find(r.Context(), body.EventId)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		if !user.CanManage(event) {
+			WriteError(w, ErrNotEventOwner)
+			return
+		}
+		if body.StartTime.IsZero() && body.EndTime.IsZero() {
+			body.StartTime, body.EndTime = event.StartTime, event.EndTime
+		}
+		if body.VenueId == "" && storage.IsObjectIdHex(event.LocationID) {
+			roomRepo := c.rooms()
+			room, err := roomRepo.Find(r.Context(), event.LocationID)
+			if err != nil && err != ErrDocumentNotFound {
+				panic(err)
+			}
+			body.VenueId = room.VenueId
+		}
+	}
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+
+	spotRepo := ParkingSpotRepo{c.db.C("parking_spots")}
+	repo := ParkingReservationRepo{c.db.C("parking_reservations")}
+
+	var spot ParkingSpot
+	if body.SpotId != "" {
+		var err error
+		spot, err = spotRepo.Find(body.SpotId)
+		if err == storage.ErrNotFound {
+			WriteError(w, ErrNotFound)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		body.VenueId = spot.VenueId
+	}
+
+	taken, err := repo.Overlapping(body)
+	if err != nil {
+		panic(err)
+	}
+	busy := map[string]bool{}
+	for _, reservation := range taken {
+		if reservation.LicensePlate == body.LicensePlate || (body.Visitor != "" && reservation.Visitor == body.Visitor) {
+			WriteError(w, ErrVisitorHasSpot)
+			return
+		}
+		busy[reservation.SpotId] = true
+	}
+
+	if body.SpotId == "" {
+		if body.VenueId == "" {
+			WriteError(w, ErrMissingVenue)
+			return
+		}
+		spots, err := spotRepo.AllByVenueId(body.VenueId)
+		if err != nil {
+			panic(err)
+		}
+		kind := body.Kind
+		if kind == "" {
+			kind = SpotStandard
+		}
+
+		// Reservations on other spots weren't part of the overlap query, so
+		// look them up for the whole venue before picking one.
+		others, err := repo.AllByVenueId(body.VenueId, body.StartTime, body.EndTime)
+		if err != nil {
+			panic(err)
+		}
+		for _, reservation := range others {
+			busy[reservation.SpotId] = true
+		}
+		for _, candidate := range spots {
+			if candidate.Kind == kind && !busy[candidate.Id.Hex()] {
+				spot = candidate
+				break
+			}
+		}
+		if spot.Id == "" {
+			WriteError(w, ErrParkingFull)
+			return
+		}
+		body.SpotId = spot.Id.Hex()
+	} else if busy[body.SpotId] {
+		WriteError(w, ErrSpotTaken)
+		return
+	}
+
+	body.Kind = spot.Kind
+	body.CreatedBy = user.Email
+	body.CreatedAt = time.Now()
+	err = repo.Create(body)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("parking_reservation", body.Id, ChangeCreated)
+
+	go notifyGate("grant", *body, spot)
+
+	WriteSuccess(w, http.StatusCreated, body)
+}
+
+func (c *appContext) deleteParkingReservationHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	if !storage.IsObjectIdHex(params.ByName("id")) {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	repo := ParkingReservationRepo{c.db.C("parking_reservations")}
+	reservation, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && reservation.CreatedBy != user.Email {
+		WriteError(w, ErrNotBookingOwner)
+		return
+	}
+
+	err = repo.Delete(params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("parking_reservation", reservation.Id, ChangeDeleted)
+
+	spotRepo := ParkingSpotRepo{c.db.C("parking_spots")}
+	spot, err := spotRepo.Find(reservation.SpotId)
+	if err != nil && err != storage.ErrNotFound {
+		panic(err)
+	}
+	go notifyGate("revoke", reservation, spot)
+
+	data := MessageSuccess{MessageInfo{Message: "Parking reservation has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Partial updates
+//
+// PATCH /venues/:id, /rooms/:id and /events/:id take a JSON merge patch, as
+// in RFC 7396: the fields sent replace those of the stored document, objects
+// are merged field by field, null removes a field and the fields left out
+// keep their values. mergePatchHandler merges the patch into the document as
+// it's read before the schema and the validation of the route run, so they
+// check the merged result, and the handlers then only write the fields that
+// change, see storage.Changes.
+//
+// The date and hour fields of an event still work: a patch sending them
+// without start_time and end_time moves the event by them.
+
+// patchBase reads the document the patch of r is merged into, as it's sent.
+type patchBase func(c *appContext, r *http.Request, patch map[string]interface{}) (interface{}, error)
+
+var eventDateFields = []string{"date", "month", "year", "start_hour", "start_minute", "end_hour", "end_minute"}
+
+// mergePatch merges patch into doc.
+func mergePatch(doc map[string]interface{}, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		if fields, ok := value.(map[string]interface{}); ok {
+			merged, ok := doc[key].(map[string]interface{})
+			if !ok {
+				merged = map[string]interface{}{}
+			}
+			mergePatch(merged, fields)
+			doc[key] = merged
+			continue
+		}
+		doc[key] = value
+	}
+}
+
+func venuePatchBase(c *appContext, r *http.Request, patch map[string]interface{}) (interface{}, error) {
+	return c.venues().Find(r.Context(), routeParams(r).ByName("id"))
+}
+
+func roomPatchBase(c *appContext, r *http.Request, patch map[string]interface{}) (interface{}, error) {
+	return c.rooms().Find(r.Context(), routeParams(r).ByName("id"))
+}
+
+// eventPatchBase returns the event as it's sent, in its time zone, or the
+// occurrence of ?occurrence= of it.
+func eventPatchBase(c *appContext, r *http.Request, patch map[string]interface{}) (interface{}, error) {
+	event, err := c.events().Find(r.Context(), routeParams(r).ByName("id"))
+	if err != nil {
+		return nil, err
+	}
+	if occurrence, errRes := occurrenceParam(r, event); errRes == nil && !occurrence.IsZero() {
+		event.EndTime = occurrence.Add(event.EndTime.Sub(event.StartTime))
+		event.StartTime = occurrence
+	}
+	if zones, errRes := c.zones(r); errRes == nil {
+		zones.event(&event)
+	}
+
+	base := EventResponse{
+		Name:        event.Name,
+		LocationID:  event.LocationID,
+		Location:    event.Location,
+		Description: event.Description,
+		Guests:      event.Guests,
+		Owner:       event.Owner,
+		TimeZone:    event.TimeZone,
+		StartTime:   event.StartTime.Format(time.RFC3339),
+		EndTime:     event.EndTime.Format(time.RFC3339),
+		Equipment:   event.Equipment,
+		Category:    event.Category,
+		Tentative:   event.Tentative,
+		Recurrence:  event.Recurrence,
+		Upgrade:     event.Upgrade,
+		GroupId:     event.GroupId,
+	}
+	doc, err := jsonObject(base)
+	if err != nil {
+		return nil, err
+	}
+	_, start := patch["start_time"]
+	_, end := patch["end_time"]
+	moved := false
+	for _, field := range eventDateFields {
+		if _, ok := patch[field]; ok && !start && !end {
+			moved = true
+			break
+		}
+	}
+	if !moved {
+		for _, field := range eventDateFields {
+			delete(doc, field)
+		}
+		return doc, nil
+	}
+
+	delete(doc, "start_time")
+	delete(doc, "end_time")
+	doc["date"] = event.StartTime.Day()
+	doc["month"] = int(event.StartTime.Month())
+	doc["year"] = event.StartTime.Year()
+	doc["start_hour"] = event.StartTime.Hour()
+	doc["start_minute"] = event.StartTime.Minute()
+	doc["end_hour"] = event.EndTime.Hour()
+	doc["end_minute"] = event.EndTime.Minute()
+
+	return doc, nil
+}
+
+// jsonObject returns v as a JSON object, without its null fields.
+func jsonObject(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	mergePatch(doc, fields)
+
+	return doc, nil
+}
+
+// Middleware
+func mergePatchHandler(c *appContext, base patchBase) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+			patch := map[string]interface{}{}
+			if err := json.Unmarshal(b, &patch); err != nil || patch == nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+
+			doc, err := base(c.forRequest(r), r, patch)
+			if err != nil {
+				WriteRepoError(w, err)
+				return
+			}
+			merged, err := jsonObject(doc)
+			if err != nil {
+				panic(err)
+			}
+			mergePatch(merged, patch)
+
+			b, err = json.Marshal(merged)
+			if err != nil {
+				panic(err)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Photos
+//
+// Admins add photos to venues and rooms with POST /venues/:id/photos and
+// POST /rooms/:id/photos, as the multipart "file" field, a PNG, JPEG or GIF
+// image of at most maxImageSize, and an optional "caption". Each upload is
+// decoded and encoded again, shrunk to fit photoMaxSize, with a thumbnail
+// fitting photoThumbnailSize, both kept in the BlobStore. Encoding it again
+// drops whatever else the file carried, so photos skip the Scanner.
+//
+// GET /venues/:id/photos and GET /rooms/:id/photos list them, newest first;
+// GET /photos/:id/image and GET /photos/:id/thumbnail serve them and
+// DELETE /photos/:id removes one.
+const (
+	photoMaxSize       = 2048
+	photoThumbnailSize = 320
+	photoMaxPixels     = 50 << 20
+)
+
+var (
+	ErrInvalidPhoto    = &Error{"invalid_photo", 415, "Unsupported Media Type", "Photos must be PNG, JPEG or GIF images."}
+	ErrPhotoDimensions = &Error{"photo_dimensions", 422, "Unprocessable Entity", "Photos must be at most 50 megapixels."}
+)
+
+type Photo struct {
+	Id           storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerType    string           `json:"owner_type"`
+	OwnerId      string           `json:"owner_id"`
+	Caption      string           `json:"caption,omitempty"`
+	ContentType  string           `json:"content_type"`
+	Width        int              `json:"width"`
+	Height       int              `json:"height"`
+	Size         int              `json:"size"`
+	URL          string           `json:"url"`
+	ThumbnailURL string           `json:"thumbnail_url"`
+	Key          string           `json:"-"`
+	ThumbnailKey string           `json:"-"`
+	UploadedBy   string           `json:"uploaded_by"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// processedPhoto is a photo as it's stored, with its thumbnail.
+type processedPhoto struct {
+	ContentType string
+	Width       int
+	Height      int
+	Image       []byte
+	Thumbnail   []byte
+}
+
+// processPhoto decodes data and encodes it again fitting photoMaxSize, with
+// a thumbnail fitting photoThumbnailSize. JPEG photos stay JPEG, the others
+// become PNG so they keep their transparency.
+func processPhoto(data []byte) (processedPhoto, *Error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedPhoto{}, ErrInvalidPhoto
+	}
+	if config.Width*config.Height > photoMaxPixels {
+		return processedPhoto{}, ErrPhotoDimensions
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processedPhoto{}, ErrInvalidPhoto
+	}
+
+	result := processedPhoto{ContentType: "image/png"}
+	if format == "jpeg" {
+		result.ContentType = "image/jpeg"
+	}
+	img := fitImage(src, photoMaxSize)
+	result.Width, result.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	if result.Image, err = encodePhoto(img, result.ContentType); err != nil {
+		panic(err)
+	}
+	if result.Thumbnail, err = encodePhoto(fitImage(img, photoThumbnailSize), result.ContentType); err != nil {
+		panic(err)
+	}
+
+	return result, nil
+}
+
+func encodePhoto(img image.Image, contentType string) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&b, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&b, img)
+	}
+
+	return b.Bytes(), err
+}
+
+// fitImage shrinks src to fit a square of size, keeping its aspect ratio,
+// each pixel the average of those of src it covers. Smaller images are
+// returned as they are.
+func fitImage(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	rgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for i := range sum {
+						sum[i] += int(row[sx*4+i])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			off := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[off+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+
+	return dst
+}
+
+// Repo Photo
+type PhotoRepo struct {
+	coll *storage.Collection
+}
+
+func (r *PhotoRepo) AllByOwner(ownerType string, ownerId string) ([]Photo, error) {
+	result := []Photo{}
+	err := r.coll.Find(bson.M{"ownertype": ownerType, "ownerid": ownerId}).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *PhotoRepo) Find(id string) (Photo, error) {
+	result := Photo{}
+	err := r.coll.FindId(storage.ObjectIdHex(id)).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *PhotoRepo) Create(photo *Photo) error {
+	err := r.coll.Insert(photo)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *PhotoRepo) Delete(id storage.ObjectId) error {
+	err := r.coll.RemoveId(id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// uploadPhoto stores the photo of the multipart "file" field for the given
+// owner.
+func (c *appContext) uploadPhoto(w http.ResponseWriter, r *http.Request, ownerType string, ownerId string) {
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+	data, _, errRes := readImage(w, r)
+	if errRes == ErrInvalidImage {
+		errRes = ErrInvalidPhoto
+	}
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	processed, errRes := processPhoto(data)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	id := storage.NewObjectId()
+	ext := imageTypes[processed.ContentType]
+	photo := Photo{
+		Id:           id,
+		OwnerType:    ownerType,
+		OwnerId:      ownerId,
+		Caption:      r.FormValue("caption"),
+		ContentType:  processed.ContentType,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Size:         len(processed.Image),
+		URL:          "/photos/" + id.Hex() + "/image",
+		ThumbnailURL: "/photos/" + id.Hex() + "/thumbnail",
+		Key:          "photos/" + id.Hex() + ext,
+		ThumbnailKey: "photos/" + id.Hex() + "-thumbnail" + ext,
+		UploadedBy:   r.Context().Value(userKey).(User).Email,
+		CreatedAt:    time.Now(),
+	}
+	if err := store.Put(r.Context(), photo.Key, photo.ContentType, processed.Image); err != nil {
+		panic(err)
+	}
+	if err := store.Put(r.Context(), photo.ThumbnailKey, photo.ContentType, processed.Thumbnail); err != nil {
+		panic(err)
+	}
+
+	repo := PhotoRepo{c.db.C("photos")}
+	if err := repo.Create(&photo); err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, photo)
+}
+
+// Photo Handlers
+func (c *appContext) uploadVenuePhotoHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, _, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	c.uploadPhoto(w, r, "venue", venue.Id.Hex())
+}
+
+func (c *appContext) uploadRoomPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	room, err := c.rooms().Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	c.uploadPhoto(w, r, "room", room.Id.Hex())
+}
+
+func (c *appContext) venuePhotosHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/photos")
+		return
+	}
+
+	repo := PhotoRepo{c.db.C("photos")}
+	photos, err := repo.AllByOwner("venue", venue.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, photos)
+}
+
+func (c *appContext) roomPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := PhotoRepo{c.db.C("photos")}
+	photos, err := repo.AllByOwner("room", params.ByName("id"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, photos)
+}
+
+func (c *appContext) photoImageHandler(w http.ResponseWriter, r *http.Request) {
+	c.servePhoto(w, r, false)
+}
+
+func (c *appContext) photoThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	c.servePhoto(w, r, true)
+}
+
+func (c *appContext) servePhoto(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	params := routeParams(r)
+	repo := PhotoRepo{c.db.C("photos")}
+	photo, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+
+	key := photo.Key
+	if thumbnail {
+		key = photo.ThumbnailKey
+	}
+	serveBlob(w, r, store, key, photo.ContentType, photo.CreatedAt)
+}
+
+func (c *appContext) deletePhotoHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := PhotoRepo{c.db.C("photos")}
+	photo, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	store, err := newBlobStore()
+	if err != nil {
+		log.Printf("blobs: %v", err)
+		WriteError(w, ErrImagesDisabled)
+		return
+	}
+
+	if err := repo.Delete(photo.Id); err != nil {
+		panic(err)
+	}
+	for _, key := range []string{photo.Key, photo.ThumbnailKey} {
+		if err := store.Delete(r.Context(), key); err != nil {
+			log.Printf("blobs: removing %s: %v", key, err)
+		}
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "Photo has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Presence
+//
+// GET /venues/:id/presence?date= lists who is expected in the venue on a day,
+// based on their desk bookings and the room events they own or attend, and
+// whether they checked in. Listing presence is an organisation-wide choice:
+//
+//	PRESENCE_VISIBILITY  "off" (default) or "on"
+//
+// Users can still hide themselves with hide_presence in the user directory.
+// ?team= narrows the list to one team.
+type Presence struct {
+	User      string   `json:"user"`
+	Name      string   `json:"name,omitempty"`
+	Team      string   `json:"team,omitempty"`
+	Desks     []string `json:"desks"`
+	Events    []string `json:"events"`
+	CheckedIn bool     `json:"checked_in"`
+}
+
+// Repo Floor venue
+func (r *FloorRepo) AllByVenueId(venueId string) ([]Floor, error) {
+	result := []Floor{}
+	err := r.coll.Find(bson.M{"venueid": venueId}).Sort("level").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Presence Handlers
+func (c *appContext) venuePresenceHandler(w http.ResponseWriter, r *http.Request) {
+	if !c.config.Features.Presence {
+		WriteError(w, ErrPresenceDisabled)
+		return
+	}
+
+	params := routeParams(r)
+	date, ok := dayParam(r)
+	if !ok {
+		WriteError(w, ErrInvalidDate)
+		return
+	}
+
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	if !canonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/presence")
+		return
+	}
+
+	people := map[string]*Presence{}
+	person := func(user string) *Presence {
+		if people[user] == nil {
+			people[user] = &Presence{User: user, Desks: []string{}, Events: []string{}}
+		}
+		return people[user]
+	}
+
+	// Desk bookings on the venue's floors.
+	floorRepo := FloorRepo{c.db.C("floors")}
+	floors, err := floorRepo.AllByVenueId(venue.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	for _, floor := range floors {
+		desks, err := c.floorStatus(floor.Id.Hex(), date)
+		if err != nil {
+			panic(err)
+		}
+		for _, desk := range desks {
+			for _, booking := range desk.Bookings {
+				p := person(booking.User)
+				p.Desks = append(p.Desks, floor.Name+" "+desk.Label)
+			}
+		}
+	}
+
+	// Events in the venue's rooms.
+	roomRepo := c.rooms()
+	rooms, err := roomRepo.AllByVenueId(r.Context(), venue.Id.Hex())
+	if err != nil {
+		panic(err)
+	}
+	roomIds := []string{}
+	for _, room := range rooms {
+		roomIds = append(roomIds, room.Id.Hex())
+	}
+
+	loc := defaultLocation
+	start_time, _ := time.ParseInLocation("2006-01-02", date, loc)
+	repo := c.events()
+	events, err := repo.AllByLocationIds(r.Context(), roomIds, start_time, start_time.AddDate(0, 0, 1))
+	if err != nil {
+		panic(err)
+	}
+	for _, event := range events {
+		for _, user := range participantsOf(event) {
+			p := person(user)
+			p.Events = append(p.Events, event.Name)
+			if !event.CheckedInAt.IsZero() {
+				p.CheckedIn = true
+			}
+		}
+	}
+
+	users := []string{}
+	for user := range people {
+		users = append(users, user)
+	}
+	userRepo := UserRepo{c.db.C("users")}
+	directory, err := userRepo.AllByEmails(users)
+	if err != nil {
+		panic(err)
+	}
+
+	team := r.URL.Query().Get("team")
+	result := []Presence{}
+	for _, p := range people {
+		entry, known := directory[p.User]
+		if known && entry.HidePresence {
+			continue
+		}
+		p.Name, p.Team = entry.Name, entry.Team
+		if team != "" && p.Team != team {
+			continue
+		}
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].User < result[j].User
+	})
+
+	WriteSuccess(w, http.StatusOK, result)
+}
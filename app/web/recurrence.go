@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurring events
+//
+// An event with a Recurrence is booked once and stands for all of its
+// occurrences, which are expanded when events are listed or checked for
+// conflicts. /event-series serves the same events, see series.go. Update and delete act on the entire
+// series unless ?occurrence= names the start time of one occurrence:
+// deleting it adds an exception, changing it adds an exception and stores
+// the changed occurrence as an event of its own with RecurringEventId and
+// OriginalStartTime set.
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// rrule returns the RRULE and EXDATE lines of r for Google Calendar.
+func rrule(r *Recurrence) []string {
+	if r == nil {
+		return []string{}
+	}
+
+	rule := "RRULE:FREQ=" + strings.ToUpper(r.Frequency)
+	if r.Interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	if r.Frequency == FrequencyWeekly {
+		rule += ";WKST=" + rruleWeekdays[r.WeekStartDay()]
+	}
+	if len(r.Weekdays) > 0 {
+		days := []string{}
+		for _, day := range r.Weekdays {
+			days = append(days, rruleWeekdays[day])
+		}
+		rule += ";BYDAY=" + strings.Join(days, ",")
+	}
+	if !r.Until.IsZero() {
+		rule += ";UNTIL=" + r.Until.UTC().Format("20060102T150405Z")
+	}
+	if r.Count > 0 {
+		rule += ";COUNT=" + strconv.Itoa(r.Count)
+	}
+
+	result := []string{rule}
+	for _, t := range r.Exceptions {
+		result = append(result, "EXDATE:"+t.UTC().Format("20060102T150405Z"))
+	}
+
+	return result
+}
+
+// occurrenceParam returns the start of the occurrence of event named by
+// ?occurrence=, or the zero time when the request is about the entire event.
+func occurrenceParam(r *http.Request, event Event) (time.Time, *Error) {
+	value := r.URL.Query().Get("occurrence")
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil || event.Recurrence == nil {
+		return time.Time{}, ErrInvalidOccurrence
+	}
+	for _, occurrence := range event.Occurrences(t, t.Add(time.Nanosecond)) {
+		if occurrence.StartTime.Equal(t) {
+			return occurrence.StartTime, nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidOccurrence
+}
+
+// withOccurrences replaces the recurring events among events, which were
+// found by their start time, with their occurrences within
+// [start_time, end_time).
+func (c *appContext) withOccurrences(ctx context.Context, events []Event, start_time time.Time, end_time time.Time) []Event {
+	result := []Event{}
+	for _, event := range events {
+		if event.Recurrence == nil {
+			result = append(result, event)
+		}
+	}
+
+	occurrences, err := c.events().AllOccurrences(ctx, "", start_time, end_time, "")
+	if err != nil {
+		panic(err)
+	}
+	for _, occurrence := range occurrences {
+		if !occurrence.StartTime.Before(start_time) {
+			result = append(result, occurrence)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].StartTime.Before(result[j].StartTime) })
+
+	return result
+}
+
+// deleteDetached deletes the changed occurrences of the recurring event,
+// which is being deleted as a whole.
+func (c *appContext) deleteDetached(ctx context.Context, event Event) {
+	repo := c.events()
+	detached, err := repo.AllByRecurringEvent(ctx, event.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, occurrence := range detached {
+		err = repo.Delete(ctx, occurrence.Id.Hex())
+		if err != nil {
+			panic(err)
+		}
+		c.recordChange("event", occurrence.Id, ChangeDeleted)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// No-show release
+//
+// Rooms with a release_after, in minutes, keep their bookings only when
+// someone checks in that long after the start, with POST
+// /events/:id/check-in, the room's check-in code or a guest token. A
+// background job looks for the bookings nobody checked into every
+// releaseInterval, cancels them so the room is free for the rest of their
+// time, lets the owner and guests know and counts a no-show for the owner.
+// Recurring events aren't released.
+const releaseInterval = time.Minute
+
+// releaseNoShows cancels the bookings nobody checked into within the
+// release_after of their room.
+func (c *appContext) releaseNoShows(ctx context.Context) error {
+	t := clockNow()
+	events, err := c.events().All(ctx, t.Add(-24*time.Hour), t, false)
+	if err != nil {
+		return err
+	}
+
+	rooms := map[string]Room{}
+	for _, event := range events {
+		if event.LocationID == "" || event.Recurrence != nil || !event.CheckedInAt.IsZero() || !t.Before(event.EndTime) {
+			continue
+		}
+
+		room, ok := rooms[event.LocationID]
+		if !ok {
+			room, err = c.rooms().Find(ctx, event.LocationID)
+			if err != nil && err != storage.ErrNotFound && err != storage.ErrInvalidId {
+				return err
+			}
+			rooms[event.LocationID] = room
+		}
+		if room.ReleaseAfter <= 0 || t.Before(event.StartTime.Add(time.Duration(room.ReleaseAfter)*time.Minute)) {
+			continue
+		}
+
+		err = c.releaseEvent(ctx, event, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseEvent cancels event at t as a no-show.
+func (c *appContext) releaseEvent(ctx context.Context, event Event, t time.Time) error {
+	repo := c.events()
+	event.ReleasedAt = &t
+	err := repo.Update(ctx, &event)
+	if err != nil {
+		return err
+	}
+	err = repo.Delete(ctx, event.Id.Hex())
+	if err != nil {
+		return err
+	}
+
+	c.recordChange("event", event.Id, ChangeDeleted)
+	notifyEvent(NotifyReleased, event)
+	c.recordFreedSlot(event.LocationID, t, event.EndTime)
+	if event.Owner == "" {
+		return nil
+	}
+
+	marks := ReliabilityMarkRepo{c.inOrgOf(event.OrgId).db.C("reliability")}
+	mark := ReliabilityMark{
+		Id:         storage.NewObjectId(),
+		User:       event.Owner,
+		EventId:    event.Id,
+		Kind:       MarkNoShow,
+		StartTime:  event.StartTime,
+		RecordedAt: t,
+	}
+
+	return marks.Create(&mark)
+}
+
+// watchReleases runs releaseNoShows for as long as the process runs.
+func (c *appContext) watchReleases() {
+	for range time.Tick(releaseInterval) {
+		if err := c.releaseNoShows(context.Background()); err != nil {
+			log.Println("Releasing no-shows failed:", err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Reliability
+//
+// Deleting an event shortly before it starts counts as a late cancellation
+// for its owner, and an event with a check-in code that ended without anyone
+// checking in counts as a no-show. A background job looks for no-shows every
+// noShowInterval. The reliability score of a user is the share of their
+// bookings over the last reliabilityPeriod that were neither. Bookings of
+// rooms marked high_demand by owners scoring below the threshold wait for an
+// admin to approve them:
+//
+//	LATE_CANCEL_WINDOW     e.g. "4h", default 2h before the start
+//	RELIABILITY_THRESHOLD  e.g. "0.8", default 0 which turns approvals off
+const (
+	MarkLateCancel = "late_cancel"
+	MarkNoShow     = "no_show"
+
+	noShowInterval    = 15 * time.Minute
+	reliabilityPeriod = 90 * 24 * time.Hour
+)
+
+type ReliabilityMark struct {
+	Id         storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	User       string           `json:"user"`
+	EventId    storage.ObjectId `json:"event_id"`
+	Kind       string           `json:"kind"`
+	StartTime  time.Time        `json:"start_time"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+type Reliability struct {
+	User          string            `json:"user"`
+	Since         time.Time         `json:"since"`
+	Bookings      int               `json:"bookings"`
+	LateCancels   int               `json:"late_cancels"`
+	NoShows       int               `json:"no_shows"`
+	Score         float64           `json:"score"`
+	NeedsApproval bool              `json:"needs_approval"`
+	Marks         []ReliabilityMark `json:"marks"`
+}
+
+func lateCancelWindow() time.Duration {
+	if d, err := time.ParseDuration(setting("LATE_CANCEL_WINDOW")); err == nil && d > 0 {
+		return d
+	}
+
+	return 2 * time.Hour
+}
+
+func reliabilityThreshold() float64 {
+	if v, err := strconv.ParseFloat(setting("RELIABILITY_THRESHOLD"), 64); err == nil && v > 0 {
+		return v
+	}
+
+	return 0
+}
+
+// Repo ReliabilityMark
+type ReliabilityMarkRepo struct {
+	coll *storage.Collection
+}
+
+func (r *ReliabilityMarkRepo) AllByUser(user string, since time.Time) ([]ReliabilityMark, error) {
+	result := []ReliabilityMark{}
+	query := bson.M{
+		"user":      user,
+		"starttime": bson.M{"$gte": since},
+	}
+
+	err := r.coll.Find(query).Sort("-starttime").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Create records mark unless the event, or the occurrence of a recurring
+// event, already has a mark of that kind.
+func (r *ReliabilityMarkRepo) Create(mark *ReliabilityMark) error {
+	selector := bson.M{"eventid": mark.EventId, "kind": mark.Kind, "starttime": mark.StartTime}
+	info, err := r.coll.Upsert(selector, bson.M{"$setOnInsert": mark})
+	if err != nil {
+		return err
+	}
+	if id, ok := info.UpsertedId.(storage.ObjectId); ok {
+		mark.Id = id
+	}
+
+	return nil
+}
+
+// recordLateCancel marks event, which is being deleted, as cancelled late if
+// it's within the window before its start and hasn't ended yet.
+func (c *appContext) recordLateCancel(event Event) {
+	t := clockNow()
+	if event.Owner == "" || t.Before(event.StartTime.Add(-lateCancelWindow())) || !t.Before(event.EndTime) {
+		return
+	}
+
+	repo := ReliabilityMarkRepo{c.db.C("reliability")}
+	mark := ReliabilityMark{
+		Id:         storage.NewObjectId(),
+		User:       event.Owner,
+		EventId:    event.Id,
+		Kind:       MarkLateCancel,
+		StartTime:  event.StartTime,
+		RecordedAt: t,
+	}
+	err := repo.Create(&mark)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// recordNoShows marks the events that ended since the previous run, with a
+// day of overlap, without a check-in.
+func (c *appContext) recordNoShows(ctx context.Context) error {
+	t := clockNow()
+	eventRepo := c.events()
+	events, err := eventRepo.EndedWithoutCheckIn(ctx, t.Add(-24*time.Hour), t)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.Owner == "" {
+			continue
+		}
+		repo := ReliabilityMarkRepo{c.inOrgOf(event.OrgId).db.C("reliability")}
+		mark := ReliabilityMark{
+			Id:         storage.NewObjectId(),
+			User:       event.Owner,
+			EventId:    event.Id,
+			Kind:       MarkNoShow,
+			StartTime:  event.StartTime,
+			RecordedAt: t,
+		}
+		err = repo.Create(&mark)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchNoShows runs recordNoShows for as long as the process runs.
+func (c *appContext) watchNoShows() {
+	for range time.Tick(noShowInterval) {
+		if err := c.recordNoShows(context.Background()); err != nil {
+			log.Println("Recording no-shows failed:", err)
+		}
+	}
+}
+
+// reliability scores user over the last reliabilityPeriod. A user without
+// bookings scores 1.
+func (c *appContext) reliability(ctx context.Context, user string) Reliability {
+	t := clockNow()
+	result := Reliability{User: user, Since: t.Add(-reliabilityPeriod), Score: 1}
+
+	repo := ReliabilityMarkRepo{c.db.C("reliability")}
+	marks, err := repo.AllByUser(user, result.Since)
+	if err != nil {
+		panic(err)
+	}
+	result.Marks = marks
+	for _, mark := range marks {
+		if mark.Kind == MarkLateCancel {
+			result.LateCancels++
+		} else {
+			result.NoShows++
+		}
+	}
+
+	// Cancelled events are gone from the events collection, so they're
+	// counted as bookings from their marks.
+	eventRepo := c.events()
+	events, err := eventRepo.AllByOwners(ctx, []string{user}, result.Since, t)
+	if err != nil {
+		panic(err)
+	}
+	result.Bookings = len(events) + result.LateCancels
+
+	if result.Bookings > 0 {
+		result.Score = 1 - float64(result.LateCancels+result.NoShows)/float64(result.Bookings)
+		if result.Score < 0 {
+			result.Score = 0
+		}
+	}
+	if threshold := reliabilityThreshold(); threshold > 0 {
+		result.NeedsApproval = result.Score < threshold
+	}
+
+	return result
+}
+
+// needsApproval reports whether event, being booked, has to wait for an
+// admin because its room is in high demand and its owner is unreliable.
+func (c *appContext) needsApproval(ctx context.Context, event Event) bool {
+	if reliabilityThreshold() == 0 || !storage.IsObjectIdHex(event.LocationID) {
+		return false
+	}
+
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(ctx, event.LocationID)
+	if err == ErrDocumentNotFound {
+		return false
+	}
+	if err != nil {
+		panic(err)
+	}
+	if !room.HighDemand {
+		return false
+	}
+
+	return c.reliability(ctx, event.Owner).NeedsApproval
+}
+
+// Reliability Handlers
+func (c *appContext) userReliabilityHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	WriteSuccess(w, http.StatusOK, c.reliability(r.Context(), params.ByName("user")))
+}
+
+func (c *appContext) pendingApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	repo := c.events()
+	events, err := repo.AllPendingApproval(r.Context())
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, events)
+}
+
+func (c *appContext) approveEventHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if !event.PendingApproval {
+		WriteError(w, ErrNotPending)
+		return
+	}
+
+	event.PendingApproval = false
+	err = repo.Replace(r.Context(), &event)
+	if err != nil {
+		panic(err)
+	}
+	c.recordChange("event", event.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusOK, event)
+}
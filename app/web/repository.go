@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ivansaputr4/ivana/internal/event"
+	"github.com/ivansaputr4/ivana/internal/postgres"
+	"github.com/ivansaputr4/ivana/internal/room"
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"github.com/ivansaputr4/ivana/internal/venue"
+)
+
+// Repositories
+//
+// Venues, rooms and events live in internal/venue, internal/room and
+// internal/event, each with a Repository interface, a MongoDB implementation
+// and an in-memory one, built by their constructors. The handlers reach
+// them through the interfaces, so they can run against either; an
+// appContext uses the ones in its repos, or the MongoDB repos of its db when
+// it's nil. The types keep their names here as aliases.
+//
+// The MongoDB repos read and write through repoPolicy, retrying reads
+// through a failover, see failover.go. The queries of features like
+// check-in, search or sync are part of the interfaces too, see
+// internal/event/features.go.
+//
+// Venues, rooms and events can be kept in PostgreSQL instead, see
+// internal/postgres:
+//
+//	DATABASE_URL  e.g. "postgres://ivana@localhost/ivana?sslmode=disable",
+//	              default empty for MongoDB
+//
+// The schema is migrated on start. Every venue, room and event lookup then
+// runs on PostgreSQL; the other features still keep their own data in
+// MongoDB.
+type (
+	Venue    = venue.Venue
+	RoomHint = venue.RoomHint
+	Room     = room.Room
+	Event    = event.Event
+
+	VenueRepository = venue.Repository
+	RoomRepository  = room.Repository
+	EventRepository = event.Repository
+	RoomFilter      = room.Filter
+	EventSearch     = event.Search
+	MapCoordinates  = room.MapCoordinates
+
+	OpeningHours = venue.OpeningHours
+	OpeningDay   = venue.OpeningDay
+	BookingRules = venue.BookingRules
+
+	EquipmentBooking  = event.EquipmentBooking
+	Participant       = event.Participant
+	RSVPSummary       = event.RSVPSummary
+	TravelWarning     = event.TravelWarning
+	Recurrence        = event.Recurrence
+	UpgradePreference = event.UpgradePreference
+	BookingSource     = event.BookingSource
+	BookingRequester  = event.BookingRequester
+	EventConflict     = event.Conflict
+)
+
+const (
+	FrequencyDaily   = event.FrequencyDaily
+	FrequencyWeekly  = event.FrequencyWeekly
+	FrequencyMonthly = event.FrequencyMonthly
+)
+
+var repoPolicy = storage.Policy{RetryRead: retryRead, WriteError: repoWriteError}
+
+func newVenueRepo(db *storage.Database) *venue.MongoRepository {
+	return venue.NewMongoRepository(db.C("venues"), repoPolicy)
+}
+
+func newRoomRepo(db *storage.Database) *room.MongoRepository {
+	return room.NewMongoRepository(db.C("rooms"), repoPolicy)
+}
+
+func newEventRepo(db *storage.Database) *event.MongoRepository {
+	return event.NewMongoRepository(db.C("events"), repoPolicy)
+}
+
+// Repositories replaces the MongoDB repos of an appContext.
+type Repositories struct {
+	Venues VenueRepository
+	Rooms  RoomRepository
+	Events EventRepository
+
+	// Users is the user directory of an appContext without a database, by
+	// email.
+	Users map[string]User
+}
+
+// NewMemRepositories returns a set of empty in-memory repos.
+func NewMemRepositories() *Repositories {
+	return &Repositories{
+		Venues: venue.NewMemRepository(),
+		Rooms:  room.NewMemRepository(),
+		Events: event.NewMemRepository(),
+	}
+}
+
+// openRepositories returns the PostgreSQL repos of url, DATABASE_URL, or
+// nil to keep venues, rooms and events in MongoDB when it's empty.
+func openRepositories(url string) (*Repositories, error) {
+	if url == "" {
+		return nil, nil
+	}
+
+	db, err := postgres.Open(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repositories{
+		Venues: venue.NewPostgresRepository(db),
+		Rooms:  room.NewPostgresRepository(db),
+		Events: event.NewPostgresRepository(db),
+	}, nil
+}
+
+// newAppContext returns the context of the app over db, using repos rather
+// than the MongoDB repos of db unless it's nil.
+func newAppContext(db *storage.Database, repos *Repositories) *appContext {
+	return &appContext{db: db, repos: repos, config: &Config{}}
+}
+
+func (c *appContext) venues() VenueRepository {
+	if c.repos != nil {
+		return c.repos.Venues
+	}
+
+	return newVenueRepo(c.db)
+}
+
+func (c *appContext) rooms() RoomRepository {
+	if c.repos != nil {
+		return c.repos.Rooms
+	}
+
+	return newRoomRepo(c.db)
+}
+
+func (c *appContext) events() EventRepository {
+	if c.repos != nil {
+		return c.repos.Events
+	}
+
+	return newEventRepo(c.db)
+}
+
+// resolveVenue finds a venue by ObjectId, current slug or old slug. The
+// returned bool is false when key is an old slug and the caller should
+// redirect.
+func resolveVenue(ctx context.Context, repo VenueRepository, key string) (Venue, bool, error) {
+	if storage.IsObjectIdHex(key) {
+		venue, err := repo.Find(ctx, key)
+		return venue, true, err
+	}
+
+	venue, err := repo.FindBySlug(ctx, key)
+	if err != ErrDocumentNotFound {
+		return venue, true, err
+	}
+
+	venue, err = repo.FindByOldSlug(ctx, key)
+	return venue, false, err
+}
+
+// resolveRoom finds a room of the given venue by ObjectId, current slug or
+// old slug. The returned bool is false when key is an old slug.
+func resolveRoom(ctx context.Context, repo RoomRepository, venueId string, key string) (Room, bool, error) {
+	if storage.IsObjectIdHex(key) {
+		room, err := repo.Find(ctx, key)
+		if err == nil && room.VenueId != venueId {
+			return room, true, ErrDocumentNotFound
+		}
+		return room, true, err
+	}
+
+	room, err := repo.FindBySlug(ctx, venueId, key)
+	if err != ErrDocumentNotFound {
+		return room, true, err
+	}
+
+	room, err = repo.FindByOldSlug(ctx, venueId, key)
+	return room, false, err
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Request context
+//
+// Middleware hands what it found out about a request down the chain as
+// values of the request's context, under these keys. A middleware that sets
+// one passes the request returned by withValue on to the next handler.
+type contextKey string
+
+const (
+	paramsKey      contextKey = "params"
+	bodyKey        contextKey = "body"
+	userKey        contextKey = "user"
+	guestTokenKey  contextKey = "guest_token"
+	bookingLinkKey contextKey = "booking_link"
+	apiKeyKey      contextKey = "api_key"
+	sourceKey      contextKey = "source"
+	appKey         contextKey = "app"
+	versionKey     contextKey = "version"
+)
+
+func withValue(r *http.Request, key contextKey, value interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), key, value))
+}
+
+// routeParams returns the path parameters httprouter matched for r.
+func routeParams(r *http.Request) httprouter.Params {
+	params, _ := r.Context().Value(paramsKey).(httprouter.Params)
+	return params
+}
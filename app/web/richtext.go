@@ -0,0 +1,146 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Rich text
+//
+// Event descriptions accept a limited Markdown dialect: paragraphs, line
+// breaks, "- " bullet lists, **bold**, *italic*, `code` and [links](url).
+// HTML tags, and what's within script and style elements, are stripped from
+// the input before it is stored, and the rendered form escapes everything
+// that isn't produced by the renderer itself, so the stored HTML is safe to
+// inject into door panels and the web UI.
+var (
+	htmlTag        = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlScript     = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(script|style)\s*>`)
+	markdownCode   = regexp.MustCompile("`([^`]+)`")
+	markdownBold   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	markdownItalic = regexp.MustCompile(`\*([^*]+)\*`)
+	markdownLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+const maxDescriptionLength = 10000
+
+// sanitizeDescription removes HTML markup and control characters from a raw
+// description.
+func sanitizeDescription(s string) string {
+	s = htmlScript.ReplaceAllString(s, "")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Replace(s, "\r", "", -1)
+	if len([]rune(s)) > maxDescriptionLength {
+		s = string([]rune(s)[:maxDescriptionLength])
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// renderDescription renders a sanitized description to HTML.
+func renderDescription(src string) string {
+	if src == "" {
+		return ""
+	}
+
+	out := []string{}
+	for _, block := range strings.Split(src, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+		if isMarkdownList(lines) {
+			items := []string{}
+			for _, line := range lines {
+				item := strings.TrimSpace(line)[2:]
+				items = append(items, "<li>"+renderInline(item)+"</li>")
+			}
+			out = append(out, "<ul>"+strings.Join(items, "")+"</ul>")
+			continue
+		}
+
+		rendered := []string{}
+		for _, line := range lines {
+			rendered = append(rendered, renderInline(strings.TrimSpace(line)))
+		}
+		out = append(out, "<p>"+strings.Join(rendered, "<br>")+"</p>")
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func isMarkdownList(lines []string) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func renderInline(s string) string {
+	s = html.EscapeString(s)
+	s = markdownCode.ReplaceAllString(s, "<code>$1</code>")
+	s = markdownLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := markdownLink.FindStringSubmatch(m)
+		if !safeLink(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return `<a href="` + parts[2] + `" rel="nofollow noopener">` + parts[1] + `</a>`
+	})
+	s = markdownBold.ReplaceAllString(s, "<strong>$1</strong>")
+	s = markdownItalic.ReplaceAllString(s, "<em>$1</em>")
+
+	return s
+}
+
+func safeLink(url string) bool {
+	url = strings.ToLower(url)
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "mailto:")
+}
+
+// renderHTML reports whether the caller asked for descriptions rendered as
+// HTML with ?render=html.
+func renderHTML(r *http.Request) bool {
+	return r.URL.Query().Get("render") == "html"
+}
+
+// withRenderedDescriptions swaps the raw description of each event for its
+// rendered form.
+func withRenderedDescriptions(events []Event) []Event {
+	for idx := range events {
+		events[idx].Description = renderedDescription(events[idx])
+	}
+
+	return events
+}
+
+// setDescription stores the sanitized raw description of e and its rendered
+// form.
+func setDescription(e *Event, raw string) {
+	e.Description = sanitizeDescription(raw)
+	e.DescriptionHTML = renderDescription(e.Description)
+}
+
+// renderedDescription returns the HTML form of the description of e,
+// rendering it on the fly for events stored before descriptions were
+// rendered on write.
+func renderedDescription(e Event) string {
+	if e.DescriptionHTML == "" && e.Description != "" {
+		return renderDescription(sanitizeDescription(e.Description))
+	}
+
+	return e.DescriptionHTML
+}
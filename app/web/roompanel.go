@@ -0,0 +1,243 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Room panels
+//
+// Tablets mounted outside meeting rooms show GET /rooms/:id/panel: whether
+// the room is free or occupied, and until when, the current meeting and the
+// next one within panelHorizon, and the actions the caller may take. Panels call through a
+// panel API key issued for their room, see apikey.go, which also lets them
+// take these actions on it, as admins can on any room:
+//
+//	POST /rooms/:id/panel/book    books the room from now for minutes, 30 by
+//	                              default, or until the next meeting
+//	POST /rooms/:id/panel/extend  extends the current meeting by minutes
+//	POST /rooms/:id/panel/end     ends the current meeting now
+//
+// Quick bookings are checked in, since someone is at the room, and booked
+// via the panel channel, see attribution.go. Meetings are extended and ended
+// like events are, see extend.go, except for occurrences of recurring ones.
+const (
+	PanelFree     = "free"
+	PanelOccupied = "occupied"
+
+	PanelBook   = "book"
+	PanelExtend = "extend"
+	PanelEnd    = "end"
+
+	panelHorizon     = 24 * time.Hour
+	defaultQuickBook = 30
+	quickBookName    = "Quick booking"
+)
+
+var (
+	ErrPanelKeyRoom      = &Error{"panel_key_room", 422, "Unprocessable Entity", "Panel keys must name the room_id of an existing room."}
+	ErrNoCurrentMeeting  = &Error{"no_current_meeting", 409, "Conflict", "No meeting is taking place in the room."}
+	ErrPanelOccurrence   = &Error{"panel_occurrence", 409, "Conflict", "Occurrences of recurring meetings can't be changed from a panel."}
+	ErrNotPanelOfTheRoom = &Error{"not_panel_of_the_room", 403, "Forbidden", "Only the room's panel or an admin can do this."}
+)
+
+type RoomPanel struct {
+	Room      Room       `json:"room"`
+	Status    string     `json:"status"`
+	Current   *Event     `json:"current,omitempty"`
+	Next      *Event     `json:"next,omitempty"`
+	FreeAt    *time.Time `json:"free_at,omitempty"`
+	FreeUntil *time.Time `json:"free_until,omitempty"`
+	Actions   []string   `json:"actions"`
+}
+
+type PanelAction struct {
+	Minutes int    `json:"minutes"`
+	Name    string `json:"name"`
+}
+
+// roomPanel tells the state of room at now from its events, listing the
+// actions only when the caller may take them.
+func roomPanel(room Room, events []Event, now time.Time, canAct bool) RoomPanel {
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+
+	result := RoomPanel{Room: room, Status: PanelFree, Actions: []string{}}
+	for i := range events {
+		event := events[i]
+		if !event.StartTime.After(now) && event.EndTime.After(now) {
+			if result.Current == nil {
+				result.Current = &event
+			}
+			continue
+		}
+		if event.StartTime.After(now) && result.Next == nil {
+			result.Next = &event
+		}
+	}
+
+	if result.Current != nil {
+		result.Status = PanelOccupied
+		// The room frees up after the meetings following back to back.
+		freeAt := result.Current.EndTime
+		for _, event := range events {
+			if !event.StartTime.After(freeAt) && event.EndTime.After(freeAt) {
+				freeAt = event.EndTime
+			}
+		}
+		result.FreeAt = &freeAt
+	} else if result.Next != nil {
+		result.FreeUntil = &result.Next.StartTime
+	}
+	if !canAct {
+		return result
+	}
+	if result.Current == nil {
+		result.Actions = append(result.Actions, PanelBook)
+	} else if !result.Current.IsOccurrence() {
+		result.Actions = append(result.Actions, PanelExtend, PanelEnd)
+	}
+
+	return result
+}
+
+// canActOnPanel reports whether the caller of r may take the actions of the
+// panel of roomId: it's the room's panel or an admin.
+func canActOnPanel(r *http.Request, roomId string) bool {
+	if key, ok := r.Context().Value(apiKeyKey).(APIKey); ok && key.Scope == APIKeyPanel {
+		return key.RoomId == roomId
+	}
+	user, ok := r.Context().Value(userKey).(User)
+
+	return ok && user.IsAdmin()
+}
+
+// panelOf reads the room of the request and its panel at now.
+func (c *appContext) panelOf(r *http.Request, now time.Time) (RoomPanel, error) {
+	params := routeParams(r)
+	room, err := c.rooms().Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		return RoomPanel{}, err
+	}
+	events, err := c.events().Overlapping(r.Context(), room.Id.Hex(), now, now.Add(panelHorizon), "")
+	if err != nil {
+		return RoomPanel{}, err
+	}
+
+	return roomPanel(room, events, now, canActOnPanel(r, room.Id.Hex())), nil
+}
+
+// Middleware
+// requirePanel lets the request through when its caller may take the
+// actions of the panel of the room :id.
+func requirePanel(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		params := routeParams(r)
+		if !canActOnPanel(r, params.ByName("id")) {
+			WriteError(w, ErrNotPanelOfTheRoom)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Room Panel Handlers
+func (c *appContext) roomPanelHandler(w http.ResponseWriter, r *http.Request) {
+	panel, err := c.panelOf(r, clockNow())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, panel)
+}
+
+func (c *appContext) panelBookHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*PanelAction)
+	user := r.Context().Value(userKey).(User)
+	now := clockNow().Truncate(time.Minute)
+	panel, err := c.panelOf(r, now)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if panel.Current != nil {
+		writeRoomOccupied(w, panel, now)
+		return
+	}
+	if body.Minutes == 0 {
+		body.Minutes = defaultQuickBook
+	}
+	if body.Name == "" {
+		body.Name = quickBookName
+	}
+
+	event := Event{
+		Name:      body.Name,
+		Owner:     user.Email,
+		EndTime:   now.Add(time.Duration(body.Minutes) * time.Minute),
+		BookedVia: BookingSource{Channel: ChannelPanel, Integration: panel.Room.Id.Hex()},
+	}
+	if errRes := c.bookFromNow(r.Context(), panel, now, &event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	WriteSuccess(w, http.StatusCreated, event)
+}
+
+func (c *appContext) panelExtendHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*PanelAction)
+	panel, err := c.panelOf(r, clockNow())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if panel.Current == nil {
+		WriteError(w, ErrNoCurrentMeeting)
+		return
+	}
+	if panel.Current.IsOccurrence() {
+		WriteError(w, ErrPanelOccurrence)
+		return
+	}
+	if body.Minutes == 0 {
+		body.Minutes = defaultQuickBook
+	}
+
+	event, errRes := c.extendEvent(r.Context(), *panel.Current, body.Minutes)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	WriteSuccess(w, http.StatusAccepted, event)
+}
+
+func (c *appContext) panelEndHandler(w http.ResponseWriter, r *http.Request) {
+	now := clockNow()
+	panel, err := c.panelOf(r, now)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if panel.Current == nil {
+		WriteError(w, ErrNoCurrentMeeting)
+		return
+	}
+	if panel.Current.IsOccurrence() {
+		WriteError(w, ErrPanelOccurrence)
+		return
+	}
+
+	event, errRes := c.endEvent(r.Context(), *panel.Current, now)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	WriteSuccess(w, http.StatusAccepted, event)
+}
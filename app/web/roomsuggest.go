@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Room suggestions
+//
+// POST /rooms/suggest finds the rooms free between start_time and end_time
+// that seat attendees and offer every one of amenities, within venue_id if
+// given, and ranks them: the closest fit first, by the fewest spare seats,
+// then those on a floor of a neighborhood of the caller's team, see desk.go,
+// then the least used, by the hours booked over the last roomUsagePeriod. Rooms
+// whose venue is closed then, or whose booking rules refuse the window, are
+// left out. Up to limit rooms are returned, 10 by default.
+const (
+	roomUsagePeriod       = 28 * 24 * time.Hour
+	defaultRoomSuggestion = 10
+)
+
+type RoomSuggestionRequest struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Attendees int       `json:"attendees"`
+	Amenities []string  `json:"amenities"`
+	VenueId   string    `json:"venue_id"`
+	Limit     int       `json:"limit"`
+}
+
+type RoomSuggestion struct {
+	Room        Room    `json:"room"`
+	SpareSeats  int     `json:"spare_seats"`
+	TeamFloor   bool    `json:"team_floor"`
+	BookedHours float64 `json:"booked_hours"`
+}
+
+// hasAmenities reports whether room offers every one of amenities, compared
+// case-insensitively.
+func hasAmenities(room Room, amenities []string) bool {
+	for _, amenity := range amenities {
+		found := false
+		for _, offered := range room.Amenities {
+			if strings.EqualFold(offered, amenity) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rankRooms ranks rooms for attendees, see Room suggestions, with the floors
+// of the caller's team and the hours booked of each room.
+func rankRooms(rooms []Room, attendees int, teamFloors map[string]bool, hours map[string]float64) []RoomSuggestion {
+	result := []RoomSuggestion{}
+	for _, room := range rooms {
+		result = append(result, RoomSuggestion{
+			Room:        room,
+			SpareSeats:  room.Capacity - attendees,
+			TeamFloor:   room.FloorId != "" && teamFloors[room.FloorId],
+			BookedHours: hours[room.Id.Hex()],
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.SpareSeats != b.SpareSeats {
+			return a.SpareSeats < b.SpareSeats
+		}
+		if a.TeamFloor != b.TeamFloor {
+			return a.TeamFloor
+		}
+		if a.BookedHours != b.BookedHours {
+			return a.BookedHours < b.BookedHours
+		}
+		return a.Room.Name < b.Room.Name
+	})
+
+	return result
+}
+
+// Repo Neighborhood teams
+func (r *NeighborhoodRepo) AllByTeam(team string) ([]Neighborhood, error) {
+	result := []Neighborhood{}
+	err := r.coll.Find(bson.M{"team": team}).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Room Suggestion Handlers
+func (c *appContext) suggestRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*RoomSuggestionRequest)
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+	if body.Limit == 0 {
+		body.Limit = defaultRoomSuggestion
+	}
+
+	var rooms []Room
+	var err error
+	if body.VenueId != "" {
+		rooms, err = c.rooms().AllByVenueId(r.Context(), body.VenueId)
+	} else {
+		rooms, _, err = c.rooms().AllMatching(r.Context(), RoomFilter{MinCapacity: body.Attendees}, ListOptions{})
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	repo := c.events()
+	candidates := []Room{}
+	ids := []string{}
+	for _, room := range rooms {
+		if room.Capacity < body.Attendees || !hasAmenities(room, body.Amenities) {
+			continue
+		}
+		probe := Event{LocationID: room.Id.Hex(), StartTime: body.StartTime, EndTime: body.EndTime}
+		if errRes := c.checkVenueRules(r.Context(), probe); errRes != nil {
+			continue
+		}
+		busy, err := repo.Overlapping(r.Context(), room.Id.Hex(), body.StartTime, body.EndTime, "")
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		if len(busy) == 0 {
+			candidates = append(candidates, room)
+			ids = append(ids, room.Id.Hex())
+		}
+	}
+
+	hours := map[string]float64{}
+	if len(ids) > 0 {
+		now := clockNow()
+		past, err := repo.AllByLocationIds(r.Context(), ids, now.Add(-roomUsagePeriod), now)
+		if err != nil {
+			WriteRepoError(w, err)
+			return
+		}
+		for _, event := range past {
+			hours[event.LocationID] += event.EndTime.Sub(event.StartTime).Hours()
+		}
+	}
+
+	teamFloors := map[string]bool{}
+	if user := r.Context().Value(userKey).(User); user.Team != "" {
+		neighborhoods, err := (&NeighborhoodRepo{c.db.C("neighborhoods")}).AllByTeam(user.Team)
+		if err != nil {
+			panic(err)
+		}
+		for _, neighborhood := range neighborhoods {
+			teamFloors[neighborhood.FloorId] = true
+		}
+	}
+
+	result := rankRooms(candidates, body.Attendees, teamFloors, hours)
+	if len(result) > body.Limit {
+		result = result[:body.Limit]
+	}
+
+	WriteSuccess(w, http.StatusOK, result)
+}
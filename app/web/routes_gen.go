@@ -0,0 +1,200 @@
+// Code generated by sdkgen; DO NOT EDIT.
+
+package main
+
+var generatedRoutes = []generatedRoute{
+	{"GET", "/venues/:id", "/venues/:id", "venueHandler", "", false, ""},
+	{"PATCH", "/venues/:id", "/venues/:id", "updateVenueHandler", "venue", true, "admin"},
+	{"DELETE", "/venues/:id", "/venues/:id", "deleteVenueHandler", "", false, "admin"},
+	{"GET", "/venues", "/venues", "venuesHandler", "", false, ""},
+	{"POST", "/venues", "/venues", "createVenueHandler", "venue", true, "admin"},
+	{"GET", "/venues/:id/rooms", "/venues/:id/rooms", "roomsVenueHandler", "", false, ""},
+	{"GET", "/venues/:id/floor-plans", "/venues/:id/floor-plans", "venueFloorPlansHandler", "", false, ""},
+	{"GET", "/venues/:id/photos", "/venues/:id/photos", "venuePhotosHandler", "", false, ""},
+	{"POST", "/venues/:id/photos", "/venues/:id/photos", "uploadVenuePhotoHandler", "", false, "admin"},
+	{"GET", "/venues/:id/rooms/:room", "/venues/:id/rooms/:room", "venueRoomHandler", "", false, ""},
+	{"GET", "/rooms/compare", "/rooms/:id", "compareRoomsHandler", "", false, ""},
+	{"GET", "/rooms/:id", "/rooms/:id", "roomHandler", "", false, ""},
+	{"PATCH", "/rooms/:id", "/rooms/:id", "updateRoomHandler", "room", true, "admin"},
+	{"DELETE", "/rooms/:id", "/rooms/:id", "deleteRoomHandler", "", false, "admin"},
+	{"POST", "/rooms/:id/book-now", "/rooms/:id/book-now", "bookNowHandler", "book_now", true, "user"},
+	{"GET", "/rooms/:id/photos", "/rooms/:id/photos", "roomPhotosHandler", "", false, ""},
+	{"POST", "/rooms/:id/photos", "/rooms/:id/photos", "uploadRoomPhotoHandler", "", false, "admin"},
+	{"GET", "/photos/:id/image", "/photos/:id/image", "photoImageHandler", "", false, ""},
+	{"GET", "/photos/:id/thumbnail", "/photos/:id/thumbnail", "photoThumbnailHandler", "", false, ""},
+	{"DELETE", "/photos/:id", "/photos/:id", "deletePhotoHandler", "", false, "admin"},
+	{"GET", "/rooms", "/rooms", "roomsHandler", "", false, ""},
+	{"POST", "/rooms", "/rooms", "createRoomHandler", "room", true, "admin"},
+	{"POST", "/rooms/suggest", "/rooms/:id", "suggestRoomsHandler", "room_suggestion", true, "user"},
+	{"POST", "/rooms/:id", "/rooms/:id", "", "", false, ""},
+	{"GET", "/events/search", "/events/:id", "searchEventsHandler", "", false, ""},
+	{"GET", "/events/export", "/events/:id", "exportEventsHandler", "", false, ""},
+	{"GET", "/events/:id", "/events/:id", "eventHandler", "", false, ""},
+	{"PATCH", "/events/:id", "/events/:id", "updateEventHandler", "event", true, "user"},
+	{"DELETE", "/events/:id", "/events/:id", "deleteEventHandler", "", false, "user"},
+	{"POST", "/events/:id/cancel", "/events/:id/cancel", "cancelEventHandler", "event_cancellation", true, "user"},
+	{"POST", "/events/:id/extend", "/events/:id/extend", "extendEventHandler", "event_extension", true, "user"},
+	{"POST", "/events/:id/end", "/events/:id/end", "endEventHandler", "", false, "user"},
+	{"POST", "/events", "/events", "createEventHandler", "event", true, "user"},
+	{"GET", "/events", "/events", "eventsHandler", "", false, ""},
+	{"POST", "/events/import", "/events/:id", "importEventsHandler", "", false, "user"},
+	{"POST", "/events/:id", "/events/:id", "", "", false, ""},
+	{"GET", "/event-imports/:id", "/event-imports/:id", "eventImportHandler", "", false, "user"},
+	{"POST", "/event-imports/:id/confirm", "/event-imports/:id/confirm", "confirmEventImportHandler", "", false, "user"},
+	{"GET", "/rooms/:id/booking-link", "/rooms/:id/booking-link", "roomBookingLinkHandler", "", false, "admin"},
+	{"PUT", "/rooms/:id/booking-link", "/rooms/:id/booking-link", "updateRoomBookingLinkHandler", "booking_link", true, "admin"},
+	{"DELETE", "/rooms/:id/booking-link", "/rooms/:id/booking-link", "deleteRoomBookingLinkHandler", "", false, "admin"},
+	{"GET", "/book/:token", "/book/:token", "bookingPageHandler", "", false, ""},
+	{"POST", "/book/:token", "/book/:token", "bookThroughLinkHandler", "booking_request", true, ""},
+	{"POST", "/rooms/:id/events", "/rooms/:id/events", "createEventHandler", "event", true, "user"},
+	{"GET", "/venues/:id/events", "/venues/:id/events", "venueEventsHandler", "", false, ""},
+	{"GET", "/venues/:id/presence", "/venues/:id/presence", "venuePresenceHandler", "", false, ""},
+	{"POST", "/integrations/inbound/:source", "/integrations/inbound/:source", "inboundBookingHandler", "inbound_booking", true, ""},
+	{"POST", "/integrations/msgraph/notifications", "/integrations/msgraph/notifications", "graphNotificationsHandler", "", false, ""},
+	{"GET", "/equipment/:id/availability", "/equipment/:id/availability", "equipmentAvailabilityHandler", "", false, ""},
+	{"GET", "/equipment/:id", "/equipment/:id", "equipmentHandler", "", false, ""},
+	{"PATCH", "/equipment/:id", "/equipment/:id", "updateEquipmentHandler", "equipment", true, "admin"},
+	{"DELETE", "/equipment/:id", "/equipment/:id", "deleteEquipmentHandler", "", false, "admin"},
+	{"GET", "/equipment", "/equipment", "equipmentListHandler", "", false, ""},
+	{"POST", "/equipment", "/equipment", "createEquipmentHandler", "equipment", true, "admin"},
+	{"POST", "/events/:id/attachments", "/events/:id/attachments", "uploadEventAttachmentHandler", "", false, ""},
+	{"GET", "/events/:id/attachments", "/events/:id/attachments", "eventAttachmentsHandler", "", false, ""},
+	{"GET", "/attachments/:id/download", "/attachments/:id/download", "downloadAttachmentHandler", "", false, ""},
+	{"GET", "/attachments/:id", "/attachments/:id", "attachmentHandler", "", false, ""},
+	{"GET", "/users/:user", "/users/:user", "userHandler", "", false, ""},
+	{"PUT", "/users/:user", "/users/:user", "updateUserHandler", "user", true, "user"},
+	{"GET", "/users", "/users", "usersHandler", "", false, ""},
+	{"GET", "/locale", "/locale", "localeHandler", "", false, ""},
+	{"GET", "/sync", "/sync", "syncHandler", "", false, "user"},
+	{"GET", "/me/events", "/me/events", "myEventsHandler", "", false, "user"},
+	{"GET", "/users/:user/events", "/users/:user/events", "userEventsHandler", "", false, "user"},
+	{"GET", "/me/limits", "/me/limits", "myLimitsHandler", "", false, "user"},
+	{"GET", "/users/:user/reliability", "/users/:user/reliability", "userReliabilityHandler", "", false, ""},
+	{"POST", "/events/:id/approve", "/events/:id/approve", "approveEventHandler", "", false, "admin"},
+	{"GET", "/admin/approvals", "/admin/approvals", "pendingApprovalsHandler", "", false, "admin"},
+	{"GET", "/teams", "/teams", "teamCapsHandler", "", false, ""},
+	{"PUT", "/teams/:team/cap", "/teams/:team/cap", "updateTeamCapHandler", "team_cap", true, "admin"},
+	{"POST", "/events/:id/feedback", "/events/:id/feedback", "createFeedbackHandler", "feedback", true, ""},
+	{"GET", "/rooms/:id/feedback/summary", "/rooms/:id/feedback/summary", "roomFeedbackSummaryHandler", "", false, ""},
+	{"GET", "/reports/maintenance", "/reports/maintenance", "maintenanceReportHandler", "", false, ""},
+	{"GET", "/reports/fairness", "/reports/fairness", "fairnessReportHandler", "", false, ""},
+	{"GET", "/reports/adoption", "/reports/adoption", "adoptionReportHandler", "", false, ""},
+	{"GET", "/analytics/rooms/:id/utilization", "/analytics/rooms/:id/utilization", "roomUtilizationHandler", "", false, "admin"},
+	{"GET", "/analytics/venues/:id/utilization", "/analytics/venues/:id/utilization", "venueUtilizationHandler", "", false, "admin"},
+	{"GET", "/users/:user/working-hours", "/users/:user/working-hours", "workingHoursHandler", "", false, ""},
+	{"PUT", "/users/:user/working-hours", "/users/:user/working-hours", "updateWorkingHoursHandler", "working_hours", true, "user"},
+	{"GET", "/find-a-time", "/find-a-time", "findTimeHandler", "", false, ""},
+	{"POST", "/freebusy", "/freebusy", "freeBusyHandler", "free_busy", true, "user"},
+	{"GET", "/users/:user/schedule-check", "/users/:user/schedule-check", "scheduleCheckHandler", "", false, ""},
+	{"POST", "/checkin/code", "/checkin/code", "checkInCodeHandler", "check_in", true, ""},
+	{"GET", "/admin/bumped-events", "/admin/bumped-events", "bumpedEventsHandler", "", false, "admin"},
+	{"GET", "/travel-times", "/travel-times", "travelTimesHandler", "", false, ""},
+	{"PUT", "/travel-times", "/travel-times", "updateTravelTimeHandler", "travel_time", true, "admin"},
+	{"POST", "/admin/venues/onboard", "/admin/venues/onboard", "onboardVenueHandler", "venue_onboarding", true, "admin"},
+	{"GET", "/event-series/:id/occurrences", "/event-series/:id/occurrences", "seriesOccurrencesHandler", "", false, ""},
+	{"GET", "/event-series/:id", "/event-series/:id", "eventSeriesHandler", "", false, ""},
+	{"PATCH", "/event-series/:id", "/event-series/:id", "updateEventSeriesHandler", "event_series", true, "user"},
+	{"DELETE", "/event-series/:id", "/event-series/:id", "deleteEventSeriesHandler", "", false, "user"},
+	{"POST", "/event-series", "/event-series", "createEventSeriesHandler", "event_series", true, "user"},
+	{"GET", "/event-groups/:id", "/event-groups/:id", "eventGroupHandler", "", false, ""},
+	{"PATCH", "/event-groups/:id", "/event-groups/:id", "updateEventGroupHandler", "event_group", true, "user"},
+	{"DELETE", "/event-groups/:id", "/event-groups/:id", "deleteEventGroupHandler", "", false, "user"},
+	{"POST", "/event-groups/:id/reschedule", "/event-groups/:id/reschedule", "rescheduleEventGroupHandler", "event_group_reschedule", true, "user"},
+	{"POST", "/event-groups/:id/cancel", "/event-groups/:id/cancel", "cancelEventGroupHandler", "", false, "user"},
+	{"GET", "/event-groups", "/event-groups", "eventGroupsHandler", "", false, ""},
+	{"POST", "/event-groups", "/event-groups", "createEventGroupHandler", "event_group", true, "user"},
+	{"GET", "/rooms/:id/panel", "/rooms/:id/panel", "roomPanelHandler", "", false, "user"},
+	{"POST", "/rooms/:id/panel/book", "/rooms/:id/panel/book", "panelBookHandler", "panel_action", true, "user"},
+	{"POST", "/rooms/:id/panel/extend", "/rooms/:id/panel/extend", "panelExtendHandler", "panel_action", true, "user"},
+	{"POST", "/rooms/:id/panel/end", "/rooms/:id/panel/end", "panelEndHandler", "", false, "user"},
+	{"GET", "/rooms/:id/panel/content", "/rooms/:id/panel/content", "roomPanelContentHandler", "", false, ""},
+	{"GET", "/venues/:id/panel/content", "/venues/:id/panel/content", "venuePanelContentHandler", "", false, ""},
+	{"POST", "/venues/:id/panel/content", "/venues/:id/panel/content", "createPanelContentHandler", "panel_content", true, "admin"},
+	{"PATCH", "/panel/content/:id", "/panel/content/:id", "updatePanelContentHandler", "panel_content", true, "admin"},
+	{"DELETE", "/panel/content/:id", "/panel/content/:id", "deletePanelContentHandler", "", false, "admin"},
+	{"GET", "/venues/:id/announcements", "/venues/:id/announcements", "venueAnnouncementsHandler", "", false, ""},
+	{"POST", "/venues/:id/announcements", "/venues/:id/announcements", "createAnnouncementHandler", "announcement", true, "admin"},
+	{"PATCH", "/announcements/:id", "/announcements/:id", "updateAnnouncementHandler", "announcement", true, "admin"},
+	{"DELETE", "/announcements/:id", "/announcements/:id", "deleteAnnouncementHandler", "", false, "admin"},
+	{"GET", "/parking/spots/:id", "/parking/spots/:id", "parkingSpotHandler", "", false, ""},
+	{"PATCH", "/parking/spots/:id", "/parking/spots/:id", "updateParkingSpotHandler", "parking_spot", true, "admin"},
+	{"DELETE", "/parking/spots/:id", "/parking/spots/:id", "deleteParkingSpotHandler", "", false, "admin"},
+	{"GET", "/parking/spots", "/parking/spots", "parkingSpotsHandler", "", false, ""},
+	{"POST", "/parking/spots", "/parking/spots", "createParkingSpotHandler", "parking_spot", true, "admin"},
+	{"DELETE", "/parking/reservations/:id", "/parking/reservations/:id", "deleteParkingReservationHandler", "", false, "user"},
+	{"GET", "/parking/reservations", "/parking/reservations", "parkingReservationsHandler", "", false, ""},
+	{"POST", "/parking/reservations", "/parking/reservations", "createParkingReservationHandler", "parking_reservation", true, "user"},
+	{"GET", "/events/:id/parking", "/events/:id/parking", "eventParkingHandler", "", false, ""},
+	{"GET", "/events/:id/ical", "/events/:id/ical", "eventICalHandler", "", false, ""},
+	{"POST", "/events/:id/guest-tokens", "/events/:id/guest-tokens", "createGuestTokenHandler", "guest_token", true, "user"},
+	{"DELETE", "/guest-tokens/:id", "/guest-tokens/:id", "revokeGuestTokenHandler", "", false, "user"},
+	{"POST", "/events/:id/rsvp", "/events/:id/rsvp", "rsvpEventHandler", "rsvp", true, "user"},
+	{"POST", "/events/:id/check-in", "/events/:id/check-in", "checkInEventHandler", "", false, "user"},
+	{"GET", "/rooms/:id/calendar.ics", "/rooms/:id/calendar.ics", "roomCalendarHandler", "", false, ""},
+	{"GET", "/floors/:id/map", "/floors/:id/map", "floorMapHandler", "", false, ""},
+	{"PUT", "/floors/:id/plan", "/floors/:id/plan", "uploadFloorPlanHandler", "", false, "admin"},
+	{"GET", "/floors/:id/plan", "/floors/:id/plan", "floorPlanHandler", "", false, ""},
+	{"DELETE", "/floors/:id/plan", "/floors/:id/plan", "deleteFloorPlanHandler", "", false, "admin"},
+	{"GET", "/floors/:id/available", "/floors/:id/available", "availableDesksHandler", "", false, ""},
+	{"GET", "/floors/:id/neighborhoods", "/floors/:id/neighborhoods", "neighborhoodsHandler", "", false, ""},
+	{"POST", "/floors/:id/neighborhoods", "/floors/:id/neighborhoods", "createNeighborhoodHandler", "neighborhood", true, "admin"},
+	{"GET", "/floors/:id/desks", "/floors/:id/desks", "desksHandler", "", false, ""},
+	{"POST", "/floors/:id/desks", "/floors/:id/desks", "createDeskHandler", "desk", true, "admin"},
+	{"GET", "/floors/:id", "/floors/:id", "floorHandler", "", false, ""},
+	{"PATCH", "/floors/:id", "/floors/:id", "updateFloorHandler", "floor", true, "admin"},
+	{"DELETE", "/floors/:id", "/floors/:id", "deleteFloorHandler", "", false, "admin"},
+	{"GET", "/floors", "/floors", "floorsHandler", "", false, ""},
+	{"POST", "/floors", "/floors", "createFloorHandler", "floor", true, "admin"},
+	{"PATCH", "/neighborhoods/:id", "/neighborhoods/:id", "updateNeighborhoodHandler", "neighborhood", true, ""},
+	{"DELETE", "/neighborhoods/:id", "/neighborhoods/:id", "deleteNeighborhoodHandler", "", false, ""},
+	{"GET", "/desks/:id", "/desks/:id", "deskHandler", "", false, ""},
+	{"PATCH", "/desks/:id", "/desks/:id", "updateDeskHandler", "desk", true, "admin"},
+	{"DELETE", "/desks/:id", "/desks/:id", "deleteDeskHandler", "", false, "admin"},
+	{"POST", "/desks/:id/bookings", "/desks/:id/bookings", "createDeskBookingHandler", "desk_booking", true, "user"},
+	{"GET", "/desk-bookings", "/desk-bookings", "deskBookingsHandler", "", false, ""},
+	{"DELETE", "/desk-bookings/:id", "/desk-bookings/:id", "deleteDeskBookingHandler", "", false, "user"},
+	{"GET", "/examples", "/examples", "examplesHandler", "", false, ""},
+	{"GET", "/examples/:resource", "/examples/:resource", "exampleHandler", "", false, ""},
+	{"POST", "/venues/:id/restore", "/venues/:id/restore", "restoreVenueHandler", "", false, "admin"},
+	{"POST", "/rooms/:id/restore", "/rooms/:id/restore", "restoreRoomHandler", "", false, "admin"},
+	{"POST", "/events/:id/restore", "/events/:id/restore", "restoreEventHandler", "", false, "user"},
+	{"GET", "/audit-logs", "/audit-logs", "auditLogsHandler", "", false, "admin"},
+	{"GET", "/changes", "/changes", "changesHandler", "", false, "user"},
+	{"GET", "/graphql", "/graphql", "", "", false, ""},
+	{"POST", "/graphql", "/graphql", "", "graphql", true, ""},
+	{"GET", "/ws", "/ws", "", "", false, "user"},
+	{"GET", "/admin/api-keys", "/admin/api-keys", "apiKeysHandler", "", false, "admin"},
+	{"POST", "/admin/api-keys", "/admin/api-keys", "createAPIKeyHandler", "api_key", true, "admin"},
+	{"DELETE", "/admin/api-keys/:id", "/admin/api-keys/:id", "revokeAPIKeyHandler", "", false, "admin"},
+	{"GET", "/admin/webhooks", "/admin/webhooks", "webhooksHandler", "", false, "admin"},
+	{"POST", "/admin/webhooks", "/admin/webhooks", "createWebhookHandler", "webhook", true, "admin"},
+	{"DELETE", "/admin/webhooks/:id", "/admin/webhooks/:id", "deleteWebhookHandler", "", false, "admin"},
+	{"GET", "/admin/webhooks/:id/deliveries", "/admin/webhooks/:id/deliveries", "webhookDeliveriesHandler", "", false, "admin"},
+	{"GET", "/admin/organizations", "/admin/organizations", "organizationsHandler", "", false, "admin"},
+	{"POST", "/admin/organizations", "/admin/organizations", "createOrganizationHandler", "organization", true, "admin"},
+	{"GET", "/admin/organizations/:id/members", "/admin/organizations/:id/members", "organizationMembersHandler", "", false, "admin"},
+	{"PUT", "/admin/organizations/:id/members/:user", "/admin/organizations/:id/members/:user", "addOrganizationMemberHandler", "", false, "admin"},
+	{"DELETE", "/admin/organizations/:id/members/:user", "/admin/organizations/:id/members/:user", "removeOrganizationMemberHandler", "", false, "admin"},
+	{"GET", "/admin/deprecations", "/admin/deprecations", "deprecationUsageHandler", "", false, "admin"},
+	{"GET", "/admin/audit/export", "/admin/audit/export", "auditExportHandler", "", false, "admin"},
+	{"GET", "/admin/errors/summary", "/admin/errors/summary", "errorSummaryHandler", "", false, "admin"},
+	{"GET", "/admin/health/history", "/admin/health/history", "healthHistoryHandler", "", false, "admin"},
+	{"GET", "/admin/locations/backfills", "/admin/locations/backfills", "locationBackfillsHandler", "", false, "admin"},
+	{"POST", "/admin/locations/backfills", "/admin/locations/backfills", "createLocationBackfillHandler", "", false, "admin"},
+	{"GET", "/admin/locations/backfills/:id", "/admin/locations/backfills/:id", "locationBackfillHandler", "", false, "admin"},
+	{"GET", "/admin/locations/report", "/admin/locations/report", "locationReportHandler", "", false, "admin"},
+	{"GET", "/admin/chaos", "/admin/chaos", "chaosSettingsHandler", "", false, "admin"},
+	{"PUT", "/admin/chaos", "/admin/chaos", "updateChaosHandler", "chaos", true, "admin"},
+	{"DELETE", "/admin/chaos", "/admin/chaos", "resetChaosHandler", "", false, "admin"},
+	{"POST", "/admin/chaos/jobs/:job", "/admin/chaos/jobs/:job", "runChaosJobHandler", "", false, "admin"},
+	{"GET", "/admin/database/failovers", "/admin/database/failovers", "failoverStatsHandler", "", false, "admin"},
+	{"GET", "/admin/legal-holds", "/admin/legal-holds", "legalHoldsHandler", "", false, "admin"},
+	{"POST", "/admin/legal-holds", "/admin/legal-holds", "createLegalHoldHandler", "legal_hold", true, "admin"},
+	{"POST", "/admin/legal-holds/:id/lift", "/admin/legal-holds/:id/lift", "liftLegalHoldHandler", "", false, "admin"},
+	{"GET", "/healthz", "/healthz", "", "", false, ""},
+	{"GET", "/readyz", "/readyz", "readyHandler", "", false, ""},
+	{"GET", "/schemas/:name", "/schemas/:name", "schemaDocHandler", "", false, ""},
+	{"GET", "/schemas", "/schemas", "schemaDocsHandler", "", false, ""},
+	{"GET", "/openapi.json", "/openapi.json", "", "", false, ""},
+	{"GET", "/docs", "/docs", "", "", false, ""},
+}
@@ -0,0 +1,140 @@
+package main
+
+import "net/http"
+
+// RSVP
+//
+// The guests of an event are its participants, each with an answer that
+// needs_action until they reply with POST /events/:id/rsvp, or with a guest
+// token scoped to rsvp. Participants are required unless the event lists
+// them as optional in participants when it's created or updated; emails
+// listed there only are added to its guests. Event responses carry the
+// participants and an rsvp summary of their answers. The answers of a
+// recurring event hold for the whole series.
+const (
+	RSVPNeedsAction = "needs_action"
+	RSVPAccepted    = "accepted"
+	RSVPDeclined    = "declined"
+	RSVPTentative   = "tentative"
+)
+
+type RSVPRequest struct {
+	Status string `json:"status"`
+}
+
+// guestList returns the guests of e with the participants it names.
+func (e *EventResponse) guestList() []string {
+	guests := e.Guests
+	for _, p := range e.Participants {
+		if !contains(guests, p.Email) {
+			guests = append(guests, p.Email)
+		}
+	}
+
+	return guests
+}
+
+// syncParticipants makes the participants of event match its guests, keeping
+// the answers of existing and the optional flags event was sent with.
+func syncParticipants(event *Event, existing []Participant) {
+	sent := map[string]Participant{}
+	for _, p := range event.Participants {
+		sent[p.Email] = p
+	}
+
+	event.Participants = existing
+	result := participants(*event)
+	for i, p := range result {
+		if s, ok := sent[p.Email]; ok {
+			result[i].Optional = s.Optional
+		}
+	}
+	event.Participants = result
+	event.RSVP = rsvpSummary(*event)
+}
+
+// participants returns a participant for each guest of event, the ones who
+// haven't got one yet, like the guests of events booked before there were
+// participants, needing to answer.
+func participants(event Event) []Participant {
+	known := map[string]Participant{}
+	for _, p := range event.Participants {
+		known[p.Email] = p
+	}
+
+	result := []Participant{}
+	for _, guest := range event.Guests {
+		p, ok := known[guest]
+		if !ok {
+			p = Participant{Email: guest, Status: RSVPNeedsAction}
+		}
+		result = append(result, p)
+	}
+
+	return result
+}
+
+func rsvpSummary(event Event) *RSVPSummary {
+	summary := &RSVPSummary{}
+	for _, p := range participants(event) {
+		switch p.Status {
+		case RSVPAccepted:
+			summary.Accepted++
+		case RSVPDeclined:
+			summary.Declined++
+		case RSVPTentative:
+			summary.Tentative++
+		default:
+			summary.NeedsAction++
+		}
+		if !p.Optional && p.Status != RSVPAccepted {
+			summary.RequiredPending++
+		}
+	}
+
+	return summary
+}
+
+// withRSVP sets the rsvp summary of events.
+func withRSVP(events []Event) {
+	for i := range events {
+		events[i].RSVP = rsvpSummary(events[i])
+	}
+}
+
+// RSVP Handlers
+func (c *appContext) rsvpEventHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*RSVPRequest)
+	repo := c.events()
+	event, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	user := r.Context().Value(userKey).(User)
+	if !contains(event.Guests, user.Email) {
+		WriteError(w, ErrNotParticipant)
+		return
+	}
+
+	now := clockNow()
+	event.Participants = participants(event)
+	for i, p := range event.Participants {
+		if p.Email == user.Email {
+			event.Participants[i].Status = body.Status
+			event.Participants[i].RespondedAt = &now
+		}
+	}
+
+	err = repo.Update(r.Context(), &event)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeUpdated)
+	event.RSVP = rsvpSummary(event)
+
+	WriteSuccess(w, http.StatusOK, event)
+}
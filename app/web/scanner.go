@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scanners
+//
+// Uploaded files are run through a Scanner before they can be downloaded.
+// The driver is picked with SCANNER:
+//
+//	clamav  clamd INSTREAM over TCP at CLAMAV_ADDR (default localhost:3310)
+//	icap    ICAP RESPMOD against ICAP_URL, e.g. icap://av.local:1344/avscan
+//	none    accept everything, for local development only
+//
+// When SCANNER is unset uploads are refused.
+type ScanResult struct {
+	Clean     bool
+	Signature string
+}
+
+type Scanner interface {
+	Scan(name string, r io.Reader) (ScanResult, error)
+}
+
+var ErrScannerNotConfigured = errors.New("no virus scanner configured")
+
+const scanTimeout = 2 * time.Minute
+
+func newScanner() (Scanner, error) {
+	switch setting("SCANNER") {
+	case "clamav":
+		addr := setting("CLAMAV_ADDR")
+		if addr == "" {
+			addr = "localhost:3310"
+		}
+		return &ClamAVScanner{Addr: addr}, nil
+	case "icap":
+		u, err := url.Parse(setting("ICAP_URL"))
+		if err != nil || u.Scheme != "icap" || u.Host == "" {
+			return nil, fmt.Errorf("ICAP_URL must look like icap://host:1344/service")
+		}
+		return &ICAPScanner{URL: u}, nil
+	case "none":
+		return noopScanner{}, nil
+	case "":
+		return nil, ErrScannerNotConfigured
+	}
+
+	return nil, fmt.Errorf("unknown SCANNER %q", setting("SCANNER"))
+}
+
+type noopScanner struct{}
+
+func (noopScanner) Scan(name string, r io.Reader) (ScanResult, error) {
+	return ScanResult{Clean: true}, nil
+}
+
+// ClamAVScanner streams files to clamd with the INSTREAM command.
+type ClamAVScanner struct {
+	Addr string
+}
+
+func (s *ClamAVScanner) Scan(name string, r io.Reader) (ScanResult, error) {
+	conn, err := net.DialTimeout("tcp", s.Addr, 10*time.Second)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(scanTimeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, err
+	}
+
+	buf := make([]byte, 32*1024)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, werr := conn.Write(size); werr != nil {
+				return ScanResult{}, werr
+			}
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return ScanResult{}, werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, err
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return ScanResult{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return ScanResult{}, err
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	// Replies look like "stream: OK" or "stream: Eicar-Signature FOUND".
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return ScanResult{Clean: true}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return ScanResult{Clean: false, Signature: signature}, nil
+	}
+
+	return ScanResult{}, fmt.Errorf("clamd: unexpected reply %q", reply)
+}
+
+// ICAPScanner submits files as the body of an HTTP response with ICAP
+// RESPMOD. A 204 means the server didn't need to modify the response, i.e.
+// the file is clean; anything it blocks or rewrites is treated as infected.
+type ICAPScanner struct {
+	URL *url.URL
+}
+
+func (s *ICAPScanner) Scan(name string, r io.Reader) (ScanResult, error) {
+	body, err := readAllLimited(r)
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	host := s.URL.Host
+	if s.URL.Port() == "" {
+		host += ":1344"
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(scanTimeout))
+
+	reqHdr := "GET /" + url.PathEscape(name) + " HTTP/1.1\r\nHost: ivana\r\n\r\n"
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n"
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "RESPMOD %s ICAP/1.0\r\n", s.URL.String())
+	fmt.Fprintf(msg, "Host: %s\r\n", s.URL.Host)
+	fmt.Fprintf(msg, "Allow: 204\r\n")
+	fmt.Fprintf(msg, "Encapsulated: req-hdr=0, res-hdr=%d, res-body=%d\r\n\r\n", len(reqHdr), len(reqHdr)+len(resHdr))
+	msg.WriteString(reqHdr)
+	msg.WriteString(resHdr)
+	if len(body) > 0 {
+		fmt.Fprintf(msg, "%x\r\n", len(body))
+		msg.Write(body)
+		msg.WriteString("\r\n")
+	}
+	msg.WriteString("0\r\n\r\n")
+
+	if _, err := conn.Write(msg.Bytes()); err != nil {
+		return ScanResult{}, err
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	status, err := tp.ReadLine()
+	if err != nil {
+		return ScanResult{}, err
+	}
+	headers, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return ScanResult{}, err
+	}
+
+	parts := strings.SplitN(status, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return ScanResult{}, fmt.Errorf("icap: unexpected status line %q", status)
+	}
+
+	switch parts[1] {
+	case "204":
+		return ScanResult{Clean: true}, nil
+	case "200":
+		signature := headers.Get("X-Infection-Found")
+		if signature == "" {
+			signature = headers.Get("X-Virus-ID")
+		}
+		if signature == "" {
+			signature = "blocked by ICAP server"
+		}
+		return ScanResult{Clean: false, Signature: signature}, nil
+	}
+
+	return ScanResult{}, fmt.Errorf("icap: unexpected status %q", status)
+}
+
+func readAllLimited(r io.Reader) ([]byte, error) {
+	b := &bytes.Buffer{}
+	n, err := io.Copy(b, io.LimitReader(r, maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxAttachmentSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxAttachmentSize)
+	}
+
+	return b.Bytes(), nil
+}
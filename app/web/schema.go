@@ -0,0 +1,972 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// JSON Schemas
+//
+// Every request body accepted by the API has a schema here. Payloads are
+// validated against it before bodyHandler decodes them, and the same documents
+// are served at /schemas/:name so clients can validate before sending.
+var schemaSources = map[string]string{
+	"venue": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/venue",
+  "title": "Venue",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "slug": {"type": "string", "pattern": "^[a-z0-9]+(-[a-z0-9]+)*$", "maxLength": 100},
+    "time_zone": {"type": "string", "maxLength": 64},
+    "opening_hours": {
+      "type": "object",
+      "properties": {
+        "days": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["weekday", "open", "close"],
+            "properties": {
+              "weekday": {"type": "integer", "minimum": 0, "maximum": 6},
+              "open": {"type": "string", "pattern": "^([01][0-9]|2[0-3]):[0-5][0-9]$"},
+              "close": {"type": "string", "pattern": "^([01][0-9]|2[0-3]):[0-5][0-9]$"}
+            }
+          }
+        },
+        "holidays": {"type": "array", "items": {"type": "string", "format": "date"}}
+      }
+    },
+    "booking_rules": {
+      "type": "object",
+      "properties": {
+        "max_advance_days": {"type": "integer", "minimum": 0},
+        "min_duration": {"type": "integer", "minimum": 0},
+        "max_duration": {"type": "integer", "minimum": 0}
+      }
+    },
+    "names": {"type": "object"},
+    "description": {"type": "string", "maxLength": 2000},
+    "descriptions": {"type": "object"}
+  }
+}`,
+	"room": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/room",
+  "title": "Room",
+  "type": "object",
+  "required": ["name", "venue_id", "capacity"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "slug": {"type": "string", "pattern": "^[a-z0-9]+(-[a-z0-9]+)*$", "maxLength": 100},
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "floor_id": {"type": "string", "pattern": "^([0-9a-fA-F]{24})?$"},
+    "capacity": {"type": "integer", "minimum": 1},
+    "amenities": {"type": "array", "items": {"type": "string", "minLength": 1, "maxLength": 50}},
+    "high_demand": {"type": "boolean"},
+    "release_after": {"type": "integer", "minimum": 0},
+    "building": {"type": "string", "maxLength": 100},
+    "floor": {"type": "string", "maxLength": 50},
+    "map_coordinates": {
+      "type": "object",
+      "required": ["x", "y"],
+      "properties": {
+        "x": {"type": "number", "minimum": 0},
+        "y": {"type": "number", "minimum": 0}
+      }
+    },
+    "photo_url": {"type": "string", "maxLength": 2000},
+    "names": {"type": "object"},
+    "description": {"type": "string", "maxLength": 2000},
+    "descriptions": {"type": "object"}
+  }
+}`,
+	"event": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event",
+  "title": "Event",
+  "type": "object",
+  "required": ["name", "location_id"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "location_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "location": {"type": "string"},
+    "description": {"type": "string"},
+    "guests": {"type": "array", "items": {"type": "string", "format": "email"}},
+    "participants": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["email"],
+        "properties": {
+          "email": {"type": "string", "format": "email"},
+          "optional": {"type": "boolean"}
+        }
+      }
+    },
+    "owner": {"type": "string"},
+    "date": {"type": "integer", "minimum": 1, "maximum": 31},
+    "month": {"type": "integer", "minimum": 1, "maximum": 12},
+    "year": {"type": "integer", "minimum": 1970},
+    "start_hour": {"type": "integer", "minimum": 0, "maximum": 23},
+    "start_minute": {"type": "integer", "minimum": 0, "maximum": 59},
+    "end_hour": {"type": "integer", "minimum": 0, "maximum": 23},
+    "end_minute": {"type": "integer", "minimum": 0, "maximum": 59},
+    "time_zone": {"type": "string", "maxLength": 64},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "category": {"type": "string", "maxLength": 50},
+    "tentative": {"type": "boolean"},
+    "recurrence": {
+      "type": "object",
+      "required": ["frequency"],
+      "properties": {
+        "frequency": {"type": "string", "enum": ["daily", "weekly", "monthly"]},
+        "interval": {"type": "integer", "minimum": 1},
+        "weekdays": {"type": "array", "items": {"type": "integer", "minimum": 0, "maximum": 6}},
+        "until": {"type": "string", "format": "date-time"},
+        "count": {"type": "integer", "minimum": 1},
+        "exceptions": {"type": "array", "items": {"type": "string", "format": "date-time"}},
+        "week_start": {"type": "string", "enum": ["sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"]}
+      }
+    },
+    "group_id": {"type": "string", "pattern": "^([0-9a-fA-F]{24})?$"},
+    "upgrade": {
+      "type": "object",
+      "properties": {
+        "rooms": {"type": "array", "items": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}},
+        "min_capacity": {"type": "integer", "minimum": 1}
+      }
+    },
+    "equipment": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["equipment_id", "quantity"],
+        "properties": {
+          "equipment_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+          "quantity": {"type": "integer", "minimum": 1}
+        }
+      }
+    }
+  }
+}`,
+	"equipment": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/equipment",
+  "title": "Equipment",
+  "type": "object",
+  "required": ["name", "venue_id", "quantity"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "quantity": {"type": "integer", "minimum": 0}
+  }
+}`,
+	"floor": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/floor",
+  "title": "Floor",
+  "type": "object",
+  "required": ["name", "venue_id"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "level": {"type": "integer"},
+    "map_image_url": {"type": "string"},
+    "width": {"type": "number", "minimum": 0},
+    "height": {"type": "number", "minimum": 0}
+  }
+}`,
+	"neighborhood": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/neighborhood",
+  "title": "Neighborhood",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "team": {"type": "string", "maxLength": 200}
+  }
+}`,
+	"desk": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/desk",
+  "title": "Desk",
+  "type": "object",
+  "required": ["label", "x", "y"],
+  "properties": {
+    "label": {"type": "string", "minLength": 1, "maxLength": 50},
+    "neighborhood_id": {"type": "string", "pattern": "^([0-9a-fA-F]{24})?$"},
+    "x": {"type": "number", "minimum": 0},
+    "y": {"type": "number", "minimum": 0},
+    "amenities": {"type": "array", "items": {"type": "string"}}
+  }
+}`,
+	"desk_booking": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/desk_booking",
+  "title": "DeskBooking",
+  "type": "object",
+  "required": ["user", "date", "slot"],
+  "properties": {
+    "user": {"type": "string", "minLength": 1},
+    "team": {"type": "string"},
+    "date": {"type": "string", "pattern": "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"},
+    "slot": {"type": "string", "enum": ["am", "pm", "full"]}
+  }
+}`,
+	"parking_spot": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/parking_spot",
+  "title": "ParkingSpot",
+  "type": "object",
+  "required": ["venue_id", "label"],
+  "properties": {
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "label": {"type": "string", "minLength": 1, "maxLength": 50},
+    "kind": {"type": "string", "enum": ["standard", "ev", "accessible"]}
+  }
+}`,
+	"parking_reservation": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/parking_reservation",
+  "title": "ParkingReservation",
+  "type": "object",
+  "required": ["license_plate"],
+  "properties": {
+    "spot_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "event_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "visitor": {"type": "string", "maxLength": 200},
+    "license_plate": {"type": "string", "minLength": 2, "maxLength": 15},
+    "kind": {"type": "string", "enum": ["standard", "ev", "accessible"]},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"}
+  }
+}`,
+	"panel_content": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/panel_content",
+  "title": "PanelContent",
+  "type": "object",
+  "required": ["kind"],
+  "properties": {
+    "room_ids": {"type": "array", "items": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}},
+    "kind": {"type": "string", "enum": ["text", "image"]},
+    "title": {"type": "string", "maxLength": 200},
+    "body": {"type": "string", "maxLength": 2000},
+    "image_url": {"type": "string", "pattern": "^https?://"},
+    "attachment_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "duration": {"type": "integer", "minimum": 0, "maximum": 300},
+    "priority": {"type": "integer"},
+    "starts_at": {"type": "string", "format": "date-time"},
+    "ends_at": {"type": "string", "format": "date-time"},
+    "created_by": {"type": "string"}
+  }
+}`,
+	"working_hours": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/working_hours",
+  "title": "WorkingHours",
+  "type": "object",
+  "required": ["days"],
+  "properties": {
+    "time_zone": {"type": "string"},
+    "days": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["weekday", "start", "end"],
+        "properties": {
+          "weekday": {"type": "integer", "minimum": 0, "maximum": 6},
+          "start": {"type": "string", "pattern": "^([01][0-9]|2[0-3]):[0-5][0-9]$"},
+          "end": {"type": "string", "pattern": "^([01][0-9]|2[0-3]):[0-5][0-9]$"}
+        }
+      }
+    }
+  }
+}`,
+	"travel_time": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/travel_time",
+  "title": "TravelTime",
+  "type": "object",
+  "required": ["from_venue_id", "to_venue_id", "minutes"],
+  "properties": {
+    "from_venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "to_venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "minutes": {"type": "integer", "minimum": 0, "maximum": 1440}
+  }
+}`,
+	"check_in": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/check_in",
+  "title": "CheckInRequest",
+  "type": "object",
+  "required": ["room_id", "code"],
+  "properties": {
+    "room_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "code": {"type": "string", "pattern": "^[0-9]{6}$"}
+  }
+}`,
+	"user": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/user",
+  "title": "User",
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "maxLength": 200},
+    "team": {"type": "string", "maxLength": 100},
+    "role": {"type": "string", "enum": ["admin", "user"]},
+    "hide_presence": {"type": "boolean"}
+  }
+}`,
+	"team_cap": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/team_cap",
+  "title": "TeamCap",
+  "type": "object",
+  "required": ["weekly_hours"],
+  "properties": {
+    "weekly_hours": {"type": "number", "minimum": 0},
+    "mode": {"type": "string", "enum": ["soft", "hard"]}
+  }
+}`,
+	"feedback": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/feedback",
+  "title": "Feedback",
+  "type": "object",
+  "required": ["attendee", "comfort", "av", "cleanliness"],
+  "properties": {
+    "attendee": {"type": "string", "minLength": 1},
+    "comfort": {"type": "integer", "minimum": 1, "maximum": 5},
+    "av": {"type": "integer", "minimum": 1, "maximum": 5},
+    "cleanliness": {"type": "integer", "minimum": 1, "maximum": 5},
+    "comment": {"type": "string", "maxLength": 2000}
+  }
+}`,
+	"event_series": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event_series",
+  "title": "EventSeries",
+  "type": "object",
+  "required": ["name", "location_id", "start_time", "end_time", "recurrence"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "location_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "location": {"type": "string"},
+    "description": {"type": "string"},
+    "guests": {"type": "array", "items": {"type": "string", "format": "email"}},
+    "owner": {"type": "string"},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "recurrence": {
+      "type": "object",
+      "required": ["frequency"],
+      "properties": {
+        "frequency": {"type": "string", "enum": ["daily", "weekly", "monthly"]},
+        "interval": {"type": "integer", "minimum": 1},
+        "weekdays": {"type": "array", "items": {"type": "integer", "minimum": 0, "maximum": 6}},
+        "until": {"type": "string", "format": "date-time"},
+        "count": {"type": "integer", "minimum": 1},
+        "exceptions": {"type": "array", "items": {"type": "string", "format": "date-time"}},
+        "week_start": {"type": "string", "enum": ["sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"]}
+      }
+    }
+  }
+}`,
+	"venue_onboarding": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/venue_onboarding",
+  "title": "VenueOnboarding",
+  "type": "object",
+  "required": ["name", "floors"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "slug": {"type": "string", "pattern": "^[a-z0-9]+(-[a-z0-9]+)*$", "maxLength": 100},
+    "floors": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 200},
+          "level": {"type": "integer"},
+          "map_image_url": {"type": "string"},
+          "width": {"type": "number", "minimum": 0},
+          "height": {"type": "number", "minimum": 0},
+          "rooms": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["name", "capacity"],
+              "properties": {
+                "name": {"type": "string", "minLength": 1, "maxLength": 200},
+                "slug": {"type": "string", "pattern": "^[a-z0-9]+(-[a-z0-9]+)*$", "maxLength": 100},
+                "capacity": {"type": "integer", "minimum": 1}
+              }
+            }
+          },
+          "neighborhoods": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["name"],
+              "properties": {
+                "name": {"type": "string", "minLength": 1, "maxLength": 200},
+                "team": {"type": "string", "maxLength": 200}
+              }
+            }
+          },
+          "desks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["label", "x", "y"],
+              "properties": {
+                "label": {"type": "string", "minLength": 1, "maxLength": 50},
+                "neighborhood": {"type": "string"},
+                "x": {"type": "number", "minimum": 0},
+                "y": {"type": "number", "minimum": 0},
+                "amenities": {"type": "array", "items": {"type": "string"}}
+              }
+            }
+          }
+        }
+      }
+    },
+    "equipment": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "quantity"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 200},
+          "quantity": {"type": "integer", "minimum": 0}
+        }
+      }
+    },
+    "parking_spots": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["label"],
+        "properties": {
+          "label": {"type": "string", "minLength": 1, "maxLength": 50},
+          "kind": {"type": "string", "enum": ["standard", "ev", "accessible"]}
+        }
+      }
+    }
+  }
+}`,
+	"announcement": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/announcement",
+  "title": "Announcement",
+  "type": "object",
+  "required": ["title"],
+  "properties": {
+    "kind": {"type": "string", "enum": ["closure", "fire_drill", "notice"]},
+    "title": {"type": "string", "minLength": 1, "maxLength": 200},
+    "body": {"type": "string", "maxLength": 2000},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "display_from": {"type": "string", "format": "date-time"},
+    "display_until": {"type": "string", "format": "date-time"},
+    "created_by": {"type": "string"}
+  }
+}`,
+	"legal_hold": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/legal_hold",
+  "title": "LegalHold",
+  "type": "object",
+  "required": ["reason"],
+  "properties": {
+    "user": {"type": "string", "format": "email"},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "reason": {"type": "string", "minLength": 1, "maxLength": 2000}
+  }
+}`,
+	"guest_token": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/guest_token",
+  "title": "GuestToken",
+  "type": "object",
+  "required": ["guest"],
+  "properties": {
+    "guest": {"type": "string", "format": "email"},
+    "scopes": {"type": "array", "items": {"type": "string", "enum": ["view", "rsvp", "waitlist", "check_in"]}}
+  }
+}`,
+	"rsvp": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/rsvp",
+  "title": "RSVP",
+  "type": "object",
+  "required": ["status"],
+  "properties": {
+    "status": {"type": "string", "enum": ["accepted", "declined", "tentative"]}
+  }
+}`,
+	"api_key": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/api_key",
+  "title": "APIKey",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "scope": {"type": "string", "enum": ["read", "read_write", "panel"]},
+    "user": {"type": "string", "format": "email"},
+    "room_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}
+  }
+}`,
+	"event_extension": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event_extension",
+  "title": "EventExtension",
+  "type": "object",
+  "required": ["minutes"],
+  "properties": {
+    "minutes": {"type": "integer", "minimum": 5, "maximum": 480}
+  }
+}`,
+	"book_now": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/book_now",
+  "title": "BookNowRequest",
+  "type": "object",
+  "properties": {
+    "duration": {"type": "integer", "minimum": 5, "maximum": 480},
+    "name": {"type": "string", "minLength": 1, "maxLength": 200}
+  }
+}`,
+	"panel_action": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/panel_action",
+  "title": "PanelAction",
+  "type": "object",
+  "properties": {
+    "minutes": {"type": "integer", "minimum": 5, "maximum": 240},
+    "name": {"type": "string", "minLength": 1, "maxLength": 200}
+  }
+}`,
+	"webhook": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/webhook",
+  "title": "Webhook",
+  "type": "object",
+  "required": ["url", "events"],
+  "properties": {
+    "url": {"type": "string", "pattern": "^https://", "maxLength": 2000},
+    "events": {"type": "array", "minItems": 1, "maxItems": 50, "items": {"type": "string", "pattern": "^(\\*|[a-z_]+\\.(created|updated|deleted|restored|cancelled))$"}},
+    "secret": {"type": "string", "minLength": 16, "maxLength": 200}
+  }
+}`,
+	"organization": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/organization",
+  "title": "Organization",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200}
+  }
+}`,
+	"graphql": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/graphql",
+  "title": "GraphQL request",
+  "type": "object",
+  "required": ["query"],
+  "properties": {
+    "query": {"type": "string", "minLength": 1}
+  }
+}`,
+	"event_group": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event_group",
+  "title": "EventGroup",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "description": {"type": "string", "maxLength": 5000},
+    "owner": {"type": "string", "format": "email"}
+  }
+}`,
+	"event_group_reschedule": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event_group_reschedule",
+  "title": "EventGroupReschedule",
+  "type": "object",
+  "required": ["offset_minutes"],
+  "properties": {
+    "offset_minutes": {"type": "integer", "minimum": -525600, "maximum": 525600}
+  }
+}`,
+	"event_cancellation": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/event_cancellation",
+  "title": "EventCancellation",
+  "type": "object",
+  "required": ["reason"],
+  "properties": {
+    "reason": {"type": "string", "minLength": 1, "maxLength": 500}
+  }
+}`,
+	"room_suggestion": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/room_suggestion",
+  "title": "RoomSuggestionRequest",
+  "type": "object",
+  "required": ["start_time", "end_time", "attendees"],
+  "properties": {
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "attendees": {"type": "integer", "minimum": 1},
+    "amenities": {"type": "array", "items": {"type": "string", "minLength": 1}},
+    "venue_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "limit": {"type": "integer", "minimum": 1, "maximum": 50}
+  }
+}`,
+	"free_busy": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/free_busy",
+  "title": "FreeBusyRequest",
+  "type": "object",
+  "required": ["start_time", "end_time"],
+  "properties": {
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "users": {"type": "array", "maxItems": 50, "items": {"type": "string", "format": "email"}},
+    "room_ids": {"type": "array", "maxItems": 50, "items": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}}
+  }
+}`,
+	"booking_link": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/booking_link",
+  "title": "BookingLink",
+  "type": "object",
+  "properties": {
+    "auto_confirm": {"type": "boolean"},
+    "days_ahead": {"type": "integer", "minimum": 1, "maximum": 365}
+  }
+}`,
+	"booking_request": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/booking_request",
+  "title": "BookingRequest",
+  "type": "object",
+  "required": ["name", "email", "start_time", "end_time"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 200},
+    "email": {"type": "string", "format": "email"},
+    "company": {"type": "string", "maxLength": 200},
+    "title": {"type": "string", "maxLength": 200},
+    "description": {"type": "string", "maxLength": 5000},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"}
+  }
+}`,
+	"chaos": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/chaos",
+  "title": "ChaosSettings",
+  "type": "object",
+  "properties": {
+    "latency_ms": {"type": "integer", "minimum": 0, "maximum": 120000},
+    "latency_path": {"type": "string"},
+    "fail_reads": {"type": "integer", "minimum": 0},
+    "fail_writes": {"type": "integer", "minimum": 0},
+    "frozen_at": {"type": "string", "format": "date-time"}
+  }
+}`,
+	"inbound_booking": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "/schemas/inbound_booking",
+  "title": "InboundBooking",
+  "type": "object",
+  "required": ["external_id"],
+  "properties": {
+    "external_id": {"type": "string", "minLength": 1},
+    "room_id": {"type": "string", "pattern": "^[0-9a-fA-F]{24}$"},
+    "name": {"type": "string", "maxLength": 200},
+    "description": {"type": "string"},
+    "owner": {"type": "string"},
+    "guests": {"type": "array", "items": {"type": "string", "format": "email"}},
+    "start_time": {"type": "string", "format": "date-time"},
+    "end_time": {"type": "string", "format": "date-time"},
+    "cancelled": {"type": "boolean"}
+  }
+}`,
+}
+
+type Schema struct {
+	Type       string             `json:"type"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Enum       []interface{}      `json:"enum"`
+	Format     string             `json:"format"`
+	Pattern    string             `json:"pattern"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	MinItems   *int               `json:"minItems"`
+	MaxItems   *int               `json:"maxItems"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+var schemas = map[string]*Schema{}
+
+var emailFormat = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+func init() {
+	for name, src := range schemaSources {
+		s := &Schema{}
+		if err := json.Unmarshal([]byte(src), s); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", name, err))
+		}
+		if err := s.compile(); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", name, err))
+		}
+		schemas[name] = s
+	}
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+
+	return nil
+}
+
+// Validate checks v, a value produced by json.Unmarshal into an interface{},
+// against the schema and returns one message per violation.
+func (s *Schema) Validate(v interface{}) []string {
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) []string {
+	field := path
+	if field == "" {
+		field = "body"
+	}
+
+	if !s.matchesType(v) {
+		return []string{fmt.Sprintf("%s: must be of type %s", field, s.Type)}
+	}
+
+	msgs := []string{}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("%s: must be one of %v", field, s.Enum))
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		if s.MinLength != nil && len([]rune(val)) < *s.MinLength {
+			msgs = append(msgs, fmt.Sprintf("%s: must be at least %d characters", field, *s.MinLength))
+		}
+		if s.MaxLength != nil && len([]rune(val)) > *s.MaxLength {
+			msgs = append(msgs, fmt.Sprintf("%s: must be at most %d characters", field, *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			msgs = append(msgs, fmt.Sprintf("%s: must match %s", field, s.Pattern))
+		}
+		if s.Format == "email" && !emailFormat.MatchString(val) {
+			msgs = append(msgs, fmt.Sprintf("%s: must be a valid email address", field))
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: must be an RFC 3339 date-time", field))
+			}
+		}
+		if s.Format == "date" {
+			if _, err := time.Parse("2006-01-02", val); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: must be an RFC 3339 full-date", field))
+			}
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			msgs = append(msgs, fmt.Sprintf("%s: must be greater than or equal to %v", field, *s.Minimum))
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			msgs = append(msgs, fmt.Sprintf("%s: must be less than or equal to %v", field, *s.Maximum))
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			msgs = append(msgs, fmt.Sprintf("%s: must have at least %d items", field, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			msgs = append(msgs, fmt.Sprintf("%s: must have at most %d items", field, *s.MaxItems))
+		}
+		if s.Items != nil {
+			for idx, item := range val {
+				msgs = append(msgs, s.Items.validate(fmt.Sprintf("%s[%d]", field, idx), item)...)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				msgs = append(msgs, fmt.Sprintf("%s: is required", joinPath(path, name)))
+			}
+		}
+
+		names := []string{}
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if pv, ok := val[name]; ok {
+				msgs = append(msgs, s.Properties[name].validate(joinPath(path, name), pv)...)
+			}
+		}
+	}
+
+	return msgs
+}
+
+func (s *Schema) matchesType(v interface{}) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "null":
+		return v == nil
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// Middleware
+func schemaHandler(name string) func(http.Handler) http.Handler {
+	s, ok := schemas[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown schema %q", name))
+	}
+
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+
+			var v interface{}
+			if err := json.Unmarshal(b, &v); err != nil {
+				WriteError(w, ErrBadRequest)
+				return
+			}
+
+			if msgs := s.Validate(v); len(msgs) > 0 {
+				errs := []*Error{}
+				for _, msg := range msgs {
+					errs = append(errs, &Error{"validation_failed", http.StatusUnprocessableEntity, "Unprocessable Entity", msg})
+				}
+				WriteErrors(w, http.StatusUnprocessableEntity, errs)
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
+
+// Schema Handlers
+func (c *appContext) schemaDocsHandler(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for name := range schemaSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []string{}
+	for _, name := range names {
+		result = append(result, "/schemas/"+name)
+	}
+
+	WriteSuccess(w, http.StatusOK, result)
+}
+
+func (c *appContext) schemaDocHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	src, ok := schemaSources[params.ByName("name")]
+	if !ok {
+		WriteError(w, ErrNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(src))
+}
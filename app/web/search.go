@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Event search
+//
+// GET /events/search finds the events matching every filter given:
+// room_ids[] (or a comma-separated room_ids), owner, guest, and q, matched
+// case-insensitively against the name and description. start_time and
+// end_time (RFC 3339) bound the window, this week by default, and recurring
+// events are expanded into their occurrences within it. Results take the
+// paging and sort parameters of GET /events, see event.Search.
+//
+// eventSearch reads the filters of a search request.
+func eventSearch(r *http.Request) (EventSearch, *Error) {
+	query := r.URL.Query()
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		return EventSearch{}, errRes
+	}
+
+	search := EventSearch{
+		RoomIds:   query["room_ids[]"],
+		Owner:     strings.TrimSpace(query.Get("owner")),
+		Guest:     strings.TrimSpace(query.Get("guest")),
+		Text:      strings.TrimSpace(query.Get("q")),
+		StartTime: start_time,
+		EndTime:   end_time,
+	}
+	if s := query.Get("room_ids"); s != "" {
+		for _, id := range strings.Split(s, ",") {
+			search.RoomIds = append(search.RoomIds, strings.TrimSpace(id))
+		}
+	}
+
+	return search, nil
+}
+
+// Event Search Handlers
+func (c *appContext) searchEventsHandler(w http.ResponseWriter, r *http.Request) {
+	search, errRes := eventSearch(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	opts, errRes := listOptions(r, "events", eventSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	repo := c.events()
+	events, err := repo.Search(r.Context(), search)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	sortEvents(events, opts.Sort)
+	total := len(events)
+	lo, hi := opts.Bounds(total)
+	events = events[lo:hi]
+	if renderHTML(r) {
+		events = withRenderedDescriptions(events)
+	}
+	zones, errRes := c.zones(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	zones.events(events)
+
+	writeList(w, r, opts, total, events)
+}
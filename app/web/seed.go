@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Seeding
+//
+// The seed command (go run . seed, see commands.go) fills the database with
+// sample venues, rooms and users, and a week of events in them, for local
+// development and demos. It goes through the repos the API uses, so seeded
+// documents look like booked ones, and finds what an earlier run created by
+// slug, email and external id, so running it again only adds what's
+// missing: the events of the current week, when it's a new one.
+const seedSource = "seed"
+
+type seedRoom struct {
+	Name     string
+	Slug     string
+	Capacity int
+}
+
+type seedVenue struct {
+	Name     string
+	Slug     string
+	TimeZone string
+	Rooms    []seedRoom
+}
+
+var seedVenues = []seedVenue{
+	{"Head Office", "head-office", "Asia/Jakarta", []seedRoom{
+		{"Board Room", "board-room", 12},
+		{"Focus Room", "focus-room", 4},
+		{"Town Hall", "town-hall", 80},
+	}},
+	{"Bandung Studio", "bandung-studio", "Asia/Jakarta", []seedRoom{
+		{"Garden Room", "garden-room", 8},
+		{"Phone Booth", "phone-booth", 1},
+	}},
+}
+
+var seedUsers = []User{
+	{Email: "admin@example.com", Name: "Ayu Admin", Team: "ops", Role: RoleAdmin},
+	{Email: "budi@example.com", Name: "Budi Santoso", Team: "engineering", Role: RoleUser},
+	{Email: "citra@example.com", Name: "Citra Lestari", Team: "engineering", Role: RoleUser},
+	{Email: "dewi@example.com", Name: "Dewi Kusuma", Team: "sales", Role: RoleUser},
+}
+
+// seedSlots are the events booked in every room of capacity > 1 each
+// weekday, by hour.
+var seedSlots = []struct {
+	Hour     int
+	Duration time.Duration
+	Name     string
+}{
+	{9, 30 * time.Minute, "Stand-up"},
+	{11, time.Hour, "Planning"},
+	{14, 90 * time.Minute, "Review"},
+}
+
+// seed creates what's missing of the sample data.
+func (c *appContext) seed(ctx context.Context) error {
+	userRepo := UserRepo{c.db.C("users")}
+	for _, user := range seedUsers {
+		user := user
+		if err := userRepo.Upsert(&user); err != nil {
+			return err
+		}
+	}
+	log.Printf("seed: %d users", len(seedUsers))
+
+	rooms := []Room{}
+	for _, v := range seedVenues {
+		venue, err := c.seedVenue(ctx, v)
+		if err != nil {
+			return err
+		}
+		for _, r := range v.Rooms {
+			room, err := c.seedRoom(ctx, venue, r)
+			if err != nil {
+				return err
+			}
+			rooms = append(rooms, room)
+		}
+	}
+	log.Printf("seed: %d venues, %d rooms", len(seedVenues), len(rooms))
+
+	created := 0
+	monday := orgLocale().BeginningOfWeek(clockNow()).In(defaultLocation)
+	for day := 0; day < 7; day++ {
+		date := monday.AddDate(0, 0, day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		for i, room := range rooms {
+			if room.Capacity < 2 {
+				continue
+			}
+			for j, slot := range seedSlots {
+				ok, err := c.seedEvent(ctx, room, date, slot.Hour, slot.Duration, slot.Name, seedUsers[1+(i+j)%(len(seedUsers)-1)])
+				if err != nil {
+					return err
+				}
+				if ok {
+					created++
+				}
+			}
+		}
+	}
+	log.Printf("seed: %d new events", created)
+
+	return nil
+}
+
+func (c *appContext) seedVenue(ctx context.Context, v seedVenue) (Venue, error) {
+	repo := c.venues()
+	venue, err := repo.FindBySlug(ctx, v.Slug)
+	if err != ErrDocumentNotFound {
+		return venue, err
+	}
+
+	venue = Venue{Name: v.Name, Slug: v.Slug, TimeZone: v.TimeZone}
+	if err := repo.Create(ctx, &venue); err != nil {
+		return venue, err
+	}
+	c.recordChange("venue", venue.Id, ChangeCreated)
+
+	return venue, nil
+}
+
+func (c *appContext) seedRoom(ctx context.Context, venue Venue, r seedRoom) (Room, error) {
+	repo := c.rooms()
+	room, err := repo.FindBySlug(ctx, venue.Id.Hex(), r.Slug)
+	if err != ErrDocumentNotFound {
+		return room, err
+	}
+
+	room = Room{Name: r.Name, Slug: r.Slug, VenueId: venue.Id.Hex(), Capacity: r.Capacity}
+	if err := repo.Create(ctx, &room); err != nil {
+		return room, err
+	}
+	c.recordChange("room", room.Id, ChangeCreated)
+
+	return room, nil
+}
+
+// seedEvent books room at hour on date for owner, unless an earlier run did.
+func (c *appContext) seedEvent(ctx context.Context, room Room, date time.Time, hour int, duration time.Duration, name string, owner User) (bool, error) {
+	repo := c.events()
+	externalId := fmt.Sprintf("%s/%s/%s/%02d", room.VenueId, room.Slug, date.Format("2006-01-02"), hour)
+	_, err := repo.FindByExternalId(ctx, seedSource, externalId)
+	if err != ErrDocumentNotFound {
+		return false, err
+	}
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, defaultLocation)
+	guests := []string{}
+	for _, user := range seedUsers[1:] {
+		if user.Email != owner.Email && len(guests) < room.Capacity-1 {
+			guests = append(guests, user.Email)
+		}
+	}
+	event := Event{
+		Name:       name + " (" + owner.Team + ")",
+		LocationID: room.Id.Hex(),
+		Location:   room.Name,
+		Guests:     guests,
+		Owner:      owner.Email,
+		StartTime:  start,
+		EndTime:    start.Add(duration),
+		Source:     seedSource,
+		ExternalId: externalId,
+		BookedVia:  BookingSource{Channel: ChannelAPI, Integration: seedSource},
+	}
+	setDescription(&event, "Sample event, see seed.go.")
+	code, err := c.newCheckInCode(ctx, event)
+	if err != nil {
+		return false, err
+	}
+	event.CheckInCode = code
+
+	err = repo.Create(ctx, &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		log.Printf("seed: %s at %s conflicts with %s, skipped", room.Name, start.Format(time.RFC3339), conflict.Event.Id.Hex())
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.recordChange("event", event.Id, ChangeCreated)
+
+	return true, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Event series
+//
+// A series describes a recurring meeting once: the first occurrence plus an
+// RRULE-style Recurrence. Occurrences are expanded on read and are never
+// stored; Exceptions lists the start times of occurrences that were removed.
+//
+// Series are stored as recurring events, see recurrence.go, so they're
+// checked for conflicts and listed with the other events. /event-series
+// reads and writes those events in the shape of an EventSeries. The
+// expansion of a Recurrence is in internal/event.
+
+type EventSeries struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string           `json:"name"`
+	LocationID  string           `json:"location_id"`
+	Location    string           `json:"location"`
+	Description string           `json:"description"`
+	Guests      []string         `json:"guests"`
+	Owner       string           `json:"owner"`
+	StartTime   time.Time        `json:"start_time"`
+	EndTime     time.Time        `json:"end_time"`
+	Recurrence  Recurrence       `json:"recurrence"`
+}
+
+type Occurrence struct {
+	SeriesId   string    `json:"series_id"`
+	Name       string    `json:"name"`
+	LocationID string    `json:"location_id"`
+	Location   string    `json:"location"`
+	Guests     []string  `json:"guests"`
+	Owner      string    `json:"owner"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+}
+
+// event returns the recurring event standing for s.
+func (s EventSeries) event() Event {
+	event := Event{
+		Id:         s.Id,
+		Name:       s.Name,
+		LocationID: s.LocationID,
+		Location:   s.Location,
+		Guests:     s.Guests,
+		Owner:      s.Owner,
+		StartTime:  s.StartTime,
+		EndTime:    s.EndTime,
+	}
+	setDescription(&event, s.Description)
+	recurrence := s.Recurrence
+	event.SetRecurrence(&recurrence)
+
+	return event
+}
+
+// seriesOf returns the series the recurring event stands for.
+func seriesOf(event Event) EventSeries {
+	return EventSeries{
+		Id:          event.Id,
+		Name:        event.Name,
+		LocationID:  event.LocationID,
+		Location:    event.Location,
+		Description: event.Description,
+		Guests:      event.Guests,
+		Owner:       event.Owner,
+		StartTime:   event.StartTime,
+		EndTime:     event.EndTime,
+		Recurrence:  *event.Recurrence,
+	}
+}
+
+// seriesOccurrences expands the recurring event within
+// [start_time, end_time).
+func seriesOccurrences(event Event, start_time time.Time, end_time time.Time) []Occurrence {
+	result := []Occurrence{}
+	for _, occurrence := range event.Occurrences(start_time, end_time) {
+		result = append(result, Occurrence{
+			SeriesId:   event.Id.Hex(),
+			Name:       occurrence.Name,
+			LocationID: occurrence.LocationID,
+			Location:   occurrence.Location,
+			Guests:     occurrence.Guests,
+			Owner:      occurrence.Owner,
+			StartTime:  occurrence.StartTime,
+			EndTime:    occurrence.EndTime,
+		})
+	}
+
+	return result
+}
+
+// findSeries finds the recurring event with id, answering 404 when there's
+// none.
+func (c *appContext) findSeries(ctx context.Context, w http.ResponseWriter, id string) (Event, bool) {
+	event, err := c.events().Find(ctx, id)
+	if err == nil && event.Recurrence == nil {
+		err = ErrDocumentNotFound
+	}
+	if err == ErrInvalidId {
+		err = ErrDocumentNotFound
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return event, false
+	}
+
+	return event, true
+}
+
+// Event Series Handlers
+func (c *appContext) eventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	event, ok := c.findSeries(r.Context(), w, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, seriesOf(event))
+}
+
+func (c *appContext) seriesOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	event, ok := c.findSeries(r.Context(), w, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	WriteSuccess(w, http.StatusOK, seriesOccurrences(event, start_time, end_time))
+}
+
+func (c *appContext) createEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*EventSeries)
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+	if body.Recurrence.WeekStart == "" {
+		body.Recurrence.WeekStart = weekdayName(requestLocale(r).WeekStart)
+	}
+
+	user := r.Context().Value(userKey).(User)
+	if body.Owner == "" {
+		body.Owner = user.Email
+	}
+	body.Id = ""
+	event := body.event()
+	if !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+	if errRes := c.checkCapacity(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	code, err := c.newCheckInCode(r.Context(), event)
+	if err != nil {
+		panic(err)
+	}
+	event.CheckInCode = code
+
+	err = c.events().Create(r.Context(), &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeCreated)
+
+	WriteSuccess(w, http.StatusCreated, seriesOf(event))
+}
+
+func (c *appContext) updateEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*EventSeries)
+	if !body.StartTime.Before(body.EndTime) {
+		WriteError(w, ErrInvalidTimeRange)
+		return
+	}
+	existing, ok := c.findSeries(r.Context(), w, params.ByName("id"))
+	if !ok {
+		return
+	}
+	if body.Recurrence.WeekStart == "" {
+		body.Recurrence.WeekStart = existing.Recurrence.WeekStart
+	}
+	if body.Recurrence.Exceptions == nil {
+		body.Recurrence.Exceptions = existing.Recurrence.Exceptions
+	}
+	if body.Owner == "" {
+		body.Owner = existing.Owner
+	}
+
+	// Only what a series describes changes, the rest of the event stays.
+	series := body.event()
+	event := existing
+	event.Name = series.Name
+	event.LocationID = series.LocationID
+	event.Location = series.Location
+	event.Description = series.Description
+	event.DescriptionHTML = series.DescriptionHTML
+	event.Guests = series.Guests
+	event.Owner = series.Owner
+	event.StartTime = series.StartTime
+	event.EndTime = series.EndTime
+	event.SetRecurrence(series.Recurrence)
+
+	user := r.Context().Value(userKey).(User)
+	if !user.CanManage(existing) || !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+	if errRes := c.checkCapacity(r.Context(), event); errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	err := c.events().Update(r.Context(), &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeUpdated)
+
+	WriteSuccess(w, http.StatusAccepted, seriesOf(event))
+}
+
+func (c *appContext) deleteEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	event, ok := c.findSeries(r.Context(), w, params.ByName("id"))
+	if !ok {
+		return
+	}
+	if user := r.Context().Value(userKey).(User); !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+
+	err := c.events().Delete(r.Context(), event.Id.Hex())
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeDeleted)
+	c.deleteDetached(r.Context(), event)
+
+	data := MessageSuccess{MessageInfo{Message: "Event series has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Server
+//
+// The API is served with timeouts so slow clients can't hold connections
+// open forever. On SIGINT or SIGTERM the server stops accepting connections,
+// lets in-flight requests finish for up to SHUTDOWN_TIMEOUT and closes the
+// database session before exiting:
+//
+//	READ_TIMEOUT      e.g. "15s", default 10s to read a request
+//	WRITE_TIMEOUT     e.g. "1m", default 30s to write a response
+//	IDLE_TIMEOUT      e.g. "5m", default 2m between keep-alive requests
+//	SHUTDOWN_TIMEOUT  e.g. "1m", default 20s to drain requests
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(setting(name)); err == nil && d > 0 {
+		return d
+	}
+
+	return fallback
+}
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  envDuration("READ_TIMEOUT", 10*time.Second),
+		WriteTimeout: envDuration("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:  envDuration("IDLE_TIMEOUT", 2*time.Minute),
+	}
+}
+
+// serve runs srv until the process is told to stop, then drains it and
+// closes session.
+func serve(srv *http.Server, session *storage.Session) {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errs:
+		session.Close()
+		log.Fatal(err)
+	case sig := <-stop:
+		log.Printf("server: received %s, shutting down", sig)
+	}
+
+	drain, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancel()
+	if err := srv.Shutdown(drain); err != nil {
+		log.Println("server: unable to drain requests:", err)
+	}
+	session.Close()
+	log.Println("server: stopped")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Request sessions
+//
+// sessionHandler binds the database session to the context of each request,
+// so the queries of a request the client gave up on, or that ran past its
+// deadline, are canceled; handlers registered with handle, and middleware
+// through forRequest, run against an appContext bound to that session. The
+// repositories take the context of the request too, r.Context(), and work
+// outside any request passes context.Background(). Each query is also
+// limited to REQUEST_DB_TIMEOUT:
+//
+//	REQUEST_DB_TIMEOUT  e.g. "10s", default 0 for no limit but the request's
+//
+// The sessions share the connection pool of the process, see
+// internal/storage for its configuration.
+//
+// Work that outlives the request, like scanning an upload, runs on a session
+// of its own from detach.
+
+// forRequest returns the appContext bound to the session of r, or c when the
+// request has none.
+func (c *appContext) forRequest(r *http.Request) *appContext {
+	if rc, ok := r.Context().Value(appKey).(*appContext); ok {
+		return rc
+	}
+
+	return c
+}
+
+// handle adapts fn, usually a method expression such as
+// (*appContext).venueHandler, to run against the session of the request.
+func (c *appContext) handle(fn func(*appContext, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn(c.forRequest(r), w, r)
+	}
+}
+
+// detach returns an appContext on a new session for work that continues
+// after the request; the caller closes it.
+func (c *appContext) detach() *appContext {
+	return &appContext{db: c.db.With(c.db.Session.Copy()), origin: c.origin, repos: c.repos, config: c.config}
+}
+
+func (c *appContext) close() {
+	c.db.Session.Close()
+}
+
+// Middleware
+func sessionHandler(c *appContext) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			// Contexts without a database have no session to open.
+			if c.db == nil {
+				next.ServeHTTP(w, withValue(r, appKey, &appContext{origin: newAuditOrigin(w, r), repos: c.repos, config: c.config}))
+				return
+			}
+
+			session := c.db.Session.WithContext(r.Context())
+			session.SetTimeout(envDuration("REQUEST_DB_TIMEOUT", 0))
+
+			r = withValue(r, appKey, &appContext{db: c.db.With(session), origin: newAuditOrigin(w, r), repos: c.repos, config: c.config})
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slack
+//
+// Bookings are posted to Slack through incoming webhooks, each of which
+// posts to the channel it was created for:
+//
+//	SLACK_WEBHOOK_URL     the webhook of rooms with none of their own
+//	SLACK_VENUE_WEBHOOKS  e.g. "<venue id>=<webhook url>,...", the webhooks of
+//	                      the rooms of given venues
+//
+// When neither is set nothing is posted. Posting is queued like emails are;
+// network errors, 429s and 5xxs are retried up to slackAttempts times,
+// waiting for Retry-After when Slack sends one.
+const (
+	slackQueueSize = 1000
+	slackAttempts  = 5
+	slackTimeout   = 10 * time.Second
+)
+
+type SlackMessage struct {
+	Text string `json:"text"`
+}
+
+type slackPost struct {
+	url string
+	msg SlackMessage
+}
+
+// slackError is a failed post, temporary when it's worth retrying.
+type slackError struct {
+	status     int
+	retryAfter time.Duration
+	temporary  bool
+	err        error
+}
+
+func (e *slackError) Error() string {
+	if e.err != nil {
+		return "slack: " + e.err.Error()
+	}
+
+	return "slack: " + http.StatusText(e.status)
+}
+
+// Slack queues booking messages for a worker posting them to their webhook.
+// A nil Slack drops everything.
+type Slack struct {
+	global string
+	venues map[string]string
+	client http.Client
+	jobs   chan slackPost
+}
+
+var slack *Slack
+
+// slackVenueWebhooks parses SLACK_VENUE_WEBHOOKS, skipping invalid entries.
+func slackVenueWebhooks(value string) map[string]string {
+	result := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "https://") {
+			continue
+		}
+		result[parts[0]] = parts[1]
+	}
+
+	return result
+}
+
+// startSlack starts posting to the webhooks of cfg, or returns nil when
+// there are none.
+func startSlack(cfg SlackConfig) *Slack {
+	s := &Slack{
+		global: cfg.WebhookURL,
+		venues: cfg.VenueWebhooks,
+		client: http.Client{Timeout: slackTimeout},
+		jobs:   make(chan slackPost, slackQueueSize),
+	}
+	if s.global == "" && len(s.venues) == 0 {
+		return nil
+	}
+
+	go s.work()
+	return s
+}
+
+// webhook returns the webhook bookings in the venue venueId go to.
+func (s *Slack) webhook(venueId string) string {
+	if url, ok := s.venues[venueId]; ok {
+		return url
+	}
+
+	return s.global
+}
+
+func (s *Slack) post(url string, msg SlackMessage) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return &slackError{temporary: true, err: err}
+	}
+	res.Body.Close()
+	if res.StatusCode < 300 {
+		return nil
+	}
+
+	e := &slackError{status: res.StatusCode}
+	e.temporary = res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return e
+}
+
+func (s *Slack) work() {
+	for job := range s.jobs {
+		var err error
+		for attempt := 1; attempt <= slackAttempts; attempt++ {
+			err = s.post(job.url, job.msg)
+			e, ok := err.(*slackError)
+			if err == nil || !ok || !e.temporary {
+				break
+			}
+			wait := time.Duration(1<<uint(attempt-1)) * time.Second
+			if e.retryAfter > wait {
+				wait = e.retryAfter
+			}
+			time.Sleep(wait)
+		}
+		if err != nil {
+			log.Println("slack: posting failed:", err)
+		}
+	}
+}
+
+// Enqueue queues msg for the webhook of venueId without blocking, dropping it
+// when the queue is full.
+func (s *Slack) Enqueue(venueId string, msg SlackMessage) {
+	if s == nil {
+		return
+	}
+	url := s.webhook(venueId)
+	if url == "" {
+		return
+	}
+
+	select {
+	case s.jobs <- slackPost{url, msg}:
+	default:
+		log.Println("slack: queue full, dropping a message")
+	}
+}
+
+func bookingMessage(event Event, room Room) SlackMessage {
+	loc := defaultLocation
+	name := event.Location
+	if room.Name != "" {
+		name = room.Name
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%s* booked *%s*\n", event.Name, name)
+	fmt.Fprintf(&b, "When: %s - %s (UTC+7)\n",
+		event.StartTime.In(loc).Format("Mon 2 Jan 2006 15:04"), event.EndTime.In(loc).Format("15:04"))
+	if event.Owner != "" {
+		fmt.Fprintf(&b, "Owner: %s\n", event.Owner)
+	} else if event.Requester != nil {
+		fmt.Fprintf(&b, "Requested by: %s <%s>\n", event.Requester.Name, event.Requester.Email)
+	}
+	if len(event.Guests) > 0 {
+		fmt.Fprintf(&b, "Guests: %s\n", strings.Join(event.Guests, ", "))
+	}
+	if event.PendingApproval {
+		b.WriteString("Waiting for approval\n")
+	}
+
+	return SlackMessage{Text: strings.TrimSpace(b.String())}
+}
+
+// announceBooking posts event, just booked, to the Slack channel of its
+// room's venue.
+func (c *appContext) announceBooking(ctx context.Context, event Event) {
+	if slack == nil {
+		return
+	}
+
+	room := Room{}
+	if event.LocationID != "" {
+		repo := c.rooms()
+		found, err := repo.Find(ctx, event.LocationID)
+		if err != nil && err != ErrDocumentNotFound && err != ErrInvalidId {
+			log.Println("slack: finding room failed:", err)
+		}
+		if err == nil {
+			room = found
+		}
+	}
+
+	slack.Enqueue(room.VenueId, bookingMessage(event, room))
+}
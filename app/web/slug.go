@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Slugs
+//
+// Venues and rooms can be addressed by a human-readable slug as well as by
+// their ObjectId. Venue slugs are unique globally, room slugs are unique
+// within their venue. Renaming a slug keeps the previous one in OldSlugs so
+// old links redirect to the new location.
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+var errSlugTaken = errors.New("slug is already in use")
+
+func slugify(s string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// uniqueSlug returns base, or base suffixed with -2, -3... until taken reports
+// it as free.
+func uniqueSlug(base string, taken func(string) (bool, error)) (string, error) {
+	if base == "" {
+		base = "untitled"
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		ok, err := taken(slug)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func redirectTo(w http.ResponseWriter, r *http.Request, path string) {
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	http.Redirect(w, r, path, http.StatusMovedPermanently)
+}
+
+// assignVenueSlug fills in venue.Slug on create and update, rejecting slugs
+// used by another venue and remembering the previous slug of existing.
+func assignVenueSlug(ctx context.Context, repo VenueRepository, venue *Venue, existing *Venue) error {
+	if existing != nil {
+		venue.OldSlugs = existing.OldSlugs
+		if venue.Slug == "" {
+			venue.Slug = existing.Slug
+		}
+	}
+
+	if venue.Slug == "" {
+		slug, err := uniqueSlug(slugify(venue.Name), func(s string) (bool, error) {
+			return repo.SlugTaken(ctx, s, venue.Id)
+		})
+		if err != nil {
+			return err
+		}
+		venue.Slug = slug
+	} else if existing == nil || venue.Slug != existing.Slug {
+		taken, err := repo.SlugTaken(ctx, venue.Slug, venue.Id)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errSlugTaken
+		}
+	}
+
+	if existing != nil && existing.Slug != "" && existing.Slug != venue.Slug {
+		venue.OldSlugs = append(venue.OldSlugs, existing.Slug)
+	}
+
+	return nil
+}
+
+// assignRoomSlug is the room counterpart of assignVenueSlug, scoped to the
+// room's venue.
+func assignRoomSlug(ctx context.Context, repo RoomRepository, room *Room, existing *Room) error {
+	if existing != nil {
+		if existing.VenueId == room.VenueId {
+			room.OldSlugs = existing.OldSlugs
+		}
+		if room.Slug == "" {
+			room.Slug = existing.Slug
+		}
+	}
+
+	if room.Slug == "" {
+		slug, err := uniqueSlug(slugify(room.Name), func(s string) (bool, error) {
+			return repo.SlugTaken(ctx, room.VenueId, s, room.Id)
+		})
+		if err != nil {
+			return err
+		}
+		room.Slug = slug
+	} else if existing == nil || room.Slug != existing.Slug || room.VenueId != existing.VenueId {
+		taken, err := repo.SlugTaken(ctx, room.VenueId, room.Slug, room.Id)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errSlugTaken
+		}
+	}
+
+	if existing != nil && existing.VenueId == room.VenueId && existing.Slug != "" && existing.Slug != room.Slug {
+		room.OldSlugs = append(room.OldSlugs, existing.Slug)
+	}
+
+	return nil
+}
+
+// Slug Handlers
+func (c *appContext) venueRoomHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	venue, canonical, err := resolveVenue(r.Context(), c.venues(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	room, roomCanonical, err := resolveRoom(r.Context(), c.rooms(), venue.Id.Hex(), params.ByName("room"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	if !canonical || !roomCanonical {
+		redirectTo(w, r, "/venues/"+venue.Slug+"/rooms/"+room.Slug)
+		return
+	}
+	localizeRoom(&room, localizing(w, r))
+
+	WriteSuccess(w, http.StatusOK, room)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Soft delete
+//
+// Deleting a venue, room or event sets its DeletedAt instead of removing the
+// document. Deleted documents are left out of every lookup, list and
+// conflict check, except that admins can list them with
+// ?include_deleted=true. POST /venues/:id/restore, /rooms/:id/restore and
+// /events/:id/restore bring one back; an event only when its room is still
+// free at its time, and no longer cancelled if it was, see cancellation.go. The repos leave them out with storage.DeletedFilter.
+
+// includeDeleted reports whether r asks for deleted documents too, which
+// only admins may.
+func (c *appContext) includeDeleted(r *http.Request) (bool, *Error) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, nil
+	}
+	user, ok := c.caller(r)
+	if !ok {
+		return false, ErrUnauthenticated
+	}
+	if !user.IsAdmin() {
+		return false, ErrForbidden
+	}
+
+	return true, nil
+}
+
+// Restore Handlers
+func (c *appContext) restoreVenueHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.venues()
+	err := repo.Restore(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	venue, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("venue", venue.Id, ChangeRestored)
+
+	WriteSuccess(w, http.StatusOK, venue)
+}
+
+func (c *appContext) restoreRoomHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.rooms()
+	err := repo.Restore(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	room, err := repo.Find(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("room", room.Id, ChangeRestored)
+
+	WriteSuccess(w, http.StatusOK, room)
+}
+
+func (c *appContext) restoreEventHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := c.events()
+	event, err := repo.FindDeleted(r.Context(), params.ByName("id"))
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	if user := r.Context().Value(userKey).(User); !user.CanManage(event) {
+		WriteError(w, ErrNotEventOwner)
+		return
+	}
+
+	err = repo.Restore(r.Context(), &event)
+	if conflict, ok := err.(*EventConflict); ok {
+		WriteError(w, conflictError(conflict.Event))
+		return
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+	c.recordChange("event", event.Id, ChangeRestored)
+	notifyEvent(NotifyCreated, event)
+
+	WriteSuccess(w, http.StatusOK, event)
+}
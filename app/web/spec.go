@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// OpenAPI
+//
+// GET /openapi.json describes the API as an OpenAPI 3.1 document and GET
+// /docs serves Swagger UI over it. The operations come from generatedRoutes,
+// the table sdkgen writes to routes_gen.go from the router registrations in
+// main() along with the SDKs, so run go generate after adding a route:
+// checkSpec fails at startup on a registered route the table doesn't know.
+// Only the routes registered in this process are described, so routes behind
+// a flag show up when it's on. Request bodies are the schemas requests are
+// validated against, served at /schemas/:name; the responses of the main
+// resources are described from their Go types, every other one as JSON.
+const (
+	openAPIVersion = "3.1.0"
+	specTitle      = "Ivana API"
+	specVersion    = "1"
+
+	// swaggerUIVersion is the release of swagger-ui-dist /docs loads from
+	// the CDN.
+	swaggerUIVersion = "5.17.14"
+)
+
+// generatedRoute is a route as registered in routes(), see routes_gen.go.
+type generatedRoute struct {
+	Method  string
+	Path    string
+	Pattern string
+	Handler string
+	Schema  string
+	Body    bool
+	Auth    string
+}
+
+// specResponse is what a handler answers with on success. List responses
+// are an array of Type, or a Page of them when paged.
+type specResponse struct {
+	Status int
+	Type   interface{}
+	List   bool
+}
+
+var specResponses = map[string]specResponse{
+	"venuesHandler":      {http.StatusOK, Venue{}, true},
+	"venueHandler":       {http.StatusOK, Venue{}, false},
+	"createVenueHandler": {http.StatusCreated, Venue{}, false},
+	"updateVenueHandler": {http.StatusAccepted, Venue{}, false},
+	"deleteVenueHandler": {http.StatusAccepted, MessageSuccess{}, false},
+	"roomsHandler":       {http.StatusOK, Room{}, true},
+	"roomsVenueHandler":  {http.StatusOK, Room{}, true},
+	"roomHandler":        {http.StatusOK, Room{}, false},
+	"venueRoomHandler":   {http.StatusOK, Room{}, false},
+	"createRoomHandler":  {http.StatusCreated, Room{}, false},
+	"updateRoomHandler":  {http.StatusAccepted, Room{}, false},
+	"deleteRoomHandler":  {http.StatusAccepted, MessageSuccess{}, false},
+	"eventsHandler":      {http.StatusOK, Event{}, true},
+	"eventHandler":       {http.StatusOK, EventResponse{}, false},
+	"createEventHandler": {http.StatusCreated, Event{}, false},
+	"updateEventHandler": {http.StatusAccepted, EventResponse{}, false},
+	"deleteEventHandler": {http.StatusAccepted, MessageSuccess{}, false},
+}
+
+// register records a route registered on r.
+func (r *router) register(method string, path string) {
+	r.routes = append(r.routes, method+" "+path)
+}
+
+// checkSpec fails on a route registered on r that generatedRoutes is missing,
+// or a schema it names that doesn't exist.
+func checkSpec(r *router) error {
+	known := map[string]bool{}
+	for _, route := range generatedRoutes {
+		known[route.Method+" "+route.Pattern] = true
+		if _, ok := schemas[route.Schema]; route.Schema != "" && !ok {
+			return fmt.Errorf("spec: %s %s validates against unknown schema %q", route.Method, route.Path, route.Schema)
+		}
+	}
+	for _, route := range r.routes {
+		if !known[route] {
+			return fmt.Errorf("spec: %s isn't in routes_gen.go, run go generate", route)
+		}
+	}
+
+	return nil
+}
+
+// specTypes builds the schemas of Go types as components, by type name.
+type specTypes map[string]interface{}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIdType = reflect.TypeOf(storage.ObjectId(""))
+)
+
+func (types specTypes) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case objectIdType:
+		return map[string]interface{}{"type": "string", "pattern": objectIdPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return types.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": types.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": types.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return types.object(t)
+		}
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := types[t.Name()]; ok {
+			return ref
+		}
+		// Claim the name first so types referring to themselves end.
+		types[t.Name()] = nil
+		types[t.Name()] = types.object(t)
+		return ref
+	}
+
+	return map[string]interface{}{}
+}
+
+// object describes the fields of struct type t the way encoding/json
+// marshals them.
+func (types specTypes) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = types.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	result := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+
+	return result
+}
+
+// specPath turns the :name segments of path into {name}.
+func specPath(path string) (string, []interface{}) {
+	params := []interface{}{}
+	segments := strings.Split(path, "/")
+	for idx, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := segment[1:]
+			segments[idx] = "{" + name + "}"
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// operationId names the operation of route after its handler, suffixed with
+// the static segments of its path when another route has the handler too.
+func operationId(route generatedRoute, seen map[string]bool) string {
+	id := strings.TrimSuffix(route.Handler, "Handler")
+	if seen[id] {
+		for _, segment := range strings.Split(route.Path, "/") {
+			if segment != "" && !strings.HasPrefix(segment, ":") {
+				id += strings.ToUpper(segment[:1]) + segment[1:]
+			}
+		}
+	}
+	seen[id] = true
+
+	return id
+}
+
+// requestSchema is the schema named name as a component, without the keys
+// that only make sense at the root of a document.
+func requestSchema(name string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(schemaSources[name]), &result); err != nil {
+		return nil, err
+	}
+	delete(result, "$schema")
+	delete(result, "$id")
+
+	return result, nil
+}
+
+// openAPI builds the document of the routes registered on r.
+func openAPI(r *router) (map[string]interface{}, error) {
+	registered := map[string]bool{}
+	for _, route := range r.routes {
+		registered[route] = true
+	}
+	// A pattern that only serves static values, answering 404 otherwise,
+	// has no operation of its own.
+	statics := map[string]bool{}
+	for _, route := range generatedRoutes {
+		if route.Path != route.Pattern {
+			statics[route.Method+" "+route.Pattern] = true
+		}
+	}
+
+	types := specTypes{}
+	errorsRef := types.schema(reflect.TypeOf(Errors{}))
+	pageMeta := types.schema(reflect.TypeOf(PageMeta{}))
+	pageLinks := types.schema(reflect.TypeOf(PageLinks{}))
+	components := map[string]interface{}{}
+
+	paths := map[string]interface{}{}
+	operationIds := map[string]bool{}
+	for _, route := range generatedRoutes {
+		if !registered[route.Method+" "+route.Pattern] {
+			continue
+		}
+		if route.Handler == "" && route.Path == route.Pattern && statics[route.Method+" "+route.Pattern] {
+			continue
+		}
+		path, params := specPath(route.Path)
+		tag := strings.SplitN(strings.TrimPrefix(route.Path, "/"), "/", 2)[0]
+		op := map[string]interface{}{
+			"tags":      []string{tag},
+			"responses": map[string]interface{}{},
+		}
+		if route.Handler != "" {
+			op["operationId"] = operationId(route, operationIds)
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if route.Auth != "" {
+			op["security"] = []interface{}{map[string]interface{}{"userEmail": []string{}}}
+			op["description"] = "Requires a caller with the " + route.Auth + " role or above."
+			if route.Auth == RoleUser {
+				op["description"] = "Requires a signed in caller."
+			}
+		}
+		if route.Schema != "" {
+			if _, ok := components[route.Schema]; !ok {
+				s, err := requestSchema(route.Schema)
+				if err != nil {
+					return nil, fmt.Errorf("schema %s: %v", route.Schema, err)
+				}
+				components[route.Schema] = s
+			}
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/" + route.Schema},
+				}},
+			}
+		}
+
+		responses := op["responses"].(map[string]interface{})
+		if res, ok := specResponses[route.Handler]; ok {
+			schema := types.schema(reflect.TypeOf(res.Type))
+			if res.List {
+				schema = map[string]interface{}{"oneOf": []interface{}{
+					map[string]interface{}{"type": "array", "items": schema},
+					map[string]interface{}{
+						"type":     "object",
+						"required": []string{"data", "meta", "links"},
+						"properties": map[string]interface{}{
+							"data":  map[string]interface{}{"type": "array", "items": schema},
+							"meta":  pageMeta,
+							"links": pageLinks,
+						},
+					},
+				}}
+			}
+			responses[fmt.Sprint(res.Status)] = map[string]interface{}{
+				"description": http.StatusText(res.Status),
+				"content":     map[string]interface{}{"application/vnd.api+json": map[string]interface{}{"schema": schema}},
+			}
+		} else {
+			responses["2XX"] = map[string]interface{}{"description": "Success"}
+		}
+		responses["default"] = map[string]interface{}{
+			"description": "Error",
+			"content":     map[string]interface{}{"application/vnd.api+json": map[string]interface{}{"schema": errorsRef}},
+		}
+
+		methods, ok := paths[path].(map[string]interface{})
+		if !ok {
+			methods = map[string]interface{}{}
+			paths[path] = methods
+		}
+		methods[strings.ToLower(route.Method)] = op
+	}
+
+	for name, s := range types {
+		components[name] = s
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info":    map[string]interface{}{"title": specTitle, "version": specVersion},
+		"servers": []interface{}{map[string]interface{}{"url": "/" + baseVersion}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": components,
+			"securitySchemes": map[string]interface{}{
+				"userEmail": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-User-Email"},
+			},
+		},
+	}, nil
+}
+
+// Spec Handlers
+func openAPIHandler(r *router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		doc, err := openAPI(r)
+		if err != nil {
+			panic(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	}
+}
+
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, docsPage, swaggerUIVersion, swaggerUIVersion)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Ivana API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%s/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@%s/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Standby
+//
+// A booking can opt into an upgrade by setting upgrade: whenever a room frees
+// up, because an event was cancelled or moved, a matcher that runs every
+// standbyInterval moves waiting events into it if their preferences rank it
+// above their current room. With upgrade.rooms, only the listed rooms are
+// wanted, best first. Otherwise any room of the same venue with at least
+// upgrade.min_capacity seats (the guests and owner by default) is better than
+// one with too few, and a larger room is better than a smaller one that's too
+// small either way. Events are only moved until standbyNotice before they
+// start, and their owners are notified. The room an upgraded event leaves is
+// offered again in turn.
+const (
+	standbyInterval = time.Minute
+	standbyNotice   = 30 * time.Minute
+)
+
+type FreedSlot struct {
+	Id         storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	LocationID string           `json:"location_id"`
+	StartTime  time.Time        `json:"start_time"`
+	EndTime    time.Time        `json:"end_time"`
+	FreedAt    time.Time        `json:"freed_at"`
+}
+
+// Repo FreedSlot
+type FreedSlotRepo struct {
+	coll *storage.Collection
+}
+
+func (r *FreedSlotRepo) All() ([]FreedSlot, error) {
+	result := []FreedSlot{}
+	err := r.coll.Find(nil).Sort("freedat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *FreedSlotRepo) Create(slot *FreedSlot) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.UpsertId(id, slot)
+	if err != nil {
+		return err
+	}
+
+	slot.Id = id
+
+	return nil
+}
+
+func (r *FreedSlotRepo) Delete(id storage.ObjectId) error {
+	err := r.coll.RemoveId(id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// recordFreedSlot offers the room at locationId for [start_time, end_time)
+// to events on standby.
+func (c *appContext) recordFreedSlot(locationId string, start_time time.Time, end_time time.Time) {
+	if locationId == "" || !end_time.After(clockNow().Add(standbyNotice)) {
+		return
+	}
+
+	repo := FreedSlotRepo{c.db.C("freed_slots")}
+	err := repo.Create(&FreedSlot{LocationID: locationId, StartTime: start_time, EndTime: end_time, FreedAt: time.Now()})
+	if err != nil {
+		log.Printf("standby: unable to record freed slot of %s: %v", locationId, err)
+	}
+}
+
+// prefers reports whether event, preferring p, would rather be in room than
+// in current.
+func prefers(p UpgradePreference, event Event, room Room, current Room) bool {
+	if len(p.Rooms) > 0 {
+		rank := func(id storage.ObjectId) int {
+			for i, preferred := range p.Rooms {
+				if preferred == id.Hex() {
+					return i
+				}
+			}
+			return len(p.Rooms)
+		}
+		return rank(room.Id) < rank(current.Id)
+	}
+
+	if room.VenueId != current.VenueId {
+		return false
+	}
+	want := p.MinCapacity
+	if want == 0 {
+		want = len(event.Guests) + 1
+	}
+	if current.Capacity >= want {
+		return false
+	}
+
+	return room.Capacity >= want || room.Capacity > current.Capacity
+}
+
+// matchStandby moves the events on standby into the freed slots, earliest
+// booking first.
+func (c *appContext) matchStandby(ctx context.Context) error {
+	slotRepo := FreedSlotRepo{c.db.C("freed_slots")}
+	slots, err := slotRepo.All()
+	if err != nil {
+		return err
+	}
+
+	for _, slot := range slots {
+		err = c.fillSlot(ctx, slot)
+		if err != nil {
+			return err
+		}
+		err = slotRepo.Delete(slot.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *appContext) fillSlot(ctx context.Context, slot FreedSlot) error {
+	repo := c.events()
+	roomRepo := c.rooms()
+	room, err := roomRepo.Find(ctx, slot.LocationID)
+	if err == ErrDocumentNotFound || err == ErrInvalidId {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	events, err := repo.AllStandby(ctx, slot.StartTime, slot.EndTime)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.OrgId != room.OrgId || event.LocationID == slot.LocationID || event.StartTime.Before(clockNow().Add(standbyNotice)) {
+			continue
+		}
+		current, err := roomRepo.Find(ctx, event.LocationID)
+		if err != nil && err != ErrDocumentNotFound && err != ErrInvalidId {
+			return err
+		}
+		if !prefers(*event.Upgrade, event, room, current) {
+			continue
+		}
+
+		from := event.Location
+		fromId := event.LocationID
+		event.LocationID = room.Id.Hex()
+		event.Location = room.Name
+		event.CheckInCode, err = c.newCheckInCode(ctx, event)
+		if err != nil {
+			return err
+		}
+		err = repo.Update(ctx, &event)
+		if _, ok := err.(*EventConflict); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		c.recordChange("event", event.Id, ChangeUpdated)
+		notifyUpgraded(event, from)
+		c.recordFreedSlot(fromId, event.StartTime, event.EndTime)
+	}
+
+	return nil
+}
+
+// watchStandby runs matchStandby for as long as the process runs.
+func (c *appContext) watchStandby() {
+	for range time.Tick(standbyInterval) {
+		if err := c.matchStandby(context.Background()); err != nil {
+			log.Println("standby: matching failed:", err)
+		}
+	}
+}
+
+func notifyUpgraded(event Event, from string) {
+	log.Printf("standby: notifying %s that %q (%s) was moved from %s to %s, check-in code %s",
+		event.Owner, event.Name, event.Id.Hex(), from, event.Location, event.CheckInCode)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Mobile sync
+//
+// GET /sync?device_token=&since=<sync token> lets the mobile app catch up in
+// one request when it comes to the foreground. It answers with the venues,
+// rooms and events of the caller (owned or invited to) that changed since
+// the token, read off the change log, the ids of those that were deleted or
+// are no longer the caller's in removed, and a new sync_token. Without since
+// the token last handed to the device is used, and without either, or when
+// more than syncMaxChanges changed, the answer is a full snapshot with full
+// set: every venue and room and the caller's events that haven't ended.
+const (
+	syncMaxChanges  = 5000
+	syncTokenPrefix = "v1:"
+)
+
+type SyncRemoved struct {
+	Venues []string `json:"venues"`
+	Rooms  []string `json:"rooms"`
+	Events []string `json:"events"`
+}
+
+type SyncResponse struct {
+	Full      bool        `json:"full"`
+	Venues    []Venue     `json:"venues"`
+	Rooms     []Room      `json:"rooms"`
+	Events    []Event     `json:"events"`
+	Removed   SyncRemoved `json:"removed"`
+	SyncToken string      `json:"sync_token"`
+}
+
+type SyncDevice struct {
+	Id       storage.ObjectId `json:"-" bson:"_id,omitempty"`
+	Token    string           `json:"device_token"`
+	User     string           `json:"user"`
+	Seq      int64            `json:"-"`
+	SyncedAt time.Time        `json:"synced_at"`
+}
+
+func syncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+func parseSyncToken(token string) (int64, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(b), syncTokenPrefix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(b), syncTokenPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false
+	}
+
+	return seq, true
+}
+
+// Repo SyncDevice
+type SyncDeviceRepo struct {
+	coll *storage.Collection
+}
+
+func (r *SyncDeviceRepo) Find(token string, user string) (SyncDevice, error) {
+	result := SyncDevice{}
+	err := r.coll.Find(bson.M{"token": token, "user": user}).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *SyncDeviceRepo) Save(device SyncDevice) error {
+	selector := bson.M{"token": device.Token, "user": device.User}
+	_, err := r.coll.Upsert(selector, bson.M{"$set": bson.M{"seq": device.Seq, "syncedat": device.SyncedAt}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo Change latest
+func (r *ChangeRepo) Latest() (int64, error) {
+	counter := struct {
+		Seq int64 `bson:"seq"`
+	}{}
+	err := r.counters.FindId(r.counterId()).One(&counter)
+	if err == storage.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Seq, nil
+}
+
+// fullSync answers with everything the device should hold, as of seq.
+func (c *appContext) fullSync(ctx context.Context, user User, seq int64) (SyncResponse, error) {
+	res := SyncResponse{Full: true, Removed: SyncRemoved{[]string{}, []string{}, []string{}}, SyncToken: syncToken(seq)}
+
+	venueRepo := c.venues()
+	venues, _, err := venueRepo.All(ctx, ListOptions{})
+	if err != nil {
+		return res, err
+	}
+	roomRepo := c.rooms()
+	rooms, _, err := roomRepo.All(ctx, ListOptions{})
+	if err != nil {
+		return res, err
+	}
+	repo := c.events()
+	events, err := repo.AllMine(ctx, user.Email, clockNow())
+	if err != nil {
+		return res, err
+	}
+	res.Venues, res.Rooms, res.Events = venues, rooms, events
+
+	return res, nil
+}
+
+// deltaSync answers with what changed in changes, the log since the device
+// last synced.
+func (c *appContext) deltaSync(ctx context.Context, user User, changes []Change, seq int64) (SyncResponse, error) {
+	res := SyncResponse{
+		Venues:    []Venue{},
+		Rooms:     []Room{},
+		Events:    []Event{},
+		Removed:   SyncRemoved{[]string{}, []string{}, []string{}},
+		SyncToken: syncToken(seq),
+	}
+
+	// The last action on each document decides whether it's sent or removed.
+	last := map[string]map[string]string{"venue": {}, "room": {}, "event": {}}
+	for _, change := range changes {
+		if actions, ok := last[change.Entity]; ok && storage.IsObjectIdHex(change.EntityId) {
+			actions[change.EntityId] = change.Action
+		}
+	}
+	changed := func(entity string) []string {
+		ids := []string{}
+		for id, action := range last[entity] {
+			if action != ChangeDeleted {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	deleted := func(entity string) []string {
+		ids := []string{}
+		for id, action := range last[entity] {
+			if action == ChangeDeleted {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	var err error
+	res.Venues, err = c.venues().AllByIds(ctx, changed("venue"))
+	if err != nil {
+		return res, err
+	}
+	res.Rooms, err = c.rooms().AllByIds(ctx, changed("room"))
+	if err != nil {
+		return res, err
+	}
+	repo := c.events()
+	eventIds := changed("event")
+	res.Events, err = repo.AllMineByIds(ctx, user.Email, eventIds)
+	if err != nil {
+		return res, err
+	}
+
+	res.Removed.Venues = deleted("venue")
+	res.Removed.Rooms = deleted("room")
+	res.Removed.Events = deleted("event")
+	mine := map[string]bool{}
+	for _, event := range res.Events {
+		mine[event.Id.Hex()] = true
+	}
+	for _, id := range eventIds {
+		if !mine[id] {
+			res.Removed.Events = append(res.Removed.Events, id)
+		}
+	}
+
+	return res, nil
+}
+
+// Sync Handlers
+func (c *appContext) syncHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userKey).(User)
+	deviceToken := strings.TrimSpace(r.URL.Query().Get("device_token"))
+	if deviceToken == "" {
+		WriteError(w, ErrMissingDeviceToken)
+		return
+	}
+
+	devices := SyncDeviceRepo{c.db.C("sync_devices")}
+	since, known := int64(0), false
+	if s := r.URL.Query().Get("since"); s != "" {
+		seq, ok := parseSyncToken(s)
+		if !ok {
+			WriteError(w, ErrInvalidSyncToken)
+			return
+		}
+		since, known = seq, true
+	} else {
+		device, err := devices.Find(deviceToken, user.Email)
+		if err != nil && err != storage.ErrNotFound {
+			panic(err)
+		}
+		since, known = device.Seq, err == nil
+	}
+
+	changeRepo := ChangeRepo{c.db.C("changes"), c.db.C("counters")}
+	latest, err := changeRepo.Latest()
+	if err != nil {
+		panic(err)
+	}
+
+	var res SyncResponse
+	changes := []Change{}
+	if known && since <= latest {
+		changes, err = changeRepo.Since(since, syncMaxChanges+1)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if !known || since > latest || len(changes) > syncMaxChanges {
+		res, err = c.fullSync(r.Context(), user, latest)
+	} else {
+		if len(changes) > 0 {
+			latest = changes[len(changes)-1].Seq
+		} else {
+			latest = since
+		}
+		res, err = c.deltaSync(r.Context(), user, changes, latest)
+	}
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	err = devices.Save(SyncDevice{Token: deviceToken, User: user.Email, Seq: latest, SyncedAt: time.Now()})
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, res)
+}
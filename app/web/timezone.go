@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Time zones
+//
+// Times are stored in UTC and read and written in the time zone of where
+// they happen: a venue's time_zone, an IANA name such as Asia/Jakarta, or
+// the org's, TIME_ZONE or else defaultTimeZone, for venues without one. A
+// request can ask for another with the Time-Zone header, and an event body
+// with its time_zone field, which then reads the date, month, year and hour
+// fields in it.
+// Events answer with their times in the zone and the zone's name in
+// time_zone, so clients render them right.
+const defaultTimeZone = "Asia/Bangkok"
+
+var (
+	ErrInvalidTimeZoneHeader = &Error{"invalid_time_zone_header", 400, "Bad request", "Time-Zone must be an IANA time zone such as Asia/Jakarta."}
+
+	// ictLocation is defaultTimeZone, which has been UTC+7 without daylight
+	// saving time since 1920, so it doesn't need the zone database.
+	ictLocation = time.FixedZone(defaultTimeZone, 7*60*60)
+
+	// defaultLocation is the org's time zone, TIME_ZONE, see config.go.
+	defaultLocation = ictLocation
+)
+
+// loadZone reads an IANA time zone name.
+func loadZone(name string) (*time.Location, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	if name == defaultTimeZone {
+		return ictLocation, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return loc, true
+}
+
+// zoneResolver finds the zone of the events of a request, the Time-Zone it
+// asks for or else the one of each event's venue.
+type zoneResolver struct {
+	c         *appContext
+	ctx       context.Context
+	requested *time.Location
+	rooms     map[string]*time.Location
+}
+
+func (c *appContext) zones(r *http.Request) (*zoneResolver, *Error) {
+	z := &zoneResolver{c: c, ctx: r.Context(), rooms: map[string]*time.Location{}}
+	if name := r.Header.Get("Time-Zone"); name != "" {
+		loc, ok := loadZone(name)
+		if !ok {
+			return nil, ErrInvalidTimeZoneHeader
+		}
+		z.requested = loc
+	}
+
+	return z, nil
+}
+
+// room returns the zone of the venue of the room with id roomId.
+func (z *zoneResolver) room(roomId string) *time.Location {
+	if z.requested != nil {
+		return z.requested
+	}
+	if loc, ok := z.rooms[roomId]; ok {
+		return loc
+	}
+
+	loc := defaultLocation
+	if room, err := z.c.rooms().Find(z.ctx, roomId); err == nil {
+		if venue, err := z.c.venues().Find(z.ctx, room.VenueId); err == nil {
+			if venueLoc, ok := loadZone(venue.TimeZone); ok {
+				loc = venueLoc
+			}
+		}
+	}
+	z.rooms[roomId] = loc
+
+	return loc
+}
+
+// event sets event's times in its zone.
+func (z *zoneResolver) event(event *Event) {
+	loc := z.room(event.LocationID)
+	event.TimeZone = loc.String()
+	event.StartTime = event.StartTime.In(loc)
+	event.EndTime = event.EndTime.In(loc)
+	if !event.OriginalStartTime.IsZero() {
+		event.OriginalStartTime = event.OriginalStartTime.In(loc)
+	}
+}
+
+func (z *zoneResolver) events(events []Event) {
+	for idx := range events {
+		z.event(&events[idx])
+	}
+}
+
+// bodyZone is the zone the date fields of body are in.
+func (z *zoneResolver) bodyZone(body *EventResponse) (*time.Location, *Error) {
+	if body.TimeZone != "" {
+		loc, ok := loadZone(body.TimeZone)
+		if !ok {
+			return nil, ErrInvalidTimeZone
+		}
+		return loc, nil
+	}
+
+	return z.room(body.LocationID), nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Travel times
+//
+// Admins configure how many minutes it takes to get from one venue to
+// another; the same figure is used in both directions. Pairs that aren't
+// configured fall back to TRAVEL_TIME_DEFAULT minutes (30 when unset).
+// Back-to-back events of the same person in venues further apart than the gap
+// between them are flagged, and find-a-time keeps that much room around
+// events elsewhere when ?venue_id= is given.
+type TravelTime struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	FromVenueId string           `json:"from_venue_id"`
+	ToVenueId   string           `json:"to_venue_id"`
+	Minutes     int              `json:"minutes"`
+}
+
+type travelMatrix struct {
+	minutes  map[[2]string]int
+	fallback int
+}
+
+// Between returns the travel time from one venue to another.
+func (m travelMatrix) Between(from string, to string) time.Duration {
+	if from == "" || to == "" || from == to {
+		return 0
+	}
+	if minutes, ok := m.minutes[[2]string{from, to}]; ok {
+		return time.Duration(minutes) * time.Minute
+	}
+
+	return time.Duration(m.fallback) * time.Minute
+}
+
+// Max returns the longest travel time the matrix can produce.
+func (m travelMatrix) Max() time.Duration {
+	max := m.fallback
+	for _, minutes := range m.minutes {
+		if minutes > max {
+			max = minutes
+		}
+	}
+
+	return time.Duration(max) * time.Minute
+}
+
+// Repo TravelTime
+type TravelTimeRepo struct {
+	coll *storage.Collection
+}
+
+func (r *TravelTimeRepo) All() ([]TravelTime, error) {
+	result := []TravelTime{}
+	err := r.coll.Find(nil).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Upsert stores the travel time between two venues, keyed on the unordered
+// pair.
+func (r *TravelTimeRepo) Upsert(travel *TravelTime) error {
+	from, to := travel.FromVenueId, travel.ToVenueId
+	if from > to {
+		from, to = to, from
+	}
+
+	info, err := r.coll.Upsert(
+		bson.M{"fromvenueid": from, "tovenueid": to},
+		bson.M{"$set": bson.M{"fromvenueid": from, "tovenueid": to, "minutes": travel.Minutes}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if id, ok := info.UpsertedId.(storage.ObjectId); ok {
+		travel.Id = id
+	}
+
+	return nil
+}
+
+func (r *TravelTimeRepo) Matrix() (travelMatrix, error) {
+	matrix := travelMatrix{minutes: map[[2]string]int{}, fallback: 30}
+	if v, err := strconv.Atoi(setting("TRAVEL_TIME_DEFAULT")); err == nil && v >= 0 {
+		matrix.fallback = v
+	}
+
+	travels, err := r.All()
+	if err != nil {
+		return matrix, err
+	}
+	for _, travel := range travels {
+		matrix.minutes[[2]string{travel.FromVenueId, travel.ToVenueId}] = travel.Minutes
+		matrix.minutes[[2]string{travel.ToVenueId, travel.FromVenueId}] = travel.Minutes
+	}
+
+	return matrix, nil
+}
+
+// roomVenues maps the room of each event to its venue.
+func (c *appContext) roomVenues(ctx context.Context, events []Event) (map[string]string, error) {
+	ids := []string{}
+	for _, event := range events {
+		if event.LocationID != "" {
+			ids = append(ids, event.LocationID)
+		}
+	}
+
+	rooms, err := c.rooms().AllByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	for _, room := range rooms {
+		result[room.Id.Hex()] = room.VenueId
+	}
+
+	return result, nil
+}
+
+// impossibleBackToBack returns a warning for every pair of consecutive events
+// of user that can't be reached in time. events must be sorted by start.
+func impossibleBackToBack(user string, events []Event, venues map[string]string, matrix travelMatrix) []TravelWarning {
+	warnings := []TravelWarning{}
+	for i := 1; i < len(events); i++ {
+		prev, next := events[i-1], events[i]
+		from, to := venues[prev.LocationID], venues[next.LocationID]
+		required := matrix.Between(from, to)
+		gap := next.StartTime.Sub(prev.EndTime)
+		if required == 0 || gap >= required {
+			continue
+		}
+
+		warnings = append(warnings, TravelWarning{
+			User:            user,
+			FromEventId:     prev.Id.Hex(),
+			FromVenueId:     from,
+			ToEventId:       next.Id.Hex(),
+			ToVenueId:       to,
+			GapMinutes:      int(gap / time.Minute),
+			RequiredMinutes: int(required / time.Minute),
+		})
+	}
+
+	return warnings
+}
+
+func participantsOf(event Event) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, p := range append([]string{event.Owner}, event.Guests...) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// travelWarnings checks whether every participant of event can make it to and
+// from their neighbouring events. Lookup failures are treated as no warning;
+// the event itself has already been saved.
+func (c *appContext) travelWarnings(ctx context.Context, event Event) []TravelWarning {
+	// Contexts without a database, running on in-memory repos, have no
+	// travel times.
+	if c.db == nil {
+		return nil
+	}
+
+	travelRepo := TravelTimeRepo{c.db.C("travel_times")}
+	matrix, err := travelRepo.Matrix()
+	if err != nil {
+		return nil
+	}
+
+	repo := c.events()
+	warnings := []TravelWarning{}
+	for _, user := range participantsOf(event) {
+		events, err := repo.AllByParticipants(ctx, []string{user}, event.StartTime.Add(-matrix.Max()), event.EndTime.Add(matrix.Max()))
+		if err != nil {
+			return nil
+		}
+		venues, err := c.roomVenues(ctx, events)
+		if err != nil {
+			return nil
+		}
+
+		for _, warning := range impossibleBackToBack(user, events, venues, matrix) {
+			if warning.FromEventId == event.Id.Hex() || warning.ToEventId == event.Id.Hex() {
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// padForTravel widens each busy event by the travel time between its venue
+// and venueId.
+func padForTravel(busy []Event, venues map[string]string, matrix travelMatrix, venueId string) []Event {
+	result := []Event{}
+	for _, event := range busy {
+		travel := matrix.Between(venues[event.LocationID], venueId)
+		event.StartTime = event.StartTime.Add(-travel)
+		event.EndTime = event.EndTime.Add(travel)
+		result = append(result, event)
+	}
+
+	return result
+}
+
+// Travel Time Handlers
+func (c *appContext) travelTimesHandler(w http.ResponseWriter, r *http.Request) {
+	repo := TravelTimeRepo{c.db.C("travel_times")}
+	travels, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, travels)
+}
+
+func (c *appContext) updateTravelTimeHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*TravelTime)
+	repo := TravelTimeRepo{c.db.C("travel_times")}
+	err := repo.Upsert(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
+
+// scheduleCheckHandler lists the impossible back-to-back events of a user in
+// the requested window.
+func (c *appContext) scheduleCheckHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	user := params.ByName("user")
+	start_time, end_time, errRes := eventsWindow(r)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+
+	travelRepo := TravelTimeRepo{c.db.C("travel_times")}
+	matrix, err := travelRepo.Matrix()
+	if err != nil {
+		panic(err)
+	}
+
+	repo := c.events()
+	events, err := repo.AllByParticipants(r.Context(), []string{user}, start_time, end_time)
+	if err != nil {
+		panic(err)
+	}
+	venues, err := c.roomVenues(r.Context(), events)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, impossibleBackToBack(user, events, venues, matrix))
+}
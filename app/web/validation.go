@@ -0,0 +1,307 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Validation
+//
+// Schemas only check the shape of a payload. Bodies that implement validator
+// are checked again by validateHandler once bodyHandler has decoded them, for
+// rules a schema can't express, and rejected with a 422 listing every invalid
+// field. Venues and rooms, whose types are in internal/venue and
+// internal/room, are checked by validateVenue and validateRoom.
+//
+// Before any of that, bodyHandler trims the strings of every body and
+// rejects those longer than maxInputLength, for fields no schema limits, and
+// idParamsHandler answers requests whose path ids are malformed with a 400,
+// so that no handler parses them.
+const maxInputLength = 20000
+
+type validator interface {
+	Validate() []*Error
+}
+
+func fieldError(field string, detail string) *Error {
+	return &Error{"validation_failed", http.StatusUnprocessableEntity, "Unprocessable Entity", field + ": " + detail}
+}
+
+// validateLocalized checks the translations in field, keyed by language tag
+// and at most max characters long.
+func validateLocalized(field string, values map[string]string, max int) []*Error {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []*Error{}
+	for _, key := range keys {
+		switch value := strings.TrimSpace(values[key]); {
+		case !languageTag.MatchString(key):
+			errs = append(errs, fieldError(field+"."+key, "must be keyed by a language tag such as ja or id-ID"))
+		case value == "":
+			errs = append(errs, fieldError(field+"."+key, "must not be blank"))
+		case len([]rune(value)) > max:
+			errs = append(errs, fieldError(field+"."+key, "must be at most "+strconv.Itoa(max)+" characters"))
+		}
+	}
+
+	return errs
+}
+
+func validateVenue(v *Venue) []*Error {
+	errs := []*Error{}
+	if strings.TrimSpace(v.Name) == "" {
+		errs = append(errs, fieldError("name", "must not be blank"))
+	}
+	if _, ok := loadZone(v.TimeZone); v.TimeZone != "" && !ok {
+		errs = append(errs, fieldError("time_zone", "must be an IANA time zone name such as Asia/Jakarta"))
+	}
+	errs = append(errs, validateLocalized("names", v.Names, 200)...)
+	errs = append(errs, validateLocalized("descriptions", v.Descriptions, 2000)...)
+
+	return errs
+}
+
+func validateRoom(r *Room) []*Error {
+	errs := []*Error{}
+	if strings.TrimSpace(r.Name) == "" {
+		errs = append(errs, fieldError("name", "must not be blank"))
+	}
+	if r.Capacity <= 0 {
+		errs = append(errs, fieldError("capacity", "must be a whole number greater than 0"))
+	}
+	errs = append(errs, validateLocalized("names", r.Names, 200)...)
+	errs = append(errs, validateLocalized("descriptions", r.Descriptions, 2000)...)
+	if r.MapCoordinates != nil {
+		if r.FloorId == "" {
+			errs = append(errs, fieldError("map_coordinates", "needs the floor_id of the plan they're on"))
+		}
+		if r.MapCoordinates.X < 0 || r.MapCoordinates.Y < 0 {
+			errs = append(errs, fieldError("map_coordinates", "must not be negative"))
+		}
+	}
+	if r.PhotoURL != "" && !validImageURL(r.PhotoURL) {
+		errs = append(errs, fieldError("photo_url", "must be an http or https URL, or a path of this API"))
+	}
+
+	return errs
+}
+
+// validImageURL reports whether s is an absolute http or https URL, or a
+// path such as those images are served at here.
+func validImageURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(u.Path, "/")
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (e *EventResponse) Validate() []*Error {
+	errs := []*Error{}
+	if strings.TrimSpace(e.Name) == "" {
+		errs = append(errs, fieldError("name", "must not be blank"))
+	}
+
+	if e.StartTime != "" || e.EndTime != "" {
+		start_time, err1 := time.Parse(time.RFC3339, e.StartTime)
+		if err1 != nil {
+			errs = append(errs, fieldError("start_time", "must be an RFC 3339 time such as 2018-06-01T09:00:00+07:00"))
+		}
+		end_time, err2 := time.Parse(time.RFC3339, e.EndTime)
+		if err2 != nil {
+			errs = append(errs, fieldError("end_time", "must be an RFC 3339 time such as 2018-06-01T10:00:00+07:00"))
+		}
+		if err1 == nil && err2 == nil && !start_time.Before(end_time) {
+			errs = append(errs, fieldError("end_time", "the event must end after it starts"))
+		}
+		if e.Year != 0 {
+			errs = append(errs, fieldError("start_time", "can't be given with the date and hour fields"))
+		}
+	} else if e.Year == 0 {
+		errs = append(errs, fieldError("start_time", "is required, or else date, month, year, start_hour, start_minute, end_hour and end_minute"))
+	} else {
+		start_time, end_time, err := e.times(time.UTC)
+		if err == nil && !start_time.Before(end_time) {
+			errs = append(errs, fieldError("end_hour", "the event must end after it starts"))
+		}
+	}
+
+	for i, guest := range e.Guests {
+		if !emailFormat.MatchString(guest) {
+			errs = append(errs, fieldError("guests["+strconv.Itoa(i)+"]", "must be a valid email address"))
+		}
+	}
+	if e.Owner != "" && !emailFormat.MatchString(e.Owner) {
+		errs = append(errs, fieldError("owner", "must be a valid email address"))
+	}
+
+	return errs
+}
+
+// sanitizeInput trims the strings of v, a decoded body, in its fields, lists
+// and maps, and reports those longer than maxInputLength.
+func sanitizeInput(v interface{}) []*Error {
+	return sanitizeValue("", reflect.ValueOf(v))
+}
+
+func sanitizeValue(field string, v reflect.Value) []*Error {
+	errs := []*Error{}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			errs = append(errs, sanitizeValue(field, v.Elem())...)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" && !f.Anonymous {
+				name = f.Name
+			}
+			if name != "" && field != "" {
+				name = field + "." + name
+			} else if name == "" {
+				name = field
+			}
+			errs = append(errs, sanitizeValue(name, v.Field(i))...)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, sanitizeValue(field+"["+strconv.Itoa(i)+"]", v.Index(i))...)
+		}
+	case reflect.Map:
+		t := v.Type()
+		if t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.String || t.Elem() == objectIdType {
+			break
+		}
+		for _, key := range v.MapKeys() {
+			value := strings.TrimSpace(v.MapIndex(key).String())
+			v.SetMapIndex(key, reflect.ValueOf(value).Convert(t.Elem()))
+			errs = append(errs, checkInputLength(field+"."+key.String(), value)...)
+		}
+	case reflect.String:
+		if v.Type() == objectIdType || !v.CanSet() {
+			break
+		}
+		value := strings.TrimSpace(v.String())
+		v.SetString(value)
+		errs = append(errs, checkInputLength(field, value)...)
+	}
+
+	return errs
+}
+
+func checkInputLength(field string, value string) []*Error {
+	if utf8.RuneCountInString(value) <= maxInputLength {
+		return nil
+	}
+
+	return []*Error{fieldError(field, "must be at most "+strconv.Itoa(maxInputLength)+" characters")}
+}
+
+// idParams names the parameters of path that are ids: :id and :room.
+func idParams(path string) []string {
+	names := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ":id" || segment == ":room" {
+			names = append(names, segment[1:])
+		}
+	}
+
+	return names
+}
+
+// validIdParam reports whether value is an ObjectId, or else a slug when
+// slugs are taken as well.
+func validIdParam(value string, slugs bool) bool {
+	if storage.IsObjectIdHex(value) {
+		return true
+	}
+
+	return slugs && value != "" && slugify(value) == value
+}
+
+func invalidIdError(name string, slugs bool) *Error {
+	detail := name + " must be a 24 character hexadecimal id such as 5b0e8f3a9d1e4c2b7a6f0e13"
+	if slugs {
+		detail += ", or a slug such as main-office"
+	}
+
+	return &Error{"invalid_id", http.StatusBadRequest, "Bad request", detail + "."}
+}
+
+// Middleware
+// idParamsHandler answers requests to the route at path whose ids are
+// malformed, see idParams. Venues, and rooms within them, are addressed by
+// slug too under /venues/:id, see slug.go. The values of the static routes
+// of a staticHandler aren't ids and are let through.
+func idParamsHandler(path string, next http.Handler) http.Handler {
+	if static, ok := next.(staticHandler); ok {
+		static.next = idParamsHandler(path, static.next)
+		return static
+	}
+	names := idParams(path)
+	if len(names) == 0 {
+		return next
+	}
+	slugs := strings.HasPrefix(path, "/venues/:id") || strings.HasPrefix(path, "/analytics/venues/:id")
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		params := routeParams(r)
+		for _, name := range names {
+			if !validIdParam(params.ByName(name), slugs) {
+				WriteError(w, invalidIdError(name, slugs))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func validateHandler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		errs := []*Error{}
+		switch body := r.Context().Value(bodyKey).(type) {
+		case validator:
+			errs = body.Validate()
+		case *Venue:
+			errs = validateVenue(body)
+		case *Room:
+			errs = validateRoom(body)
+		}
+		if len(errs) > 0 {
+			WriteErrors(w, http.StatusUnprocessableEntity, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Venue summaries
+//
+// GET /venues no longer embeds every room of every venue. Each venue carries
+// rooms_count and a next_available_room hint instead: a room free right now,
+// or the one whose current booking ends first when all of them are taken.
+// Both come from one query for the rooms of the listed venues and one for
+// what's booked now. ?include=rooms embeds the rooms as before.
+
+// included reports whether ?include= lists relation.
+func included(r *http.Request, relation string) bool {
+	for _, name := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(name) == relation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// summarizeVenues sets RoomsCount and NextAvailableRoom of venues.
+func (c *appContext) summarizeVenues(ctx context.Context, venues []Venue) error {
+	venueIds := []string{}
+	for _, venue := range venues {
+		venueIds = append(venueIds, venue.Id.Hex())
+	}
+
+	rooms, err := c.rooms().AllByVenueIds(ctx, venueIds)
+	if err != nil {
+		return err
+	}
+
+	byVenue := map[string][]Room{}
+	roomIds := []string{}
+	for _, room := range rooms {
+		byVenue[room.VenueId] = append(byVenue[room.VenueId], room)
+		roomIds = append(roomIds, room.Id.Hex())
+	}
+
+	t := clockNow()
+	ongoing, err := c.events().AllOngoing(ctx, roomIds, t)
+	if err != nil {
+		return err
+	}
+	busyUntil := map[string]time.Time{}
+	for _, event := range ongoing {
+		if event.EndTime.After(busyUntil[event.LocationID]) {
+			busyUntil[event.LocationID] = event.EndTime
+		}
+	}
+
+	for idx, venue := range venues {
+		count := len(byVenue[venue.Id.Hex()])
+		venues[idx].RoomsCount = &count
+
+		var hint *RoomHint
+		for _, room := range byVenue[venue.Id.Hex()] {
+			availableAt, busy := busyUntil[room.Id.Hex()]
+			if !busy {
+				availableAt = t
+			}
+			if hint == nil || availableAt.Before(hint.AvailableAt) {
+				hint = &RoomHint{RoomId: room.Id.Hex(), Name: room.Name, Names: room.Names, AvailableAt: availableAt}
+			}
+		}
+		venues[idx].NextAvailableRoom = hint
+	}
+
+	return nil
+}
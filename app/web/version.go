@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// API versions
+//
+// Every route is served under /v1, as in /v1/venues, and at its unprefixed
+// path, where the version is negotiated from the Accept header:
+//
+//	Accept: application/vnd.ivana.v2+json
+//
+// Requests asking for no version get baseVersion, so clients written before
+// versioning keep working, and requests asking for a version that isn't
+// served answer 406. Responses name the version they were served with in the
+// API-Version header.
+//
+// The routes registered on the router are those of baseVersion. A newer
+// version is declared with router.Version, and only registers the routes
+// whose request or response shape it changes; the others answer as in the
+// latest older version that has them. A version's routes are registered
+// after the base ones, and a route only it has answers 404 in older ones.
+const baseVersion = "v1"
+
+var (
+	ErrUnsupportedVersion = &Error{"unsupported_version", 406, "Not Acceptable", "Accept must name a served API version, such as application/vnd.ivana.v1+json."}
+)
+
+var (
+	versionPrefix    = regexp.MustCompile(`^/(v[0-9]+)(/.*)$`)
+	versionMediaType = regexp.MustCompile(`^application/vnd\.ivana\.(v[0-9]+)\+json$`)
+)
+
+// routeGroup registers the routes of a version of the API on a router.
+type routeGroup struct {
+	r       *router
+	version string
+}
+
+// Version declares version, newer than those declared before, and returns
+// the group its routes are registered on.
+func (r *router) Version(version string) *routeGroup {
+	r.versions = append(r.versions, version)
+	return &routeGroup{r, version}
+}
+
+func (g *routeGroup) Get(path string, handler http.Handler) {
+	g.r.override("GET", path, g.version, handler)
+}
+
+func (g *routeGroup) Post(path string, handler http.Handler) {
+	g.r.override("POST", path, g.version, handler)
+}
+
+func (g *routeGroup) Put(path string, handler http.Handler) {
+	g.r.override("PUT", path, g.version, handler)
+}
+
+func (g *routeGroup) Patch(path string, handler http.Handler) {
+	g.r.override("PATCH", path, g.version, handler)
+}
+
+func (g *routeGroup) Delete(path string, handler http.Handler) {
+	g.r.override("DELETE", path, g.version, handler)
+}
+
+// override registers handler for method and path in version, registering
+// the route as not found in the older versions if they don't have it.
+func (r *router) override(method string, path string, version string, handler http.Handler) {
+	route := method + " " + path
+	if r.overrides == nil {
+		r.overrides = map[string]map[string]http.Handler{}
+	}
+	if r.overrides[route] == nil {
+		r.overrides[route] = map[string]http.Handler{}
+	}
+	r.overrides[route][version] = handler
+
+	for _, registered := range r.routes {
+		if registered == route {
+			return
+		}
+	}
+	r.handle(method, path, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteError(w, ErrNotFound)
+	}))
+}
+
+// versioned serves route with the handler of the version of the request,
+// falling back to handler, the one of baseVersion.
+func (r *router) versioned(route string, handler http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, req *http.Request) {
+		version := requestVersion(req)
+		for i := len(r.versions) - 1; i >= 0; i-- {
+			if !r.newer(r.versions[i], version) {
+				if h, ok := r.overrides[route][r.versions[i]]; ok {
+					h.ServeHTTP(w, req)
+					return
+				}
+			}
+		}
+
+		handler.ServeHTTP(w, req)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// newer reports whether version a was declared after version b.
+func (r *router) newer(a string, b string) bool {
+	for _, version := range r.versions {
+		if version == b {
+			return false
+		}
+		if version == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// served reports whether version is one the router serves.
+func (r *router) served(version string) bool {
+	if version == baseVersion {
+		return true
+	}
+	for _, v := range r.versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServeHTTP routes req with the version prefix of its path taken off, or
+// with the version it accepts.
+func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	version := ""
+	if m := versionPrefix.FindStringSubmatch(req.URL.Path); m != nil && r.served(m[1]) {
+		version = m[1]
+		u := *req.URL
+		u.Path = m[2]
+		u.RawPath = ""
+		stripped := *req
+		stripped.URL = &u
+		req = &stripped
+	} else {
+		if len(r.versions) > 0 {
+			// Responses only differ by Accept with versions to choose from.
+			w.Header().Add("Vary", "Accept")
+		}
+		accepted, ok := acceptedVersion(req)
+		if ok && !r.served(accepted) {
+			WriteError(w, ErrUnsupportedVersion)
+			return
+		}
+		version = baseVersion
+		if ok {
+			version = accepted
+		}
+	}
+
+	w.Header().Set("API-Version", version)
+	r.Router.ServeHTTP(w, withValue(req, versionKey, version))
+}
+
+// acceptedVersion returns the version named by the Accept header of r, if
+// it names one.
+func acceptedVersion(r *http.Request) (string, bool) {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		if m := versionMediaType.FindStringSubmatch(strings.ToLower(mediaType)); m != nil {
+			return m[1], true
+		}
+	}
+
+	return "", false
+}
+
+// requestVersion returns the API version r is served with.
+func requestVersion(r *http.Request) string {
+	if version, ok := r.Context().Value(versionKey).(string); ok {
+		return version
+	}
+
+	return baseVersion
+}
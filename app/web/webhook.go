@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Webhooks
+//
+// Admins register HTTPS endpoints with POST /admin/webhooks, each with the
+// types of change it wants: "<entity>.<action>" of the change log, see
+// changes.go, like event.created or room.updated, or "*" for all. Deleted
+// and cancelled events are both event.cancelled. A webhook has a secret,
+// generated unless given, which is only returned when it's registered.
+//
+// Every change recorded queues a delivery to the webhooks of its
+// organization that want it. Deliveries are POSTed as JSON, signed like
+// inbound bookings are, see inbound.go, with an X-Signature header of
+// "sha256=<hex HMAC-SHA256 of the body with the secret>", plus
+// X-Webhook-Event and X-Webhook-Delivery headers. Any answer but a 2xx is
+// retried, waiting webhookBackoff, then twice as long each time, up to
+// webhookAttempts times. Deliveries are kept in webhook_deliveries, so
+// retries survive restarts, and GET /admin/webhooks/:id/deliveries lists
+// them, newest first.
+const (
+	webhookAttempts     = 8
+	webhookBackoff      = 30 * time.Second
+	webhookTimeout      = 10 * time.Second
+	webhookPollInterval = 15 * time.Second
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+var (
+	ErrInvalidWebhookURL = &Error{"invalid_webhook_url", 422, "Unprocessable Entity", "url must be an absolute https URL."}
+)
+
+var webhookSortFields = map[string]string{"created_at": "createdat", "status": "status"}
+
+type Webhook struct {
+	Id        storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	URL       string           `json:"url"`
+	Events    []string         `json:"events"`
+	Secret    string           `json:"secret,omitempty"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+
+	// OrgId is the organization the webhook belongs to, see organization.go.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+}
+
+// WebhookPayload is the body of a delivery.
+type WebhookPayload struct {
+	Id       string    `json:"id"`
+	Type     string    `json:"type"`
+	Entity   string    `json:"entity"`
+	EntityId string    `json:"entity_id"`
+	Seq      int64     `json:"seq"`
+	Time     time.Time `json:"time"`
+}
+
+type WebhookDelivery struct {
+	Id             storage.ObjectId `json:"id" bson:"_id"`
+	WebhookId      storage.ObjectId `json:"webhook_id"`
+	Type           string           `json:"type"`
+	Payload        string           `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	ResponseStatus int              `json:"response_status,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time       `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// webhookWake wakes the delivery worker of this process up as soon as
+// deliveries are queued.
+var webhookWake = make(chan struct{}, 1)
+
+// webhookType returns the type of change webhooks see.
+func webhookType(change Change) string {
+	if change.Entity == "event" && change.Action == ChangeDeleted {
+		return "event.cancelled"
+	}
+
+	return change.Entity + "." + change.Action
+}
+
+// Wants reports whether w is delivered changes of type kind.
+func (w Webhook) Wants(kind string) bool {
+	for _, event := range w.Events {
+		if event == "*" || event == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signWebhook returns the X-Signature of payload signed with secret.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryAt returns when to try a delivery again after its attempts
+// failed at t.
+func webhookRetryAt(t time.Time, attempts int) time.Time {
+	return t.Add(webhookBackoff << uint(attempts-1))
+}
+
+// Repo Webhook
+type WebhookRepo struct {
+	coll *storage.Collection
+}
+
+func (r *WebhookRepo) All() ([]Webhook, error) {
+	result := []Webhook{}
+	err := r.coll.Find(nil).Sort("-createdat").All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *WebhookRepo) AllByOrg(org string) ([]Webhook, error) {
+	result := []Webhook{}
+	query := bson.M{"orgid": org}
+	if org == "" {
+		query = bson.M{"orgid": nil}
+	}
+	err := r.coll.Find(query).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *WebhookRepo) Find(id string) (Webhook, error) {
+	result := Webhook{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.coll.FindId(oid).One(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *WebhookRepo) Create(webhook *Webhook) error {
+	webhook.Id = storage.NewObjectId()
+	err := r.coll.Insert(webhook)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) Delete(id storage.ObjectId) error {
+	err := r.coll.RemoveId(id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Repo WebhookDelivery
+type WebhookDeliveryRepo struct {
+	coll *storage.Collection
+}
+
+func (r *WebhookDeliveryRepo) AllByWebhook(webhookId storage.ObjectId, opts ListOptions) ([]WebhookDelivery, int, error) {
+	result := []WebhookDelivery{}
+	query := bson.M{"webhookid": webhookId}
+	total, err := r.coll.Find(query).Count()
+	if err != nil {
+		return result, 0, err
+	}
+	err = opts.Apply(r.coll.Find(query)).All(&result)
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *WebhookDeliveryRepo) Create(delivery *WebhookDelivery) error {
+	err := r.coll.Insert(delivery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Claim takes the next delivery due at t for lease, so no other worker
+// tries it meanwhile, failing with storage.ErrNotFound when there's none.
+func (r *WebhookDeliveryRepo) Claim(t time.Time, lease time.Duration) (WebhookDelivery, error) {
+	result := WebhookDelivery{}
+	query := bson.M{"status": DeliveryPending, "nextattemptat": bson.M{"$lte": t}}
+	_, err := r.coll.Find(query).Sort("nextattemptat").Apply(storage.Change{
+		Update:    bson.M{"$set": bson.M{"nextattemptat": t.Add(lease)}},
+		ReturnNew: true,
+	}, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *WebhookDeliveryRepo) Update(delivery WebhookDelivery) error {
+	err := r.coll.UpdateId(delivery.Id, delivery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) DeleteByWebhook(webhookId storage.ObjectId) error {
+	_, err := r.coll.RemoveAll(bson.M{"webhookid": webhookId})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// queueWebhooks queues the deliveries of change, just recorded, to the
+// webhooks of its organization that want it.
+func (c *appContext) queueWebhooks(change Change) {
+	webhooks, err := (&WebhookRepo{c.db.C("webhooks")}).AllByOrg(change.OrgId)
+	if err != nil {
+		log.Printf("webhooks: unable to list webhooks: %v", err)
+		return
+	}
+
+	kind := webhookType(change)
+	repo := WebhookDeliveryRepo{c.db.C("webhook_deliveries")}
+	queued := false
+	for _, webhook := range webhooks {
+		if !webhook.Wants(kind) {
+			continue
+		}
+		id := storage.NewObjectId()
+		payload, err := json.Marshal(WebhookPayload{id.Hex(), kind, change.Entity, change.EntityId, change.Seq, change.Time})
+		if err != nil {
+			panic(err)
+		}
+		delivery := WebhookDelivery{
+			Id:            id,
+			WebhookId:     webhook.Id,
+			Type:          kind,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: change.Time,
+			CreatedAt:     change.Time,
+		}
+		if err := repo.Create(&delivery); err != nil {
+			log.Printf("webhooks: unable to queue %s for %s: %v", kind, webhook.Id.Hex(), err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case webhookWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// deliver attempts delivery to webhook and records how it went.
+func deliver(client *http.Client, webhook Webhook, delivery *WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Signature", signWebhook(webhook.Secret, []byte(delivery.Payload)))
+		req.Header.Set("X-Webhook-Event", delivery.Type)
+		req.Header.Set("X-Webhook-Delivery", delivery.Id.Hex())
+		var res *http.Response
+		res, err = client.Do(req)
+		if err == nil {
+			res.Body.Close()
+			delivery.ResponseStatus = res.StatusCode
+		}
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	switch {
+	case delivery.ResponseStatus >= 200 && delivery.ResponseStatus < 300:
+		delivery.Status = DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= webhookAttempts:
+		delivery.Status = DeliveryFailed
+	default:
+		delivery.NextAttemptAt = webhookRetryAt(now, delivery.Attempts)
+	}
+}
+
+// deliverWebhooks attempts the deliveries due until there are none left.
+func (c *appContext) deliverWebhooks(client *http.Client) error {
+	webhooks := WebhookRepo{c.db.C("webhooks")}
+	repo := WebhookDeliveryRepo{c.db.C("webhook_deliveries")}
+	for {
+		delivery, err := repo.Claim(time.Now(), 2*webhookTimeout)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		webhook, err := webhooks.Find(delivery.WebhookId.Hex())
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+		if err == storage.ErrNotFound {
+			delivery.Status = DeliveryFailed
+			delivery.Error = "webhook deleted"
+		} else {
+			deliver(client, webhook, &delivery)
+		}
+		if err := repo.Update(delivery); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *appContext) watchWebhooks() {
+	client := &http.Client{Timeout: webhookTimeout}
+	tick := time.Tick(webhookPollInterval)
+	for {
+		select {
+		case <-tick:
+		case <-webhookWake:
+		}
+		if err := c.deliverWebhooks(client); err != nil {
+			log.Println("Delivering webhooks failed:", err)
+		}
+	}
+}
+
+// Webhook Handlers
+func (c *appContext) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	repo := WebhookRepo{c.db.C("webhooks")}
+	webhooks, err := repo.All()
+	if err != nil {
+		panic(err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	WriteSuccess(w, http.StatusOK, webhooks)
+}
+
+func (c *appContext) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body := r.Context().Value(bodyKey).(*Webhook)
+	user := r.Context().Value(userKey).(User)
+	if u, err := url.Parse(body.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+		WriteError(w, ErrInvalidWebhookURL)
+		return
+	}
+
+	webhook := Webhook{
+		URL:       body.URL,
+		Events:    body.Events,
+		Secret:    body.Secret,
+		CreatedBy: user.Email,
+		CreatedAt: time.Now(),
+	}
+	// Generated secrets are as random as API keys.
+	if webhook.Secret == "" {
+		webhook.Secret = newAPIKey()
+	}
+
+	repo := WebhookRepo{c.db.C("webhooks")}
+	if err := repo.Create(&webhook); err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusCreated, webhook)
+}
+
+func (c *appContext) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := WebhookRepo{c.db.C("webhooks")}
+	webhook, err := repo.Find(params.ByName("id"))
+	if err == storage.ErrNotFound || err == storage.ErrInvalidId {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err := repo.Delete(webhook.Id); err != nil {
+		panic(err)
+	}
+	deliveries := WebhookDeliveryRepo{c.db.C("webhook_deliveries")}
+	if err := deliveries.DeleteByWebhook(webhook.Id); err != nil {
+		panic(err)
+	}
+
+	data := MessageSuccess{MessageInfo{Message: "Webhook has been deleted successfully"}}
+	WriteSuccess(w, http.StatusAccepted, data)
+}
+
+func (c *appContext) webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	webhook, err := (&WebhookRepo{c.db.C("webhooks")}).Find(params.ByName("id"))
+	if err == storage.ErrNotFound || err == storage.ErrInvalidId {
+		WriteError(w, ErrNotFound)
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	opts, errRes := listOptions(r, "webhook_deliveries", webhookSortFields)
+	if errRes != nil {
+		WriteError(w, errRes)
+		return
+	}
+	// The log only grows, so it's always paged.
+	opts.Paged = true
+	if len(opts.Sort) == 0 {
+		opts.Sort = []string{"-createdat"}
+	}
+
+	repo := WebhookDeliveryRepo{c.db.C("webhook_deliveries")}
+	deliveries, total, err := repo.AllByWebhook(webhook.Id, opts)
+	if err != nil {
+		WriteRepoError(w, err)
+		return
+	}
+
+	writeList(w, r, opts, total, deliveries)
+}
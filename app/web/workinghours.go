@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Working hours
+//
+// Each user can store the days and hours they work, in their own time zone.
+// Users who haven't set any are assumed to work Monday to Friday, 09:00 to
+// 17:00 in the org's time zone, defaultTimeZone.
+type WorkingDay struct {
+	Weekday time.Weekday `json:"weekday"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+type WorkingHours struct {
+	Id       storage.ObjectId `json:"-" bson:"_id,omitempty"`
+	User     string           `json:"user"`
+	TimeZone string           `json:"time_zone"`
+	Days     []WorkingDay     `json:"days"`
+}
+
+func defaultWorkingHours(user string) WorkingHours {
+	days := []WorkingDay{}
+	for d := time.Monday; d <= time.Friday; d++ {
+		days = append(days, WorkingDay{d, "09:00", "17:00"})
+	}
+
+	return WorkingHours{User: user, Days: days}
+}
+
+// location returns the time zone the hours are expressed in.
+func (h WorkingHours) location() *time.Location {
+	if h.TimeZone != "" {
+		if loc, err := time.LoadLocation(h.TimeZone); err == nil {
+			return loc
+		}
+	}
+
+	return defaultLocation
+}
+
+// Covers reports whether [start_time, end_time) falls entirely inside one
+// working day.
+func (h WorkingHours) Covers(start_time time.Time, end_time time.Time) bool {
+	loc := h.location()
+	start_time, end_time = start_time.In(loc), end_time.In(loc)
+
+	for _, day := range h.Days {
+		if day.Weekday != start_time.Weekday() {
+			continue
+		}
+		from, err1 := time.ParseInLocation("15:04", day.Start, loc)
+		to, err2 := time.ParseInLocation("15:04", day.End, loc)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		y, m, d := start_time.Date()
+		from = time.Date(y, m, d, from.Hour(), from.Minute(), 0, 0, loc)
+		to = time.Date(y, m, d, to.Hour(), to.Minute(), 0, 0, loc)
+		if !start_time.Before(from) && !end_time.After(to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Repo WorkingHours
+type WorkingHoursRepo struct {
+	coll *storage.Collection
+}
+
+// Find returns the stored hours of user, or the defaults.
+func (r *WorkingHoursRepo) Find(user string) (WorkingHours, error) {
+	result := WorkingHours{}
+	err := r.coll.Find(bson.M{"user": user}).One(&result)
+	if err == storage.ErrNotFound {
+		return defaultWorkingHours(user), nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *WorkingHoursRepo) AllByUsers(users []string) (map[string]WorkingHours, error) {
+	stored := []WorkingHours{}
+	err := r.coll.Find(bson.M{"user": bson.M{"$in": users}}).All(&stored)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]WorkingHours{}
+	for _, user := range users {
+		result[user] = defaultWorkingHours(user)
+	}
+	for _, hours := range stored {
+		result[hours.User] = hours
+	}
+
+	return result, nil
+}
+
+func (r *WorkingHoursRepo) Upsert(hours *WorkingHours) error {
+	_, err := r.coll.Upsert(bson.M{"user": hours.User}, bson.M{"$set": bson.M{
+		"user":     hours.User,
+		"timezone": hours.TimeZone,
+		"days":     hours.Days,
+	}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Working Hours Handlers
+func (c *appContext) workingHoursHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	repo := WorkingHoursRepo{c.db.C("working_hours")}
+	hours, err := repo.Find(params.ByName("user"))
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusOK, hours)
+}
+
+func (c *appContext) updateWorkingHoursHandler(w http.ResponseWriter, r *http.Request) {
+	params := routeParams(r)
+	body := r.Context().Value(bodyKey).(*WorkingHours)
+	body.User = params.ByName("user")
+	if user := r.Context().Value(userKey).(User); !user.IsAdmin() && body.User != user.Email {
+		WriteError(w, ErrNotOwnWorkingHours)
+		return
+	}
+	if body.TimeZone != "" {
+		if _, err := time.LoadLocation(body.TimeZone); err != nil {
+			WriteError(w, ErrInvalidTimeZone)
+			return
+		}
+	}
+	for _, day := range body.Days {
+		if day.Start >= day.End {
+			WriteError(w, ErrInvalidTimeRange)
+			return
+		}
+	}
+
+	repo := WorkingHoursRepo{c.db.C("working_hours")}
+	err := repo.Upsert(body)
+	if err != nil {
+		panic(err)
+	}
+
+	WriteSuccess(w, http.StatusAccepted, body)
+}
@@ -0,0 +1,185 @@
+// Package event holds events, the bookings of rooms, and their
+// repositories: MongoRepository over the events collection,
+// PostgresRepository over the events table and MemRepository in memory. All
+// refuse an event overlapping another booking of its room with a *Conflict.
+// The handlers of app/web reach them through Repository.
+package event
+
+import (
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+type Event struct {
+	Id          storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string           `json:"name"`
+	LocationID  string           `json:"location_id"`
+	Location    string           `json:"location"`
+	Description string           `json:"description"`
+	Guests      []string         `json:"guests"`
+	Owner       string           `json:"owner"`
+	StartTime   time.Time        `json:"start_time"`
+	EndTime     time.Time        `json:"end_time"`
+	Source      string           `json:"source,omitempty"`
+	ExternalId  string           `json:"external_id,omitempty"`
+
+	// Participants are the guests with their answers, see app/web/rsvp.go.
+	Participants []Participant `json:"participants,omitempty" bson:",omitempty"`
+	RSVP         *RSVPSummary  `json:"rsvp,omitempty" bson:"-"`
+
+	// TimeZone names the zone StartTime and EndTime are in, see
+	// app/web/timezone.go.
+	TimeZone string `json:"time_zone,omitempty" bson:"-"`
+
+	// DescriptionHTML is the rendered form of Description, see
+	// app/web/richtext.go.
+	DescriptionHTML string             `json:"-"`
+	Equipment       []EquipmentBooking `json:"equipment,omitempty"`
+	TravelWarnings  []TravelWarning    `json:"travel_warnings,omitempty" bson:"-"`
+	Category        string             `json:"category,omitempty"`
+	Tentative       bool               `json:"tentative"`
+	CheckInCode     string             `json:"check_in_code,omitempty"`
+	CheckedInAt     time.Time          `json:"checked_in_at"`
+	CapWarning      string             `json:"cap_warning,omitempty" bson:"-"`
+	PendingApproval bool               `json:"pending_approval"`
+
+	// Recurring events and changed occurrences, see recurrence.go.
+	Recurrence        *Recurrence      `json:"recurrence,omitempty" bson:",omitempty"`
+	SeriesEndTime     time.Time        `json:"-"`
+	RecurringEventId  storage.ObjectId `json:"recurring_event_id,omitempty" bson:",omitempty"`
+	OriginalStartTime time.Time        `json:"original_start_time,omitempty" bson:",omitempty"`
+
+	// Upgrade opts into being moved to a better room, see
+	// app/web/standby.go.
+	Upgrade *UpgradePreference `json:"upgrade,omitempty" bson:",omitempty"`
+
+	// BookedVia is where the event was booked, see app/web/attribution.go.
+	BookedVia BookingSource `json:"booked_via"`
+
+	// GroupId is the event group the event is a session of, see
+	// app/web/eventgroup.go.
+	GroupId string `json:"group_id,omitempty" bson:",omitempty"`
+
+	// Requester booked the event through a booking link, see
+	// app/web/booklink.go.
+	Requester *BookingRequester `json:"requester,omitempty" bson:",omitempty"`
+
+	// ReleasedAt is set on bookings cancelled because nobody checked in,
+	// see app/web/release.go.
+	ReleasedAt *time.Time `json:"released_at,omitempty" bson:",omitempty"`
+
+	// CancelledAt, CancelledBy and CancellationReason are set on events
+	// cancelled with POST /events/:id/cancel, see app/web/cancellation.go.
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty" bson:",omitempty"`
+	CancelledBy        string     `json:"cancelled_by,omitempty" bson:",omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty" bson:",omitempty"`
+
+	// OrgId is the organization the event belongs to, see storage.ScopeByOrg.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+
+	// DeletedAt is set on deleted events, see storage.DeletedFilter.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:",omitempty"`
+}
+
+// EquipmentBooking reserves Quantity of a piece of equipment for the event,
+// see app/web/equipment.go.
+type EquipmentBooking struct {
+	EquipmentId string `json:"equipment_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// Participant is a guest of the event and their answer to it, see
+// app/web/rsvp.go.
+type Participant struct {
+	Email       string     `json:"email"`
+	Status      string     `json:"status"`
+	Optional    bool       `json:"optional"`
+	RespondedAt *time.Time `json:"responded_at,omitempty" bson:",omitempty"`
+}
+
+// RSVPSummary counts the answers of the participants of an event.
+type RSVPSummary struct {
+	Accepted    int `json:"accepted"`
+	Declined    int `json:"declined"`
+	Tentative   int `json:"tentative"`
+	NeedsAction int `json:"needs_action"`
+
+	// RequiredPending counts the required participants who haven't
+	// accepted.
+	RequiredPending int `json:"required_pending"`
+}
+
+// TravelWarning flags a guest with too little time to get from the venue of
+// one event to the next, see app/web/travel.go.
+type TravelWarning struct {
+	User            string `json:"user"`
+	FromEventId     string `json:"from_event_id"`
+	FromVenueId     string `json:"from_venue_id"`
+	ToEventId       string `json:"to_event_id"`
+	ToVenueId       string `json:"to_venue_id"`
+	GapMinutes      int    `json:"gap_minutes"`
+	RequiredMinutes int    `json:"required_minutes"`
+}
+
+// UpgradePreference lists the rooms, or the capacity, the event would rather
+// have, see app/web/standby.go.
+type UpgradePreference struct {
+	Rooms       []string `json:"rooms,omitempty"`
+	MinCapacity int      `json:"min_capacity,omitempty"`
+}
+
+// BookingSource is the channel, and the integration within it, the event was
+// booked through, see app/web/attribution.go.
+type BookingSource struct {
+	Channel     string `json:"channel"`
+	Integration string `json:"integration,omitempty"`
+}
+
+func (s BookingSource) String() string {
+	if s.Integration == "" {
+		return s.Channel
+	}
+
+	return s.Channel + ":" + s.Integration
+}
+
+// BookingRequester is who booked the event through a booking link, see
+// app/web/booklink.go.
+type BookingRequester struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company,omitempty"`
+}
+
+// Conflict is returned by Create and Update when the room is already booked
+// for part of the event's time.
+type Conflict struct {
+	Event Event
+}
+
+func (e *Conflict) Error() string {
+	return "room is already booked by event " + e.Event.Id.Hex()
+}
+
+// conflict returns the booking among others that event overlaps, if any.
+func conflict(event *Event, end_time time.Time, others []Event) error {
+	for _, occurrence := range event.Occurrences(event.StartTime, end_time) {
+		for _, other := range others {
+			if other.StartTime.Before(occurrence.EndTime) && other.EndTime.After(occurrence.StartTime) && !event.Replaces(other) {
+				return &Conflict{other}
+			}
+		}
+	}
+
+	return nil
+}
+
+// seriesEnd is when the last occurrence of event ends.
+func seriesEnd(event *Event) time.Time {
+	if event.Recurrence != nil {
+		return event.SeriesEndTime
+	}
+
+	return event.EndTime
+}
@@ -0,0 +1,519 @@
+package event
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Feature queries
+//
+// Check-in, equipment, event groups, fairness, find-time, free/busy, inbound
+// and ICS imports, locations, no-shows, search, standby, sync and usage
+// reports look events up by more than their room and time window, see the
+// files of app/web named after them. MongoDB runs the queries below; the
+// in-memory and PostgreSQL repos match the events with the predicates next
+// to them, PostgreSQL narrowing the rows down by room and time window first
+// where it can.
+//
+// Search holds the filters of GET /events/search, see app/web/search.go.
+type Search struct {
+	RoomIds   []string
+	Owner     string
+	Guest     string
+	Text      string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// overlaps reports whether the time window of event intersects
+// [start_time, end_time).
+func overlaps(event Event, start_time time.Time, end_time time.Time) bool {
+	return event.StartTime.Before(end_time) && event.EndTime.After(start_time)
+}
+
+// participant reports whether one of participants owns event or is invited
+// to it.
+func participant(event Event, participants []string) bool {
+	if contains(participants, event.Owner) {
+		return true
+	}
+	for _, guest := range event.Guests {
+		if contains(participants, guest) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Upcoming reports whether the event hasn't started by t, or has
+// occurrences after t.
+func (e Event) Upcoming(t time.Time) bool {
+	if e.Recurrence != nil {
+		return !e.SeriesEndTime.Before(t)
+	}
+
+	return !e.StartTime.Before(t)
+}
+
+func upcomingQuery(t time.Time) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"recurrence": nil, "starttime": bson.M{"$gte": t}},
+		{"recurrence": bson.M{"$ne": nil}, "seriesendtime": bson.M{"$gte": t}},
+	}}
+}
+
+// Predicates of the feature queries
+func byCheckInCode(locationId string, code string, t time.Time, early time.Duration) func(Event) bool {
+	return func(event Event) bool {
+		return event.LocationID == locationId && event.CheckInCode == code &&
+			!event.StartTime.After(t.Add(early)) && event.EndTime.After(t)
+	}
+}
+
+func checkInCodeTaken(locationId string, code string, start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return event.LocationID == locationId && event.CheckInCode == code && overlaps(event, start_time, end_time)
+	}
+}
+
+func byEquipment(equipmentId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) func(Event) bool {
+	return func(event Event) bool {
+		if event.Id == exceptId && exceptId != "" || !overlaps(event, start_time, end_time) {
+			return false
+		}
+		for _, booking := range event.Equipment {
+			if booking.EquipmentId == equipmentId {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func byOwners(owners []string, start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return contains(owners, event.Owner) && overlaps(event, start_time, end_time)
+	}
+}
+
+func byParticipants(participants []string, start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return participant(event, participants) && overlaps(event, start_time, end_time)
+	}
+}
+
+// busy matches the events of participants or at locationIds.
+func busy(participants []string, locationIds []string) func(Event) bool {
+	return func(event Event) bool {
+		return contains(locationIds, event.LocationID) || participant(event, participants)
+	}
+}
+
+func locationMismatch(roomId string, name string) func(Event) bool {
+	return func(event Event) bool {
+		return event.LocationID == roomId && event.Location != name
+	}
+}
+
+func endedWithoutCheckIn(start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return !event.EndTime.Before(start_time) && event.EndTime.Before(end_time) &&
+			event.CheckInCode != "" && event.CheckedInAt.IsZero()
+	}
+}
+
+func standby(start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return event.Upgrade != nil && event.Recurrence == nil && overlaps(event, start_time, end_time)
+	}
+}
+
+func mine(email string) func(Event) bool {
+	return func(event Event) bool {
+		return participant(event, []string{email})
+	}
+}
+
+// notEnded matches the events and series not over by t.
+func notEnded(t time.Time) func(Event) bool {
+	return func(event Event) bool {
+		if event.Recurrence != nil {
+			return !event.SeriesEndTime.Before(t)
+		}
+
+		return !event.EndTime.Before(t)
+	}
+}
+
+// usage matches the bookings at locationIds starting within
+// [start_time, end_time), deleted or not: the released ones count as
+// no-shows.
+func usage(locationIds []string, start_time time.Time, end_time time.Time) func(Event) bool {
+	return func(event Event) bool {
+		return (event.DeletedAt == nil || event.ReleasedAt != nil) && event.Recurrence == nil &&
+			contains(locationIds, event.LocationID) && !event.StartTime.Before(start_time) && event.StartTime.Before(end_time)
+	}
+}
+
+// matches reports whether event passes the filters of s, recurring events
+// by their series.
+func (s Search) matches(event Event) bool {
+	if !event.StartTime.Before(s.EndTime) {
+		return false
+	}
+	if event.Recurrence == nil && !event.EndTime.After(s.StartTime) || event.Recurrence != nil && !event.SeriesEndTime.After(s.StartTime) {
+		return false
+	}
+	if len(s.RoomIds) > 0 && !contains(s.RoomIds, event.LocationID) {
+		return false
+	}
+	if s.Owner != "" && event.Owner != s.Owner || s.Guest != "" && !contains(event.Guests, s.Guest) {
+		return false
+	}
+	text := strings.ToLower(s.Text)
+
+	return text == "" || strings.Contains(strings.ToLower(event.Name), text) || strings.Contains(strings.ToLower(event.Description), text)
+}
+
+// expand replaces the recurring events among found with their occurrences
+// within the window of s, sorted by start time.
+func (s Search) expand(found []Event) []Event {
+	result := []Event{}
+	for _, event := range found {
+		if event.Recurrence == nil {
+			result = append(result, event)
+			continue
+		}
+		for _, occurrence := range event.Occurrences(s.StartTime, s.EndTime) {
+			if !occurrence.StartTime.Before(s.StartTime) {
+				result = append(result, occurrence)
+			}
+		}
+	}
+	sortByStartTime(result)
+
+	return result
+}
+
+// withBusyOccurrences adds the occurrences matching accepts to events.
+func withBusyOccurrences(events []Event, occurrences []Event, accepts func(Event) bool) []Event {
+	for _, occurrence := range occurrences {
+		if accepts(occurrence) {
+			events = append(events, occurrence)
+		}
+	}
+
+	return events
+}
+
+func sortByStartTime(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+}
+
+func idsOf(events []Event) []storage.ObjectId {
+	result := []storage.ObjectId{}
+	for _, event := range events {
+		result = append(result, event.Id)
+	}
+
+	return result
+}
+
+func containsId(ids []storage.ObjectId, id storage.ObjectId) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the events the MongoDB query matches, sorted by fields.
+func (r *MongoRepository) find(ctx context.Context, query bson.M, fields ...string) ([]Event, error) {
+	result := []Event{}
+	err := r.policy.Read(func() error {
+		q := r.coll.WithContext(ctx).Find(storage.NotDeleted(query))
+		if len(fields) > 0 {
+			q = q.Sort(fields...)
+		}
+		return q.All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) findOne(ctx context.Context, query bson.M) (Event, error) {
+	result := Event{}
+	err := r.policy.Read(func() error { return r.coll.WithContext(ctx).Find(storage.NotDeleted(query)).One(&result) })
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+// Repo Event check-in
+func (r *MongoRepository) FindByCheckInCode(ctx context.Context, locationId string, code string, t time.Time, early time.Duration) (Event, error) {
+	return r.findOne(ctx, bson.M{
+		"locationid":  locationId,
+		"checkincode": code,
+		"starttime":   bson.M{"$lte": t.Add(early)},
+		"endtime":     bson.M{"$gt": t},
+	})
+}
+
+func (r *MongoRepository) CheckInCodeTaken(ctx context.Context, locationId string, code string, start_time time.Time, end_time time.Time) (bool, error) {
+	count := 0
+	err := r.policy.Read(func() error {
+		var err error
+		count, err = r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{
+			"locationid":  locationId,
+			"checkincode": code,
+			"starttime":   bson.M{"$lt": end_time},
+			"endtime":     bson.M{"$gt": start_time},
+		})).Count()
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *MongoRepository) CheckIn(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	err := r.coll.WithContext(ctx).UpdateId(id, bson.M{"$set": bson.M{"checkedinat": t}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Repo Event equipment
+func (r *MongoRepository) AllByEquipment(ctx context.Context, equipmentId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	query := bson.M{
+		"equipment.equipmentid": equipmentId,
+		"starttime":             bson.M{"$lt": end_time},
+		"endtime":               bson.M{"$gt": start_time},
+	}
+	if exceptId != "" {
+		query["_id"] = bson.M{"$ne": exceptId}
+	}
+
+	return r.find(ctx, query)
+}
+
+// Repo Event group
+func (r *MongoRepository) AllByGroupId(ctx context.Context, groupId string) ([]Event, error) {
+	return r.find(ctx, bson.M{"groupid": groupId}, "starttime")
+}
+
+func (r *MongoRepository) Ungroup(ctx context.Context, groupId string) error {
+	_, err := r.coll.WithContext(ctx).UpdateAll(bson.M{"groupid": groupId}, bson.M{"$unset": bson.M{"groupid": ""}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Repo Event owners
+func (r *MongoRepository) AllByOwners(ctx context.Context, owners []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.find(ctx, bson.M{
+		"owner":     bson.M{"$in": owners},
+		"starttime": bson.M{"$lt": end_time},
+		"endtime":   bson.M{"$gt": start_time},
+	})
+}
+
+// Repo Event participants
+func (r *MongoRepository) AllByParticipants(ctx context.Context, participants []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.find(ctx, bson.M{
+		"$or": []bson.M{
+			{"owner": bson.M{"$in": participants}},
+			{"guests": bson.M{"$in": participants}},
+		},
+		"starttime": bson.M{"$lt": end_time},
+		"endtime":   bson.M{"$gt": start_time},
+	}, "starttime")
+}
+
+// Repo Event free/busy
+func (r *MongoRepository) AllBusy(ctx context.Context, participants []string, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result, err := r.find(ctx, bson.M{
+		"$or": []bson.M{
+			{"owner": bson.M{"$in": participants}},
+			{"guests": bson.M{"$in": participants}},
+			{"locationid": bson.M{"$in": locationIds}},
+		},
+		"recurrence": nil,
+		"starttime":  bson.M{"$lt": end_time},
+		"endtime":    bson.M{"$gt": start_time},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, "", start_time, end_time, "")
+	if err != nil {
+		return result, err
+	}
+
+	return withBusyOccurrences(result, occurrences, busy(participants, locationIds)), nil
+}
+
+// Repo Event inbound
+func (r *MongoRepository) FindByExternalId(ctx context.Context, source string, externalId string) (Event, error) {
+	return r.findOne(ctx, bson.M{"source": source, "externalid": externalId})
+}
+
+// Repo Event locations
+func (r *MongoRepository) LocationMismatches(ctx context.Context, roomId string, name string) ([]Event, error) {
+	return r.find(ctx, bson.M{"locationid": roomId, "location": bson.M{"$ne": name}}, "starttime")
+}
+
+// StaleLocations returns the ids of the events in roomId that haven't started
+// by t and aren't named name.
+func (r *MongoRepository) StaleLocations(ctx context.Context, roomId string, name string, t time.Time) ([]storage.ObjectId, error) {
+	events := []Event{}
+	query := storage.NotDeleted(bson.M{"$and": []bson.M{{"locationid": roomId, "location": bson.M{"$ne": name}}, upcomingQuery(t)}})
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(query).Select(bson.M{"_id": 1}).All(&events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return idsOf(events), nil
+}
+
+func (r *MongoRepository) SetLocation(ctx context.Context, ids []storage.ObjectId, name string) error {
+	_, err := r.coll.WithContext(ctx).UpdateAll(bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"location": name}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Repo Event recurrence exceptions
+func (r *MongoRepository) AllByRecurringEvent(ctx context.Context, id storage.ObjectId) ([]Event, error) {
+	return r.find(ctx, bson.M{"recurringeventid": id})
+}
+
+// Repo Event no-shows
+func (r *MongoRepository) EndedWithoutCheckIn(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.find(ctx, bson.M{
+		"endtime":     bson.M{"$gte": start_time, "$lt": end_time},
+		"checkincode": bson.M{"$ne": ""},
+		"checkedinat": time.Time{},
+	})
+}
+
+func (r *MongoRepository) AllPendingApproval(ctx context.Context) ([]Event, error) {
+	return r.find(ctx, bson.M{"pendingapproval": true}, "starttime")
+}
+
+// Repo Event search
+func (r *MongoRepository) Search(ctx context.Context, search Search) ([]Event, error) {
+	query := bson.M{
+		"starttime": bson.M{"$lt": search.EndTime},
+		"$or": []bson.M{
+			{"recurrence": nil, "endtime": bson.M{"$gt": search.StartTime}},
+			{"recurrence": bson.M{"$ne": nil}, "seriesendtime": bson.M{"$gt": search.StartTime}},
+		},
+	}
+	if len(search.RoomIds) > 0 {
+		query["locationid"] = bson.M{"$in": search.RoomIds}
+	}
+	if search.Owner != "" {
+		query["owner"] = search.Owner
+	}
+	if search.Guest != "" {
+		query["guests"] = search.Guest
+	}
+	if search.Text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search.Text), Options: "i"}
+		query["$and"] = []bson.M{{"$or": []bson.M{{"name": pattern}, {"description": pattern}}}}
+	}
+
+	found, err := r.find(ctx, query)
+	if err != nil {
+		return []Event{}, err
+	}
+
+	return search.expand(found), nil
+}
+
+// Repo Event standby
+func (r *MongoRepository) AllStandby(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.find(ctx, bson.M{
+		"upgrade":    bson.M{"$ne": nil},
+		"recurrence": nil,
+		"starttime":  bson.M{"$lt": end_time},
+		"endtime":    bson.M{"$gt": start_time},
+	}, "_id")
+}
+
+// Repo Event sync
+func mineQuery(email string) bson.M {
+	return bson.M{"$or": []bson.M{{"owner": email}, {"guests": email}}}
+}
+
+// AllMine returns the events email owns or is invited to that haven't ended
+// by t.
+func (r *MongoRepository) AllMine(ctx context.Context, email string, t time.Time) ([]Event, error) {
+	return r.find(ctx, bson.M{"$and": []bson.M{mineQuery(email), {"$or": []bson.M{
+		{"recurrence": nil, "endtime": bson.M{"$gte": t}},
+		{"recurrence": bson.M{"$ne": nil}, "seriesendtime": bson.M{"$gte": t}},
+	}}}}, "starttime")
+}
+
+// AllMineByIds returns those of the events with ids that email owns or is
+// invited to.
+func (r *MongoRepository) AllMineByIds(ctx context.Context, email string, ids []string) ([]Event, error) {
+	oids := []storage.ObjectId{}
+	for _, id := range ids {
+		if storage.IsObjectIdHex(id) {
+			oids = append(oids, storage.ObjectIdHex(id))
+		}
+	}
+
+	return r.find(ctx, bson.M{"$and": []bson.M{mineQuery(email), {"_id": bson.M{"$in": oids}}}})
+}
+
+// Repo Event usage
+//
+// AllUsage returns the bookings at locationIds starting within
+// [start_time, end_time), with those deleted when they were released for
+// nobody checking in.
+func (r *MongoRepository) AllUsage(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result := []Event{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(bson.M{
+			"locationid": bson.M{"$in": locationIds},
+			"starttime":  bson.M{"$gte": start_time, "$lt": end_time},
+			"recurrence": nil,
+			"$or":        []bson.M{{"deletedat": nil}, {"releasedat": bson.M{"$ne": nil}}},
+		}).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
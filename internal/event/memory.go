@@ -0,0 +1,467 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Event in memory
+//
+// MemRepository behaves like MongoRepository: deletes are soft, lookups
+// leave deleted events out, and events conflicting with a booking of their
+// room are refused with a *Conflict. It never blocks, so it only reads the
+// organization to keep to from ctx, see storage.WithOrg.
+type MemRepository struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemRepository() *MemRepository {
+	return &MemRepository{}
+}
+
+// index returns the lookup of the events seen in ctx by id.
+func (r *MemRepository) index(ctx context.Context) func(storage.ObjectId) int {
+	return func(id storage.ObjectId) int {
+		for idx, event := range r.events {
+			if event.Id == id && event.DeletedAt == nil && storage.InOrg(ctx, event.OrgId) {
+				return idx
+			}
+		}
+
+		return -1
+	}
+}
+
+// checkConflict is MongoRepository.CheckConflict over the events in memory.
+func (r *MemRepository) checkConflict(event *Event) error {
+	if event.LocationID == "" {
+		return nil
+	}
+
+	end_time := seriesEnd(event)
+	others := []Event{}
+	for _, stored := range r.events {
+		if stored.DeletedAt != nil || stored.LocationID != event.LocationID || stored.Id == event.Id {
+			continue
+		}
+		others = append(others, stored.Occurrences(event.StartTime, end_time)...)
+	}
+
+	return conflict(event, end_time, others)
+}
+
+// copyEvent keeps the stored events from sharing their recurrence with the
+// caller's.
+func copyEvent(event Event) Event {
+	if event.Recurrence != nil {
+		recurrence := *event.Recurrence
+		recurrence.Exceptions = append([]time.Time{}, recurrence.Exceptions...)
+		event.Recurrence = &recurrence
+	}
+
+	return event
+}
+
+// allBy returns copies of the stored events seen in ctx match accepts.
+func (r *MemRepository) allBy(ctx context.Context, match func(Event) bool) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Event{}
+	for _, event := range r.events {
+		if storage.InOrg(ctx, event.OrgId) && match(event) {
+			result = append(result, copyEvent(event))
+		}
+	}
+
+	return result
+}
+
+func (r *MemRepository) All(ctx context.Context, start_time time.Time, end_time time.Time, includeDeleted bool) ([]Event, error) {
+	return r.allBy(ctx, func(event Event) bool {
+		return (event.DeletedAt == nil || includeDeleted) &&
+			!event.StartTime.Before(start_time) && !event.StartTime.After(end_time)
+	}), nil
+}
+
+func (r *MemRepository) AllByLocationIds(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.allBy(ctx, func(event Event) bool {
+		return event.DeletedAt == nil && contains(locationIds, event.LocationID) &&
+			!event.StartTime.Before(start_time) && !event.StartTime.After(end_time)
+	}), nil
+}
+
+func (r *MemRepository) AllOngoing(ctx context.Context, locationIds []string, t time.Time) ([]Event, error) {
+	result := r.allBy(ctx, func(event Event) bool {
+		return event.DeletedAt == nil && event.Recurrence == nil && contains(locationIds, event.LocationID) &&
+			!event.StartTime.After(t) && event.EndTime.After(t)
+	})
+
+	occurrences, _ := r.AllOccurrences(ctx, "", t, t.Add(time.Nanosecond), "")
+	for _, occurrence := range occurrences {
+		if contains(locationIds, occurrence.LocationID) {
+			result = append(result, occurrence)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *MemRepository) AllOccurrences(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	recurring := r.allBy(ctx, func(event Event) bool {
+		return event.DeletedAt == nil && event.Recurrence != nil && event.Id != exceptId &&
+			(locationId == "" || event.LocationID == locationId) &&
+			event.StartTime.Before(end_time) && event.SeriesEndTime.After(start_time)
+	})
+
+	result := []Event{}
+	for _, event := range recurring {
+		result = append(result, event.Occurrences(start_time, end_time)...)
+	}
+
+	return result, nil
+}
+
+func (r *MemRepository) Overlapping(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	result := r.allBy(ctx, func(event Event) bool {
+		return event.DeletedAt == nil && event.Recurrence == nil && event.Id != exceptId &&
+			event.LocationID == locationId && event.StartTime.Before(end_time) && event.EndTime.After(start_time)
+	})
+
+	occurrences, _ := r.AllOccurrences(ctx, locationId, start_time, end_time, exceptId)
+
+	return append(result, occurrences...), nil
+}
+
+func (r *MemRepository) Find(ctx context.Context, id string) (Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return Event{}, err
+	}
+
+	return copyEvent(r.events[idx]), nil
+}
+
+// Create stores a new event, keeping its Id if the caller already picked one.
+func (r *MemRepository) Create(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkConflict(event); err != nil {
+		return err
+	}
+	r.insert(ctx, event)
+
+	return nil
+}
+
+// Insert stores a new event without checking it for conflicts.
+func (r *MemRepository) Insert(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insert(ctx, event)
+
+	return nil
+}
+
+func (r *MemRepository) insert(ctx context.Context, event *Event) {
+	if event.Id == "" {
+		event.Id = storage.NewObjectId()
+	}
+	event.OrgId = storage.OrgFor(ctx, event.OrgId)
+	r.events = append(r.events, copyEvent(*event))
+}
+
+func (r *MemRepository) Update(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkConflict(event); err != nil {
+		return err
+	}
+	for idx := range r.events {
+		if r.events[idx].Id == event.Id && storage.InOrg(ctx, r.events[idx].OrgId) {
+			event.OrgId = r.events[idx].OrgId
+			r.events[idx] = copyEvent(*event)
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// Patch is Update, the repo keeps whole documents.
+func (r *MemRepository) Patch(ctx context.Context, event *Event, existing Event) error {
+	return r.Update(ctx, event)
+}
+
+func (r *MemRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	r.events[idx].DeletedAt = &now
+
+	return nil
+}
+
+func (r *MemRepository) Cancel(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(event.Id.Hex(), r.index(ctx))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	r.events[idx].CancelledAt = event.CancelledAt
+	r.events[idx].CancelledBy = event.CancelledBy
+	r.events[idx].CancellationReason = event.CancellationReason
+	r.events[idx].DeletedAt = &now
+	event.DeletedAt = &now
+
+	return nil
+}
+
+func (r *MemRepository) AddException(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx := range r.events {
+		if r.events[idx].Id == id && r.events[idx].Recurrence != nil && storage.InOrg(ctx, r.events[idx].OrgId) {
+			r.events[idx].Recurrence.Exceptions = append(r.events[idx].Recurrence.Exceptions, t)
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// CheckConflict returns a *Conflict when the room of event is booked for
+// part of its time.
+func (r *MemRepository) CheckConflict(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.checkConflict(event)
+}
+
+// Replace writes event without checking it for conflicts.
+func (r *MemRepository) Replace(ctx context.Context, event *Event) error {
+	return r.update(ctx, func(stored Event) bool { return stored.Id == event.Id }, func(stored *Event) {
+		orgId := stored.OrgId
+		*stored = copyEvent(*event)
+		stored.OrgId = orgId
+	})
+}
+
+// update changes the stored events seen in ctx match accepts, deleted or
+// not, with change.
+func (r *MemRepository) update(ctx context.Context, match func(Event) bool, change func(*Event)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := false
+	for idx := range r.events {
+		if storage.InOrg(ctx, r.events[idx].OrgId) && match(r.events[idx]) {
+			change(&r.events[idx])
+			found = true
+		}
+	}
+	if !found {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// live matches the events match accepts that aren't deleted.
+func live(match func(Event) bool) func(Event) bool {
+	return func(event Event) bool {
+		return event.DeletedAt == nil && match(event)
+	}
+}
+
+func (r *MemRepository) findBy(ctx context.Context, match func(Event) bool) (Event, error) {
+	events := r.allBy(ctx, live(match))
+	if len(events) == 0 {
+		return Event{}, storage.ErrNotFound
+	}
+
+	return events[0], nil
+}
+
+func (r *MemRepository) FindDeleted(ctx context.Context, id string) (Event, error) {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return Event{}, err
+	}
+	events := r.allBy(ctx, func(event Event) bool { return event.Id == oid && event.DeletedAt != nil })
+	if len(events) == 0 {
+		return Event{}, storage.ErrNotFound
+	}
+
+	return events[0], nil
+}
+
+// Restore brings event, deleted or cancelled, back unless it conflicts with
+// an event booked since.
+func (r *MemRepository) Restore(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkConflict(event); err != nil {
+		return err
+	}
+	for idx := range r.events {
+		stored := &r.events[idx]
+		if stored.Id == event.Id && stored.DeletedAt != nil && storage.InOrg(ctx, stored.OrgId) {
+			stored.DeletedAt, stored.CancelledAt, stored.CancelledBy, stored.CancellationReason = nil, nil, "", ""
+			event.DeletedAt, event.CancelledAt, event.CancelledBy, event.CancellationReason = nil, nil, "", ""
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+func (r *MemRepository) AllRecurring(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	return r.allBy(ctx, func(event Event) bool {
+		return event.DeletedAt == nil && event.Recurrence != nil && event.Id != exceptId &&
+			(locationId == "" || event.LocationID == locationId) &&
+			event.StartTime.Before(end_time) && event.SeriesEndTime.After(start_time)
+	}), nil
+}
+
+// Repo Event in memory, feature queries
+func (r *MemRepository) FindByCheckInCode(ctx context.Context, locationId string, code string, t time.Time, early time.Duration) (Event, error) {
+	return r.findBy(ctx, byCheckInCode(locationId, code, t, early))
+}
+
+func (r *MemRepository) CheckInCodeTaken(ctx context.Context, locationId string, code string, start_time time.Time, end_time time.Time) (bool, error) {
+	return len(r.allBy(ctx, live(checkInCodeTaken(locationId, code, start_time, end_time)))) > 0, nil
+}
+
+func (r *MemRepository) CheckIn(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	return r.update(ctx, func(event Event) bool { return event.Id == id }, func(event *Event) { event.CheckedInAt = t })
+}
+
+func (r *MemRepository) AllByEquipment(ctx context.Context, equipmentId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	return r.allBy(ctx, live(byEquipment(equipmentId, start_time, end_time, exceptId))), nil
+}
+
+func (r *MemRepository) AllByGroupId(ctx context.Context, groupId string) ([]Event, error) {
+	result := r.allBy(ctx, live(func(event Event) bool { return event.GroupId == groupId }))
+	sortByStartTime(result)
+
+	return result, nil
+}
+
+func (r *MemRepository) Ungroup(ctx context.Context, groupId string) error {
+	err := r.update(ctx, func(event Event) bool { return event.GroupId == groupId }, func(event *Event) { event.GroupId = "" })
+	if err == storage.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func (r *MemRepository) AllByOwners(ctx context.Context, owners []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.allBy(ctx, live(byOwners(owners, start_time, end_time))), nil
+}
+
+func (r *MemRepository) AllByParticipants(ctx context.Context, participants []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result := r.allBy(ctx, live(byParticipants(participants, start_time, end_time)))
+	sortByStartTime(result)
+
+	return result, nil
+}
+
+func (r *MemRepository) AllBusy(ctx context.Context, participants []string, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result := r.allBy(ctx, live(func(event Event) bool {
+		return event.Recurrence == nil && overlaps(event, start_time, end_time) && busy(participants, locationIds)(event)
+	}))
+	occurrences, _ := r.AllOccurrences(ctx, "", start_time, end_time, "")
+
+	return withBusyOccurrences(result, occurrences, busy(participants, locationIds)), nil
+}
+
+func (r *MemRepository) FindByExternalId(ctx context.Context, source string, externalId string) (Event, error) {
+	return r.findBy(ctx, func(event Event) bool { return event.Source == source && event.ExternalId == externalId })
+}
+
+func (r *MemRepository) LocationMismatches(ctx context.Context, roomId string, name string) ([]Event, error) {
+	result := r.allBy(ctx, live(locationMismatch(roomId, name)))
+	sortByStartTime(result)
+
+	return result, nil
+}
+
+func (r *MemRepository) StaleLocations(ctx context.Context, roomId string, name string, t time.Time) ([]storage.ObjectId, error) {
+	return idsOf(r.allBy(ctx, live(func(event Event) bool { return locationMismatch(roomId, name)(event) && event.Upcoming(t) }))), nil
+}
+
+func (r *MemRepository) SetLocation(ctx context.Context, ids []storage.ObjectId, name string) error {
+	err := r.update(ctx, func(event Event) bool { return containsId(ids, event.Id) }, func(event *Event) { event.Location = name })
+	if err == storage.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func (r *MemRepository) AllByRecurringEvent(ctx context.Context, id storage.ObjectId) ([]Event, error) {
+	return r.allBy(ctx, live(func(event Event) bool { return event.RecurringEventId == id })), nil
+}
+
+func (r *MemRepository) EndedWithoutCheckIn(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.allBy(ctx, live(endedWithoutCheckIn(start_time, end_time))), nil
+}
+
+func (r *MemRepository) AllPendingApproval(ctx context.Context) ([]Event, error) {
+	result := r.allBy(ctx, live(func(event Event) bool { return event.PendingApproval }))
+	sortByStartTime(result)
+
+	return result, nil
+}
+
+func (r *MemRepository) Search(ctx context.Context, search Search) ([]Event, error) {
+	return search.expand(r.allBy(ctx, live(search.matches))), nil
+}
+
+// AllStandby returns the events on standby in the order they were created,
+// as MongoDB's ObjectIds sort.
+func (r *MemRepository) AllStandby(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result := r.allBy(ctx, live(standby(start_time, end_time)))
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+
+	return result, nil
+}
+
+func (r *MemRepository) AllMine(ctx context.Context, email string, t time.Time) ([]Event, error) {
+	result := r.allBy(ctx, live(func(event Event) bool { return mine(email)(event) && notEnded(t)(event) }))
+	sortByStartTime(result)
+
+	return result, nil
+}
+
+func (r *MemRepository) AllMineByIds(ctx context.Context, email string, ids []string) ([]Event, error) {
+	return r.allBy(ctx, live(func(event Event) bool { return mine(email)(event) && contains(ids, event.Id.Hex()) })), nil
+}
+
+func (r *MemRepository) AllUsage(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	return r.allBy(ctx, usage(locationIds, start_time, end_time)), nil
+}
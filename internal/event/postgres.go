@@ -0,0 +1,619 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/postgres"
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Event in PostgreSQL
+//
+// PostgresRepository behaves like MongoRepository over the events table, see
+// internal/postgres. Time windows are looked up through the indexes of the
+// table: events by start_time, bookings of a room by location_id and their
+// window, and recurring events by location_id and series_end_time. The
+// feature queries on other fields narrow the rows down by these columns as
+// far as they can, and match the rest of the document in Go.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db}
+}
+
+// query returns the events of the rows of query.
+func (r *PostgresRepository) query(ctx context.Context, query string, args ...interface{}) ([]Event, error) {
+	return scan(r.db.QueryContext(ctx, query, args...))
+}
+
+// scan returns the events of rows, the doc and deleted_at of events.
+func scan(rows *sql.Rows, err error) ([]Event, error) {
+	result := []Event{}
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc []byte
+		var deletedAt *time.Time
+		if err := rows.Scan(&doc, &deletedAt); err != nil {
+			return result, err
+		}
+		event := Event{}
+		if err := postgres.Unmarshal(doc, &event); err != nil {
+			return result, err
+		}
+		event.DeletedAt = deletedAt
+		result = append(result, event)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgresRepository) All(ctx context.Context, start_time time.Time, end_time time.Time, includeDeleted bool) ([]Event, error) {
+	params := postgres.Params{Args: []interface{}{start_time, end_time}}
+	query := `SELECT doc, deleted_at FROM events WHERE start_time >= $1 AND start_time <= $2 AND ` + params.Org(ctx)
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+
+	return r.query(ctx, query+` ORDER BY id`, params.Args...)
+}
+
+func (r *PostgresRepository) AllByLocationIds(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE location_id IN (` + params.In(locationIds) + `)` +
+		` AND start_time >= ` + params.Add(start_time) + ` AND start_time <= ` + params.Add(end_time) +
+		` AND deleted_at IS NULL AND ` + params.Org(ctx) + ` ORDER BY id`
+
+	return r.query(ctx, query, params.Args...)
+}
+
+// AllOngoing returns the events and occurrences at locationIds taking place
+// at t.
+func (r *PostgresRepository) AllOngoing(ctx context.Context, locationIds []string, t time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE location_id IN (` + params.In(locationIds) + `)` +
+		` AND NOT recurring AND start_time <= ` + params.Add(t) + ` AND end_time > ` + params.Add(t) +
+		` AND deleted_at IS NULL AND ` + params.Org(ctx) + ` ORDER BY id`
+	result, err := r.query(ctx, query, params.Args...)
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, "", t, t.Add(time.Nanosecond), "")
+	if err != nil {
+		return result, err
+	}
+	for _, occurrence := range occurrences {
+		if contains(locationIds, occurrence.LocationID) {
+			result = append(result, occurrence)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *PostgresRepository) AllRecurring(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE recurring` +
+		` AND start_time < ` + params.Add(end_time) + ` AND series_end_time > ` + params.Add(start_time) +
+		` AND id <> ` + params.Add(exceptId.Hex()) + ` AND deleted_at IS NULL AND ` + params.Org(ctx)
+	if locationId != "" {
+		query += ` AND location_id = ` + params.Add(locationId)
+	}
+
+	return r.query(ctx, query+` ORDER BY id`, params.Args...)
+}
+
+// AllOccurrences expands the recurring events at locationId, or anywhere when
+// it's empty, within [start_time, end_time).
+func (r *PostgresRepository) AllOccurrences(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	recurring, err := r.AllRecurring(ctx, locationId, start_time, end_time, exceptId)
+	if err != nil {
+		return []Event{}, err
+	}
+
+	result := []Event{}
+	for _, event := range recurring {
+		result = append(result, event.Occurrences(start_time, end_time)...)
+	}
+
+	return result, nil
+}
+
+// Overlapping returns the events and occurrences of recurring events at
+// locationId whose time window intersects [start_time, end_time), ignoring
+// exceptId.
+func (r *PostgresRepository) Overlapping(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	params := postgres.Params{Args: []interface{}{locationId, end_time, start_time, exceptId.Hex()}}
+	result, err := r.query(ctx, `SELECT doc, deleted_at FROM events WHERE location_id = $1 AND NOT recurring`+
+		` AND start_time < $2 AND end_time > $3 AND id <> $4 AND deleted_at IS NULL AND `+params.Org(ctx)+` ORDER BY id`,
+		params.Args...)
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, locationId, start_time, end_time, exceptId)
+	if err != nil {
+		return result, err
+	}
+
+	return append(result, occurrences...), nil
+}
+
+func (r *PostgresRepository) Find(ctx context.Context, id string) (Event, error) {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return Event{}, err
+	}
+	params := postgres.Params{}
+	events, err := r.query(ctx, `SELECT doc, deleted_at FROM events WHERE id = `+params.Add(oid.Hex())+` AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(events) == 0 {
+		return Event{}, storage.ErrNotFound
+	}
+
+	return events[0], nil
+}
+
+// CheckConflict returns a *Conflict when the room of event is booked for
+// part of its time.
+func (r *PostgresRepository) CheckConflict(ctx context.Context, event *Event) error {
+	if event.LocationID == "" {
+		return nil
+	}
+
+	end_time := seriesEnd(event)
+	others, err := r.Overlapping(ctx, event.LocationID, event.StartTime, end_time, event.Id)
+	if err != nil {
+		return err
+	}
+
+	return conflict(event, end_time, others)
+}
+
+// Create stores a new event, keeping its Id if the caller already picked one.
+func (r *PostgresRepository) Create(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	return r.Insert(ctx, event)
+}
+
+// Insert stores a new event without checking it for conflicts.
+func (r *PostgresRepository) Insert(ctx context.Context, event *Event) error {
+	created := *event
+	if created.Id == "" {
+		created.Id = storage.NewObjectId()
+	}
+	created.OrgId = storage.OrgFor(ctx, event.OrgId)
+	doc, err := postgres.Marshal(created)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO events (id, location_id, start_time, end_time, recurring, series_end_time, deleted_at, doc, org_id)`+
+		` VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		created.Id.Hex(), created.LocationID, created.StartTime, created.EndTime, created.Recurrence != nil, seriesEndColumn(&created), created.DeletedAt, doc, created.OrgId)
+	if err != nil {
+		return postgres.Error(err)
+	}
+
+	event.Id = created.Id
+	event.OrgId = created.OrgId
+
+	return nil
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	return r.replace(ctx, r.db, event)
+}
+
+// execer is the database, or a transaction of it.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *PostgresRepository) replace(ctx context.Context, db execer, event *Event) error {
+	event.OrgId = storage.OrgFor(ctx, event.OrgId)
+	doc, err := postgres.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{
+		event.Id.Hex(), event.LocationID, event.StartTime, event.EndTime, event.Recurrence != nil, seriesEndColumn(event), event.DeletedAt, doc,
+	}}
+	return postgres.Affected(db.ExecContext(ctx, `UPDATE events SET location_id = $2, start_time = $3, end_time = $4,`+
+		` recurring = $5, series_end_time = $6, deleted_at = $7, doc = $8 WHERE id = $1 AND `+params.Org(ctx),
+		params.Args...))
+}
+
+// Patch is Update, the repo writes the whole document of a row.
+func (r *PostgresRepository) Patch(ctx context.Context, event *Event, existing Event) error {
+	return r.Update(ctx, event)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{oid.Hex(), time.Now()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE events SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...))
+}
+
+// Cancel writes event back deleted, without checking it for conflicts.
+func (r *PostgresRepository) Cancel(ctx context.Context, event *Event) error {
+	now := time.Now()
+	cancelled := *event
+	cancelled.DeletedAt = &now
+	if err := r.replace(ctx, r.db, &cancelled); err != nil {
+		return err
+	}
+	event.DeletedAt = &now
+
+	return nil
+}
+
+// AddException reads and writes the series back in a transaction, the
+// exceptions being part of the document.
+func (r *PostgresRepository) AddException(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	params := postgres.Params{}
+	where := `id = ` + params.Add(id.Hex()) + ` AND recurring AND ` + params.Org(ctx)
+	n, err := r.update(ctx, where, params.Args, func(Event) bool { return true }, func(event *Event) {
+		event.Recurrence.Exceptions = append(event.Recurrence.Exceptions, t)
+	})
+	if err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return err
+}
+
+// update changes the events of the rows where selects that match accepts,
+// deleted or not, with change. The fields being part of the document, they
+// are read and written back in a transaction, locking only the rows that
+// match. It returns how many it changed.
+func (r *PostgresRepository) update(ctx context.Context, where string, args []interface{}, match func(Event) bool, change func(*Event)) (int, error) {
+	found, err := r.query(ctx, `SELECT doc, deleted_at FROM events WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	ids := []string{}
+	for _, event := range found {
+		if match(event) {
+			ids = append(ids, event.Id.Hex())
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE id IN (` + params.In(ids) + `) AND ` + params.Org(ctx) + ` ORDER BY id FOR UPDATE`
+	events, err := scan(tx.QueryContext(ctx, query, params.Args...))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, event := range events {
+		if !match(event) {
+			continue
+		}
+		change(&event)
+		if err := r.replace(ctx, tx, &event); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, tx.Commit()
+}
+
+// seriesEndColumn is the series_end_time of event, NULL unless it recurs.
+func seriesEndColumn(event *Event) *time.Time {
+	if event.Recurrence == nil {
+		return nil
+	}
+
+	return &event.SeriesEndTime
+}
+
+// Replace writes event without checking it for conflicts.
+func (r *PostgresRepository) Replace(ctx context.Context, event *Event) error {
+	return r.replace(ctx, r.db, event)
+}
+
+// matching returns the events of the rows not deleted where selects that
+// match accepts, for the lookups on fields without a column.
+func (r *PostgresRepository) matching(ctx context.Context, params *postgres.Params, where string, match func(Event) bool) ([]Event, error) {
+	query := `SELECT doc, deleted_at FROM events WHERE ` + where + ` AND deleted_at IS NULL AND ` + params.Org(ctx) + ` ORDER BY id`
+	events, err := r.query(ctx, query, params.Args...)
+	if err != nil {
+		return []Event{}, err
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if match(event) {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *PostgresRepository) findBy(ctx context.Context, params *postgres.Params, where string, match func(Event) bool) (Event, error) {
+	events, err := r.matching(ctx, params, where, match)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(events) == 0 {
+		return Event{}, storage.ErrNotFound
+	}
+
+	return events[0], nil
+}
+
+// overlapping returns the condition on the columns of an event overlapping
+// [start_time, end_time).
+func overlapping(params *postgres.Params, start_time time.Time, end_time time.Time) string {
+	return `start_time < ` + params.Add(end_time) + ` AND end_time > ` + params.Add(start_time)
+}
+
+func (r *PostgresRepository) FindDeleted(ctx context.Context, id string) (Event, error) {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return Event{}, err
+	}
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE id = ` + params.Add(oid.Hex()) + ` AND deleted_at IS NOT NULL AND ` + params.Org(ctx)
+	events, err := r.query(ctx, query, params.Args...)
+	if err != nil {
+		return Event{}, err
+	}
+	if len(events) == 0 {
+		return Event{}, storage.ErrNotFound
+	}
+
+	return events[0], nil
+}
+
+// Restore brings event, deleted or cancelled, back unless it conflicts with
+// an event booked since.
+func (r *PostgresRepository) Restore(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{}
+	where := `id = ` + params.Add(event.Id.Hex()) + ` AND deleted_at IS NOT NULL AND ` + params.Org(ctx)
+	n, err := r.update(ctx, where, params.Args, func(Event) bool { return true }, func(stored *Event) {
+		stored.DeletedAt, stored.CancelledAt, stored.CancelledBy, stored.CancellationReason = nil, nil, "", ""
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	event.DeletedAt, event.CancelledAt, event.CancelledBy, event.CancellationReason = nil, nil, "", ""
+
+	return nil
+}
+
+// Repo Event in PostgreSQL, feature queries
+func (r *PostgresRepository) FindByCheckInCode(ctx context.Context, locationId string, code string, t time.Time, early time.Duration) (Event, error) {
+	params := postgres.Params{}
+	where := `location_id = ` + params.Add(locationId) + ` AND start_time <= ` + params.Add(t.Add(early)) + ` AND end_time > ` + params.Add(t)
+
+	return r.findBy(ctx, &params, where, byCheckInCode(locationId, code, t, early))
+}
+
+func (r *PostgresRepository) CheckInCodeTaken(ctx context.Context, locationId string, code string, start_time time.Time, end_time time.Time) (bool, error) {
+	params := postgres.Params{}
+	where := `location_id = ` + params.Add(locationId) + ` AND ` + overlapping(&params, start_time, end_time)
+	events, err := r.matching(ctx, &params, where, checkInCodeTaken(locationId, code, start_time, end_time))
+
+	return len(events) > 0, err
+}
+
+func (r *PostgresRepository) CheckIn(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	params := postgres.Params{}
+	where := `id = ` + params.Add(id.Hex()) + ` AND ` + params.Org(ctx)
+	n, err := r.update(ctx, where, params.Args, func(Event) bool { return true }, func(event *Event) { event.CheckedInAt = t })
+	if err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return err
+}
+
+func (r *PostgresRepository) AllByEquipment(ctx context.Context, equipmentId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	params := postgres.Params{}
+
+	return r.matching(ctx, &params, overlapping(&params, start_time, end_time), byEquipment(equipmentId, start_time, end_time, exceptId))
+}
+
+func (r *PostgresRepository) AllByGroupId(ctx context.Context, groupId string) ([]Event, error) {
+	params := postgres.Params{}
+	result, err := r.matching(ctx, &params, `TRUE`, func(event Event) bool { return event.GroupId == groupId })
+	sortByStartTime(result)
+
+	return result, err
+}
+
+func (r *PostgresRepository) Ungroup(ctx context.Context, groupId string) error {
+	params := postgres.Params{}
+	_, err := r.update(ctx, params.Org(ctx), params.Args, func(event Event) bool { return event.GroupId == groupId }, func(event *Event) { event.GroupId = "" })
+
+	return err
+}
+
+func (r *PostgresRepository) AllByOwners(ctx context.Context, owners []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+
+	return r.matching(ctx, &params, overlapping(&params, start_time, end_time), byOwners(owners, start_time, end_time))
+}
+
+func (r *PostgresRepository) AllByParticipants(ctx context.Context, participants []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	result, err := r.matching(ctx, &params, overlapping(&params, start_time, end_time), byParticipants(participants, start_time, end_time))
+	sortByStartTime(result)
+
+	return result, err
+}
+
+func (r *PostgresRepository) AllBusy(ctx context.Context, participants []string, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	result, err := r.matching(ctx, &params, `NOT recurring AND `+overlapping(&params, start_time, end_time), busy(participants, locationIds))
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, "", start_time, end_time, "")
+	if err != nil {
+		return result, err
+	}
+
+	return withBusyOccurrences(result, occurrences, busy(participants, locationIds)), nil
+}
+
+func (r *PostgresRepository) FindByExternalId(ctx context.Context, source string, externalId string) (Event, error) {
+	params := postgres.Params{}
+
+	return r.findBy(ctx, &params, `TRUE`, func(event Event) bool { return event.Source == source && event.ExternalId == externalId })
+}
+
+func (r *PostgresRepository) LocationMismatches(ctx context.Context, roomId string, name string) ([]Event, error) {
+	params := postgres.Params{}
+	result, err := r.matching(ctx, &params, `location_id = `+params.Add(roomId), locationMismatch(roomId, name))
+	sortByStartTime(result)
+
+	return result, err
+}
+
+func (r *PostgresRepository) StaleLocations(ctx context.Context, roomId string, name string, t time.Time) ([]storage.ObjectId, error) {
+	params := postgres.Params{}
+	events, err := r.matching(ctx, &params, `location_id = `+params.Add(roomId), func(event Event) bool {
+		return locationMismatch(roomId, name)(event) && event.Upcoming(t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return idsOf(events), nil
+}
+
+func (r *PostgresRepository) SetLocation(ctx context.Context, ids []storage.ObjectId, name string) error {
+	hexes := []string{}
+	for _, id := range ids {
+		hexes = append(hexes, id.Hex())
+	}
+	params := postgres.Params{}
+	where := `id IN (` + params.In(hexes) + `) AND ` + params.Org(ctx)
+	_, err := r.update(ctx, where, params.Args, func(Event) bool { return true }, func(event *Event) { event.Location = name })
+
+	return err
+}
+
+func (r *PostgresRepository) AllByRecurringEvent(ctx context.Context, id storage.ObjectId) ([]Event, error) {
+	params := postgres.Params{}
+
+	return r.matching(ctx, &params, `TRUE`, func(event Event) bool { return event.RecurringEventId == id })
+}
+
+func (r *PostgresRepository) EndedWithoutCheckIn(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	where := `end_time >= ` + params.Add(start_time) + ` AND end_time < ` + params.Add(end_time)
+
+	return r.matching(ctx, &params, where, endedWithoutCheckIn(start_time, end_time))
+}
+
+func (r *PostgresRepository) AllPendingApproval(ctx context.Context) ([]Event, error) {
+	params := postgres.Params{}
+	result, err := r.matching(ctx, &params, `TRUE`, func(event Event) bool { return event.PendingApproval })
+	sortByStartTime(result)
+
+	return result, err
+}
+
+func (r *PostgresRepository) Search(ctx context.Context, search Search) ([]Event, error) {
+	params := postgres.Params{}
+	where := `start_time < ` + params.Add(search.EndTime)
+	if len(search.RoomIds) > 0 {
+		where += ` AND location_id IN (` + params.In(search.RoomIds) + `)`
+	}
+	found, err := r.matching(ctx, &params, where, search.matches)
+	if err != nil {
+		return []Event{}, err
+	}
+
+	return search.expand(found), nil
+}
+
+// AllStandby returns the events on standby in the order they were created,
+// as MongoDB's ObjectIds sort.
+func (r *PostgresRepository) AllStandby(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+
+	return r.matching(ctx, &params, `NOT recurring AND `+overlapping(&params, start_time, end_time), standby(start_time, end_time))
+}
+
+func (r *PostgresRepository) AllMine(ctx context.Context, email string, t time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	at := params.Add(t)
+	where := `(NOT recurring AND end_time >= ` + at + ` OR recurring AND series_end_time >= ` + at + `)`
+	result, err := r.matching(ctx, &params, where, mine(email))
+	sortByStartTime(result)
+
+	return result, err
+}
+
+func (r *PostgresRepository) AllMineByIds(ctx context.Context, email string, ids []string) ([]Event, error) {
+	params := postgres.Params{}
+
+	return r.matching(ctx, &params, `id IN (`+params.In(ids)+`)`, mine(email))
+}
+
+func (r *PostgresRepository) AllUsage(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM events WHERE location_id IN (` + params.In(locationIds) + `) AND NOT recurring` +
+		` AND start_time >= ` + params.Add(start_time) + ` AND start_time < ` + params.Add(end_time) + ` AND ` + params.Org(ctx) + ` ORDER BY id`
+	events, err := r.query(ctx, query, params.Args...)
+	if err != nil {
+		return []Event{}, err
+	}
+
+	result := []Event{}
+	for _, event := range events {
+		if usage(locationIds, start_time, end_time)(event) {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package event
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Recurring events
+//
+// An event with a Recurrence is booked once and stands for all of its
+// occurrences: the first one plus an RRULE-style Recurrence. Occurrences are
+// expanded when events are listed or checked for conflicts and are never
+// stored; Exceptions lists the start times of occurrences that were removed.
+// A changed occurrence is stored as an event of its own with
+// RecurringEventId and OriginalStartTime set.
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+
+	// maxOccurrences bounds the expansion of series without an end.
+	maxOccurrences = 1000
+)
+
+type Recurrence struct {
+	Frequency  string         `json:"frequency"`
+	Interval   int            `json:"interval"`
+	Weekdays   []time.Weekday `json:"weekdays,omitempty"`
+	Until      time.Time      `json:"until,omitempty"`
+	Count      int            `json:"count,omitempty"`
+	Exceptions []time.Time    `json:"exceptions,omitempty"`
+
+	// WeekStart is the day weekly recurrences count weeks from, monday
+	// when empty, see app/web/locale.go.
+	WeekStart string `json:"week_start,omitempty" bson:",omitempty"`
+}
+
+// WeekStartDay is the day named by WeekStart.
+func (r Recurrence) WeekStartDay() time.Weekday {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(strings.TrimSpace(r.WeekStart), day.String()) {
+			return day
+		}
+	}
+
+	return time.Monday
+}
+
+// Starts returns the start of every occurrence up to the end of the series
+// or maxOccurrences, whichever comes first, including exceptions.
+func (r Recurrence) Starts(first time.Time) []time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	result := []time.Time{}
+	add := func(t time.Time) bool {
+		if t.Before(first) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if r.Count > 0 && len(result) >= r.Count {
+			return false
+		}
+		result = append(result, t)
+		return len(result) < maxOccurrences
+	}
+
+	// offset is how many days into a week starting on WeekStartDay day is.
+	weekStart := r.WeekStartDay()
+	offset := func(day time.Weekday) int {
+		return (int(day) - int(weekStart) + 7) % 7
+	}
+
+	for i := 0; ; i++ {
+		switch r.Frequency {
+		case FrequencyDaily:
+			if !add(first.AddDate(0, 0, i*interval)) {
+				return result
+			}
+		case FrequencyWeekly:
+			week := first.AddDate(0, 0, 7*i*interval)
+			if len(r.Weekdays) == 0 {
+				if !add(week) {
+					return result
+				}
+				continue
+			}
+			start := week.AddDate(0, 0, -offset(week.Weekday()))
+			days := append([]time.Weekday{}, r.Weekdays...)
+			sort.Slice(days, func(a, b int) bool { return offset(days[a]) < offset(days[b]) })
+			for _, day := range days {
+				if !add(start.AddDate(0, 0, offset(day))) {
+					return result
+				}
+			}
+		case FrequencyMonthly:
+			// Months without the day of the first occurrence are skipped,
+			// like RRULE does, rather than spilling into the next month.
+			t := first.AddDate(0, i*interval, 0)
+			if t.Day() != first.Day() {
+				if i > maxOccurrences {
+					return result
+				}
+				continue
+			}
+			if !add(t) {
+				return result
+			}
+		default:
+			add(first)
+			return result
+		}
+	}
+}
+
+// Within returns the starts of the occurrences of duration that intersect
+// [start_time, end_time), leaving out exceptions.
+func (r Recurrence) Within(first time.Time, duration time.Duration, start_time time.Time, end_time time.Time) []time.Time {
+	skipped := map[int64]bool{}
+	for _, t := range r.Exceptions {
+		skipped[t.Unix()] = true
+	}
+
+	result := []time.Time{}
+	for _, start := range r.Starts(first) {
+		if !start.Before(end_time) {
+			break
+		}
+		if !start.Add(duration).After(start_time) || skipped[start.Unix()] {
+			continue
+		}
+		result = append(result, start)
+	}
+
+	return result
+}
+
+// SetRecurrence makes e recur, or stop recurring when r is nil. StartTime
+// and EndTime must be set first.
+func (e *Event) SetRecurrence(r *Recurrence) {
+	e.Recurrence = r
+	e.SeriesEndTime = time.Time{}
+	if r == nil {
+		return
+	}
+
+	e.SeriesEndTime = e.EndTime
+	if starts := r.Starts(e.StartTime); len(starts) > 0 {
+		e.SeriesEndTime = starts[len(starts)-1].Add(e.EndTime.Sub(e.StartTime))
+	}
+}
+
+// Occurrences expands e within [start_time, end_time). An event that doesn't
+// recur is its only occurrence.
+func (e Event) Occurrences(start_time time.Time, end_time time.Time) []Event {
+	if e.Recurrence == nil {
+		if e.StartTime.Before(end_time) && e.EndTime.After(start_time) {
+			return []Event{e}
+		}
+		return []Event{}
+	}
+
+	duration := e.EndTime.Sub(e.StartTime)
+	result := []Event{}
+	for _, start := range e.Recurrence.Within(e.StartTime, duration, start_time, end_time) {
+		occurrence := e
+		occurrence.Recurrence = nil
+		occurrence.SeriesEndTime = time.Time{}
+		occurrence.StartTime = start
+		occurrence.EndTime = start.Add(duration)
+		occurrence.RecurringEventId = e.Id
+		occurrence.OriginalStartTime = start
+		result = append(result, occurrence)
+	}
+
+	return result
+}
+
+// IsOccurrence reports whether e was expanded from a recurring event rather
+// than stored.
+func (e Event) IsOccurrence() bool {
+	return e.RecurringEventId != "" && e.Id == e.RecurringEventId
+}
+
+// Replaces reports whether e is a changed occurrence taking the place of
+// other.
+func (e Event) Replaces(other Event) bool {
+	return other.IsOccurrence() &&
+		other.RecurringEventId == e.RecurringEventId &&
+		other.StartTime.Equal(e.OriginalStartTime)
+}
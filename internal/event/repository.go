@@ -0,0 +1,373 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Repository holds the event listings and the writes of the handlers, with
+// the lookups made when checking a booking for conflicts and the queries of
+// the features of app/web, see features.go. Queries are canceled with ctx,
+// the context of the request.
+type Repository interface {
+	All(ctx context.Context, start_time time.Time, end_time time.Time, includeDeleted bool) ([]Event, error)
+	AllByLocationIds(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error)
+	AllOngoing(ctx context.Context, locationIds []string, t time.Time) ([]Event, error)
+	AllRecurring(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error)
+	AllOccurrences(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error)
+	Overlapping(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error)
+	CheckConflict(ctx context.Context, event *Event) error
+	Find(ctx context.Context, id string) (Event, error)
+	FindDeleted(ctx context.Context, id string) (Event, error)
+	Create(ctx context.Context, event *Event) error
+	Update(ctx context.Context, event *Event) error
+	Patch(ctx context.Context, event *Event, existing Event) error
+	Insert(ctx context.Context, event *Event) error
+	Replace(ctx context.Context, event *Event) error
+	Delete(ctx context.Context, id string) error
+	Cancel(ctx context.Context, event *Event) error
+	Restore(ctx context.Context, event *Event) error
+	AddException(ctx context.Context, id storage.ObjectId, t time.Time) error
+
+	FindByCheckInCode(ctx context.Context, locationId string, code string, t time.Time, early time.Duration) (Event, error)
+	CheckInCodeTaken(ctx context.Context, locationId string, code string, start_time time.Time, end_time time.Time) (bool, error)
+	CheckIn(ctx context.Context, id storage.ObjectId, t time.Time) error
+	AllByEquipment(ctx context.Context, equipmentId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error)
+	AllByGroupId(ctx context.Context, groupId string) ([]Event, error)
+	Ungroup(ctx context.Context, groupId string) error
+	AllByOwners(ctx context.Context, owners []string, start_time time.Time, end_time time.Time) ([]Event, error)
+	AllByParticipants(ctx context.Context, participants []string, start_time time.Time, end_time time.Time) ([]Event, error)
+	AllBusy(ctx context.Context, participants []string, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error)
+	FindByExternalId(ctx context.Context, source string, externalId string) (Event, error)
+	LocationMismatches(ctx context.Context, roomId string, name string) ([]Event, error)
+	StaleLocations(ctx context.Context, roomId string, name string, t time.Time) ([]storage.ObjectId, error)
+	SetLocation(ctx context.Context, ids []storage.ObjectId, name string) error
+	AllByRecurringEvent(ctx context.Context, id storage.ObjectId) ([]Event, error)
+	EndedWithoutCheckIn(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error)
+	AllPendingApproval(ctx context.Context) ([]Event, error)
+	Search(ctx context.Context, search Search) ([]Event, error)
+	AllStandby(ctx context.Context, start_time time.Time, end_time time.Time) ([]Event, error)
+	AllMine(ctx context.Context, email string, t time.Time) ([]Event, error)
+	AllMineByIds(ctx context.Context, email string, ids []string) ([]Event, error)
+	AllUsage(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error)
+}
+
+// Repo Event
+type MongoRepository struct {
+	coll   *storage.Collection
+	policy storage.Policy
+}
+
+func NewMongoRepository(coll *storage.Collection, policy storage.Policy) *MongoRepository {
+	return &MongoRepository{coll, policy}
+}
+
+// All returns the events starting within [start_time, end_time], recurring
+// ones by their first occurrence.
+func (r *MongoRepository) All(ctx context.Context, start_time time.Time, end_time time.Time, includeDeleted bool) ([]Event, error) {
+	result := []Event{}
+	query := bson.M{"starttime": bson.M{"$gte": start_time, "$lte": end_time}}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.DeletedFilter(query, includeDeleted)).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) Find(ctx context.Context, id string) (Event, error) {
+	result := Event{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.policy.Read(func() error { return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"_id": oid})).One(&result) })
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+// CheckConflict returns a *Conflict when the room of event is booked for
+// part of its time.
+func (r *MongoRepository) CheckConflict(ctx context.Context, event *Event) error {
+	if event.LocationID == "" {
+		return nil
+	}
+
+	end_time := seriesEnd(event)
+	others, err := r.Overlapping(ctx, event.LocationID, event.StartTime, end_time, event.Id)
+	if err != nil {
+		return err
+	}
+
+	return conflict(event, end_time, others)
+}
+
+// Create stores a new event, keeping its Id if the caller already picked one.
+func (r *MongoRepository) Create(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	return r.Insert(ctx, event)
+}
+
+func (r *MongoRepository) Update(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	return r.Replace(ctx, event)
+}
+
+// Patch is Update writing only the fields event changes from existing, the
+// event as it was read, see storage.Changes.
+func (r *MongoRepository) Patch(ctx context.Context, event *Event, existing Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+	update, err := storage.Changes(existing, event)
+	if err != nil || update == nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).UpdateId(event.Id, update)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Insert and Replace write without checking for conflicts.
+func (r *MongoRepository) Insert(ctx context.Context, event *Event) error {
+	id := event.Id
+	if id == "" {
+		id = storage.NewObjectId()
+	}
+	_, err := r.coll.WithContext(ctx).UpsertId(id, event)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	event.Id = id
+
+	return nil
+}
+
+func (r *MongoRepository) Replace(ctx context.Context, event *Event) error {
+	err := r.coll.WithContext(ctx).UpdateId(event.Id, event)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(storage.NotDeleted(bson.M{"_id": oid}), bson.M{"$set": bson.M{"deletedat": time.Now()}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Cancel deletes event, recording its CancelledAt, CancelledBy and
+// CancellationReason. Its time is freed, not booked, so it isn't checked
+// for conflicts.
+func (r *MongoRepository) Cancel(ctx context.Context, event *Event) error {
+	now := time.Now()
+	err := r.coll.WithContext(ctx).Update(storage.NotDeleted(bson.M{"_id": event.Id}), bson.M{"$set": bson.M{
+		"cancelledat":        event.CancelledAt,
+		"cancelledby":        event.CancelledBy,
+		"cancellationreason": event.CancellationReason,
+		"deletedat":          now,
+	}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+	event.DeletedAt = &now
+
+	return nil
+}
+
+// FindDeleted finds an event that was deleted.
+func (r *MongoRepository) FindDeleted(ctx context.Context, id string) (Event, error) {
+	result := Event{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.coll.WithContext(ctx).Find(bson.M{"_id": oid, "deletedat": bson.M{"$ne": nil}}).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+// Restore brings event, deleted or cancelled, back unless it conflicts with
+// an event booked since.
+func (r *MongoRepository) Restore(ctx context.Context, event *Event) error {
+	err := r.CheckConflict(ctx, event)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(bson.M{"_id": event.Id, "deletedat": bson.M{"$ne": nil}}, bson.M{"$unset": bson.M{
+		"deletedat":          "",
+		"cancelledat":        "",
+		"cancelledby":        "",
+		"cancellationreason": "",
+	}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+	event.DeletedAt = nil
+	event.CancelledAt = nil
+	event.CancelledBy = ""
+	event.CancellationReason = ""
+
+	return nil
+}
+
+func (r *MongoRepository) AllByLocationIds(ctx context.Context, locationIds []string, start_time time.Time, end_time time.Time) ([]Event, error) {
+	result := []Event{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{
+			"locationid": bson.M{"$in": locationIds},
+			"starttime":  bson.M{"$gte": start_time, "$lte": end_time},
+		})).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Overlapping returns the events and occurrences of recurring events at
+// locationId whose time window intersects [start_time, end_time), ignoring
+// exceptId.
+func (r *MongoRepository) Overlapping(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	result := []Event{}
+	query := bson.M{
+		"locationid": locationId,
+		"recurrence": nil,
+		"starttime":  bson.M{"$lt": end_time},
+		"endtime":    bson.M{"$gt": start_time},
+	}
+	if exceptId != "" {
+		query["_id"] = bson.M{"$ne": exceptId}
+	}
+
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(query)).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, locationId, start_time, end_time, exceptId)
+	if err != nil {
+		return result, err
+	}
+
+	return append(result, occurrences...), nil
+}
+
+// AllOngoing returns the events and occurrences at locationIds taking place
+// at t.
+func (r *MongoRepository) AllOngoing(ctx context.Context, locationIds []string, t time.Time) ([]Event, error) {
+	result := []Event{}
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{
+		"locationid": bson.M{"$in": locationIds},
+		"recurrence": nil,
+		"starttime":  bson.M{"$lte": t},
+		"endtime":    bson.M{"$gt": t},
+	})).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	occurrences, err := r.AllOccurrences(ctx, "", t, t.Add(time.Nanosecond), "")
+	if err != nil {
+		return result, err
+	}
+	for _, occurrence := range occurrences {
+		if contains(locationIds, occurrence.LocationID) {
+			result = append(result, occurrence)
+		}
+	}
+
+	return result, nil
+}
+
+// Repo Event recurrence
+func (r *MongoRepository) AllRecurring(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	result := []Event{}
+	query := bson.M{
+		"recurrence":    bson.M{"$ne": nil},
+		"starttime":     bson.M{"$lt": end_time},
+		"seriesendtime": bson.M{"$gt": start_time},
+	}
+	if locationId != "" {
+		query["locationid"] = locationId
+	}
+	if exceptId != "" {
+		query["_id"] = bson.M{"$ne": exceptId}
+	}
+
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(query)).All(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// AllOccurrences expands the recurring events at locationId, or anywhere when
+// it's empty, within [start_time, end_time).
+func (r *MongoRepository) AllOccurrences(ctx context.Context, locationId string, start_time time.Time, end_time time.Time, exceptId storage.ObjectId) ([]Event, error) {
+	result := []Event{}
+	recurring, err := r.AllRecurring(ctx, locationId, start_time, end_time, exceptId)
+	if err != nil {
+		return result, err
+	}
+
+	for _, event := range recurring {
+		result = append(result, event.Occurrences(start_time, end_time)...)
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) AddException(ctx context.Context, id storage.ObjectId, t time.Time) error {
+	err := r.coll.WithContext(ctx).UpdateId(id, bson.M{"$push": bson.M{"recurrence.exceptions": t}})
+	if err != nil {
+		return storage.Error(err)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
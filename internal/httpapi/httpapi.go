@@ -0,0 +1,60 @@
+// Package httpapi is how the app answers requests: JSON API error documents
+// and successful responses. The handlers, routes and middlewares stay in
+// app/web and write through it.
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Errors
+type Errors struct {
+	Errors []*Error `json:"errors"`
+}
+
+type Error struct {
+	Id     string `json:"id"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// ErrorRecorder is a ResponseWriter that keeps the id of the error it
+// answered with, such as the one of app/web/metrics.go.
+type ErrorRecorder interface {
+	RecordError(id string)
+}
+
+func WriteError(w http.ResponseWriter, err *Error) {
+	if rec, ok := w.(ErrorRecorder); ok {
+		rec.RecordError(err.Id)
+	}
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(Errors{[]*Error{err}})
+}
+
+func WriteErrors(w http.ResponseWriter, httpStatus int, errs []*Error) {
+	if rec, ok := w.(ErrorRecorder); ok && len(errs) > 0 {
+		rec.RecordError(errs[0].Id)
+	}
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(Errors{errs})
+}
+
+// Response Success
+type MessageSuccess struct {
+	Data MessageInfo `json:"data"`
+}
+
+type MessageInfo struct {
+	Message string `json:"message"`
+}
+
+func WriteSuccess(w http.ResponseWriter, httpStatus int, data interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(data)
+}
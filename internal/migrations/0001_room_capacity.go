@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Room capacities used to be strings. Rooms whose capacity doesn't parse are
+// left at 0 and logged, for an admin to fix.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "room_capacity_int",
+		Up:      roomCapacityUp,
+		Down:    roomCapacityDown,
+	})
+}
+
+func roomCapacityUp(db *storage.Database) error {
+	coll := db.C("rooms")
+	var doc struct {
+		Id       storage.ObjectId `bson:"_id"`
+		Capacity string
+	}
+	iter := coll.Find(bson.M{"capacity": bson.M{"$type": "string"}}).Select(bson.M{"capacity": 1}).Iter()
+	for iter.Next(&doc) {
+		capacity, err := strconv.Atoi(strings.TrimSpace(doc.Capacity))
+		if err != nil {
+			log.Printf("migrations: room %s has capacity %q, which isn't a number, setting 0", doc.Id.Hex(), doc.Capacity)
+		}
+		if err := coll.UpdateId(doc.Id, bson.M{"$set": bson.M{"capacity": capacity}}); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+
+	return iter.Close()
+}
+
+func roomCapacityDown(db *storage.Database) error {
+	coll := db.C("rooms")
+	var doc struct {
+		Id       storage.ObjectId `bson:"_id"`
+		Capacity int
+	}
+	iter := coll.Find(bson.M{"capacity": bson.M{"$type": "number"}}).Select(bson.M{"capacity": 1}).Iter()
+	for iter.Next(&doc) {
+		if err := coll.UpdateId(doc.Id, bson.M{"$set": bson.M{"capacity": strconv.Itoa(doc.Capacity)}}); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+
+	return iter.Close()
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mgo kept the content type of a GridFS file in contentType, the official
+// driver keeps it in metadata.contentType. Files uploaded through mgo get a
+// copy there; contentType is left in place, so reverting only drops the
+// copy.
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "gridfs_content_type_metadata",
+		Up:      gridfsContentTypeUp,
+		Down:    gridfsContentTypeDown,
+	})
+}
+
+// gridfsPrefixes are the GridFS buckets of the app, see attachment.go.
+var gridfsPrefixes = []string{"attachments"}
+
+func gridfsContentTypeUp(db *storage.Database) error {
+	for _, prefix := range gridfsPrefixes {
+		_, err := db.C(prefix+".files").UpdateAll(
+			bson.M{"contentType": bson.M{"$exists": true}, "metadata.contentType": bson.M{"$exists": false}},
+			[]bson.M{{"$set": bson.M{"metadata.contentType": "$contentType"}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func gridfsContentTypeDown(db *storage.Database) error {
+	for _, prefix := range gridfsPrefixes {
+		_, err := db.C(prefix+".files").UpdateAll(
+			bson.M{"contentType": bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{"metadata.contentType": ""}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Event series used to be kept in event_series, apart from the events they
+// could conflict with. They're recurring events now, under the same id.
+// event_series is left as it was, so reverting only drops the copies.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "event_series_to_events",
+		Up:      eventSeriesUp,
+		Down:    eventSeriesDown,
+	})
+}
+
+// seriesFields are the fields an event series shares with an event.
+var seriesFields = []string{"name", "locationid", "location", "description", "guests", "owner", "starttime", "endtime", "recurrence"}
+
+// openEnded is the seriesendtime of copied series without an until. Expanding
+// the recurrence is left to the app, which stores the real end the next time
+// the series is saved; until then it's only queried a little more often.
+var openEnded = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+func eventSeriesUp(db *storage.Database) error {
+	events := db.C("events")
+	var series bson.Raw
+	iter := db.C("event_series").Find(nil).Iter()
+	for iter.Next(&series) {
+		event := bson.M{"seriesendtime": seriesEnd(series)}
+		for _, field := range seriesFields {
+			if value, err := series.LookupErr(field); err == nil {
+				event[field] = value
+			}
+		}
+		if _, err := events.UpsertId(series.Lookup("_id"), bson.M{"$setOnInsert": event}); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+
+	return iter.Close()
+}
+
+// seriesEnd returns when the last occurrence of series ends at the latest.
+func seriesEnd(series bson.Raw) time.Time {
+	until, ok := series.Lookup("recurrence", "until").TimeOK()
+	if !ok || until.Year() <= 1 {
+		return openEnded
+	}
+	start, _ := series.Lookup("starttime").TimeOK()
+	end, _ := series.Lookup("endtime").TimeOK()
+
+	return until.Add(end.Sub(start))
+}
+
+func eventSeriesDown(db *storage.Database) error {
+	var series struct {
+		Id storage.ObjectId `bson:"_id"`
+	}
+	iter := db.C("event_series").Find(nil).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&series) {
+		if err := db.C("events").RemoveId(series.Id); err != nil && err != storage.ErrNotFound {
+			iter.Close()
+			return err
+		}
+	}
+
+	return iter.Close()
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import "github.com/ivansaputr4/ivana/internal/storage"
+
+// Venue slugs used to be unique across the whole database; they're unique
+// within an organization now, under orgid_1_slug_1, which the app creates
+// on start. Reverting brings the global index back, which fails while two
+// organizations share a slug.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "venue_slug_per_org",
+		Up:      venueSlugPerOrgUp,
+		Down:    venueSlugPerOrgDown,
+	})
+}
+
+func venueSlugPerOrgUp(db *storage.Database) error {
+	return db.C("venues").DropIndex("slug_1")
+}
+
+func venueSlugPerOrgDown(db *storage.Database) error {
+	err := db.C("venues").DropIndex("orgid_1_slug_1")
+	if err != nil {
+		return err
+	}
+
+	return db.C("venues").EnsureIndex(storage.Index{Key: []string{"slug"}, Unique: true, Sparse: true})
+}
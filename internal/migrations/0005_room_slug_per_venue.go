@@ -0,0 +1,29 @@
+package migrations
+
+import "github.com/ivansaputr4/ivana/internal/storage"
+
+// Room slugs are unique within their venue under
+// orgid_1_venueid_1_slug_1, which the app creates on start, rather than only
+// checked before they're written. The index it replaces didn't enforce it.
+// Reverting brings the plain index back.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "room_slug_per_venue",
+		Up:      roomSlugPerVenueUp,
+		Down:    roomSlugPerVenueDown,
+	})
+}
+
+func roomSlugPerVenueUp(db *storage.Database) error {
+	return db.C("rooms").DropIndex("venueid_1_slug_1")
+}
+
+func roomSlugPerVenueDown(db *storage.Database) error {
+	err := db.C("rooms").DropIndex("orgid_1_venueid_1_slug_1")
+	if err != nil {
+		return err
+	}
+
+	return db.C("rooms").EnsureIndexKey("venueid", "slug")
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import "github.com/ivansaputr4/ivana/internal/storage"
+
+// The change log, team caps and working hours are kept per organization, so
+// their unique keys are too: orgid_1_seq_1, orgid_1_team_1 and
+// orgid_1_user_1, which the app creates on start, replace the global ones.
+// Reverting brings the global indexes back.
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "org_scoped_side_collections",
+		Up:      orgScopedSideCollectionsUp,
+		Down:    orgScopedSideCollectionsDown,
+	})
+}
+
+var orgScopedKeys = []struct {
+	collection, key string
+}{
+	{"changes", "seq"},
+	{"team_caps", "team"},
+	{"working_hours", "user"},
+}
+
+func orgScopedSideCollectionsUp(db *storage.Database) error {
+	for _, k := range orgScopedKeys {
+		err := db.C(k.collection).DropIndex(k.key + "_1")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func orgScopedSideCollectionsDown(db *storage.Database) error {
+	for _, k := range orgScopedKeys {
+		err := db.C(k.collection).DropIndex("orgid_1_" + k.key + "_1")
+		if err != nil {
+			return err
+		}
+
+		err = db.C(k.collection).EnsureIndex(storage.Index{Key: []string{k.key}, Unique: true})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
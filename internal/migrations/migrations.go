@@ -0,0 +1,144 @@
+// Package migrations changes the shape of stored documents in versioned
+// steps, so every environment goes through the same ones in the same order.
+//
+// Each migration lives in a file of its own, named after its version, and
+// registers itself with register from init. Applied versions are recorded in
+// schema_migrations; Up applies the ones missing in order of version, Down
+// reverts the latest ones, newest first.
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Collection is where applied migrations are recorded.
+const Collection = "schema_migrations"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(db *storage.Database) error
+	Down    func(db *storage.Database) error
+}
+
+// Applied is the record of an applied migration.
+type Applied struct {
+	Version   int       `json:"version" bson:"_id"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Status is a migration and when it was applied, if it was.
+type Status struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+var all = map[int]Migration{}
+
+func register(m Migration) {
+	if _, ok := all[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: version %d registered twice", m.Version))
+	}
+	all[m.Version] = m
+}
+
+// sorted returns the migrations in order of version.
+func sorted() []Migration {
+	result := []Migration{}
+	for _, m := range all {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result
+}
+
+func applied(db *storage.Database) (map[int]Applied, error) {
+	records := []Applied{}
+	if err := db.C(Collection).Find(nil).All(&records); err != nil {
+		return nil, err
+	}
+	result := map[int]Applied{}
+	for _, record := range records {
+		result[record.Version] = record
+	}
+
+	return result, nil
+}
+
+// List returns every migration with when it was applied.
+func List(db *storage.Database) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	result := []Status{}
+	for _, m := range sorted() {
+		result = append(result, Status{m.Version, m.Name, done[m.Version].AppliedAt})
+	}
+
+	return result, nil
+}
+
+// Up applies the migrations not applied yet, returning how many it applied.
+func Up(db *storage.Database) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, m := range sorted() {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		log.Printf("migrations: applying %04d %s", m.Version, m.Name)
+		if err := m.Up(db); err != nil {
+			return n, fmt.Errorf("migration %04d %s: %v", m.Version, m.Name, err)
+		}
+		if err := db.C(Collection).Insert(Applied{m.Version, m.Name, time.Now()}); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// Down reverts the latest steps applied migrations, returning how many it
+// reverted.
+func Down(db *storage.Database, steps int) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+
+	migrations := sorted()
+	n := 0
+	for i := len(migrations) - 1; i >= 0 && n < steps; i-- {
+		m := migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return n, fmt.Errorf("migration %04d %s can't be reverted", m.Version, m.Name)
+		}
+		log.Printf("migrations: reverting %04d %s", m.Version, m.Name)
+		if err := m.Down(db); err != nil {
+			return n, fmt.Errorf("migration %04d %s: %v", m.Version, m.Name, err)
+		}
+		if err := db.C(Collection).RemoveId(m.Version); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// Schema
+//
+// The schema changes in versioned steps like the documents of MongoDB do,
+// see internal/migrations. Applied versions are recorded in
+// schema_migrations; Migrate applies the missing ones in order of version,
+// each in a transaction of its own.
+//
+// Events are listed by the start of their time window, and checked for
+// conflicts by room and time window, so both have an index. Recurring
+// events are indexed apart, by room and the end of the series, like
+// MongoDB's events.locationid_1_seriesendtime_1.
+//
+// Venues, rooms and events belong to an organization, org_id, empty for the
+// default one, see storage.ScopeByOrg. Venue slugs are unique within it,
+// room slugs within their venue.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+}
+
+var schema = []Migration{
+	{1, "venues_rooms_events", `
+CREATE TABLE venues (
+	id         text PRIMARY KEY,
+	name       text NOT NULL,
+	slug       text NOT NULL DEFAULT '',
+	deleted_at timestamptz,
+	doc        bytea NOT NULL
+);
+CREATE UNIQUE INDEX venues_slug ON venues (slug) WHERE slug <> '';
+
+CREATE TABLE rooms (
+	id         text PRIMARY KEY,
+	venue_id   text NOT NULL,
+	name       text NOT NULL,
+	slug       text NOT NULL DEFAULT '',
+	capacity   integer NOT NULL DEFAULT 0,
+	deleted_at timestamptz,
+	doc        bytea NOT NULL
+);
+CREATE INDEX rooms_venue_id_slug ON rooms (venue_id, slug);
+
+CREATE TABLE events (
+	id              text PRIMARY KEY,
+	location_id     text NOT NULL DEFAULT '',
+	start_time      timestamptz NOT NULL,
+	end_time        timestamptz NOT NULL,
+	recurring       boolean NOT NULL DEFAULT false,
+	series_end_time timestamptz,
+	deleted_at      timestamptz,
+	doc             bytea NOT NULL
+);
+CREATE INDEX events_start_time ON events (start_time);
+CREATE INDEX events_location_id_time ON events (location_id, start_time, end_time) WHERE NOT recurring;
+CREATE INDEX events_location_id_series_end_time ON events (location_id, series_end_time) WHERE recurring;
+`},
+	{2, "org_id", `
+ALTER TABLE venues ADD COLUMN org_id text NOT NULL DEFAULT '';
+ALTER TABLE rooms ADD COLUMN org_id text NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN org_id text NOT NULL DEFAULT '';
+DROP INDEX venues_slug;
+CREATE UNIQUE INDEX venues_org_id_slug ON venues (org_id, slug) WHERE slug <> '';
+`},
+	{3, "room_slug_per_venue", `
+DROP INDEX rooms_venue_id_slug;
+CREATE UNIQUE INDEX rooms_venue_id_slug ON rooms (venue_id, slug) WHERE slug <> '';
+`},
+}
+
+// Migrate applies the steps of the schema not applied yet, returning how
+// many it applied.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    integer PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return 0, err
+	}
+
+	done := map[int]bool{}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		done[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, m := range schema {
+		if done[m.Version] {
+			continue
+		}
+		log.Printf("postgres: applying %04d %s", m.Version, m.Name)
+		if err := apply(ctx, db, m); err != nil {
+			return n, fmt.Errorf("migration %04d %s: %v", m.Version, m.Name, err)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
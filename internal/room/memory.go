@@ -0,0 +1,265 @@
+package room
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Room in memory
+//
+// MemRepository behaves like MongoRepository: deletes are soft, lookups
+// leave deleted rooms out and slugs are unique within their venue. It never blocks, so it only reads the
+// organization to keep to from ctx, see storage.WithOrg.
+type MemRepository struct {
+	mu    sync.Mutex
+	rooms []Room
+}
+
+func NewMemRepository() *MemRepository {
+	return &MemRepository{}
+}
+
+// index returns the lookup of the rooms seen in ctx by id.
+func (r *MemRepository) index(ctx context.Context) func(storage.ObjectId) int {
+	return func(id storage.ObjectId) int {
+		for idx, room := range r.rooms {
+			if room.Id == id && room.DeletedAt == nil && storage.InOrg(ctx, room.OrgId) {
+				return idx
+			}
+		}
+
+		return -1
+	}
+}
+
+func (r *MemRepository) findBy(ctx context.Context, match func(Room) bool) (Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, room := range r.rooms {
+		if room.DeletedAt == nil && storage.InOrg(ctx, room.OrgId) && match(room) {
+			return room, nil
+		}
+	}
+
+	return Room{}, storage.ErrNotFound
+}
+
+func (r *MemRepository) All(ctx context.Context, opts storage.ListOptions) ([]Room, int, error) {
+	return r.AllMatching(ctx, Filter{}, opts)
+}
+
+func (r *MemRepository) AllMatching(ctx context.Context, filter Filter, opts storage.ListOptions) ([]Room, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Room{}
+	for _, room := range r.rooms {
+		if (room.DeletedAt == nil || opts.IncludeDeleted) && storage.InOrg(ctx, room.OrgId) && filter.matches(room) {
+			result = append(result, room)
+		}
+	}
+	lo, hi := opts.ApplySlice(len(result), func(i int, j int, field string) int {
+		switch field {
+		case "name":
+			return strings.Compare(result[i].Name, result[j].Name)
+		case "slug":
+			return strings.Compare(result[i].Slug, result[j].Slug)
+		case "venueid":
+			return strings.Compare(result[i].VenueId, result[j].VenueId)
+		case "capacity":
+			return result[i].Capacity - result[j].Capacity
+		}
+		return 0
+	}, func(i int, j int) { result[i], result[j] = result[j], result[i] })
+
+	return result[lo:hi], len(result), nil
+}
+
+func (r *MemRepository) AllByVenueId(ctx context.Context, venueId string) ([]Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Room{}
+	for _, room := range r.rooms {
+		if room.DeletedAt == nil && storage.InOrg(ctx, room.OrgId) && room.VenueId == venueId {
+			result = append(result, room)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *MemRepository) AllByVenueIds(ctx context.Context, venueIds []string) ([]Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Room{}
+	for _, room := range r.rooms {
+		if room.DeletedAt == nil && storage.InOrg(ctx, room.OrgId) && contains(venueIds, room.VenueId) {
+			result = append(result, room)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+func (r *MemRepository) AllByIds(ctx context.Context, ids []string) ([]Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Room{}
+	for _, room := range r.rooms {
+		if room.DeletedAt == nil && storage.InOrg(ctx, room.OrgId) && contains(ids, room.Id.Hex()) {
+			result = append(result, room)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *MemRepository) Find(ctx context.Context, id string) (Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return Room{}, err
+	}
+
+	return r.rooms[idx], nil
+}
+
+func (r *MemRepository) FindBySlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	return r.findBy(ctx, func(room Room) bool { return room.VenueId == venueId && room.Slug == slug })
+}
+
+func (r *MemRepository) FindByOldSlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	return r.findBy(ctx, func(room Room) bool { return room.VenueId == venueId && contains(room.OldSlugs, slug) })
+}
+
+func (r *MemRepository) SlugTaken(ctx context.Context, venueId string, slug string, exceptId storage.ObjectId) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, room := range r.rooms {
+		if room.Id != exceptId && storage.InOrg(ctx, room.OrgId) && room.VenueId == venueId && (room.Slug == slug || contains(room.OldSlugs, slug)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// slugConflict reports whether another room of the venue of room has its
+// slug, as the unique index of MongoRepository would.
+func (r *MemRepository) slugConflict(room *Room) bool {
+	for _, other := range r.rooms {
+		if other.Id != room.Id && other.OrgId == room.OrgId && other.VenueId == room.VenueId && room.Slug != "" && other.Slug == room.Slug {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *MemRepository) Create(ctx context.Context, room *Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if created := (Room{OrgId: storage.OrgFor(ctx, room.OrgId), VenueId: room.VenueId, Slug: room.Slug}); r.slugConflict(&created) {
+		return storage.ErrConflict
+	}
+	room.Id = storage.NewObjectId()
+	room.OrgId = storage.OrgFor(ctx, room.OrgId)
+	r.rooms = append(r.rooms, *room)
+
+	return nil
+}
+
+func (r *MemRepository) Update(ctx context.Context, room *Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx := range r.rooms {
+		if r.rooms[idx].Id == room.Id && storage.InOrg(ctx, r.rooms[idx].OrgId) {
+			room.OrgId = r.rooms[idx].OrgId
+			if r.slugConflict(room) {
+				return storage.ErrConflict
+			}
+			r.rooms[idx] = *room
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// Patch is Update, the repo keeps whole documents.
+func (r *MemRepository) Patch(ctx context.Context, room *Room, existing Room) error {
+	return r.Update(ctx, room)
+}
+
+func (r *MemRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	r.rooms[idx].DeletedAt = &now
+
+	return nil
+}
+
+// Restore brings a deleted room back.
+func (r *MemRepository) Restore(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	for idx := range r.rooms {
+		if r.rooms[idx].Id == oid && r.rooms[idx].DeletedAt != nil && storage.InOrg(ctx, r.rooms[idx].OrgId) {
+			r.rooms[idx].DeletedAt = nil
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// Remove deletes the room with id for good, undoing its creation.
+func (r *MemRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx := range r.rooms {
+		if r.rooms[idx].Id == id && storage.InOrg(ctx, r.rooms[idx].OrgId) {
+			r.rooms = append(r.rooms[:idx], r.rooms[idx+1:]...)
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
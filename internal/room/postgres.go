@@ -0,0 +1,218 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/postgres"
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Room in PostgreSQL
+//
+// PostgresRepository behaves like MongoRepository over the rooms table, see
+// internal/postgres.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db}
+}
+
+// sortColumns are the columns of the stored fields rooms sort by.
+var sortColumns = map[string]string{"name": "name", "slug": "slug", "venueid": "venue_id", "capacity": "capacity"}
+
+// query returns the rooms of the rows of query.
+func (r *PostgresRepository) query(ctx context.Context, query string, args ...interface{}) ([]Room, error) {
+	result := []Room{}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc []byte
+		var deletedAt *time.Time
+		if err := rows.Scan(&doc, &deletedAt); err != nil {
+			return result, err
+		}
+		room := Room{}
+		if err := postgres.Unmarshal(doc, &room); err != nil {
+			return result, err
+		}
+		room.DeletedAt = deletedAt
+		result = append(result, room)
+	}
+
+	return result, rows.Err()
+}
+
+// one is query for a single room.
+func (r *PostgresRepository) one(ctx context.Context, query string, args ...interface{}) (Room, error) {
+	rooms, err := r.query(ctx, query, args...)
+	if err != nil {
+		return Room{}, err
+	}
+	if len(rooms) == 0 {
+		return Room{}, storage.ErrNotFound
+	}
+
+	return rooms[0], nil
+}
+
+func (r *PostgresRepository) All(ctx context.Context, opts storage.ListOptions) ([]Room, int, error) {
+	return r.AllMatching(ctx, Filter{}, opts)
+}
+
+func (r *PostgresRepository) AllMatching(ctx context.Context, filter Filter, opts storage.ListOptions) ([]Room, int, error) {
+	params := postgres.Params{}
+	where := ` WHERE ` + params.Org(ctx)
+	if !opts.IncludeDeleted {
+		where += ` AND deleted_at IS NULL`
+	}
+	if filter.MinCapacity > 0 {
+		where += ` AND capacity >= ` + params.Add(filter.MinCapacity)
+	}
+
+	total := 0
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM rooms`+where, params.Args...).Scan(&total)
+	if err != nil {
+		return []Room{}, 0, err
+	}
+	result, err := r.query(ctx, `SELECT doc, deleted_at FROM rooms`+where+postgres.Page(opts, sortColumns), params.Args...)
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *PostgresRepository) AllByVenueId(ctx context.Context, venueId string) ([]Room, error) {
+	params := postgres.Params{}
+	return r.query(ctx, `SELECT doc, deleted_at FROM rooms WHERE venue_id = `+params.Add(venueId)+` AND deleted_at IS NULL AND `+params.Org(ctx)+` ORDER BY id`, params.Args...)
+}
+
+func (r *PostgresRepository) AllByVenueIds(ctx context.Context, venueIds []string) ([]Room, error) {
+	params := postgres.Params{}
+	return r.query(ctx, `SELECT doc, deleted_at FROM rooms WHERE venue_id IN (`+params.In(venueIds)+`) AND deleted_at IS NULL AND `+params.Org(ctx)+` ORDER BY name`, params.Args...)
+}
+
+func (r *PostgresRepository) AllByIds(ctx context.Context, ids []string) ([]Room, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM rooms WHERE id IN (` + params.In(ids) + `) AND deleted_at IS NULL AND ` + params.Org(ctx) + ` ORDER BY id`
+
+	return r.query(ctx, query, params.Args...)
+}
+
+func (r *PostgresRepository) Find(ctx context.Context, id string) (Room, error) {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return Room{}, err
+	}
+
+	params := postgres.Params{}
+	return r.one(ctx, `SELECT doc, deleted_at FROM rooms WHERE id = `+params.Add(oid.Hex())+` AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...)
+}
+
+func (r *PostgresRepository) FindBySlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	params := postgres.Params{Args: []interface{}{venueId, slug}}
+	return r.one(ctx, `SELECT doc, deleted_at FROM rooms WHERE venue_id = $1 AND slug = $2 AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...)
+}
+
+// FindByOldSlug and SlugTaken look through the old slugs of the rooms of the
+// venue, which aren't a column of their own.
+func (r *PostgresRepository) FindByOldSlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	rooms, err := r.AllByVenueId(ctx, venueId)
+	if err != nil {
+		return Room{}, err
+	}
+	for _, room := range rooms {
+		if contains(room.OldSlugs, slug) {
+			return room, nil
+		}
+	}
+
+	return Room{}, storage.ErrNotFound
+}
+
+func (r *PostgresRepository) SlugTaken(ctx context.Context, venueId string, slug string, exceptId storage.ObjectId) (bool, error) {
+	params := postgres.Params{Args: []interface{}{venueId, exceptId.Hex()}}
+	rooms, err := r.query(ctx, `SELECT doc, deleted_at FROM rooms WHERE venue_id = $1 AND id <> $2 AND `+params.Org(ctx), params.Args...)
+	if err != nil {
+		return false, err
+	}
+	for _, room := range rooms {
+		if room.Slug == slug || contains(room.OldSlugs, slug) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, room *Room) error {
+	created := *room
+	created.Id = storage.NewObjectId()
+	created.OrgId = storage.OrgFor(ctx, room.OrgId)
+	doc, err := postgres.Marshal(created)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO rooms (id, venue_id, name, slug, capacity, deleted_at, doc, org_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		created.Id.Hex(), created.VenueId, created.Name, created.Slug, created.Capacity, created.DeletedAt, doc, created.OrgId)
+	if err != nil {
+		return postgres.Error(err)
+	}
+
+	room.Id = created.Id
+	room.OrgId = created.OrgId
+
+	return nil
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, room *Room) error {
+	room.OrgId = storage.OrgFor(ctx, room.OrgId)
+	doc, err := postgres.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{room.Id.Hex(), room.VenueId, room.Name, room.Slug, room.Capacity, room.DeletedAt, doc}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE rooms SET venue_id = $2, name = $3, slug = $4, capacity = $5, deleted_at = $6, doc = $7 WHERE id = $1 AND `+params.Org(ctx),
+		params.Args...))
+}
+
+// Patch is Update, the repo writes the whole document of a row.
+func (r *PostgresRepository) Patch(ctx context.Context, room *Room, existing Room) error {
+	return r.Update(ctx, room)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{oid.Hex(), time.Now()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE rooms SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...))
+}
+
+// Restore brings a deleted room back.
+func (r *PostgresRepository) Restore(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{oid.Hex()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE rooms SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND `+params.Org(ctx), params.Args...))
+}
+
+// Remove deletes the room with id for good, undoing its creation.
+func (r *PostgresRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	params := postgres.Params{Args: []interface{}{id.Hex()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1 AND `+params.Org(ctx), params.Args...))
+}
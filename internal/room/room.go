@@ -0,0 +1,299 @@
+// Package room holds rooms, the bookable spaces of a venue, and their
+// repositories: MongoRepository over the rooms collection,
+// PostgresRepository over the rooms table and MemRepository in memory. The
+// handlers of app/web reach them through Repository.
+package room
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Room struct {
+	Id       storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name     string           `json:"name"`
+	Slug     string           `json:"slug"`
+	OldSlugs []string         `json:"-"`
+	VenueId  string           `json:"venue_id"`
+	FloorId  string           `json:"floor_id,omitempty"`
+	Capacity int              `json:"capacity"`
+
+	// Translations by language tag, see app/web/localized.go.
+	Names        map[string]string `json:"names,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Descriptions map[string]string `json:"descriptions,omitempty"`
+
+	// Amenities are what the room offers, such as "projector", see
+	// app/web/roomsuggest.go.
+	Amenities []string `json:"amenities,omitempty" bson:",omitempty"`
+
+	// HighDemand rooms need approval for bookings of unreliable users, see
+	// app/web/reliability.go.
+	HighDemand bool `json:"high_demand"`
+
+	// ReleaseAfter is how many minutes after their start bookings nobody
+	// checked into are cancelled, see app/web/release.go. 0 keeps them.
+	ReleaseAfter int `json:"release_after,omitempty"`
+
+	// Where the room is, for people looking for it, see
+	// app/web/floorplan.go: the building and floor as signposted, the spot
+	// on the plan of its floor and a photo.
+	Building       string          `json:"building,omitempty" bson:",omitempty"`
+	Floor          string          `json:"floor,omitempty" bson:",omitempty"`
+	MapCoordinates *MapCoordinates `json:"map_coordinates,omitempty" bson:",omitempty"`
+	PhotoURL       string          `json:"photo_url,omitempty" bson:",omitempty"`
+
+	// OrgId is the organization the room belongs to, see storage.ScopeByOrg.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+
+	// DeletedAt is set on deleted rooms, see storage.DeletedFilter.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:",omitempty"`
+}
+
+// MapCoordinates place a room on the plan of its floor, in the units of the
+// floor's width and height, like desks.
+type MapCoordinates struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Repository interface {
+	All(ctx context.Context, opts storage.ListOptions) ([]Room, int, error)
+	AllMatching(ctx context.Context, filter Filter, opts storage.ListOptions) ([]Room, int, error)
+	AllByVenueId(ctx context.Context, venueId string) ([]Room, error)
+	AllByVenueIds(ctx context.Context, venueIds []string) ([]Room, error)
+	AllByIds(ctx context.Context, ids []string) ([]Room, error)
+	Find(ctx context.Context, id string) (Room, error)
+	FindBySlug(ctx context.Context, venueId string, slug string) (Room, error)
+	FindByOldSlug(ctx context.Context, venueId string, slug string) (Room, error)
+	SlugTaken(ctx context.Context, venueId string, slug string, exceptId storage.ObjectId) (bool, error)
+	Create(ctx context.Context, room *Room) error
+	Update(ctx context.Context, room *Room) error
+	Patch(ctx context.Context, room *Room, existing Room) error
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Remove(ctx context.Context, id storage.ObjectId) error
+}
+
+// Filter selects the rooms of Repository.AllMatching.
+type Filter struct {
+	// MinCapacity, when positive, leaves out the rooms seating fewer.
+	MinCapacity int
+}
+
+func (f Filter) query() bson.M {
+	query := bson.M{}
+	if f.MinCapacity > 0 {
+		query["capacity"] = bson.M{"$gte": f.MinCapacity}
+	}
+
+	return query
+}
+
+func (f Filter) matches(room Room) bool {
+	return f.MinCapacity <= 0 || room.Capacity >= f.MinCapacity
+}
+
+// Repo Room
+type MongoRepository struct {
+	coll   *storage.Collection
+	policy storage.Policy
+}
+
+func NewMongoRepository(coll *storage.Collection, policy storage.Policy) *MongoRepository {
+	return &MongoRepository{coll, policy}
+}
+
+// All returns the rooms on the page opts asks for, and how many there are in
+// total.
+func (r *MongoRepository) All(ctx context.Context, opts storage.ListOptions) ([]Room, int, error) {
+	return r.AllMatching(ctx, Filter{}, opts)
+}
+
+// AllMatching is All over the rooms matching filter.
+func (r *MongoRepository) AllMatching(ctx context.Context, filter Filter, opts storage.ListOptions) ([]Room, int, error) {
+	result := []Room{}
+	total := 0
+	err := r.policy.Read(func() (err error) {
+		query := r.coll.WithContext(ctx).Find(storage.DeletedFilter(filter.query(), opts.IncludeDeleted))
+		total, err = query.Count()
+		if err != nil {
+			return err
+		}
+
+		return opts.Apply(query).All(&result)
+	})
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *MongoRepository) Find(ctx context.Context, id string) (Room, error) {
+	result := Room{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.policy.Read(func() error { return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"_id": oid})).One(&result) })
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+// AllByIds returns the rooms with ids.
+func (r *MongoRepository) AllByIds(ctx context.Context, ids []string) ([]Room, error) {
+	oids := []storage.ObjectId{}
+	for _, id := range ids {
+		if storage.IsObjectIdHex(id) {
+			oids = append(oids, storage.ObjectIdHex(id))
+		}
+	}
+	result := []Room{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"_id": bson.M{"$in": oids}})).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) Create(ctx context.Context, room *Room) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.WithContext(ctx).UpsertId(id, room)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	room.Id = id
+
+	return nil
+}
+
+func (r *MongoRepository) Update(ctx context.Context, room *Room) error {
+	err := r.coll.WithContext(ctx).UpdateId(room.Id, room)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Patch writes the fields room changes from existing, the room as it was
+// read, leaving the others alone, see storage.Changes.
+func (r *MongoRepository) Patch(ctx context.Context, room *Room, existing Room) error {
+	update, err := storage.Changes(existing, room)
+	if err != nil || update == nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).UpdateId(room.Id, update)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(storage.NotDeleted(bson.M{"_id": oid}), bson.M{"$set": bson.M{"deletedat": time.Now()}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Restore brings a deleted room back.
+func (r *MongoRepository) Restore(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(bson.M{"_id": oid, "deletedat": bson.M{"$ne": nil}}, bson.M{"$unset": bson.M{"deletedat": ""}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) AllByVenueId(ctx context.Context, venueId string) ([]Room, error) {
+	result := []Room{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"venueid": venueId})).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// AllByVenueIds returns the rooms of the venues, by name.
+func (r *MongoRepository) AllByVenueIds(ctx context.Context, venueIds []string) ([]Room, error) {
+	result := []Room{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"venueid": bson.M{"$in": venueIds}})).Sort("name").All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Repo Room slugs
+func (r *MongoRepository) FindBySlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	result := Room{}
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"venueid": venueId, "slug": slug})).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) FindByOldSlug(ctx context.Context, venueId string, slug string) (Room, error) {
+	result := Room{}
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"venueid": venueId, "oldslugs": slug})).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) SlugTaken(ctx context.Context, venueId string, slug string, exceptId storage.ObjectId) (bool, error) {
+	query := bson.M{"venueid": venueId, "$or": []bson.M{{"slug": slug}, {"oldslugs": slug}}}
+	if exceptId != "" {
+		query["_id"] = bson.M{"$ne": exceptId}
+	}
+
+	n, err := r.coll.WithContext(ctx).Find(query).Count()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Remove deletes the room with id for good, undoing its creation.
+func (r *MongoRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	err := r.coll.WithContext(ctx).RemoveId(id)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
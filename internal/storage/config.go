@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Config is how to connect to MongoDB, read from the environment:
+//
+//	MONGODB_URI                       default mongodb://localhost
+//	MONGODB_MAX_POOL_SIZE             connections per server, default 100
+//	MONGODB_MIN_POOL_SIZE             connections kept open per server, default 0
+//	MONGODB_MAX_CONN_IDLE_TIME        e.g. "5m", default 0 which never closes idle ones
+//	MONGODB_CONNECT_TIMEOUT           e.g. "10s", default 10s
+//	MONGODB_SERVER_SELECTION_TIMEOUT  how long to wait for a primary, default 30s
+type Config struct {
+	URI                    string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+}
+
+func ConfigFromEnv() Config {
+	return ConfigFrom(os.Getenv)
+}
+
+// ConfigFrom reads the Config from the settings getenv returns by name.
+func ConfigFrom(getenv func(string) string) Config {
+	return Config{
+		URI:                    envString(getenv, "MONGODB_URI", "mongodb://localhost"),
+		MaxPoolSize:            envUint(getenv, "MONGODB_MAX_POOL_SIZE", 100),
+		MinPoolSize:            envUint(getenv, "MONGODB_MIN_POOL_SIZE", 0),
+		MaxConnIdleTime:        envDuration(getenv, "MONGODB_MAX_CONN_IDLE_TIME", 0),
+		ConnectTimeout:         envDuration(getenv, "MONGODB_CONNECT_TIMEOUT", 10*time.Second),
+		ServerSelectionTimeout: envDuration(getenv, "MONGODB_SERVER_SELECTION_TIMEOUT", 30*time.Second),
+	}
+}
+
+func (c Config) clientOptions() *options.ClientOptions {
+	return options.Client().
+		ApplyURI(c.URI).
+		SetMaxPoolSize(c.MaxPoolSize).
+		SetMinPoolSize(c.MinPoolSize).
+		SetMaxConnIdleTime(c.MaxConnIdleTime).
+		SetConnectTimeout(c.ConnectTimeout).
+		SetServerSelectionTimeout(c.ServerSelectionTimeout)
+}
+
+func envString(getenv func(string) string, name string, fallback string) string {
+	if s := getenv(name); s != "" {
+		return s
+	}
+
+	return fallback
+}
+
+func envUint(getenv func(string) string, name string, fallback uint64) uint64 {
+	n, err := strconv.ParseUint(getenv(name), 10, 64)
+	if err != nil {
+		return fallback
+	}
+
+	return n
+}
+
+func envDuration(getenv func(string) string, name string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(getenv(name))
+	if err != nil {
+		return fallback
+	}
+
+	return d
+}
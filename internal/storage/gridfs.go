@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFS stores files too large for a document in the collections
+// prefix.files and prefix.chunks.
+type GridFS struct {
+	db     *Database
+	prefix string
+}
+
+func (d *Database) GridFS(prefix string) *GridFS {
+	return &GridFS{db: d, prefix: prefix}
+}
+
+func (fs *GridFS) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(fs.db.db, options.GridFSBucket().SetName(fs.prefix))
+}
+
+// GridFile is a file being written, from Create, or read, from OpenId.
+type GridFile struct {
+	fs          *GridFS
+	id          ObjectId
+	name        string
+	contentType string
+	writing     bool
+	upload      *gridfs.UploadStream
+
+	download   *gridfs.DownloadStream
+	length     int64
+	uploadDate time.Time
+	offset     int64
+}
+
+// Create starts writing a new file named name; it's stored once closed.
+func (fs *GridFS) Create(name string) (*GridFile, error) {
+	return &GridFile{fs: fs, id: NewObjectId(), name: name, writing: true}, nil
+}
+
+// OpenId opens the file with id for reading.
+func (fs *GridFS) OpenId(id ObjectId) (*GridFile, error) {
+	file := &GridFile{fs: fs, id: id}
+	if err := file.open(0); err != nil {
+		return nil, err
+	}
+	info := file.download.GetFile()
+	file.name, file.length, file.uploadDate = info.Name, info.Length, info.UploadDate
+
+	return file, nil
+}
+
+func (f *GridFile) Id() ObjectId {
+	return f.id
+}
+
+func (f *GridFile) Name() string {
+	return f.name
+}
+
+func (f *GridFile) Size() int64 {
+	return f.length
+}
+
+func (f *GridFile) UploadDate() time.Time {
+	return f.uploadDate
+}
+
+// SetContentType records the content type of a file being written, before
+// the first Write.
+func (f *GridFile) SetContentType(contentType string) {
+	f.contentType = contentType
+}
+
+func (f *GridFile) Write(p []byte) (int, error) {
+	if !f.writing {
+		return 0, errors.New("gridfs: file not open for writing")
+	}
+	if err := f.startUpload(); err != nil {
+		return 0, err
+	}
+
+	n, err := f.upload.Write(p)
+	f.length += int64(n)
+
+	return n, err
+}
+
+func (f *GridFile) startUpload() error {
+	if f.upload != nil {
+		return nil
+	}
+	bucket, err := f.fs.bucket()
+	if err != nil {
+		return err
+	}
+	opts := options.GridFSUpload()
+	if f.contentType != "" {
+		opts.SetMetadata(bson.M{"contentType": f.contentType})
+	}
+	f.upload, err = bucket.OpenUploadStreamWithID(f.id.objectID(), f.name, opts)
+
+	return err
+}
+
+// Abort drops what was written of a new file.
+func (f *GridFile) Abort() {
+	if f.upload != nil {
+		f.upload.Abort()
+		f.upload = nil
+	}
+	f.writing = false
+}
+
+// open starts reading the file at offset.
+func (f *GridFile) open(offset int64) error {
+	bucket, err := f.fs.bucket()
+	if err != nil {
+		return err
+	}
+	if f.download != nil {
+		f.download.Close()
+	}
+	f.download, err = bucket.OpenDownloadStream(f.id.objectID())
+	if err == gridfs.ErrFileNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if _, err := f.download.Skip(offset); err != nil {
+			return err
+		}
+	}
+	f.offset = offset
+
+	return nil
+}
+
+func (f *GridFile) Read(p []byte) (int, error) {
+	if f.download == nil {
+		return 0, errors.New("gridfs: file not open for reading")
+	}
+	if f.offset >= f.length {
+		return 0, io.EOF
+	}
+	n, err := f.download.Read(p)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+// Seek moves where Read continues, reopening the file to go back.
+func (f *GridFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.length
+	}
+	if offset < 0 {
+		return f.offset, errors.New("gridfs: seek before the start of the file")
+	}
+	if offset > f.length {
+		offset = f.length
+	}
+
+	switch {
+	case offset == f.offset:
+	case offset > f.offset && offset < f.length:
+		n, err := f.download.Skip(offset - f.offset)
+		f.offset += n
+		if err != nil {
+			return f.offset, err
+		}
+	case offset == f.length:
+		// Reads at the end return io.EOF without touching the stream.
+		f.offset = offset
+	default:
+		if err := f.open(offset); err != nil {
+			return f.offset, err
+		}
+	}
+
+	return f.offset, nil
+}
+
+// Close stores a file being written, or releases one being read.
+func (f *GridFile) Close() error {
+	if f.writing {
+		f.writing = false
+		if err := f.startUpload(); err != nil {
+			return err
+		}
+		err := f.upload.Close()
+		f.upload = nil
+		return err
+	}
+	if f.download != nil {
+		err := f.download.Close()
+		f.download = nil
+		return err
+	}
+
+	return nil
+}
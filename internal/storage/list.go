@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ListOptions is the page and order of a list of documents, read from the
+// request by the app, see app/web/pagination.go.
+type ListOptions struct {
+	Page    int
+	PerPage int
+	// Sort holds stored field names, "-" prefixed when descending.
+	Sort  []string
+	Paged bool
+	// IncludeDeleted lists deleted documents too, see DeletedFilter.
+	IncludeDeleted bool
+}
+
+// Apply sorts and pages query.
+func (o ListOptions) Apply(query *Query) *Query {
+	if len(o.Sort) > 0 {
+		query = query.Sort(o.Sort...)
+	}
+	if o.Paged {
+		query = query.Skip((o.Page - 1) * o.PerPage).Limit(o.PerPage)
+	}
+
+	return query
+}
+
+// Bounds returns the slice of a list of total items that's on the page.
+func (o ListOptions) Bounds(total int) (int, int) {
+	if !o.Paged {
+		return 0, total
+	}
+
+	lo := (o.Page - 1) * o.PerPage
+	if lo > total {
+		lo = total
+	}
+	hi := lo + o.PerPage
+	if hi > total {
+		hi = total
+	}
+
+	return lo, hi
+}
+
+// ApplySlice sorts a list of n items held in memory like Apply sorts a
+// query, with compare comparing the items at i and j by a stored field name,
+// and returns the bounds of its page.
+func (o ListOptions) ApplySlice(n int, compare func(i int, j int, field string) int, swap func(i int, j int)) (int, int) {
+	sort.Stable(sorter{n, o.Sort, compare, swap})
+
+	return o.Bounds(n)
+}
+
+type sorter struct {
+	n       int
+	keys    []string
+	compare func(i int, j int, field string) int
+	swap    func(i int, j int)
+}
+
+func (s sorter) Len() int      { return s.n }
+func (s sorter) Swap(i, j int) { s.swap(i, j) }
+func (s sorter) Less(i, j int) bool {
+	for _, key := range s.keys {
+		field, desc := key, false
+		if strings.HasPrefix(key, "-") {
+			field, desc = key[1:], true
+		}
+		if n := s.compare(i, j, field); n != 0 {
+			return (n < 0) != desc
+		}
+	}
+
+	return false
+}
+
+// DeletedFilter adds the condition leaving deleted documents out to query,
+// unless include. Deleted documents have their deletedat set rather than
+// being removed.
+func DeletedFilter(query bson.M, include bool) bson.M {
+	if query == nil {
+		query = bson.M{}
+	}
+	if !include {
+		query["deletedat"] = nil
+	}
+
+	return query
+}
+
+func NotDeleted(query bson.M) bson.M {
+	return DeletedFilter(query, false)
+}
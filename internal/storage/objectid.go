@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// ObjectId is a document id: the 12 bytes of a BSON ObjectId in a string, or
+// "" for none, like mgo's bson.ObjectId. It's stored as an ObjectId and
+// answered in JSON as its hex form.
+type ObjectId string
+
+func NewObjectId() ObjectId {
+	id := primitive.NewObjectID()
+	return ObjectId(id[:])
+}
+
+// ObjectIdHex returns the ObjectId of the hex form s, which must be valid,
+// see IsObjectIdHex.
+func ObjectIdHex(s string) ObjectId {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 12 {
+		panic(fmt.Sprintf("invalid input to ObjectIdHex: %q", s))
+	}
+
+	return ObjectId(b)
+}
+
+func IsObjectIdHex(s string) bool {
+	if len(s) != 24 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+
+	return err == nil
+}
+
+func (id ObjectId) Hex() string {
+	return hex.EncodeToString([]byte(id))
+}
+
+func (id ObjectId) String() string {
+	return fmt.Sprintf("ObjectIdHex(%q)", id.Hex())
+}
+
+func (id ObjectId) Valid() bool {
+	return len(id) == 12
+}
+
+func (id ObjectId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.Hex())
+}
+
+func (id *ObjectId) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*id = ""
+		return nil
+	}
+	if !IsObjectIdHex(s) {
+		return fmt.Errorf("invalid ObjectId in JSON: %s", string(data))
+	}
+	*id = ObjectIdHex(s)
+
+	return nil
+}
+
+// MarshalBSONValue stores id as an ObjectId, or null when it's "".
+func (id ObjectId) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if id == "" {
+		return bsontype.Null, nil, nil
+	}
+	if !id.Valid() {
+		return 0, nil, fmt.Errorf("invalid ObjectId %q", string(id))
+	}
+
+	return bsontype.ObjectID, []byte(id), nil
+}
+
+func (id *ObjectId) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*id = ""
+	case bsontype.ObjectID:
+		oid, _, ok := bsoncore.ReadObjectID(data)
+		if !ok {
+			return fmt.Errorf("invalid ObjectId")
+		}
+		*id = ObjectId(oid[:])
+	default:
+		return fmt.Errorf("cannot decode %v into an ObjectId", t)
+	}
+
+	return nil
+}
+
+func (id ObjectId) objectID() primitive.ObjectID {
+	var oid primitive.ObjectID
+	copy(oid[:], id)
+
+	return oid
+}
+
+// objectId converts the id the driver generated for a document.
+func objectId(v interface{}) interface{} {
+	if oid, ok := v.(primitive.ObjectID); ok {
+		return ObjectId(oid[:])
+	}
+
+	return v
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Organizations
+//
+// Each organization only sees its own documents in the collections
+// registered with ScopeByOrg. A session bound to a context carrying an
+// organization, see WithOrg, only matches the documents of that
+// organization in the queries, updates and removals it runs there, and
+// stamps the documents it inserts or replaces with it in their orgid field.
+// The organization "" is the default one, whose documents have no orgid.
+// Sessions bound to a context without an organization, like those of
+// background jobs, see every organization.
+type orgKey struct{}
+
+// orgScope is the organization of a context, if it's scoped.
+type orgScope struct {
+	id     string
+	scoped bool
+}
+
+var orgCollections = map[string]bool{}
+
+// ScopeByOrg registers the collections whose documents belong to an
+// organization. It's called once, before serving.
+func ScopeByOrg(names ...string) {
+	for _, name := range names {
+		orgCollections[name] = true
+	}
+}
+
+// WithOrg returns ctx scoped to the organization with id org.
+func WithOrg(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgScope{org, true})
+}
+
+// WithoutOrg returns ctx seeing every organization, for looking up what
+// tells which organization a request is of.
+func WithoutOrg(ctx context.Context) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgScope{})
+}
+
+// OrgOf returns the organization ctx is scoped to, if it is.
+func OrgOf(ctx context.Context) (string, bool) {
+	scope, _ := ctx.Value(orgKey{}).(orgScope)
+	return scope.id, scope.scoped
+}
+
+// Org returns the organization d is scoped to, if it is.
+func (d *Database) Org() (string, bool) {
+	return OrgOf(d.Session.ctx)
+}
+
+// InOrg reports whether a document of the organization org is seen in ctx.
+func InOrg(ctx context.Context, org string) bool {
+	scope, ok := OrgOf(ctx)
+	return !ok || scope == org
+}
+
+// OrgFor returns the organization to store a document of org under in ctx,
+// the one ctx is scoped to if it is.
+func OrgFor(ctx context.Context, org string) string {
+	if scope, ok := OrgOf(ctx); ok {
+		return scope
+	}
+
+	return org
+}
+
+// detachOrg returns a background context scoped like ctx.
+func detachOrg(ctx context.Context) context.Context {
+	if scope, ok := ctx.Value(orgKey{}).(orgScope); ok {
+		return context.WithValue(context.Background(), orgKey{}, scope)
+	}
+
+	return context.Background()
+}
+
+// orgFilter returns the filter matching the documents of the organization c
+// is scoped to, if it is.
+func (c *Collection) orgFilter() (bson.M, bool) {
+	if !orgCollections[c.Name] {
+		return nil, false
+	}
+	org, ok := OrgOf(c.Database.Session.ctx)
+	if !ok {
+		return nil, false
+	}
+	if org == "" {
+		return bson.M{"orgid": nil}, true
+	}
+
+	return bson.M{"orgid": org}, true
+}
+
+// scope restricts selector to the organization of c.
+func (c *Collection) scope(selector interface{}) interface{} {
+	org, ok := c.orgFilter()
+	if !ok {
+		return filter(selector)
+	}
+
+	return bson.M{"$and": []interface{}{filter(selector), org}}
+}
+
+// stamp returns doc, a document being inserted or replacing another, with
+// the organization of c in its orgid field.
+func (c *Collection) stamp(doc interface{}) (interface{}, error) {
+	if _, ok := c.orgFilter(); !ok {
+		return doc, nil
+	}
+	org, _ := OrgOf(c.Database.Session.ctx)
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	fields := bson.D{}
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	result := bson.D{}
+	for _, field := range fields {
+		if field.Key != "orgid" {
+			result = append(result, field)
+		}
+	}
+	if org != "" {
+		result = append(result, bson.E{Key: "orgid", Value: org})
+	}
+
+	return result, nil
+}
+
+// scopePipeline starts pipeline with matching the organization of c.
+func (c *Collection) scopePipeline(pipeline interface{}) interface{} {
+	org, ok := c.orgFilter()
+	if !ok {
+		return pipeline
+	}
+
+	stages := bson.A{bson.M{"$match": org}}
+	switch p := pipeline.(type) {
+	case []bson.M:
+		for _, stage := range p {
+			stages = append(stages, stage)
+		}
+	case []bson.D:
+		for _, stage := range p {
+			stages = append(stages, stage)
+		}
+	case []interface{}:
+		stages = append(stages, p...)
+	case bson.A:
+		stages = append(stages, p...)
+	default:
+		panic("storage: unsupported pipeline type for an organization's collection")
+	}
+
+	return stages
+}
@@ -0,0 +1,31 @@
+package storage
+
+// Policy is how a repository runs its reads and reports the errors of its
+// writes. The app retries reads through a failover and counts what it sees,
+// see app/web/failover.go; the zero Policy reads once and translates errors
+// with Error.
+type Policy struct {
+	// RetryRead runs read, retrying it while it fails with a transient
+	// error.
+	RetryRead func(read func() error) error
+	// WriteError translates the driver error of a write.
+	WriteError func(err error) error
+}
+
+// Read runs read with RetryRead.
+func (p Policy) Read(read func() error) error {
+	if p.RetryRead == nil {
+		return read()
+	}
+
+	return p.RetryRead(read)
+}
+
+// Write translates err, the error of a write, with WriteError.
+func (p Policy) Write(err error) error {
+	if p.WriteError == nil {
+		return Error(err)
+	}
+
+	return p.WriteError(err)
+}
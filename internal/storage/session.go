@@ -0,0 +1,565 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Session is a connection pool to MongoDB, and the context and timeout its
+// operations run with. Copies share the pool of the session Dial returned,
+// which closing that one disconnects.
+type Session struct {
+	client  *mongo.Client
+	root    bool
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// Dial connects to the MongoDB of config. Reads go to the primary, so they
+// see the writes before them.
+func Dial(config Config) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, config.clientOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return &Session{client: client, root: true, ctx: context.Background()}, nil
+}
+
+// Copy returns a session on the same pool, not bound to the context or
+// timeout of s but scoped to its organization, see WithOrg.
+func (s *Session) Copy() *Session {
+	return &Session{client: s.client, ctx: detachOrg(s.ctx)}
+}
+
+// WithContext returns a copy of s whose operations are canceled with ctx.
+func (s *Session) WithContext(ctx context.Context) *Session {
+	return &Session{client: s.client, ctx: ctx, timeout: s.timeout}
+}
+
+// SetTimeout limits each operation of s to d, 0 for no limit but the
+// context's.
+func (s *Session) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// Close disconnects the session Dial returned; closing a copy does nothing.
+func (s *Session) Close() {
+	if s.root {
+		s.client.Disconnect(context.Background())
+	}
+}
+
+func (s *Session) Ping() error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+func (s *Session) DB(name string) *Database {
+	return &Database{Session: s, Name: name, db: s.client.Database(name)}
+}
+
+func (s *Session) context() (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(s.ctx, s.timeout)
+	}
+
+	return context.WithCancel(s.ctx)
+}
+
+type Database struct {
+	Session *Session
+	Name    string
+	db      *mongo.Database
+}
+
+// With returns the database on session.
+func (d *Database) With(session *Session) *Database {
+	return session.DB(d.Name)
+}
+
+func (d *Database) C(name string) *Collection {
+	return &Collection{Database: d, Name: name, FullName: d.Name + "." + name, coll: d.db.Collection(name)}
+}
+
+type Collection struct {
+	Database *Database
+	Name     string
+	FullName string
+	coll     *mongo.Collection
+}
+
+// ChangeInfo is what a write changed.
+type ChangeInfo struct {
+	Updated    int
+	Removed    int
+	Matched    int
+	UpsertedId interface{}
+}
+
+// Index is an index of a collection. Keys prefixed with - are descending.
+// Partial indexes only the documents matching PartialFilter.
+type Index struct {
+	Key           []string
+	Unique        bool
+	Sparse        bool
+	ExpireAfter   time.Duration
+	Name          string
+	PartialFilter bson.M
+}
+
+// WithContext returns the collection on a copy of its session whose
+// operations are canceled with ctx.
+func (c *Collection) WithContext(ctx context.Context) *Collection {
+	return c.Database.With(c.Database.Session.WithContext(ctx)).C(c.Name)
+}
+
+func (c *Collection) context() (context.Context, context.CancelFunc) {
+	return c.Database.Session.context()
+}
+
+func (c *Collection) Find(query interface{}) *Query {
+	return &Query{coll: c, filter: c.scope(query)}
+}
+
+func (c *Collection) FindId(id interface{}) *Query {
+	return c.Find(bson.M{"_id": id})
+}
+
+func (c *Collection) Count() (int, error) {
+	return c.Find(nil).Count()
+}
+
+func (c *Collection) Insert(docs ...interface{}) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	for i, doc := range docs {
+		stamped, err := c.stamp(doc)
+		if err != nil {
+			return err
+		}
+		docs[i] = stamped
+	}
+	_, err := c.coll.InsertMany(ctx, docs)
+	return err
+}
+
+// Update changes the first document matching selector, failing with
+// ErrNotFound when there's none. update is either a document replacing it or
+// update operators such as $set.
+func (c *Collection) Update(selector interface{}, update interface{}) error {
+	info, err := c.update(selector, update, false)
+	if err == nil && info.Matched == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func (c *Collection) UpdateId(id interface{}, update interface{}) error {
+	return c.Update(bson.M{"_id": id}, update)
+}
+
+func (c *Collection) UpdateAll(selector interface{}, update interface{}) (*ChangeInfo, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.coll.UpdateMany(ctx, c.scope(selector), update)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeInfo{Updated: int(res.ModifiedCount), Matched: int(res.MatchedCount)}, nil
+}
+
+// Upsert is Update, inserting the document when none matches selector.
+func (c *Collection) Upsert(selector interface{}, update interface{}) (*ChangeInfo, error) {
+	return c.update(selector, update, true)
+}
+
+func (c *Collection) UpsertId(id interface{}, update interface{}) (*ChangeInfo, error) {
+	return c.Upsert(bson.M{"_id": id}, update)
+}
+
+func (c *Collection) update(selector interface{}, update interface{}, upsert bool) (*ChangeInfo, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	var res *mongo.UpdateResult
+	var err error
+	if isOperators(update) {
+		res, err = c.coll.UpdateOne(ctx, c.scope(selector), update, options.Update().SetUpsert(upsert))
+	} else {
+		update, err = c.stamp(update)
+		if err != nil {
+			return nil, err
+		}
+		res, err = c.coll.ReplaceOne(ctx, c.scope(selector), update, options.Replace().SetUpsert(upsert))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeInfo{Updated: int(res.ModifiedCount), Matched: int(res.MatchedCount), UpsertedId: objectId(res.UpsertedID)}, nil
+}
+
+// Remove deletes the first document matching selector, failing with
+// ErrNotFound when there's none.
+func (c *Collection) Remove(selector interface{}) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.coll.DeleteOne(ctx, c.scope(selector))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (c *Collection) RemoveId(id interface{}) error {
+	return c.Remove(bson.M{"_id": id})
+}
+
+func (c *Collection) RemoveAll(selector interface{}) (*ChangeInfo, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	res, err := c.coll.DeleteMany(ctx, c.scope(selector))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeInfo{Removed: int(res.DeletedCount), Matched: int(res.DeletedCount)}, nil
+}
+
+func (c *Collection) EnsureIndex(index Index) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	opts := options.Index().SetUnique(index.Unique).SetSparse(index.Sparse)
+	if index.ExpireAfter > 0 {
+		opts.SetExpireAfterSeconds(int32(index.ExpireAfter / time.Second))
+	}
+	if index.Name != "" {
+		opts.SetName(index.Name)
+	}
+	if index.PartialFilter != nil {
+		opts.SetPartialFilterExpression(index.PartialFilter)
+	}
+	_, err := c.coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: sortKeys(index.Key), Options: opts})
+
+	return err
+}
+
+func (c *Collection) EnsureIndexKey(key ...string) error {
+	return c.EnsureIndex(Index{Key: key})
+}
+
+// indexNotFound is the code the server answers dropping a missing index with.
+const indexNotFound = 27
+
+// DropIndex removes the index named name, doing nothing when there's none.
+func (c *Collection) DropIndex(name string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.coll.Indexes().DropOne(ctx, name)
+	if HasErrorCode(err, indexNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// Pipe runs the aggregation pipeline over the collection.
+func (c *Collection) Pipe(pipeline interface{}) *Pipe {
+	return &Pipe{coll: c, pipeline: pipeline}
+}
+
+type Pipe struct {
+	coll     *Collection
+	pipeline interface{}
+}
+
+func (p *Pipe) All(result interface{}) error {
+	ctx, cancel := p.coll.context()
+	defer cancel()
+
+	cursor, err := p.coll.coll.Aggregate(ctx, p.coll.scopePipeline(p.pipeline))
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(ctx, result)
+}
+
+// Query is a find, run by One, All, Count, Iter or Apply.
+type Query struct {
+	coll       *Collection
+	filter     interface{}
+	sort       bson.D
+	projection interface{}
+	skip       int64
+	limit      int64
+}
+
+// Sort orders the documents by fields, descending those prefixed with -.
+func (q *Query) Sort(fields ...string) *Query {
+	q.sort = sortKeys(fields)
+	return q
+}
+
+func (q *Query) Skip(n int) *Query {
+	q.skip = int64(n)
+	return q
+}
+
+func (q *Query) Limit(n int) *Query {
+	q.limit = int64(n)
+	return q
+}
+
+// Select keeps only the fields of selector, such as {"name": 1}.
+func (q *Query) Select(selector interface{}) *Query {
+	q.projection = selector
+	return q
+}
+
+func (q *Query) findOptions() *options.FindOptions {
+	opts := options.Find()
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.projection != nil {
+		opts.SetProjection(q.projection)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.limit > 0 {
+		opts.SetLimit(q.limit)
+	}
+
+	return opts
+}
+
+// One decodes the first document into result, failing with ErrNotFound when
+// there's none.
+func (q *Query) One(result interface{}) error {
+	ctx, cancel := q.coll.context()
+	defer cancel()
+
+	opts := options.FindOne()
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.projection != nil {
+		opts.SetProjection(q.projection)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	err := q.coll.coll.FindOne(ctx, q.filter, opts).Decode(result)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// All decodes the documents into result, a pointer to a slice.
+func (q *Query) All(result interface{}) error {
+	ctx, cancel := q.coll.context()
+	defer cancel()
+
+	cursor, err := q.coll.coll.Find(ctx, q.filter, q.findOptions())
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(ctx, result)
+}
+
+func (q *Query) Count() (int, error) {
+	ctx, cancel := q.coll.context()
+	defer cancel()
+
+	opts := options.Count()
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.limit > 0 {
+		opts.SetLimit(q.limit)
+	}
+	n, err := q.coll.coll.CountDocuments(ctx, q.filter, opts)
+
+	return int(n), err
+}
+
+// Iter returns the documents one at a time, for results too large to hold.
+func (q *Query) Iter() *Iter {
+	ctx, cancel := q.coll.context()
+	cursor, err := q.coll.coll.Find(ctx, q.filter, q.findOptions())
+
+	return &Iter{ctx: ctx, cancel: cancel, cursor: cursor, err: err}
+}
+
+// Change is the findAndModify Apply runs.
+type Change struct {
+	Update    interface{}
+	Upsert    bool
+	Remove    bool
+	ReturnNew bool
+}
+
+// Apply runs change on the first document of q and decodes it, before or
+// after the change, into result, failing with ErrNotFound when there's none.
+func (q *Query) Apply(change Change, result interface{}) (*ChangeInfo, error) {
+	ctx, cancel := q.coll.context()
+	defer cancel()
+
+	var res *mongo.SingleResult
+	switch {
+	case change.Remove:
+		opts := options.FindOneAndDelete()
+		if q.sort != nil {
+			opts.SetSort(q.sort)
+		}
+		res = q.coll.coll.FindOneAndDelete(ctx, q.filter, opts)
+	case isOperators(change.Update):
+		opts := options.FindOneAndUpdate().SetUpsert(change.Upsert)
+		if change.ReturnNew {
+			opts.SetReturnDocument(options.After)
+		}
+		if q.sort != nil {
+			opts.SetSort(q.sort)
+		}
+		res = q.coll.coll.FindOneAndUpdate(ctx, q.filter, change.Update, opts)
+	default:
+		replacement, err := q.coll.stamp(change.Update)
+		if err != nil {
+			return nil, err
+		}
+		opts := options.FindOneAndReplace().SetUpsert(change.Upsert)
+		if change.ReturnNew {
+			opts.SetReturnDocument(options.After)
+		}
+		if q.sort != nil {
+			opts.SetSort(q.sort)
+		}
+		res = q.coll.coll.FindOneAndReplace(ctx, q.filter, replacement, opts)
+	}
+
+	err := res.Decode(result)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if change.Remove {
+		return &ChangeInfo{Removed: 1, Matched: 1}, nil
+	}
+
+	return &ChangeInfo{Updated: 1, Matched: 1}, nil
+}
+
+type Iter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cursor *mongo.Cursor
+	err    error
+}
+
+// Next decodes the next document into result, returning false when there's
+// none left or it failed, see Close.
+func (it *Iter) Next(result interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.cursor.Next(it.ctx) {
+		it.err = it.cursor.Err()
+		return false
+	}
+	it.err = it.cursor.Decode(result)
+
+	return it.err == nil
+}
+
+func (it *Iter) Err() error {
+	return it.err
+}
+
+// Close releases the cursor and returns the error that ended the iteration,
+// if any.
+func (it *Iter) Close() error {
+	defer it.cancel()
+	if it.cursor != nil {
+		if err := it.cursor.Close(it.ctx); err != nil && it.err == nil {
+			it.err = err
+		}
+	}
+
+	return it.err
+}
+
+// filter is selector, or all documents when it's nil.
+func filter(selector interface{}) interface{} {
+	if selector == nil {
+		return bson.M{}
+	}
+
+	return selector
+}
+
+// isOperators reports whether update is update operators such as $set
+// rather than a replacement document.
+func isOperators(update interface{}) bool {
+	switch u := update.(type) {
+	case bson.M:
+		for key := range u {
+			return strings.HasPrefix(key, "$")
+		}
+	case map[string]interface{}:
+		for key := range u {
+			return strings.HasPrefix(key, "$")
+		}
+	case bson.D:
+		return len(u) > 0 && strings.HasPrefix(u[0].Key, "$")
+	}
+
+	return false
+}
+
+func sortKeys(fields []string) bson.D {
+	keys := bson.D{}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			keys = append(keys, bson.E{Key: field[1:], Value: -1})
+		} else {
+			keys = append(keys, bson.E{Key: strings.TrimPrefix(field, "+"), Value: 1})
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,100 @@
+// Package storage is what the repositories share over MongoDB: the session,
+// the errors they answer with instead of driver errors, and the parsing of
+// ids that come from requests.
+//
+// It's the first of the packages app/web is being split into; the domain
+// packages (venue, room, event) and the HTTP layer will build on it.
+//
+// The repositories were written against mgo, which is no longer maintained.
+// Session, Database, Collection and Query keep the part of mgo's API they
+// use, over the official driver (go.mongodb.org/mongo-driver), so they moved
+// over unchanged; see session.go. The documents keep their shape:
+//
+//   - fields are still named after the lowercased Go field, the default of
+//     both drivers, and bson tags mean the same
+//   - ObjectId is still a string holding the 12 bytes of the id, stored as
+//     an ObjectId, see objectid.go
+//   - times are still stored in UTC, but read back in UTC rather than in
+//     the local time zone
+//   - GridFS files keep the content type in metadata.contentType rather
+//     than contentType, see gridfs.go
+package storage
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DatabaseName is the database the app keeps its collections in.
+const DatabaseName = "ivana"
+
+var (
+	ErrNotFound            = errors.New("document not found")
+	ErrInvalidId           = errors.New("invalid object id")
+	ErrConflict            = errors.New("document conflicts with an existing one")
+	ErrRetryableWrite      = errors.New("write interrupted by a database failover")
+	ErrDatabaseUnavailable = errors.New("database unavailable")
+)
+
+// Error translates a driver error into a storage error.
+func Error(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+// HasErrorCode reports whether the server answered with one of codes.
+func HasErrorCode(err error, codes ...int) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	for _, code := range codes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsNetworkError reports whether err is the connection to the server
+// failing, or no server being reachable.
+func IsNetworkError(err error) bool {
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "server selection error")
+}
+
+// ParseObjectId parses id, which comes from a request and may be malformed.
+func ParseObjectId(id string) (ObjectId, error) {
+	if !IsObjectIdHex(id) {
+		return "", ErrInvalidId
+	}
+
+	return ObjectIdHex(id), nil
+}
+
+// FindIndex looks id, which comes from a request, up with find, which
+// returns the index of the document in a list held in memory or -1.
+func FindIndex(id string, find func(ObjectId) int) (int, error) {
+	oid, err := ParseObjectId(id)
+	if err != nil {
+		return -1, err
+	}
+	idx := find(oid)
+	if idx < 0 {
+		return -1, ErrNotFound
+	}
+
+	return idx, nil
+}
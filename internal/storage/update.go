@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Changes returns the update operators turning existing into doc, two
+// documents of the same struct type: $set for the fields doc changes and
+// $unset for the fields of the type it leaves out, empty ones omitted. The
+// fields the type doesn't have, written by other features, are left alone,
+// as are _id and orgid. It's nil when nothing changes.
+func Changes(existing interface{}, doc interface{}) (bson.M, error) {
+	before, err := bson.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	after, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := bson.Raw(after).Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	for _, element := range elements {
+		key := element.Key()
+		if key == "_id" || key == "orgid" {
+			continue
+		}
+		value, err := bson.Raw(before).LookupErr(key)
+		if err != nil || !value.Equal(element.Value()) {
+			set[key] = element.Value()
+		}
+	}
+	unset := bson.M{}
+	for _, key := range fieldKeys(reflect.TypeOf(doc)) {
+		if key == "_id" || key == "orgid" {
+			continue
+		}
+		if _, err := bson.Raw(after).LookupErr(key); err == nil {
+			continue
+		}
+		if _, err := bson.Raw(before).LookupErr(key); err == nil {
+			unset[key] = ""
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil, nil
+	}
+
+	return update, nil
+}
+
+// fieldKeys returns the keys the fields of the struct type t are stored
+// under.
+func fieldKeys(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	keys := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("bson"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		inline := false
+		for _, option := range tag[1:] {
+			inline = inline || option == "inline"
+		}
+		if inline {
+			keys = append(keys, fieldKeys(field.Type)...)
+			continue
+		}
+		if tag[0] != "" {
+			keys = append(keys, tag[0])
+			continue
+		}
+		keys = append(keys, strings.ToLower(field.Name))
+	}
+
+	return keys
+}
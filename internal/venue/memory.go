@@ -0,0 +1,209 @@
+package venue
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Venue in memory
+//
+// MemRepository behaves like MongoRepository: deletes are soft and lookups
+// leave deleted venues out. It never blocks, so it only reads the
+// organization to keep to from ctx, see storage.WithOrg.
+type MemRepository struct {
+	mu     sync.Mutex
+	venues []Venue
+}
+
+func NewMemRepository() *MemRepository {
+	return &MemRepository{}
+}
+
+// index returns the lookup of the venues seen in ctx by id.
+func (r *MemRepository) index(ctx context.Context) func(storage.ObjectId) int {
+	return func(id storage.ObjectId) int {
+		for idx, venue := range r.venues {
+			if venue.Id == id && venue.DeletedAt == nil && storage.InOrg(ctx, venue.OrgId) {
+				return idx
+			}
+		}
+
+		return -1
+	}
+}
+
+func (r *MemRepository) findBy(ctx context.Context, match func(Venue) bool) (Venue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, venue := range r.venues {
+		if venue.DeletedAt == nil && storage.InOrg(ctx, venue.OrgId) && match(venue) {
+			return venue, nil
+		}
+	}
+
+	return Venue{}, storage.ErrNotFound
+}
+
+func (r *MemRepository) All(ctx context.Context, opts storage.ListOptions) ([]Venue, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Venue{}
+	for _, venue := range r.venues {
+		if (venue.DeletedAt == nil || opts.IncludeDeleted) && storage.InOrg(ctx, venue.OrgId) {
+			result = append(result, venue)
+		}
+	}
+	lo, hi := opts.ApplySlice(len(result), func(i int, j int, field string) int {
+		switch field {
+		case "name":
+			return strings.Compare(result[i].Name, result[j].Name)
+		case "slug":
+			return strings.Compare(result[i].Slug, result[j].Slug)
+		}
+		return 0
+	}, func(i int, j int) { result[i], result[j] = result[j], result[i] })
+
+	return result[lo:hi], len(result), nil
+}
+
+func (r *MemRepository) AllByIds(ctx context.Context, ids []string) ([]Venue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []Venue{}
+	for _, venue := range r.venues {
+		if venue.DeletedAt == nil && storage.InOrg(ctx, venue.OrgId) && contains(ids, venue.Id.Hex()) {
+			result = append(result, venue)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *MemRepository) Find(ctx context.Context, id string) (Venue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return Venue{}, err
+	}
+
+	return r.venues[idx], nil
+}
+
+func (r *MemRepository) FindBySlug(ctx context.Context, slug string) (Venue, error) {
+	return r.findBy(ctx, func(venue Venue) bool { return venue.Slug == slug })
+}
+
+func (r *MemRepository) FindByOldSlug(ctx context.Context, slug string) (Venue, error) {
+	return r.findBy(ctx, func(venue Venue) bool { return contains(venue.OldSlugs, slug) })
+}
+
+func (r *MemRepository) SlugTaken(ctx context.Context, slug string, exceptId storage.ObjectId) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, venue := range r.venues {
+		if venue.Id != exceptId && storage.InOrg(ctx, venue.OrgId) && (venue.Slug == slug || contains(venue.OldSlugs, slug)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *MemRepository) Create(ctx context.Context, venue *Venue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	venue.Id = storage.NewObjectId()
+	venue.OrgId = storage.OrgFor(ctx, venue.OrgId)
+	r.venues = append(r.venues, *venue)
+
+	return nil
+}
+
+func (r *MemRepository) Update(ctx context.Context, venue *Venue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx := range r.venues {
+		if r.venues[idx].Id == venue.Id && storage.InOrg(ctx, r.venues[idx].OrgId) {
+			venue.OrgId = r.venues[idx].OrgId
+			r.venues[idx] = *venue
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// Patch is Update, the repo keeps whole documents.
+func (r *MemRepository) Patch(ctx context.Context, venue *Venue, existing Venue) error {
+	return r.Update(ctx, venue)
+}
+
+func (r *MemRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, err := storage.FindIndex(id, r.index(ctx))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	r.venues[idx].DeletedAt = &now
+
+	return nil
+}
+
+// Restore brings a deleted venue back.
+func (r *MemRepository) Restore(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	for idx := range r.venues {
+		if r.venues[idx].Id == oid && r.venues[idx].DeletedAt != nil && storage.InOrg(ctx, r.venues[idx].OrgId) {
+			r.venues[idx].DeletedAt = nil
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+// Remove deletes the venue with id for good, undoing its creation.
+func (r *MemRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx := range r.venues {
+		if r.venues[idx].Id == id && storage.InOrg(ctx, r.venues[idx].OrgId) {
+			r.venues = append(r.venues[:idx], r.venues[idx+1:]...)
+			return nil
+		}
+	}
+
+	return storage.ErrNotFound
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
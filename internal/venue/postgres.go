@@ -0,0 +1,202 @@
+package venue
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/postgres"
+	"github.com/ivansaputr4/ivana/internal/storage"
+)
+
+// Repo Venue in PostgreSQL
+//
+// PostgresRepository behaves like MongoRepository over the venues table, see
+// internal/postgres.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db}
+}
+
+// sortColumns are the columns of the stored fields venues sort by.
+var sortColumns = map[string]string{"name": "name", "slug": "slug"}
+
+// query returns the venues of the rows of query.
+func (r *PostgresRepository) query(ctx context.Context, query string, args ...interface{}) ([]Venue, error) {
+	result := []Venue{}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc []byte
+		var deletedAt *time.Time
+		if err := rows.Scan(&doc, &deletedAt); err != nil {
+			return result, err
+		}
+		venue := Venue{}
+		if err := postgres.Unmarshal(doc, &venue); err != nil {
+			return result, err
+		}
+		venue.DeletedAt = deletedAt
+		result = append(result, venue)
+	}
+
+	return result, rows.Err()
+}
+
+// one is query for a single venue.
+func (r *PostgresRepository) one(ctx context.Context, query string, args ...interface{}) (Venue, error) {
+	venues, err := r.query(ctx, query, args...)
+	if err != nil {
+		return Venue{}, err
+	}
+	if len(venues) == 0 {
+		return Venue{}, storage.ErrNotFound
+	}
+
+	return venues[0], nil
+}
+
+func (r *PostgresRepository) All(ctx context.Context, opts storage.ListOptions) ([]Venue, int, error) {
+	params := postgres.Params{}
+	where := ` WHERE deleted_at IS NULL AND ` + params.Org(ctx)
+	if opts.IncludeDeleted {
+		where = ` WHERE ` + params.Org(ctx)
+	}
+
+	total := 0
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM venues`+where, params.Args...).Scan(&total)
+	if err != nil {
+		return []Venue{}, 0, err
+	}
+	result, err := r.query(ctx, `SELECT doc, deleted_at FROM venues`+where+postgres.Page(opts, sortColumns), params.Args...)
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *PostgresRepository) AllByIds(ctx context.Context, ids []string) ([]Venue, error) {
+	params := postgres.Params{}
+	query := `SELECT doc, deleted_at FROM venues WHERE id IN (` + params.In(ids) + `) AND deleted_at IS NULL AND ` + params.Org(ctx) + ` ORDER BY id`
+
+	return r.query(ctx, query, params.Args...)
+}
+
+func (r *PostgresRepository) Find(ctx context.Context, id string) (Venue, error) {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return Venue{}, err
+	}
+
+	params := postgres.Params{}
+	return r.one(ctx, `SELECT doc, deleted_at FROM venues WHERE id = `+params.Add(oid.Hex())+` AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...)
+}
+
+func (r *PostgresRepository) FindBySlug(ctx context.Context, slug string) (Venue, error) {
+	params := postgres.Params{}
+	return r.one(ctx, `SELECT doc, deleted_at FROM venues WHERE slug = `+params.Add(slug)+` AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...)
+}
+
+// FindByOldSlug and SlugTaken look through the old slugs of every venue,
+// which aren't a column of their own.
+func (r *PostgresRepository) FindByOldSlug(ctx context.Context, slug string) (Venue, error) {
+	params := postgres.Params{}
+	venues, err := r.query(ctx, `SELECT doc, deleted_at FROM venues WHERE deleted_at IS NULL AND `+params.Org(ctx)+` ORDER BY id`, params.Args...)
+	if err != nil {
+		return Venue{}, err
+	}
+	for _, venue := range venues {
+		if contains(venue.OldSlugs, slug) {
+			return venue, nil
+		}
+	}
+
+	return Venue{}, storage.ErrNotFound
+}
+
+func (r *PostgresRepository) SlugTaken(ctx context.Context, slug string, exceptId storage.ObjectId) (bool, error) {
+	params := postgres.Params{}
+	venues, err := r.query(ctx, `SELECT doc, deleted_at FROM venues WHERE id <> `+params.Add(exceptId.Hex())+` AND `+params.Org(ctx), params.Args...)
+	if err != nil {
+		return false, err
+	}
+	for _, venue := range venues {
+		if venue.Slug == slug || contains(venue.OldSlugs, slug) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, venue *Venue) error {
+	created := *venue
+	created.Id = storage.NewObjectId()
+	created.OrgId = storage.OrgFor(ctx, venue.OrgId)
+	doc, err := postgres.Marshal(created)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO venues (id, name, slug, deleted_at, doc, org_id) VALUES ($1, $2, $3, $4, $5, $6)`,
+		created.Id.Hex(), created.Name, created.Slug, created.DeletedAt, doc, created.OrgId)
+	if err != nil {
+		return postgres.Error(err)
+	}
+
+	venue.Id = created.Id
+	venue.OrgId = created.OrgId
+
+	return nil
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, venue *Venue) error {
+	venue.OrgId = storage.OrgFor(ctx, venue.OrgId)
+	doc, err := postgres.Marshal(venue)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{venue.Id.Hex(), venue.Name, venue.Slug, venue.DeletedAt, doc}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE venues SET name = $2, slug = $3, deleted_at = $4, doc = $5 WHERE id = $1 AND `+params.Org(ctx),
+		params.Args...))
+}
+
+// Patch is Update, the repo writes the whole document of a row.
+func (r *PostgresRepository) Patch(ctx context.Context, venue *Venue, existing Venue) error {
+	return r.Update(ctx, venue)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{oid.Hex(), time.Now()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE venues SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL AND `+params.Org(ctx), params.Args...))
+}
+
+// Restore brings a deleted venue back.
+func (r *PostgresRepository) Restore(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+
+	params := postgres.Params{Args: []interface{}{oid.Hex()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `UPDATE venues SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND `+params.Org(ctx), params.Args...))
+}
+
+// Remove deletes the venue with id for good, undoing its creation.
+func (r *PostgresRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	params := postgres.Params{Args: []interface{}{id.Hex()}}
+	return postgres.Affected(r.db.ExecContext(ctx, `DELETE FROM venues WHERE id = $1 AND `+params.Org(ctx), params.Args...))
+}
@@ -0,0 +1,296 @@
+// Package venue holds venues, the sites rooms are in, and their
+// repositories: MongoRepository over the venues collection,
+// PostgresRepository over the venues table and MemRepository in memory. The
+// handlers of app/web reach them through Repository.
+package venue
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivansaputr4/ivana/internal/room"
+	"github.com/ivansaputr4/ivana/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Venue struct {
+	Id       storage.ObjectId `json:"id,omitempty" bson:"_id,omitempty"`
+	Name     string           `json:"name"`
+	Slug     string           `json:"slug"`
+	OldSlugs []string         `json:"-"`
+	Rooms    []room.Room      `json:"rooms,omitempty"`
+
+	// TimeZone is where the venue is, see app/web/timezone.go.
+	TimeZone string `json:"time_zone,omitempty" bson:",omitempty"`
+
+	// OpeningHours and BookingRules limit when its rooms can be booked, see
+	// app/web/openinghours.go.
+	OpeningHours *OpeningHours `json:"opening_hours,omitempty" bson:",omitempty"`
+	BookingRules *BookingRules `json:"booking_rules,omitempty" bson:",omitempty"`
+
+	// Translations by language tag, see app/web/localized.go.
+	Names        map[string]string `json:"names,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Descriptions map[string]string `json:"descriptions,omitempty"`
+
+	// Listed venues summarize their rooms, see app/web/venuesummary.go.
+	RoomsCount        *int      `json:"rooms_count,omitempty" bson:"-"`
+	NextAvailableRoom *RoomHint `json:"next_available_room,omitempty" bson:"-"`
+
+	// OrgId is the organization the venue belongs to, see storage.ScopeByOrg.
+	OrgId string `json:"-" bson:"orgid,omitempty"`
+
+	// DeletedAt is set on deleted venues, see storage.DeletedFilter.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:",omitempty"`
+}
+
+// OpeningHours are the times a venue is open each weekday, as "15:04" in its
+// time zone, and the dates, as "2006-01-02", it's closed all day. Without
+// days it's open at any time but on holidays.
+type OpeningHours struct {
+	Days     []OpeningDay `json:"days"`
+	Holidays []string     `json:"holidays,omitempty"`
+}
+
+type OpeningDay struct {
+	Weekday time.Weekday `json:"weekday"`
+	Open    string       `json:"open"`
+	Close   string       `json:"close"`
+}
+
+// Covers reports whether the venue is open for all of [start_time, end_time)
+// in loc, within a single day.
+func (h OpeningHours) Covers(start_time time.Time, end_time time.Time, loc *time.Location) bool {
+	start_time, end_time = start_time.In(loc), end_time.In(loc)
+	for _, holiday := range h.Holidays {
+		if holiday == start_time.Format("2006-01-02") {
+			return false
+		}
+	}
+	if len(h.Days) == 0 {
+		return true
+	}
+
+	for _, day := range h.Days {
+		if day.Weekday != start_time.Weekday() {
+			continue
+		}
+		open, err1 := time.ParseInLocation("15:04", day.Open, loc)
+		close, err2 := time.ParseInLocation("15:04", day.Close, loc)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		y, m, d := start_time.Date()
+		open = time.Date(y, m, d, open.Hour(), open.Minute(), 0, 0, loc)
+		close = time.Date(y, m, d, close.Hour(), close.Minute(), 0, 0, loc)
+		if !start_time.Before(open) && !end_time.After(close) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BookingRules limit how far ahead, in days, and for how long, in minutes,
+// the rooms of a venue can be booked. Zero leaves a limit out.
+type BookingRules struct {
+	MaxAdvanceDays int `json:"max_advance_days,omitempty"`
+	MinDuration    int `json:"min_duration,omitempty"`
+	MaxDuration    int `json:"max_duration,omitempty"`
+}
+
+// RoomHint is a room of a listed venue that's free now, or soonest.
+type RoomHint struct {
+	RoomId      string            `json:"room_id"`
+	Name        string            `json:"name"`
+	Names       map[string]string `json:"-"`
+	AvailableAt time.Time         `json:"available_at"`
+}
+
+type Repository interface {
+	All(ctx context.Context, opts storage.ListOptions) ([]Venue, int, error)
+	AllByIds(ctx context.Context, ids []string) ([]Venue, error)
+	Find(ctx context.Context, id string) (Venue, error)
+	FindBySlug(ctx context.Context, slug string) (Venue, error)
+	FindByOldSlug(ctx context.Context, slug string) (Venue, error)
+	SlugTaken(ctx context.Context, slug string, exceptId storage.ObjectId) (bool, error)
+	Create(ctx context.Context, venue *Venue) error
+	Update(ctx context.Context, venue *Venue) error
+	Patch(ctx context.Context, venue *Venue, existing Venue) error
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	Remove(ctx context.Context, id storage.ObjectId) error
+}
+
+// Repo Venue
+type MongoRepository struct {
+	coll   *storage.Collection
+	policy storage.Policy
+}
+
+func NewMongoRepository(coll *storage.Collection, policy storage.Policy) *MongoRepository {
+	return &MongoRepository{coll, policy}
+}
+
+// All returns the venues on the page opts asks for, and how many there are in
+// total.
+func (r *MongoRepository) All(ctx context.Context, opts storage.ListOptions) ([]Venue, int, error) {
+	result := []Venue{}
+	total := 0
+	err := r.policy.Read(func() (err error) {
+		query := r.coll.WithContext(ctx).Find(storage.DeletedFilter(nil, opts.IncludeDeleted))
+		total, err = query.Count()
+		if err != nil {
+			return err
+		}
+
+		return opts.Apply(query).All(&result)
+	})
+	if err != nil {
+		return result, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *MongoRepository) Find(ctx context.Context, id string) (Venue, error) {
+	result := Venue{}
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return result, err
+	}
+	err = r.policy.Read(func() error { return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"_id": oid})).One(&result) })
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+// AllByIds returns the venues with ids.
+func (r *MongoRepository) AllByIds(ctx context.Context, ids []string) ([]Venue, error) {
+	oids := []storage.ObjectId{}
+	for _, id := range ids {
+		if storage.IsObjectIdHex(id) {
+			oids = append(oids, storage.ObjectIdHex(id))
+		}
+	}
+	result := []Venue{}
+	err := r.policy.Read(func() error {
+		return r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"_id": bson.M{"$in": oids}})).All(&result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) Create(ctx context.Context, venue *Venue) error {
+	id := storage.NewObjectId()
+	_, err := r.coll.WithContext(ctx).UpsertId(id, venue)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	venue.Id = id
+
+	return nil
+}
+
+func (r *MongoRepository) Update(ctx context.Context, venue *Venue) error {
+	err := r.coll.WithContext(ctx).UpdateId(venue.Id, venue)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Patch writes the fields venue changes from existing, the venue as it was
+// read, leaving the others alone, see storage.Changes.
+func (r *MongoRepository) Patch(ctx context.Context, venue *Venue, existing Venue) error {
+	update, err := storage.Changes(existing, venue)
+	if err != nil || update == nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).UpdateId(venue.Id, update)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Delete(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(storage.NotDeleted(bson.M{"_id": oid}), bson.M{"$set": bson.M{"deletedat": time.Now()}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Restore brings a deleted venue back.
+func (r *MongoRepository) Restore(ctx context.Context, id string) error {
+	oid, err := storage.ParseObjectId(id)
+	if err != nil {
+		return err
+	}
+	err = r.coll.WithContext(ctx).Update(bson.M{"_id": oid, "deletedat": bson.M{"$ne": nil}}, bson.M{"$unset": bson.M{"deletedat": ""}})
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
+
+// Repo Venue slugs
+func (r *MongoRepository) FindBySlug(ctx context.Context, slug string) (Venue, error) {
+	result := Venue{}
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"slug": slug})).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) FindByOldSlug(ctx context.Context, slug string) (Venue, error) {
+	result := Venue{}
+	err := r.coll.WithContext(ctx).Find(storage.NotDeleted(bson.M{"oldslugs": slug})).One(&result)
+	if err != nil {
+		return result, storage.Error(err)
+	}
+
+	return result, nil
+}
+
+func (r *MongoRepository) SlugTaken(ctx context.Context, slug string, exceptId storage.ObjectId) (bool, error) {
+	query := bson.M{"$or": []bson.M{{"slug": slug}, {"oldslugs": slug}}}
+	if exceptId != "" {
+		query["_id"] = bson.M{"$ne": exceptId}
+	}
+
+	n, err := r.coll.WithContext(ctx).Find(query).Count()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Remove deletes the venue with id for good, undoing its creation.
+func (r *MongoRepository) Remove(ctx context.Context, id storage.ObjectId) error {
+	err := r.coll.WithContext(ctx).RemoveId(id)
+	if err != nil {
+		return r.policy.Write(err)
+	}
+
+	return nil
+}
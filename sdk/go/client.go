@@ -0,0 +1,1428 @@
+// Code generated by sdkgen from app/web; DO NOT EDIT.
+
+// Package ivana is a client for the ivana booking API.
+package ivana
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient, Header: http.Header{}}
+}
+
+type Error struct {
+	Id     string `json:"id"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// APIError is returned for responses with a 4xx or 5xx status.
+type APIError struct {
+	Status int
+	Errors []Error `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("ivana: %d %s: %s", e.Status, e.Errors[0].Id, e.Errors[0].Detail)
+	}
+	return fmt.Sprintf("ivana: %d", e.Status)
+}
+
+func (c *Client) do(method string, path string, query url.Values, body interface{}) (json.RawMessage, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil && res.StatusCode < 400 {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		apiErr := &APIError{Status: res.StatusCode}
+		json.Unmarshal(raw, apiErr)
+		return nil, apiErr
+	}
+
+	return raw, nil
+}
+
+type Announcement struct {
+	Body         string     `json:"body,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	DisplayFrom  *time.Time `json:"display_from,omitempty"`
+	DisplayUntil *time.Time `json:"display_until,omitempty"`
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	Kind         string     `json:"kind,omitempty"`
+	StartTime    *time.Time `json:"start_time,omitempty"`
+	Title        string     `json:"title"`
+}
+
+type ApiKey struct {
+	Name   string `json:"name"`
+	RoomId string `json:"room_id,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+	User   string `json:"user,omitempty"`
+}
+
+type BookNow struct {
+	Duration int    `json:"duration,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+type BookingLink struct {
+	AutoConfirm bool `json:"auto_confirm,omitempty"`
+	DaysAhead   int  `json:"days_ahead,omitempty"`
+}
+
+type BookingRequest struct {
+	Company     string     `json:"company,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Email       string     `json:"email"`
+	EndTime     *time.Time `json:"end_time"`
+	Name        string     `json:"name"`
+	StartTime   *time.Time `json:"start_time"`
+	Title       string     `json:"title,omitempty"`
+}
+
+type Chaos struct {
+	FailReads   int        `json:"fail_reads,omitempty"`
+	FailWrites  int        `json:"fail_writes,omitempty"`
+	FrozenAt    *time.Time `json:"frozen_at,omitempty"`
+	LatencyMs   int        `json:"latency_ms,omitempty"`
+	LatencyPath string     `json:"latency_path,omitempty"`
+}
+
+type CheckIn struct {
+	Code   string `json:"code"`
+	RoomId string `json:"room_id"`
+}
+
+type Desk struct {
+	Amenities      []string `json:"amenities,omitempty"`
+	Label          string   `json:"label"`
+	NeighborhoodId string   `json:"neighborhood_id,omitempty"`
+	X              float64  `json:"x"`
+	Y              float64  `json:"y"`
+}
+
+type DeskBooking struct {
+	Date string `json:"date"`
+	Slot string `json:"slot"`
+	Team string `json:"team,omitempty"`
+	User string `json:"user"`
+}
+
+type Equipment struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	VenueId  string `json:"venue_id"`
+}
+
+type Event struct {
+	Category     string              `json:"category,omitempty"`
+	Date         int                 `json:"date,omitempty"`
+	Description  string              `json:"description,omitempty"`
+	EndHour      int                 `json:"end_hour,omitempty"`
+	EndMinute    int                 `json:"end_minute,omitempty"`
+	EndTime      *time.Time          `json:"end_time,omitempty"`
+	Equipment    []EventEquipment    `json:"equipment,omitempty"`
+	GroupId      string              `json:"group_id,omitempty"`
+	Guests       []string            `json:"guests,omitempty"`
+	Location     string              `json:"location,omitempty"`
+	LocationId   string              `json:"location_id"`
+	Month        int                 `json:"month,omitempty"`
+	Name         string              `json:"name"`
+	Owner        string              `json:"owner,omitempty"`
+	Participants []EventParticipants `json:"participants,omitempty"`
+	Recurrence   *EventRecurrence    `json:"recurrence,omitempty"`
+	StartHour    int                 `json:"start_hour,omitempty"`
+	StartMinute  int                 `json:"start_minute,omitempty"`
+	StartTime    *time.Time          `json:"start_time,omitempty"`
+	Tentative    bool                `json:"tentative,omitempty"`
+	TimeZone     string              `json:"time_zone,omitempty"`
+	Upgrade      *EventUpgrade       `json:"upgrade,omitempty"`
+	Year         int                 `json:"year,omitempty"`
+}
+
+type EventEquipment struct {
+	EquipmentId string `json:"equipment_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+type EventParticipants struct {
+	Email    string `json:"email"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+type EventRecurrence struct {
+	Count      int         `json:"count,omitempty"`
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+	Frequency  string      `json:"frequency"`
+	Interval   int         `json:"interval,omitempty"`
+	Until      *time.Time  `json:"until,omitempty"`
+	WeekStart  string      `json:"week_start,omitempty"`
+	Weekdays   []int       `json:"weekdays,omitempty"`
+}
+
+type EventUpgrade struct {
+	MinCapacity int      `json:"min_capacity,omitempty"`
+	Rooms       []string `json:"rooms,omitempty"`
+}
+
+type EventCancellation struct {
+	Reason string `json:"reason"`
+}
+
+type EventExtension struct {
+	Minutes int `json:"minutes"`
+}
+
+type EventGroup struct {
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+type EventGroupReschedule struct {
+	OffsetMinutes int `json:"offset_minutes"`
+}
+
+type EventSeries struct {
+	Description string                 `json:"description,omitempty"`
+	EndTime     *time.Time             `json:"end_time"`
+	Guests      []string               `json:"guests,omitempty"`
+	Location    string                 `json:"location,omitempty"`
+	LocationId  string                 `json:"location_id"`
+	Name        string                 `json:"name"`
+	Owner       string                 `json:"owner,omitempty"`
+	Recurrence  *EventSeriesRecurrence `json:"recurrence"`
+	StartTime   *time.Time             `json:"start_time"`
+}
+
+type EventSeriesRecurrence struct {
+	Count      int         `json:"count,omitempty"`
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+	Frequency  string      `json:"frequency"`
+	Interval   int         `json:"interval,omitempty"`
+	Until      *time.Time  `json:"until,omitempty"`
+	WeekStart  string      `json:"week_start,omitempty"`
+	Weekdays   []int       `json:"weekdays,omitempty"`
+}
+
+type Feedback struct {
+	Attendee    string `json:"attendee"`
+	Av          int    `json:"av"`
+	Cleanliness int    `json:"cleanliness"`
+	Comfort     int    `json:"comfort"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+type Floor struct {
+	Height      float64 `json:"height,omitempty"`
+	Level       int     `json:"level,omitempty"`
+	MapImageUrl string  `json:"map_image_url,omitempty"`
+	Name        string  `json:"name"`
+	VenueId     string  `json:"venue_id"`
+	Width       float64 `json:"width,omitempty"`
+}
+
+type FreeBusy struct {
+	EndTime   *time.Time `json:"end_time"`
+	RoomIds   []string   `json:"room_ids,omitempty"`
+	StartTime *time.Time `json:"start_time"`
+	Users     []string   `json:"users,omitempty"`
+}
+
+type Graphql struct {
+	Query string `json:"query"`
+}
+
+type GuestToken struct {
+	Guest  string   `json:"guest"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+type InboundBooking struct {
+	Cancelled   bool       `json:"cancelled,omitempty"`
+	Description string     `json:"description,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	ExternalId  string     `json:"external_id"`
+	Guests      []string   `json:"guests,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	RoomId      string     `json:"room_id,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+}
+
+type LegalHold struct {
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Reason    string     `json:"reason"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	User      string     `json:"user,omitempty"`
+}
+
+type Neighborhood struct {
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+}
+
+type Organization struct {
+	Name string `json:"name"`
+}
+
+type PanelAction struct {
+	Minutes int    `json:"minutes,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+type PanelContent struct {
+	AttachmentId string     `json:"attachment_id,omitempty"`
+	Body         string     `json:"body,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	Duration     int        `json:"duration,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	ImageUrl     string     `json:"image_url,omitempty"`
+	Kind         string     `json:"kind"`
+	Priority     int        `json:"priority,omitempty"`
+	RoomIds      []string   `json:"room_ids,omitempty"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	Title        string     `json:"title,omitempty"`
+}
+
+type ParkingReservation struct {
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	EventId      string     `json:"event_id,omitempty"`
+	Kind         string     `json:"kind,omitempty"`
+	LicensePlate string     `json:"license_plate"`
+	SpotId       string     `json:"spot_id,omitempty"`
+	StartTime    *time.Time `json:"start_time,omitempty"`
+	VenueId      string     `json:"venue_id,omitempty"`
+	Visitor      string     `json:"visitor,omitempty"`
+}
+
+type ParkingSpot struct {
+	Kind    string `json:"kind,omitempty"`
+	Label   string `json:"label"`
+	VenueId string `json:"venue_id"`
+}
+
+type Room struct {
+	Amenities      []string               `json:"amenities,omitempty"`
+	Building       string                 `json:"building,omitempty"`
+	Capacity       int                    `json:"capacity"`
+	Description    string                 `json:"description,omitempty"`
+	Descriptions   map[string]interface{} `json:"descriptions,omitempty"`
+	Floor          string                 `json:"floor,omitempty"`
+	FloorId        string                 `json:"floor_id,omitempty"`
+	HighDemand     bool                   `json:"high_demand,omitempty"`
+	MapCoordinates *RoomMapCoordinates    `json:"map_coordinates,omitempty"`
+	Name           string                 `json:"name"`
+	Names          map[string]interface{} `json:"names,omitempty"`
+	PhotoUrl       string                 `json:"photo_url,omitempty"`
+	ReleaseAfter   int                    `json:"release_after,omitempty"`
+	Slug           string                 `json:"slug,omitempty"`
+	VenueId        string                 `json:"venue_id"`
+}
+
+type RoomMapCoordinates struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type RoomSuggestion struct {
+	Amenities []string   `json:"amenities,omitempty"`
+	Attendees int        `json:"attendees"`
+	EndTime   *time.Time `json:"end_time"`
+	Limit     int        `json:"limit,omitempty"`
+	StartTime *time.Time `json:"start_time"`
+	VenueId   string     `json:"venue_id,omitempty"`
+}
+
+type Rsvp struct {
+	Status string `json:"status"`
+}
+
+type TeamCap struct {
+	Mode        string  `json:"mode,omitempty"`
+	WeeklyHours float64 `json:"weekly_hours"`
+}
+
+type TravelTime struct {
+	FromVenueId string `json:"from_venue_id"`
+	Minutes     int    `json:"minutes"`
+	ToVenueId   string `json:"to_venue_id"`
+}
+
+type User struct {
+	HidePresence bool   `json:"hide_presence,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Role         string `json:"role,omitempty"`
+	Team         string `json:"team,omitempty"`
+}
+
+type Venue struct {
+	BookingRules *VenueBookingRules     `json:"booking_rules,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Descriptions map[string]interface{} `json:"descriptions,omitempty"`
+	Name         string                 `json:"name"`
+	Names        map[string]interface{} `json:"names,omitempty"`
+	OpeningHours *VenueOpeningHours     `json:"opening_hours,omitempty"`
+	Slug         string                 `json:"slug,omitempty"`
+	TimeZone     string                 `json:"time_zone,omitempty"`
+}
+
+type VenueBookingRules struct {
+	MaxAdvanceDays int `json:"max_advance_days,omitempty"`
+	MaxDuration    int `json:"max_duration,omitempty"`
+	MinDuration    int `json:"min_duration,omitempty"`
+}
+
+type VenueOpeningHours struct {
+	Days     []VenueOpeningHoursDays `json:"days,omitempty"`
+	Holidays []string                `json:"holidays,omitempty"`
+}
+
+type VenueOpeningHoursDays struct {
+	Close   string `json:"close"`
+	Open    string `json:"open"`
+	Weekday int    `json:"weekday"`
+}
+
+type VenueOnboarding struct {
+	Equipment    []VenueOnboardingEquipment    `json:"equipment,omitempty"`
+	Floors       []VenueOnboardingFloors       `json:"floors"`
+	Name         string                        `json:"name"`
+	ParkingSpots []VenueOnboardingParkingSpots `json:"parking_spots,omitempty"`
+	Slug         string                        `json:"slug,omitempty"`
+}
+
+type VenueOnboardingEquipment struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+type VenueOnboardingFloors struct {
+	Desks         []VenueOnboardingFloorsDesks         `json:"desks,omitempty"`
+	Height        float64                              `json:"height,omitempty"`
+	Level         int                                  `json:"level,omitempty"`
+	MapImageUrl   string                               `json:"map_image_url,omitempty"`
+	Name          string                               `json:"name"`
+	Neighborhoods []VenueOnboardingFloorsNeighborhoods `json:"neighborhoods,omitempty"`
+	Rooms         []VenueOnboardingFloorsRooms         `json:"rooms,omitempty"`
+	Width         float64                              `json:"width,omitempty"`
+}
+
+type VenueOnboardingFloorsDesks struct {
+	Amenities    []string `json:"amenities,omitempty"`
+	Label        string   `json:"label"`
+	Neighborhood string   `json:"neighborhood,omitempty"`
+	X            float64  `json:"x"`
+	Y            float64  `json:"y"`
+}
+
+type VenueOnboardingFloorsNeighborhoods struct {
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+}
+
+type VenueOnboardingFloorsRooms struct {
+	Capacity int    `json:"capacity"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug,omitempty"`
+}
+
+type VenueOnboardingParkingSpots struct {
+	Kind  string `json:"kind,omitempty"`
+	Label string `json:"label"`
+}
+
+type Webhook struct {
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+	Url    string   `json:"url"`
+}
+
+type WorkingHours struct {
+	Days     []WorkingHoursDays `json:"days"`
+	TimeZone string             `json:"time_zone,omitempty"`
+}
+
+type WorkingHoursDays struct {
+	End     string `json:"end"`
+	Start   string `json:"start"`
+	Weekday int    `json:"weekday"`
+}
+
+// Venue calls GET /venues/:id.
+func (c *Client) Venue(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateVenue calls PATCH /venues/:id.
+func (c *Client) UpdateVenue(id string, body *Venue, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/venues/"+url.PathEscape(id), query, body)
+}
+
+// DeleteVenue calls DELETE /venues/:id.
+func (c *Client) DeleteVenue(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/venues/"+url.PathEscape(id), query, nil)
+}
+
+// Venues calls GET /venues.
+func (c *Client) Venues(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues", query, nil)
+}
+
+// CreateVenue calls POST /venues.
+func (c *Client) CreateVenue(body *Venue, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/venues", query, body)
+}
+
+// RoomsVenue calls GET /venues/:id/rooms.
+func (c *Client) RoomsVenue(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/rooms", query, nil)
+}
+
+// VenueFloorPlans calls GET /venues/:id/floor-plans.
+func (c *Client) VenueFloorPlans(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/floor-plans", query, nil)
+}
+
+// VenuePhotos calls GET /venues/:id/photos.
+func (c *Client) VenuePhotos(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/photos", query, nil)
+}
+
+// UploadVenuePhoto calls POST /venues/:id/photos.
+func (c *Client) UploadVenuePhoto(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/venues/"+url.PathEscape(id)+"/photos", query, body)
+}
+
+// VenueRoom calls GET /venues/:id/rooms/:room.
+func (c *Client) VenueRoom(id string, room string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/rooms/"+url.PathEscape(room), query, nil)
+}
+
+// CompareRooms calls GET /rooms/compare.
+func (c *Client) CompareRooms(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/compare", query, nil)
+}
+
+// Room calls GET /rooms/:id.
+func (c *Client) Room(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateRoom calls PATCH /rooms/:id.
+func (c *Client) UpdateRoom(id string, body *Room, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/rooms/"+url.PathEscape(id), query, body)
+}
+
+// DeleteRoom calls DELETE /rooms/:id.
+func (c *Client) DeleteRoom(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/rooms/"+url.PathEscape(id), query, nil)
+}
+
+// BookNow calls POST /rooms/:id/book-now.
+func (c *Client) BookNow(id string, body *BookNow, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/book-now", query, body)
+}
+
+// RoomPhotos calls GET /rooms/:id/photos.
+func (c *Client) RoomPhotos(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/photos", query, nil)
+}
+
+// UploadRoomPhoto calls POST /rooms/:id/photos.
+func (c *Client) UploadRoomPhoto(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/photos", query, body)
+}
+
+// PhotoImage calls GET /photos/:id/image.
+func (c *Client) PhotoImage(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/photos/"+url.PathEscape(id)+"/image", query, nil)
+}
+
+// PhotoThumbnail calls GET /photos/:id/thumbnail.
+func (c *Client) PhotoThumbnail(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/photos/"+url.PathEscape(id)+"/thumbnail", query, nil)
+}
+
+// DeletePhoto calls DELETE /photos/:id.
+func (c *Client) DeletePhoto(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/photos/"+url.PathEscape(id), query, nil)
+}
+
+// Rooms calls GET /rooms.
+func (c *Client) Rooms(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms", query, nil)
+}
+
+// CreateRoom calls POST /rooms.
+func (c *Client) CreateRoom(body *Room, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms", query, body)
+}
+
+// SuggestRooms calls POST /rooms/suggest.
+func (c *Client) SuggestRooms(body *RoomSuggestion, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/suggest", query, body)
+}
+
+// SearchEvents calls GET /events/search.
+func (c *Client) SearchEvents(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/search", query, nil)
+}
+
+// ExportEvents calls GET /events/export.
+func (c *Client) ExportEvents(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/export", query, nil)
+}
+
+// Event calls GET /events/:id.
+func (c *Client) Event(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateEvent calls PATCH /events/:id.
+func (c *Client) UpdateEvent(id string, body *Event, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/events/"+url.PathEscape(id), query, body)
+}
+
+// DeleteEvent calls DELETE /events/:id.
+func (c *Client) DeleteEvent(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/events/"+url.PathEscape(id), query, nil)
+}
+
+// CancelEvent calls POST /events/:id/cancel.
+func (c *Client) CancelEvent(id string, body *EventCancellation, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/cancel", query, body)
+}
+
+// ExtendEvent calls POST /events/:id/extend.
+func (c *Client) ExtendEvent(id string, body *EventExtension, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/extend", query, body)
+}
+
+// EndEvent calls POST /events/:id/end.
+func (c *Client) EndEvent(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/end", query, body)
+}
+
+// CreateEvent calls POST /events.
+func (c *Client) CreateEvent(body *Event, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events", query, body)
+}
+
+// Events calls GET /events.
+func (c *Client) Events(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events", query, nil)
+}
+
+// ImportEvents calls POST /events/import.
+func (c *Client) ImportEvents(body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/import", query, body)
+}
+
+// EventImport calls GET /event-imports/:id.
+func (c *Client) EventImport(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/event-imports/"+url.PathEscape(id), query, nil)
+}
+
+// ConfirmEventImport calls POST /event-imports/:id/confirm.
+func (c *Client) ConfirmEventImport(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/event-imports/"+url.PathEscape(id)+"/confirm", query, body)
+}
+
+// RoomBookingLink calls GET /rooms/:id/booking-link.
+func (c *Client) RoomBookingLink(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/booking-link", query, nil)
+}
+
+// UpdateRoomBookingLink calls PUT /rooms/:id/booking-link.
+func (c *Client) UpdateRoomBookingLink(id string, body *BookingLink, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/rooms/"+url.PathEscape(id)+"/booking-link", query, body)
+}
+
+// DeleteRoomBookingLink calls DELETE /rooms/:id/booking-link.
+func (c *Client) DeleteRoomBookingLink(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/rooms/"+url.PathEscape(id)+"/booking-link", query, nil)
+}
+
+// BookingPage calls GET /book/:token.
+func (c *Client) BookingPage(token string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/book/"+url.PathEscape(token), query, nil)
+}
+
+// BookThroughLink calls POST /book/:token.
+func (c *Client) BookThroughLink(token string, body *BookingRequest, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/book/"+url.PathEscape(token), query, body)
+}
+
+// CreateEventRoomsEvents calls POST /rooms/:id/events.
+func (c *Client) CreateEventRoomsEvents(id string, body *Event, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/events", query, body)
+}
+
+// VenueEvents calls GET /venues/:id/events.
+func (c *Client) VenueEvents(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/events", query, nil)
+}
+
+// VenuePresence calls GET /venues/:id/presence.
+func (c *Client) VenuePresence(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/presence", query, nil)
+}
+
+// InboundBooking calls POST /integrations/inbound/:source.
+func (c *Client) InboundBooking(source string, body *InboundBooking, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/integrations/inbound/"+url.PathEscape(source), query, body)
+}
+
+// GraphNotifications calls POST /integrations/msgraph/notifications.
+func (c *Client) GraphNotifications(body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/integrations/msgraph/notifications", query, body)
+}
+
+// EquipmentAvailability calls GET /equipment/:id/availability.
+func (c *Client) EquipmentAvailability(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/equipment/"+url.PathEscape(id)+"/availability", query, nil)
+}
+
+// Equipment calls GET /equipment/:id.
+func (c *Client) Equipment(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/equipment/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateEquipment calls PATCH /equipment/:id.
+func (c *Client) UpdateEquipment(id string, body *Equipment, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/equipment/"+url.PathEscape(id), query, body)
+}
+
+// DeleteEquipment calls DELETE /equipment/:id.
+func (c *Client) DeleteEquipment(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/equipment/"+url.PathEscape(id), query, nil)
+}
+
+// EquipmentList calls GET /equipment.
+func (c *Client) EquipmentList(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/equipment", query, nil)
+}
+
+// CreateEquipment calls POST /equipment.
+func (c *Client) CreateEquipment(body *Equipment, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/equipment", query, body)
+}
+
+// UploadEventAttachment calls POST /events/:id/attachments.
+func (c *Client) UploadEventAttachment(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/attachments", query, body)
+}
+
+// EventAttachments calls GET /events/:id/attachments.
+func (c *Client) EventAttachments(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/"+url.PathEscape(id)+"/attachments", query, nil)
+}
+
+// DownloadAttachment calls GET /attachments/:id/download.
+func (c *Client) DownloadAttachment(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/attachments/"+url.PathEscape(id)+"/download", query, nil)
+}
+
+// Attachment calls GET /attachments/:id.
+func (c *Client) Attachment(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/attachments/"+url.PathEscape(id), query, nil)
+}
+
+// User calls GET /users/:user.
+func (c *Client) User(user string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users/"+url.PathEscape(user), query, nil)
+}
+
+// UpdateUser calls PUT /users/:user.
+func (c *Client) UpdateUser(user string, body *User, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/users/"+url.PathEscape(user), query, body)
+}
+
+// Users calls GET /users.
+func (c *Client) Users(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users", query, nil)
+}
+
+// Locale calls GET /locale.
+func (c *Client) Locale(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/locale", query, nil)
+}
+
+// Sync calls GET /sync.
+func (c *Client) Sync(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/sync", query, nil)
+}
+
+// MyEvents calls GET /me/events.
+func (c *Client) MyEvents(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/me/events", query, nil)
+}
+
+// UserEvents calls GET /users/:user/events.
+func (c *Client) UserEvents(user string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users/"+url.PathEscape(user)+"/events", query, nil)
+}
+
+// MyLimits calls GET /me/limits.
+func (c *Client) MyLimits(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/me/limits", query, nil)
+}
+
+// UserReliability calls GET /users/:user/reliability.
+func (c *Client) UserReliability(user string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users/"+url.PathEscape(user)+"/reliability", query, nil)
+}
+
+// ApproveEvent calls POST /events/:id/approve.
+func (c *Client) ApproveEvent(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/approve", query, body)
+}
+
+// PendingApprovals calls GET /admin/approvals.
+func (c *Client) PendingApprovals(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/approvals", query, nil)
+}
+
+// TeamCaps calls GET /teams.
+func (c *Client) TeamCaps(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/teams", query, nil)
+}
+
+// UpdateTeamCap calls PUT /teams/:team/cap.
+func (c *Client) UpdateTeamCap(team string, body *TeamCap, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/teams/"+url.PathEscape(team)+"/cap", query, body)
+}
+
+// CreateFeedback calls POST /events/:id/feedback.
+func (c *Client) CreateFeedback(id string, body *Feedback, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/feedback", query, body)
+}
+
+// RoomFeedbackSummary calls GET /rooms/:id/feedback/summary.
+func (c *Client) RoomFeedbackSummary(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/feedback/summary", query, nil)
+}
+
+// MaintenanceReport calls GET /reports/maintenance.
+func (c *Client) MaintenanceReport(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/reports/maintenance", query, nil)
+}
+
+// FairnessReport calls GET /reports/fairness.
+func (c *Client) FairnessReport(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/reports/fairness", query, nil)
+}
+
+// AdoptionReport calls GET /reports/adoption.
+func (c *Client) AdoptionReport(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/reports/adoption", query, nil)
+}
+
+// RoomUtilization calls GET /analytics/rooms/:id/utilization.
+func (c *Client) RoomUtilization(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/analytics/rooms/"+url.PathEscape(id)+"/utilization", query, nil)
+}
+
+// VenueUtilization calls GET /analytics/venues/:id/utilization.
+func (c *Client) VenueUtilization(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/analytics/venues/"+url.PathEscape(id)+"/utilization", query, nil)
+}
+
+// WorkingHours calls GET /users/:user/working-hours.
+func (c *Client) WorkingHours(user string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users/"+url.PathEscape(user)+"/working-hours", query, nil)
+}
+
+// UpdateWorkingHours calls PUT /users/:user/working-hours.
+func (c *Client) UpdateWorkingHours(user string, body *WorkingHours, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/users/"+url.PathEscape(user)+"/working-hours", query, body)
+}
+
+// FindTime calls GET /find-a-time.
+func (c *Client) FindTime(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/find-a-time", query, nil)
+}
+
+// FreeBusy calls POST /freebusy.
+func (c *Client) FreeBusy(body *FreeBusy, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/freebusy", query, body)
+}
+
+// ScheduleCheck calls GET /users/:user/schedule-check.
+func (c *Client) ScheduleCheck(user string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/users/"+url.PathEscape(user)+"/schedule-check", query, nil)
+}
+
+// CheckInCode calls POST /checkin/code.
+func (c *Client) CheckInCode(body *CheckIn, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/checkin/code", query, body)
+}
+
+// BumpedEvents calls GET /admin/bumped-events.
+func (c *Client) BumpedEvents(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/bumped-events", query, nil)
+}
+
+// TravelTimes calls GET /travel-times.
+func (c *Client) TravelTimes(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/travel-times", query, nil)
+}
+
+// UpdateTravelTime calls PUT /travel-times.
+func (c *Client) UpdateTravelTime(body *TravelTime, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/travel-times", query, body)
+}
+
+// OnboardVenue calls POST /admin/venues/onboard.
+func (c *Client) OnboardVenue(body *VenueOnboarding, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/venues/onboard", query, body)
+}
+
+// SeriesOccurrences calls GET /event-series/:id/occurrences.
+func (c *Client) SeriesOccurrences(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/event-series/"+url.PathEscape(id)+"/occurrences", query, nil)
+}
+
+// EventSeries calls GET /event-series/:id.
+func (c *Client) EventSeries(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/event-series/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateEventSeries calls PATCH /event-series/:id.
+func (c *Client) UpdateEventSeries(id string, body *EventSeries, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/event-series/"+url.PathEscape(id), query, body)
+}
+
+// DeleteEventSeries calls DELETE /event-series/:id.
+func (c *Client) DeleteEventSeries(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/event-series/"+url.PathEscape(id), query, nil)
+}
+
+// CreateEventSeries calls POST /event-series.
+func (c *Client) CreateEventSeries(body *EventSeries, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/event-series", query, body)
+}
+
+// EventGroup calls GET /event-groups/:id.
+func (c *Client) EventGroup(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/event-groups/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateEventGroup calls PATCH /event-groups/:id.
+func (c *Client) UpdateEventGroup(id string, body *EventGroup, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/event-groups/"+url.PathEscape(id), query, body)
+}
+
+// DeleteEventGroup calls DELETE /event-groups/:id.
+func (c *Client) DeleteEventGroup(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/event-groups/"+url.PathEscape(id), query, nil)
+}
+
+// RescheduleEventGroup calls POST /event-groups/:id/reschedule.
+func (c *Client) RescheduleEventGroup(id string, body *EventGroupReschedule, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/event-groups/"+url.PathEscape(id)+"/reschedule", query, body)
+}
+
+// CancelEventGroup calls POST /event-groups/:id/cancel.
+func (c *Client) CancelEventGroup(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/event-groups/"+url.PathEscape(id)+"/cancel", query, body)
+}
+
+// EventGroups calls GET /event-groups.
+func (c *Client) EventGroups(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/event-groups", query, nil)
+}
+
+// CreateEventGroup calls POST /event-groups.
+func (c *Client) CreateEventGroup(body *EventGroup, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/event-groups", query, body)
+}
+
+// RoomPanel calls GET /rooms/:id/panel.
+func (c *Client) RoomPanel(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/panel", query, nil)
+}
+
+// PanelBook calls POST /rooms/:id/panel/book.
+func (c *Client) PanelBook(id string, body *PanelAction, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/panel/book", query, body)
+}
+
+// PanelExtend calls POST /rooms/:id/panel/extend.
+func (c *Client) PanelExtend(id string, body *PanelAction, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/panel/extend", query, body)
+}
+
+// PanelEnd calls POST /rooms/:id/panel/end.
+func (c *Client) PanelEnd(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/panel/end", query, body)
+}
+
+// RoomPanelContent calls GET /rooms/:id/panel/content.
+func (c *Client) RoomPanelContent(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/panel/content", query, nil)
+}
+
+// VenuePanelContent calls GET /venues/:id/panel/content.
+func (c *Client) VenuePanelContent(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/panel/content", query, nil)
+}
+
+// CreatePanelContent calls POST /venues/:id/panel/content.
+func (c *Client) CreatePanelContent(id string, body *PanelContent, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/venues/"+url.PathEscape(id)+"/panel/content", query, body)
+}
+
+// UpdatePanelContent calls PATCH /panel/content/:id.
+func (c *Client) UpdatePanelContent(id string, body *PanelContent, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/panel/content/"+url.PathEscape(id), query, body)
+}
+
+// DeletePanelContent calls DELETE /panel/content/:id.
+func (c *Client) DeletePanelContent(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/panel/content/"+url.PathEscape(id), query, nil)
+}
+
+// VenueAnnouncements calls GET /venues/:id/announcements.
+func (c *Client) VenueAnnouncements(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/venues/"+url.PathEscape(id)+"/announcements", query, nil)
+}
+
+// CreateAnnouncement calls POST /venues/:id/announcements.
+func (c *Client) CreateAnnouncement(id string, body *Announcement, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/venues/"+url.PathEscape(id)+"/announcements", query, body)
+}
+
+// UpdateAnnouncement calls PATCH /announcements/:id.
+func (c *Client) UpdateAnnouncement(id string, body *Announcement, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/announcements/"+url.PathEscape(id), query, body)
+}
+
+// DeleteAnnouncement calls DELETE /announcements/:id.
+func (c *Client) DeleteAnnouncement(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/announcements/"+url.PathEscape(id), query, nil)
+}
+
+// ParkingSpot calls GET /parking/spots/:id.
+func (c *Client) ParkingSpot(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/parking/spots/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateParkingSpot calls PATCH /parking/spots/:id.
+func (c *Client) UpdateParkingSpot(id string, body *ParkingSpot, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/parking/spots/"+url.PathEscape(id), query, body)
+}
+
+// DeleteParkingSpot calls DELETE /parking/spots/:id.
+func (c *Client) DeleteParkingSpot(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/parking/spots/"+url.PathEscape(id), query, nil)
+}
+
+// ParkingSpots calls GET /parking/spots.
+func (c *Client) ParkingSpots(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/parking/spots", query, nil)
+}
+
+// CreateParkingSpot calls POST /parking/spots.
+func (c *Client) CreateParkingSpot(body *ParkingSpot, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/parking/spots", query, body)
+}
+
+// DeleteParkingReservation calls DELETE /parking/reservations/:id.
+func (c *Client) DeleteParkingReservation(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/parking/reservations/"+url.PathEscape(id), query, nil)
+}
+
+// ParkingReservations calls GET /parking/reservations.
+func (c *Client) ParkingReservations(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/parking/reservations", query, nil)
+}
+
+// CreateParkingReservation calls POST /parking/reservations.
+func (c *Client) CreateParkingReservation(body *ParkingReservation, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/parking/reservations", query, body)
+}
+
+// EventParking calls GET /events/:id/parking.
+func (c *Client) EventParking(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/"+url.PathEscape(id)+"/parking", query, nil)
+}
+
+// EventICal calls GET /events/:id/ical.
+func (c *Client) EventICal(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/events/"+url.PathEscape(id)+"/ical", query, nil)
+}
+
+// CreateGuestToken calls POST /events/:id/guest-tokens.
+func (c *Client) CreateGuestToken(id string, body *GuestToken, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/guest-tokens", query, body)
+}
+
+// RevokeGuestToken calls DELETE /guest-tokens/:id.
+func (c *Client) RevokeGuestToken(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/guest-tokens/"+url.PathEscape(id), query, nil)
+}
+
+// RsvpEvent calls POST /events/:id/rsvp.
+func (c *Client) RsvpEvent(id string, body *Rsvp, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/rsvp", query, body)
+}
+
+// CheckInEvent calls POST /events/:id/check-in.
+func (c *Client) CheckInEvent(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/check-in", query, body)
+}
+
+// RoomCalendar calls GET /rooms/:id/calendar.ics.
+func (c *Client) RoomCalendar(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/rooms/"+url.PathEscape(id)+"/calendar.ics", query, nil)
+}
+
+// FloorMap calls GET /floors/:id/map.
+func (c *Client) FloorMap(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id)+"/map", query, nil)
+}
+
+// UploadFloorPlan calls PUT /floors/:id/plan.
+func (c *Client) UploadFloorPlan(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/floors/"+url.PathEscape(id)+"/plan", query, body)
+}
+
+// FloorPlan calls GET /floors/:id/plan.
+func (c *Client) FloorPlan(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id)+"/plan", query, nil)
+}
+
+// DeleteFloorPlan calls DELETE /floors/:id/plan.
+func (c *Client) DeleteFloorPlan(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/floors/"+url.PathEscape(id)+"/plan", query, nil)
+}
+
+// AvailableDesks calls GET /floors/:id/available.
+func (c *Client) AvailableDesks(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id)+"/available", query, nil)
+}
+
+// Neighborhoods calls GET /floors/:id/neighborhoods.
+func (c *Client) Neighborhoods(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id)+"/neighborhoods", query, nil)
+}
+
+// CreateNeighborhood calls POST /floors/:id/neighborhoods.
+func (c *Client) CreateNeighborhood(id string, body *Neighborhood, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/floors/"+url.PathEscape(id)+"/neighborhoods", query, body)
+}
+
+// Desks calls GET /floors/:id/desks.
+func (c *Client) Desks(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id)+"/desks", query, nil)
+}
+
+// CreateDesk calls POST /floors/:id/desks.
+func (c *Client) CreateDesk(id string, body *Desk, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/floors/"+url.PathEscape(id)+"/desks", query, body)
+}
+
+// Floor calls GET /floors/:id.
+func (c *Client) Floor(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateFloor calls PATCH /floors/:id.
+func (c *Client) UpdateFloor(id string, body *Floor, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/floors/"+url.PathEscape(id), query, body)
+}
+
+// DeleteFloor calls DELETE /floors/:id.
+func (c *Client) DeleteFloor(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/floors/"+url.PathEscape(id), query, nil)
+}
+
+// Floors calls GET /floors.
+func (c *Client) Floors(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/floors", query, nil)
+}
+
+// CreateFloor calls POST /floors.
+func (c *Client) CreateFloor(body *Floor, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/floors", query, body)
+}
+
+// UpdateNeighborhood calls PATCH /neighborhoods/:id.
+func (c *Client) UpdateNeighborhood(id string, body *Neighborhood, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/neighborhoods/"+url.PathEscape(id), query, body)
+}
+
+// DeleteNeighborhood calls DELETE /neighborhoods/:id.
+func (c *Client) DeleteNeighborhood(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/neighborhoods/"+url.PathEscape(id), query, nil)
+}
+
+// Desk calls GET /desks/:id.
+func (c *Client) Desk(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/desks/"+url.PathEscape(id), query, nil)
+}
+
+// UpdateDesk calls PATCH /desks/:id.
+func (c *Client) UpdateDesk(id string, body *Desk, query url.Values) (json.RawMessage, error) {
+	return c.do("PATCH", "/desks/"+url.PathEscape(id), query, body)
+}
+
+// DeleteDesk calls DELETE /desks/:id.
+func (c *Client) DeleteDesk(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/desks/"+url.PathEscape(id), query, nil)
+}
+
+// CreateDeskBooking calls POST /desks/:id/bookings.
+func (c *Client) CreateDeskBooking(id string, body *DeskBooking, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/desks/"+url.PathEscape(id)+"/bookings", query, body)
+}
+
+// DeskBookings calls GET /desk-bookings.
+func (c *Client) DeskBookings(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/desk-bookings", query, nil)
+}
+
+// DeleteDeskBooking calls DELETE /desk-bookings/:id.
+func (c *Client) DeleteDeskBooking(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/desk-bookings/"+url.PathEscape(id), query, nil)
+}
+
+// Examples calls GET /examples.
+func (c *Client) Examples(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/examples", query, nil)
+}
+
+// Example calls GET /examples/:resource.
+func (c *Client) Example(resource string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/examples/"+url.PathEscape(resource), query, nil)
+}
+
+// RestoreVenue calls POST /venues/:id/restore.
+func (c *Client) RestoreVenue(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/venues/"+url.PathEscape(id)+"/restore", query, body)
+}
+
+// RestoreRoom calls POST /rooms/:id/restore.
+func (c *Client) RestoreRoom(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/rooms/"+url.PathEscape(id)+"/restore", query, body)
+}
+
+// RestoreEvent calls POST /events/:id/restore.
+func (c *Client) RestoreEvent(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/events/"+url.PathEscape(id)+"/restore", query, body)
+}
+
+// AuditLogs calls GET /audit-logs.
+func (c *Client) AuditLogs(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/audit-logs", query, nil)
+}
+
+// Changes calls GET /changes.
+func (c *Client) Changes(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/changes", query, nil)
+}
+
+// ApiKeys calls GET /admin/api-keys.
+func (c *Client) ApiKeys(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/api-keys", query, nil)
+}
+
+// CreateAPIKey calls POST /admin/api-keys.
+func (c *Client) CreateAPIKey(body *ApiKey, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/api-keys", query, body)
+}
+
+// RevokeAPIKey calls DELETE /admin/api-keys/:id.
+func (c *Client) RevokeAPIKey(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/admin/api-keys/"+url.PathEscape(id), query, nil)
+}
+
+// Webhooks calls GET /admin/webhooks.
+func (c *Client) Webhooks(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/webhooks", query, nil)
+}
+
+// CreateWebhook calls POST /admin/webhooks.
+func (c *Client) CreateWebhook(body *Webhook, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/webhooks", query, body)
+}
+
+// DeleteWebhook calls DELETE /admin/webhooks/:id.
+func (c *Client) DeleteWebhook(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/admin/webhooks/"+url.PathEscape(id), query, nil)
+}
+
+// WebhookDeliveries calls GET /admin/webhooks/:id/deliveries.
+func (c *Client) WebhookDeliveries(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/webhooks/"+url.PathEscape(id)+"/deliveries", query, nil)
+}
+
+// Organizations calls GET /admin/organizations.
+func (c *Client) Organizations(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/organizations", query, nil)
+}
+
+// CreateOrganization calls POST /admin/organizations.
+func (c *Client) CreateOrganization(body *Organization, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/organizations", query, body)
+}
+
+// OrganizationMembers calls GET /admin/organizations/:id/members.
+func (c *Client) OrganizationMembers(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/organizations/"+url.PathEscape(id)+"/members", query, nil)
+}
+
+// AddOrganizationMember calls PUT /admin/organizations/:id/members/:user.
+func (c *Client) AddOrganizationMember(id string, user string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/admin/organizations/"+url.PathEscape(id)+"/members/"+url.PathEscape(user), query, body)
+}
+
+// RemoveOrganizationMember calls DELETE /admin/organizations/:id/members/:user.
+func (c *Client) RemoveOrganizationMember(id string, user string, query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/admin/organizations/"+url.PathEscape(id)+"/members/"+url.PathEscape(user), query, nil)
+}
+
+// DeprecationUsage calls GET /admin/deprecations.
+func (c *Client) DeprecationUsage(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/deprecations", query, nil)
+}
+
+// AuditExport calls GET /admin/audit/export.
+func (c *Client) AuditExport(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/audit/export", query, nil)
+}
+
+// ErrorSummary calls GET /admin/errors/summary.
+func (c *Client) ErrorSummary(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/errors/summary", query, nil)
+}
+
+// HealthHistory calls GET /admin/health/history.
+func (c *Client) HealthHistory(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/health/history", query, nil)
+}
+
+// LocationBackfills calls GET /admin/locations/backfills.
+func (c *Client) LocationBackfills(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/locations/backfills", query, nil)
+}
+
+// CreateLocationBackfill calls POST /admin/locations/backfills.
+func (c *Client) CreateLocationBackfill(body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/locations/backfills", query, body)
+}
+
+// LocationBackfill calls GET /admin/locations/backfills/:id.
+func (c *Client) LocationBackfill(id string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/locations/backfills/"+url.PathEscape(id), query, nil)
+}
+
+// LocationReport calls GET /admin/locations/report.
+func (c *Client) LocationReport(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/locations/report", query, nil)
+}
+
+// ChaosSettings calls GET /admin/chaos.
+func (c *Client) ChaosSettings(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/chaos", query, nil)
+}
+
+// UpdateChaos calls PUT /admin/chaos.
+func (c *Client) UpdateChaos(body *Chaos, query url.Values) (json.RawMessage, error) {
+	return c.do("PUT", "/admin/chaos", query, body)
+}
+
+// ResetChaos calls DELETE /admin/chaos.
+func (c *Client) ResetChaos(query url.Values) (json.RawMessage, error) {
+	return c.do("DELETE", "/admin/chaos", query, nil)
+}
+
+// RunChaosJob calls POST /admin/chaos/jobs/:job.
+func (c *Client) RunChaosJob(job string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/chaos/jobs/"+url.PathEscape(job), query, body)
+}
+
+// FailoverStats calls GET /admin/database/failovers.
+func (c *Client) FailoverStats(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/database/failovers", query, nil)
+}
+
+// LegalHolds calls GET /admin/legal-holds.
+func (c *Client) LegalHolds(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/admin/legal-holds", query, nil)
+}
+
+// CreateLegalHold calls POST /admin/legal-holds.
+func (c *Client) CreateLegalHold(body *LegalHold, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/legal-holds", query, body)
+}
+
+// LiftLegalHold calls POST /admin/legal-holds/:id/lift.
+func (c *Client) LiftLegalHold(id string, body interface{}, query url.Values) (json.RawMessage, error) {
+	return c.do("POST", "/admin/legal-holds/"+url.PathEscape(id)+"/lift", query, body)
+}
+
+// Ready calls GET /readyz.
+func (c *Client) Ready(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/readyz", query, nil)
+}
+
+// SchemaDoc calls GET /schemas/:name.
+func (c *Client) SchemaDoc(name string, query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/schemas/"+url.PathEscape(name), query, nil)
+}
+
+// SchemaDocs calls GET /schemas.
+func (c *Client) SchemaDocs(query url.Values) (json.RawMessage, error) {
+	return c.do("GET", "/schemas", query, nil)
+}
@@ -0,0 +1,1388 @@
+// Code generated by sdkgen from app/web; DO NOT EDIT.
+
+export type Query = Record<string, string | string[]>;
+
+export interface ApiErrorDetail {
+  id: string;
+  status: number;
+  title: string;
+  detail: string;
+}
+
+export class ApiError extends Error {
+  constructor(public status: number, public errors: ApiErrorDetail[]) {
+    super(errors.length > 0 ? `${status} ${errors[0].id}: ${errors[0].detail}` : String(status));
+  }
+}
+
+export class BaseClient {
+  constructor(public baseURL: string, public headers: Record<string, string> = {}) {
+    this.baseURL = baseURL.replace(/\/$/, "");
+  }
+
+  protected async request(method: string, path: string, query?: Query, body?: unknown): Promise<unknown> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query ?? {})) {
+      for (const v of Array.isArray(value) ? value : [value]) {
+        params.append(key, v);
+      }
+    }
+    const qs = params.toString();
+
+    const headers: Record<string, string> = { ...this.headers };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+
+    const res = await fetch(this.baseURL + path + (qs ? "?" + qs : ""), {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const data = await res.json().catch(() => undefined);
+    if (!res.ok) {
+      throw new ApiError(res.status, (data && data.errors) || []);
+    }
+
+    return data;
+  }
+}
+
+export interface Announcement {
+  body?: string;
+  created_by?: string;
+  display_from?: string;
+  display_until?: string;
+  end_time?: string;
+  kind?: "closure" | "fire_drill" | "notice";
+  start_time?: string;
+  title: string;
+}
+
+export interface ApiKey {
+  name: string;
+  room_id?: string;
+  scope?: "read" | "read_write" | "panel";
+  user?: string;
+}
+
+export interface BookNow {
+  duration?: number;
+  name?: string;
+}
+
+export interface BookingLink {
+  auto_confirm?: boolean;
+  days_ahead?: number;
+}
+
+export interface BookingRequest {
+  company?: string;
+  description?: string;
+  email: string;
+  end_time: string;
+  name: string;
+  start_time: string;
+  title?: string;
+}
+
+export interface Chaos {
+  fail_reads?: number;
+  fail_writes?: number;
+  frozen_at?: string;
+  latency_ms?: number;
+  latency_path?: string;
+}
+
+export interface CheckIn {
+  code: string;
+  room_id: string;
+}
+
+export interface Desk {
+  amenities?: string[];
+  label: string;
+  neighborhood_id?: string;
+  x: number;
+  y: number;
+}
+
+export interface DeskBooking {
+  date: string;
+  slot: "am" | "pm" | "full";
+  team?: string;
+  user: string;
+}
+
+export interface Equipment {
+  name: string;
+  quantity: number;
+  venue_id: string;
+}
+
+export interface Event {
+  category?: string;
+  date?: number;
+  description?: string;
+  end_hour?: number;
+  end_minute?: number;
+  end_time?: string;
+  equipment?: EventEquipment[];
+  group_id?: string;
+  guests?: string[];
+  location?: string;
+  location_id: string;
+  month?: number;
+  name: string;
+  owner?: string;
+  participants?: EventParticipants[];
+  recurrence?: EventRecurrence;
+  start_hour?: number;
+  start_minute?: number;
+  start_time?: string;
+  tentative?: boolean;
+  time_zone?: string;
+  upgrade?: EventUpgrade;
+  year?: number;
+}
+
+export interface EventEquipment {
+  equipment_id: string;
+  quantity: number;
+}
+
+export interface EventParticipants {
+  email: string;
+  optional?: boolean;
+}
+
+export interface EventRecurrence {
+  count?: number;
+  exceptions?: string[];
+  frequency: "daily" | "weekly" | "monthly";
+  interval?: number;
+  until?: string;
+  week_start?: "sunday" | "monday" | "tuesday" | "wednesday" | "thursday" | "friday" | "saturday";
+  weekdays?: number[];
+}
+
+export interface EventUpgrade {
+  min_capacity?: number;
+  rooms?: string[];
+}
+
+export interface EventCancellation {
+  reason: string;
+}
+
+export interface EventExtension {
+  minutes: number;
+}
+
+export interface EventGroup {
+  description?: string;
+  name: string;
+  owner?: string;
+}
+
+export interface EventGroupReschedule {
+  offset_minutes: number;
+}
+
+export interface EventSeries {
+  description?: string;
+  end_time: string;
+  guests?: string[];
+  location?: string;
+  location_id: string;
+  name: string;
+  owner?: string;
+  recurrence: EventSeriesRecurrence;
+  start_time: string;
+}
+
+export interface EventSeriesRecurrence {
+  count?: number;
+  exceptions?: string[];
+  frequency: "daily" | "weekly" | "monthly";
+  interval?: number;
+  until?: string;
+  week_start?: "sunday" | "monday" | "tuesday" | "wednesday" | "thursday" | "friday" | "saturday";
+  weekdays?: number[];
+}
+
+export interface Feedback {
+  attendee: string;
+  av: number;
+  cleanliness: number;
+  comfort: number;
+  comment?: string;
+}
+
+export interface Floor {
+  height?: number;
+  level?: number;
+  map_image_url?: string;
+  name: string;
+  venue_id: string;
+  width?: number;
+}
+
+export interface FreeBusy {
+  end_time: string;
+  room_ids?: string[];
+  start_time: string;
+  users?: string[];
+}
+
+export interface Graphql {
+  query: string;
+}
+
+export interface GuestToken {
+  guest: string;
+  scopes?: ("view" | "rsvp" | "waitlist" | "check_in")[];
+}
+
+export interface InboundBooking {
+  cancelled?: boolean;
+  description?: string;
+  end_time?: string;
+  external_id: string;
+  guests?: string[];
+  name?: string;
+  owner?: string;
+  room_id?: string;
+  start_time?: string;
+}
+
+export interface LegalHold {
+  end_time?: string;
+  reason: string;
+  start_time?: string;
+  user?: string;
+}
+
+export interface Neighborhood {
+  name: string;
+  team?: string;
+}
+
+export interface Organization {
+  name: string;
+}
+
+export interface PanelAction {
+  minutes?: number;
+  name?: string;
+}
+
+export interface PanelContent {
+  attachment_id?: string;
+  body?: string;
+  created_by?: string;
+  duration?: number;
+  ends_at?: string;
+  image_url?: string;
+  kind: "text" | "image";
+  priority?: number;
+  room_ids?: string[];
+  starts_at?: string;
+  title?: string;
+}
+
+export interface ParkingReservation {
+  end_time?: string;
+  event_id?: string;
+  kind?: "standard" | "ev" | "accessible";
+  license_plate: string;
+  spot_id?: string;
+  start_time?: string;
+  venue_id?: string;
+  visitor?: string;
+}
+
+export interface ParkingSpot {
+  kind?: "standard" | "ev" | "accessible";
+  label: string;
+  venue_id: string;
+}
+
+export interface Room {
+  amenities?: string[];
+  building?: string;
+  capacity: number;
+  description?: string;
+  descriptions?: Record<string, unknown>;
+  floor?: string;
+  floor_id?: string;
+  high_demand?: boolean;
+  map_coordinates?: RoomMapCoordinates;
+  name: string;
+  names?: Record<string, unknown>;
+  photo_url?: string;
+  release_after?: number;
+  slug?: string;
+  venue_id: string;
+}
+
+export interface RoomMapCoordinates {
+  x: number;
+  y: number;
+}
+
+export interface RoomSuggestion {
+  amenities?: string[];
+  attendees: number;
+  end_time: string;
+  limit?: number;
+  start_time: string;
+  venue_id?: string;
+}
+
+export interface Rsvp {
+  status: "accepted" | "declined" | "tentative";
+}
+
+export interface TeamCap {
+  mode?: "soft" | "hard";
+  weekly_hours: number;
+}
+
+export interface TravelTime {
+  from_venue_id: string;
+  minutes: number;
+  to_venue_id: string;
+}
+
+export interface User {
+  hide_presence?: boolean;
+  name?: string;
+  role?: "admin" | "user";
+  team?: string;
+}
+
+export interface Venue {
+  booking_rules?: VenueBookingRules;
+  description?: string;
+  descriptions?: Record<string, unknown>;
+  name: string;
+  names?: Record<string, unknown>;
+  opening_hours?: VenueOpeningHours;
+  slug?: string;
+  time_zone?: string;
+}
+
+export interface VenueBookingRules {
+  max_advance_days?: number;
+  max_duration?: number;
+  min_duration?: number;
+}
+
+export interface VenueOpeningHours {
+  days?: VenueOpeningHoursDays[];
+  holidays?: string[];
+}
+
+export interface VenueOpeningHoursDays {
+  close: string;
+  open: string;
+  weekday: number;
+}
+
+export interface VenueOnboarding {
+  equipment?: VenueOnboardingEquipment[];
+  floors: VenueOnboardingFloors[];
+  name: string;
+  parking_spots?: VenueOnboardingParkingSpots[];
+  slug?: string;
+}
+
+export interface VenueOnboardingEquipment {
+  name: string;
+  quantity: number;
+}
+
+export interface VenueOnboardingFloors {
+  desks?: VenueOnboardingFloorsDesks[];
+  height?: number;
+  level?: number;
+  map_image_url?: string;
+  name: string;
+  neighborhoods?: VenueOnboardingFloorsNeighborhoods[];
+  rooms?: VenueOnboardingFloorsRooms[];
+  width?: number;
+}
+
+export interface VenueOnboardingFloorsDesks {
+  amenities?: string[];
+  label: string;
+  neighborhood?: string;
+  x: number;
+  y: number;
+}
+
+export interface VenueOnboardingFloorsNeighborhoods {
+  name: string;
+  team?: string;
+}
+
+export interface VenueOnboardingFloorsRooms {
+  capacity: number;
+  name: string;
+  slug?: string;
+}
+
+export interface VenueOnboardingParkingSpots {
+  kind?: "standard" | "ev" | "accessible";
+  label: string;
+}
+
+export interface Webhook {
+  events: string[];
+  secret?: string;
+  url: string;
+}
+
+export interface WorkingHours {
+  days: WorkingHoursDays[];
+  time_zone?: string;
+}
+
+export interface WorkingHoursDays {
+  end: string;
+  start: string;
+  weekday: number;
+}
+
+export class IvanaClient extends BaseClient {
+  /** GET /venues/:id */
+  venue(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /venues/:id */
+  updateVenue(id: string, body: Venue, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/venues/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /venues/:id */
+  deleteVenue(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/venues/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /venues */
+  venues(query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues`, query, undefined);
+  }
+
+  /** POST /venues */
+  createVenue(body: Venue, query?: Query): Promise<unknown> {
+    return this.request("POST", `/venues`, query, body);
+  }
+
+  /** GET /venues/:id/rooms */
+  roomsVenue(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/rooms`, query, undefined);
+  }
+
+  /** GET /venues/:id/floor-plans */
+  venueFloorPlans(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/floor-plans`, query, undefined);
+  }
+
+  /** GET /venues/:id/photos */
+  venuePhotos(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/photos`, query, undefined);
+  }
+
+  /** POST /venues/:id/photos */
+  uploadVenuePhoto(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/venues/${encodeURIComponent(id)}/photos`, query, body);
+  }
+
+  /** GET /venues/:id/rooms/:room */
+  venueRoom(id: string, room: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/rooms/${encodeURIComponent(room)}`, query, undefined);
+  }
+
+  /** GET /rooms/compare */
+  compareRooms(query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/compare`, query, undefined);
+  }
+
+  /** GET /rooms/:id */
+  room(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /rooms/:id */
+  updateRoom(id: string, body: Room, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/rooms/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /rooms/:id */
+  deleteRoom(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/rooms/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /rooms/:id/book-now */
+  bookNow(id: string, body: BookNow, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/book-now`, query, body);
+  }
+
+  /** GET /rooms/:id/photos */
+  roomPhotos(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/photos`, query, undefined);
+  }
+
+  /** POST /rooms/:id/photos */
+  uploadRoomPhoto(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/photos`, query, body);
+  }
+
+  /** GET /photos/:id/image */
+  photoImage(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/photos/${encodeURIComponent(id)}/image`, query, undefined);
+  }
+
+  /** GET /photos/:id/thumbnail */
+  photoThumbnail(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/photos/${encodeURIComponent(id)}/thumbnail`, query, undefined);
+  }
+
+  /** DELETE /photos/:id */
+  deletePhoto(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/photos/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /rooms */
+  rooms(query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms`, query, undefined);
+  }
+
+  /** POST /rooms */
+  createRoom(body: Room, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms`, query, body);
+  }
+
+  /** POST /rooms/suggest */
+  suggestRooms(body: RoomSuggestion, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/suggest`, query, body);
+  }
+
+  /** GET /events/search */
+  searchEvents(query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/search`, query, undefined);
+  }
+
+  /** GET /events/export */
+  exportEvents(query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/export`, query, undefined);
+  }
+
+  /** GET /events/:id */
+  event(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /events/:id */
+  updateEvent(id: string, body: Event, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/events/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /events/:id */
+  deleteEvent(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/events/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /events/:id/cancel */
+  cancelEvent(id: string, body: EventCancellation, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/cancel`, query, body);
+  }
+
+  /** POST /events/:id/extend */
+  extendEvent(id: string, body: EventExtension, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/extend`, query, body);
+  }
+
+  /** POST /events/:id/end */
+  endEvent(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/end`, query, body);
+  }
+
+  /** POST /events */
+  createEvent(body: Event, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events`, query, body);
+  }
+
+  /** GET /events */
+  events(query?: Query): Promise<unknown> {
+    return this.request("GET", `/events`, query, undefined);
+  }
+
+  /** POST /events/import */
+  importEvents(body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/import`, query, body);
+  }
+
+  /** GET /event-imports/:id */
+  eventImport(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/event-imports/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /event-imports/:id/confirm */
+  confirmEventImport(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/event-imports/${encodeURIComponent(id)}/confirm`, query, body);
+  }
+
+  /** GET /rooms/:id/booking-link */
+  roomBookingLink(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/booking-link`, query, undefined);
+  }
+
+  /** PUT /rooms/:id/booking-link */
+  updateRoomBookingLink(id: string, body: BookingLink, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/rooms/${encodeURIComponent(id)}/booking-link`, query, body);
+  }
+
+  /** DELETE /rooms/:id/booking-link */
+  deleteRoomBookingLink(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/rooms/${encodeURIComponent(id)}/booking-link`, query, undefined);
+  }
+
+  /** GET /book/:token */
+  bookingPage(token: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/book/${encodeURIComponent(token)}`, query, undefined);
+  }
+
+  /** POST /book/:token */
+  bookThroughLink(token: string, body: BookingRequest, query?: Query): Promise<unknown> {
+    return this.request("POST", `/book/${encodeURIComponent(token)}`, query, body);
+  }
+
+  /** POST /rooms/:id/events */
+  createEventRoomsEvents(id: string, body: Event, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/events`, query, body);
+  }
+
+  /** GET /venues/:id/events */
+  venueEvents(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/events`, query, undefined);
+  }
+
+  /** GET /venues/:id/presence */
+  venuePresence(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/presence`, query, undefined);
+  }
+
+  /** POST /integrations/inbound/:source */
+  inboundBooking(source: string, body: InboundBooking, query?: Query): Promise<unknown> {
+    return this.request("POST", `/integrations/inbound/${encodeURIComponent(source)}`, query, body);
+  }
+
+  /** POST /integrations/msgraph/notifications */
+  graphNotifications(body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/integrations/msgraph/notifications`, query, body);
+  }
+
+  /** GET /equipment/:id/availability */
+  equipmentAvailability(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/equipment/${encodeURIComponent(id)}/availability`, query, undefined);
+  }
+
+  /** GET /equipment/:id */
+  equipment(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/equipment/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /equipment/:id */
+  updateEquipment(id: string, body: Equipment, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/equipment/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /equipment/:id */
+  deleteEquipment(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/equipment/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /equipment */
+  equipmentList(query?: Query): Promise<unknown> {
+    return this.request("GET", `/equipment`, query, undefined);
+  }
+
+  /** POST /equipment */
+  createEquipment(body: Equipment, query?: Query): Promise<unknown> {
+    return this.request("POST", `/equipment`, query, body);
+  }
+
+  /** POST /events/:id/attachments */
+  uploadEventAttachment(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/attachments`, query, body);
+  }
+
+  /** GET /events/:id/attachments */
+  eventAttachments(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/${encodeURIComponent(id)}/attachments`, query, undefined);
+  }
+
+  /** GET /attachments/:id/download */
+  downloadAttachment(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/attachments/${encodeURIComponent(id)}/download`, query, undefined);
+  }
+
+  /** GET /attachments/:id */
+  attachment(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/attachments/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /users/:user */
+  user(user: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/users/${encodeURIComponent(user)}`, query, undefined);
+  }
+
+  /** PUT /users/:user */
+  updateUser(user: string, body: User, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/users/${encodeURIComponent(user)}`, query, body);
+  }
+
+  /** GET /users */
+  users(query?: Query): Promise<unknown> {
+    return this.request("GET", `/users`, query, undefined);
+  }
+
+  /** GET /locale */
+  locale(query?: Query): Promise<unknown> {
+    return this.request("GET", `/locale`, query, undefined);
+  }
+
+  /** GET /sync */
+  sync(query?: Query): Promise<unknown> {
+    return this.request("GET", `/sync`, query, undefined);
+  }
+
+  /** GET /me/events */
+  myEvents(query?: Query): Promise<unknown> {
+    return this.request("GET", `/me/events`, query, undefined);
+  }
+
+  /** GET /users/:user/events */
+  userEvents(user: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/users/${encodeURIComponent(user)}/events`, query, undefined);
+  }
+
+  /** GET /me/limits */
+  myLimits(query?: Query): Promise<unknown> {
+    return this.request("GET", `/me/limits`, query, undefined);
+  }
+
+  /** GET /users/:user/reliability */
+  userReliability(user: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/users/${encodeURIComponent(user)}/reliability`, query, undefined);
+  }
+
+  /** POST /events/:id/approve */
+  approveEvent(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/approve`, query, body);
+  }
+
+  /** GET /admin/approvals */
+  pendingApprovals(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/approvals`, query, undefined);
+  }
+
+  /** GET /teams */
+  teamCaps(query?: Query): Promise<unknown> {
+    return this.request("GET", `/teams`, query, undefined);
+  }
+
+  /** PUT /teams/:team/cap */
+  updateTeamCap(team: string, body: TeamCap, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/teams/${encodeURIComponent(team)}/cap`, query, body);
+  }
+
+  /** POST /events/:id/feedback */
+  createFeedback(id: string, body: Feedback, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/feedback`, query, body);
+  }
+
+  /** GET /rooms/:id/feedback/summary */
+  roomFeedbackSummary(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/feedback/summary`, query, undefined);
+  }
+
+  /** GET /reports/maintenance */
+  maintenanceReport(query?: Query): Promise<unknown> {
+    return this.request("GET", `/reports/maintenance`, query, undefined);
+  }
+
+  /** GET /reports/fairness */
+  fairnessReport(query?: Query): Promise<unknown> {
+    return this.request("GET", `/reports/fairness`, query, undefined);
+  }
+
+  /** GET /reports/adoption */
+  adoptionReport(query?: Query): Promise<unknown> {
+    return this.request("GET", `/reports/adoption`, query, undefined);
+  }
+
+  /** GET /analytics/rooms/:id/utilization */
+  roomUtilization(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/analytics/rooms/${encodeURIComponent(id)}/utilization`, query, undefined);
+  }
+
+  /** GET /analytics/venues/:id/utilization */
+  venueUtilization(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/analytics/venues/${encodeURIComponent(id)}/utilization`, query, undefined);
+  }
+
+  /** GET /users/:user/working-hours */
+  workingHours(user: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/users/${encodeURIComponent(user)}/working-hours`, query, undefined);
+  }
+
+  /** PUT /users/:user/working-hours */
+  updateWorkingHours(user: string, body: WorkingHours, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/users/${encodeURIComponent(user)}/working-hours`, query, body);
+  }
+
+  /** GET /find-a-time */
+  findTime(query?: Query): Promise<unknown> {
+    return this.request("GET", `/find-a-time`, query, undefined);
+  }
+
+  /** POST /freebusy */
+  freeBusy(body: FreeBusy, query?: Query): Promise<unknown> {
+    return this.request("POST", `/freebusy`, query, body);
+  }
+
+  /** GET /users/:user/schedule-check */
+  scheduleCheck(user: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/users/${encodeURIComponent(user)}/schedule-check`, query, undefined);
+  }
+
+  /** POST /checkin/code */
+  checkInCode(body: CheckIn, query?: Query): Promise<unknown> {
+    return this.request("POST", `/checkin/code`, query, body);
+  }
+
+  /** GET /admin/bumped-events */
+  bumpedEvents(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/bumped-events`, query, undefined);
+  }
+
+  /** GET /travel-times */
+  travelTimes(query?: Query): Promise<unknown> {
+    return this.request("GET", `/travel-times`, query, undefined);
+  }
+
+  /** PUT /travel-times */
+  updateTravelTime(body: TravelTime, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/travel-times`, query, body);
+  }
+
+  /** POST /admin/venues/onboard */
+  onboardVenue(body: VenueOnboarding, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/venues/onboard`, query, body);
+  }
+
+  /** GET /event-series/:id/occurrences */
+  seriesOccurrences(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/event-series/${encodeURIComponent(id)}/occurrences`, query, undefined);
+  }
+
+  /** GET /event-series/:id */
+  eventSeries(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/event-series/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /event-series/:id */
+  updateEventSeries(id: string, body: EventSeries, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/event-series/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /event-series/:id */
+  deleteEventSeries(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/event-series/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /event-series */
+  createEventSeries(body: EventSeries, query?: Query): Promise<unknown> {
+    return this.request("POST", `/event-series`, query, body);
+  }
+
+  /** GET /event-groups/:id */
+  eventGroup(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/event-groups/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /event-groups/:id */
+  updateEventGroup(id: string, body: EventGroup, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/event-groups/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /event-groups/:id */
+  deleteEventGroup(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/event-groups/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /event-groups/:id/reschedule */
+  rescheduleEventGroup(id: string, body: EventGroupReschedule, query?: Query): Promise<unknown> {
+    return this.request("POST", `/event-groups/${encodeURIComponent(id)}/reschedule`, query, body);
+  }
+
+  /** POST /event-groups/:id/cancel */
+  cancelEventGroup(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/event-groups/${encodeURIComponent(id)}/cancel`, query, body);
+  }
+
+  /** GET /event-groups */
+  eventGroups(query?: Query): Promise<unknown> {
+    return this.request("GET", `/event-groups`, query, undefined);
+  }
+
+  /** POST /event-groups */
+  createEventGroup(body: EventGroup, query?: Query): Promise<unknown> {
+    return this.request("POST", `/event-groups`, query, body);
+  }
+
+  /** GET /rooms/:id/panel */
+  roomPanel(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/panel`, query, undefined);
+  }
+
+  /** POST /rooms/:id/panel/book */
+  panelBook(id: string, body: PanelAction, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/panel/book`, query, body);
+  }
+
+  /** POST /rooms/:id/panel/extend */
+  panelExtend(id: string, body: PanelAction, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/panel/extend`, query, body);
+  }
+
+  /** POST /rooms/:id/panel/end */
+  panelEnd(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/panel/end`, query, body);
+  }
+
+  /** GET /rooms/:id/panel/content */
+  roomPanelContent(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/panel/content`, query, undefined);
+  }
+
+  /** GET /venues/:id/panel/content */
+  venuePanelContent(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/panel/content`, query, undefined);
+  }
+
+  /** POST /venues/:id/panel/content */
+  createPanelContent(id: string, body: PanelContent, query?: Query): Promise<unknown> {
+    return this.request("POST", `/venues/${encodeURIComponent(id)}/panel/content`, query, body);
+  }
+
+  /** PATCH /panel/content/:id */
+  updatePanelContent(id: string, body: PanelContent, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/panel/content/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /panel/content/:id */
+  deletePanelContent(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/panel/content/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /venues/:id/announcements */
+  venueAnnouncements(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/venues/${encodeURIComponent(id)}/announcements`, query, undefined);
+  }
+
+  /** POST /venues/:id/announcements */
+  createAnnouncement(id: string, body: Announcement, query?: Query): Promise<unknown> {
+    return this.request("POST", `/venues/${encodeURIComponent(id)}/announcements`, query, body);
+  }
+
+  /** PATCH /announcements/:id */
+  updateAnnouncement(id: string, body: Announcement, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/announcements/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /announcements/:id */
+  deleteAnnouncement(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/announcements/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /parking/spots/:id */
+  parkingSpot(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/parking/spots/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /parking/spots/:id */
+  updateParkingSpot(id: string, body: ParkingSpot, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/parking/spots/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /parking/spots/:id */
+  deleteParkingSpot(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/parking/spots/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /parking/spots */
+  parkingSpots(query?: Query): Promise<unknown> {
+    return this.request("GET", `/parking/spots`, query, undefined);
+  }
+
+  /** POST /parking/spots */
+  createParkingSpot(body: ParkingSpot, query?: Query): Promise<unknown> {
+    return this.request("POST", `/parking/spots`, query, body);
+  }
+
+  /** DELETE /parking/reservations/:id */
+  deleteParkingReservation(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/parking/reservations/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /parking/reservations */
+  parkingReservations(query?: Query): Promise<unknown> {
+    return this.request("GET", `/parking/reservations`, query, undefined);
+  }
+
+  /** POST /parking/reservations */
+  createParkingReservation(body: ParkingReservation, query?: Query): Promise<unknown> {
+    return this.request("POST", `/parking/reservations`, query, body);
+  }
+
+  /** GET /events/:id/parking */
+  eventParking(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/${encodeURIComponent(id)}/parking`, query, undefined);
+  }
+
+  /** GET /events/:id/ical */
+  eventICal(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/events/${encodeURIComponent(id)}/ical`, query, undefined);
+  }
+
+  /** POST /events/:id/guest-tokens */
+  createGuestToken(id: string, body: GuestToken, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/guest-tokens`, query, body);
+  }
+
+  /** DELETE /guest-tokens/:id */
+  revokeGuestToken(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/guest-tokens/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /events/:id/rsvp */
+  rsvpEvent(id: string, body: Rsvp, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/rsvp`, query, body);
+  }
+
+  /** POST /events/:id/check-in */
+  checkInEvent(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/check-in`, query, body);
+  }
+
+  /** GET /rooms/:id/calendar.ics */
+  roomCalendar(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/rooms/${encodeURIComponent(id)}/calendar.ics`, query, undefined);
+  }
+
+  /** GET /floors/:id/map */
+  floorMap(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}/map`, query, undefined);
+  }
+
+  /** PUT /floors/:id/plan */
+  uploadFloorPlan(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/floors/${encodeURIComponent(id)}/plan`, query, body);
+  }
+
+  /** GET /floors/:id/plan */
+  floorPlan(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}/plan`, query, undefined);
+  }
+
+  /** DELETE /floors/:id/plan */
+  deleteFloorPlan(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/floors/${encodeURIComponent(id)}/plan`, query, undefined);
+  }
+
+  /** GET /floors/:id/available */
+  availableDesks(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}/available`, query, undefined);
+  }
+
+  /** GET /floors/:id/neighborhoods */
+  neighborhoods(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}/neighborhoods`, query, undefined);
+  }
+
+  /** POST /floors/:id/neighborhoods */
+  createNeighborhood(id: string, body: Neighborhood, query?: Query): Promise<unknown> {
+    return this.request("POST", `/floors/${encodeURIComponent(id)}/neighborhoods`, query, body);
+  }
+
+  /** GET /floors/:id/desks */
+  desks(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}/desks`, query, undefined);
+  }
+
+  /** POST /floors/:id/desks */
+  createDesk(id: string, body: Desk, query?: Query): Promise<unknown> {
+    return this.request("POST", `/floors/${encodeURIComponent(id)}/desks`, query, body);
+  }
+
+  /** GET /floors/:id */
+  floor(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /floors/:id */
+  updateFloor(id: string, body: Floor, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/floors/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /floors/:id */
+  deleteFloor(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/floors/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /floors */
+  floors(query?: Query): Promise<unknown> {
+    return this.request("GET", `/floors`, query, undefined);
+  }
+
+  /** POST /floors */
+  createFloor(body: Floor, query?: Query): Promise<unknown> {
+    return this.request("POST", `/floors`, query, body);
+  }
+
+  /** PATCH /neighborhoods/:id */
+  updateNeighborhood(id: string, body: Neighborhood, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/neighborhoods/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /neighborhoods/:id */
+  deleteNeighborhood(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/neighborhoods/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /desks/:id */
+  desk(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/desks/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** PATCH /desks/:id */
+  updateDesk(id: string, body: Desk, query?: Query): Promise<unknown> {
+    return this.request("PATCH", `/desks/${encodeURIComponent(id)}`, query, body);
+  }
+
+  /** DELETE /desks/:id */
+  deleteDesk(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/desks/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** POST /desks/:id/bookings */
+  createDeskBooking(id: string, body: DeskBooking, query?: Query): Promise<unknown> {
+    return this.request("POST", `/desks/${encodeURIComponent(id)}/bookings`, query, body);
+  }
+
+  /** GET /desk-bookings */
+  deskBookings(query?: Query): Promise<unknown> {
+    return this.request("GET", `/desk-bookings`, query, undefined);
+  }
+
+  /** DELETE /desk-bookings/:id */
+  deleteDeskBooking(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/desk-bookings/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /examples */
+  examples(query?: Query): Promise<unknown> {
+    return this.request("GET", `/examples`, query, undefined);
+  }
+
+  /** GET /examples/:resource */
+  example(resource: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/examples/${encodeURIComponent(resource)}`, query, undefined);
+  }
+
+  /** POST /venues/:id/restore */
+  restoreVenue(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/venues/${encodeURIComponent(id)}/restore`, query, body);
+  }
+
+  /** POST /rooms/:id/restore */
+  restoreRoom(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/rooms/${encodeURIComponent(id)}/restore`, query, body);
+  }
+
+  /** POST /events/:id/restore */
+  restoreEvent(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/events/${encodeURIComponent(id)}/restore`, query, body);
+  }
+
+  /** GET /audit-logs */
+  auditLogs(query?: Query): Promise<unknown> {
+    return this.request("GET", `/audit-logs`, query, undefined);
+  }
+
+  /** GET /changes */
+  changes(query?: Query): Promise<unknown> {
+    return this.request("GET", `/changes`, query, undefined);
+  }
+
+  /** GET /admin/api-keys */
+  apiKeys(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/api-keys`, query, undefined);
+  }
+
+  /** POST /admin/api-keys */
+  createAPIKey(body: ApiKey, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/api-keys`, query, body);
+  }
+
+  /** DELETE /admin/api-keys/:id */
+  revokeAPIKey(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/admin/api-keys/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /admin/webhooks */
+  webhooks(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/webhooks`, query, undefined);
+  }
+
+  /** POST /admin/webhooks */
+  createWebhook(body: Webhook, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/webhooks`, query, body);
+  }
+
+  /** DELETE /admin/webhooks/:id */
+  deleteWebhook(id: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/admin/webhooks/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /admin/webhooks/:id/deliveries */
+  webhookDeliveries(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/webhooks/${encodeURIComponent(id)}/deliveries`, query, undefined);
+  }
+
+  /** GET /admin/organizations */
+  organizations(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/organizations`, query, undefined);
+  }
+
+  /** POST /admin/organizations */
+  createOrganization(body: Organization, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/organizations`, query, body);
+  }
+
+  /** GET /admin/organizations/:id/members */
+  organizationMembers(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/organizations/${encodeURIComponent(id)}/members`, query, undefined);
+  }
+
+  /** PUT /admin/organizations/:id/members/:user */
+  addOrganizationMember(id: string, user: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/admin/organizations/${encodeURIComponent(id)}/members/${encodeURIComponent(user)}`, query, body);
+  }
+
+  /** DELETE /admin/organizations/:id/members/:user */
+  removeOrganizationMember(id: string, user: string, query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/admin/organizations/${encodeURIComponent(id)}/members/${encodeURIComponent(user)}`, query, undefined);
+  }
+
+  /** GET /admin/deprecations */
+  deprecationUsage(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/deprecations`, query, undefined);
+  }
+
+  /** GET /admin/audit/export */
+  auditExport(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/audit/export`, query, undefined);
+  }
+
+  /** GET /admin/errors/summary */
+  errorSummary(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/errors/summary`, query, undefined);
+  }
+
+  /** GET /admin/health/history */
+  healthHistory(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/health/history`, query, undefined);
+  }
+
+  /** GET /admin/locations/backfills */
+  locationBackfills(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/locations/backfills`, query, undefined);
+  }
+
+  /** POST /admin/locations/backfills */
+  createLocationBackfill(body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/locations/backfills`, query, body);
+  }
+
+  /** GET /admin/locations/backfills/:id */
+  locationBackfill(id: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/locations/backfills/${encodeURIComponent(id)}`, query, undefined);
+  }
+
+  /** GET /admin/locations/report */
+  locationReport(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/locations/report`, query, undefined);
+  }
+
+  /** GET /admin/chaos */
+  chaosSettings(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/chaos`, query, undefined);
+  }
+
+  /** PUT /admin/chaos */
+  updateChaos(body: Chaos, query?: Query): Promise<unknown> {
+    return this.request("PUT", `/admin/chaos`, query, body);
+  }
+
+  /** DELETE /admin/chaos */
+  resetChaos(query?: Query): Promise<unknown> {
+    return this.request("DELETE", `/admin/chaos`, query, undefined);
+  }
+
+  /** POST /admin/chaos/jobs/:job */
+  runChaosJob(job: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/chaos/jobs/${encodeURIComponent(job)}`, query, body);
+  }
+
+  /** GET /admin/database/failovers */
+  failoverStats(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/database/failovers`, query, undefined);
+  }
+
+  /** GET /admin/legal-holds */
+  legalHolds(query?: Query): Promise<unknown> {
+    return this.request("GET", `/admin/legal-holds`, query, undefined);
+  }
+
+  /** POST /admin/legal-holds */
+  createLegalHold(body: LegalHold, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/legal-holds`, query, body);
+  }
+
+  /** POST /admin/legal-holds/:id/lift */
+  liftLegalHold(id: string, body?: unknown, query?: Query): Promise<unknown> {
+    return this.request("POST", `/admin/legal-holds/${encodeURIComponent(id)}/lift`, query, body);
+  }
+
+  /** GET /readyz */
+  ready(query?: Query): Promise<unknown> {
+    return this.request("GET", `/readyz`, query, undefined);
+  }
+
+  /** GET /schemas/:name */
+  schemaDoc(name: string, query?: Query): Promise<unknown> {
+    return this.request("GET", `/schemas/${encodeURIComponent(name)}`, query, undefined);
+  }
+
+  /** GET /schemas */
+  schemaDocs(query?: Query): Promise<unknown> {
+    return this.request("GET", `/schemas`, query, undefined);
+  }
+}